@@ -27,9 +27,14 @@ import (
 
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
 
+	"metacontroller/pkg/audit"
+	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/hooks"
 	"metacontroller/pkg/options"
 	"metacontroller/pkg/server"
+	"metacontroller/pkg/tracing"
 
+	"k8s.io/apimachinery/pkg/api/resource"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
 	"k8s.io/client-go/tools/record"
 	controllerruntime "sigs.k8s.io/controller-runtime"
@@ -41,9 +46,30 @@ var (
 	metricsAddr       = flag.String("metrics-address", ":9999", "The address to bind metrics endpoint - /metrics")
 	clientGoQPS       = flag.Float64("client-go-qps", 5, "Number of queries per second client-go is allowed to make (default 5)")
 	clientGoBurst     = flag.Int("client-go-burst", 10, "Allowed burst queries for client-go (default 10)")
+	dynamicQPS        = flag.Float64("dynamic-client-qps", 0, "Number of queries per second controllers are allowed to make for parents and children, unless they set their own clientRateLimit (defaults to --client-go-qps)")
+	dynamicBurst      = flag.Int("dynamic-client-burst", 0, "Allowed burst queries of controllers for parents and children (defaults to --client-go-burst)")
+	statusQPS         = flag.Float64("status-client-qps", 0, "Number of status updates of parents per second, separate from the other queries of controllers (0 shares the budget of --dynamic-client-qps)")
+	statusBurst       = flag.Int("status-client-burst", 0, "Allowed burst of status updates of parents (defaults to --status-client-qps)")
 	workers           = flag.Int("workers", 5, "Number of sync workers to run (default 5)")
 	eventsQPS         = flag.Float64("events-qps", 1./300., "Rate of events flowing per object (default - 1 event per 5 minutes)")
 	eventsBurst       = flag.Int("events-burst", 25, "Number of events allowed to send per object (default 25)")
+	leaderElection    = flag.Bool("leader-election", false, "Enable leader election, so only one replica runs controllers while the others stand by")
+	leaderElectionNS  = flag.String("leader-election-namespace", "", "Namespace of the leader election lock (defaults to the namespace metacontroller runs in)")
+	leaderElectionID  = flag.String("leader-election-id", "metacontroller", "Name of the leader election lock")
+	rbacPreflight     = flag.Bool("rbac-preflight", false, "Check that metacontroller has the permissions each controller needs before starting it")
+	hookProbeInterval = flag.Duration("hook-probe-interval", time.Minute, "How often to probe webhooks for reachability (0 disables probing)")
+	maxHookResponse   = flag.String("max-hook-response-size", "64Mi", "Maximum size of a hook response body, unless overridden by the hook's maxResponseSize (e.g. 64Mi)")
+	admissionPort     = flag.Int("admission-webhook-port", 0, "Port of the webhook server rejecting changes to the selectors of parents and converting CompositeControllers and DecoratorControllers between API versions (0 disables it)")
+	admissionCertDir  = flag.String("admission-webhook-cert-dir", "", "Directory holding tls.crt and tls.key for the webhook server (defaults to <temp-dir>/k8s-webhook-server/serving-certs)")
+	stripManaged      = flag.Bool("strip-managed-fields", true, "Drop the managed fields of cached objects which aren't server-side applied, to save memory")
+	listPageSize      = flag.Int64("informer-list-page-size", 500, "Maximum number of objects fetched by each list request when starting to watch a resource (0 lets the API server send them all at once)")
+	informerIdle      = flag.Duration("informer-idle-timeout", time.Minute, "How long to keep watching a resource once no controller uses it, so controllers recreated in the meantime don't relist it (0 stops watching immediately)")
+	debugTokenFile    = flag.String("debug-token-file", "", "File holding the bearer token required to read /debug/controllers and /debug/hooks (not served if empty), and to drain endpoints on /debug/endpoints (forbidden if empty), on the metrics endpoint")
+	dryRun            = flag.Bool("dry-run", false, "Only dry-run the creations, updates and deletions of children of all controllers, reporting them in events and metrics instead")
+	auditLog          = flag.String("audit-log", "", "File to append the creations, updates, patches and deletions Metacontroller makes to as JSON lines, or - for stdout (disabled if empty)")
+	syncLoopSyncs     = flag.Int("sync-loop-syncs", common.DefaultSyncLoopSyncs, "Number of syncs of a parent writing its children or status within --sync-loop-window which make a hot loop, backing its syncs off (0 disables loop detection)")
+	syncLoopWindow    = flag.Duration("sync-loop-window", common.DefaultSyncLoopWindow, "Window in which --sync-loop-syncs writing syncs of a parent make a hot loop")
+	otlpEndpoint      = flag.String("otlp-endpoint", "", "OTLP/HTTP collector endpoint to export traces to, e.g. http://otel-collector:4318 (disabled if empty)")
 	version           = "No version provided"
 )
 
@@ -58,6 +84,41 @@ func main() {
 	logging.Logger.Info("Metrics http server address", "port", *metricsAddr)
 	logging.Logger.Info("Metacontroller build information", "version", version)
 
+	maxResponseSize, err := resource.ParseQuantity(*maxHookResponse)
+	if err != nil || maxResponseSize.Sign() <= 0 {
+		logging.Logger.Error(err, "Terminating: invalid --max-hook-response-size", "value", *maxHookResponse)
+		os.Exit(1)
+	}
+	hooks.DefaultMaxResponseSize = maxResponseSize.Value()
+
+	if *dryRun {
+		logging.Logger.Info("Dry-running the writes to children of all controllers")
+		common.ForceDryRun = true
+	}
+
+	if *otlpEndpoint != "" {
+		logging.Logger.Info("Exporting traces", "otlp_endpoint", *otlpEndpoint)
+		exporter := tracing.NewOTLPExporter(*otlpEndpoint, "metacontroller")
+		exporter.OnError = func(err error) {
+			logging.Logger.Error(err, "Failed to export traces")
+		}
+		tracing.SetExporter(exporter)
+		defer exporter.Shutdown()
+	}
+
+	if *auditLog != "" {
+		logging.Logger.Info("Auditing writes", "audit_log", *auditLog)
+		out, err := audit.Open(*auditLog)
+		if err != nil {
+			logging.Logger.Error(err, "Terminating")
+			os.Exit(1)
+		}
+		if out != os.Stdout {
+			defer out.Close()
+		}
+		audit.Log.SetOutput(out)
+	}
+
 	config, err := controllerruntime.GetConfig()
 	if err != nil {
 		logging.Logger.Error(err, "Terminating")
@@ -75,7 +136,24 @@ func main() {
 			BurstSize: *eventsBurst,
 			QPS:       float32(*eventsQPS),
 		},
-		MetricsEndpoint: *metricsAddr,
+		MetricsEndpoint:         *metricsAddr,
+		LeaderElection:          *leaderElection,
+		LeaderElectionNamespace: *leaderElectionNS,
+		LeaderElectionID:        *leaderElectionID,
+		RBACPreflight:           *rbacPreflight,
+		HookProbeInterval:       *hookProbeInterval,
+		AdmissionPort:           *admissionPort,
+		AdmissionCertDir:        *admissionCertDir,
+		StripManagedFields:      *stripManaged,
+		InformerIdleTimeout:     *informerIdle,
+		InformerListPageSize:    *listPageSize,
+		DynamicClientQPS:        float32(*dynamicQPS),
+		DynamicClientBurst:      *dynamicBurst,
+		StatusClientQPS:         float32(*statusQPS),
+		StatusClientBurst:       *statusBurst,
+		DebugTokenFile:          *debugTokenFile,
+		SyncLoopSyncs:           *syncLoopSyncs,
+		SyncLoopWindow:          *syncLoopWindow,
 	}
 
 	// Create a new manager with a stop function
@@ -45,6 +45,16 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&CompositeControllerList{},
 		&DecoratorController{},
 		&DecoratorControllerList{},
+		&GlobalController{},
+		&GlobalControllerList{},
+		&CronController{},
+		&CronControllerList{},
+		&ExternalResourceController{},
+		&ExternalResourceControllerList{},
+		&StateMachineController{},
+		&StateMachineControllerList{},
+		&ControllerGroup{},
+		&ControllerGroupList{},
 		&ControllerRevision{},
 		&ControllerRevisionList{},
 	)
@@ -18,14 +18,18 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // CompositeController
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:path=compositecontrollers,scope=Cluster,shortName=cc;cctl
+// +kubebuilder:storageversion
 type CompositeController struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata"`
@@ -44,11 +48,212 @@ func (cc *CompositeController) GetCustomizeHook() *Hook {
 type CompositeControllerSpec struct {
 	ParentResource CompositeControllerParentResourceRule  `json:"parentResource"`
 	ChildResources []CompositeControllerChildResourceRule `json:"childResources,omitempty"`
+	ChildTemplates []ChildTemplate                        `json:"childTemplates,omitempty"`
+	UpdateStrategy *CompositeControllerUpdateStrategy     `json:"updateStrategy,omitempty"`
+	KindOrder      []string                               `json:"kindOrder,omitempty"`
 
+	Mode  ControllerMode            `json:"mode,omitempty"`
 	Hooks *CompositeControllerHooks `json:"hooks,omitempty"`
 
-	ResyncPeriodSeconds *int32 `json:"resyncPeriodSeconds,omitempty"`
-	GenerateSelector    *bool  `json:"generateSelector,omitempty"`
+	ResyncPeriodSeconds *int32           `json:"resyncPeriodSeconds,omitempty"`
+	ChildEventDebounce  *metav1.Duration `json:"childEventDebounce,omitempty"`
+	GenerateSelector    *bool            `json:"generateSelector,omitempty"`
+	ConsistentReads     *bool            `json:"consistentReads,omitempty"`
+	DeltaSync           *bool            `json:"deltaSync,omitempty"`
+	Paused              *bool            `json:"paused,omitempty"`
+
+	ApplyStrategy    ChildApplyStrategy `json:"applyStrategy,omitempty"`
+	FieldManager     string             `json:"fieldManager,omitempty"`
+	UpdateDiff       *ChildUpdateDiff   `json:"updateDiff,omitempty"`
+	ValidateChildren *bool              `json:"validateChildren,omitempty"`
+	ChildConcurrency *int32             `json:"childConcurrency,omitempty"`
+	DryRun           *bool              `json:"dryRun,omitempty"`
+
+	StatusApplyStrategy StatusApplyStrategy `json:"statusApplyStrategy,omitempty"`
+
+	DeletionPolicy ControllerDeletionPolicy `json:"deletionPolicy,omitempty"`
+	AdoptionPolicy AdoptionPolicy           `json:"adoptionPolicy,omitempty"`
+
+	DependsOn []ControllerDependency `json:"dependsOn,omitempty"`
+
+	Workers         *int32            `json:"workers,omitempty"`
+	RateLimit       *HookRateLimit    `json:"rateLimit,omitempty"`
+	ClientRateLimit *ClientRateLimit  `json:"clientRateLimit,omitempty"`
+	Limits          *ControllerLimits `json:"limits,omitempty"`
+
+	DerivedFields []DerivedField `json:"derivedFields,omitempty"`
+
+	StatusConventions *StatusConventions `json:"statusConventions,omitempty"`
+
+	HookTransport *HookTransport `json:"hookTransport,omitempty"`
+
+	ControllerGroupRef *ControllerGroupReference `json:"controllerGroupRef,omitempty"`
+
+	ManagedWebhook *ManagedWebhook `json:"managedWebhook,omitempty"`
+}
+
+// ChildTemplate is a Go template of the manifests of children, rendered
+// against the parent and its derived fields.
+type ChildTemplate struct {
+	Name     string `json:"name"`
+	Template string `json:"template"`
+}
+
+// ControllerMode is whether a controller changes its parents and children.
+// +kubebuilder:validation:Enum=Enforce;ReportOnly
+type ControllerMode string
+
+const (
+	// ControllerModeEnforce syncs parents and their children. This is the
+	// default.
+	ControllerModeEnforce ControllerMode = "Enforce"
+	// ControllerModeReportOnly calls the hooks, but only reports how the
+	// children differ from the desired ones, without writing anything.
+	ControllerModeReportOnly ControllerMode = "ReportOnly"
+)
+
+// ControllerLimits caps the number of children the hooks of a controller may
+// return for a parent, in total and per kind.
+type ControllerLimits struct {
+	// +kubebuilder:validation:Minimum=0
+	MaxChildrenPerParent *int32           `json:"maxChildrenPerParent,omitempty"`
+	MaxChildrenPerKind   []ChildKindLimit `json:"maxChildrenPerKind,omitempty"`
+}
+
+// ChildKindLimit caps the number of children of a kind the hooks of a
+// controller may return for a parent.
+type ChildKindLimit struct {
+	ResourceRule `json:",inline"`
+	// +kubebuilder:validation:Minimum=0
+	MaxChildren int32 `json:"maxChildren"`
+}
+
+// ControllerDeletionPolicy is what happens to the children of a controller's
+// parents when the controller itself is deleted.
+// +kubebuilder:validation:Enum=Orphan;DeleteChildren;Abandon
+type ControllerDeletionPolicy string
+
+const (
+	// ControllerDeletionOrphan leaves children as they are.
+	ControllerDeletionOrphan ControllerDeletionPolicy = "Orphan"
+	// ControllerDeletionDeleteChildren deletes the children of all parents.
+	ControllerDeletionDeleteChildren ControllerDeletionPolicy = "DeleteChildren"
+	// ControllerDeletionAbandon detaches children from their parents, and
+	// labels them with the name of the deleted controller.
+	ControllerDeletionAbandon ControllerDeletionPolicy = "Abandon"
+)
+
+// ChildUpdateDiff reports why children are updated, with the diff between
+// each child and what a server-side dry run of its update returns.
+type ChildUpdateDiff struct {
+	Report ChildUpdateDiffReport `json:"report"`
+}
+
+// ChildUpdateDiffReport is where the diffs of child updates are reported.
+// +kubebuilder:validation:Enum=Log;Event
+type ChildUpdateDiffReport string
+
+const (
+	// ChildUpdateDiffLog logs the diffs.
+	ChildUpdateDiffLog ChildUpdateDiffReport = "Log"
+	// ChildUpdateDiffEvent logs the diffs and records them in events on the
+	// parent.
+	ChildUpdateDiffEvent ChildUpdateDiffReport = "Event"
+)
+
+// StatusApplyStrategy is how the status returned by hooks is written to the
+// parent.
+// +kubebuilder:validation:Enum=Replace;ServerSideApply
+type StatusApplyStrategy string
+
+const (
+	// StatusApplyReplace replaces the whole status of the parent.
+	StatusApplyReplace StatusApplyStrategy = "Replace"
+	// StatusApplyServerSideApply server-side applies the status to the
+	// status subresource of the parent, leaving the fields owned by other
+	// field managers alone.
+	StatusApplyServerSideApply StatusApplyStrategy = "ServerSideApply"
+)
+
+// AdoptionPolicy is which orphans matching the selector of a parent are
+// adopted as its children.
+// +kubebuilder:validation:Enum=Never;IfMatchingSelector;RequireAnnotation;Review
+type AdoptionPolicy string
+
+const (
+	// AdoptionNever never adopts orphans.
+	AdoptionNever AdoptionPolicy = "Never"
+	// AdoptionIfMatchingSelector adopts all orphans matching the selector.
+	AdoptionIfMatchingSelector AdoptionPolicy = "IfMatchingSelector"
+	// AdoptionRequireAnnotation only adopts orphans matching the selector
+	// which have the metacontroller.k8s.io/adopt: "true" annotation.
+	AdoptionRequireAnnotation AdoptionPolicy = "RequireAnnotation"
+	// AdoptionReview adopts orphans like RequireAnnotation, and reports the
+	// changes adopting the other desired orphans would make.
+	AdoptionReview AdoptionPolicy = "Review"
+)
+
+// StatusConventions makes Metacontroller merge the conditions returned by
+// hooks into the parent status by type, compute a Ready condition with a
+// CEL expression over the parent and its children, and optionally record the
+// outcome of the last sync in status.sync.
+type StatusConventions struct {
+	ReadyExpression string `json:"readyExpression,omitempty"`
+	SyncStatus      *bool  `json:"syncStatus,omitempty"`
+}
+
+// DerivedField is a value computed with a CEL expression over the parent and
+// its children, and sent to the hooks in the `derived` field of requests.
+type DerivedField struct {
+	// +kubebuilder:validation:MinLength=1
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+}
+
+// HookRateLimit throttles the hook invocations of a controller.
+type HookRateLimit struct {
+	// +kubebuilder:validation:Minimum=1
+	QPS int32 `json:"qps"`
+	// +kubebuilder:validation:Minimum=1
+	Burst *int32 `json:"burst,omitempty"`
+}
+
+// ClientRateLimit gives a controller its own client-side rate limits for its
+// requests to the API server, instead of sharing those of Metacontroller.
+type ClientRateLimit struct {
+	// +kubebuilder:validation:Minimum=1
+	QPS int32 `json:"qps"`
+	// +kubebuilder:validation:Minimum=1
+	Burst *int32 `json:"burst,omitempty"`
+	// Status is the budget of the status updates of parents, separate from
+	// the other requests. If unset, they share the same budget.
+	Status *ClientRateBudget `json:"status,omitempty"`
+}
+
+// ClientRateBudget is a rate limit of client requests.
+type ClientRateBudget struct {
+	// +kubebuilder:validation:Minimum=1
+	QPS int32 `json:"qps"`
+	// +kubebuilder:validation:Minimum=1
+	Burst *int32 `json:"burst,omitempty"`
+}
+
+// HookTransport tunes the HTTP connections used to call the webhooks of a
+// controller.
+type HookTransport struct {
+	// +kubebuilder:validation:Minimum=0
+	MaxIdleConnsPerHost *int32           `json:"maxIdleConnsPerHost,omitempty"`
+	IdleConnTimeout     *metav1.Duration `json:"idleConnTimeout,omitempty"`
+	KeepAlive           *metav1.Duration `json:"keepAlive,omitempty"`
+	DisableKeepAlives   *bool            `json:"disableKeepAlives,omitempty"`
+}
+
+// ControllerDependency references another controller which must be Ready
+// before this controller is started.
+type ControllerDependency struct {
+	// +kubebuilder:validation:Enum=CompositeController;DecoratorController;GlobalController;CronController;ExternalResourceController;StateMachineController
+	Kind string `json:"kind"`
+	Name string `json:"name"`
 }
 
 type ResourceRule struct {
@@ -56,15 +261,34 @@ type ResourceRule struct {
 	Resource   string `json:"resource"`
 }
 
+// WatchSelector restricts the informer watching the objects of a resource rule
+// to those matching its label and field selectors. Unlike the other selectors,
+// it's evaluated by the API server, so the objects which don't match are
+// never cached by Metacontroller.
+type WatchSelector struct {
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+	FieldSelector string                `json:"fieldSelector,omitempty"`
+}
+
 type CompositeControllerParentResourceRule struct {
-	ResourceRule    `json:",inline"`
-	RevisionHistory *CompositeControllerRevisionHistory `json:"revisionHistory,omitempty"`
+	ResourceRule       `json:",inline"`
+	LabelSelector      *metav1.LabelSelector               `json:"labelSelector,omitempty"`
+	AnnotationSelector *AnnotationSelector                 `json:"annotationSelector,omitempty"`
+	RevisionHistory    *CompositeControllerRevisionHistory `json:"revisionHistory,omitempty"`
+	WatchSelector      *WatchSelector                      `json:"watchSelector,omitempty"`
 }
 
 type CompositeControllerRevisionHistory struct {
 	FieldPaths []string `json:"fieldPaths,omitempty"`
 }
 
+// CompositeControllerUpdateStrategy configures the rolling updates of a
+// controller as a whole.
+type CompositeControllerUpdateStrategy struct {
+	// +kubebuilder:validation:Minimum=0
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+}
+
 type ChildUpdateMethod string
 
 const (
@@ -73,16 +297,86 @@ const (
 	ChildUpdateInPlace         ChildUpdateMethod = "InPlace"
 	ChildUpdateRollingRecreate ChildUpdateMethod = "RollingRecreate"
 	ChildUpdateRollingInPlace  ChildUpdateMethod = "RollingInPlace"
+	ChildUpdateBlueGreen       ChildUpdateMethod = "BlueGreen"
 )
 
 type CompositeControllerChildResourceRule struct {
 	ResourceRule   `json:",inline"`
 	UpdateStrategy *CompositeControllerChildUpdateStrategy `json:"updateStrategy,omitempty"`
+	ApplyStrategy  ChildApplyStrategy                      `json:"applyStrategy,omitempty"`
+	ConflictPolicy *ChildConflictPolicy                    `json:"conflictPolicy,omitempty"`
+	IgnorePaths    []string                                `json:"ignorePaths,omitempty"`
+	CrossNamespace *ChildCrossNamespacePolicy              `json:"crossNamespace,omitempty"`
+	ClusterScoped  *bool                                   `json:"clusterScoped,omitempty"`
+	WatchSelector  *WatchSelector                          `json:"watchSelector,omitempty"`
+
+	ApplyWave           int32  `json:"applyWave,omitempty"`
+	ReadinessExpression string `json:"readinessExpression,omitempty"`
+	FinalizeWave        *int32 `json:"finalizeWave,omitempty"`
+
+	ResyncPeriodSeconds *int32 `json:"resyncPeriodSeconds,omitempty"`
+
+	DeletionPolicy ChildDeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+// ChildCrossNamespacePolicy allows children of a kind to be placed in
+// namespaces other than the parent's, either listed or matching a selector.
+type ChildCrossNamespacePolicy struct {
+	Namespaces        []string              `json:"namespaces,omitempty"`
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// ChildDeletionPolicy is what happens to children of a kind when their parent
+// is deleted, like the reclaim policy of a PersistentVolume.
+// +kubebuilder:validation:Enum=Delete;Retain
+type ChildDeletionPolicy string
+
+const (
+	// ChildDeletionDelete deletes the children with their parent.
+	ChildDeletionDelete ChildDeletionPolicy = "Delete"
+	// ChildDeletionRetain releases the children from their parent, so they
+	// outlive it.
+	ChildDeletionRetain ChildDeletionPolicy = "Retain"
+)
+
+// ChildApplyStrategy is how the desired state of children is written.
+// +kubebuilder:validation:Enum=ThreeWayMerge;ServerSideApply
+type ChildApplyStrategy string
+
+const (
+	// ChildApplyThreeWayMerge merges the desired state into children on the
+	// client side, remembering it in the last-applied-configuration annotation.
+	ChildApplyThreeWayMerge ChildApplyStrategy = "ThreeWayMerge"
+	// ChildApplyServerSide writes children with server-side apply.
+	ChildApplyServerSide ChildApplyStrategy = "ServerSideApply"
+)
+
+// ChildConflictPolicy is how server-side apply conflicts with other field
+// managers are handled for a kind of children.
+type ChildConflictPolicy struct {
+	Mode           ChildConflictMode `json:"mode,omitempty"`
+	SurrenderPaths []string          `json:"surrenderPaths,omitempty"`
 }
 
+// ChildConflictMode is what to do when an applied field is owned by another
+// field manager.
+// +kubebuilder:validation:Enum=Force;Fail
+type ChildConflictMode string
+
+const (
+	// ChildConflictForce takes over the conflicting fields.
+	ChildConflictForce ChildConflictMode = "Force"
+	// ChildConflictFail fails the apply, leaving the child untouched.
+	ChildConflictFail ChildConflictMode = "Fail"
+)
+
 type CompositeControllerChildUpdateStrategy struct {
 	Method       ChildUpdateMethod       `json:"method,omitempty"`
 	StatusChecks ChildUpdateStatusChecks `json:"statusChecks,omitempty"`
+
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	Partition *int32 `json:"partition,omitempty"`
 }
 
 type ChildUpdateStatusChecks struct {
@@ -95,6 +389,44 @@ type StatusConditionCheck struct {
 	Reason *string `json:"reason,omitempty"`
 }
 
+// ManagedWebhook is a webhook server which metacontroller deploys for a
+// controller, as a Deployment and a Service.
+type ManagedWebhook struct {
+	// +kubebuilder:validation:MinLength=1
+	Image string `json:"image"`
+	// +kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
+
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port *int32 `json:"port,omitempty"`
+
+	Command   []string                     `json:"command,omitempty"`
+	Args      []string                     `json:"args,omitempty"`
+	Env       []corev1.EnvVar              `json:"env,omitempty"`
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+}
+
+// ManagedWebhookStatus is where the managed webhook of a controller runs.
+type ManagedWebhookStatus struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// SyncErrorSummary is the error of the last sync of a parent.
+type SyncErrorSummary struct {
+	Kind      string `json:"kind,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Message   string `json:"message"`
+	// Since is when the parent started failing with this error.
+	Since metav1.Time `json:"since"`
+}
+
 type ServiceReference struct {
 	Name      string  `json:"name"`
 	Namespace string  `json:"namespace"`
@@ -103,27 +435,137 @@ type ServiceReference struct {
 }
 
 type CompositeControllerHooks struct {
-	Customize *Hook `json:"customize,omitempty"`
-	Sync      *Hook `json:"sync,omitempty"`
-	Finalize  *Hook `json:"finalize,omitempty"`
+	Customize  *Hook `json:"customize,omitempty"`
+	Sync       *Hook `json:"sync,omitempty"`
+	Finalize   *Hook `json:"finalize,omitempty"`
+	Events     *Hook `json:"events,omitempty"`
+	ApplyError *Hook `json:"applyError,omitempty"`
 
 	PreUpdateChild  *Hook `json:"preUpdateChild,omitempty"`
 	PostUpdateChild *Hook `json:"postUpdateChild,omitempty"`
 }
 
 type Hook struct {
-	Webhook *Webhook `json:"webhook,omitempty"`
+	Webhook *Webhook     `json:"webhook,omitempty"`
+	Exec    *ExecHook    `json:"exec,omitempty"`
+	NATS    *NATSHook    `json:"nats,omitempty"`
+	Version *HookVersion `json:"version,omitempty"`
+
+	MaxResponseSize *resource.Quantity  `json:"maxResponseSize,omitempty"`
+	ResponseSchema  *HookResponseSchema `json:"responseSchema,omitempty"`
+}
+
+// HookResponseSchema is a JSON Schema that the responses of a hook must
+// conform to, given either inline or in a ConfigMap.
+type HookResponseSchema struct {
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	Inline       *runtime.RawExtension  `json:"inline,omitempty"`
+	ConfigMapRef *ConfigMapKeyReference `json:"configMapRef,omitempty"`
 }
 
+// HookVersion is the version of the request/response schema used by a hook.
+// +kubebuilder:validation:Enum=v1;v2
+type HookVersion string
+
+const (
+	// HookVersionV1 is the original, unversioned hook payload schema.
+	HookVersionV1 HookVersion = "v1"
+	// HookVersionV2 adds an explicit apiVersion to hook requests and responses.
+	HookVersionV2 HookVersion = "v2"
+)
+
 type Webhook struct {
 	URL     *string          `json:"url,omitempty"`
 	Timeout *metav1.Duration `json:"timeout,omitempty"`
 
+	ShadowURL *string `json:"shadowURL,omitempty"`
+
+	ConnectTimeout      *metav1.Duration `json:"connectTimeout,omitempty"`
+	TLSHandshakeTimeout *metav1.Duration `json:"tlsHandshakeTimeout,omitempty"`
+
+	Proxy    *string `json:"proxy,omitempty"`
+	CABundle []byte  `json:"caBundle,omitempty"`
+
 	Path    *string           `json:"path,omitempty"`
 	Service *ServiceReference `json:"service,omitempty"`
+
+	Signing *WebhookSigning `json:"signing,omitempty"`
+
+	Compression *WebhookCompression `json:"compression,omitempty"`
+
+	HealthPath *string `json:"healthPath,omitempty"`
 }
 
-type CompositeControllerStatus struct{}
+// WebhookCompression is the content encoding used for webhook request and
+// response bodies.
+// +kubebuilder:validation:Enum=gzip
+type WebhookCompression string
+
+const (
+	WebhookCompressionGzip WebhookCompression = "gzip"
+)
+
+// ExecHook runs a hook locally, either as a command inside the metacontroller
+// container or through a UNIX socket served by a sidecar.
+type ExecHook struct {
+	Command []string         `json:"command,omitempty"`
+	Socket  *string          `json:"socket,omitempty"`
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// NATSHook sends requests to a NATS subject and waits for a reply, so hook
+// implementations can be scaled as a queue group.
+type NATSHook struct {
+	URL     string           `json:"url"`
+	Subject string           `json:"subject"`
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+type WebhookSigning struct {
+	SecretRef SecretKeyReference `json:"secretRef"`
+}
+
+type SecretKeyReference struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Key       string `json:"key"`
+}
+
+type ConfigMapKeyReference struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Key       string `json:"key"`
+}
+
+type CompositeControllerStatus struct {
+	Conditions         []metav1.Condition    `json:"conditions,omitempty"`
+	ObservedGeneration int64                 `json:"observedGeneration,omitempty"`
+	ManagedWebhook     *ManagedWebhookStatus `json:"managedWebhook,omitempty"`
+
+	// Parents is the number of parents matched by the controller.
+	Parents int32 `json:"parents,omitempty"`
+	// FailingParents is the number of parents whose last sync failed.
+	FailingParents int32 `json:"failingParents,omitempty"`
+	// LastErrors are the most recent errors of the failing parents, newest
+	// first.
+	LastErrors []SyncErrorSummary `json:"lastErrors,omitempty"`
+}
+
+const (
+	// ConditionReady is True while the controller is running.
+	ConditionReady = "Ready"
+	// ConditionWaitingForDependency is True while the controller isn't started
+	// because some controllers it depends on aren't Ready.
+	ConditionWaitingForDependency = "WaitingForDependency"
+	// ConditionMissingRBAC is True while the controller isn't started because
+	// metacontroller lacks permissions it needs (see --rbac-preflight).
+	ConditionMissingRBAC = "MissingRBAC"
+	// ConditionHooksReachable is False while some of the controller's webhooks
+	// don't respond to health probes (see --hook-probe-interval).
+	ConditionHooksReachable = "HooksReachable"
+)
 
 // CompositeControllerList
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -145,6 +587,7 @@ type ControllerRevision struct {
 
 	ParentPatch runtime.RawExtension         `json:"parentPatch"`
 	Children    []ControllerRevisionChildren `json:"children,omitempty"`
+	Revision    int64                        `json:"revision,omitempty"`
 }
 
 type ControllerRevisionChildren struct {
@@ -165,6 +608,7 @@ type ControllerRevisionList struct {
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:path=decoratorcontrollers,scope=Cluster,shortName=dec;decorators
+// +kubebuilder:storageversion
 type DecoratorController struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata"`
@@ -184,38 +628,105 @@ type DecoratorControllerSpec struct {
 	Resources   []DecoratorControllerResourceRule   `json:"resources"`
 	Attachments []DecoratorControllerAttachmentRule `json:"attachments,omitempty"`
 
+	ExcludeNamespaces      []string              `json:"excludeNamespaces,omitempty"`
+	ExcludeObjectsMatching *metav1.LabelSelector `json:"excludeObjectsMatching,omitempty"`
+
 	Hooks *DecoratorControllerHooks `json:"hooks,omitempty"`
 
-	ResyncPeriodSeconds *int32 `json:"resyncPeriodSeconds,omitempty"`
+	ResyncPeriodSeconds *int32           `json:"resyncPeriodSeconds,omitempty"`
+	ChildEventDebounce  *metav1.Duration `json:"childEventDebounce,omitempty"`
+	Paused              *bool            `json:"paused,omitempty"`
+
+	DependsOn []ControllerDependency `json:"dependsOn,omitempty"`
+
+	Workers         *int32           `json:"workers,omitempty"`
+	RateLimit       *HookRateLimit   `json:"rateLimit,omitempty"`
+	ClientRateLimit *ClientRateLimit `json:"clientRateLimit,omitempty"`
+
+	DerivedFields []DerivedField `json:"derivedFields,omitempty"`
+
+	HookTransport *HookTransport `json:"hookTransport,omitempty"`
+
+	ApplyStrategy    ChildApplyStrategy `json:"applyStrategy,omitempty"`
+	FieldManager     string             `json:"fieldManager,omitempty"`
+	UpdateDiff       *ChildUpdateDiff   `json:"updateDiff,omitempty"`
+	ValidateChildren *bool              `json:"validateChildren,omitempty"`
+	ChildConcurrency *int32             `json:"childConcurrency,omitempty"`
+	DryRun           *bool              `json:"dryRun,omitempty"`
+
+	TargetPatchPaths []string `json:"targetPatchPaths,omitempty"`
+
+	SyncBatch *DecoratorSyncBatch `json:"syncBatch,omitempty"`
+
+	ControllerGroupRef *ControllerGroupReference `json:"controllerGroupRef,omitempty"`
+
+	ManagedWebhook *ManagedWebhook `json:"managedWebhook,omitempty"`
+}
+
+// DecoratorSyncBatch lets the sync hook handle several targets per call.
+type DecoratorSyncBatch struct {
+	// +kubebuilder:validation:Minimum=2
+	MaxSize int32 `json:"maxSize"`
+	// +kubebuilder:validation:Minimum=1
+	MaxWaitMilliseconds *int32 `json:"maxWaitMilliseconds,omitempty"`
 }
 
 type DecoratorControllerResourceRule struct {
 	ResourceRule       `json:",inline"`
 	LabelSelector      *metav1.LabelSelector `json:"labelSelector,omitempty"`
 	AnnotationSelector *AnnotationSelector   `json:"annotationSelector,omitempty"`
+	NamespaceSelector  *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	Namespaces         []string              `json:"namespaces,omitempty"`
+	ExcludeNamespaces  []string              `json:"excludeNamespaces,omitempty"`
+	FilterExpression   string                `json:"filterExpression,omitempty"`
+	WatchSelector      *WatchSelector        `json:"watchSelector,omitempty"`
 }
 
 type AnnotationSelector struct {
 	MatchAnnotations map[string]string                 `json:"matchAnnotations,omitempty"`
 	MatchExpressions []metav1.LabelSelectorRequirement `json:"matchExpressions,omitempty"`
+	MatchPatterns    map[string]string                 `json:"matchPatterns,omitempty"`
 }
 
 type DecoratorControllerAttachmentRule struct {
 	ResourceRule   `json:",inline"`
 	UpdateStrategy *DecoratorControllerAttachmentUpdateStrategy `json:"updateStrategy,omitempty"`
+	ApplyStrategy  ChildApplyStrategy                           `json:"applyStrategy,omitempty"`
+	ConflictPolicy *ChildConflictPolicy                         `json:"conflictPolicy,omitempty"`
+	IgnorePaths    []string                                     `json:"ignorePaths,omitempty"`
+	ClusterScoped  *bool                                        `json:"clusterScoped,omitempty"`
+	Shared         *bool                                        `json:"shared,omitempty"`
+	WatchSelector  *WatchSelector                               `json:"watchSelector,omitempty"`
 }
 
 type DecoratorControllerAttachmentUpdateStrategy struct {
-	Method ChildUpdateMethod `json:"method,omitempty"`
+	Method       ChildUpdateMethod       `json:"method,omitempty"`
+	StatusChecks ChildUpdateStatusChecks `json:"statusChecks,omitempty"`
+
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
 }
 
 type DecoratorControllerHooks struct {
-	Customize *Hook `json:"customize,omitempty"`
-	Sync      *Hook `json:"sync,omitempty"`
-	Finalize  *Hook `json:"finalize,omitempty"`
+	Customize  *Hook `json:"customize,omitempty"`
+	Sync       *Hook `json:"sync,omitempty"`
+	Finalize   *Hook `json:"finalize,omitempty"`
+	Events     *Hook `json:"events,omitempty"`
+	ApplyError *Hook `json:"applyError,omitempty"`
 }
 
-type DecoratorControllerStatus struct{}
+type DecoratorControllerStatus struct {
+	Conditions         []metav1.Condition    `json:"conditions,omitempty"`
+	ObservedGeneration int64                 `json:"observedGeneration,omitempty"`
+	ManagedWebhook     *ManagedWebhookStatus `json:"managedWebhook,omitempty"`
+
+	// Targets is the number of targets matched by the controller.
+	Targets int32 `json:"targets,omitempty"`
+	// FailingTargets is the number of targets whose last sync failed.
+	FailingTargets int32 `json:"failingTargets,omitempty"`
+	// LastErrors are the most recent errors of the failing targets, newest
+	// first.
+	LastErrors []SyncErrorSummary `json:"lastErrors,omitempty"`
+}
 
 // DecoratorControllerList
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -225,9 +736,321 @@ type DecoratorControllerList struct {
 	Items           []DecoratorController `json:"items"`
 }
 
+// GlobalController
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=globalcontrollers,scope=Cluster,shortName=gctl
+type GlobalController struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   GlobalControllerSpec   `json:"spec"`
+	Status GlobalControllerStatus `json:"status,omitempty"`
+}
+
+type GlobalControllerSpec struct {
+	Attachments []GlobalControllerAttachmentRule `json:"attachments,omitempty"`
+	Related     []GlobalControllerRelatedRule    `json:"related,omitempty"`
+
+	Hooks *GlobalControllerHooks `json:"hooks,omitempty"`
+
+	ResyncPeriodSeconds *int32 `json:"resyncPeriodSeconds,omitempty"`
+
+	DependsOn []ControllerDependency `json:"dependsOn,omitempty"`
+
+	RateLimit *HookRateLimit `json:"rateLimit,omitempty"`
+
+	HookTransport *HookTransport `json:"hookTransport,omitempty"`
+
+	ApplyStrategy ChildApplyStrategy `json:"applyStrategy,omitempty"`
+	FieldManager  string             `json:"fieldManager,omitempty"`
+	DryRun        *bool              `json:"dryRun,omitempty"`
+}
+
+type GlobalControllerAttachmentRule struct {
+	ResourceRule   `json:",inline"`
+	UpdateStrategy *GlobalControllerAttachmentUpdateStrategy `json:"updateStrategy,omitempty"`
+	ApplyStrategy  ChildApplyStrategy                        `json:"applyStrategy,omitempty"`
+}
+
+type GlobalControllerAttachmentUpdateStrategy struct {
+	Method ChildUpdateMethod `json:"method,omitempty"`
+}
+
+type GlobalControllerRelatedRule struct {
+	ResourceRule  `json:",inline"`
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+	Namespace     string                `json:"namespace,omitempty"`
+}
+
+type GlobalControllerHooks struct {
+	Sync *Hook `json:"sync,omitempty"`
+}
+
+type GlobalControllerStatus struct {
+	Conditions         []metav1.Condition `json:"conditions,omitempty"`
+	ObservedGeneration int64              `json:"observedGeneration,omitempty"`
+}
+
+// GlobalControllerList
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type GlobalControllerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []GlobalController `json:"items"`
+}
+
+// CronController
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=croncontrollers,scope=Cluster,shortName=cronctl
+type CronController struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   CronControllerSpec   `json:"spec"`
+	Status CronControllerStatus `json:"status,omitempty"`
+}
+
+type CronControllerSpec struct {
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+	Suspend  *bool  `json:"suspend,omitempty"`
+
+	ParentResource *CronControllerParentResourceRule `json:"parentResource,omitempty"`
+	ChildResources []CronControllerChildResourceRule `json:"childResources,omitempty"`
+
+	Hooks *CronControllerHooks `json:"hooks,omitempty"`
+
+	DependsOn []ControllerDependency `json:"dependsOn,omitempty"`
+
+	RateLimit *HookRateLimit `json:"rateLimit,omitempty"`
+
+	HookTransport *HookTransport `json:"hookTransport,omitempty"`
+
+	ApplyStrategy ChildApplyStrategy `json:"applyStrategy,omitempty"`
+	FieldManager  string             `json:"fieldManager,omitempty"`
+	DryRun        *bool              `json:"dryRun,omitempty"`
+}
+
+type CronControllerParentResourceRule struct {
+	ResourceRule  `json:",inline"`
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+}
+
+type CronControllerChildResourceRule struct {
+	ResourceRule   `json:",inline"`
+	UpdateStrategy *CronControllerChildUpdateStrategy `json:"updateStrategy,omitempty"`
+	ApplyStrategy  ChildApplyStrategy                 `json:"applyStrategy,omitempty"`
+}
+
+type CronControllerChildUpdateStrategy struct {
+	Method ChildUpdateMethod `json:"method,omitempty"`
+}
+
+type CronControllerHooks struct {
+	Sync *Hook `json:"sync,omitempty"`
+}
+
+type CronControllerStatus struct {
+	Conditions         []metav1.Condition `json:"conditions,omitempty"`
+	ObservedGeneration int64              `json:"observedGeneration,omitempty"`
+}
+
+// CronControllerList
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type CronControllerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []CronController `json:"items"`
+}
+
+// ExternalResourceController
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=externalresourcecontrollers,scope=Cluster,shortName=extctl
+type ExternalResourceController struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   ExternalResourceControllerSpec   `json:"spec"`
+	Status ExternalResourceControllerStatus `json:"status,omitempty"`
+}
+
+type ExternalResourceControllerSpec struct {
+	ParentResource ExternalResourceControllerParentResourceRule `json:"parentResource"`
+
+	Hooks *ExternalResourceControllerHooks `json:"hooks,omitempty"`
+
+	ResyncPeriodSeconds *int32 `json:"resyncPeriodSeconds,omitempty"`
+
+	DependsOn []ControllerDependency `json:"dependsOn,omitempty"`
+
+	RateLimit *HookRateLimit `json:"rateLimit,omitempty"`
+
+	HookTransport *HookTransport `json:"hookTransport,omitempty"`
+}
+
+type ExternalResourceControllerParentResourceRule struct {
+	ResourceRule  `json:",inline"`
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+}
+
+type ExternalResourceControllerHooks struct {
+	Observe *Hook `json:"observe,omitempty"`
+	Diff    *Hook `json:"diff,omitempty"`
+	Create  *Hook `json:"create,omitempty"`
+	Update  *Hook `json:"update,omitempty"`
+	Delete  *Hook `json:"delete,omitempty"`
+}
+
+type ExternalResourceControllerStatus struct {
+	Conditions         []metav1.Condition `json:"conditions,omitempty"`
+	ObservedGeneration int64              `json:"observedGeneration,omitempty"`
+}
+
+// ExternalResourceControllerList
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type ExternalResourceControllerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []ExternalResourceController `json:"items"`
+}
+
+// StateMachineController
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=statemachinecontrollers,scope=Cluster,shortName=smctl
+type StateMachineController struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   StateMachineControllerSpec   `json:"spec"`
+	Status StateMachineControllerStatus `json:"status,omitempty"`
+}
+
+type StateMachineControllerSpec struct {
+	ParentResource StateMachineControllerParentResourceRule  `json:"parentResource"`
+	ChildResources []StateMachineControllerChildResourceRule `json:"childResources,omitempty"`
+
+	// +kubebuilder:validation:MinLength=1
+	InitialState string              `json:"initialState"`
+	States       []StateMachineState `json:"states"`
+
+	ResyncPeriodSeconds *int32 `json:"resyncPeriodSeconds,omitempty"`
+
+	DependsOn []ControllerDependency `json:"dependsOn,omitempty"`
+
+	RateLimit *HookRateLimit `json:"rateLimit,omitempty"`
+
+	HookTransport *HookTransport `json:"hookTransport,omitempty"`
+
+	ApplyStrategy ChildApplyStrategy `json:"applyStrategy,omitempty"`
+	FieldManager  string             `json:"fieldManager,omitempty"`
+	DryRun        *bool              `json:"dryRun,omitempty"`
+}
+
+type StateMachineControllerParentResourceRule struct {
+	ResourceRule  `json:",inline"`
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+	StateField    string                `json:"stateField,omitempty"`
+}
+
+type StateMachineControllerChildResourceRule struct {
+	ResourceRule   `json:",inline"`
+	UpdateStrategy *StateMachineControllerChildUpdateStrategy `json:"updateStrategy,omitempty"`
+	ApplyStrategy  ChildApplyStrategy                         `json:"applyStrategy,omitempty"`
+}
+
+type StateMachineControllerChildUpdateStrategy struct {
+	Method ChildUpdateMethod `json:"method,omitempty"`
+}
+
+type StateMachineState struct {
+	// +kubebuilder:validation:MinLength=1
+	Name        string                   `json:"name"`
+	Hook        *Hook                    `json:"hook,omitempty"`
+	Transitions []StateMachineTransition `json:"transitions,omitempty"`
+}
+
+type StateMachineTransition struct {
+	// +kubebuilder:validation:MinLength=1
+	To string `json:"to"`
+	// +kubebuilder:validation:MinLength=1
+	Condition string `json:"condition"`
+}
+
+type StateMachineControllerStatus struct {
+	Conditions         []metav1.Condition `json:"conditions,omitempty"`
+	ObservedGeneration int64              `json:"observedGeneration,omitempty"`
+}
+
+// StateMachineControllerList
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type StateMachineControllerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []StateMachineController `json:"items"`
+}
+
+// ControllerGroup holds the webhook settings shared by the controllers which
+// reference it.
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:path=controllergroups,scope=Cluster,shortName=cg
+type ControllerGroup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec ControllerGroupSpec `json:"spec"`
+}
+
+type ControllerGroupSpec struct {
+	Webhook *ControllerGroupWebhook `json:"webhook,omitempty"`
+
+	RateLimit *HookRateLimit `json:"rateLimit,omitempty"`
+
+	HookTransport *HookTransport `json:"hookTransport,omitempty"`
+}
+
+// ControllerGroupWebhook holds the defaults of the webhooks of the controllers
+// in a group. A webhook which only has a path is called at the base URL (or
+// the service) of the group.
+type ControllerGroupWebhook struct {
+	BaseURL *string           `json:"baseURL,omitempty"`
+	Service *ServiceReference `json:"service,omitempty"`
+
+	Timeout             *metav1.Duration `json:"timeout,omitempty"`
+	ConnectTimeout      *metav1.Duration `json:"connectTimeout,omitempty"`
+	TLSHandshakeTimeout *metav1.Duration `json:"tlsHandshakeTimeout,omitempty"`
+
+	Proxy    *string `json:"proxy,omitempty"`
+	CABundle []byte  `json:"caBundle,omitempty"`
+
+	Signing *WebhookSigning `json:"signing,omitempty"`
+
+	Compression *WebhookCompression `json:"compression,omitempty"`
+}
+
+// ControllerGroupList
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type ControllerGroupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []ControllerGroup `json:"items"`
+}
+
+// ControllerGroupReference references the ControllerGroup a controller
+// belongs to.
+type ControllerGroupReference struct {
+	Name string `json:"name"`
+}
+
 type RelatedResourceRule struct {
 	ResourceRule          `json:",inline"`
 	*metav1.LabelSelector `json:"labelSelector"`
 	Namespace             string   `json:"namespace,omitempty"`
 	Names                 []string `json:"names"`
+
+	FieldSelector     string                `json:"fieldSelector,omitempty"`
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
 }
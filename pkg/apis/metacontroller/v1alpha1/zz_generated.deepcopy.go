@@ -20,8 +20,10 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	intstr "k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -41,6 +43,13 @@ func (in *AnnotationSelector) DeepCopyInto(out *AnnotationSelector) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.MatchPatterns != nil {
+		in, out := &in.MatchPatterns, &out.MatchPatterns
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -54,6 +63,102 @@ func (in *AnnotationSelector) DeepCopy() *AnnotationSelector {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChildConflictPolicy) DeepCopyInto(out *ChildConflictPolicy) {
+	*out = *in
+	if in.SurrenderPaths != nil {
+		in, out := &in.SurrenderPaths, &out.SurrenderPaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChildConflictPolicy.
+func (in *ChildConflictPolicy) DeepCopy() *ChildConflictPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ChildConflictPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChildCrossNamespacePolicy) DeepCopyInto(out *ChildCrossNamespacePolicy) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChildCrossNamespacePolicy.
+func (in *ChildCrossNamespacePolicy) DeepCopy() *ChildCrossNamespacePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ChildCrossNamespacePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChildKindLimit) DeepCopyInto(out *ChildKindLimit) {
+	*out = *in
+	out.ResourceRule = in.ResourceRule
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChildKindLimit.
+func (in *ChildKindLimit) DeepCopy() *ChildKindLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(ChildKindLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChildTemplate) DeepCopyInto(out *ChildTemplate) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChildTemplate.
+func (in *ChildTemplate) DeepCopy() *ChildTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(ChildTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChildUpdateDiff) DeepCopyInto(out *ChildUpdateDiff) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChildUpdateDiff.
+func (in *ChildUpdateDiff) DeepCopy() *ChildUpdateDiff {
+	if in == nil {
+		return nil
+	}
+	out := new(ChildUpdateDiff)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ChildUpdateStatusChecks) DeepCopyInto(out *ChildUpdateStatusChecks) {
 	*out = *in
@@ -77,13 +182,60 @@ func (in *ChildUpdateStatusChecks) DeepCopy() *ChildUpdateStatusChecks {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClientRateBudget) DeepCopyInto(out *ClientRateBudget) {
+	*out = *in
+	if in.Burst != nil {
+		in, out := &in.Burst, &out.Burst
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClientRateBudget.
+func (in *ClientRateBudget) DeepCopy() *ClientRateBudget {
+	if in == nil {
+		return nil
+	}
+	out := new(ClientRateBudget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClientRateLimit) DeepCopyInto(out *ClientRateLimit) {
+	*out = *in
+	if in.Burst != nil {
+		in, out := &in.Burst, &out.Burst
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Status != nil {
+		in, out := &in.Status, &out.Status
+		*out = new(ClientRateBudget)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClientRateLimit.
+func (in *ClientRateLimit) DeepCopy() *ClientRateLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(ClientRateLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CompositeController) DeepCopyInto(out *CompositeController) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -114,6 +266,41 @@ func (in *CompositeControllerChildResourceRule) DeepCopyInto(out *CompositeContr
 		*out = new(CompositeControllerChildUpdateStrategy)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ConflictPolicy != nil {
+		in, out := &in.ConflictPolicy, &out.ConflictPolicy
+		*out = new(ChildConflictPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IgnorePaths != nil {
+		in, out := &in.IgnorePaths, &out.IgnorePaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CrossNamespace != nil {
+		in, out := &in.CrossNamespace, &out.CrossNamespace
+		*out = new(ChildCrossNamespacePolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClusterScoped != nil {
+		in, out := &in.ClusterScoped, &out.ClusterScoped
+		*out = new(bool)
+		**out = **in
+	}
+	if in.WatchSelector != nil {
+		in, out := &in.WatchSelector, &out.WatchSelector
+		*out = new(WatchSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FinalizeWave != nil {
+		in, out := &in.FinalizeWave, &out.FinalizeWave
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ResyncPeriodSeconds != nil {
+		in, out := &in.ResyncPeriodSeconds, &out.ResyncPeriodSeconds
+		*out = new(int32)
+		**out = **in
+	}
 	return
 }
 
@@ -131,6 +318,16 @@ func (in *CompositeControllerChildResourceRule) DeepCopy() *CompositeControllerC
 func (in *CompositeControllerChildUpdateStrategy) DeepCopyInto(out *CompositeControllerChildUpdateStrategy) {
 	*out = *in
 	in.StatusChecks.DeepCopyInto(&out.StatusChecks)
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.Partition != nil {
+		in, out := &in.Partition, &out.Partition
+		*out = new(int32)
+		**out = **in
+	}
 	return
 }
 
@@ -162,6 +359,16 @@ func (in *CompositeControllerHooks) DeepCopyInto(out *CompositeControllerHooks)
 		*out = new(Hook)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Events != nil {
+		in, out := &in.Events, &out.Events
+		*out = new(Hook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ApplyError != nil {
+		in, out := &in.ApplyError, &out.ApplyError
+		*out = new(Hook)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.PreUpdateChild != nil {
 		in, out := &in.PreUpdateChild, &out.PreUpdateChild
 		*out = new(Hook)
@@ -222,11 +429,26 @@ func (in *CompositeControllerList) DeepCopyObject() runtime.Object {
 func (in *CompositeControllerParentResourceRule) DeepCopyInto(out *CompositeControllerParentResourceRule) {
 	*out = *in
 	out.ResourceRule = in.ResourceRule
+	if in.LabelSelector != nil {
+		in, out := &in.LabelSelector, &out.LabelSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AnnotationSelector != nil {
+		in, out := &in.AnnotationSelector, &out.AnnotationSelector
+		*out = new(AnnotationSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.RevisionHistory != nil {
 		in, out := &in.RevisionHistory, &out.RevisionHistory
 		*out = new(CompositeControllerRevisionHistory)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.WatchSelector != nil {
+		in, out := &in.WatchSelector, &out.WatchSelector
+		*out = new(WatchSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -272,6 +494,21 @@ func (in *CompositeControllerSpec) DeepCopyInto(out *CompositeControllerSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ChildTemplates != nil {
+		in, out := &in.ChildTemplates, &out.ChildTemplates
+		*out = make([]ChildTemplate, len(*in))
+		copy(*out, *in)
+	}
+	if in.UpdateStrategy != nil {
+		in, out := &in.UpdateStrategy, &out.UpdateStrategy
+		*out = new(CompositeControllerUpdateStrategy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.KindOrder != nil {
+		in, out := &in.KindOrder, &out.KindOrder
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.Hooks != nil {
 		in, out := &in.Hooks, &out.Hooks
 		*out = new(CompositeControllerHooks)
@@ -282,11 +519,101 @@ func (in *CompositeControllerSpec) DeepCopyInto(out *CompositeControllerSpec) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.ChildEventDebounce != nil {
+		in, out := &in.ChildEventDebounce, &out.ChildEventDebounce
+		*out = new(v1.Duration)
+		**out = **in
+	}
 	if in.GenerateSelector != nil {
 		in, out := &in.GenerateSelector, &out.GenerateSelector
 		*out = new(bool)
 		**out = **in
 	}
+	if in.ConsistentReads != nil {
+		in, out := &in.ConsistentReads, &out.ConsistentReads
+		*out = new(bool)
+		**out = **in
+	}
+	if in.DeltaSync != nil {
+		in, out := &in.DeltaSync, &out.DeltaSync
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Paused != nil {
+		in, out := &in.Paused, &out.Paused
+		*out = new(bool)
+		**out = **in
+	}
+	if in.UpdateDiff != nil {
+		in, out := &in.UpdateDiff, &out.UpdateDiff
+		*out = new(ChildUpdateDiff)
+		**out = **in
+	}
+	if in.ValidateChildren != nil {
+		in, out := &in.ValidateChildren, &out.ValidateChildren
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ChildConcurrency != nil {
+		in, out := &in.ChildConcurrency, &out.ChildConcurrency
+		*out = new(int32)
+		**out = **in
+	}
+	if in.DryRun != nil {
+		in, out := &in.DryRun, &out.DryRun
+		*out = new(bool)
+		**out = **in
+	}
+	if in.DependsOn != nil {
+		in, out := &in.DependsOn, &out.DependsOn
+		*out = make([]ControllerDependency, len(*in))
+		copy(*out, *in)
+	}
+	if in.Workers != nil {
+		in, out := &in.Workers, &out.Workers
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RateLimit != nil {
+		in, out := &in.RateLimit, &out.RateLimit
+		*out = new(HookRateLimit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClientRateLimit != nil {
+		in, out := &in.ClientRateLimit, &out.ClientRateLimit
+		*out = new(ClientRateLimit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Limits != nil {
+		in, out := &in.Limits, &out.Limits
+		*out = new(ControllerLimits)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DerivedFields != nil {
+		in, out := &in.DerivedFields, &out.DerivedFields
+		*out = make([]DerivedField, len(*in))
+		copy(*out, *in)
+	}
+	if in.StatusConventions != nil {
+		in, out := &in.StatusConventions, &out.StatusConventions
+		*out = new(StatusConventions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HookTransport != nil {
+		in, out := &in.HookTransport, &out.HookTransport
+		*out = new(HookTransport)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ControllerGroupRef != nil {
+		in, out := &in.ControllerGroupRef, &out.ControllerGroupRef
+		*out = new(ControllerGroupReference)
+		**out = **in
+	}
+	if in.ManagedWebhook != nil {
+		in, out := &in.ManagedWebhook, &out.ManagedWebhook
+		*out = new(ManagedWebhook)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -303,6 +630,25 @@ func (in *CompositeControllerSpec) DeepCopy() *CompositeControllerSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CompositeControllerStatus) DeepCopyInto(out *CompositeControllerStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ManagedWebhook != nil {
+		in, out := &in.ManagedWebhook, &out.ManagedWebhook
+		*out = new(ManagedWebhookStatus)
+		**out = **in
+	}
+	if in.LastErrors != nil {
+		in, out := &in.LastErrors, &out.LastErrors
+		*out = make([]SyncErrorSummary, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -317,62 +663,309 @@ func (in *CompositeControllerStatus) DeepCopy() *CompositeControllerStatus {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ControllerRevision) DeepCopyInto(out *ControllerRevision) {
+func (in *CompositeControllerUpdateStrategy) DeepCopyInto(out *CompositeControllerUpdateStrategy) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.ParentPatch.DeepCopyInto(&out.ParentPatch)
-	if in.Children != nil {
-		in, out := &in.Children, &out.Children
-		*out = make([]ControllerRevisionChildren, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.RevisionHistoryLimit != nil {
+		in, out := &in.RevisionHistoryLimit, &out.RevisionHistoryLimit
+		*out = new(int32)
+		**out = **in
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllerRevision.
-func (in *ControllerRevision) DeepCopy() *ControllerRevision {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CompositeControllerUpdateStrategy.
+func (in *CompositeControllerUpdateStrategy) DeepCopy() *CompositeControllerUpdateStrategy {
 	if in == nil {
 		return nil
 	}
-	out := new(ControllerRevision)
+	out := new(CompositeControllerUpdateStrategy)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ControllerRevision) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapKeyReference) DeepCopyInto(out *ConfigMapKeyReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapKeyReference.
+func (in *ConfigMapKeyReference) DeepCopy() *ConfigMapKeyReference {
+	if in == nil {
+		return nil
 	}
-	return nil
+	out := new(ConfigMapKeyReference)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ControllerRevisionChildren) DeepCopyInto(out *ControllerRevisionChildren) {
+func (in *ControllerDependency) DeepCopyInto(out *ControllerDependency) {
 	*out = *in
-	if in.Names != nil {
-		in, out := &in.Names, &out.Names
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllerRevisionChildren.
-func (in *ControllerRevisionChildren) DeepCopy() *ControllerRevisionChildren {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllerDependency.
+func (in *ControllerDependency) DeepCopy() *ControllerDependency {
 	if in == nil {
 		return nil
 	}
-	out := new(ControllerRevisionChildren)
+	out := new(ControllerDependency)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ControllerRevisionList) DeepCopyInto(out *ControllerRevisionList) {
+func (in *ControllerGroup) DeepCopyInto(out *ControllerGroup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllerGroup.
+func (in *ControllerGroup) DeepCopy() *ControllerGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(ControllerGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ControllerGroup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControllerGroupList) DeepCopyInto(out *ControllerGroupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ControllerGroup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllerGroupList.
+func (in *ControllerGroupList) DeepCopy() *ControllerGroupList {
+	if in == nil {
+		return nil
+	}
+	out := new(ControllerGroupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ControllerGroupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControllerGroupReference) DeepCopyInto(out *ControllerGroupReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllerGroupReference.
+func (in *ControllerGroupReference) DeepCopy() *ControllerGroupReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ControllerGroupReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControllerGroupSpec) DeepCopyInto(out *ControllerGroupSpec) {
+	*out = *in
+	if in.Webhook != nil {
+		in, out := &in.Webhook, &out.Webhook
+		*out = new(ControllerGroupWebhook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RateLimit != nil {
+		in, out := &in.RateLimit, &out.RateLimit
+		*out = new(HookRateLimit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HookTransport != nil {
+		in, out := &in.HookTransport, &out.HookTransport
+		*out = new(HookTransport)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllerGroupSpec.
+func (in *ControllerGroupSpec) DeepCopy() *ControllerGroupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ControllerGroupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControllerGroupWebhook) DeepCopyInto(out *ControllerGroupWebhook) {
+	*out = *in
+	if in.BaseURL != nil {
+		in, out := &in.BaseURL, &out.BaseURL
+		*out = new(string)
+		**out = **in
+	}
+	if in.Service != nil {
+		in, out := &in.Service, &out.Service
+		*out = new(ServiceReference)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.ConnectTimeout != nil {
+		in, out := &in.ConnectTimeout, &out.ConnectTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.TLSHandshakeTimeout != nil {
+		in, out := &in.TLSHandshakeTimeout, &out.TLSHandshakeTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(string)
+		**out = **in
+	}
+	if in.CABundle != nil {
+		in, out := &in.CABundle, &out.CABundle
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
+	if in.Signing != nil {
+		in, out := &in.Signing, &out.Signing
+		*out = new(WebhookSigning)
+		**out = **in
+	}
+	if in.Compression != nil {
+		in, out := &in.Compression, &out.Compression
+		*out = new(WebhookCompression)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllerGroupWebhook.
+func (in *ControllerGroupWebhook) DeepCopy() *ControllerGroupWebhook {
+	if in == nil {
+		return nil
+	}
+	out := new(ControllerGroupWebhook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControllerLimits) DeepCopyInto(out *ControllerLimits) {
+	*out = *in
+	if in.MaxChildrenPerParent != nil {
+		in, out := &in.MaxChildrenPerParent, &out.MaxChildrenPerParent
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxChildrenPerKind != nil {
+		in, out := &in.MaxChildrenPerKind, &out.MaxChildrenPerKind
+		*out = make([]ChildKindLimit, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllerLimits.
+func (in *ControllerLimits) DeepCopy() *ControllerLimits {
+	if in == nil {
+		return nil
+	}
+	out := new(ControllerLimits)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControllerRevision) DeepCopyInto(out *ControllerRevision) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.ParentPatch.DeepCopyInto(&out.ParentPatch)
+	if in.Children != nil {
+		in, out := &in.Children, &out.Children
+		*out = make([]ControllerRevisionChildren, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllerRevision.
+func (in *ControllerRevision) DeepCopy() *ControllerRevision {
+	if in == nil {
+		return nil
+	}
+	out := new(ControllerRevision)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ControllerRevision) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControllerRevisionChildren) DeepCopyInto(out *ControllerRevisionChildren) {
+	*out = *in
+	if in.Names != nil {
+		in, out := &in.Names, &out.Names
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllerRevisionChildren.
+func (in *ControllerRevisionChildren) DeepCopy() *ControllerRevisionChildren {
+	if in == nil {
+		return nil
+	}
+	out := new(ControllerRevisionChildren)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControllerRevisionList) DeepCopyInto(out *ControllerRevisionList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
@@ -386,46 +979,1438 @@ func (in *ControllerRevisionList) DeepCopyInto(out *ControllerRevisionList) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllerRevisionList.
-func (in *ControllerRevisionList) DeepCopy() *ControllerRevisionList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllerRevisionList.
+func (in *ControllerRevisionList) DeepCopy() *ControllerRevisionList {
+	if in == nil {
+		return nil
+	}
+	out := new(ControllerRevisionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ControllerRevisionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CronController) DeepCopyInto(out *CronController) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CronController.
+func (in *CronController) DeepCopy() *CronController {
+	if in == nil {
+		return nil
+	}
+	out := new(CronController)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CronController) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CronControllerChildResourceRule) DeepCopyInto(out *CronControllerChildResourceRule) {
+	*out = *in
+	out.ResourceRule = in.ResourceRule
+	if in.UpdateStrategy != nil {
+		in, out := &in.UpdateStrategy, &out.UpdateStrategy
+		*out = new(CronControllerChildUpdateStrategy)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CronControllerChildResourceRule.
+func (in *CronControllerChildResourceRule) DeepCopy() *CronControllerChildResourceRule {
+	if in == nil {
+		return nil
+	}
+	out := new(CronControllerChildResourceRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CronControllerChildUpdateStrategy) DeepCopyInto(out *CronControllerChildUpdateStrategy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CronControllerChildUpdateStrategy.
+func (in *CronControllerChildUpdateStrategy) DeepCopy() *CronControllerChildUpdateStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(CronControllerChildUpdateStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CronControllerHooks) DeepCopyInto(out *CronControllerHooks) {
+	*out = *in
+	if in.Sync != nil {
+		in, out := &in.Sync, &out.Sync
+		*out = new(Hook)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CronControllerHooks.
+func (in *CronControllerHooks) DeepCopy() *CronControllerHooks {
+	if in == nil {
+		return nil
+	}
+	out := new(CronControllerHooks)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CronControllerList) DeepCopyInto(out *CronControllerList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]CronController, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CronControllerList.
+func (in *CronControllerList) DeepCopy() *CronControllerList {
+	if in == nil {
+		return nil
+	}
+	out := new(CronControllerList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CronControllerList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CronControllerParentResourceRule) DeepCopyInto(out *CronControllerParentResourceRule) {
+	*out = *in
+	out.ResourceRule = in.ResourceRule
+	if in.LabelSelector != nil {
+		in, out := &in.LabelSelector, &out.LabelSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CronControllerParentResourceRule.
+func (in *CronControllerParentResourceRule) DeepCopy() *CronControllerParentResourceRule {
+	if in == nil {
+		return nil
+	}
+	out := new(CronControllerParentResourceRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CronControllerSpec) DeepCopyInto(out *CronControllerSpec) {
+	*out = *in
+	if in.Suspend != nil {
+		in, out := &in.Suspend, &out.Suspend
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ParentResource != nil {
+		in, out := &in.ParentResource, &out.ParentResource
+		*out = new(CronControllerParentResourceRule)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ChildResources != nil {
+		in, out := &in.ChildResources, &out.ChildResources
+		*out = make([]CronControllerChildResourceRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Hooks != nil {
+		in, out := &in.Hooks, &out.Hooks
+		*out = new(CronControllerHooks)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DependsOn != nil {
+		in, out := &in.DependsOn, &out.DependsOn
+		*out = make([]ControllerDependency, len(*in))
+		copy(*out, *in)
+	}
+	if in.RateLimit != nil {
+		in, out := &in.RateLimit, &out.RateLimit
+		*out = new(HookRateLimit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HookTransport != nil {
+		in, out := &in.HookTransport, &out.HookTransport
+		*out = new(HookTransport)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DryRun != nil {
+		in, out := &in.DryRun, &out.DryRun
+		*out = new(bool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CronControllerSpec.
+func (in *CronControllerSpec) DeepCopy() *CronControllerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CronControllerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CronControllerStatus) DeepCopyInto(out *CronControllerStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CronControllerStatus.
+func (in *CronControllerStatus) DeepCopy() *CronControllerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CronControllerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DecoratorController) DeepCopyInto(out *DecoratorController) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DecoratorController.
+func (in *DecoratorController) DeepCopy() *DecoratorController {
+	if in == nil {
+		return nil
+	}
+	out := new(DecoratorController)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DecoratorController) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DecoratorControllerAttachmentRule) DeepCopyInto(out *DecoratorControllerAttachmentRule) {
+	*out = *in
+	out.ResourceRule = in.ResourceRule
+	if in.UpdateStrategy != nil {
+		in, out := &in.UpdateStrategy, &out.UpdateStrategy
+		*out = new(DecoratorControllerAttachmentUpdateStrategy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ConflictPolicy != nil {
+		in, out := &in.ConflictPolicy, &out.ConflictPolicy
+		*out = new(ChildConflictPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IgnorePaths != nil {
+		in, out := &in.IgnorePaths, &out.IgnorePaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ClusterScoped != nil {
+		in, out := &in.ClusterScoped, &out.ClusterScoped
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Shared != nil {
+		in, out := &in.Shared, &out.Shared
+		*out = new(bool)
+		**out = **in
+	}
+	if in.WatchSelector != nil {
+		in, out := &in.WatchSelector, &out.WatchSelector
+		*out = new(WatchSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DecoratorControllerAttachmentRule.
+func (in *DecoratorControllerAttachmentRule) DeepCopy() *DecoratorControllerAttachmentRule {
+	if in == nil {
+		return nil
+	}
+	out := new(DecoratorControllerAttachmentRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DecoratorControllerAttachmentUpdateStrategy) DeepCopyInto(out *DecoratorControllerAttachmentUpdateStrategy) {
+	*out = *in
+	in.StatusChecks.DeepCopyInto(&out.StatusChecks)
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DecoratorControllerAttachmentUpdateStrategy.
+func (in *DecoratorControllerAttachmentUpdateStrategy) DeepCopy() *DecoratorControllerAttachmentUpdateStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(DecoratorControllerAttachmentUpdateStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DecoratorControllerHooks) DeepCopyInto(out *DecoratorControllerHooks) {
+	*out = *in
+	if in.Customize != nil {
+		in, out := &in.Customize, &out.Customize
+		*out = new(Hook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Sync != nil {
+		in, out := &in.Sync, &out.Sync
+		*out = new(Hook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Finalize != nil {
+		in, out := &in.Finalize, &out.Finalize
+		*out = new(Hook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Events != nil {
+		in, out := &in.Events, &out.Events
+		*out = new(Hook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ApplyError != nil {
+		in, out := &in.ApplyError, &out.ApplyError
+		*out = new(Hook)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DecoratorControllerHooks.
+func (in *DecoratorControllerHooks) DeepCopy() *DecoratorControllerHooks {
+	if in == nil {
+		return nil
+	}
+	out := new(DecoratorControllerHooks)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DecoratorControllerList) DeepCopyInto(out *DecoratorControllerList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]DecoratorController, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DecoratorControllerList.
+func (in *DecoratorControllerList) DeepCopy() *DecoratorControllerList {
+	if in == nil {
+		return nil
+	}
+	out := new(DecoratorControllerList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DecoratorControllerList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DecoratorControllerResourceRule) DeepCopyInto(out *DecoratorControllerResourceRule) {
+	*out = *in
+	out.ResourceRule = in.ResourceRule
+	if in.LabelSelector != nil {
+		in, out := &in.LabelSelector, &out.LabelSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AnnotationSelector != nil {
+		in, out := &in.AnnotationSelector, &out.AnnotationSelector
+		*out = new(AnnotationSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeNamespaces != nil {
+		in, out := &in.ExcludeNamespaces, &out.ExcludeNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.WatchSelector != nil {
+		in, out := &in.WatchSelector, &out.WatchSelector
+		*out = new(WatchSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DecoratorControllerResourceRule.
+func (in *DecoratorControllerResourceRule) DeepCopy() *DecoratorControllerResourceRule {
+	if in == nil {
+		return nil
+	}
+	out := new(DecoratorControllerResourceRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DecoratorControllerSpec) DeepCopyInto(out *DecoratorControllerSpec) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]DecoratorControllerResourceRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Attachments != nil {
+		in, out := &in.Attachments, &out.Attachments
+		*out = make([]DecoratorControllerAttachmentRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExcludeNamespaces != nil {
+		in, out := &in.ExcludeNamespaces, &out.ExcludeNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeObjectsMatching != nil {
+		in, out := &in.ExcludeObjectsMatching, &out.ExcludeObjectsMatching
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Hooks != nil {
+		in, out := &in.Hooks, &out.Hooks
+		*out = new(DecoratorControllerHooks)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ResyncPeriodSeconds != nil {
+		in, out := &in.ResyncPeriodSeconds, &out.ResyncPeriodSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ChildEventDebounce != nil {
+		in, out := &in.ChildEventDebounce, &out.ChildEventDebounce
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Paused != nil {
+		in, out := &in.Paused, &out.Paused
+		*out = new(bool)
+		**out = **in
+	}
+	if in.DependsOn != nil {
+		in, out := &in.DependsOn, &out.DependsOn
+		*out = make([]ControllerDependency, len(*in))
+		copy(*out, *in)
+	}
+	if in.Workers != nil {
+		in, out := &in.Workers, &out.Workers
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RateLimit != nil {
+		in, out := &in.RateLimit, &out.RateLimit
+		*out = new(HookRateLimit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClientRateLimit != nil {
+		in, out := &in.ClientRateLimit, &out.ClientRateLimit
+		*out = new(ClientRateLimit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DerivedFields != nil {
+		in, out := &in.DerivedFields, &out.DerivedFields
+		*out = make([]DerivedField, len(*in))
+		copy(*out, *in)
+	}
+	if in.HookTransport != nil {
+		in, out := &in.HookTransport, &out.HookTransport
+		*out = new(HookTransport)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UpdateDiff != nil {
+		in, out := &in.UpdateDiff, &out.UpdateDiff
+		*out = new(ChildUpdateDiff)
+		**out = **in
+	}
+	if in.ValidateChildren != nil {
+		in, out := &in.ValidateChildren, &out.ValidateChildren
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ChildConcurrency != nil {
+		in, out := &in.ChildConcurrency, &out.ChildConcurrency
+		*out = new(int32)
+		**out = **in
+	}
+	if in.DryRun != nil {
+		in, out := &in.DryRun, &out.DryRun
+		*out = new(bool)
+		**out = **in
+	}
+	if in.TargetPatchPaths != nil {
+		in, out := &in.TargetPatchPaths, &out.TargetPatchPaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SyncBatch != nil {
+		in, out := &in.SyncBatch, &out.SyncBatch
+		*out = new(DecoratorSyncBatch)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ControllerGroupRef != nil {
+		in, out := &in.ControllerGroupRef, &out.ControllerGroupRef
+		*out = new(ControllerGroupReference)
+		**out = **in
+	}
+	if in.ManagedWebhook != nil {
+		in, out := &in.ManagedWebhook, &out.ManagedWebhook
+		*out = new(ManagedWebhook)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DecoratorControllerSpec.
+func (in *DecoratorControllerSpec) DeepCopy() *DecoratorControllerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DecoratorControllerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DecoratorControllerStatus) DeepCopyInto(out *DecoratorControllerStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ManagedWebhook != nil {
+		in, out := &in.ManagedWebhook, &out.ManagedWebhook
+		*out = new(ManagedWebhookStatus)
+		**out = **in
+	}
+	if in.LastErrors != nil {
+		in, out := &in.LastErrors, &out.LastErrors
+		*out = make([]SyncErrorSummary, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DecoratorControllerStatus.
+func (in *DecoratorControllerStatus) DeepCopy() *DecoratorControllerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DecoratorControllerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DecoratorSyncBatch) DeepCopyInto(out *DecoratorSyncBatch) {
+	*out = *in
+	if in.MaxWaitMilliseconds != nil {
+		in, out := &in.MaxWaitMilliseconds, &out.MaxWaitMilliseconds
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DecoratorSyncBatch.
+func (in *DecoratorSyncBatch) DeepCopy() *DecoratorSyncBatch {
+	if in == nil {
+		return nil
+	}
+	out := new(DecoratorSyncBatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DerivedField) DeepCopyInto(out *DerivedField) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DerivedField.
+func (in *DerivedField) DeepCopy() *DerivedField {
+	if in == nil {
+		return nil
+	}
+	out := new(DerivedField)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExecHook) DeepCopyInto(out *ExecHook) {
+	*out = *in
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Socket != nil {
+		in, out := &in.Socket, &out.Socket
+		*out = new(string)
+		**out = **in
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExecHook.
+func (in *ExecHook) DeepCopy() *ExecHook {
+	if in == nil {
+		return nil
+	}
+	out := new(ExecHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalResourceController) DeepCopyInto(out *ExternalResourceController) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalResourceController.
+func (in *ExternalResourceController) DeepCopy() *ExternalResourceController {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalResourceController)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ExternalResourceController) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalResourceControllerHooks) DeepCopyInto(out *ExternalResourceControllerHooks) {
+	*out = *in
+	if in.Observe != nil {
+		in, out := &in.Observe, &out.Observe
+		*out = new(Hook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Diff != nil {
+		in, out := &in.Diff, &out.Diff
+		*out = new(Hook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Create != nil {
+		in, out := &in.Create, &out.Create
+		*out = new(Hook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Update != nil {
+		in, out := &in.Update, &out.Update
+		*out = new(Hook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Delete != nil {
+		in, out := &in.Delete, &out.Delete
+		*out = new(Hook)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalResourceControllerHooks.
+func (in *ExternalResourceControllerHooks) DeepCopy() *ExternalResourceControllerHooks {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalResourceControllerHooks)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalResourceControllerList) DeepCopyInto(out *ExternalResourceControllerList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ExternalResourceController, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalResourceControllerList.
+func (in *ExternalResourceControllerList) DeepCopy() *ExternalResourceControllerList {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalResourceControllerList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ExternalResourceControllerList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalResourceControllerParentResourceRule) DeepCopyInto(out *ExternalResourceControllerParentResourceRule) {
+	*out = *in
+	out.ResourceRule = in.ResourceRule
+	if in.LabelSelector != nil {
+		in, out := &in.LabelSelector, &out.LabelSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalResourceControllerParentResourceRule.
+func (in *ExternalResourceControllerParentResourceRule) DeepCopy() *ExternalResourceControllerParentResourceRule {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalResourceControllerParentResourceRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalResourceControllerSpec) DeepCopyInto(out *ExternalResourceControllerSpec) {
+	*out = *in
+	in.ParentResource.DeepCopyInto(&out.ParentResource)
+	if in.Hooks != nil {
+		in, out := &in.Hooks, &out.Hooks
+		*out = new(ExternalResourceControllerHooks)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ResyncPeriodSeconds != nil {
+		in, out := &in.ResyncPeriodSeconds, &out.ResyncPeriodSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.DependsOn != nil {
+		in, out := &in.DependsOn, &out.DependsOn
+		*out = make([]ControllerDependency, len(*in))
+		copy(*out, *in)
+	}
+	if in.RateLimit != nil {
+		in, out := &in.RateLimit, &out.RateLimit
+		*out = new(HookRateLimit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HookTransport != nil {
+		in, out := &in.HookTransport, &out.HookTransport
+		*out = new(HookTransport)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalResourceControllerSpec.
+func (in *ExternalResourceControllerSpec) DeepCopy() *ExternalResourceControllerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalResourceControllerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalResourceControllerStatus) DeepCopyInto(out *ExternalResourceControllerStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalResourceControllerStatus.
+func (in *ExternalResourceControllerStatus) DeepCopy() *ExternalResourceControllerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalResourceControllerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GlobalController) DeepCopyInto(out *GlobalController) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GlobalController.
+func (in *GlobalController) DeepCopy() *GlobalController {
+	if in == nil {
+		return nil
+	}
+	out := new(GlobalController)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GlobalController) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GlobalControllerAttachmentRule) DeepCopyInto(out *GlobalControllerAttachmentRule) {
+	*out = *in
+	out.ResourceRule = in.ResourceRule
+	if in.UpdateStrategy != nil {
+		in, out := &in.UpdateStrategy, &out.UpdateStrategy
+		*out = new(GlobalControllerAttachmentUpdateStrategy)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GlobalControllerAttachmentRule.
+func (in *GlobalControllerAttachmentRule) DeepCopy() *GlobalControllerAttachmentRule {
+	if in == nil {
+		return nil
+	}
+	out := new(GlobalControllerAttachmentRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GlobalControllerAttachmentUpdateStrategy) DeepCopyInto(out *GlobalControllerAttachmentUpdateStrategy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GlobalControllerAttachmentUpdateStrategy.
+func (in *GlobalControllerAttachmentUpdateStrategy) DeepCopy() *GlobalControllerAttachmentUpdateStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(GlobalControllerAttachmentUpdateStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GlobalControllerHooks) DeepCopyInto(out *GlobalControllerHooks) {
+	*out = *in
+	if in.Sync != nil {
+		in, out := &in.Sync, &out.Sync
+		*out = new(Hook)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GlobalControllerHooks.
+func (in *GlobalControllerHooks) DeepCopy() *GlobalControllerHooks {
+	if in == nil {
+		return nil
+	}
+	out := new(GlobalControllerHooks)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GlobalControllerList) DeepCopyInto(out *GlobalControllerList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GlobalController, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GlobalControllerList.
+func (in *GlobalControllerList) DeepCopy() *GlobalControllerList {
+	if in == nil {
+		return nil
+	}
+	out := new(GlobalControllerList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GlobalControllerList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GlobalControllerRelatedRule) DeepCopyInto(out *GlobalControllerRelatedRule) {
+	*out = *in
+	out.ResourceRule = in.ResourceRule
+	if in.LabelSelector != nil {
+		in, out := &in.LabelSelector, &out.LabelSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GlobalControllerRelatedRule.
+func (in *GlobalControllerRelatedRule) DeepCopy() *GlobalControllerRelatedRule {
+	if in == nil {
+		return nil
+	}
+	out := new(GlobalControllerRelatedRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GlobalControllerSpec) DeepCopyInto(out *GlobalControllerSpec) {
+	*out = *in
+	if in.Attachments != nil {
+		in, out := &in.Attachments, &out.Attachments
+		*out = make([]GlobalControllerAttachmentRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Related != nil {
+		in, out := &in.Related, &out.Related
+		*out = make([]GlobalControllerRelatedRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Hooks != nil {
+		in, out := &in.Hooks, &out.Hooks
+		*out = new(GlobalControllerHooks)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ResyncPeriodSeconds != nil {
+		in, out := &in.ResyncPeriodSeconds, &out.ResyncPeriodSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.DependsOn != nil {
+		in, out := &in.DependsOn, &out.DependsOn
+		*out = make([]ControllerDependency, len(*in))
+		copy(*out, *in)
+	}
+	if in.RateLimit != nil {
+		in, out := &in.RateLimit, &out.RateLimit
+		*out = new(HookRateLimit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HookTransport != nil {
+		in, out := &in.HookTransport, &out.HookTransport
+		*out = new(HookTransport)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DryRun != nil {
+		in, out := &in.DryRun, &out.DryRun
+		*out = new(bool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GlobalControllerSpec.
+func (in *GlobalControllerSpec) DeepCopy() *GlobalControllerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GlobalControllerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GlobalControllerStatus) DeepCopyInto(out *GlobalControllerStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GlobalControllerStatus.
+func (in *GlobalControllerStatus) DeepCopy() *GlobalControllerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GlobalControllerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Hook) DeepCopyInto(out *Hook) {
+	*out = *in
+	if in.Webhook != nil {
+		in, out := &in.Webhook, &out.Webhook
+		*out = new(Webhook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Exec != nil {
+		in, out := &in.Exec, &out.Exec
+		*out = new(ExecHook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NATS != nil {
+		in, out := &in.NATS, &out.NATS
+		*out = new(NATSHook)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Version != nil {
+		in, out := &in.Version, &out.Version
+		*out = new(HookVersion)
+		**out = **in
+	}
+	if in.MaxResponseSize != nil {
+		in, out := &in.MaxResponseSize, &out.MaxResponseSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.ResponseSchema != nil {
+		in, out := &in.ResponseSchema, &out.ResponseSchema
+		*out = new(HookResponseSchema)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Hook.
+func (in *Hook) DeepCopy() *Hook {
+	if in == nil {
+		return nil
+	}
+	out := new(Hook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HookRateLimit) DeepCopyInto(out *HookRateLimit) {
+	*out = *in
+	if in.Burst != nil {
+		in, out := &in.Burst, &out.Burst
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HookRateLimit.
+func (in *HookRateLimit) DeepCopy() *HookRateLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(HookRateLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HookResponseSchema) DeepCopyInto(out *HookResponseSchema) {
+	*out = *in
+	if in.Inline != nil {
+		in, out := &in.Inline, &out.Inline
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ConfigMapRef != nil {
+		in, out := &in.ConfigMapRef, &out.ConfigMapRef
+		*out = new(ConfigMapKeyReference)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HookResponseSchema.
+func (in *HookResponseSchema) DeepCopy() *HookResponseSchema {
+	if in == nil {
+		return nil
+	}
+	out := new(HookResponseSchema)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HookTransport) DeepCopyInto(out *HookTransport) {
+	*out = *in
+	if in.MaxIdleConnsPerHost != nil {
+		in, out := &in.MaxIdleConnsPerHost, &out.MaxIdleConnsPerHost
+		*out = new(int32)
+		**out = **in
+	}
+	if in.IdleConnTimeout != nil {
+		in, out := &in.IdleConnTimeout, &out.IdleConnTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.KeepAlive != nil {
+		in, out := &in.KeepAlive, &out.KeepAlive
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.DisableKeepAlives != nil {
+		in, out := &in.DisableKeepAlives, &out.DisableKeepAlives
+		*out = new(bool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HookTransport.
+func (in *HookTransport) DeepCopy() *HookTransport {
+	if in == nil {
+		return nil
+	}
+	out := new(HookTransport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedWebhook) DeepCopyInto(out *ManagedWebhook) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Port != nil {
+		in, out := &in.Port, &out.Port
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedWebhook.
+func (in *ManagedWebhook) DeepCopy() *ManagedWebhook {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedWebhook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedWebhookStatus) DeepCopyInto(out *ManagedWebhookStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedWebhookStatus.
+func (in *ManagedWebhookStatus) DeepCopy() *ManagedWebhookStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedWebhookStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NATSHook) DeepCopyInto(out *NATSHook) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NATSHook.
+func (in *NATSHook) DeepCopy() *NATSHook {
+	if in == nil {
+		return nil
+	}
+	out := new(NATSHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RelatedResourceRule) DeepCopyInto(out *RelatedResourceRule) {
+	*out = *in
+	out.ResourceRule = in.ResourceRule
+	if in.LabelSelector != nil {
+		in, out := &in.LabelSelector, &out.LabelSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Names != nil {
+		in, out := &in.Names, &out.Names
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RelatedResourceRule.
+func (in *RelatedResourceRule) DeepCopy() *RelatedResourceRule {
+	if in == nil {
+		return nil
+	}
+	out := new(RelatedResourceRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceRule) DeepCopyInto(out *ResourceRule) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceRule.
+func (in *ResourceRule) DeepCopy() *ResourceRule {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretKeyReference) DeepCopyInto(out *SecretKeyReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretKeyReference.
+func (in *SecretKeyReference) DeepCopy() *SecretKeyReference {
 	if in == nil {
 		return nil
 	}
-	out := new(ControllerRevisionList)
+	out := new(SecretKeyReference)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ControllerRevisionList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceReference) DeepCopyInto(out *ServiceReference) {
+	*out = *in
+	if in.Port != nil {
+		in, out := &in.Port, &out.Port
+		*out = new(int32)
+		**out = **in
 	}
-	return nil
+	if in.Protocol != nil {
+		in, out := &in.Protocol, &out.Protocol
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceReference.
+func (in *ServiceReference) DeepCopy() *ServiceReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceReference)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DecoratorController) DeepCopyInto(out *DecoratorController) {
+func (in *StateMachineController) DeepCopyInto(out *StateMachineController) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DecoratorController.
-func (in *DecoratorController) DeepCopy() *DecoratorController {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StateMachineController.
+func (in *StateMachineController) DeepCopy() *StateMachineController {
 	if in == nil {
 		return nil
 	}
-	out := new(DecoratorController)
+	out := new(StateMachineController)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *DecoratorController) DeepCopyObject() runtime.Object {
+func (in *StateMachineController) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -433,82 +2418,51 @@ func (in *DecoratorController) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DecoratorControllerAttachmentRule) DeepCopyInto(out *DecoratorControllerAttachmentRule) {
+func (in *StateMachineControllerChildResourceRule) DeepCopyInto(out *StateMachineControllerChildResourceRule) {
 	*out = *in
 	out.ResourceRule = in.ResourceRule
 	if in.UpdateStrategy != nil {
 		in, out := &in.UpdateStrategy, &out.UpdateStrategy
-		*out = new(DecoratorControllerAttachmentUpdateStrategy)
+		*out = new(StateMachineControllerChildUpdateStrategy)
 		**out = **in
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DecoratorControllerAttachmentRule.
-func (in *DecoratorControllerAttachmentRule) DeepCopy() *DecoratorControllerAttachmentRule {
-	if in == nil {
-		return nil
-	}
-	out := new(DecoratorControllerAttachmentRule)
-	in.DeepCopyInto(out)
-	return out
-}
-
-// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DecoratorControllerAttachmentUpdateStrategy) DeepCopyInto(out *DecoratorControllerAttachmentUpdateStrategy) {
-	*out = *in
-	return
-}
-
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DecoratorControllerAttachmentUpdateStrategy.
-func (in *DecoratorControllerAttachmentUpdateStrategy) DeepCopy() *DecoratorControllerAttachmentUpdateStrategy {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StateMachineControllerChildResourceRule.
+func (in *StateMachineControllerChildResourceRule) DeepCopy() *StateMachineControllerChildResourceRule {
 	if in == nil {
 		return nil
 	}
-	out := new(DecoratorControllerAttachmentUpdateStrategy)
+	out := new(StateMachineControllerChildResourceRule)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DecoratorControllerHooks) DeepCopyInto(out *DecoratorControllerHooks) {
+func (in *StateMachineControllerChildUpdateStrategy) DeepCopyInto(out *StateMachineControllerChildUpdateStrategy) {
 	*out = *in
-	if in.Customize != nil {
-		in, out := &in.Customize, &out.Customize
-		*out = new(Hook)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.Sync != nil {
-		in, out := &in.Sync, &out.Sync
-		*out = new(Hook)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.Finalize != nil {
-		in, out := &in.Finalize, &out.Finalize
-		*out = new(Hook)
-		(*in).DeepCopyInto(*out)
-	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DecoratorControllerHooks.
-func (in *DecoratorControllerHooks) DeepCopy() *DecoratorControllerHooks {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StateMachineControllerChildUpdateStrategy.
+func (in *StateMachineControllerChildUpdateStrategy) DeepCopy() *StateMachineControllerChildUpdateStrategy {
 	if in == nil {
 		return nil
 	}
-	out := new(DecoratorControllerHooks)
+	out := new(StateMachineControllerChildUpdateStrategy)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DecoratorControllerList) DeepCopyInto(out *DecoratorControllerList) {
+func (in *StateMachineControllerList) DeepCopyInto(out *StateMachineControllerList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]DecoratorController, len(*in))
+		*out = make([]StateMachineController, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -516,18 +2470,18 @@ func (in *DecoratorControllerList) DeepCopyInto(out *DecoratorControllerList) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DecoratorControllerList.
-func (in *DecoratorControllerList) DeepCopy() *DecoratorControllerList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StateMachineControllerList.
+func (in *StateMachineControllerList) DeepCopy() *StateMachineControllerList {
 	if in == nil {
 		return nil
 	}
-	out := new(DecoratorControllerList)
+	out := new(StateMachineControllerList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *DecoratorControllerList) DeepCopyObject() runtime.Object {
+func (in *StateMachineControllerList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -535,7 +2489,7 @@ func (in *DecoratorControllerList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DecoratorControllerResourceRule) DeepCopyInto(out *DecoratorControllerResourceRule) {
+func (in *StateMachineControllerParentResourceRule) DeepCopyInto(out *StateMachineControllerParentResourceRule) {
 	*out = *in
 	out.ResourceRule = in.ResourceRule
 	if in.LabelSelector != nil {
@@ -543,192 +2497,221 @@ func (in *DecoratorControllerResourceRule) DeepCopyInto(out *DecoratorController
 		*out = new(v1.LabelSelector)
 		(*in).DeepCopyInto(*out)
 	}
-	if in.AnnotationSelector != nil {
-		in, out := &in.AnnotationSelector, &out.AnnotationSelector
-		*out = new(AnnotationSelector)
-		(*in).DeepCopyInto(*out)
-	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DecoratorControllerResourceRule.
-func (in *DecoratorControllerResourceRule) DeepCopy() *DecoratorControllerResourceRule {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StateMachineControllerParentResourceRule.
+func (in *StateMachineControllerParentResourceRule) DeepCopy() *StateMachineControllerParentResourceRule {
 	if in == nil {
 		return nil
 	}
-	out := new(DecoratorControllerResourceRule)
+	out := new(StateMachineControllerParentResourceRule)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DecoratorControllerSpec) DeepCopyInto(out *DecoratorControllerSpec) {
+func (in *StateMachineControllerSpec) DeepCopyInto(out *StateMachineControllerSpec) {
 	*out = *in
-	if in.Resources != nil {
-		in, out := &in.Resources, &out.Resources
-		*out = make([]DecoratorControllerResourceRule, len(*in))
+	in.ParentResource.DeepCopyInto(&out.ParentResource)
+	if in.ChildResources != nil {
+		in, out := &in.ChildResources, &out.ChildResources
+		*out = make([]StateMachineControllerChildResourceRule, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	if in.Attachments != nil {
-		in, out := &in.Attachments, &out.Attachments
-		*out = make([]DecoratorControllerAttachmentRule, len(*in))
+	if in.States != nil {
+		in, out := &in.States, &out.States
+		*out = make([]StateMachineState, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	if in.Hooks != nil {
-		in, out := &in.Hooks, &out.Hooks
-		*out = new(DecoratorControllerHooks)
-		(*in).DeepCopyInto(*out)
-	}
 	if in.ResyncPeriodSeconds != nil {
 		in, out := &in.ResyncPeriodSeconds, &out.ResyncPeriodSeconds
 		*out = new(int32)
 		**out = **in
 	}
+	if in.DependsOn != nil {
+		in, out := &in.DependsOn, &out.DependsOn
+		*out = make([]ControllerDependency, len(*in))
+		copy(*out, *in)
+	}
+	if in.RateLimit != nil {
+		in, out := &in.RateLimit, &out.RateLimit
+		*out = new(HookRateLimit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HookTransport != nil {
+		in, out := &in.HookTransport, &out.HookTransport
+		*out = new(HookTransport)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DryRun != nil {
+		in, out := &in.DryRun, &out.DryRun
+		*out = new(bool)
+		**out = **in
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DecoratorControllerSpec.
-func (in *DecoratorControllerSpec) DeepCopy() *DecoratorControllerSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StateMachineControllerSpec.
+func (in *StateMachineControllerSpec) DeepCopy() *StateMachineControllerSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(DecoratorControllerSpec)
+	out := new(StateMachineControllerSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DecoratorControllerStatus) DeepCopyInto(out *DecoratorControllerStatus) {
+func (in *StateMachineControllerStatus) DeepCopyInto(out *StateMachineControllerStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DecoratorControllerStatus.
-func (in *DecoratorControllerStatus) DeepCopy() *DecoratorControllerStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StateMachineControllerStatus.
+func (in *StateMachineControllerStatus) DeepCopy() *StateMachineControllerStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(DecoratorControllerStatus)
+	out := new(StateMachineControllerStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Hook) DeepCopyInto(out *Hook) {
+func (in *StateMachineState) DeepCopyInto(out *StateMachineState) {
 	*out = *in
-	if in.Webhook != nil {
-		in, out := &in.Webhook, &out.Webhook
-		*out = new(Webhook)
+	if in.Hook != nil {
+		in, out := &in.Hook, &out.Hook
+		*out = new(Hook)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Transitions != nil {
+		in, out := &in.Transitions, &out.Transitions
+		*out = make([]StateMachineTransition, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Hook.
-func (in *Hook) DeepCopy() *Hook {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StateMachineState.
+func (in *StateMachineState) DeepCopy() *StateMachineState {
 	if in == nil {
 		return nil
 	}
-	out := new(Hook)
+	out := new(StateMachineState)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RelatedResourceRule) DeepCopyInto(out *RelatedResourceRule) {
+func (in *StateMachineTransition) DeepCopyInto(out *StateMachineTransition) {
 	*out = *in
-	out.ResourceRule = in.ResourceRule
-	if in.LabelSelector != nil {
-		in, out := &in.LabelSelector, &out.LabelSelector
-		*out = new(v1.LabelSelector)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.Names != nil {
-		in, out := &in.Names, &out.Names
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RelatedResourceRule.
-func (in *RelatedResourceRule) DeepCopy() *RelatedResourceRule {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StateMachineTransition.
+func (in *StateMachineTransition) DeepCopy() *StateMachineTransition {
 	if in == nil {
 		return nil
 	}
-	out := new(RelatedResourceRule)
+	out := new(StateMachineTransition)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ResourceRule) DeepCopyInto(out *ResourceRule) {
+func (in *StatusConditionCheck) DeepCopyInto(out *StatusConditionCheck) {
 	*out = *in
+	if in.Status != nil {
+		in, out := &in.Status, &out.Status
+		*out = new(string)
+		**out = **in
+	}
+	if in.Reason != nil {
+		in, out := &in.Reason, &out.Reason
+		*out = new(string)
+		**out = **in
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceRule.
-func (in *ResourceRule) DeepCopy() *ResourceRule {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StatusConditionCheck.
+func (in *StatusConditionCheck) DeepCopy() *StatusConditionCheck {
 	if in == nil {
 		return nil
 	}
-	out := new(ResourceRule)
+	out := new(StatusConditionCheck)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ServiceReference) DeepCopyInto(out *ServiceReference) {
+func (in *StatusConventions) DeepCopyInto(out *StatusConventions) {
 	*out = *in
-	if in.Port != nil {
-		in, out := &in.Port, &out.Port
-		*out = new(int32)
-		**out = **in
-	}
-	if in.Protocol != nil {
-		in, out := &in.Protocol, &out.Protocol
-		*out = new(string)
+	if in.SyncStatus != nil {
+		in, out := &in.SyncStatus, &out.SyncStatus
+		*out = new(bool)
 		**out = **in
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceReference.
-func (in *ServiceReference) DeepCopy() *ServiceReference {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StatusConventions.
+func (in *StatusConventions) DeepCopy() *StatusConventions {
 	if in == nil {
 		return nil
 	}
-	out := new(ServiceReference)
+	out := new(StatusConventions)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *StatusConditionCheck) DeepCopyInto(out *StatusConditionCheck) {
+func (in *SyncErrorSummary) DeepCopyInto(out *SyncErrorSummary) {
 	*out = *in
-	if in.Status != nil {
-		in, out := &in.Status, &out.Status
-		*out = new(string)
-		**out = **in
+	in.Since.DeepCopyInto(&out.Since)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SyncErrorSummary.
+func (in *SyncErrorSummary) DeepCopy() *SyncErrorSummary {
+	if in == nil {
+		return nil
 	}
-	if in.Reason != nil {
-		in, out := &in.Reason, &out.Reason
-		*out = new(string)
-		**out = **in
+	out := new(SyncErrorSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WatchSelector) DeepCopyInto(out *WatchSelector) {
+	*out = *in
+	if in.LabelSelector != nil {
+		in, out := &in.LabelSelector, &out.LabelSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StatusConditionCheck.
-func (in *StatusConditionCheck) DeepCopy() *StatusConditionCheck {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WatchSelector.
+func (in *WatchSelector) DeepCopy() *WatchSelector {
 	if in == nil {
 		return nil
 	}
-	out := new(StatusConditionCheck)
+	out := new(WatchSelector)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -746,6 +2729,31 @@ func (in *Webhook) DeepCopyInto(out *Webhook) {
 		*out = new(v1.Duration)
 		**out = **in
 	}
+	if in.ShadowURL != nil {
+		in, out := &in.ShadowURL, &out.ShadowURL
+		*out = new(string)
+		**out = **in
+	}
+	if in.ConnectTimeout != nil {
+		in, out := &in.ConnectTimeout, &out.ConnectTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.TLSHandshakeTimeout != nil {
+		in, out := &in.TLSHandshakeTimeout, &out.TLSHandshakeTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(string)
+		**out = **in
+	}
+	if in.CABundle != nil {
+		in, out := &in.CABundle, &out.CABundle
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
 	if in.Path != nil {
 		in, out := &in.Path, &out.Path
 		*out = new(string)
@@ -756,6 +2764,21 @@ func (in *Webhook) DeepCopyInto(out *Webhook) {
 		*out = new(ServiceReference)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Signing != nil {
+		in, out := &in.Signing, &out.Signing
+		*out = new(WebhookSigning)
+		**out = **in
+	}
+	if in.Compression != nil {
+		in, out := &in.Compression, &out.Compression
+		*out = new(WebhookCompression)
+		**out = **in
+	}
+	if in.HealthPath != nil {
+		in, out := &in.HealthPath, &out.HealthPath
+		*out = new(string)
+		**out = **in
+	}
 	return
 }
 
@@ -768,3 +2791,20 @@ func (in *Webhook) DeepCopy() *Webhook {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookSigning) DeepCopyInto(out *WebhookSigning) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookSigning.
+func (in *WebhookSigning) DeepCopy() *WebhookSigning {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookSigning)
+	in.DeepCopyInto(out)
+	return out
+}
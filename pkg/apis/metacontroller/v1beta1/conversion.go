@@ -0,0 +1,241 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"encoding/json"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// The fields which are the same in both versions are converted through JSON,
+// and the ones which moved are converted explicitly.
+
+// ConvertTo converts cc to the v1alpha1 hub version.
+func (cc *CompositeController) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.CompositeController)
+	dst.ObjectMeta = cc.ObjectMeta
+	if err := convertJSON(&cc.Spec, &dst.Spec); err != nil {
+		return err
+	}
+	if err := convertJSON(&cc.Status, &dst.Status); err != nil {
+		return err
+	}
+
+	if selector := cc.Spec.ParentResource.Selector; selector != nil {
+		if err := convertJSON(selector.Labels, &dst.Spec.ParentResource.LabelSelector); err != nil {
+			return err
+		}
+		if err := convertJSON(selector.Annotations, &dst.Spec.ParentResource.AnnotationSelector); err != nil {
+			return err
+		}
+	}
+	if apply := cc.Spec.Apply; apply != nil {
+		dst.Spec.ApplyStrategy = v1alpha1.ChildApplyStrategy(apply.Strategy)
+		dst.Spec.FieldManager = apply.FieldManager
+	}
+	for i := range cc.Spec.ChildResources {
+		child := &dst.Spec.ChildResources[i]
+		err := convertChildApplyPolicyTo(cc.Spec.ChildResources[i].Apply, &child.ApplyStrategy, &child.ConflictPolicy, &child.IgnorePaths)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConvertFrom converts the v1alpha1 hub version to cc.
+func (cc *CompositeController) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha1.CompositeController)
+	cc.ObjectMeta = src.ObjectMeta
+	if err := convertJSON(&src.Spec, &cc.Spec); err != nil {
+		return err
+	}
+	if err := convertJSON(&src.Status, &cc.Status); err != nil {
+		return err
+	}
+
+	parent := &src.Spec.ParentResource
+	if parent.LabelSelector != nil || parent.AnnotationSelector != nil {
+		cc.Spec.ParentResource.Selector = &ObjectSelector{}
+		if err := convertJSON(parent.LabelSelector, &cc.Spec.ParentResource.Selector.Labels); err != nil {
+			return err
+		}
+		if err := convertJSON(parent.AnnotationSelector, &cc.Spec.ParentResource.Selector.Annotations); err != nil {
+			return err
+		}
+	}
+	if src.Spec.ApplyStrategy != "" || src.Spec.FieldManager != "" {
+		cc.Spec.Apply = &ApplyPolicy{
+			Strategy:     ChildApplyStrategy(src.Spec.ApplyStrategy),
+			FieldManager: src.Spec.FieldManager,
+		}
+	}
+	for i := range src.Spec.ChildResources {
+		child := &src.Spec.ChildResources[i]
+		apply, err := convertChildApplyPolicyFrom(child.ApplyStrategy, child.ConflictPolicy, child.IgnorePaths)
+		if err != nil {
+			return err
+		}
+		cc.Spec.ChildResources[i].Apply = apply
+	}
+	return nil
+}
+
+// ConvertTo converts dc to the v1alpha1 hub version.
+func (dc *DecoratorController) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.DecoratorController)
+	dst.ObjectMeta = dc.ObjectMeta
+	if err := convertJSON(&dc.Spec, &dst.Spec); err != nil {
+		return err
+	}
+	if err := convertJSON(&dc.Status, &dst.Status); err != nil {
+		return err
+	}
+
+	if exclude := dc.Spec.Exclude; exclude != nil {
+		dst.Spec.ExcludeNamespaces = exclude.Namespaces
+		dst.Spec.ExcludeObjectsMatching = exclude.Objects
+	}
+	if apply := dc.Spec.Apply; apply != nil {
+		dst.Spec.ApplyStrategy = v1alpha1.ChildApplyStrategy(apply.Strategy)
+		dst.Spec.FieldManager = apply.FieldManager
+	}
+	for i := range dc.Spec.Resources {
+		selector := dc.Spec.Resources[i].Selector
+		if selector == nil {
+			continue
+		}
+		resource := &dst.Spec.Resources[i]
+		resource.LabelSelector = selector.Labels
+		if err := convertJSON(selector.Annotations, &resource.AnnotationSelector); err != nil {
+			return err
+		}
+		if namespaces := selector.Namespaces; namespaces != nil {
+			resource.Namespaces = namespaces.Names
+			resource.ExcludeNamespaces = namespaces.Exclude
+			resource.NamespaceSelector = namespaces.Labels
+		}
+		resource.FilterExpression = selector.Expression
+	}
+	for i := range dc.Spec.Attachments {
+		attachment := &dst.Spec.Attachments[i]
+		err := convertChildApplyPolicyTo(dc.Spec.Attachments[i].Apply, &attachment.ApplyStrategy, &attachment.ConflictPolicy, &attachment.IgnorePaths)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ConvertFrom converts the v1alpha1 hub version to dc.
+func (dc *DecoratorController) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha1.DecoratorController)
+	dc.ObjectMeta = src.ObjectMeta
+	if err := convertJSON(&src.Spec, &dc.Spec); err != nil {
+		return err
+	}
+	if err := convertJSON(&src.Status, &dc.Status); err != nil {
+		return err
+	}
+
+	if src.Spec.ExcludeNamespaces != nil || src.Spec.ExcludeObjectsMatching != nil {
+		dc.Spec.Exclude = &DecoratorControllerExclusions{
+			Namespaces: src.Spec.ExcludeNamespaces,
+			Objects:    src.Spec.ExcludeObjectsMatching,
+		}
+	}
+	if src.Spec.ApplyStrategy != "" || src.Spec.FieldManager != "" {
+		dc.Spec.Apply = &ApplyPolicy{
+			Strategy:     ChildApplyStrategy(src.Spec.ApplyStrategy),
+			FieldManager: src.Spec.FieldManager,
+		}
+	}
+	for i := range src.Spec.Resources {
+		resource := &src.Spec.Resources[i]
+		if resource.LabelSelector == nil && resource.AnnotationSelector == nil && resource.NamespaceSelector == nil &&
+			resource.Namespaces == nil && resource.ExcludeNamespaces == nil && resource.FilterExpression == "" {
+			continue
+		}
+		selector := &DecoratorResourceSelector{
+			ObjectSelector: ObjectSelector{Labels: resource.LabelSelector},
+			Expression:     resource.FilterExpression,
+		}
+		if err := convertJSON(resource.AnnotationSelector, &selector.Annotations); err != nil {
+			return err
+		}
+		if resource.NamespaceSelector != nil || resource.Namespaces != nil || resource.ExcludeNamespaces != nil {
+			selector.Namespaces = &NamespaceSelector{
+				Names:   resource.Namespaces,
+				Exclude: resource.ExcludeNamespaces,
+				Labels:  resource.NamespaceSelector,
+			}
+		}
+		dc.Spec.Resources[i].Selector = selector
+	}
+	for i := range src.Spec.Attachments {
+		attachment := &src.Spec.Attachments[i]
+		apply, err := convertChildApplyPolicyFrom(attachment.ApplyStrategy, attachment.ConflictPolicy, attachment.IgnorePaths)
+		if err != nil {
+			return err
+		}
+		dc.Spec.Attachments[i].Apply = apply
+	}
+	return nil
+}
+
+// convertChildApplyPolicyTo sets the v1alpha1 fields of a child resource rule
+// from its apply policy.
+func convertChildApplyPolicyTo(apply *ChildApplyPolicy, strategy *v1alpha1.ChildApplyStrategy, conflictPolicy **v1alpha1.ChildConflictPolicy, ignorePaths *[]string) error {
+	if apply == nil {
+		return nil
+	}
+	*strategy = v1alpha1.ChildApplyStrategy(apply.Strategy)
+	*ignorePaths = apply.IgnorePaths
+	return convertJSON(apply.ConflictPolicy, conflictPolicy)
+}
+
+// convertChildApplyPolicyFrom returns the apply policy of a child resource
+// rule made of its v1alpha1 fields, or nil if none of them is set.
+func convertChildApplyPolicyFrom(strategy v1alpha1.ChildApplyStrategy, conflictPolicy *v1alpha1.ChildConflictPolicy, ignorePaths []string) (*ChildApplyPolicy, error) {
+	if strategy == "" && conflictPolicy == nil && ignorePaths == nil {
+		return nil, nil
+	}
+	apply := &ChildApplyPolicy{
+		Strategy:    ChildApplyStrategy(strategy),
+		IgnorePaths: ignorePaths,
+	}
+	if err := convertJSON(conflictPolicy, &apply.ConflictPolicy); err != nil {
+		return nil, err
+	}
+	return apply, nil
+}
+
+// convertJSON converts src to dst, two types with the same JSON schema.
+// A nil src leaves dst untouched.
+func convertJSON(src, dst interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	if string(data) == "null" {
+		return nil
+	}
+	return json.Unmarshal(data, dst)
+}
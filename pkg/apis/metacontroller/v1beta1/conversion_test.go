@@ -0,0 +1,134 @@
+package v1beta1
+
+import (
+	"math/rand"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/apitesting/fuzzer"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metafuzzer "k8s.io/apimachinery/pkg/apis/meta/fuzzer"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/diff"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+func TestCompositeController_ConvertFrom(t *testing.T) {
+	src := &v1alpha1.CompositeController{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Spec: v1alpha1.CompositeControllerSpec{
+			ParentResource: v1alpha1.CompositeControllerParentResourceRule{
+				ResourceRule:  v1alpha1.ResourceRule{APIVersion: "ctl.example.com/v1", Resource: "foos"},
+				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}},
+			},
+			ChildResources: []v1alpha1.CompositeControllerChildResourceRule{{
+				ResourceRule:  v1alpha1.ResourceRule{APIVersion: "v1", Resource: "configmaps"},
+				ApplyStrategy: v1alpha1.ChildApplyServerSide,
+				IgnorePaths:   []string{"data.generated"},
+			}, {
+				ResourceRule: v1alpha1.ResourceRule{APIVersion: "v1", Resource: "secrets"},
+			}},
+			FieldManager: "foo-controller",
+		},
+	}
+
+	var cc CompositeController
+	if err := cc.ConvertFrom(src); err != nil {
+		t.Fatal(err)
+	}
+
+	if cc.Name != "test" || cc.Spec.ParentResource.Resource != "foos" {
+		t.Errorf("unchanged fields not converted: %+v", cc)
+	}
+	if cc.Spec.ParentResource.Selector == nil || cc.Spec.ParentResource.Selector.Labels.MatchLabels["app"] != "foo" {
+		t.Errorf("expected parent label selector in selector.labels, got %+v", cc.Spec.ParentResource.Selector)
+	}
+	expectedApply := &ChildApplyPolicy{Strategy: "ServerSideApply", IgnorePaths: []string{"data.generated"}}
+	if !apiequality.Semantic.DeepEqual(cc.Spec.ChildResources[0].Apply, expectedApply) {
+		t.Errorf("expected child apply %+v, got %+v", expectedApply, cc.Spec.ChildResources[0].Apply)
+	}
+	if cc.Spec.ChildResources[1].Apply != nil {
+		t.Errorf("expected no apply for child without apply fields, got %+v", cc.Spec.ChildResources[1].Apply)
+	}
+	if cc.Spec.Apply == nil || cc.Spec.Apply.FieldManager != "foo-controller" {
+		t.Errorf("expected field manager in apply, got %+v", cc.Spec.Apply)
+	}
+}
+
+func TestDecoratorController_ConvertFrom(t *testing.T) {
+	src := &v1alpha1.DecoratorController{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Spec: v1alpha1.DecoratorControllerSpec{
+			Resources: []v1alpha1.DecoratorControllerResourceRule{{
+				ResourceRule:       v1alpha1.ResourceRule{APIVersion: "v1", Resource: "services"},
+				Namespaces:         []string{"default"},
+				FilterExpression:   "has(object.spec.ports)",
+				AnnotationSelector: &v1alpha1.AnnotationSelector{MatchAnnotations: map[string]string{"expose": "true"}},
+			}},
+			ExcludeNamespaces: []string{"kube-system"},
+		},
+	}
+
+	var dc DecoratorController
+	if err := dc.ConvertFrom(src); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedSelector := &DecoratorResourceSelector{
+		ObjectSelector: ObjectSelector{Annotations: &AnnotationSelector{MatchAnnotations: map[string]string{"expose": "true"}}},
+		Namespaces:     &NamespaceSelector{Names: []string{"default"}},
+		Expression:     "has(object.spec.ports)",
+	}
+	if !apiequality.Semantic.DeepEqual(dc.Spec.Resources[0].Selector, expectedSelector) {
+		t.Errorf("expected selector %+v, got %+v", expectedSelector, dc.Spec.Resources[0].Selector)
+	}
+	expectedExclude := &DecoratorControllerExclusions{Namespaces: []string{"kube-system"}}
+	if !apiequality.Semantic.DeepEqual(dc.Spec.Exclude, expectedExclude) {
+		t.Errorf("expected exclusions %+v, got %+v", expectedExclude, dc.Spec.Exclude)
+	}
+}
+
+// TestConversionRoundTrip tests that v1alpha1 objects are converted to v1beta1
+// and back without the loss of information.
+func TestConversionRoundTrip(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	codecs := serializer.NewCodecFactory(scheme)
+	f := fuzzer.FuzzerFor(metafuzzer.Funcs, rand.NewSource(1), codecs)
+
+	for i := 0; i < 50; i++ {
+		var cc v1alpha1.CompositeController
+		f.Fuzz(&cc)
+		var converted CompositeController
+		if err := converted.ConvertFrom(&cc); err != nil {
+			t.Fatal(err)
+		}
+		var roundTripped v1alpha1.CompositeController
+		if err := converted.ConvertTo(&roundTripped); err != nil {
+			t.Fatal(err)
+		}
+		roundTripped.TypeMeta = cc.TypeMeta
+		if !apiequality.Semantic.DeepEqual(&cc, &roundTripped) {
+			t.Fatalf("CompositeController changed after round trip: %s", diff.ObjectReflectDiff(&cc, &roundTripped))
+		}
+
+		var dc v1alpha1.DecoratorController
+		f.Fuzz(&dc)
+		var convertedDC DecoratorController
+		if err := convertedDC.ConvertFrom(&dc); err != nil {
+			t.Fatal(err)
+		}
+		var roundTrippedDC v1alpha1.DecoratorController
+		if err := convertedDC.ConvertTo(&roundTrippedDC); err != nil {
+			t.Fatal(err)
+		}
+		roundTrippedDC.TypeMeta = dc.TypeMeta
+		if !apiequality.Semantic.DeepEqual(&dc, &roundTrippedDC) {
+			t.Fatalf("DecoratorController changed after round trip: %s", diff.ObjectReflectDiff(&dc, &roundTrippedDC))
+		}
+	}
+}
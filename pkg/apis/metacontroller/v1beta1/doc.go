@@ -0,0 +1,22 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// +k8s:deepcopy-gen=package,register
+
+// Package v1beta1 is the v1beta1 version of the CompositeController and
+// DecoratorController APIs. Objects are stored as v1alpha1, and converted by
+// the conversion webhook served by metacontroller.
+package v1beta1 // import "metacontroller.io/pkg/apis/metacontroller/v1beta1"
@@ -0,0 +1,631 @@
+/*
+Copyright 2017 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// +groupName=metacontroller.k8s.io
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// CompositeController
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=compositecontrollers,scope=Cluster,shortName=cc;cctl
+// +kubebuilder:unservedversion
+type CompositeController struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   CompositeControllerSpec   `json:"spec"`
+	Status CompositeControllerStatus `json:"status,omitempty"`
+}
+
+type CompositeControllerSpec struct {
+	ParentResource CompositeControllerParentResourceRule  `json:"parentResource"`
+	ChildResources []CompositeControllerChildResourceRule `json:"childResources,omitempty"`
+	ChildTemplates []ChildTemplate                        `json:"childTemplates,omitempty"`
+	UpdateStrategy *CompositeControllerUpdateStrategy     `json:"updateStrategy,omitempty"`
+	KindOrder      []string                               `json:"kindOrder,omitempty"`
+
+	Mode  ControllerMode            `json:"mode,omitempty"`
+	Hooks *CompositeControllerHooks `json:"hooks,omitempty"`
+
+	ResyncPeriodSeconds *int32           `json:"resyncPeriodSeconds,omitempty"`
+	ChildEventDebounce  *metav1.Duration `json:"childEventDebounce,omitempty"`
+	GenerateSelector    *bool            `json:"generateSelector,omitempty"`
+	ConsistentReads     *bool            `json:"consistentReads,omitempty"`
+	DeltaSync           *bool            `json:"deltaSync,omitempty"`
+	Paused              *bool            `json:"paused,omitempty"`
+
+	Apply            *ApplyPolicy     `json:"apply,omitempty"`
+	UpdateDiff       *ChildUpdateDiff `json:"updateDiff,omitempty"`
+	ValidateChildren *bool            `json:"validateChildren,omitempty"`
+	ChildConcurrency *int32           `json:"childConcurrency,omitempty"`
+	DryRun           *bool            `json:"dryRun,omitempty"`
+
+	StatusApplyStrategy StatusApplyStrategy `json:"statusApplyStrategy,omitempty"`
+
+	DeletionPolicy ControllerDeletionPolicy `json:"deletionPolicy,omitempty"`
+	AdoptionPolicy AdoptionPolicy           `json:"adoptionPolicy,omitempty"`
+
+	DependsOn []ControllerDependency `json:"dependsOn,omitempty"`
+
+	Workers         *int32            `json:"workers,omitempty"`
+	RateLimit       *HookRateLimit    `json:"rateLimit,omitempty"`
+	ClientRateLimit *ClientRateLimit  `json:"clientRateLimit,omitempty"`
+	Limits          *ControllerLimits `json:"limits,omitempty"`
+
+	DerivedFields []DerivedField `json:"derivedFields,omitempty"`
+
+	StatusConventions *StatusConventions `json:"statusConventions,omitempty"`
+
+	HookTransport *HookTransport `json:"hookTransport,omitempty"`
+
+	ControllerGroupRef *ControllerGroupReference `json:"controllerGroupRef,omitempty"`
+
+	ManagedWebhook *ManagedWebhook `json:"managedWebhook,omitempty"`
+}
+
+// ApplyPolicy is how a controller writes its children (or attachments) by
+// default. It replaces the applyStrategy and fieldManager fields of v1alpha1.
+type ApplyPolicy struct {
+	Strategy     ChildApplyStrategy `json:"strategy,omitempty"`
+	FieldManager string             `json:"fieldManager,omitempty"`
+}
+
+// ChildApplyPolicy is how children (or attachments) of a kind are written. It
+// replaces the applyStrategy, conflictPolicy and ignorePaths fields of the
+// resource rules of v1alpha1.
+type ChildApplyPolicy struct {
+	Strategy       ChildApplyStrategy   `json:"strategy,omitempty"`
+	ConflictPolicy *ChildConflictPolicy `json:"conflictPolicy,omitempty"`
+	IgnorePaths    []string             `json:"ignorePaths,omitempty"`
+}
+
+// ChildTemplate is a Go template of the manifests of children, rendered
+// against the parent and its derived fields.
+type ChildTemplate struct {
+	Name     string `json:"name"`
+	Template string `json:"template"`
+}
+
+// ControllerMode is whether a controller changes its parents and children.
+// +kubebuilder:validation:Enum=Enforce;ReportOnly
+type ControllerMode string
+
+// ControllerLimits caps the number of children the hooks of a controller may
+// return for a parent, in total and per kind.
+type ControllerLimits struct {
+	// +kubebuilder:validation:Minimum=0
+	MaxChildrenPerParent *int32           `json:"maxChildrenPerParent,omitempty"`
+	MaxChildrenPerKind   []ChildKindLimit `json:"maxChildrenPerKind,omitempty"`
+}
+
+// ChildKindLimit caps the number of children of a kind the hooks of a
+// controller may return for a parent.
+type ChildKindLimit struct {
+	ResourceRule `json:",inline"`
+	// +kubebuilder:validation:Minimum=0
+	MaxChildren int32 `json:"maxChildren"`
+}
+
+// ControllerDeletionPolicy is what happens to the children of a controller's
+// parents when the controller itself is deleted.
+// +kubebuilder:validation:Enum=Orphan;DeleteChildren;Abandon
+type ControllerDeletionPolicy string
+
+// ChildUpdateDiff reports why children are updated, with the diff between
+// each child and what a server-side dry run of its update returns.
+type ChildUpdateDiff struct {
+	Report ChildUpdateDiffReport `json:"report"`
+}
+
+// ChildUpdateDiffReport is where the diffs of child updates are reported.
+// +kubebuilder:validation:Enum=Log;Event
+type ChildUpdateDiffReport string
+
+// StatusApplyStrategy is how the status returned by hooks is written to the
+// parent.
+// +kubebuilder:validation:Enum=Replace;ServerSideApply
+type StatusApplyStrategy string
+
+// AdoptionPolicy is which orphans matching the selector of a parent are
+// adopted as its children.
+// +kubebuilder:validation:Enum=Never;IfMatchingSelector;RequireAnnotation;Review
+type AdoptionPolicy string
+
+// StatusConventions makes Metacontroller merge the conditions returned by
+// hooks into the parent status by type, compute a Ready condition with a
+// CEL expression over the parent and its children, and optionally record the
+// outcome of the last sync in status.sync.
+type StatusConventions struct {
+	ReadyExpression string `json:"readyExpression,omitempty"`
+	SyncStatus      *bool  `json:"syncStatus,omitempty"`
+}
+
+// DerivedField is a value computed with a CEL expression over the parent and
+// its children, and sent to the hooks in the `derived` field of requests.
+type DerivedField struct {
+	// +kubebuilder:validation:MinLength=1
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+}
+
+// HookRateLimit throttles the hook invocations of a controller.
+type HookRateLimit struct {
+	// +kubebuilder:validation:Minimum=1
+	QPS int32 `json:"qps"`
+	// +kubebuilder:validation:Minimum=1
+	Burst *int32 `json:"burst,omitempty"`
+}
+
+// ClientRateLimit gives a controller its own client-side rate limits for its
+// requests to the API server, instead of sharing those of Metacontroller.
+type ClientRateLimit struct {
+	// +kubebuilder:validation:Minimum=1
+	QPS int32 `json:"qps"`
+	// +kubebuilder:validation:Minimum=1
+	Burst *int32 `json:"burst,omitempty"`
+	// Status is the budget of the status updates of parents, separate from
+	// the other requests. If unset, they share the same budget.
+	Status *ClientRateBudget `json:"status,omitempty"`
+}
+
+// ClientRateBudget is a rate limit of client requests.
+type ClientRateBudget struct {
+	// +kubebuilder:validation:Minimum=1
+	QPS int32 `json:"qps"`
+	// +kubebuilder:validation:Minimum=1
+	Burst *int32 `json:"burst,omitempty"`
+}
+
+// HookTransport tunes the HTTP connections used to call the webhooks of a
+// controller.
+type HookTransport struct {
+	// +kubebuilder:validation:Minimum=0
+	MaxIdleConnsPerHost *int32           `json:"maxIdleConnsPerHost,omitempty"`
+	IdleConnTimeout     *metav1.Duration `json:"idleConnTimeout,omitempty"`
+	KeepAlive           *metav1.Duration `json:"keepAlive,omitempty"`
+	DisableKeepAlives   *bool            `json:"disableKeepAlives,omitempty"`
+}
+
+// ControllerDependency references another controller which must be Ready
+// before this controller is started.
+type ControllerDependency struct {
+	// +kubebuilder:validation:Enum=CompositeController;DecoratorController;GlobalController;CronController;ExternalResourceController;StateMachineController
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// ControllerGroupReference references the ControllerGroup a controller
+// belongs to.
+type ControllerGroupReference struct {
+	Name string `json:"name"`
+}
+
+type ResourceRule struct {
+	APIVersion string `json:"apiVersion"`
+	Resource   string `json:"resource"`
+}
+
+// WatchSelector restricts the informer watching the objects of a resource rule
+// to those matching its label and field selectors. Unlike the other selectors,
+// it's evaluated by the API server, so the objects which don't match are
+// never cached by Metacontroller.
+type WatchSelector struct {
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+	FieldSelector string                `json:"fieldSelector,omitempty"`
+}
+
+type CompositeControllerParentResourceRule struct {
+	ResourceRule    `json:",inline"`
+	Selector        *ObjectSelector                     `json:"selector,omitempty"`
+	RevisionHistory *CompositeControllerRevisionHistory `json:"revisionHistory,omitempty"`
+	WatchSelector   *WatchSelector                      `json:"watchSelector,omitempty"`
+}
+
+// ObjectSelector selects objects by their labels and annotations. It replaces
+// the labelSelector and annotationSelector fields of v1alpha1.
+type ObjectSelector struct {
+	Labels      *metav1.LabelSelector `json:"labels,omitempty"`
+	Annotations *AnnotationSelector   `json:"annotations,omitempty"`
+}
+
+type CompositeControllerRevisionHistory struct {
+	FieldPaths []string `json:"fieldPaths,omitempty"`
+}
+
+// CompositeControllerUpdateStrategy configures the rolling updates of a
+// controller as a whole.
+type CompositeControllerUpdateStrategy struct {
+	// +kubebuilder:validation:Minimum=0
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+}
+
+// ChildUpdateMethod is how children are updated when their desired state
+// changes.
+// +kubebuilder:validation:Enum=OnDelete;Recreate;InPlace;RollingRecreate;RollingInPlace;BlueGreen
+type ChildUpdateMethod string
+
+type CompositeControllerChildResourceRule struct {
+	ResourceRule   `json:",inline"`
+	UpdateStrategy *CompositeControllerChildUpdateStrategy `json:"updateStrategy,omitempty"`
+	Apply          *ChildApplyPolicy                       `json:"apply,omitempty"`
+	CrossNamespace *ChildCrossNamespacePolicy              `json:"crossNamespace,omitempty"`
+	ClusterScoped  *bool                                   `json:"clusterScoped,omitempty"`
+	WatchSelector  *WatchSelector                          `json:"watchSelector,omitempty"`
+
+	ApplyWave           int32  `json:"applyWave,omitempty"`
+	ReadinessExpression string `json:"readinessExpression,omitempty"`
+	FinalizeWave        *int32 `json:"finalizeWave,omitempty"`
+
+	ResyncPeriodSeconds *int32 `json:"resyncPeriodSeconds,omitempty"`
+
+	DeletionPolicy ChildDeletionPolicy `json:"deletionPolicy,omitempty"`
+}
+
+// ChildCrossNamespacePolicy allows children of a kind to be placed in
+// namespaces other than the parent's, either listed or matching a selector.
+type ChildCrossNamespacePolicy struct {
+	Namespaces        []string              `json:"namespaces,omitempty"`
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// ChildDeletionPolicy is what happens to children of a kind when their parent
+// is deleted, like the reclaim policy of a PersistentVolume.
+// +kubebuilder:validation:Enum=Delete;Retain
+type ChildDeletionPolicy string
+
+// ChildApplyStrategy is how the desired state of children is written.
+// +kubebuilder:validation:Enum=ThreeWayMerge;ServerSideApply
+type ChildApplyStrategy string
+
+// ChildConflictPolicy is how server-side apply conflicts with other field
+// managers are handled for a kind of children.
+type ChildConflictPolicy struct {
+	Mode           ChildConflictMode `json:"mode,omitempty"`
+	SurrenderPaths []string          `json:"surrenderPaths,omitempty"`
+}
+
+// ChildConflictMode is what to do when an applied field is owned by another
+// field manager.
+// +kubebuilder:validation:Enum=Force;Fail
+type ChildConflictMode string
+
+type CompositeControllerChildUpdateStrategy struct {
+	Method       ChildUpdateMethod       `json:"method,omitempty"`
+	StatusChecks ChildUpdateStatusChecks `json:"statusChecks,omitempty"`
+
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	Partition *int32 `json:"partition,omitempty"`
+}
+
+type ChildUpdateStatusChecks struct {
+	Conditions []StatusConditionCheck `json:"conditions,omitempty"`
+}
+
+type StatusConditionCheck struct {
+	Type   string  `json:"type"`
+	Status *string `json:"status,omitempty"`
+	Reason *string `json:"reason,omitempty"`
+}
+
+// ManagedWebhook is a webhook server which metacontroller deploys for a
+// controller, as a Deployment and a Service.
+type ManagedWebhook struct {
+	// +kubebuilder:validation:MinLength=1
+	Image string `json:"image"`
+	// +kubebuilder:validation:MinLength=1
+	Namespace string `json:"namespace"`
+
+	// +kubebuilder:validation:Minimum=0
+	Replicas *int32 `json:"replicas,omitempty"`
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port *int32 `json:"port,omitempty"`
+
+	Command   []string                     `json:"command,omitempty"`
+	Args      []string                     `json:"args,omitempty"`
+	Env       []corev1.EnvVar              `json:"env,omitempty"`
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+}
+
+// ManagedWebhookStatus is where the managed webhook of a controller runs.
+type ManagedWebhookStatus struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// SyncErrorSummary is the error of the last sync of a parent.
+type SyncErrorSummary struct {
+	Kind      string `json:"kind,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Message   string `json:"message"`
+	// Since is when the parent started failing with this error.
+	Since metav1.Time `json:"since"`
+}
+
+type ServiceReference struct {
+	Name      string  `json:"name"`
+	Namespace string  `json:"namespace"`
+	Port      *int32  `json:"port,omitempty"`
+	Protocol  *string `json:"protocol,omitempty"`
+}
+
+type CompositeControllerHooks struct {
+	Customize  *Hook `json:"customize,omitempty"`
+	Sync       *Hook `json:"sync,omitempty"`
+	Finalize   *Hook `json:"finalize,omitempty"`
+	Events     *Hook `json:"events,omitempty"`
+	ApplyError *Hook `json:"applyError,omitempty"`
+
+	PreUpdateChild  *Hook `json:"preUpdateChild,omitempty"`
+	PostUpdateChild *Hook `json:"postUpdateChild,omitempty"`
+}
+
+type Hook struct {
+	Webhook *Webhook     `json:"webhook,omitempty"`
+	Exec    *ExecHook    `json:"exec,omitempty"`
+	NATS    *NATSHook    `json:"nats,omitempty"`
+	Version *HookVersion `json:"version,omitempty"`
+
+	MaxResponseSize *resource.Quantity  `json:"maxResponseSize,omitempty"`
+	ResponseSchema  *HookResponseSchema `json:"responseSchema,omitempty"`
+}
+
+// HookResponseSchema is a JSON Schema that the responses of a hook must
+// conform to, given either inline or in a ConfigMap.
+type HookResponseSchema struct {
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	Inline       *runtime.RawExtension  `json:"inline,omitempty"`
+	ConfigMapRef *ConfigMapKeyReference `json:"configMapRef,omitempty"`
+}
+
+// HookVersion is the version of the request/response schema used by a hook.
+// +kubebuilder:validation:Enum=v1;v2
+type HookVersion string
+
+type Webhook struct {
+	URL     *string          `json:"url,omitempty"`
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	ShadowURL *string `json:"shadowURL,omitempty"`
+
+	ConnectTimeout      *metav1.Duration `json:"connectTimeout,omitempty"`
+	TLSHandshakeTimeout *metav1.Duration `json:"tlsHandshakeTimeout,omitempty"`
+
+	Proxy    *string `json:"proxy,omitempty"`
+	CABundle []byte  `json:"caBundle,omitempty"`
+
+	Path    *string           `json:"path,omitempty"`
+	Service *ServiceReference `json:"service,omitempty"`
+
+	Signing *WebhookSigning `json:"signing,omitempty"`
+
+	Compression *WebhookCompression `json:"compression,omitempty"`
+
+	HealthPath *string `json:"healthPath,omitempty"`
+}
+
+// WebhookCompression is the content encoding used for webhook request and
+// response bodies.
+// +kubebuilder:validation:Enum=gzip
+type WebhookCompression string
+
+// ExecHook runs a hook locally, either as a command inside the metacontroller
+// container or through a UNIX socket served by a sidecar.
+type ExecHook struct {
+	Command []string         `json:"command,omitempty"`
+	Socket  *string          `json:"socket,omitempty"`
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// NATSHook sends requests to a NATS subject and waits for a reply, so hook
+// implementations can be scaled as a queue group.
+type NATSHook struct {
+	URL     string           `json:"url"`
+	Subject string           `json:"subject"`
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+type WebhookSigning struct {
+	SecretRef SecretKeyReference `json:"secretRef"`
+}
+
+type SecretKeyReference struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Key       string `json:"key"`
+}
+
+type ConfigMapKeyReference struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Key       string `json:"key"`
+}
+
+type CompositeControllerStatus struct {
+	Conditions         []metav1.Condition    `json:"conditions,omitempty"`
+	ObservedGeneration int64                 `json:"observedGeneration,omitempty"`
+	ManagedWebhook     *ManagedWebhookStatus `json:"managedWebhook,omitempty"`
+
+	// Parents is the number of parents matched by the controller.
+	Parents int32 `json:"parents,omitempty"`
+	// FailingParents is the number of parents whose last sync failed.
+	FailingParents int32 `json:"failingParents,omitempty"`
+	// LastErrors are the most recent errors of the failing parents, newest
+	// first.
+	LastErrors []SyncErrorSummary `json:"lastErrors,omitempty"`
+}
+
+// CompositeControllerList
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type CompositeControllerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []CompositeController `json:"items"`
+}
+
+// DecoratorController
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=decoratorcontrollers,scope=Cluster,shortName=dec;decorators
+// +kubebuilder:unservedversion
+type DecoratorController struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   DecoratorControllerSpec   `json:"spec"`
+	Status DecoratorControllerStatus `json:"status,omitempty"`
+}
+
+type DecoratorControllerSpec struct {
+	Resources   []DecoratorControllerResourceRule   `json:"resources"`
+	Attachments []DecoratorControllerAttachmentRule `json:"attachments,omitempty"`
+
+	Exclude *DecoratorControllerExclusions `json:"exclude,omitempty"`
+
+	Hooks *DecoratorControllerHooks `json:"hooks,omitempty"`
+
+	ResyncPeriodSeconds *int32           `json:"resyncPeriodSeconds,omitempty"`
+	ChildEventDebounce  *metav1.Duration `json:"childEventDebounce,omitempty"`
+	Paused              *bool            `json:"paused,omitempty"`
+
+	DependsOn []ControllerDependency `json:"dependsOn,omitempty"`
+
+	Workers         *int32           `json:"workers,omitempty"`
+	RateLimit       *HookRateLimit   `json:"rateLimit,omitempty"`
+	ClientRateLimit *ClientRateLimit `json:"clientRateLimit,omitempty"`
+
+	DerivedFields []DerivedField `json:"derivedFields,omitempty"`
+
+	HookTransport *HookTransport `json:"hookTransport,omitempty"`
+
+	Apply            *ApplyPolicy     `json:"apply,omitempty"`
+	UpdateDiff       *ChildUpdateDiff `json:"updateDiff,omitempty"`
+	ValidateChildren *bool            `json:"validateChildren,omitempty"`
+	ChildConcurrency *int32           `json:"childConcurrency,omitempty"`
+	DryRun           *bool            `json:"dryRun,omitempty"`
+
+	TargetPatchPaths []string `json:"targetPatchPaths,omitempty"`
+
+	SyncBatch *DecoratorSyncBatch `json:"syncBatch,omitempty"`
+
+	ControllerGroupRef *ControllerGroupReference `json:"controllerGroupRef,omitempty"`
+
+	ManagedWebhook *ManagedWebhook `json:"managedWebhook,omitempty"`
+}
+
+// DecoratorControllerExclusions are the targets a controller never decorates,
+// whatever its resource rules. It replaces the excludeNamespaces and
+// excludeObjectsMatching fields of v1alpha1.
+type DecoratorControllerExclusions struct {
+	Namespaces []string              `json:"namespaces,omitempty"`
+	Objects    *metav1.LabelSelector `json:"objects,omitempty"`
+}
+
+// DecoratorSyncBatch lets the sync hook handle several targets per call.
+type DecoratorSyncBatch struct {
+	// +kubebuilder:validation:Minimum=2
+	MaxSize int32 `json:"maxSize"`
+	// +kubebuilder:validation:Minimum=1
+	MaxWaitMilliseconds *int32 `json:"maxWaitMilliseconds,omitempty"`
+}
+
+type DecoratorControllerResourceRule struct {
+	ResourceRule  `json:",inline"`
+	Selector      *DecoratorResourceSelector `json:"selector,omitempty"`
+	WatchSelector *WatchSelector             `json:"watchSelector,omitempty"`
+}
+
+// DecoratorResourceSelector selects the targets of a resource rule. It
+// replaces the labelSelector, annotationSelector, namespaceSelector,
+// namespaces, excludeNamespaces and filterExpression fields of v1alpha1.
+type DecoratorResourceSelector struct {
+	ObjectSelector `json:",inline"`
+	Namespaces     *NamespaceSelector `json:"namespaces,omitempty"`
+	Expression     string             `json:"expression,omitempty"`
+}
+
+// NamespaceSelector selects the namespaces of targets by name or by label.
+type NamespaceSelector struct {
+	Names   []string              `json:"names,omitempty"`
+	Exclude []string              `json:"exclude,omitempty"`
+	Labels  *metav1.LabelSelector `json:"labels,omitempty"`
+}
+
+type AnnotationSelector struct {
+	MatchAnnotations map[string]string                 `json:"matchAnnotations,omitempty"`
+	MatchExpressions []metav1.LabelSelectorRequirement `json:"matchExpressions,omitempty"`
+	MatchPatterns    map[string]string                 `json:"matchPatterns,omitempty"`
+}
+
+type DecoratorControllerAttachmentRule struct {
+	ResourceRule   `json:",inline"`
+	UpdateStrategy *DecoratorControllerAttachmentUpdateStrategy `json:"updateStrategy,omitempty"`
+	Apply          *ChildApplyPolicy                            `json:"apply,omitempty"`
+	ClusterScoped  *bool                                        `json:"clusterScoped,omitempty"`
+	Shared         *bool                                        `json:"shared,omitempty"`
+	WatchSelector  *WatchSelector                               `json:"watchSelector,omitempty"`
+}
+
+type DecoratorControllerAttachmentUpdateStrategy struct {
+	Method       ChildUpdateMethod       `json:"method,omitempty"`
+	StatusChecks ChildUpdateStatusChecks `json:"statusChecks,omitempty"`
+
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+type DecoratorControllerHooks struct {
+	Customize  *Hook `json:"customize,omitempty"`
+	Sync       *Hook `json:"sync,omitempty"`
+	Finalize   *Hook `json:"finalize,omitempty"`
+	Events     *Hook `json:"events,omitempty"`
+	ApplyError *Hook `json:"applyError,omitempty"`
+}
+
+type DecoratorControllerStatus struct {
+	Conditions         []metav1.Condition    `json:"conditions,omitempty"`
+	ObservedGeneration int64                 `json:"observedGeneration,omitempty"`
+	ManagedWebhook     *ManagedWebhookStatus `json:"managedWebhook,omitempty"`
+
+	// Targets is the number of targets matched by the controller.
+	Targets int32 `json:"targets,omitempty"`
+	// FailingTargets is the number of targets whose last sync failed.
+	FailingTargets int32 `json:"failingTargets,omitempty"`
+	// LastErrors are the most recent errors of the failing targets, newest
+	// first.
+	LastErrors []SyncErrorSummary `json:"lastErrors,omitempty"`
+}
+
+// DecoratorControllerList
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type DecoratorControllerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []DecoratorController `json:"items"`
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"metacontroller/pkg/logging"
+)
+
+// Log records the write operations of controllers, once enabled with
+// SetOutput.
+var Log = NewLogger()
+
+// Reference identifies an object.
+type Reference struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+// Controller identifies the controller which made a write.
+type Controller struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Entry is a write operation made by a controller on behalf of a parent, on a
+// child of the parent or on the parent itself.
+type Entry struct {
+	Time       time.Time  `json:"time"`
+	Controller Controller `json:"controller"`
+	Parent     Reference  `json:"parent"`
+	Child      Reference  `json:"child"`
+	// Action is Create, Update, Patch or Delete.
+	Action string `json:"action"`
+	// Subresource is the subresource written, e.g. status, if any.
+	Subresource string `json:"subresource,omitempty"`
+	// Result is either "Succeeded" or "Failed".
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+	// DryRun is whether the operation was only dry-run, so nothing changed.
+	DryRun bool `json:"dryRun,omitempty"`
+	// Diff is the JSON merge patch of an update, possibly truncated.
+	Diff string `json:"diff,omitempty"`
+}
+
+// Actions of entries.
+const (
+	ActionCreate = "Create"
+	ActionUpdate = "Update"
+	ActionPatch  = "Patch"
+	ActionDelete = "Delete"
+)
+
+// Recorder records in Log the writes a controller makes on behalf of a parent,
+// besides the operations on its children reported with their own results.
+type Recorder struct {
+	Controller Controller
+	Parent     Reference
+}
+
+// Record records an operation on child, which may be the parent itself, if
+// Log is enabled. err is the error of the operation, if it failed.
+func (r Recorder) Record(child Reference, action, subresource string, dryRun bool, err error) {
+	if !Log.Enabled() {
+		return
+	}
+	entry := Entry{
+		Controller:  r.Controller,
+		Parent:      r.Parent,
+		Child:       child,
+		Action:      action,
+		Subresource: subresource,
+		Result:      "Succeeded",
+		DryRun:      dryRun,
+	}
+	if err != nil {
+		entry.Result = "Failed"
+		entry.Error = err.Error()
+	}
+	Log.Record(entry)
+}
+
+// ObjectReference returns a reference to obj.
+func ObjectReference(obj *unstructured.Unstructured) Reference {
+	return Reference{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+	}
+}
+
+// Logger writes entries as JSON lines.
+type Logger struct {
+	mutex sync.Mutex
+	out   io.Writer
+
+	now func() time.Time
+}
+
+func NewLogger() *Logger {
+	return &Logger{now: time.Now}
+}
+
+// SetOutput makes the logger write to out, or disables it if out is nil.
+func (l *Logger) SetOutput(out io.Writer) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.out = out
+}
+
+// Enabled returns whether entries are written anywhere.
+func (l *Logger) Enabled() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.out != nil
+}
+
+// Record writes entry, stamped with the current time, if the logger is
+// enabled.
+func (l *Logger) Record(entry Entry) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.out == nil {
+		return
+	}
+	entry.Time = l.now().UTC()
+	line, err := json.Marshal(entry)
+	if err != nil {
+		logging.Logger.Error(err, "Can't marshal audit entry")
+		return
+	}
+	if _, err := l.out.Write(append(line, '\n')); err != nil {
+		logging.Logger.Error(err, "Can't write audit entry")
+	}
+}
+
+// Open opens the file at path for appending entries, or returns stdout if
+// path is "-".
+func Open(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return os.Stdout, nil
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("can't open audit log: %w", err)
+	}
+	return file, nil
+}
@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestLogger(t *testing.T) {
+	logger := NewLogger()
+	now := time.Date(2021, 7, 14, 20, 25, 9, 0, time.UTC)
+	logger.now = func() time.Time { return now }
+	entry := Entry{
+		Controller: Controller{Name: "my-controller", Type: "CompositeController"},
+		Parent:     Reference{APIVersion: "example.com/v1", Kind: "MyParent", Namespace: "default", Name: "parent"},
+		Child:      Reference{APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "child"},
+		Action:     "Update",
+		Result:     "Succeeded",
+		Diff:       `{"data":{"key":"value"}}`,
+	}
+
+	// Entries are dropped until an output is set.
+	logger.Record(entry)
+	if logger.Enabled() {
+		t.Fatal("expected the logger to be disabled")
+	}
+
+	var out bytes.Buffer
+	logger.SetOutput(&out)
+	logger.Record(entry)
+	logger.Record(entry)
+
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %s", len(lines), out.String())
+	}
+	var got Entry
+	if err := json.Unmarshal(lines[0], &got); err != nil {
+		t.Fatalf("can't decode entry: %v", err)
+	}
+	entry.Time = now
+	if got != entry {
+		t.Errorf("expected %+v, got %+v", entry, got)
+	}
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	dynamicapply "metacontroller/pkg/dynamic/apply"
+)
+
+// AdoptAnnotation approves the adoption of an orphan with the
+// RequireAnnotation and Review adoption policies.
+const AdoptAnnotation = "metacontroller.k8s.io/adopt"
+
+// AdoptionPendingCondition is the parent status condition type set while some
+// desired children exist as orphans which aren't approved for adoption.
+const AdoptionPendingCondition = "AdoptionPending"
+
+// PendingAdoption is a desired child which already exists as an orphan, and
+// is left alone until its adoption is approved.
+type PendingAdoption struct {
+	// Child is the existing orphan.
+	Child *unstructured.Unstructured
+	// Drift is the update adopting Child would make, if any.
+	Drift *ChildDrift
+}
+
+// Message describes what adopting the child would do.
+func (p PendingAdoption) Message() string {
+	if p.Child.GetAnnotations()[AdoptAnnotation] == "true" {
+		// Approved orphans are claimed unless they don't match the selector.
+		return fmt.Sprintf("%v is approved for adoption, but doesn't match the parent's selector", describeObject(p.Child))
+	}
+	if p.Drift == nil {
+		return fmt.Sprintf("%v would be adopted without changes", describeObject(p.Child))
+	}
+	return fmt.Sprintf("%v would be adopted and updated: %s", describeObject(p.Child), truncatePatch(p.Drift.Patch))
+}
+
+// FindPendingAdoptions removes from desired the children which aren't
+// observed, but already exist as orphans, and returns them along with the
+// update adopting them would make. get is the same as for FindConflicts.
+func FindPendingAdoptions(updateStrategy ChildUpdateStrategy, applyStrategies *ChildApplyStrategies, parent *unstructured.Unstructured, observed, desired RelativeObjectMap, get func(key GroupVersionKind, namespace, name string) *unstructured.Unstructured) ([]PendingAdoption, error) {
+	var pending []PendingAdoption
+	for key, group := range desired {
+		for name, obj := range group {
+			if observed[key][name] != nil {
+				continue
+			}
+			namespace := obj.GetNamespace()
+			if namespace == "" {
+				namespace = parent.GetNamespace()
+			}
+			existing := get(key, namespace, obj.GetName())
+			if existing == nil || metav1.GetControllerOf(existing) != nil {
+				continue
+			}
+			if _, _, _, found := LabelOwnerOf(existing); found {
+				continue
+			}
+			// An orphan has no last applied config. Give it an empty one, which
+			// diffs leave out, so they only show changes to its content.
+			orphan := existing.DeepCopy()
+			annotations := orphan.GetAnnotations()
+			if annotations == nil {
+				annotations = make(map[string]string, 1)
+			}
+			annotations[dynamicapply.LastAppliedAnnotation] = ""
+			orphan.SetAnnotations(annotations)
+			drifts, err := DiffChildren(updateStrategy, applyStrategies, parent,
+				RelativeObjectMap{key: {name: orphan}},
+				RelativeObjectMap{key: {name: obj}})
+			if err != nil {
+				return nil, err
+			}
+			adoption := PendingAdoption{Child: existing}
+			// Only the last applied config would change with an empty patch.
+			if len(drifts) > 0 && string(drifts[0].Patch) != "{}" {
+				adoption.Drift = &drifts[0]
+			}
+			pending = append(pending, adoption)
+			delete(group, name)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return describeObject(pending[i].Child) < describeObject(pending[j].Child)
+	})
+	return pending, nil
+}
+
+// SetAdoptionPendingCondition sets the AdoptionPending condition in status if
+// some adoptions are pending, and removes it otherwise.
+// It returns the updated status, which is only allocated if needed.
+func SetAdoptionPendingCondition(status map[string]interface{}, pending []PendingAdoption) map[string]interface{} {
+	if len(pending) == 0 {
+		return setCondition(status, AdoptionPendingCondition, nil)
+	}
+	messages := make([]string, 0, len(pending))
+	for _, adoption := range pending {
+		messages = append(messages, adoption.Message())
+	}
+	return setCondition(status, AdoptionPendingCondition, map[string]interface{}{
+		"status":  "True",
+		"reason":  "AwaitingApproval",
+		"message": strings.Join(messages, "; "),
+	})
+}
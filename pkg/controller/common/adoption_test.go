@@ -0,0 +1,101 @@
+package common
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/pointer"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+func TestFindPendingAdoptions(t *testing.T) {
+	parent := &unstructured.Unstructured{}
+	parent.SetKind("App")
+	parent.SetNamespace("ns")
+	parent.SetName("app")
+	parent.SetUID("app-uid")
+
+	newConfigMap := func(name, value string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind("ConfigMap")
+		obj.SetNamespace("ns")
+		obj.SetName(name)
+		_ = unstructured.SetNestedField(obj.Object, value, "data", "key")
+		return obj
+	}
+	owned := newConfigMap("owned", "a")
+	owned.SetOwnerReferences([]metav1.OwnerReference{{Kind: "Other", Name: "other", UID: "other-uid", Controller: pointer.BoolPtr(true)}})
+	approved := newConfigMap("approved", "a")
+	approved.SetAnnotations(map[string]string{AdoptAnnotation: "true"})
+	existing := map[string]*unstructured.Unstructured{
+		"owned":     owned,
+		"unchanged": newConfigMap("unchanged", "a"),
+		"changed":   newConfigMap("changed", "a"),
+		"approved":  approved,
+	}
+
+	observed := MakeRelativeObjectMap(parent, nil)
+	desired := MakeRelativeObjectMap(parent, []*unstructured.Unstructured{
+		newConfigMap("new", "b"), newConfigMap("owned", "b"), newConfigMap("unchanged", "a"),
+		newConfigMap("changed", "b"), newConfigMap("approved", "b"),
+	})
+
+	pending, err := FindPendingAdoptions(fixedUpdateMethod(v1alpha1.ChildUpdateInPlace), NewChildApplyStrategies("", ""), parent, observed, desired, func(key GroupVersionKind, namespace, name string) *unstructured.Unstructured {
+		return existing[name]
+	})
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	var messages []string
+	for _, adoption := range pending {
+		messages = append(messages, adoption.Message())
+	}
+	expected := []string{
+		"ConfigMap ns/approved is approved for adoption, but doesn't match the parent's selector",
+		`ConfigMap ns/changed would be adopted and updated: {"data":{"key":"b"}}`,
+		"ConfigMap ns/unchanged would be adopted without changes",
+	}
+	if diff := cmp.Diff(expected, messages); diff != "" {
+		t.Errorf("unexpected pending adoptions (-want +got):\n%s", diff)
+	}
+	var remaining []string
+	for _, group := range desired {
+		for _, obj := range group {
+			remaining = append(remaining, obj.GetName())
+		}
+	}
+	sort.Strings(remaining)
+	if diff := cmp.Diff([]string{"new", "owned"}, remaining); diff != "" {
+		t.Errorf("unexpected desired children (-want +got):\n%s", diff)
+	}
+}
+
+func TestSetAdoptionPendingCondition(t *testing.T) {
+	child := &unstructured.Unstructured{}
+	child.SetKind("ConfigMap")
+	child.SetNamespace("ns")
+	child.SetName("a")
+
+	status := SetAdoptionPendingCondition(nil, []PendingAdoption{{Child: child}})
+
+	conditions, _, _ := unstructured.NestedSlice(status, "conditions")
+	if len(conditions) != 1 {
+		t.Fatalf("expected 1 condition, got %v", conditions)
+	}
+	condition := conditions[0].(map[string]interface{})
+	if condition["reason"] != "AwaitingApproval" || condition["message"] != "ConfigMap ns/a would be adopted without changes" {
+		t.Errorf("unexpected condition %v", condition)
+	}
+
+	status = SetAdoptionPendingCondition(status, nil)
+
+	if conditions, _, _ := unstructured.NestedSlice(status, "conditions"); len(conditions) != 0 {
+		t.Errorf("expected condition to be removed, got %v", conditions)
+	}
+}
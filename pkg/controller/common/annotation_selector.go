@@ -0,0 +1,112 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"regexp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+// AnnotationSelector matches objects by their annotations. It has the same
+// semantics as a label selector, but annotation values aren't restricted to
+// the syntax of label values, and can also be matched by regular expressions.
+type AnnotationSelector struct {
+	requirements []annotationRequirement
+}
+
+type annotationRequirement struct {
+	key      string
+	operator metav1.LabelSelectorOperator
+	values   sets.String
+	pattern  *regexp.Regexp
+}
+
+// NewAnnotationSelector converts selector to its internal form.
+// A nil selector matches everything.
+func NewAnnotationSelector(selector *v1alpha1.AnnotationSelector) (*AnnotationSelector, error) {
+	as := &AnnotationSelector{}
+	if selector == nil {
+		return as, nil
+	}
+	for key, value := range selector.MatchAnnotations {
+		as.requirements = append(as.requirements, annotationRequirement{
+			key:      key,
+			operator: metav1.LabelSelectorOpIn,
+			values:   sets.NewString(value),
+		})
+	}
+	for _, expression := range selector.MatchExpressions {
+		switch expression.Operator {
+		case metav1.LabelSelectorOpIn, metav1.LabelSelectorOpNotIn:
+			if len(expression.Values) == 0 {
+				return nil, fmt.Errorf("annotation %q: values must be non-empty for operator %q", expression.Key, expression.Operator)
+			}
+		case metav1.LabelSelectorOpExists, metav1.LabelSelectorOpDoesNotExist:
+			if len(expression.Values) != 0 {
+				return nil, fmt.Errorf("annotation %q: values must be empty for operator %q", expression.Key, expression.Operator)
+			}
+		default:
+			return nil, fmt.Errorf("annotation %q: invalid operator %q", expression.Key, expression.Operator)
+		}
+		as.requirements = append(as.requirements, annotationRequirement{
+			key:      expression.Key,
+			operator: expression.Operator,
+			values:   sets.NewString(expression.Values...),
+		})
+	}
+	for key, pattern := range selector.MatchPatterns {
+		// The pattern must match the whole value.
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("annotation %q: can't parse pattern %q: %w", key, pattern, err)
+		}
+		as.requirements = append(as.requirements, annotationRequirement{key: key, pattern: re})
+	}
+	return as, nil
+}
+
+// Matches returns whether annotations satisfy all requirements of the selector.
+func (as *AnnotationSelector) Matches(annotations map[string]string) bool {
+	for _, r := range as.requirements {
+		if !r.matches(annotations) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *annotationRequirement) matches(annotations map[string]string) bool {
+	value, found := annotations[r.key]
+	if r.pattern != nil {
+		return found && r.pattern.MatchString(value)
+	}
+	switch r.operator {
+	case metav1.LabelSelectorOpIn:
+		return found && r.values.Has(value)
+	case metav1.LabelSelectorOpNotIn:
+		return !found || !r.values.Has(value)
+	case metav1.LabelSelectorOpExists:
+		return found
+	default:
+		return !found
+	}
+}
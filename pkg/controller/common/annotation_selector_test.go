@@ -0,0 +1,83 @@
+package common
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+func TestAnnotationSelector_Matches(t *testing.T) {
+	ingressClass := "kubernetes.io/ingress.class"
+	tests := []struct {
+		name        string
+		selector    *v1alpha1.AnnotationSelector
+		annotations map[string]string
+		expected    bool
+	}{
+		{"nil selector", nil, nil, true},
+		{"value which isn't a valid label value", &v1alpha1.AnnotationSelector{
+			MatchAnnotations: map[string]string{"description": "Public ingress, see https://example.com"},
+		}, map[string]string{"description": "Public ingress, see https://example.com"}, true},
+		{"value differs", &v1alpha1.AnnotationSelector{
+			MatchAnnotations: map[string]string{ingressClass: "nginx"},
+		}, map[string]string{ingressClass: "traefik"}, false},
+		{"in", &v1alpha1.AnnotationSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{{Key: ingressClass, Operator: metav1.LabelSelectorOpIn, Values: []string{"nginx", "nginx-internal"}}},
+		}, map[string]string{ingressClass: "nginx-internal"}, true},
+		{"not in missing", &v1alpha1.AnnotationSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{{Key: ingressClass, Operator: metav1.LabelSelectorOpNotIn, Values: []string{"nginx"}}},
+		}, nil, true},
+		{"does not exist", &v1alpha1.AnnotationSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{{Key: ingressClass, Operator: metav1.LabelSelectorOpDoesNotExist}},
+		}, map[string]string{ingressClass: "nginx"}, false},
+		{"pattern matches", &v1alpha1.AnnotationSelector{
+			MatchPatterns: map[string]string{ingressClass: "nginx(-.*)?"},
+		}, map[string]string{ingressClass: "nginx-public"}, true},
+		{"pattern matches only part of the value", &v1alpha1.AnnotationSelector{
+			MatchPatterns: map[string]string{ingressClass: "nginx"},
+		}, map[string]string{ingressClass: "nginx-public"}, false},
+		{"pattern and missing annotation", &v1alpha1.AnnotationSelector{
+			MatchPatterns: map[string]string{ingressClass: ".*"},
+		}, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := NewAnnotationSelector(tt.selector)
+			if err != nil {
+				t.Fatalf("err should be nil, got: %v", err)
+			}
+			if got := selector.Matches(tt.annotations); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNewAnnotationSelector_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector *v1alpha1.AnnotationSelector
+	}{
+		{"in without values", &v1alpha1.AnnotationSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "a", Operator: metav1.LabelSelectorOpIn}},
+		}},
+		{"exists with values", &v1alpha1.AnnotationSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "a", Operator: metav1.LabelSelectorOpExists, Values: []string{"b"}}},
+		}},
+		{"unknown operator", &v1alpha1.AnnotationSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "a", Operator: "Gt", Values: []string{"1"}}},
+		}},
+		{"invalid pattern", &v1alpha1.AnnotationSelector{
+			MatchPatterns: map[string]string{"a": "("},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewAnnotationSelector(tt.selector); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
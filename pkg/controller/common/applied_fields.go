@@ -0,0 +1,210 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// appliedConfiguration returns the fields of obj owned by the server-side
+// applies of fieldManager, with their current values. It's what the last
+// apply set, like the last-applied-configuration annotation of client-side
+// applies, so that the fields removed from the desired state since then are
+// known. It returns nil if fieldManager owns no fields of obj.
+func appliedConfiguration(obj *unstructured.Unstructured, fieldManager string) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	found := false
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Manager != fieldManager || entry.Operation != metav1.ManagedFieldsOperationApply || entry.Subresource != "" || entry.FieldsV1 == nil {
+			continue
+		}
+		entryFields := map[string]interface{}{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &entryFields); err != nil {
+			return nil, fmt.Errorf("can't unmarshal fields of manager %q of %v: %w", entry.Manager, describeObject(obj), err)
+		}
+		unionFields(fields, entryFields)
+		found = true
+	}
+	if !found {
+		return nil, nil
+	}
+	applied, _ := extractFields(fields, obj.UnstructuredContent()).(map[string]interface{})
+	if applied == nil {
+		return nil, nil
+	}
+	// The controllerRef is added to each apply rather than returned by the
+	// hooks, so it's not removed for being missing from the desired state.
+	if controllerRef := metav1.GetControllerOf(obj); controllerRef != nil {
+		removeOwnerReference(applied, controllerRef.UID)
+	}
+	return applied, nil
+}
+
+// extractFields returns the part of value in the given field set, in the
+// FieldsV1 format of managed fields.
+func extractFields(fields map[string]interface{}, value interface{}) interface{} {
+	if !hasChildFields(fields) {
+		// The whole value is owned.
+		return runtime.DeepCopyJSONValue(value)
+	}
+	switch value := value.(type) {
+	case map[string]interface{}:
+		extracted := make(map[string]interface{}, len(fields))
+		for key, childFields := range fields {
+			if !strings.HasPrefix(key, "f:") {
+				continue
+			}
+			name := strings.TrimPrefix(key, "f:")
+			childValue, ok := value[name]
+			if !ok {
+				continue
+			}
+			childFieldSet, _ := childFields.(map[string]interface{})
+			extracted[name] = extractFields(childFieldSet, childValue)
+		}
+		return extracted
+	case []interface{}:
+		var extracted []interface{}
+		for i, item := range value {
+			if itemFields, keys, ok := listItemFields(fields, i, item); ok {
+				extracted = append(extracted, extractListItem(itemFields, keys, item))
+			}
+		}
+		if extracted == nil {
+			return []interface{}{}
+		}
+		return extracted
+	default:
+		return runtime.DeepCopyJSONValue(value)
+	}
+}
+
+// hasChildFields returns whether the field set names fields of the value,
+// rather than owning it as a whole.
+func hasChildFields(fields map[string]interface{}) bool {
+	for key := range fields {
+		if key != "." {
+			return true
+		}
+	}
+	return false
+}
+
+// listItemFields returns the fields of the i-th item of a list in the field
+// set of the list, and the key values which identify the item, if it's in the
+// field set.
+func listItemFields(fields map[string]interface{}, i int, item interface{}) (map[string]interface{}, map[string]interface{}, bool) {
+	for key, value := range fields {
+		itemFields, _ := value.(map[string]interface{})
+		switch {
+		case strings.HasPrefix(key, "k:"):
+			keys := map[string]interface{}{}
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(key, "k:")), &keys); err != nil {
+				continue
+			}
+			if matchesKeys(item, keys) {
+				return itemFields, keys, true
+			}
+		case strings.HasPrefix(key, "v:"):
+			var setValue interface{}
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(key, "v:")), &setValue); err != nil {
+				continue
+			}
+			if reflect.DeepEqual(normalizeJSON(setValue), normalizeJSON(item)) {
+				return itemFields, nil, true
+			}
+		case key == fmt.Sprintf("i:%d", i):
+			return itemFields, nil, true
+		}
+	}
+	return nil, nil, false
+}
+
+// extractListItem returns the part of a list item in the given field set,
+// along with its key values, so it can still be matched to the desired item.
+func extractListItem(fields, keys map[string]interface{}, item interface{}) interface{} {
+	extracted := extractFields(fields, item)
+	obj, ok := item.(map[string]interface{})
+	extractedObj, extractedOK := extracted.(map[string]interface{})
+	if !ok || !extractedOK {
+		return extracted
+	}
+	for key := range keys {
+		if value, found := obj[key]; found {
+			extractedObj[key] = runtime.DeepCopyJSONValue(value)
+		}
+	}
+	return extractedObj
+}
+
+// matchesKeys returns whether the list item has the given key values.
+func matchesKeys(item interface{}, keys map[string]interface{}) bool {
+	obj, ok := item.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	for key, value := range keys {
+		if !reflect.DeepEqual(normalizeJSON(obj[key]), normalizeJSON(value)) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeJSON makes the numbers of a JSON value comparable, whether they
+// were decoded as int64 or float64.
+func normalizeJSON(value interface{}) interface{} {
+	switch value := value.(type) {
+	case int64:
+		return float64(value)
+	case int32:
+		return float64(value)
+	case int:
+		return float64(value)
+	default:
+		return value
+	}
+}
+
+// removeOwnerReference removes the ownerReference with the given UID from the
+// metadata of obj.
+func removeOwnerReference(obj map[string]interface{}, uid types.UID) {
+	ownerRefs, found, _ := unstructured.NestedSlice(obj, "metadata", "ownerReferences")
+	if !found {
+		return
+	}
+	kept := make([]interface{}, 0, len(ownerRefs))
+	for _, ownerRef := range ownerRefs {
+		if ref, ok := ownerRef.(map[string]interface{}); ok && ref["uid"] == string(uid) {
+			continue
+		}
+		kept = append(kept, ownerRef)
+	}
+	if len(kept) == 0 {
+		unstructured.RemoveNestedField(obj, "metadata", "ownerReferences")
+		return
+	}
+	_ = unstructured.SetNestedSlice(obj, kept, "metadata", "ownerReferences")
+}
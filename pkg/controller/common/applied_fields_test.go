@@ -0,0 +1,92 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/pointer"
+)
+
+func appliedChild() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":   "test",
+			"labels": map[string]interface{}{"app": "test", "other": "x"},
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(3),
+			"paused":   true,
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "main", "image": "nginx", "args": []interface{}{"-v"}},
+						map[string]interface{}{"name": "sidecar", "image": "envoy"},
+					},
+				},
+			},
+		},
+	}}
+	obj.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "v1", Kind: "Parent", Name: "parent", UID: "1", Controller: pointer.BoolPtr(true)}})
+	obj.SetManagedFields([]metav1.ManagedFieldsEntry{
+		{
+			Manager:    "metacontroller",
+			Operation:  metav1.ManagedFieldsOperationApply,
+			FieldsType: "FieldsV1",
+			FieldsV1: &metav1.FieldsV1{Raw: []byte(`{
+				"f:metadata": {"f:labels": {"f:app": {}}, "f:ownerReferences": {"k:{\"uid\":\"1\"}": {}}},
+				"f:spec": {
+					"f:paused": {},
+					"f:template": {"f:spec": {"f:containers": {
+						"k:{\"name\":\"main\"}": {".": {}, "f:name": {}, "f:image": {}, "f:args": {}}
+					}}}
+				}
+			}`)},
+		},
+		{
+			Manager:    "kube-controller-manager",
+			Operation:  metav1.ManagedFieldsOperationUpdate,
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:spec": {"f:replicas": {}}}`)},
+		},
+	})
+	return obj
+}
+
+func TestAppliedConfiguration(t *testing.T) {
+	applied, err := appliedConfiguration(appliedChild(), "metacontroller")
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	want := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{"app": "test"},
+		},
+		"spec": map[string]interface{}{
+			"paused": true,
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "main", "image": "nginx", "args": []interface{}{"-v"}},
+					},
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, applied); diff != "" {
+		t.Errorf("unexpected applied configuration (-want +got):\n%s", diff)
+	}
+}
+
+func TestAppliedConfiguration_otherFieldManager(t *testing.T) {
+	applied, err := appliedConfiguration(appliedChild(), "other")
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if applied != nil {
+		t.Errorf("expected no applied configuration, got: %v", applied)
+	}
+}
@@ -0,0 +1,327 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/audit"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	"metacontroller/pkg/logging"
+)
+
+// DefaultFieldManager is the field manager of server-side applied children,
+// unless the controller sets its own.
+const DefaultFieldManager = "metacontroller"
+
+// DefaultChildConcurrency is how many children of the same kind are written
+// at once, unless the controller sets its own concurrency.
+const DefaultChildConcurrency = 10
+
+// ForceDryRun makes all controllers dry-run the writes to their children, as
+// if they all set dryRun.
+var ForceDryRun = false
+
+// StatusFieldManager returns the field manager of server-side applied parent
+// statuses, which is dedicated to them so applying children doesn't remove
+// status fields, or the other way around.
+func StatusFieldManager(fieldManager string) string {
+	if fieldManager == "" {
+		fieldManager = DefaultFieldManager
+	}
+	return fieldManager + "-status"
+}
+
+// ChildApplyStrategies holds how the children of each kind are written.
+// A nil *ChildApplyStrategies uses ThreeWayMerge for all of them.
+type ChildApplyStrategies struct {
+	defaultStrategy v1alpha1.ChildApplyStrategy
+	fieldManager    string
+	kinds           map[string]childApplyOptions
+	updateDiff      *v1alpha1.ChildUpdateDiff
+	concurrency     int
+	dryRun          bool
+	// controller is the controller the writes which aren't reported in child
+	// results are recorded for in the audit log.
+	controller audit.Controller
+	// logger logs the writes to children, with the verbosity of the controller.
+	logger logr.Logger
+}
+
+type childApplyOptions struct {
+	strategy    v1alpha1.ChildApplyStrategy
+	conflicts   *v1alpha1.ChildConflictPolicy
+	ignorePaths []fieldPath
+}
+
+// NewChildApplyStrategies returns ChildApplyStrategies which use
+// defaultStrategy for kinds without a strategy of their own, and fieldManager
+// (or DefaultFieldManager if empty) for server-side apply.
+func NewChildApplyStrategies(defaultStrategy v1alpha1.ChildApplyStrategy, fieldManager string) *ChildApplyStrategies {
+	if fieldManager == "" {
+		fieldManager = DefaultFieldManager
+	}
+	return &ChildApplyStrategies{
+		defaultStrategy: defaultStrategy,
+		fieldManager:    fieldManager,
+		kinds:           make(map[string]childApplyOptions),
+		concurrency:     DefaultChildConcurrency,
+		dryRun:          ForceDryRun,
+	}
+}
+
+// Set sets the strategy and conflict policy of the given kind. An empty
+// strategy means the default one. A conflict policy is only valid with
+// server-side apply.
+func (s *ChildApplyStrategies) Set(apiGroup, kind string, strategy v1alpha1.ChildApplyStrategy, conflicts *v1alpha1.ChildConflictPolicy) error {
+	if strategy == "" {
+		strategy = s.defaultStrategy
+	}
+	if conflicts != nil && strategy != v1alpha1.ChildApplyServerSide {
+		return fmt.Errorf("conflictPolicy of %v requires the %v applyStrategy", kind, v1alpha1.ChildApplyServerSide)
+	}
+	key := fmt.Sprintf("%s.%s", kind, apiGroup)
+	options := s.kinds[key]
+	options.strategy, options.conflicts = strategy, conflicts
+	s.kinds[key] = options
+	return nil
+}
+
+// SetIgnorePaths sets the paths of the given kind which are left as observed,
+// either JSON Pointers or dot-separated paths.
+func (s *ChildApplyStrategies) SetIgnorePaths(apiGroup, kind string, paths []string) error {
+	key := fmt.Sprintf("%s.%s", kind, apiGroup)
+	options := s.kinds[key]
+	options.ignorePaths = nil
+	for _, path := range paths {
+		parsed, err := parseFieldPath(path)
+		if err != nil {
+			return fmt.Errorf("invalid ignorePaths of %v: %w", kind, err)
+		}
+		options.ignorePaths = append(options.ignorePaths, parsed)
+	}
+	s.kinds[key] = options
+	return nil
+}
+
+// SetUpdateDiff sets how the diffs of child updates are reported, or nil to
+// not compute them.
+func (s *ChildApplyStrategies) SetUpdateDiff(updateDiff *v1alpha1.ChildUpdateDiff) {
+	s.updateDiff = updateDiff
+}
+
+// UpdateDiff returns how the diffs of child updates are reported, or nil if
+// they aren't computed.
+func (s *ChildApplyStrategies) UpdateDiff() *v1alpha1.ChildUpdateDiff {
+	if s == nil {
+		return nil
+	}
+	return s.updateDiff
+}
+
+// SetConcurrency sets how many children of the same kind are written at
+// once, or DefaultChildConcurrency if nil.
+func (s *ChildApplyStrategies) SetConcurrency(concurrency *int32) error {
+	if concurrency == nil {
+		s.concurrency = DefaultChildConcurrency
+		return nil
+	}
+	if *concurrency < 1 {
+		return fmt.Errorf("invalid childConcurrency %d: must be at least 1", *concurrency)
+	}
+	s.concurrency = int(*concurrency)
+	return nil
+}
+
+// Concurrency returns how many children of the same kind are written at once.
+func (s *ChildApplyStrategies) Concurrency() int {
+	if s == nil {
+		return DefaultChildConcurrency
+	}
+	return s.concurrency
+}
+
+// SetDryRun sets whether the writes to children are only dry-run by the API
+// server, which validates them without persisting them. They always are if
+// ForceDryRun is set.
+func (s *ChildApplyStrategies) SetDryRun(dryRun *bool) {
+	s.dryRun = ForceDryRun || (dryRun != nil && *dryRun)
+}
+
+// DryRun returns whether the writes to children are only dry-run.
+func (s *ChildApplyStrategies) DryRun() bool {
+	if s == nil {
+		return ForceDryRun
+	}
+	return s.dryRun
+}
+
+// SetAuditController sets the controller the writes made while applying
+// children, besides the operations reported in their results, are recorded for
+// in the audit log.
+func (s *ChildApplyStrategies) SetAuditController(controllerName string, controllerType ControllerType) {
+	s.controller = audit.Controller{Name: controllerName, Type: controllerType.String()}
+}
+
+// SetLogger sets the logger of the writes to children, which follows the
+// verbosity of the controller.
+func (s *ChildApplyStrategies) SetLogger(logger logr.Logger) {
+	s.logger = logger
+}
+
+// Logger returns the logger of the writes to children, or Logger if the
+// controller didn't set one.
+func (s *ChildApplyStrategies) Logger() logr.Logger {
+	if s == nil || s.logger == nil {
+		return logging.Logger
+	}
+	return s.logger
+}
+
+// auditRecorder returns the recorder of the writes made while applying the
+// children of parent.
+func (s *ChildApplyStrategies) auditRecorder(parent *unstructured.Unstructured) audit.Recorder {
+	return audit.Recorder{Controller: s.controller, Parent: audit.ObjectReference(parent)}
+}
+
+// dryRunOptions returns the dry run options of the writes to children.
+func (s *ChildApplyStrategies) dryRunOptions() []string {
+	if s.DryRun() {
+		return []string{metav1.DryRunAll}
+	}
+	return nil
+}
+
+// Get returns the strategy of the given kind.
+func (s *ChildApplyStrategies) Get(apiGroup, kind string) v1alpha1.ChildApplyStrategy {
+	return s.options(apiGroup, kind).strategy
+}
+
+func (s *ChildApplyStrategies) options(apiGroup, kind string) childApplyOptions {
+	if s == nil {
+		return childApplyOptions{strategy: v1alpha1.ChildApplyThreeWayMerge}
+	}
+	options := s.kinds[fmt.Sprintf("%s.%s", kind, apiGroup)]
+	if options.strategy == "" {
+		options.strategy = s.defaultStrategy
+	}
+	if options.strategy == "" {
+		options.strategy = v1alpha1.ChildApplyThreeWayMerge
+	}
+	return options
+}
+
+// ApplyUpdate returns orig with update applied, like the ApplyUpdate func,
+// but without the last-applied-configuration annotation for children which
+// are server-side applied. For those, the fields owned by the field manager
+// tell which fields were removed from the desired state instead, so they're
+// removed from the result, like the next apply removes them.
+// Ignored paths are left as they are in orig.
+func (s *ChildApplyStrategies) ApplyUpdate(orig, update *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	apiGroup, _ := ParseAPIVersion(orig.GetAPIVersion())
+	options := s.options(apiGroup, orig.GetKind())
+	var newObj *unstructured.Unstructured
+	var err error
+	if options.strategy == v1alpha1.ChildApplyServerSide {
+		var lastApplied map[string]interface{}
+		lastApplied, err = appliedConfiguration(orig, s.fieldManager)
+		if err != nil {
+			return nil, err
+		}
+		newObj, err = mergeUpdate(orig, lastApplied, options.applied(update))
+	} else {
+		newObj, err = ApplyUpdate(orig, update, s.Logger())
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range options.ignorePaths {
+		path.revert(newObj.UnstructuredContent(), orig.UnstructuredContent())
+	}
+	return newObj, nil
+}
+
+// applied returns the part of obj which is server-side applied, i.e. without
+// the ignored paths and those surrendered to other field managers.
+func (o childApplyOptions) applied(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	if len(o.ignorePaths) == 0 && (o.conflicts == nil || len(o.conflicts.SurrenderPaths) == 0) {
+		return obj
+	}
+	applied := obj.DeepCopy()
+	for _, path := range o.ignorePaths {
+		path.remove(applied.UnstructuredContent())
+	}
+	if o.conflicts != nil {
+		for _, path := range o.conflicts.SurrenderPaths {
+			unstructured.RemoveNestedField(applied.UnstructuredContent(), strings.Split(path, ".")...)
+		}
+	}
+	return applied
+}
+
+// serverSideApply creates or updates obj with server-side apply. Fields owned
+// by other field managers are taken over, unless the conflict policy says to
+// fail instead.
+func (s *ChildApplyStrategies) serverSideApply(client *dynamicclientset.ResourceClient, namespace string, parent, obj *unstructured.Unstructured) error {
+	_, err := s.applyPatch(client, namespace, parent, obj, s.dryRunOptions())
+	return err
+}
+
+// applyPatch server-side applies obj with the given dry run options, and
+// returns the object the API server stored, or would store.
+func (s *ChildApplyStrategies) applyPatch(client *dynamicclientset.ResourceClient, namespace string, parent, obj *unstructured.Unstructured, dryRun []string) (*unstructured.Unstructured, error) {
+	options := s.options(client.Group, client.Kind)
+	applied := options.applied(obj).DeepCopy()
+	// The controllerRef is part of the applied configuration, so that it's
+	// never removed by a later apply. Children in other namespaces, or
+	// cluster-scoped children of a namespaced parent, are owned through labels
+	// instead.
+	if canOwnByReference(parent, client.Namespaced, namespace) {
+		controllerRef := MakeControllerRef(parent)
+		ownerRefs := applied.GetOwnerReferences()
+		found := false
+		for _, ownerRef := range ownerRefs {
+			if ownerRef.UID == controllerRef.UID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			applied.SetOwnerReferences(append(ownerRefs, *controllerRef))
+		}
+	}
+	data, err := json.Marshal(applied)
+	if err != nil {
+		return nil, fmt.Errorf("can't marshal %v: %w", describeObject(obj), err)
+	}
+	force := options.conflicts == nil || options.conflicts.Mode != v1alpha1.ChildConflictFail
+	return client.Namespace(namespace).Patch(context.TODO(), applied.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+		FieldManager: s.fieldManager,
+		Force:        pointer.BoolPtr(force),
+		DryRun:       dryRun,
+	})
+}
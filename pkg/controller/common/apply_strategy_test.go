@@ -0,0 +1,185 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/pointer"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	dynamicapply "metacontroller/pkg/dynamic/apply"
+)
+
+func TestChildApplyStrategies_Get(t *testing.T) {
+	var none *ChildApplyStrategies
+	if strategy := none.Get("apps", "Deployment"); strategy != v1alpha1.ChildApplyThreeWayMerge {
+		t.Errorf("expected ThreeWayMerge without strategies, got: %s", strategy)
+	}
+
+	strategies := NewChildApplyStrategies(v1alpha1.ChildApplyServerSide, "")
+	if err := strategies.Set("", "ConfigMap", v1alpha1.ChildApplyThreeWayMerge, nil); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	if strategy := strategies.Get("apps", "Deployment"); strategy != v1alpha1.ChildApplyServerSide {
+		t.Errorf("expected the default ServerSideApply, got: %s", strategy)
+	}
+	if strategy := strategies.Get("", "ConfigMap"); strategy != v1alpha1.ChildApplyThreeWayMerge {
+		t.Errorf("expected ThreeWayMerge for ConfigMap, got: %s", strategy)
+	}
+}
+
+func TestChildApplyStrategies_ApplyUpdate_whenServerSide_noLastAppliedAnnotation(t *testing.T) {
+	orig := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "test", "resourceVersion": "1"},
+		"data":       map[string]interface{}{"a": "1"},
+	}}
+	update := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "test"},
+		"data":       map[string]interface{}{"a": "1"},
+	}}
+	strategies := NewChildApplyStrategies(v1alpha1.ChildApplyServerSide, "")
+
+	updated, err := strategies.ApplyUpdate(orig, update)
+
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if _, ok := updated.GetAnnotations()[dynamicapply.LastAppliedAnnotation]; ok {
+		t.Errorf("expected no %s annotation", dynamicapply.LastAppliedAnnotation)
+	}
+	if !reflect.DeepEqual(orig, updated) {
+		t.Errorf("expected no change, got: %v", updated)
+	}
+}
+
+func TestChildApplyStrategies_Set_whenConflictPolicyWithoutServerSide_returnError(t *testing.T) {
+	strategies := NewChildApplyStrategies("", "")
+
+	err := strategies.Set("apps", "Deployment", "", &v1alpha1.ChildConflictPolicy{Mode: v1alpha1.ChildConflictFail})
+
+	if err == nil {
+		t.Errorf("expected an error for a conflictPolicy with ThreeWayMerge")
+	}
+}
+
+func TestChildApplyStrategies_ApplyUpdate_ignoresSurrenderedPaths(t *testing.T) {
+	orig := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "test"},
+		"spec":       map[string]interface{}{"replicas": int64(5), "paused": false},
+	}}
+	update := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "test"},
+		"spec":       map[string]interface{}{"replicas": int64(1), "paused": false},
+	}}
+	strategies := NewChildApplyStrategies(v1alpha1.ChildApplyServerSide, "")
+	if err := strategies.Set("apps", "Deployment", "", &v1alpha1.ChildConflictPolicy{SurrenderPaths: []string{"spec.replicas"}}); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	updated, err := strategies.ApplyUpdate(orig, update)
+
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if !reflect.DeepEqual(orig, updated) {
+		t.Errorf("expected spec.replicas to be left alone, got: %v", updated)
+	}
+}
+
+func TestChildApplyStrategies_ApplyUpdate_whenServerSide_removesFieldsNoLongerDesired(t *testing.T) {
+	orig := appliedChild()
+	update := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":   "test",
+			"labels": map[string]interface{}{"app": "test"},
+		},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "main", "image": "nginx"},
+					},
+				},
+			},
+		},
+	}}
+	strategies := NewChildApplyStrategies(v1alpha1.ChildApplyServerSide, "")
+
+	updated, err := strategies.ApplyUpdate(orig, update)
+
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(updated.Object, "spec", "paused"); found {
+		t.Errorf("expected spec.paused to be removed, got: %v", updated)
+	}
+	containers, _, _ := unstructured.NestedSlice(updated.Object, "spec", "template", "spec", "containers")
+	want := []interface{}{
+		map[string]interface{}{"name": "main", "image": "nginx"},
+		map[string]interface{}{"name": "sidecar", "image": "envoy"},
+	}
+	if !reflect.DeepEqual(containers, want) {
+		t.Errorf("expected args of main to be removed and sidecar kept, got: %v", containers)
+	}
+	if replicas, _, _ := unstructured.NestedInt64(updated.Object, "spec", "replicas"); replicas != 3 {
+		t.Errorf("expected spec.replicas owned by another manager to be kept, got: %v", replicas)
+	}
+	if labels := updated.GetLabels(); labels["other"] != "x" {
+		t.Errorf("expected labels owned by no one to be kept, got: %v", labels)
+	}
+	if len(updated.GetOwnerReferences()) != 1 {
+		t.Errorf("expected the controllerRef to be kept, got: %v", updated.GetOwnerReferences())
+	}
+}
+
+func TestChildApplyStrategies_SetConcurrency(t *testing.T) {
+	strategies := NewChildApplyStrategies("", "")
+	if got := strategies.Concurrency(); got != DefaultChildConcurrency {
+		t.Errorf("expected the default concurrency, got: %d", got)
+	}
+
+	if err := strategies.SetConcurrency(pointer.Int32Ptr(1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strategies.Concurrency(); got != 1 {
+		t.Errorf("expected a concurrency of 1, got: %d", got)
+	}
+
+	if err := strategies.SetConcurrency(pointer.Int32Ptr(0)); err == nil {
+		t.Errorf("expected an error for a childConcurrency of 0")
+	}
+}
+
+func TestChildApplyStrategies_SetDryRun(t *testing.T) {
+	strategies := NewChildApplyStrategies("", "")
+	if strategies.DryRun() || strategies.dryRunOptions() != nil {
+		t.Errorf("expected writes not to be dry-run by default")
+	}
+
+	strategies.SetDryRun(pointer.BoolPtr(true))
+	if !strategies.DryRun() || !reflect.DeepEqual(strategies.dryRunOptions(), []string{"All"}) {
+		t.Errorf("expected writes to be dry-run, got options: %v", strategies.dryRunOptions())
+	}
+
+	ForceDryRun = true
+	defer func() { ForceDryRun = false }()
+	strategies.SetDryRun(pointer.BoolPtr(false))
+	if !strategies.DryRun() {
+		t.Errorf("expected ForceDryRun to take precedence over dryRun: false")
+	}
+	if !NewChildApplyStrategies("", "").DryRun() {
+		t.Errorf("expected ForceDryRun to apply without dryRun")
+	}
+}
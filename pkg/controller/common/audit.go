@@ -0,0 +1,63 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"metacontroller/pkg/audit"
+)
+
+// AuditRecorder returns the recorder of the writes the controller makes on
+// behalf of parent, besides the operations on its children, which are recorded
+// with AuditChildren. Clients are made to record their writes with
+// ResourceClient.Audited.
+func AuditRecorder(controllerName string, controllerType ControllerType, parent *unstructured.Unstructured) audit.Recorder {
+	return audit.Recorder{
+		Controller: audit.Controller{Name: controllerName, Type: controllerType.String()},
+		Parent:     audit.ObjectReference(parent),
+	}
+}
+
+// AuditChildren records the operations on the children of parent in the
+// audit log, if it's enabled.
+func AuditChildren(controllerName string, controllerType ControllerType, parent *unstructured.Unstructured, results []ChildResult) {
+	if !audit.Log.Enabled() {
+		return
+	}
+	recorder := AuditRecorder(controllerName, controllerType, parent)
+	for _, result := range results {
+		entry := audit.Entry{
+			Controller: recorder.Controller,
+			Parent:     recorder.Parent,
+			Child: audit.Reference{
+				APIVersion: result.APIVersion,
+				Kind:       result.Kind,
+				Namespace:  result.Namespace,
+				Name:       result.Name,
+			},
+			Action: string(result.Action),
+			Result: result.Result,
+			Error:  result.Error,
+			DryRun: result.DryRun,
+		}
+		if result.Diff != nil {
+			entry.Diff = truncatePatch(result.Diff)
+		}
+		audit.Log.Record(entry)
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"hash/fnv"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sjson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/rand"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+// BlueGreenNameAnnotation records the name returned by the hooks for a child
+// updated with the BlueGreen method, whose actual name has a generated suffix.
+const BlueGreenNameAnnotation = "metacontroller.k8s.io/blue-green-name"
+
+// BlueGreen renames the desired children of kinds updated with the BlueGreen
+// method, adding a hash of their content to their names, so a changed child
+// is created alongside the existing one instead of updating it.
+// Until the replacement is ready, the previous children of the same name are
+// left out of the observed children, so they're kept; once it is, they're
+// deleted since they aren't desired anymore.
+// It returns the observed and desired children to manage, and whether a
+// replacement is waited for.
+func BlueGreen(updateStrategy ChildUpdateStrategy, observed, desired RelativeObjectMap, ready func(obj *unstructured.Unstructured) (bool, error)) (RelativeObjectMap, RelativeObjectMap, bool, error) {
+	managedObserved := make(RelativeObjectMap, len(observed))
+	for gvk, group := range observed {
+		managedObserved[gvk] = group
+	}
+	managedDesired := make(RelativeObjectMap, len(desired))
+	waiting := false
+	for gvk, group := range desired {
+		if updateStrategy.GetMethod(gvk.Group, gvk.Kind) != v1alpha1.ChildUpdateBlueGreen {
+			managedDesired[gvk] = group
+			continue
+		}
+		// The previous children of each name returned by the hooks.
+		previous := make(map[string][]string)
+		for name, obj := range observed[gvk] {
+			base, ok := obj.GetAnnotations()[BlueGreenNameAnnotation]
+			if !ok {
+				base = name
+			}
+			previous[base] = append(previous[base], name)
+		}
+
+		renamed := make(map[string]*unstructured.Unstructured, len(group))
+		managed := make(map[string]*unstructured.Unstructured, len(observed[gvk]))
+		for name, obj := range observed[gvk] {
+			managed[name] = obj
+		}
+		for name, obj := range group {
+			hash, err := blueGreenHash(obj)
+			if err != nil {
+				return nil, nil, false, err
+			}
+			child := obj.DeepCopy()
+			annotations := child.GetAnnotations()
+			if annotations == nil {
+				annotations = make(map[string]string, 1)
+			}
+			annotations[BlueGreenNameAnnotation] = name
+			child.SetAnnotations(annotations)
+			child.SetName(name + "-" + hash)
+			renamed[child.GetName()] = child
+
+			current := observed[gvk][child.GetName()]
+			currentReady := current != nil
+			if currentReady {
+				if currentReady, err = ready(current); err != nil {
+					return nil, nil, false, err
+				}
+			}
+			if currentReady {
+				continue
+			}
+			for _, previousName := range previous[name] {
+				if previousName != child.GetName() {
+					delete(managed, previousName)
+					waiting = true
+				}
+			}
+		}
+		managedObserved[gvk] = managed
+		managedDesired[gvk] = renamed
+	}
+	return managedObserved, managedDesired, waiting, nil
+}
+
+// blueGreenHash returns a short hash of the content of a desired child.
+func blueGreenHash(obj *unstructured.Unstructured) (string, error) {
+	data, err := k8sjson.Marshal(obj.UnstructuredContent())
+	if err != nil {
+		return "", err
+	}
+	hasher := fnv.New32a()
+	_, _ = hasher.Write(data)
+	return rand.SafeEncodeString(strconv.FormatUint(uint64(hasher.Sum32()), 10)), nil
+}
@@ -0,0 +1,89 @@
+package common
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+func TestBlueGreen(t *testing.T) {
+	parent := &unstructured.Unstructured{}
+	parent.SetNamespace("ns")
+	newStatefulSet := func(name, image string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("apps/v1")
+		obj.SetKind("StatefulSet")
+		obj.SetNamespace("ns")
+		obj.SetName(name)
+		_ = unstructured.SetNestedField(obj.Object, image, "spec", "image")
+		return obj
+	}
+	desiredV2 := newStatefulSet("db", "v2")
+	hash, err := blueGreenHash(desiredV2)
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	replacement := newStatefulSet("db-"+hash, "v2")
+	replacement.SetAnnotations(map[string]string{BlueGreenNameAnnotation: "db"})
+
+	tests := []struct {
+		name             string
+		observed         []*unstructured.Unstructured
+		ready            bool
+		expectedObserved []string
+		expectedWaiting  bool
+	}{
+		{
+			name:             "replacement missing",
+			observed:         []*unstructured.Unstructured{newStatefulSet("db", "v1")},
+			expectedObserved: []string{},
+			expectedWaiting:  true,
+		},
+		{
+			name:             "replacement not ready",
+			observed:         []*unstructured.Unstructured{newStatefulSet("db", "v1"), replacement},
+			expectedObserved: []string{"db-" + hash},
+			expectedWaiting:  true,
+		},
+		{
+			name:             "replacement ready",
+			observed:         []*unstructured.Unstructured{newStatefulSet("db", "v1"), replacement},
+			ready:            true,
+			expectedObserved: []string{"db", "db-" + hash},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			observed := MakeRelativeObjectMap(parent, tt.observed)
+			desired := MakeRelativeObjectMap(parent, []*unstructured.Unstructured{newStatefulSet("db", "v2")})
+			ready := func(obj *unstructured.Unstructured) (bool, error) { return tt.ready, nil }
+
+			managedObserved, managedDesired, waiting, err := BlueGreen(fixedUpdateMethod(v1alpha1.ChildUpdateBlueGreen), observed, desired, ready)
+			if err != nil {
+				t.Fatalf("err should be nil, got: %v", err)
+			}
+
+			if waiting != tt.expectedWaiting {
+				t.Errorf("expected waiting to be %v", tt.expectedWaiting)
+			}
+			names := []string{}
+			for _, group := range managedObserved {
+				for name := range group {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+			if diff := cmp.Diff(tt.expectedObserved, names); diff != "" {
+				t.Errorf("unexpected observed children (-want +got):\n%s", diff)
+			}
+			child := managedDesired.List()[0]
+			if child.GetName() != "db-"+hash || child.GetAnnotations()[BlueGreenNameAnnotation] != "db" {
+				t.Errorf("unexpected desired child %v with annotations %v", child.GetName(), child.GetAnnotations())
+			}
+		})
+	}
+}
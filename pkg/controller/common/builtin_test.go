@@ -0,0 +1,21 @@
+package common
+
+import "testing"
+
+func TestIsBuiltInAPIGroup(t *testing.T) {
+	tests := map[string]bool{
+		"":                          true,
+		"apps":                      true,
+		"networking.k8s.io":         true,
+		"rbac.authorization.k8s.io": true,
+		"example.com":               false,
+		"metacontroller.k8s.io":     false,
+		"gateway.networking.k8s.io": false,
+		"snapshot.storage.k8s.io":   false,
+	}
+	for group, expected := range tests {
+		if got := IsBuiltInAPIGroup(group); got != expected {
+			t.Errorf("IsBuiltInAPIGroup(%q) = %v, expected %v", group, got, expected)
+		}
+	}
+}
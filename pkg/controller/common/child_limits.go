@@ -0,0 +1,83 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ChildLimitExceededCondition is the parent status condition type set while
+// the hooks return more children than allowed for the parent.
+const ChildLimitExceededCondition = "ChildLimitExceeded"
+
+// ChildLimits caps the number of desired children of a parent, in total and
+// per kind. A nil *ChildLimits allows any number.
+type ChildLimits struct {
+	max   *int32
+	kinds map[string]int32
+}
+
+// NewChildLimits returns ChildLimits which cap the total number of children
+// to max, if not nil.
+func NewChildLimits(max *int32) *ChildLimits {
+	return &ChildLimits{max: max, kinds: make(map[string]int32)}
+}
+
+// Set caps the number of children of the given kind to max.
+func (l *ChildLimits) Set(apiGroup, kind string, max int32) {
+	l.kinds[fmt.Sprintf("%s.%s", kind, apiGroup)] = max
+}
+
+// Check returns an error describing the exceeded limits, if desired has more
+// children than allowed.
+func (l *ChildLimits) Check(desired RelativeObjectMap) error {
+	if l == nil {
+		return nil
+	}
+	var exceeded []string
+	total := 0
+	for key, group := range desired {
+		total += len(group)
+		if max, ok := l.kinds[fmt.Sprintf("%s.%s", key.Kind, key.Group)]; ok && len(group) > int(max) {
+			exceeded = append(exceeded, fmt.Sprintf("%d %v children exceed the limit of %d", len(group), key.Kind, max))
+		}
+	}
+	sort.Strings(exceeded)
+	if l.max != nil && total > int(*l.max) {
+		exceeded = append([]string{fmt.Sprintf("%d children exceed the limit of %d per parent", total, *l.max)}, exceeded...)
+	}
+	if len(exceeded) == 0 {
+		return nil
+	}
+	return fmt.Errorf("sync response rejected: %s", strings.Join(exceeded, ", "))
+}
+
+// SetChildLimitExceededCondition sets the ChildLimitExceeded condition in
+// status if limitErr is not nil, and removes it otherwise.
+// It returns the updated status, which is only allocated if needed.
+func SetChildLimitExceededCondition(status map[string]interface{}, limitErr error) map[string]interface{} {
+	if limitErr == nil {
+		return setCondition(status, ChildLimitExceededCondition, nil)
+	}
+	return setCondition(status, ChildLimitExceededCondition, map[string]interface{}{
+		"status":  "True",
+		"reason":  "TooManyChildren",
+		"message": limitErr.Error(),
+	})
+}
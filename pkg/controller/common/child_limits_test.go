@@ -0,0 +1,71 @@
+package common
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/pointer"
+)
+
+func TestChildLimits_Check(t *testing.T) {
+	parent := &unstructured.Unstructured{}
+	parent.SetNamespace("ns")
+	var children []*unstructured.Unstructured
+	for _, name := range []string{"a", "b", "c"} {
+		pod := &unstructured.Unstructured{}
+		pod.SetAPIVersion("v1")
+		pod.SetKind("Pod")
+		pod.SetName(name)
+		children = append(children, pod)
+	}
+	deployment := &unstructured.Unstructured{}
+	deployment.SetAPIVersion("apps/v1")
+	deployment.SetKind("Deployment")
+	deployment.SetName("a")
+	children = append(children, deployment)
+	desired := MakeRelativeObjectMap(parent, children)
+
+	tests := []struct {
+		name     string
+		limits   func() *ChildLimits
+		expected string
+	}{
+		{
+			name:   "no limits",
+			limits: func() *ChildLimits { return nil },
+		},
+		{
+			name: "within limits",
+			limits: func() *ChildLimits {
+				limits := NewChildLimits(pointer.Int32Ptr(4))
+				limits.Set("", "Pod", 3)
+				return limits
+			},
+		},
+		{
+			name: "exceeded",
+			limits: func() *ChildLimits {
+				limits := NewChildLimits(pointer.Int32Ptr(3))
+				limits.Set("", "Pod", 2)
+				limits.Set("apps", "Deployment", 1)
+				return limits
+			},
+			expected: "sync response rejected: 4 children exceed the limit of 3 per parent, 3 Pod children exceed the limit of 2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits().Check(desired)
+
+			if tt.expected == "" {
+				if err != nil {
+					t.Errorf("err should be nil, got: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("expected error %q, got: %v", tt.expected, err)
+			}
+		})
+	}
+}
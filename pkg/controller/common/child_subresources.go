@@ -0,0 +1,156 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"reflect"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"metacontroller/pkg/audit"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	"metacontroller/pkg/logging"
+)
+
+// ChildSubresource is the desired content of a subresource of a child, like
+// its scale, which is written apart from the child itself.
+type ChildSubresource struct {
+	APIVersion  string                 `json:"apiVersion"`
+	Kind        string                 `json:"kind"`
+	Namespace   string                 `json:"namespace,omitempty"`
+	Name        string                 `json:"name"`
+	Subresource string                 `json:"subresource"`
+	Content     map[string]interface{} `json:"content"`
+}
+
+func (s ChildSubresource) String() string {
+	if s.Namespace != "" {
+		return fmt.Sprintf("%s %s/%s %s", s.Kind, s.Namespace, s.Name, s.Subresource)
+	}
+	return fmt.Sprintf("%s %s %s", s.Kind, s.Name, s.Subresource)
+}
+
+// ApplyChildSubresources merges the desired content of each subresource into
+// it with a JSON merge patch, unless it's already there. Only the
+// subresources of the observed children of parent are written, and the
+// status of children is left to their own controllers. The writes are recorded
+// with recorder.
+func ApplyChildSubresources(dynClient *dynamicclientset.Clientset, recorder audit.Recorder, parent *unstructured.Unstructured, observed RelativeObjectMap, subresources []ChildSubresource) error {
+	var errs []error
+	for _, desired := range subresources {
+		if err := applyChildSubresource(dynClient, recorder, parent, observed, desired); err != nil {
+			errs = append(errs, fmt.Errorf("can't apply %v: %w", desired, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func applyChildSubresource(dynClient *dynamicclientset.Clientset, recorder audit.Recorder, parent *unstructured.Unstructured, observed RelativeObjectMap, desired ChildSubresource) error {
+	if desired.Subresource == "" || desired.Subresource == "status" {
+		return fmt.Errorf("invalid subresource %q", desired.Subresource)
+	}
+	child := findObservedChild(parent, observed, desired)
+	if child == nil {
+		// The child may not be created yet, in which case the subresource is
+		// applied by the sync its creation triggers.
+		logging.Logger.V(4).Info("Not updating subresource", "parent", parent, "subresource", desired.String(), "reason", "Not an observed child")
+		return nil
+	}
+	client, err := dynClient.Kind(desired.APIVersion, desired.Kind)
+	if err != nil {
+		return err
+	}
+	client = client.Namespace(child.GetNamespace()).Audited(recorder)
+	current, err := client.GetSubresource(child.GetName(), desired.Subresource)
+	if err != nil {
+		return err
+	}
+	if !mergePatchChanges(current.UnstructuredContent(), desired.Content) {
+		return nil
+	}
+	logging.Logger.Info("Updating subresource", "parent", parent, "child", child, "subresource", desired.Subresource)
+	_, err = client.PatchSubresource(child.GetName(), desired.Subresource, desired.Content)
+	return err
+}
+
+// findObservedChild returns the observed child the subresource belongs to,
+// or nil if there's none.
+func findObservedChild(parent *unstructured.Unstructured, observed RelativeObjectMap, desired ChildSubresource) *unstructured.Unstructured {
+	gv, err := schema.ParseGroupVersion(desired.APIVersion)
+	if err != nil {
+		return nil
+	}
+	// Children in the namespace of the parent, and cluster-scoped children,
+	// are keyed by name only.
+	key := &unstructured.Unstructured{}
+	key.SetNamespace(desired.Namespace)
+	if key.GetNamespace() == "" {
+		key.SetNamespace(parent.GetNamespace())
+	}
+	key.SetName(desired.Name)
+	return observed[GroupVersionKind{gv.WithKind(desired.Kind)}][relativeName(parent, key)]
+}
+
+// mergePatchChanges returns whether applying patch to obj as a JSON merge
+// patch would change it.
+func mergePatchChanges(obj, patch map[string]interface{}) bool {
+	for key, patchValue := range patch {
+		value, found := obj[key]
+		if patchValue == nil {
+			if found {
+				return true
+			}
+			continue
+		}
+		patchMap, patchIsMap := patchValue.(map[string]interface{})
+		valueMap, valueIsMap := value.(map[string]interface{})
+		if patchIsMap && valueIsMap {
+			if mergePatchChanges(valueMap, patchMap) {
+				return true
+			}
+			continue
+		}
+		if !found || !reflect.DeepEqual(normalizeJSONValue(value), normalizeJSONValue(patchValue)) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeJSONValue makes all the numbers of a JSON value comparable, whether
+// they were decoded as integers or float64.
+func normalizeJSONValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		normalized := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			normalized[key] = normalizeJSONValue(item)
+		}
+		return normalized
+	case []interface{}:
+		normalized := make([]interface{}, len(value))
+		for i, item := range value {
+			normalized[i] = normalizeJSONValue(item)
+		}
+		return normalized
+	default:
+		return normalizeJSON(value)
+	}
+}
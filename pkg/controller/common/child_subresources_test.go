@@ -0,0 +1,56 @@
+package common
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestMergePatchChanges(t *testing.T) {
+	scale := map[string]interface{}{
+		"spec":   map[string]interface{}{"replicas": int64(3)},
+		"status": map[string]interface{}{"replicas": int64(2), "selector": "app=test"},
+	}
+	tests := []struct {
+		name     string
+		patch    map[string]interface{}
+		expected bool
+	}{
+		{name: "same value", patch: map[string]interface{}{"spec": map[string]interface{}{"replicas": float64(3)}}},
+		{name: "empty", patch: map[string]interface{}{}},
+		{name: "other value", patch: map[string]interface{}{"spec": map[string]interface{}{"replicas": float64(5)}}, expected: true},
+		{name: "new field", patch: map[string]interface{}{"spec": map[string]interface{}{"paused": true}}, expected: true},
+		{name: "remove field", patch: map[string]interface{}{"status": map[string]interface{}{"selector": nil}}, expected: true},
+		{name: "remove missing field", patch: map[string]interface{}{"spec": map[string]interface{}{"paused": nil}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergePatchChanges(scale, tt.patch); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFindObservedChild(t *testing.T) {
+	parent := &unstructured.Unstructured{}
+	parent.SetNamespace("ns")
+	child := &unstructured.Unstructured{}
+	child.SetAPIVersion("apps/v1")
+	child.SetKind("Deployment")
+	child.SetNamespace("ns")
+	child.SetName("web")
+	other := child.DeepCopy()
+	other.SetNamespace("other")
+	observed := MakeRelativeObjectMap(parent, []*unstructured.Unstructured{child, other})
+
+	if got := findObservedChild(parent, observed, ChildSubresource{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"}); got != child {
+		t.Errorf("expected the child in the namespace of the parent, got: %v", got)
+	}
+	if got := findObservedChild(parent, observed, ChildSubresource{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "other", Name: "web"}); got != other {
+		t.Errorf("expected the child in the other namespace, got: %v", got)
+	}
+	if got := findObservedChild(parent, observed, ChildSubresource{APIVersion: "apps/v1", Kind: "StatefulSet", Name: "web"}); got != nil {
+		t.Errorf("expected no child, got: %v", got)
+	}
+}
@@ -0,0 +1,464 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+)
+
+// ChildInvalidCondition is the parent status condition type set while the
+// hooks return children which don't match the schemas of their kinds.
+const ChildInvalidCondition = "ChildInvalid"
+
+// crdSchemaTTL is how long the schemas of CRDs are cached.
+const crdSchemaTTL = time.Minute
+
+// ChildValidator validates desired children before they're written, against
+// the Go types of built-in kinds, and the OpenAPI schemas of custom resources.
+// A nil *ChildValidator accepts all children.
+type ChildValidator struct {
+	// crdSchema returns the OpenAPI schema of a custom resource kind, or nil
+	// if it has none.
+	crdSchema func(gvk schema.GroupVersionKind) (map[string]interface{}, error)
+}
+
+// NewChildValidator returns a ChildValidator which reads the schemas of
+// custom resources from their CRDs with dynClient.
+func NewChildValidator(dynClient *dynamicclientset.Clientset) *ChildValidator {
+	cache := &crdSchemaCache{dynClient: dynClient, schemas: make(map[string]cachedCRDSchema)}
+	return &ChildValidator{crdSchema: cache.get}
+}
+
+// Check returns an error naming each invalid child and field, if some of the
+// desired children don't match their schemas.
+func (v *ChildValidator) Check(desired RelativeObjectMap) error {
+	if v == nil {
+		return nil
+	}
+	var violations []string
+	for key, group := range desired {
+		for _, obj := range group {
+			errs, err := v.validate(key.GroupVersionKind, obj)
+			if err != nil {
+				return err
+			}
+			for _, e := range errs {
+				violations = append(violations, fmt.Sprintf("%v: %s", describeObject(obj), e))
+			}
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	sort.Strings(violations)
+	return fmt.Errorf("sync response rejected: invalid children: %s", strings.Join(violations, "; "))
+}
+
+func (v *ChildValidator) validate(gvk schema.GroupVersionKind, obj *unstructured.Unstructured) ([]string, error) {
+	// The status of children is never written.
+	content := make(map[string]interface{}, len(obj.Object))
+	for key, value := range obj.Object {
+		if key != "status" {
+			content[key] = value
+		}
+	}
+	if typed, err := clientgoscheme.Scheme.New(gvk); err == nil {
+		return validateGoValue("", reflect.TypeOf(typed), content), nil
+	}
+	crdSchema, err := v.crdSchema(gvk)
+	if err != nil {
+		return nil, fmt.Errorf("can't get schema of %v: %w", gvk, err)
+	}
+	if crdSchema == nil {
+		return nil, nil
+	}
+	return validateSchemaValue("", crdSchema, content), nil
+}
+
+// SetChildInvalidCondition sets the ChildInvalid condition in status if
+// validationErr is not nil, and removes it otherwise.
+// It returns the updated status, which is only allocated if needed.
+func SetChildInvalidCondition(status map[string]interface{}, validationErr error) map[string]interface{} {
+	if validationErr == nil {
+		return setCondition(status, ChildInvalidCondition, nil)
+	}
+	return setCondition(status, ChildInvalidCondition, map[string]interface{}{
+		"status":  "True",
+		"reason":  "SchemaViolation",
+		"message": validationErr.Error(),
+	})
+}
+
+// joinFieldPath returns the path of the given key of the object at path.
+func joinFieldPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func typeMismatch(path, expected string, value interface{}) string {
+	return fmt.Sprintf("%s: expected %s, got %s", path, expected, jsonType(value))
+}
+
+// jsonType returns the JSON type of an unstructured value.
+func jsonType(value interface{}) string {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int64, int32, int:
+		return "integer"
+	case float64:
+		if value == float64(int64(value)) {
+			return "integer"
+		}
+		return "number"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// validateGoValue returns the fields of value which are unknown to the Go
+// type t, or which the Go type can't decode.
+func validateGoValue(path string, t reflect.Type, value interface{}) []string {
+	if value == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(jsonUnmarshalerType) {
+		// Types like Quantity, Time or IntOrString decode themselves.
+		return nil
+	}
+	actual := jsonType(value)
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{typeMismatch(path, "object", value)}
+		}
+		fields := jsonFields(t)
+		var errs []string
+		for _, key := range sortedKeys(obj) {
+			fieldType, ok := fields[key]
+			if !ok {
+				errs = append(errs, fmt.Sprintf("%s: unknown field", joinFieldPath(path, key)))
+				continue
+			}
+			errs = append(errs, validateGoValue(joinFieldPath(path, key), fieldType, obj[key])...)
+		}
+		return errs
+	case reflect.Map:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{typeMismatch(path, "object", value)}
+		}
+		var errs []string
+		for _, key := range sortedKeys(obj) {
+			errs = append(errs, validateGoValue(joinFieldPath(path, key), t.Elem(), obj[key])...)
+		}
+		return errs
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// Bytes are encoded in base64 strings.
+			if actual != "string" {
+				return []string{typeMismatch(path, "string", value)}
+			}
+			return nil
+		}
+		list, ok := value.([]interface{})
+		if !ok {
+			return []string{typeMismatch(path, "array", value)}
+		}
+		var errs []string
+		for i, item := range list {
+			errs = append(errs, validateGoValue(fmt.Sprintf("%s[%d]", path, i), t.Elem(), item)...)
+		}
+		return errs
+	case reflect.String:
+		if actual != "string" {
+			return []string{typeMismatch(path, "string", value)}
+		}
+	case reflect.Bool:
+		if actual != "boolean" {
+			return []string{typeMismatch(path, "boolean", value)}
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if actual != "integer" {
+			return []string{typeMismatch(path, "integer", value)}
+		}
+	case reflect.Float32, reflect.Float64:
+		if actual != "integer" && actual != "number" {
+			return []string{typeMismatch(path, "number", value)}
+		}
+	}
+	return nil
+}
+
+var jsonFieldsCache sync.Map
+
+// jsonFields returns the type of each JSON field of the struct type t,
+// including those of inlined structs.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	if fields, ok := jsonFieldsCache.Load(t); ok {
+		return fields.(map[string]reflect.Type)
+	}
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		name := strings.Split(tag, ",")[0]
+		if name == "-" || (field.PkgPath != "" && !field.Anonymous) {
+			continue
+		}
+		if name == "" && field.Anonymous {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for key, fieldType := range jsonFields(embedded) {
+					fields[key] = fieldType
+				}
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	jsonFieldsCache.Store(t, fields)
+	return fields
+}
+
+// validateSchemaValue returns the violations of the OpenAPI v3 schema of a
+// CRD by value. Unknown fields aren't violations, since the API server prunes
+// them.
+func validateSchemaValue(path string, schema map[string]interface{}, value interface{}) []string {
+	if value == nil {
+		// The API server prunes nulls of fields which aren't nullable.
+		return nil
+	}
+	actual := jsonType(value)
+	if intOrString, _ := schema["x-kubernetes-int-or-string"].(bool); intOrString {
+		if actual != "integer" && actual != "string" {
+			return []string{typeMismatch(path, "integer or string", value)}
+		}
+		return nil
+	}
+	var errs []string
+	switch expected, _ := schema["type"].(string); expected {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{typeMismatch(path, "object", value)}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		additional, _ := schema["additionalProperties"].(map[string]interface{})
+		for _, key := range sortedKeys(obj) {
+			if property, ok := properties[key].(map[string]interface{}); ok {
+				errs = append(errs, validateSchemaValue(joinFieldPath(path, key), property, obj[key])...)
+			} else if additional != nil {
+				errs = append(errs, validateSchemaValue(joinFieldPath(path, key), additional, obj[key])...)
+			}
+		}
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if key, ok := name.(string); ok {
+				if _, found := obj[key]; !found {
+					errs = append(errs, fmt.Sprintf("%s: required field missing", joinFieldPath(path, key)))
+				}
+			}
+		}
+	case "array":
+		list, ok := value.([]interface{})
+		if !ok {
+			return []string{typeMismatch(path, "array", value)}
+		}
+		if min, ok := schemaNumber(schema, "minItems"); ok && float64(len(list)) < min {
+			errs = append(errs, fmt.Sprintf("%s: should have at least %v items", path, min))
+		}
+		if max, ok := schemaNumber(schema, "maxItems"); ok && float64(len(list)) > max {
+			errs = append(errs, fmt.Sprintf("%s: should have at most %v items", path, max))
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range list {
+				errs = append(errs, validateSchemaValue(fmt.Sprintf("%s[%d]", path, i), items, item)...)
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return []string{typeMismatch(path, "string", value)}
+		}
+		if min, ok := schemaNumber(schema, "minLength"); ok && float64(len(str)) < min {
+			errs = append(errs, fmt.Sprintf("%s: should be at least %v characters long", path, min))
+		}
+		if max, ok := schemaNumber(schema, "maxLength"); ok && float64(len(str)) > max {
+			errs = append(errs, fmt.Sprintf("%s: should be at most %v characters long", path, max))
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(str) {
+				errs = append(errs, fmt.Sprintf("%s: should match %q", path, pattern))
+			}
+		}
+	case "integer", "number":
+		if actual != "integer" && (expected == "integer" || actual != "number") {
+			return []string{typeMismatch(path, expected, value)}
+		}
+		number := toFloat(value)
+		if min, ok := schemaNumber(schema, "minimum"); ok && number < min {
+			errs = append(errs, fmt.Sprintf("%s: should be greater than or equal to %v", path, min))
+		}
+		if max, ok := schemaNumber(schema, "maximum"); ok && number > max {
+			errs = append(errs, fmt.Sprintf("%s: should be less than or equal to %v", path, max))
+		}
+	case "boolean":
+		if actual != "boolean" {
+			return []string{typeMismatch(path, "boolean", value)}
+		}
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && !inEnum(enum, value) {
+		errs = append(errs, fmt.Sprintf("%s: should be one of %v", path, enum))
+	}
+	return errs
+}
+
+func schemaNumber(schema map[string]interface{}, key string) (float64, bool) {
+	value, ok := schema[key]
+	if !ok {
+		return 0, false
+	}
+	return toFloat(value), true
+}
+
+func toFloat(value interface{}) float64 {
+	switch value := value.(type) {
+	case int64:
+		return float64(value)
+	case int32:
+		return float64(value)
+	case int:
+		return float64(value)
+	case float64:
+		return value
+	default:
+		return 0
+	}
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, allowed := range enum {
+		if reflect.DeepEqual(allowed, value) || (jsonType(allowed) == "integer" && jsonType(value) == "integer" && toFloat(allowed) == toFloat(value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// crdSchemaCache caches the OpenAPI schemas of custom resources, read from
+// their CRDs.
+type crdSchemaCache struct {
+	dynClient *dynamicclientset.Clientset
+
+	mutex   sync.Mutex
+	schemas map[string]cachedCRDSchema
+}
+
+type cachedCRDSchema struct {
+	schema  map[string]interface{}
+	fetched time.Time
+}
+
+func (c *crdSchemaCache) get(gvk schema.GroupVersionKind) (map[string]interface{}, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	key := gvk.String()
+	if cached, ok := c.schemas[key]; ok && time.Since(cached.fetched) < crdSchemaTTL {
+		return cached.schema, nil
+	}
+	resource, err := c.dynClient.Kind(gvk.GroupVersion().String(), gvk.Kind)
+	if err != nil {
+		return nil, err
+	}
+	crdClient, err := c.dynClient.Resource("apiextensions.k8s.io/v1", "customresourcedefinitions")
+	if err != nil {
+		return nil, err
+	}
+	crd, err := crdClient.Get(context.TODO(), resource.Name+"."+gvk.Group, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	var crdSchema map[string]interface{}
+	if err == nil {
+		// Kinds without a CRD, like those of aggregated APIs, aren't validated.
+		crdSchema = crdVersionSchema(crd, gvk.Version)
+	}
+	c.schemas[key] = cachedCRDSchema{schema: crdSchema, fetched: time.Now()}
+	return crdSchema, nil
+}
+
+// crdVersionSchema returns the OpenAPI schema of the given version of crd,
+// or nil if it has none.
+func crdVersionSchema(crd *unstructured.Unstructured, version string) map[string]interface{} {
+	versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	for _, v := range versions {
+		versionMap, ok := v.(map[string]interface{})
+		if !ok || versionMap["name"] != version {
+			continue
+		}
+		crdSchema, _, _ := unstructured.NestedMap(versionMap, "schema", "openAPIV3Schema")
+		return crdSchema
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var widgetSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"spec": map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"size"},
+			"properties": map[string]interface{}{
+				"size": map[string]interface{}{
+					"type": "string",
+					"enum": []interface{}{"small", "large"},
+				},
+				"replicas": map[string]interface{}{
+					"type":    "integer",
+					"minimum": int64(1),
+				},
+				"port": map[string]interface{}{
+					"x-kubernetes-int-or-string": true,
+				},
+			},
+		},
+	},
+}
+
+func TestChildValidator_Check(t *testing.T) {
+	validator := &ChildValidator{
+		crdSchema: func(gvk schema.GroupVersionKind) (map[string]interface{}, error) {
+			if gvk.Kind == "Widget" {
+				return widgetSchema, nil
+			}
+			return nil, nil
+		},
+	}
+	parent := &unstructured.Unstructured{}
+	parent.SetNamespace("ns")
+
+	tests := []struct {
+		name     string
+		child    map[string]interface{}
+		expected []string
+	}{
+		{
+			name: "valid built-in",
+			child: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]interface{}{"name": "a", "labels": map[string]interface{}{"app": "a"}},
+				"data":       map[string]interface{}{"key": "value"},
+				"binaryData": map[string]interface{}{"key": "dmFsdWU="},
+			},
+		},
+		{
+			name: "valid built-in with self-decoding fields",
+			child: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Pod",
+				"metadata":   map[string]interface{}{"name": "a"},
+				"spec": map[string]interface{}{
+					"containers": []interface{}{map[string]interface{}{
+						"name":      "main",
+						"resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": "100m"}},
+						"ports":     []interface{}{map[string]interface{}{"containerPort": float64(80)}},
+					}},
+				},
+				"status": map[string]interface{}{"ignored": true},
+			},
+		},
+		{
+			name: "invalid built-in",
+			child: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Pod",
+				"metadata":   map[string]interface{}{"name": "a"},
+				"spec": map[string]interface{}{
+					"containers": []interface{}{map[string]interface{}{
+						"name":  "main",
+						"imag":  "nginx",
+						"ports": []interface{}{map[string]interface{}{"containerPort": "80"}},
+					}},
+				},
+			},
+			expected: []string{
+				"spec.containers[0].imag: unknown field",
+				"spec.containers[0].ports[0].containerPort: expected integer, got string",
+			},
+		},
+		{
+			name: "valid custom resource",
+			child: map[string]interface{}{
+				"apiVersion": "example.com/v1",
+				"kind":       "Widget",
+				"metadata":   map[string]interface{}{"name": "a"},
+				"spec":       map[string]interface{}{"size": "small", "replicas": int64(2), "port": "http", "unknown": true},
+			},
+		},
+		{
+			name: "invalid custom resource",
+			child: map[string]interface{}{
+				"apiVersion": "example.com/v1",
+				"kind":       "Widget",
+				"metadata":   map[string]interface{}{"name": "a"},
+				"spec":       map[string]interface{}{"replicas": 0.5, "port": true},
+			},
+			expected: []string{
+				"spec.port: expected integer or string, got boolean",
+				"spec.replicas: expected integer, got number",
+				"spec.size: required field missing",
+			},
+		},
+		{
+			name: "custom resource not in enum",
+			child: map[string]interface{}{
+				"apiVersion": "example.com/v1",
+				"kind":       "Widget",
+				"metadata":   map[string]interface{}{"name": "a"},
+				"spec":       map[string]interface{}{"size": "medium", "replicas": int64(0)},
+			},
+			expected: []string{
+				"spec.replicas: should be greater than or equal to 1",
+				"spec.size: should be one of [small large]",
+			},
+		},
+		{
+			name: "custom resource without schema",
+			child: map[string]interface{}{
+				"apiVersion": "example.com/v1",
+				"kind":       "Gadget",
+				"metadata":   map[string]interface{}{"name": "a"},
+				"spec":       "anything",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			child := &unstructured.Unstructured{Object: tt.child}
+			err := validator.Check(MakeRelativeObjectMap(parent, []*unstructured.Unstructured{child}))
+			if len(tt.expected) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error")
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(err.Error(), expected) {
+					t.Errorf("expected %q in error %q", expected, err.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestChildValidator_CheckNil(t *testing.T) {
+	var validator *ChildValidator
+	child := &unstructured.Unstructured{Object: map[string]interface{}{"apiVersion": "v1", "kind": "Pod", "bogus": true}}
+	if err := validator.Check(MakeRelativeObjectMap(&unstructured.Unstructured{}, []*unstructured.Unstructured{child})); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
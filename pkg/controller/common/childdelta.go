@@ -0,0 +1,140 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ChildVersions holds the resourceVersion of each child, keyed the same way
+// as a RelativeObjectMap.
+type ChildVersions map[GroupVersionKind]map[string]string
+
+// MakeChildVersions returns the resourceVersions of the given children.
+func MakeChildVersions(children RelativeObjectMap) ChildVersions {
+	versions := make(ChildVersions, len(children))
+	for gvk, group := range children {
+		versions[gvk] = make(map[string]string, len(group))
+		for name, obj := range group {
+			versions[gvk][name] = obj.GetResourceVersion()
+		}
+	}
+	return versions
+}
+
+// Digest returns the hex encoded SHA-256 of the sorted
+// "<Kind>.<apiVersion>/<name>@<resourceVersion>" lines, one per child,
+// each terminated by a newline.
+func (v ChildVersions) Digest() string {
+	var lines []string
+	for gvk, group := range v {
+		key, _ := gvk.MarshalText()
+		for name, version := range group {
+			lines = append(lines, fmt.Sprintf("%s/%s@%s\n", key, name, version))
+		}
+	}
+	sort.Strings(lines)
+	hash := sha256.New()
+	for _, line := range lines {
+		hash.Write([]byte(line))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// ChildDelta returns the children which were added or changed since the
+// given versions were observed, and the names of those which were removed.
+func ChildDelta(children RelativeObjectMap, previous ChildVersions) (RelativeObjectMap, map[GroupVersionKind][]string) {
+	changed := make(RelativeObjectMap)
+	for gvk, group := range children {
+		// Keep every type in the map, so the hook can tell them apart from
+		// types which aren't children.
+		changed[gvk] = make(map[string]*unstructured.Unstructured)
+		for name, obj := range group {
+			if version, ok := previous[gvk][name]; !ok || version != obj.GetResourceVersion() {
+				changed[gvk][name] = obj
+			}
+		}
+	}
+	var removed map[GroupVersionKind][]string
+	for gvk, group := range previous {
+		for name := range group {
+			if _, ok := children[gvk][name]; ok {
+				continue
+			}
+			if removed == nil {
+				removed = make(map[GroupVersionKind][]string)
+			}
+			removed[gvk] = append(removed[gvk], name)
+		}
+	}
+	for _, names := range removed {
+		sort.Strings(names)
+	}
+	return changed, removed
+}
+
+// ChildVersionStore remembers, for each parent, the versions of the children
+// sent by the last sync which was fully applied. Like SyncTokenStore, it's
+// only kept in memory.
+type ChildVersionStore struct {
+	mutex    sync.Mutex
+	versions map[string]childVersions
+}
+
+type childVersions struct {
+	uid      types.UID
+	versions ChildVersions
+}
+
+// NewChildVersionStore returns an empty ChildVersionStore.
+func NewChildVersionStore() *ChildVersionStore {
+	return &ChildVersionStore{versions: make(map[string]childVersions)}
+}
+
+// Get returns the child versions of the parent with the given queue key, or
+// nil if there are none or they were stored for a previous object with the
+// same name.
+func (s *ChildVersionStore) Get(key string, uid types.UID) ChildVersions {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	stored, ok := s.versions[key]
+	if !ok || stored.uid != uid {
+		return nil
+	}
+	return stored.versions
+}
+
+// Set stores the child versions of a parent.
+func (s *ChildVersionStore) Set(key string, uid types.UID, versions ChildVersions) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.versions[key] = childVersions{uid: uid, versions: versions}
+}
+
+// Forget removes the child versions of a parent, e.g. once it's deleted.
+func (s *ChildVersionStore) Forget(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.versions, key)
+}
@@ -0,0 +1,61 @@
+package common
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func newChild(name, resourceVersion string) *unstructured.Unstructured {
+	child := &unstructured.Unstructured{}
+	child.SetAPIVersion("v1")
+	child.SetKind("Pod")
+	child.SetName(name)
+	child.SetResourceVersion(resourceVersion)
+	return child
+}
+
+func TestChildDelta(t *testing.T) {
+	pods := GroupVersionKind{schema.GroupVersionKind{Version: "v1", Kind: "Pod"}}
+	previous := ChildVersions{pods: {"unchanged": "1", "changed": "1", "removed": "1"}}
+	children := RelativeObjectMap{pods: {
+		"unchanged": newChild("unchanged", "1"),
+		"changed":   newChild("changed", "2"),
+		"added":     newChild("added", "1"),
+	}}
+
+	changed, removed := ChildDelta(children, previous)
+
+	if diff := cmp.Diff([]string{"added", "changed"}, sortedNames(changed[pods])); diff != "" {
+		t.Errorf("unexpected changed children (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[GroupVersionKind][]string{pods: {"removed"}}, removed); diff != "" {
+		t.Errorf("unexpected removed children (-want +got):\n%s", diff)
+	}
+}
+
+func TestChildVersions_Digest(t *testing.T) {
+	pods := GroupVersionKind{schema.GroupVersionKind{Version: "v1", Kind: "Pod"}}
+	versions := ChildVersions{pods: {"a": "1", "b": "2"}}
+
+	// sha256 of "Pod.v1/a@1\nPod.v1/b@2\n"
+	expected := "5af6ac32161cf704b52977a2c063e8a3f81f1406b00cf1a82c7f16c6415f60b1"
+	if digest := versions.Digest(); digest != expected {
+		t.Errorf("expected %s, got: %s", expected, digest)
+	}
+	if MakeChildVersions(RelativeObjectMap{pods: {"a": newChild("a", "1"), "b": newChild("b", "3")}}).Digest() == expected {
+		t.Errorf("expected digest to change with a resourceVersion")
+	}
+}
+
+func sortedNames(group map[string]*unstructured.Unstructured) []string {
+	var names []string
+	for name := range group {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
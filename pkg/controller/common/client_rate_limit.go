@@ -0,0 +1,95 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"math"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	"metacontroller/pkg/options"
+)
+
+// clientRateLimits returns the rate limits of the clientset shared by
+// controllers, which default to those of the REST config.
+func clientRateLimits(configuration options.Configuration) dynamicclientset.RateLimits {
+	limits := dynamicclientset.RateLimits{
+		QPS:         configuration.DynamicClientQPS,
+		Burst:       configuration.DynamicClientBurst,
+		StatusQPS:   configuration.StatusClientQPS,
+		StatusBurst: configuration.StatusClientBurst,
+	}
+	if limits.QPS <= 0 {
+		limits.QPS = configuration.RestConfig.QPS
+	}
+	if limits.Burst <= 0 {
+		limits.Burst = configuration.RestConfig.Burst
+	}
+	if limits.StatusBurst <= 0 {
+		limits.StatusBurst = int(math.Ceil(float64(limits.StatusQPS)))
+	}
+	return limits
+}
+
+// ControllerClientset returns the clientset a controller uses to write
+// objects: dynClient, shared by all controllers, or a copy of it with the own
+// rate limits of the controller, if it has some.
+func ControllerClientset(dynClient *dynamicclientset.Clientset, rateLimit *v1alpha1.ClientRateLimit) (*dynamicclientset.Clientset, error) {
+	if rateLimit == nil {
+		return dynClient, nil
+	}
+	limits, err := controllerRateLimits(rateLimit)
+	if err != nil {
+		return nil, err
+	}
+	return dynClient.WithRateLimits(limits)
+}
+
+// controllerRateLimits validates the rate limits of a controller. Without a
+// status budget, StatusQPS is left to zero: the status writes then share the
+// budget of the other requests, rather than getting one of the same size,
+// which would double the rate the controller is allowed.
+func controllerRateLimits(rateLimit *v1alpha1.ClientRateLimit) (dynamicclientset.RateLimits, error) {
+	limits := dynamicclientset.RateLimits{}
+	var err error
+	limits.QPS, limits.Burst, err = clientRateBudget(rateLimit.QPS, rateLimit.Burst)
+	if err != nil {
+		return dynamicclientset.RateLimits{}, fmt.Errorf("invalid client rate limit: %w", err)
+	}
+	if rateLimit.Status != nil {
+		limits.StatusQPS, limits.StatusBurst, err = clientRateBudget(rateLimit.Status.QPS, rateLimit.Status.Burst)
+		if err != nil {
+			return dynamicclientset.RateLimits{}, fmt.Errorf("invalid client rate limit for status updates: %w", err)
+		}
+	}
+	return limits, nil
+}
+
+// clientRateBudget validates a rate limit, whose burst defaults to its qps.
+func clientRateBudget(qps int32, burst *int32) (float32, int, error) {
+	if qps <= 0 {
+		return 0, 0, fmt.Errorf("'qps' must be positive")
+	}
+	if burst == nil {
+		return float32(qps), int(qps), nil
+	}
+	if *burst <= 0 {
+		return 0, 0, fmt.Errorf("'burst' must be positive")
+	}
+	return float32(qps), int(*burst), nil
+}
@@ -0,0 +1,89 @@
+package common
+
+import (
+	"testing"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/utils/pointer"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	"metacontroller/pkg/options"
+)
+
+func TestControllerClientset(t *testing.T) {
+	dynClient, err := dynamicclientset.New(&rest.Config{Host: "https://example.com"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shared, err := ControllerClientset(dynClient, nil)
+	if err != nil || shared != dynClient {
+		t.Errorf("expected the shared clientset without rate limit, got %v, %v", shared, err)
+	}
+
+	own, err := ControllerClientset(dynClient, &v1alpha1.ClientRateLimit{
+		QPS:    20,
+		Status: &v1alpha1.ClientRateBudget{QPS: 5, Burst: pointer.Int32Ptr(10)},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if own == dynClient {
+		t.Errorf("expected a clientset with its own rate limits")
+	}
+}
+
+func TestControllerRateLimits(t *testing.T) {
+	limits, err := controllerRateLimits(&v1alpha1.ClientRateLimit{QPS: 20})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := dynamicclientset.RateLimits{QPS: 20, Burst: 20}
+	if limits != expected {
+		t.Errorf("expected the status writes to share the budget %+v, got %+v", expected, limits)
+	}
+
+	limits, err = controllerRateLimits(&v1alpha1.ClientRateLimit{
+		QPS:    20,
+		Burst:  pointer.Int32Ptr(40),
+		Status: &v1alpha1.ClientRateBudget{QPS: 5},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = dynamicclientset.RateLimits{QPS: 20, Burst: 40, StatusQPS: 5, StatusBurst: 5}
+	if limits != expected {
+		t.Errorf("expected %+v, got %+v", expected, limits)
+	}
+}
+
+func TestControllerClientset_Invalid(t *testing.T) {
+	for name, rateLimit := range map[string]*v1alpha1.ClientRateLimit{
+		"qps":          {QPS: 0},
+		"burst":        {QPS: 1, Burst: pointer.Int32Ptr(0)},
+		"status qps":   {QPS: 1, Status: &v1alpha1.ClientRateBudget{QPS: -1}},
+		"status burst": {QPS: 1, Status: &v1alpha1.ClientRateBudget{QPS: 1, Burst: pointer.Int32Ptr(-1)}},
+	} {
+		if _, err := ControllerClientset(&dynamicclientset.Clientset{}, rateLimit); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestClientRateLimits(t *testing.T) {
+	configuration := options.Configuration{RestConfig: &rest.Config{QPS: 5, Burst: 10}}
+	limits := clientRateLimits(configuration)
+	expected := dynamicclientset.RateLimits{QPS: 5, Burst: 10}
+	if limits != expected {
+		t.Errorf("expected the limits of the REST config %+v, got %+v", expected, limits)
+	}
+
+	configuration.DynamicClientQPS = 50
+	configuration.StatusClientQPS = 2.5
+	limits = clientRateLimits(configuration)
+	expected = dynamicclientset.RateLimits{QPS: 50, Burst: 10, StatusQPS: 2.5, StatusBurst: 3}
+	if limits != expected {
+		t.Errorf("expected %+v, got %+v", expected, limits)
+	}
+}
@@ -19,6 +19,7 @@ package common
 import (
 	"fmt"
 	"metacontroller/pkg/logging"
+	"sort"
 	"strings"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -66,8 +67,20 @@ const (
 	FinalizeHook        HookType       = "finalize"
 	CustomizeHook       HookType       = "customize"
 	SyncHook            HookType       = "sync"
+	EventsHook          HookType       = "events"
+	ApplyErrorHook      HookType       = "applyError"
+	ObserveHook         HookType       = "observe"
+	DiffHook            HookType       = "diff"
+	CreateHook          HookType       = "create"
+	UpdateHook          HookType       = "update"
+	DeleteHook          HookType       = "delete"
 	CompositeController ControllerType = "CompositeController"
 	DecoratorController ControllerType = "DecoratorController"
+	GlobalController    ControllerType = "GlobalController"
+	CronController      ControllerType = "CronController"
+
+	ExternalResourceController ControllerType = "ExternalResourceController"
+	StateMachineController     ControllerType = "StateMachineController"
 )
 
 func (h HookType) String() string {
@@ -89,7 +102,10 @@ type ControllerContext struct {
 	McClient          mcclientset.Interface
 	EventRecorder     record.EventRecorder
 	Broadcaster       record.EventBroadcaster
-	configuration     options.Configuration
+	// SyncLoops are the limits above which controllers back off the objects
+	// they sync in a hot loop.
+	SyncLoops     SyncLoopLimits
+	configuration options.Configuration
 }
 
 // NewControllerContext creates a new ControllerContext using given Configuration and metacontroller client
@@ -101,12 +117,17 @@ func NewControllerContext(configuration options.Configuration, mcClient *mcclien
 	mcInformerFactory := mcinformers.NewSharedInformerFactory(mcClient, configuration.InformerRelist)
 
 	// Create dynamic clientset (factory for dynamic clients).
-	dynClient, err := dynamicclientset.New(configuration.RestConfig, resources)
+	dynClient, err := dynamicclientset.NewWithRateLimits(configuration.RestConfig, resources, clientRateLimits(configuration))
 	if err != nil {
 		return nil, err
 	}
 	// Create dynamic informer factory (for sharing dynamic informers).
 	dynInformers := dynamicinformer.NewSharedInformerFactory(dynClient, configuration.InformerRelist)
+	if configuration.StripManagedFields {
+		dynInformers.SetTransform(dynamicinformer.StripManagedFields)
+	}
+	dynInformers.SetIdleTimeout(configuration.InformerIdleTimeout)
+	dynInformers.SetListPageSize(configuration.InformerListPageSize)
 
 	// Start metacontrollers (controllers that spawn controllers).
 	// Each one requests the informers it needs from the factory.
@@ -123,6 +144,7 @@ func NewControllerContext(configuration options.Configuration, mcClient *mcclien
 		McInformerFactory: mcInformerFactory,
 		EventRecorder:     recorder,
 		Broadcaster:       broadcaster,
+		SyncLoops:         SyncLoopLimits{Syncs: configuration.SyncLoopSyncs, Window: configuration.SyncLoopWindow},
 		configuration:     configuration,
 	}, nil
 }
@@ -222,11 +244,11 @@ func (m RelativeObjectMap) FindGroupKindName(gk schema.GroupKind, name string) *
 }
 
 // relativeName returns the name of the object relative to the parent.
-// If the parent is cluster scoped and the object namespaced scoped the
-// name is of the format <namespace>/<name>. Otherwise the name of the object
-// is returned.
+// If the parent is cluster scoped and the object namespaced scoped, or the
+// object is in another namespace than the parent, the name is of the format
+// <namespace>/<name>. Otherwise the name of the object is returned.
 func relativeName(parent metav1.Object, obj *unstructured.Unstructured) string {
-	if parent.GetNamespace() == "" && obj.GetNamespace() != "" {
+	if (parent.GetNamespace() == "" && obj.GetNamespace() != "") || IsCrossNamespace(parent, obj.GetNamespace()) {
 		return fmt.Sprintf("%s/%s", obj.GetNamespace(), obj.GetName())
 	}
 	return obj.GetName()
@@ -275,8 +297,8 @@ func (m RelativeObjectMap) List() []*unstructured.Unstructured {
 // This function returns a RelativeObjectMap which is a map of maps. The outer most map
 // is keyed  using the object's type and the inner map is keyed using the
 // object's name. If the parent resource is clustered and the object resource
-// is namespaced the inner map's keys are prefixed by the namespace of the
-// object resource.
+// is namespaced, or the object is in another namespace than the parent, the
+// inner map's keys are prefixed by the namespace of the object resource.
 //
 // This function requires parent resources has the meta.Namespace accurately
 // set. If the namespace of the parent is empty it's considered a clustered
@@ -322,6 +344,36 @@ func (m InformerMap) Get(gvr schema.GroupVersionResource) *dynamicinformer.Resou
 	return m[gvr]
 }
 
+// Informers returns the informers of m.
+func (m InformerMap) Informers() []*dynamicinformer.ResourceInformer {
+	informers := make([]*dynamicinformer.ResourceInformer, 0, len(m))
+	for _, informer := range m {
+		informers = append(informers, informer)
+	}
+	return informers
+}
+
+// WatchStates returns the state of the watches of the given informers, sorted
+// by resource. Nil informers are skipped.
+func WatchStates(informers ...*dynamicinformer.ResourceInformer) []dynamicinformer.WatchState {
+	states := make([]dynamicinformer.WatchState, 0, len(informers))
+	for _, informer := range informers {
+		if informer != nil {
+			states = append(states, informer.WatchState())
+		}
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].APIVersion != states[j].APIVersion {
+			return states[i].APIVersion < states[j].APIVersion
+		}
+		if states[i].Resource != states[j].Resource {
+			return states[i].Resource < states[j].Resource
+		}
+		return states[i].Selectors < states[j].Selectors
+	})
+	return states
+}
+
 // GetObject return object via Lister from given informer, namespaced or not.
 func GetObject(informer *dynamicinformer.ResourceInformer, namespace, name string) (*unstructured.Unstructured, error) {
 	if namespace == "" {
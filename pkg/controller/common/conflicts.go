@@ -0,0 +1,144 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+)
+
+// ChildConflictCondition is the parent status condition type set while some
+// desired children are owned by someone else.
+const ChildConflictCondition = "ChildConflict"
+
+// ChildConflict is a desired child which already exists, but is owned by
+// another parent or controller.
+type ChildConflict struct {
+	// Child is the existing object.
+	Child *unstructured.Unstructured
+	// Owner describes the other owner of Child.
+	Owner string
+}
+
+// Message describes the conflict, naming both claimants.
+func (c ChildConflict) Message(parent *unstructured.Unstructured) string {
+	return fmt.Sprintf("%v is desired by %v but owned by %v", describeObject(c.Child), describeObject(parent), c.Owner)
+}
+
+// FindConflicts removes from desired the children which aren't observed, but
+// already exist and are owned by someone other than parent, and returns them.
+// get returns the existing child of a kind with the given namespace and name,
+// or nil if there's none.
+func FindConflicts(parent *unstructured.Unstructured, observed, desired RelativeObjectMap, get func(key GroupVersionKind, namespace, name string) *unstructured.Unstructured) []ChildConflict {
+	var conflicts []ChildConflict
+	for key, group := range desired {
+		for name, obj := range group {
+			if observed[key][name] != nil {
+				continue
+			}
+			namespace := obj.GetNamespace()
+			if namespace == "" {
+				namespace = parent.GetNamespace()
+			}
+			existing := get(key, namespace, obj.GetName())
+			if existing == nil {
+				continue
+			}
+			if owner, found := otherOwner(parent, existing); found {
+				conflicts = append(conflicts, ChildConflict{Child: existing, Owner: owner})
+				delete(group, name)
+			}
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return describeObject(conflicts[i].Child) < describeObject(conflicts[j].Child)
+	})
+	return conflicts
+}
+
+// otherOwner describes the owner of obj, through its controllerRef or labels,
+// if it isn't parent.
+func otherOwner(parent, obj *unstructured.Unstructured) (string, bool) {
+	if controllerRef := metav1.GetControllerOf(obj); controllerRef != nil {
+		if controllerRef.UID == parent.GetUID() {
+			return "", false
+		}
+		// An ownerReference points to an owner in the same namespace.
+		owner := &unstructured.Unstructured{}
+		owner.SetKind(controllerRef.Kind)
+		owner.SetNamespace(obj.GetNamespace())
+		owner.SetName(controllerRef.Name)
+		return fmt.Sprintf("%v (uid %v)", describeObject(owner), controllerRef.UID), true
+	}
+	if namespace, name, uid, found := LabelOwnerOf(obj); found && uid != parent.GetUID() {
+		// Labels don't record the kind of the owner.
+		if namespace != "" {
+			name = namespace + "/" + name
+		}
+		return fmt.Sprintf("parent %v (uid %v)", name, uid), true
+	}
+	return "", false
+}
+
+// SetChildConflictCondition sets the ChildConflict condition in status if
+// there are conflicts, and removes it otherwise.
+// It returns the updated status, which is only allocated if needed.
+func SetChildConflictCondition(status map[string]interface{}, parent *unstructured.Unstructured, conflicts []ChildConflict) map[string]interface{} {
+	if len(conflicts) == 0 {
+		return setCondition(status, ChildConflictCondition, nil)
+	}
+	messages := make([]string, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		messages = append(messages, conflict.Message(parent))
+	}
+	return setCondition(status, ChildConflictCondition, map[string]interface{}{
+		"status":  "True",
+		"reason":  "ChildOwnedByOther",
+		"message": strings.Join(messages, "; "),
+	})
+}
+
+// CachedChildGetter returns a get func for FindConflicts, which reads children
+// from the given informers, whatever version they're requested in.
+func CachedChildGetter(dynClient *dynamicclientset.Clientset, informers InformerMap) func(key GroupVersionKind, namespace, name string) *unstructured.Unstructured {
+	return func(key GroupVersionKind, namespace, name string) *unstructured.Unstructured {
+		client, err := dynClient.Kind(key.GroupVersion().String(), key.Kind)
+		if err != nil {
+			return nil
+		}
+		if !client.Namespaced {
+			namespace = ""
+		}
+		for gvr, informer := range informers {
+			if gvr.Group != client.Group || gvr.Resource != client.Name {
+				continue
+			}
+			obj, err := GetObject(informer, namespace, name)
+			if err != nil {
+				return nil
+			}
+			return obj
+		}
+		return nil
+	}
+}
@@ -0,0 +1,99 @@
+package common
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/pointer"
+)
+
+func TestFindConflicts(t *testing.T) {
+	parent := &unstructured.Unstructured{}
+	parent.SetKind("App")
+	parent.SetNamespace("ns")
+	parent.SetName("app")
+	parent.SetUID("app-uid")
+
+	newChild := func(name string) *unstructured.Unstructured {
+		child := &unstructured.Unstructured{}
+		child.SetAPIVersion("v1")
+		child.SetKind("Secret")
+		child.SetNamespace("ns")
+		child.SetName(name)
+		return child
+	}
+	ownedByOther := newChild("by-reference")
+	ownedByOther.SetOwnerReferences([]metav1.OwnerReference{{Kind: "Other", Name: "other", UID: "other-uid", Controller: pointer.BoolPtr(true)}})
+	labelOwnedByOther := newChild("by-labels")
+	SetLabelOwner(&metav1.ObjectMeta{Namespace: "elsewhere", Name: "other", UID: "other-uid"}, labelOwnedByOther)
+	orphan := newChild("orphan")
+	existing := map[string]*unstructured.Unstructured{
+		"by-reference": ownedByOther,
+		"by-labels":    labelOwnedByOther,
+		"orphan":       orphan,
+	}
+
+	observed := MakeRelativeObjectMap(parent, []*unstructured.Unstructured{newChild("observed")})
+	desired := MakeRelativeObjectMap(parent, []*unstructured.Unstructured{
+		newChild("observed"), newChild("new"), newChild("by-reference"), newChild("by-labels"), newChild("orphan"),
+	})
+
+	conflicts := FindConflicts(parent, observed, desired, func(key GroupVersionKind, namespace, name string) *unstructured.Unstructured {
+		if namespace != "ns" {
+			t.Errorf("expected namespace ns, got %q", namespace)
+		}
+		return existing[name]
+	})
+
+	var messages []string
+	for _, conflict := range conflicts {
+		messages = append(messages, conflict.Message(parent))
+	}
+	expected := []string{
+		"Secret ns/by-labels is desired by App ns/app but owned by parent elsewhere/other (uid other-uid)",
+		"Secret ns/by-reference is desired by App ns/app but owned by Other ns/other (uid other-uid)",
+	}
+	if diff := cmp.Diff(expected, messages); diff != "" {
+		t.Errorf("unexpected conflicts (-want +got):\n%s", diff)
+	}
+	var remaining []string
+	for _, group := range desired {
+		for _, obj := range group {
+			remaining = append(remaining, obj.GetName())
+		}
+	}
+	sort.Strings(remaining)
+	if diff := cmp.Diff([]string{"new", "observed", "orphan"}, remaining); diff != "" {
+		t.Errorf("unexpected desired children (-want +got):\n%s", diff)
+	}
+}
+
+func TestSetChildConflictCondition(t *testing.T) {
+	parent := &unstructured.Unstructured{}
+	parent.SetKind("App")
+	parent.SetName("app")
+	child := &unstructured.Unstructured{}
+	child.SetKind("ClusterRole")
+	child.SetName("role")
+	conflicts := []ChildConflict{{Child: child, Owner: "parent other (uid other-uid)"}}
+
+	status := SetChildConflictCondition(nil, parent, conflicts)
+
+	conditions, _, _ := unstructured.NestedSlice(status, "conditions")
+	if len(conditions) != 1 {
+		t.Fatalf("expected 1 condition, got %v", conditions)
+	}
+	condition := conditions[0].(map[string]interface{})
+	if condition["message"] != "ClusterRole role is desired by App app but owned by parent other (uid other-uid)" {
+		t.Errorf("unexpected message %q", condition["message"])
+	}
+
+	status = SetChildConflictCondition(status, parent, nil)
+
+	if conditions, _, _ := unstructured.NestedSlice(status, "conditions"); len(conditions) != 0 {
+		t.Errorf("expected condition to be removed, got %v", conditions)
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+// ApplyControllerGroup fills in the settings of the webhooks of a controller
+// from the ControllerGroup it references, unless the controller sets them
+// itself. The hooks, rateLimit and hookTransport are changed in place.
+func ApplyControllerGroup(
+	ctx context.Context,
+	k8sClient client.Client,
+	ref *v1alpha1.ControllerGroupReference,
+	hooks []*v1alpha1.Hook,
+	rateLimit **v1alpha1.HookRateLimit,
+	hookTransport **v1alpha1.HookTransport) error {
+	if ref == nil {
+		return nil
+	}
+	group := v1alpha1.ControllerGroup{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: ref.Name}, &group); err != nil {
+		return fmt.Errorf("can't get ControllerGroup %s: %w", ref.Name, err)
+	}
+	if *rateLimit == nil {
+		*rateLimit = group.Spec.RateLimit
+	}
+	if *hookTransport == nil {
+		*hookTransport = group.Spec.HookTransport
+	}
+	if group.Spec.Webhook == nil {
+		return nil
+	}
+	for _, hook := range hooks {
+		if hook != nil && hook.Webhook != nil {
+			applyGroupWebhook(hook.Webhook, group.Spec.Webhook)
+		}
+	}
+	return nil
+}
+
+func applyGroupWebhook(webhook *v1alpha1.Webhook, defaults *v1alpha1.ControllerGroupWebhook) {
+	if webhook.URL == nil && webhook.Service == nil && webhook.Path != nil {
+		if defaults.BaseURL != nil {
+			url := strings.TrimSuffix(*defaults.BaseURL, "/") + *webhook.Path
+			webhook.URL = &url
+		} else {
+			webhook.Service = defaults.Service
+		}
+	}
+	if webhook.Timeout == nil {
+		webhook.Timeout = defaults.Timeout
+	}
+	if webhook.ConnectTimeout == nil {
+		webhook.ConnectTimeout = defaults.ConnectTimeout
+	}
+	if webhook.TLSHandshakeTimeout == nil {
+		webhook.TLSHandshakeTimeout = defaults.TLSHandshakeTimeout
+	}
+	if webhook.Proxy == nil {
+		webhook.Proxy = defaults.Proxy
+	}
+	if webhook.CABundle == nil {
+		webhook.CABundle = defaults.CABundle
+	}
+	if webhook.Signing == nil {
+		webhook.Signing = defaults.Signing
+	}
+	if webhook.Compression == nil {
+		webhook.Compression = defaults.Compression
+	}
+}
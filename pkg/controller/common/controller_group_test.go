@@ -0,0 +1,84 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+func TestApplyControllerGroup(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	group := &v1alpha1.ControllerGroup{
+		ObjectMeta: metav1.ObjectMeta{Name: "platform"},
+		Spec: v1alpha1.ControllerGroupSpec{
+			Webhook: &v1alpha1.ControllerGroupWebhook{
+				BaseURL: pointer.StringPtr("https://hooks.platform.svc/"),
+				Timeout: &metav1.Duration{Duration: 30 * time.Second},
+				Proxy:   pointer.StringPtr("http://proxy:3128"),
+			},
+			RateLimit: &v1alpha1.HookRateLimit{QPS: 5},
+		},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(group).Build()
+	ownTimeout := &metav1.Duration{Duration: time.Second}
+	sync := &v1alpha1.Hook{Webhook: &v1alpha1.Webhook{Path: pointer.StringPtr("/sync"), Timeout: ownTimeout}}
+	finalize := &v1alpha1.Hook{Webhook: &v1alpha1.Webhook{URL: pointer.StringPtr("http://other/finalize")}}
+	exec := &v1alpha1.Hook{Exec: &v1alpha1.ExecHook{Command: []string{"customize"}}}
+	var rateLimit *v1alpha1.HookRateLimit
+	var hookTransport *v1alpha1.HookTransport
+
+	err := ApplyControllerGroup(context.Background(), k8sClient, &v1alpha1.ControllerGroupReference{Name: "platform"},
+		[]*v1alpha1.Hook{sync, finalize, exec, nil}, &rateLimit, &hookTransport)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sync.Webhook.URL == nil || *sync.Webhook.URL != "https://hooks.platform.svc/sync" {
+		t.Errorf("expected sync URL from the group base URL, got %v", sync.Webhook.URL)
+	}
+	if sync.Webhook.Timeout != ownTimeout {
+		t.Errorf("expected sync hook to keep its own timeout, got %v", sync.Webhook.Timeout)
+	}
+	if *finalize.Webhook.URL != "http://other/finalize" {
+		t.Errorf("expected finalize hook to keep its own URL, got %v", *finalize.Webhook.URL)
+	}
+	if finalize.Webhook.Proxy == nil || *finalize.Webhook.Proxy != "http://proxy:3128" {
+		t.Errorf("expected finalize proxy from the group, got %v", finalize.Webhook.Proxy)
+	}
+	if exec.Webhook != nil {
+		t.Errorf("expected exec hook to be unchanged, got %v", exec.Webhook)
+	}
+	if rateLimit == nil || rateLimit.QPS != 5 {
+		t.Errorf("expected rate limit from the group, got %v", rateLimit)
+	}
+	if hookTransport != nil {
+		t.Errorf("expected no hook transport, got %v", hookTransport)
+	}
+}
+
+func TestApplyControllerGroup_Missing(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	var rateLimit *v1alpha1.HookRateLimit
+	var hookTransport *v1alpha1.HookTransport
+
+	err := ApplyControllerGroup(context.Background(), k8sClient, &v1alpha1.ControllerGroupReference{Name: "missing"},
+		nil, &rateLimit, &hookTransport)
+
+	if err == nil {
+		t.Errorf("expected an error for a missing ControllerGroup")
+	}
+}
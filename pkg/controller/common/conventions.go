@@ -0,0 +1,151 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+// ReadyCondition is the parent status condition type computed from the
+// readyExpression of the status conventions.
+const ReadyCondition = "Ready"
+
+// StatusConventions manages the conventional parts of parent statuses, so
+// hooks don't have to. A nil *StatusConventions leaves statuses as they are.
+type StatusConventions struct {
+	ready      cel.Program
+	syncStatus bool
+}
+
+// NewStatusConventions compiles the readyExpression of conventions, which can
+// use the `parent` and `children` variables. It returns nil if conventions
+// is nil.
+func NewStatusConventions(conventions *v1alpha1.StatusConventions) (*StatusConventions, error) {
+	if conventions == nil {
+		return nil, nil
+	}
+	c := &StatusConventions{syncStatus: conventions.SyncStatus != nil && *conventions.SyncStatus}
+	if conventions.ReadyExpression == "" {
+		return c, nil
+	}
+	env, err := cel.NewEnv(cel.Declarations(
+		decls.NewVar("parent", decls.Dyn),
+		decls.NewVar("children", decls.Dyn),
+	))
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(conventions.ReadyExpression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid readyExpression: %w", issues.Err())
+	}
+	c.ready, err = env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid readyExpression: %w", err)
+	}
+	return c, nil
+}
+
+// Apply merges the conditions of status into those of oldStatus by type, and
+// sets the Ready condition from the readyExpression evaluated over the given
+// variables. Conditions missing from status are kept, and the
+// lastTransitionTime of a condition is kept as long as its status doesn't
+// change. It returns the updated status, which is only allocated if needed.
+func (c *StatusConventions) Apply(oldStatus, status map[string]interface{}, variables map[string]interface{}) map[string]interface{} {
+	if c == nil {
+		return status
+	}
+	if status == nil {
+		status = make(map[string]interface{})
+	}
+	now := metav1.Now().UTC().Format(time.RFC3339)
+	oldConditions, _ := oldStatus["conditions"].([]interface{})
+	newConditions, _ := status["conditions"].([]interface{})
+	conditions := mergeConditions(oldConditions, newConditions, now)
+	if c.ready != nil {
+		conditions = mergeConditions(conditions, []interface{}{c.readyCondition(variables)}, now)
+	}
+	if conditions != nil {
+		status["conditions"] = conditions
+	}
+	return status
+}
+
+// readyCondition evaluates the readyExpression. Evaluation failures are
+// reported with an Unknown status rather than failing the sync.
+func (c *StatusConventions) readyCondition(variables map[string]interface{}) map[string]interface{} {
+	out, _, err := c.ready.Eval(variables)
+	if err == nil {
+		if ready, ok := out.Value().(bool); ok && ready {
+			return map[string]interface{}{"type": ReadyCondition, "status": "True", "reason": "ReadyExpressionTrue", "message": ""}
+		} else if ok {
+			return map[string]interface{}{"type": ReadyCondition, "status": "False", "reason": "ReadyExpressionFalse", "message": ""}
+		}
+		err = fmt.Errorf("readyExpression returned %T instead of a bool", out.Value())
+	}
+	return map[string]interface{}{"type": ReadyCondition, "status": "Unknown", "reason": "ReadyExpressionFailed", "message": err.Error()}
+}
+
+// mergeConditions returns oldConditions where each condition is replaced by
+// the one of newConditions with the same type, if any, followed by the
+// conditions of new types.
+func mergeConditions(oldConditions, newConditions []interface{}, now string) []interface{} {
+	if len(newConditions) == 0 {
+		return oldConditions
+	}
+	merged := make([]interface{}, 0, len(oldConditions)+len(newConditions))
+	index := make(map[string]int, len(oldConditions))
+	for _, condition := range oldConditions {
+		if c, ok := condition.(map[string]interface{}); ok {
+			if conditionType, ok := c["type"].(string); ok {
+				index[conditionType] = len(merged)
+			}
+		}
+		merged = append(merged, condition)
+	}
+	for _, condition := range newConditions {
+		c, _ := condition.(map[string]interface{})
+		conditionType, ok := c["type"].(string)
+		if !ok {
+			merged = append(merged, condition)
+			continue
+		}
+		i, found := index[conditionType]
+		if c["lastTransitionTime"] == nil {
+			c["lastTransitionTime"] = now
+			if found {
+				if previous, ok := merged[i].(map[string]interface{}); ok && previous["status"] == c["status"] && previous["lastTransitionTime"] != nil {
+					c["lastTransitionTime"] = previous["lastTransitionTime"]
+				}
+			}
+		}
+		if found {
+			merged[i] = c
+		} else {
+			index[conditionType] = len(merged)
+			merged = append(merged, c)
+		}
+	}
+	return merged
+}
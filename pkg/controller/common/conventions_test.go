@@ -0,0 +1,80 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+func TestStatusConventions_Apply(t *testing.T) {
+	conventions, err := NewStatusConventions(&v1alpha1.StatusConventions{
+		ReadyExpression: `children["Pod.v1"].all(name, children["Pod.v1"][name].status.phase == "Running")`,
+	})
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	pod := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{"phase": "Running"},
+	}}
+	pod.SetAPIVersion("v1")
+	pod.SetKind("Pod")
+	pod.SetName("a")
+	oldStatus := map[string]interface{}{
+		"conditions": []interface{}{
+			map[string]interface{}{"type": "Synced", "status": "True", "lastTransitionTime": "2021-01-01T00:00:00Z"},
+			map[string]interface{}{"type": "Degraded", "status": "True", "lastTransitionTime": "2021-01-01T00:00:00Z"},
+			map[string]interface{}{"type": ReadyCondition, "status": "True", "reason": "ReadyExpressionTrue", "message": "", "lastTransitionTime": "2021-01-01T00:00:00Z"},
+		},
+	}
+	status := map[string]interface{}{
+		"replicas": int64(1),
+		"conditions": []interface{}{
+			map[string]interface{}{"type": "Degraded", "status": "True"},
+		},
+	}
+
+	status = conventions.Apply(oldStatus, status, map[string]interface{}{
+		"children": RelativeObjectMapVariable(MakeRelativeObjectMap(&unstructured.Unstructured{}, []*unstructured.Unstructured{pod})),
+	})
+
+	expected := map[string]interface{}{
+		"replicas": int64(1),
+		"conditions": []interface{}{
+			map[string]interface{}{"type": "Synced", "status": "True", "lastTransitionTime": "2021-01-01T00:00:00Z"},
+			map[string]interface{}{"type": "Degraded", "status": "True", "lastTransitionTime": "2021-01-01T00:00:00Z"},
+			map[string]interface{}{"type": ReadyCondition, "status": "True", "reason": "ReadyExpressionTrue", "message": "", "lastTransitionTime": "2021-01-01T00:00:00Z"},
+		},
+	}
+	if diff := cmp.Diff(expected, status); diff != "" {
+		t.Errorf("unexpected status (-want +got):\n%s", diff)
+	}
+}
+
+func TestStatusConventions_Apply_whenExpressionFails_setReadyUnknown(t *testing.T) {
+	conventions, err := NewStatusConventions(&v1alpha1.StatusConventions{ReadyExpression: `parent.status.replicas > 0`})
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	status := conventions.Apply(nil, nil, map[string]interface{}{"parent": map[string]interface{}{}})
+
+	conditions, _, _ := unstructured.NestedSlice(status, "conditions")
+	if len(conditions) != 1 {
+		t.Fatalf("expected a single condition, got: %v", conditions)
+	}
+	if condition := conditions[0].(map[string]interface{}); condition["status"] != "Unknown" || condition["reason"] != "ReadyExpressionFailed" {
+		t.Errorf("expected Unknown Ready condition, got: %v", condition)
+	}
+}
+
+func TestStatusConventions_Apply_whenNil_returnStatus(t *testing.T) {
+	var conventions *StatusConventions
+	status := map[string]interface{}{"conditions": []interface{}{}}
+
+	if diff := cmp.Diff(status, conventions.Apply(map[string]interface{}{"a": "b"}, status, nil)); diff != "" {
+		t.Errorf("unexpected status (-want +got):\n%s", diff)
+	}
+}
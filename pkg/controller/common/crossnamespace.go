@@ -0,0 +1,236 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+// Children which can't have an ownerReference to their parent, since they're in
+// another namespace or cluster-scoped, record it with these labels instead. The parent name is an annotation, since it may be
+// longer than a label value.
+const (
+	OwnerUIDLabel       = "metacontroller.k8s.io/owner-uid"
+	OwnerNamespaceLabel = "metacontroller.k8s.io/owner-namespace"
+	OwnerNameAnnotation = "metacontroller.k8s.io/owner-name"
+)
+
+// IsCrossNamespace returns true if a child in the given namespace can't be
+// owned by parent through an ownerReference, since those can't point to a
+// namespaced owner in another namespace.
+func IsCrossNamespace(parent metav1.Object, namespace string) bool {
+	return parent.GetNamespace() != "" && namespace != "" && namespace != parent.GetNamespace()
+}
+
+// canOwnByReference returns true if parent can own a child of a resource with
+// the given scope, in the given namespace, through an ownerReference.
+// Cluster-scoped children can't have an ownerReference to a namespaced parent.
+func canOwnByReference(parent metav1.Object, namespaced bool, namespace string) bool {
+	if parent.GetNamespace() == "" {
+		return true
+	}
+	return namespaced && !IsCrossNamespace(parent, namespace)
+}
+
+// SetLabelOwner records parent as the owner of obj with labels.
+func SetLabelOwner(parent metav1.Object, obj *unstructured.Unstructured) {
+	objLabels := obj.GetLabels()
+	if objLabels == nil {
+		objLabels = make(map[string]string, 2)
+	}
+	objLabels[OwnerUIDLabel] = string(parent.GetUID())
+	objLabels[OwnerNamespaceLabel] = parent.GetNamespace()
+	obj.SetLabels(objLabels)
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string, 1)
+	}
+	annotations[OwnerNameAnnotation] = parent.GetName()
+	obj.SetAnnotations(annotations)
+}
+
+// IsLabelOwnedBy returns true if obj was marked as owned by parent with
+// SetLabelOwner.
+func IsLabelOwnedBy(parent, obj metav1.Object) bool {
+	return obj.GetLabels()[OwnerUIDLabel] == string(parent.GetUID())
+}
+
+// LabelOwnerOf returns the owner recorded on obj with SetLabelOwner, if any.
+func LabelOwnerOf(obj metav1.Object) (namespace, name string, uid types.UID, found bool) {
+	objLabels := obj.GetLabels()
+	uid = types.UID(objLabels[OwnerUIDLabel])
+	name = obj.GetAnnotations()[OwnerNameAnnotation]
+	if uid == "" || name == "" {
+		return "", "", "", false
+	}
+	return objLabels[OwnerNamespaceLabel], name, uid, true
+}
+
+// ChildNamespaces holds the namespaces, other than the parent's, in which
+// children of each kind may be placed, and which cluster-scoped kinds may be
+// children of a namespaced parent. A nil *ChildNamespaces allows none.
+type ChildNamespaces struct {
+	kinds map[string]childNamespacePolicy
+	// namespaceLabels returns the labels of the namespace with the given name.
+	namespaceLabels func(name string) (map[string]string, error)
+}
+
+type childNamespacePolicy struct {
+	namespaces map[string]bool
+	selector   labels.Selector
+
+	// clusterScoped is true for cluster-scoped kinds, which are only allowed
+	// with allowClusterScoped.
+	clusterScoped      bool
+	allowClusterScoped bool
+}
+
+// NewChildNamespaces returns ChildNamespaces which allow no other namespaces.
+// namespaceLabels is only called for kinds with a namespaceSelector.
+func NewChildNamespaces(namespaceLabels func(name string) (map[string]string, error)) *ChildNamespaces {
+	return &ChildNamespaces{kinds: make(map[string]childNamespacePolicy), namespaceLabels: namespaceLabels}
+}
+
+// Set sets the namespaces in which children of the given kind may be placed.
+func (n *ChildNamespaces) Set(apiGroup, kind string, policy *v1alpha1.ChildCrossNamespacePolicy) error {
+	if len(policy.Namespaces) == 0 && policy.NamespaceSelector == nil {
+		return fmt.Errorf("crossNamespace of %v must have namespaces, a namespaceSelector, or both", kind)
+	}
+	options := childNamespacePolicy{namespaces: make(map[string]bool, len(policy.Namespaces))}
+	for _, namespace := range policy.Namespaces {
+		options.namespaces[namespace] = true
+	}
+	if policy.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(policy.NamespaceSelector)
+		if err != nil {
+			return fmt.Errorf("invalid namespaceSelector of %v: %w", kind, err)
+		}
+		options.selector = selector
+	}
+	n.kinds[fmt.Sprintf("%s.%s", kind, apiGroup)] = options
+	return nil
+}
+
+// SetClusterScoped records that the given kind is cluster-scoped, while the
+// parent is namespaced, and whether such children are allowed.
+func (n *ChildNamespaces) SetClusterScoped(apiGroup, kind string, allowed bool) {
+	n.kinds[fmt.Sprintf("%s.%s", kind, apiGroup)] = childNamespacePolicy{clusterScoped: true, allowClusterScoped: allowed}
+}
+
+// Any returns true if children of any kind may be placed outside of the
+// parent's namespace.
+func (n *ChildNamespaces) Any() bool {
+	if n == nil {
+		return false
+	}
+	for _, options := range n.kinds {
+		if options.enabled() {
+			return true
+		}
+	}
+	return false
+}
+
+// Enabled returns true if children of the given kind may be placed outside of
+// the parent's namespace.
+func (n *ChildNamespaces) Enabled(apiGroup, kind string) bool {
+	if n == nil {
+		return false
+	}
+	return n.kinds[fmt.Sprintf("%s.%s", kind, apiGroup)].enabled()
+}
+
+func (p childNamespacePolicy) enabled() bool {
+	if p.clusterScoped {
+		return p.allowClusterScoped
+	}
+	return len(p.namespaces) > 0 || p.selector != nil
+}
+
+// IsLabelOwned returns true if obj, a child of parent, is owned through
+// labels rather than an ownerReference.
+func (n *ChildNamespaces) IsLabelOwned(parent metav1.Object, obj *unstructured.Unstructured) bool {
+	if parent.GetNamespace() == "" {
+		return false
+	}
+	if n != nil {
+		apiGroup, _ := ParseAPIVersion(obj.GetAPIVersion())
+		if n.kinds[fmt.Sprintf("%s.%s", obj.GetKind(), apiGroup)].clusterScoped {
+			return true
+		}
+	}
+	return IsCrossNamespace(parent, obj.GetNamespace())
+}
+
+// Check returns an error if a desired child is placed in a namespace, other
+// than the parent's, which isn't allowed for its kind, or is cluster-scoped
+// without being allowed.
+func (n *ChildNamespaces) Check(parent metav1.Object, desired RelativeObjectMap) error {
+	for gvk, objects := range desired {
+		if n != nil && parent.GetNamespace() != "" {
+			if options := n.kinds[fmt.Sprintf("%s.%s", gvk.Kind, gvk.Group)]; options.clusterScoped {
+				if !options.allowClusterScoped && len(objects) > 0 {
+					return fmt.Errorf("desired children of cluster-scoped %v require clusterScoped on their child resource rule", gvk.Kind)
+				}
+				continue
+			}
+		}
+		for _, obj := range objects {
+			namespace := obj.GetNamespace()
+			if !IsCrossNamespace(parent, namespace) {
+				continue
+			}
+			allowed, err := n.allowed(gvk.Group, gvk.Kind, namespace)
+			if err != nil {
+				return err
+			}
+			if !allowed {
+				return fmt.Errorf("desired child %v is in namespace %v, which isn't allowed by the crossNamespace policy of %v", describeObject(obj), namespace, gvk.Kind)
+			}
+		}
+	}
+	return nil
+}
+
+func (n *ChildNamespaces) allowed(apiGroup, kind, namespace string) (bool, error) {
+	if n == nil {
+		return false, nil
+	}
+	options, found := n.kinds[fmt.Sprintf("%s.%s", kind, apiGroup)]
+	if !found {
+		return false, nil
+	}
+	if options.namespaces[namespace] {
+		return true, nil
+	}
+	if options.selector == nil {
+		return false, nil
+	}
+	namespaceLabels, err := n.namespaceLabels(namespace)
+	if err != nil {
+		return false, fmt.Errorf("can't get labels of namespace %v: %w", namespace, err)
+	}
+	return options.selector.Matches(labels.Set(namespaceLabels)), nil
+}
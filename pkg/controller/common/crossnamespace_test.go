@@ -0,0 +1,142 @@
+package common
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+func newNamespacedChild(namespace, name string) *unstructured.Unstructured {
+	child := &unstructured.Unstructured{}
+	child.SetAPIVersion("v1")
+	child.SetKind("ConfigMap")
+	child.SetNamespace(namespace)
+	child.SetName(name)
+	return child
+}
+
+func TestChildNamespaces_Check(t *testing.T) {
+	configMaps := GroupVersionKind{schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}}
+	parent := &metav1.ObjectMeta{Namespace: "app", Name: "parent"}
+	namespaces := NewChildNamespaces(func(name string) (map[string]string, error) {
+		return map[string]string{"team": name}, nil
+	})
+	err := namespaces.Set("", "ConfigMap", &v1alpha1.ChildCrossNamespacePolicy{
+		Namespaces:        []string{"shared"},
+		NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "blue"}},
+	})
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		namespace string
+		expectErr bool
+	}{
+		{name: "parent namespace", namespace: "app"},
+		{name: "no namespace", namespace: ""},
+		{name: "listed namespace", namespace: "shared"},
+		{name: "selected namespace", namespace: "blue"},
+		{name: "other namespace", namespace: "red", expectErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desired := RelativeObjectMap{configMaps: {"child": newNamespacedChild(tt.namespace, "child")}}
+
+			err := namespaces.Check(parent, desired)
+
+			if tt.expectErr && err == nil {
+				t.Errorf("expected error for namespace %q", tt.namespace)
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("err should be nil, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestChildNamespaces_Check_whenNotEnabled_returnError(t *testing.T) {
+	configMaps := GroupVersionKind{schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}}
+	parent := &metav1.ObjectMeta{Namespace: "app", Name: "parent"}
+	var namespaces *ChildNamespaces
+
+	err := namespaces.Check(parent, RelativeObjectMap{configMaps: {"child": newNamespacedChild("shared", "child")}})
+
+	if err == nil {
+		t.Errorf("expected error for child in another namespace")
+	}
+}
+
+func TestSetLabelOwner(t *testing.T) {
+	parent := &metav1.ObjectMeta{Namespace: "app", Name: "parent", UID: "1234"}
+	child := newNamespacedChild("shared", "child")
+
+	SetLabelOwner(parent, child)
+
+	if !IsLabelOwnedBy(parent, child) {
+		t.Errorf("expected child to be owned by parent")
+	}
+	namespace, name, uid, found := LabelOwnerOf(child)
+	if !found || namespace != "app" || name != "parent" || uid != "1234" {
+		t.Errorf("unexpected owner %v/%v (%v), found: %v", namespace, name, uid, found)
+	}
+}
+
+func TestMakeRelativeObjectMap_crossNamespace(t *testing.T) {
+	parent := &metav1.ObjectMeta{Namespace: "app", Name: "parent"}
+
+	relative := MakeRelativeObjectMap(parent, []*unstructured.Unstructured{
+		newNamespacedChild("app", "local"),
+		newNamespacedChild("shared", "remote"),
+	})
+
+	configMaps := relative[GroupVersionKind{schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}}]
+	if configMaps["local"] == nil || configMaps["shared/remote"] == nil {
+		t.Errorf("unexpected relative names: %v", configMaps)
+	}
+}
+
+func TestChildNamespaces_clusterScoped(t *testing.T) {
+	clusterRoles := GroupVersionKind{schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}}
+	parent := &metav1.ObjectMeta{Namespace: "app", Name: "parent"}
+	clusterRole := &unstructured.Unstructured{}
+	clusterRole.SetAPIVersion("rbac.authorization.k8s.io/v1")
+	clusterRole.SetKind("ClusterRole")
+	clusterRole.SetName("reader")
+	desired := RelativeObjectMap{clusterRoles: {"reader": clusterRole}}
+
+	tests := []struct {
+		name      string
+		allowed   bool
+		expectErr bool
+	}{
+		{name: "allowed", allowed: true},
+		{name: "not allowed", expectErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespaces := NewChildNamespaces(nil)
+			namespaces.SetClusterScoped("rbac.authorization.k8s.io", "ClusterRole", tt.allowed)
+
+			err := namespaces.Check(parent, desired)
+
+			if tt.expectErr && err == nil {
+				t.Errorf("expected error for cluster-scoped child")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("err should be nil, got: %v", err)
+			}
+			if !namespaces.IsLabelOwned(parent, clusterRole) {
+				t.Errorf("expected cluster-scoped child to be owned through labels")
+			}
+			if namespaces.Enabled("rbac.authorization.k8s.io", "ClusterRole") != tt.allowed {
+				t.Errorf("expected Enabled to be %v", tt.allowed)
+			}
+		})
+	}
+}
@@ -62,3 +62,13 @@ func (responseCache *ResponseCache) Get(name string, parentGeneration int64) *Cu
 	}
 	return responseCacheEntry.cachedResponse
 }
+
+// GetLastKnownGood returns the last response added for given parent,
+// regardless of its generation, or nil when not found.
+func (responseCache *ResponseCache) GetLastKnownGood(name string) *CustomizeHookResponse {
+	value, found := responseCache.cache.Get(name)
+	if !found {
+		return nil
+	}
+	return value.(*customizeResponseCacheEntry).cachedResponse
+}
@@ -93,3 +93,16 @@ func Test_ConcurrentMapAccess(t *testing.T) {
 	go responseCache.Add("some_three", 1, &someResponse)
 	go responseCache.Add("some_four", 1, &someResponse)
 }
+
+func TestGetLastKnownGood_IgnoresGeneration(t *testing.T) {
+	responseCache := NewResponseCache()
+	mockResponse := CustomizeHookResponse{}
+	responseCache.Add("some", 12, &mockResponse)
+
+	if cachedElement := responseCache.GetLastKnownGood("some"); cachedElement != &mockResponse {
+		t.Errorf("Incorrect cache entry, got: %v, expected: %v", cachedElement, &mockResponse)
+	}
+	if cachedElement := responseCache.GetLastKnownGood("other"); cachedElement != nil {
+		t.Errorf("Incorrect cache entry, got: %v, expected nil", cachedElement)
+	}
+}
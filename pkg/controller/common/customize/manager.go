@@ -17,17 +17,22 @@ limitations under the License.
 package customize
 
 import (
+	"context"
 	"fmt"
 	"metacontroller/pkg/hooks"
+	"strings"
+	"sync"
 
 	"github.com/go-logr/logr"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/flowcontrol"
 
 	"metacontroller/pkg/apis/metacontroller/v1alpha1"
 	"metacontroller/pkg/controller/common"
@@ -61,6 +66,9 @@ type Manager struct {
 	enqueueParent func(interface{})
 
 	customizeHook hooks.HookExecutor
+	// customizeErrors holds, by parent UID, the error of the last customize hook
+	// call for parents whose related objects come from last known-good rules.
+	customizeErrors sync.Map
 
 	logger logr.Logger
 }
@@ -74,14 +82,17 @@ func NewCustomizeManager(
 	parentInformers common.InformerMap,
 	parentKinds common.GroupKindMap,
 	logger logr.Logger,
-	controllerType common.ControllerType) (*Manager, error) {
+	controllerType common.ControllerType,
+	hookRateLimiter flowcontrol.RateLimiter,
+	hookTransport *v1alpha1.HookTransport) (*Manager, error) {
 	var executor hooks.HookExecutor
 	var err error
 	if controller.GetCustomizeHook() != nil {
-		executor, err = hooks.NewHookExecutor(controller.GetCustomizeHook(), name, controllerType, common.CustomizeHook)
+		executor, err = hooks.NewHookExecutor(controller.GetCustomizeHook(), name, controllerType, common.CustomizeHook, dynClient, hookTransport, logger)
 		if err != nil {
 			return nil, err
 		}
+		executor = hooks.WithRateLimiter(executor, hookRateLimiter)
 	} else {
 		executor = nil
 	}
@@ -119,7 +130,7 @@ func (rm *Manager) getCachedCustomizeHookResponse(parent *unstructured.Unstructu
 	return rm.customizeCache.Get(parent.GetName(), parent.GetGeneration())
 }
 
-func (rm *Manager) getCustomizeHookResponse(parent *unstructured.Unstructured) (*CustomizeHookResponse, error) {
+func (rm *Manager) getCustomizeHookResponse(ctx context.Context, parent *unstructured.Unstructured) (*CustomizeHookResponse, error) {
 	cached := rm.getCachedCustomizeHookResponse(parent)
 	if cached != nil {
 		return cached, nil
@@ -129,15 +140,33 @@ func (rm *Manager) getCustomizeHookResponse(parent *unstructured.Unstructured) (
 			Controller: rm.controller,
 			Parent:     parent,
 		}
-		if err := rm.customizeHook.Execute(request, &response); err != nil {
+		if err := rm.customizeHook.Execute(hooks.WithParent(ctx, parent), request, &response); err != nil {
+			// Related resource rules change far less often than sync results,
+			// so prefer stale rules over failing the whole sync.
+			if fallback := rm.customizeCache.GetLastKnownGood(parent.GetName()); fallback != nil {
+				rm.logger.Info("Customize hook failed, using last known-good related resource rules", "parent", parent, "error", err.Error())
+				rm.customizeErrors.Store(parent.GetUID(), err)
+				return fallback, nil
+			}
 			return nil, err
 		}
 
+		rm.customizeErrors.Delete(parent.GetUID())
 		rm.customizeCache.Add(parent.GetName(), parent.GetGeneration(), &response)
 		return &response, nil
 	}
 }
 
+// CustomizeHookError returns the error of the last customize hook call for
+// parent, if its related objects are currently selected using last known-good
+// rules because of that error. It returns nil otherwise.
+func (rm *Manager) CustomizeHookError(parent *unstructured.Unstructured) error {
+	if value, ok := rm.customizeErrors.Load(parent.GetUID()); ok {
+		return value.(error)
+	}
+	return nil
+}
+
 func (rm *Manager) getRelatedClient(apiVersion, resource string) (*dynamicclientset.ResourceClient, *dynamicinformer.ResourceInformer, error) {
 	client, err := rm.dynClient.Resource(apiVersion, resource)
 
@@ -265,6 +294,9 @@ func determineSelectionType(relatedRule *v1alpha1.RelatedResourceRule) (relatedO
 	if hasLabelSelector && hasNamespaceOrNames {
 		return invalid, fmt.Errorf("related rule cannot have both labelSelector and Namespace/Names specifcied : %#v", relatedRule)
 	}
+	if relatedRule.NamespaceSelector != nil && hasNamespaceOrNames {
+		return invalid, fmt.Errorf("related rule cannot have both namespaceSelector and Namespace/Names specified : %#v", relatedRule)
+	}
 	if hasNamespaceOrNames {
 		return selectByNamespaceAndNames, nil
 	}
@@ -288,6 +320,75 @@ func toSelector(labelSelector *metav1.LabelSelector) (labels.Selector, error) {
 	}
 }
 
+func toFieldSelector(fieldSelector string) (fields.Selector, error) {
+	if fieldSelector == "" {
+		return fields.Everything(), nil
+	}
+	selector, err := fields.ParseSelector(fieldSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fieldSelector %q of related rule: %w", fieldSelector, err)
+	}
+	return selector, nil
+}
+
+// matchesFields tells whether obj matches selector. Unlike the field selectors
+// of the API server, any field can be used, e.g. `spec.nodeName`, since
+// objects are filtered in Metacontroller's cache. Missing fields are empty.
+func matchesFields(selector fields.Selector, obj *unstructured.Unstructured) bool {
+	if selector.Empty() {
+		return true
+	}
+	set := make(fields.Set)
+	for _, requirement := range selector.Requirements() {
+		value, found, err := unstructured.NestedFieldNoCopy(obj.UnstructuredContent(), strings.Split(requirement.Field, ".")...)
+		if err == nil && found && value != nil {
+			set[requirement.Field] = fmt.Sprint(value)
+		}
+	}
+	return selector.Matches(set)
+}
+
+// filterFields returns the objects of all which match fieldSelector.
+func filterFields(fieldSelector string, all []*unstructured.Unstructured) ([]*unstructured.Unstructured, error) {
+	selector, err := toFieldSelector(fieldSelector)
+	if err != nil || selector.Empty() {
+		return all, err
+	}
+	var matching []*unstructured.Unstructured
+	for _, obj := range all {
+		if matchesFields(selector, obj) {
+			matching = append(matching, obj)
+		}
+	}
+	return matching, nil
+}
+
+// listInSelectedNamespaces lists the objects matching selector in all
+// namespaces whose labels match namespaceSelector.
+func (rm *Manager) listInSelectedNamespaces(selector labels.Selector, namespaceSelector *metav1.LabelSelector, informer *dynamicinformer.ResourceInformer) ([]*unstructured.Unstructured, error) {
+	nsSelector, err := metav1.LabelSelectorAsSelector(namespaceSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid namespaceSelector of related rule: %w", err)
+	}
+	_, nsInformer, err := rm.getRelatedClient("v1", "namespaces")
+	if err != nil {
+		return nil, err
+	}
+	namespaces, err := nsInformer.Lister().List(nsSelector)
+	if err != nil {
+		return nil, err
+	}
+	var all []*unstructured.Unstructured
+	for _, ns := range namespaces {
+		objects, err := informer.Lister().Namespace(ns.GetName()).List(selector)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, objects...)
+	}
+	return all, nil
+}
+
 func (rm *Manager) matchesRelatedRule(parent, related *unstructured.Unstructured, relatedRule *v1alpha1.RelatedResourceRule) (bool, error) {
 	parentGroup, _ := schema.ParseGroupVersion(parent.GetAPIVersion())
 	parentResource := rm.parentKinds.Get(schema.GroupKind{Group: parentGroup.Group, Kind: parent.GetKind()})
@@ -296,6 +397,13 @@ func (rm *Manager) matchesRelatedRule(parent, related *unstructured.Unstructured
 	}
 
 	selectionType, err := determineSelectionType(relatedRule)
+	fieldSelector, fieldErr := toFieldSelector(relatedRule.FieldSelector)
+	if fieldErr != nil {
+		return false, fieldErr
+	}
+	if !matchesFields(fieldSelector, related) {
+		return false, nil
+	}
 
 	switch selectionType {
 	case selectByLabels:
@@ -310,7 +418,8 @@ func (rm *Manager) matchesRelatedRule(parent, related *unstructured.Unstructured
 			if len(relatedRule.Namespace) != 0 && parentNamespace != relatedRule.Namespace {
 				return false, fmt.Errorf("%s: Namespace of parent %s does not match with namespace %s of related rule for %s/%s", parentResource.Kind, parent.GetName(), relatedRule.Namespace, relatedRule.APIVersion, relatedRule.Resource)
 			}
-			if parentNamespace != related.GetNamespace() {
+			// Cluster-scoped related objects have no namespace to compare.
+			if related.GetNamespace() != "" && parentNamespace != related.GetNamespace() {
 				return false, nil
 			}
 		}
@@ -332,7 +441,7 @@ func listObjects(selector labels.Selector, namespace string, informer *dynamicin
 	return informer.Lister().List(selector)
 }
 
-func (rm *Manager) GetRelatedObjects(parent *unstructured.Unstructured) (common.RelativeObjectMap, error) {
+func (rm *Manager) GetRelatedObjects(ctx context.Context, parent *unstructured.Unstructured) (common.RelativeObjectMap, error) {
 	childMap := make(common.RelativeObjectMap)
 	if !rm.IsEnabled() {
 		return childMap, nil
@@ -345,7 +454,7 @@ func (rm *Manager) GetRelatedObjects(parent *unstructured.Unstructured) (common.
 
 	parentNamespace := parent.GetNamespace()
 
-	customizeHookResponse, err := rm.getCustomizeHookResponse(parent)
+	customizeHookResponse, err := rm.getCustomizeHookResponse(ctx, parent)
 
 	if err != nil {
 		return nil, err
@@ -366,11 +475,20 @@ func (rm *Manager) GetRelatedObjects(parent *unstructured.Unstructured) (common.
 				return nil, err
 			}
 			var all []*unstructured.Unstructured
-			if parentResource.Namespaced {
+			switch {
+			case !relatedClient.Namespaced:
+				// Cluster-scoped kinds, like Nodes, are related to all parents.
+				all, err = informer.Lister().List(selector)
+			case relatedRule.NamespaceSelector != nil:
+				all, err = rm.listInSelectedNamespaces(selector, relatedRule.NamespaceSelector, informer)
+			case parentResource.Namespaced:
 				all, err = informer.Lister().Namespace(parentNamespace).List(selector)
-			} else {
+			default:
 				all, err = informer.Lister().List(selector)
 			}
+			if err == nil {
+				all, err = filterFields(relatedRule.FieldSelector, all)
+			}
 			if err != nil {
 				return nil, fmt.Errorf("can't list %v related objects: %w", relatedClient.Kind, err)
 			}
@@ -382,6 +500,9 @@ func (rm *Manager) GetRelatedObjects(parent *unstructured.Unstructured) (common.
 				return nil, fmt.Errorf("requested related object namespace %s differs from parent object namespace %s", relatedRule.Namespace, parentNamespace)
 			}
 			all, err := listObjects(labels.Everything(), relatedRule.Namespace, informer)
+			if err == nil {
+				all, err = filterFields(relatedRule.FieldSelector, all)
+			}
 			if err != nil {
 				return nil, fmt.Errorf("can't list %v related objects: %w", relatedClient.Kind, err)
 			}
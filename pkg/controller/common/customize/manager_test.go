@@ -17,6 +17,7 @@ limitations under the License.
 package customize
 
 import (
+	"context"
 	"metacontroller/pkg/internal/testutils"
 	"reflect"
 	"testing"
@@ -63,6 +64,8 @@ var customizeManagerWithNilController, _ = NewCustomizeManager(
 	make(common.GroupKindMap),
 	nil,
 	common.CompositeController,
+	nil,
+	nil,
 )
 
 var customizeManagerWithFakeController, _ = NewCustomizeManager(
@@ -75,6 +78,8 @@ var customizeManagerWithFakeController, _ = NewCustomizeManager(
 	make(common.GroupKindMap),
 	nil,
 	common.DecoratorController,
+	nil,
+	nil,
 )
 
 func TestGetRelatedObjects_whenHookDisabled_returnEmptyMap(t *testing.T) {
@@ -82,7 +87,7 @@ func TestGetRelatedObjects_whenHookDisabled_returnEmptyMap(t *testing.T) {
 	parent.SetName("test")
 	parent.SetGeneration(1)
 
-	relatedObjects, err := customizeManagerWithNilController.GetRelatedObjects(parent)
+	relatedObjects, err := customizeManagerWithNilController.GetRelatedObjects(context.Background(), parent)
 
 	if err != nil {
 		t.Errorf("Incorrect invocation, err should be nil, got: %v", err)
@@ -111,7 +116,7 @@ func TestGetRelatedObject_requestResponse(t *testing.T) {
 	parent.SetName("othertest")
 	parent.SetGeneration(1)
 
-	response, err := customizeManagerWithFakeController.getCustomizeHookResponse(parent)
+	response, err := customizeManagerWithFakeController.getCustomizeHookResponse(context.Background(), parent)
 
 	if err != nil {
 		t.Errorf("Incorrect invocation, err should be nil, got: %v", err)
@@ -225,3 +230,50 @@ func TestDetermineSelectionType_returnLabelsWhenNothingIsPresent(t *testing.T) {
 		t.Errorf("Expected %v selection type, but got %v", selectByLabels, selectionType)
 	}
 }
+
+func TestDetermineSelectionType_returnErrorWhenNamespaceSelectorAndNamespaceIsPresent(t *testing.T) {
+	resourceRule := v1alpha1.RelatedResourceRule{
+		ResourceRule: v1alpha1.ResourceRule{
+			APIVersion: "some",
+			Resource:   "some",
+		},
+		NamespaceSelector: &v1.LabelSelector{MatchLabels: map[string]string{"aaa": "bbb"}},
+		Namespace:         "Namespace",
+	}
+
+	selectionType, err := determineSelectionType(&resourceRule)
+
+	if selectionType != invalid || err == nil {
+		t.Errorf("Expected error and 'invalid' selection type, but got %v", selectionType)
+	}
+}
+
+func TestFilterFields(t *testing.T) {
+	node := func(name string, unschedulable bool) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{"unschedulable": unschedulable},
+		}}
+		obj.SetName(name)
+		return obj
+	}
+	all := []*unstructured.Unstructured{node("a", false), node("b", true), {}}
+
+	matching, err := filterFields("spec.unschedulable!=true,metadata.name!=c", all)
+
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	var names []string
+	for _, obj := range matching {
+		names = append(names, obj.GetName())
+	}
+	if !reflect.DeepEqual(names, []string{"a", ""}) {
+		t.Errorf("expected nodes [a ''], got %v", names)
+	}
+}
+
+func TestFilterFields_whenInvalid_returnError(t *testing.T) {
+	if _, err := filterFields("spec.nodeName", nil); err == nil {
+		t.Errorf("expected an error for an invalid field selector")
+	}
+}
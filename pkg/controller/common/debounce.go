@@ -0,0 +1,37 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ChildEventDebounce returns how long a controller waits after an event of a
+// child before it syncs the parent, so a burst of child events is coalesced
+// into a single sync, or zero to sync right away.
+func ChildEventDebounce(debounce *metav1.Duration) (time.Duration, error) {
+	if debounce == nil {
+		return 0, nil
+	}
+	if debounce.Duration < 0 {
+		return 0, fmt.Errorf("invalid childEventDebounce %v: must not be negative", debounce.Duration)
+	}
+	return debounce.Duration, nil
+}
@@ -0,0 +1,20 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestChildEventDebounce(t *testing.T) {
+	if got, err := ChildEventDebounce(nil); err != nil || got != 0 {
+		t.Errorf("expected no debounce when unset, got: %v, %v", got, err)
+	}
+	if got, err := ChildEventDebounce(&metav1.Duration{Duration: 2 * time.Second}); err != nil || got != 2*time.Second {
+		t.Errorf("expected a debounce of 2s, got: %v, %v", got, err)
+	}
+	if _, err := ChildEventDebounce(&metav1.Duration{Duration: -time.Second}); err == nil {
+		t.Errorf("expected an error for a negative debounce")
+	}
+}
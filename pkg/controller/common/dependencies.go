@@ -0,0 +1,140 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+// DependencyRecheckInterval is how often a controller waiting for its
+// dependencies checks them again.
+const DependencyRecheckInterval = 10 * time.Second
+
+// UnmetDependencies returns a description of each dependency which doesn't
+// exist yet or isn't Ready.
+func UnmetDependencies(ctx context.Context, k8sClient client.Client, dependencies []v1alpha1.ControllerDependency) ([]string, error) {
+	var unmet []string
+	for _, dependency := range dependencies {
+		conditions, err := dependencyConditions(ctx, k8sClient, dependency)
+		if apierrors.IsNotFound(err) {
+			unmet = append(unmet, fmt.Sprintf("%s/%s not found", dependency.Kind, dependency.Name))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !meta.IsStatusConditionTrue(conditions, v1alpha1.ConditionReady) {
+			unmet = append(unmet, fmt.Sprintf("%s/%s not ready", dependency.Kind, dependency.Name))
+		}
+	}
+	return unmet, nil
+}
+
+func dependencyConditions(ctx context.Context, k8sClient client.Client, dependency v1alpha1.ControllerDependency) ([]metav1.Condition, error) {
+	key := client.ObjectKey{Name: dependency.Name}
+	switch dependency.Kind {
+	case "CompositeController":
+		cc := v1alpha1.CompositeController{}
+		if err := k8sClient.Get(ctx, key, &cc); err != nil {
+			return nil, err
+		}
+		return cc.Status.Conditions, nil
+	case "DecoratorController":
+		dc := v1alpha1.DecoratorController{}
+		if err := k8sClient.Get(ctx, key, &dc); err != nil {
+			return nil, err
+		}
+		return dc.Status.Conditions, nil
+	case "GlobalController":
+		gc := v1alpha1.GlobalController{}
+		if err := k8sClient.Get(ctx, key, &gc); err != nil {
+			return nil, err
+		}
+		return gc.Status.Conditions, nil
+	case "CronController":
+		cc := v1alpha1.CronController{}
+		if err := k8sClient.Get(ctx, key, &cc); err != nil {
+			return nil, err
+		}
+		return cc.Status.Conditions, nil
+	case "ExternalResourceController":
+		erc := v1alpha1.ExternalResourceController{}
+		if err := k8sClient.Get(ctx, key, &erc); err != nil {
+			return nil, err
+		}
+		return erc.Status.Conditions, nil
+	case "StateMachineController":
+		smc := v1alpha1.StateMachineController{}
+		if err := k8sClient.Get(ctx, key, &smc); err != nil {
+			return nil, err
+		}
+		return smc.Status.Conditions, nil
+	default:
+		return nil, fmt.Errorf("invalid dependency %s/%s: unknown kind", dependency.Kind, dependency.Name)
+	}
+}
+
+// SetDependencyConditions records on conditions whether the controller is
+// waiting for the given unmet dependencies, or is Ready, and reports whether
+// anything changed.
+func SetDependencyConditions(conditions *[]metav1.Condition, generation int64, unmet []string) bool {
+	before := make([]metav1.Condition, len(*conditions))
+	copy(before, *conditions)
+
+	if len(unmet) > 0 {
+		message := "Waiting for " + strings.Join(unmet, ", ")
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               v1alpha1.ConditionWaitingForDependency,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: generation,
+			Reason:             "DependenciesNotReady",
+			Message:            message,
+		})
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               v1alpha1.ConditionReady,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: generation,
+			Reason:             "WaitingForDependency",
+			Message:            message,
+		})
+	} else {
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               v1alpha1.ConditionWaitingForDependency,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: generation,
+			Reason:             "DependenciesReady",
+		})
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               v1alpha1.ConditionReady,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: generation,
+			Reason:             "Started",
+		})
+	}
+	return !equality.Semantic.DeepEqual(before, *conditions)
+}
@@ -0,0 +1,64 @@
+package common
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+func TestUnmetDependencies(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ready := &v1alpha1.CompositeController{
+		ObjectMeta: metav1.ObjectMeta{Name: "ready"},
+		Status: v1alpha1.CompositeControllerStatus{
+			Conditions: []metav1.Condition{{Type: v1alpha1.ConditionReady, Status: metav1.ConditionTrue}},
+		},
+	}
+	notReady := &v1alpha1.DecoratorController{
+		ObjectMeta: metav1.ObjectMeta{Name: "not-ready"},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(ready, notReady).Build()
+
+	unmet, err := UnmetDependencies(context.Background(), k8sClient, []v1alpha1.ControllerDependency{
+		{Kind: "CompositeController", Name: "ready"},
+		{Kind: "DecoratorController", Name: "not-ready"},
+		{Kind: "CompositeController", Name: "missing"},
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"DecoratorController/not-ready not ready", "CompositeController/missing not found"}
+	if len(unmet) != len(expected) || unmet[0] != expected[0] || unmet[1] != expected[1] {
+		t.Errorf("expected %v, got %v", expected, unmet)
+	}
+}
+
+func TestSetDependencyConditions(t *testing.T) {
+	var conditions []metav1.Condition
+
+	if !SetDependencyConditions(&conditions, 1, []string{"CompositeController/foo not ready"}) {
+		t.Errorf("expected conditions to change")
+	}
+	if !meta.IsStatusConditionTrue(conditions, v1alpha1.ConditionWaitingForDependency) {
+		t.Errorf("expected %s to be True", v1alpha1.ConditionWaitingForDependency)
+	}
+	if SetDependencyConditions(&conditions, 1, []string{"CompositeController/foo not ready"}) {
+		t.Errorf("expected conditions to be unchanged")
+	}
+	if !SetDependencyConditions(&conditions, 1, nil) {
+		t.Errorf("expected conditions to change")
+	}
+	if !meta.IsStatusConditionTrue(conditions, v1alpha1.ConditionReady) {
+		t.Errorf("expected %s to be True", v1alpha1.ConditionReady)
+	}
+}
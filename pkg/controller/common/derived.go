@@ -0,0 +1,124 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+	"google.golang.org/protobuf/types/known/structpb"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+var jsonValueType = reflect.TypeOf(&structpb.Value{})
+
+// DerivedFields evaluates the derived fields of a controller.
+type DerivedFields struct {
+	fields []derivedField
+}
+
+type derivedField struct {
+	name    string
+	program cel.Program
+}
+
+// NewDerivedFields compiles the expressions of fields. Each of the given
+// variables can be used in the expressions, with a dynamic type.
+func NewDerivedFields(fields []v1alpha1.DerivedField, variables ...string) (*DerivedFields, error) {
+	derived := &DerivedFields{}
+	if len(fields) == 0 {
+		return derived, nil
+	}
+	declarations := make([]*exprpb.Decl, 0, len(variables))
+	for _, variable := range variables {
+		declarations = append(declarations, decls.NewVar(variable, decls.Dyn))
+	}
+	env, err := cel.NewEnv(cel.Declarations(declarations...))
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if field.Name == "" {
+			return nil, fmt.Errorf("invalid derived field: name must not be empty")
+		}
+		if names[field.Name] {
+			return nil, fmt.Errorf("invalid derived field %q: duplicate name", field.Name)
+		}
+		names[field.Name] = true
+		ast, issues := env.Compile(field.Expression)
+		if issues != nil && issues.Err() != nil {
+			return nil, fmt.Errorf("invalid derived field %q: %w", field.Name, issues.Err())
+		}
+		program, err := env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("invalid derived field %q: %w", field.Name, err)
+		}
+		derived.fields = append(derived.fields, derivedField{name: field.Name, program: program})
+	}
+	return derived, nil
+}
+
+// Evaluate returns the value of each derived field, given the values of the
+// variables, or nil if there are no derived fields.
+func (d *DerivedFields) Evaluate(variables map[string]interface{}) (map[string]interface{}, error) {
+	if d == nil || len(d.fields) == 0 {
+		return nil, nil
+	}
+	values := make(map[string]interface{}, len(d.fields))
+	for _, field := range d.fields {
+		out, _, err := field.program.Eval(variables)
+		if err != nil {
+			return nil, fmt.Errorf("can't evaluate derived field %q: %w", field.name, err)
+		}
+		value, err := out.ConvertToNative(jsonValueType)
+		if err != nil {
+			return nil, fmt.Errorf("can't convert derived field %q to JSON: %w", field.name, err)
+		}
+		values[field.name] = value.(*structpb.Value).AsInterface()
+	}
+	return values, nil
+}
+
+// ObjectVariable returns obj in the form used for derived field variables.
+func ObjectVariable(obj *unstructured.Unstructured) interface{} {
+	if obj == nil {
+		return nil
+	}
+	return obj.UnstructuredContent()
+}
+
+// RelativeObjectMapVariable returns m in the form used for derived field
+// variables, which is the same as in hook requests: a map from
+// "<kind>.<apiVersion>" to a map from each object's name to the object.
+func RelativeObjectMapVariable(m RelativeObjectMap) map[string]interface{} {
+	result := make(map[string]interface{}, len(m))
+	for gvk, objects := range m {
+		key, _ := gvk.MarshalText()
+		group := make(map[string]interface{}, len(objects))
+		for name, obj := range objects {
+			group[name] = obj.UnstructuredContent()
+		}
+		result[string(key)] = group
+	}
+	return result
+}
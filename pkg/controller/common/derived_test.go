@@ -0,0 +1,58 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+func TestDerivedFields_Evaluate(t *testing.T) {
+	derived, err := NewDerivedFields([]v1alpha1.DerivedField{
+		{Name: "podCount", Expression: `size(children["Pod.v1"])`},
+		{Name: "replicas", Expression: `parent.spec.replicas`},
+		{Name: "ready", Expression: `children["Pod.v1"].all(name, children["Pod.v1"][name].status.phase == "Running")`},
+	}, "parent", "children")
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	parent := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"replicas": int64(2)},
+	}}
+	pod := func(name, phase string) *unstructured.Unstructured {
+		pod := &unstructured.Unstructured{Object: map[string]interface{}{
+			"status": map[string]interface{}{"phase": phase},
+		}}
+		pod.SetAPIVersion("v1")
+		pod.SetKind("Pod")
+		pod.SetName(name)
+		return pod
+	}
+	children := MakeRelativeObjectMap(parent, []*unstructured.Unstructured{pod("a", "Running"), pod("b", "Pending")})
+
+	values, err := derived.Evaluate(map[string]interface{}{
+		"parent":   ObjectVariable(parent),
+		"children": RelativeObjectMapVariable(children),
+	})
+
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	expected := map[string]interface{}{"podCount": float64(2), "replicas": float64(2), "ready": false}
+	if diff := cmp.Diff(expected, values); diff != "" {
+		t.Errorf("unexpected derived values (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewDerivedFields_invalidExpression(t *testing.T) {
+	_, err := NewDerivedFields([]v1alpha1.DerivedField{
+		{Name: "broken", Expression: `parent.spec.`},
+	}, "parent")
+
+	if err == nil {
+		t.Errorf("expected error for invalid expression")
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+// maxDriftPatchLength is the length above which the patch of a ChildDrift is
+// truncated in its message.
+const maxDriftPatchLength = 1024
+
+// ChildDrift is an operation ManageChildren would do to make the children
+// match the desired ones.
+type ChildDrift struct {
+	Action ChildAction
+	// Object is the desired object, or the observed one for deletes.
+	Object *unstructured.Unstructured
+	// Patch is the JSON merge patch which would be applied by an update.
+	Patch []byte
+}
+
+// Message describes the drift.
+func (d ChildDrift) Message() string {
+	message := fmt.Sprintf("Would %s %v", strings.ToLower(string(d.Action)), describeObject(d.Object))
+	if len(d.Patch) == 0 {
+		return message
+	}
+	return message + ": " + truncatePatch(d.Patch)
+}
+
+// truncatePatch returns patch as a string of at most maxDriftPatchLength.
+func truncatePatch(patch []byte) string {
+	if len(patch) > maxDriftPatchLength {
+		return string(patch[:maxDriftPatchLength]) + "..."
+	}
+	return string(patch)
+}
+
+// DiffChildren returns the operations ManageChildren would do to make the
+// observed children match the desired ones, without doing any of them.
+func DiffChildren(updateStrategy ChildUpdateStrategy, applyStrategies *ChildApplyStrategies, parent *unstructured.Unstructured, observedChildren, desiredChildren RelativeObjectMap) ([]ChildDrift, error) {
+	var errs []error
+	var drifts []ChildDrift
+	for key, observed := range observedChildren {
+		for name, obj := range observed {
+			if obj.GetDeletionTimestamp() == nil && desiredChildren[key][name] == nil {
+				drifts = append(drifts, ChildDrift{Action: ChildDelete, Object: obj})
+			}
+		}
+	}
+	for key, desired := range desiredChildren {
+		for name, obj := range desired {
+			oldObj := observedChildren[key][name]
+			if oldObj == nil {
+				drifts = append(drifts, ChildDrift{Action: ChildCreate, Object: obj})
+				continue
+			}
+			if oldObj.GetDeletionTimestamp() != nil {
+				continue
+			}
+			switch updateStrategy.GetMethod(key.Group, key.Kind) {
+			case v1alpha1.ChildUpdateOnDelete, "":
+				continue
+			}
+			newObj, err := applyStrategies.ApplyUpdate(oldObj, obj)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("can't diff %v: %w", describeObject(obj), err))
+				continue
+			}
+			if reflect.DeepEqual(newObj.UnstructuredContent(), oldObj.UnstructuredContent()) {
+				continue
+			}
+			patch, err := JsonMergePatch(oldObj, newObj)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("can't diff %v: %w", describeObject(obj), err))
+				continue
+			}
+			drifts = append(drifts, ChildDrift{Action: ChildUpdate, Object: obj, Patch: patch})
+		}
+	}
+	sort.Slice(drifts, func(i, j int) bool {
+		return describeObject(drifts[i].Object) < describeObject(drifts[j].Object)
+	})
+	return drifts, utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,84 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	dynamicapply "metacontroller/pkg/dynamic/apply"
+)
+
+type fixedUpdateMethod v1alpha1.ChildUpdateMethod
+
+func (m fixedUpdateMethod) GetMethod(apiGroup, kind string) v1alpha1.ChildUpdateMethod {
+	return v1alpha1.ChildUpdateMethod(m)
+}
+
+func TestDiffChildren(t *testing.T) {
+	parent := &unstructured.Unstructured{}
+	parent.SetNamespace("ns")
+	newConfigMap := func(name, value string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind("ConfigMap")
+		obj.SetNamespace("ns")
+		obj.SetName(name)
+		_ = unstructured.SetNestedField(obj.Object, value, "data", "key")
+		return obj
+	}
+	observedChild := func(name, value string) *unstructured.Unstructured {
+		obj := newConfigMap(name, value)
+		if err := dynamicapply.SetLastApplied(obj, newConfigMap(name, value).UnstructuredContent()); err != nil {
+			t.Fatalf("err should be nil, got: %v", err)
+		}
+		return obj
+	}
+	observed := MakeRelativeObjectMap(parent, []*unstructured.Unstructured{
+		observedChild("same", "a"), observedChild("changed", "a"), observedChild("extra", "a"),
+	})
+	desired := MakeRelativeObjectMap(parent, []*unstructured.Unstructured{
+		newConfigMap("same", "a"), newConfigMap("changed", "b"), newConfigMap("new", "a"),
+	})
+
+	tests := []struct {
+		name     string
+		method   v1alpha1.ChildUpdateMethod
+		expected []string
+	}{
+		{
+			name:   "InPlace",
+			method: v1alpha1.ChildUpdateInPlace,
+			expected: []string{
+				`Would update ConfigMap ns/changed: {"data":{"key":"b"}}`,
+				"Would delete ConfigMap ns/extra",
+				"Would create ConfigMap ns/new",
+			},
+		},
+		{
+			name:   "OnDelete",
+			method: v1alpha1.ChildUpdateOnDelete,
+			expected: []string{
+				"Would delete ConfigMap ns/extra",
+				"Would create ConfigMap ns/new",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drifts, err := DiffChildren(fixedUpdateMethod(tt.method), NewChildApplyStrategies("", ""), parent, observed, desired)
+			if err != nil {
+				t.Fatalf("err should be nil, got: %v", err)
+			}
+
+			var messages []string
+			for _, drift := range drifts {
+				messages = append(messages, drift.Message())
+			}
+			if diff := cmp.Diff(tt.expected, messages); diff != "" {
+				t.Errorf("unexpected drifts (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
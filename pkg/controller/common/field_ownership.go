@@ -0,0 +1,178 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	dynamicapply "metacontroller/pkg/dynamic/apply"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+)
+
+const (
+	// MigrateFieldOwnershipAnnotation on a server-side applied child makes
+	// Metacontroller take over the fields owned by the field managers it
+	// lists, comma-separated, like those it owns through client-side updates.
+	MigrateFieldOwnershipAnnotation = "metacontroller.k8s.io/migrate-field-ownership"
+
+	// clientSideFieldManager is the field manager of the updates Metacontroller
+	// makes without server-side apply, after its user agent.
+	clientSideFieldManager = "metacontroller"
+	// beforeFirstApplyFieldManager owns the fields of objects which were
+	// server-side applied for the first time.
+	beforeFirstApplyFieldManager = "before-first-apply"
+)
+
+// needsFieldOwnershipMigration returns whether obj may still have fields owned
+// by client-side updates, which a server-side apply wouldn't remove.
+func needsFieldOwnershipMigration(obj *unstructured.Unstructured) bool {
+	annotations := obj.GetAnnotations()
+	_, lastApplied := annotations[dynamicapply.LastAppliedAnnotation]
+	_, requested := annotations[MigrateFieldOwnershipAnnotation]
+	return lastApplied || requested
+}
+
+// jsonPatchOperation is an operation of a JSON patch.
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// fieldOwnershipMigrationPatch returns a JSON patch which moves the fields of
+// obj owned by the client-side updates of Metacontroller, and by the field
+// managers listed in its MigrateFieldOwnershipAnnotation, to the Apply entry
+// of fieldManager. It also removes the last-applied-configuration and
+// migration annotations, so they don't show up as diffs. The patch fails if
+// obj changed in the meantime.
+func fieldOwnershipMigrationPatch(obj *unstructured.Unstructured, fieldManager string, now time.Time) ([]byte, error) {
+	managers := map[string]bool{}
+	for _, manager := range strings.Split(obj.GetAnnotations()[MigrateFieldOwnershipAnnotation], ",") {
+		if manager = strings.TrimSpace(manager); manager != "" {
+			managers[manager] = true
+		}
+	}
+	migrated := func(entry metav1.ManagedFieldsEntry) bool {
+		if entry.Subresource != "" {
+			// Status fields aren't applied with the child.
+			return false
+		}
+		switch {
+		case entry.Manager == fieldManager && entry.Operation == metav1.ManagedFieldsOperationApply:
+			return true
+		case entry.Manager == clientSideFieldManager || entry.Manager == beforeFirstApplyFieldManager:
+			return entry.Operation == metav1.ManagedFieldsOperationUpdate
+		default:
+			return managers[entry.Manager]
+		}
+	}
+
+	var entries []metav1.ManagedFieldsEntry
+	fields := map[string]interface{}{}
+	found := false
+	for _, entry := range obj.GetManagedFields() {
+		if !migrated(entry) {
+			entries = append(entries, entry)
+			continue
+		}
+		found = true
+		if entry.FieldsV1 == nil {
+			continue
+		}
+		entryFields := map[string]interface{}{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &entryFields); err != nil {
+			return nil, fmt.Errorf("can't unmarshal fields of manager %q of %v: %w", entry.Manager, describeObject(obj), err)
+		}
+		unionFields(fields, entryFields)
+	}
+	patch := []jsonPatchOperation{
+		// Replacing the resourceVersion makes the patch fail if obj changed.
+		{Op: "replace", Path: "/metadata/resourceVersion", Value: obj.GetResourceVersion()},
+	}
+	if found {
+		raw, err := json.Marshal(fields)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, metav1.ManagedFieldsEntry{
+			Manager:    fieldManager,
+			Operation:  metav1.ManagedFieldsOperationApply,
+			APIVersion: obj.GetAPIVersion(),
+			Time:       &metav1.Time{Time: now},
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: raw},
+		})
+		patch = append(patch, jsonPatchOperation{Op: "replace", Path: "/metadata/managedFields", Value: entries})
+	}
+	for _, annotation := range []string{dynamicapply.LastAppliedAnnotation, MigrateFieldOwnershipAnnotation} {
+		if _, ok := obj.GetAnnotations()[annotation]; ok {
+			path := "/metadata/annotations/" + strings.ReplaceAll(strings.ReplaceAll(annotation, "~", "~0"), "/", "~1")
+			patch = append(patch, jsonPatchOperation{Op: "remove", Path: path})
+		}
+	}
+	return json.Marshal(patch)
+}
+
+// unionFields adds the field set from to the field set into.
+func unionFields(into, from map[string]interface{}) {
+	for key, value := range from {
+		fromChild, ok := value.(map[string]interface{})
+		if !ok {
+			into[key] = value
+			continue
+		}
+		intoChild, ok := into[key].(map[string]interface{})
+		if !ok {
+			intoChild = map[string]interface{}{}
+			into[key] = intoChild
+		}
+		unionFields(intoChild, fromChild)
+	}
+}
+
+// migrateFieldOwnership moves the fields of obj, a child of parent, owned
+// through client-side updates to the server-side apply field manager, and
+// returns the updated obj.
+func (s *ChildApplyStrategies) migrateFieldOwnership(client *dynamicclientset.ResourceClient, parent *unstructured.Unstructured, namespace string, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	// The managed fields of cached objects may be stripped, so they're read
+	// from the API server.
+	current, err := client.Namespace(namespace).Get(context.TODO(), obj.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("can't get %v to migrate its field ownership: %w", describeObject(obj), err)
+	}
+	if current.GetUID() != obj.GetUID() {
+		return nil, fmt.Errorf("can't migrate field ownership of %v: it was replaced", describeObject(obj))
+	}
+	obj = current
+	patch, err := fieldOwnershipMigrationPatch(obj, s.fieldManager, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	migrated, err := client.Namespace(namespace).Audited(s.auditRecorder(parent)).Patch(context.TODO(), obj.GetName(), types.JSONPatchType, patch, metav1.PatchOptions{DryRun: s.dryRunOptions()})
+	if err != nil {
+		return nil, fmt.Errorf("can't migrate field ownership of %v: %w", describeObject(obj), err)
+	}
+	return migrated, nil
+}
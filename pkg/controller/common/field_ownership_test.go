@@ -0,0 +1,91 @@
+package common
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	dynamicapply "metacontroller/pkg/dynamic/apply"
+)
+
+func TestFieldOwnershipMigrationPatch(t *testing.T) {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetName("config")
+	obj.SetResourceVersion("42")
+	obj.SetAnnotations(map[string]string{
+		dynamicapply.LastAppliedAnnotation: `{"data":{"a":"1"}}`,
+		MigrateFieldOwnershipAnnotation:    "kubectl-client-side-apply",
+		"keep":                             "me",
+	})
+	fields := func(raw string) *metav1.FieldsV1 { return &metav1.FieldsV1{Raw: []byte(raw)} }
+	obj.SetManagedFields([]metav1.ManagedFieldsEntry{
+		{Manager: "metacontroller", Operation: metav1.ManagedFieldsOperationUpdate, FieldsType: "FieldsV1", FieldsV1: fields(`{"f:data":{"f:a":{}}}`)},
+		{Manager: "kubectl-client-side-apply", Operation: metav1.ManagedFieldsOperationUpdate, FieldsType: "FieldsV1", FieldsV1: fields(`{"f:data":{"f:b":{}}}`)},
+		{Manager: "other", Operation: metav1.ManagedFieldsOperationUpdate, FieldsType: "FieldsV1", FieldsV1: fields(`{"f:data":{"f:c":{}}}`)},
+	})
+	now := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	patch, err := fieldOwnershipMigrationPatch(obj, "my-controller", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []struct {
+		Op    string          `json:"op"`
+		Path  string          `json:"path"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(patch, &got); err != nil {
+		t.Fatalf("can't unmarshal patch: %v", err)
+	}
+	var paths []string
+	for _, op := range got {
+		paths = append(paths, op.Op+" "+op.Path)
+	}
+	wantPaths := []string{
+		"replace /metadata/resourceVersion",
+		"replace /metadata/managedFields",
+		"remove /metadata/annotations/metacontroller.k8s.io~1last-applied-configuration",
+		"remove /metadata/annotations/metacontroller.k8s.io~1migrate-field-ownership",
+	}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Fatalf("expected operations %v, got %v", wantPaths, paths)
+	}
+
+	var entries []metav1.ManagedFieldsEntry
+	if err := json.Unmarshal(got[1].Value, &entries); err != nil {
+		t.Fatalf("can't unmarshal managedFields: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Manager != "other" {
+		t.Fatalf("expected the entry of other to be kept, got %v", entries)
+	}
+	applied := entries[1]
+	if applied.Manager != "my-controller" || applied.Operation != metav1.ManagedFieldsOperationApply {
+		t.Errorf("expected an Apply entry of my-controller, got %v", applied)
+	}
+	var appliedFields map[string]interface{}
+	if err := json.Unmarshal(applied.FieldsV1.Raw, &appliedFields); err != nil {
+		t.Fatalf("can't unmarshal fields: %v", err)
+	}
+	wantFields := map[string]interface{}{"f:data": map[string]interface{}{"f:a": map[string]interface{}{}, "f:b": map[string]interface{}{}}}
+	if !reflect.DeepEqual(appliedFields, wantFields) {
+		t.Errorf("expected fields %v, got %v", wantFields, appliedFields)
+	}
+}
+
+func TestNeedsFieldOwnershipMigration(t *testing.T) {
+	obj := &unstructured.Unstructured{}
+	if needsFieldOwnershipMigration(obj) {
+		t.Error("expected no migration without annotations")
+	}
+	obj.SetAnnotations(map[string]string{dynamicapply.LastAppliedAnnotation: "{}"})
+	if !needsFieldOwnershipMigration(obj) {
+		t.Error("expected migration with the last-applied-configuration annotation")
+	}
+}
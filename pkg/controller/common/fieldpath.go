@@ -0,0 +1,107 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// fieldPath is a path to a field of an object, made of map keys and, for
+// JSON Pointers, array indices.
+type fieldPath []string
+
+// parseFieldPath parses either a JSON Pointer (e.g. "/metadata/annotations/a~1b")
+// or a dot-separated path (e.g. "spec.replicas").
+func parseFieldPath(path string) (fieldPath, error) {
+	if path == "" || path == "/" {
+		return nil, fmt.Errorf("invalid path %q: must not be empty", path)
+	}
+	if !strings.HasPrefix(path, "/") {
+		return strings.Split(path, "."), nil
+	}
+	steps := strings.Split(path[1:], "/")
+	for i, step := range steps {
+		steps[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(step)
+	}
+	return steps, nil
+}
+
+// get returns the value at the path in obj, if any.
+func (p fieldPath) get(obj interface{}) (interface{}, bool) {
+	for _, step := range p {
+		switch node := obj.(type) {
+		case map[string]interface{}:
+			value, ok := node[step]
+			if !ok {
+				return nil, false
+			}
+			obj = value
+		case []interface{}:
+			index, err := strconv.Atoi(step)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			obj = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return obj, true
+}
+
+// set sets the value at the path in obj. It does nothing if the parent of
+// the field doesn't exist.
+func (p fieldPath) set(obj interface{}, value interface{}) {
+	parent, ok := p[:len(p)-1].get(obj)
+	if !ok {
+		return
+	}
+	last := p[len(p)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+	case []interface{}:
+		if index, err := strconv.Atoi(last); err == nil && index >= 0 && index < len(node) {
+			node[index] = value
+		}
+	}
+}
+
+// remove removes the field at the path in obj. Array items are never removed,
+// since that would shift the following ones.
+func (p fieldPath) remove(obj interface{}) {
+	parent, ok := p[:len(p)-1].get(obj)
+	if !ok {
+		return
+	}
+	if node, ok := parent.(map[string]interface{}); ok {
+		delete(node, p[len(p)-1])
+	}
+}
+
+// revert sets the value at the path in newObj back to what it is in orig.
+func (p fieldPath) revert(newObj, orig map[string]interface{}) {
+	if value, found := p.get(orig); found {
+		p.set(newObj, runtime.DeepCopyJSONValue(value))
+	} else {
+		p.remove(newObj)
+	}
+}
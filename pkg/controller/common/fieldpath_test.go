@@ -0,0 +1,61 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseFieldPath(t *testing.T) {
+	tests := map[string]fieldPath{
+		"spec.replicas":                        {"spec", "replicas"},
+		"/metadata/annotations/example.com~1a": {"metadata", "annotations", "example.com/a"},
+		"/spec/containers/1/image":             {"spec", "containers", "1", "image"},
+	}
+	for path, expected := range tests {
+		parsed, err := parseFieldPath(path)
+		if err != nil {
+			t.Fatalf("err should be nil for %q, got: %v", path, err)
+		}
+		if diff := cmp.Diff(expected, parsed); diff != "" {
+			t.Errorf("unexpected path for %q (-want +got):\n%s", path, diff)
+		}
+	}
+}
+
+func TestFieldPath_revert(t *testing.T) {
+	orig := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "main", "image": "main:1"},
+				map[string]interface{}{"name": "sidecar", "image": "sidecar:1"},
+			},
+		},
+	}
+	newObj := map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": map[string]interface{}{"injected": "false"}},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "main", "image": "main:2"},
+				map[string]interface{}{"name": "sidecar", "image": "sidecar:2"},
+			},
+		},
+	}
+	for _, path := range []string{"/spec/containers/1/image", "metadata.annotations.injected"} {
+		parsed, _ := parseFieldPath(path)
+		parsed.revert(newObj, orig)
+	}
+
+	expected := map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": map[string]interface{}{}},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "main", "image": "main:2"},
+				map[string]interface{}{"name": "sidecar", "image": "sidecar:1"},
+			},
+		},
+	}
+	if diff := cmp.Diff(expected, newObj); diff != "" {
+		t.Errorf("unexpected object (-want +got):\n%s", diff)
+	}
+}
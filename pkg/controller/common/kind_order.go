@@ -0,0 +1,107 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"sort"
+)
+
+// DefaultKindOrder is the order in which children are applied by default,
+// like kubectl and Helm do, so that, for example, namespaces, CRDs and RBAC
+// exist before the workloads which need them.
+var DefaultKindOrder = []string{
+	"Namespace",
+	"NetworkPolicy",
+	"ResourceQuota",
+	"LimitRange",
+	"PodSecurityPolicy",
+	"PodDisruptionBudget",
+	"ServiceAccount",
+	"Secret",
+	"ConfigMap",
+	"StorageClass",
+	"PersistentVolume",
+	"PersistentVolumeClaim",
+	"CustomResourceDefinition",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"Role",
+	"RoleBinding",
+	"Service",
+	"DaemonSet",
+	"Pod",
+	"ReplicationController",
+	"ReplicaSet",
+	"Deployment",
+	"HorizontalPodAutoscaler",
+	"StatefulSet",
+	"Job",
+	"CronJob",
+	"IngressClass",
+	"Ingress",
+	"APIService",
+}
+
+// KindOrder is the order in which children are created and updated, by kind.
+// They're deleted in the reverse order. Kinds which aren't listed come last,
+// and a nil *KindOrder uses DefaultKindOrder.
+type KindOrder struct {
+	ranks map[string]int
+}
+
+var defaultKindOrder = NewKindOrder(nil)
+
+// NewKindOrder returns the KindOrder of the given kinds, or of
+// DefaultKindOrder if there are none.
+func NewKindOrder(kinds []string) *KindOrder {
+	if len(kinds) == 0 {
+		kinds = DefaultKindOrder
+	}
+	order := &KindOrder{ranks: make(map[string]int, len(kinds))}
+	for i, kind := range kinds {
+		if _, ok := order.ranks[kind]; !ok {
+			order.ranks[kind] = i
+		}
+	}
+	return order
+}
+
+func (o *KindOrder) rank(kind string) int {
+	if o == nil {
+		o = defaultKindOrder
+	}
+	if rank, ok := o.ranks[kind]; ok {
+		return rank
+	}
+	return len(o.ranks)
+}
+
+// Sorted returns the kinds of m in apply order. Kinds of the same rank are
+// sorted by group, version and kind, so the order is stable.
+func (o *KindOrder) Sorted(m RelativeObjectMap) []GroupVersionKind {
+	keys := make([]GroupVersionKind, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if ri, rj := o.rank(keys[i].Kind), o.rank(keys[j].Kind); ri != rj {
+			return ri < rj
+		}
+		return keys[i].String() < keys[j].String()
+	})
+	return keys
+}
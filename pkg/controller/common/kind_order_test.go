@@ -0,0 +1,59 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestKindOrder_Sorted(t *testing.T) {
+	gvk := func(group, kind string) GroupVersionKind {
+		return GroupVersionKind{schema.GroupVersionKind{Group: group, Version: "v1", Kind: kind}}
+	}
+	children := RelativeObjectMap{
+		gvk("example.com", "Widget"):                            nil,
+		gvk("apps", "Deployment"):                               nil,
+		gvk("", "Namespace"):                                    nil,
+		gvk("apiextensions.k8s.io", "CustomResourceDefinition"): nil,
+		gvk("", "ConfigMap"):                                    nil,
+		gvk("example.com", "Gadget"):                            nil,
+	}
+	tests := []struct {
+		name     string
+		order    *KindOrder
+		expected []GroupVersionKind
+	}{
+		{
+			name:  "default",
+			order: nil,
+			expected: []GroupVersionKind{
+				gvk("", "Namespace"),
+				gvk("", "ConfigMap"),
+				gvk("apiextensions.k8s.io", "CustomResourceDefinition"),
+				gvk("apps", "Deployment"),
+				gvk("example.com", "Gadget"),
+				gvk("example.com", "Widget"),
+			},
+		},
+		{
+			name:  "override",
+			order: NewKindOrder([]string{"Widget", "ConfigMap"}),
+			expected: []GroupVersionKind{
+				gvk("example.com", "Widget"),
+				gvk("", "ConfigMap"),
+				gvk("", "Namespace"),
+				gvk("apiextensions.k8s.io", "CustomResourceDefinition"),
+				gvk("apps", "Deployment"),
+				gvk("example.com", "Gadget"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.expected, tt.order.Sorted(children)); diff != "" {
+				t.Errorf("unexpected order (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
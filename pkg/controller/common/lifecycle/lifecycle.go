@@ -0,0 +1,276 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lifecycle starts, restarts and stops the controllers of controller
+// objects, such as GlobalControllers, as they're created, updated and deleted.
+package lifecycle
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/events"
+	"metacontroller/pkg/hooks"
+	"metacontroller/pkg/logging"
+	"metacontroller/pkg/metrics"
+)
+
+// Controller is the controller started for a controller object.
+type Controller interface {
+	// Start starts the controller in the background.
+	Start()
+	// Stop stops the controller and waits for it to finish.
+	Stop()
+}
+
+// Kind adapts a kind of controller objects to a Reconciler, which only
+// handles them as client.Objects.
+type Kind interface {
+	// NewObject returns an empty controller object of this kind.
+	NewObject() client.Object
+	// Spec returns the spec of obj. Its controller is restarted when it changes.
+	Spec(obj client.Object) interface{}
+	// Status returns the conditions and the observedGeneration in the status
+	// of obj, to be updated in place.
+	Status(obj client.Object) (*[]metav1.Condition, *int64)
+	// DependsOn returns the controllers which must be Ready before the
+	// controller of obj is started.
+	DependsOn(obj client.Object) []v1alpha1.ControllerDependency
+	// RBACRules returns the permissions the controller of obj needs.
+	RBACRules(obj client.Object) []common.RBACRule
+	// Webhooks returns the hooks of obj which can be probed.
+	Webhooks(obj client.Object) map[common.HookType]*v1alpha1.Hook
+	// NewController creates the controller of obj, without starting it.
+	NewController(obj client.Object, logger logr.Logger) (Controller, error)
+}
+
+// Reconciler reconciles the controller objects of a Kind: it waits for their
+// dependencies and RBAC permissions, starts their controllers, restarts them
+// when their spec changes, stops them when they're deleted, and probes their
+// webhooks.
+type Reconciler struct {
+	kind           Kind
+	controllerType common.ControllerType
+
+	// k8sClient is a client used to interact with the Kubernetes API
+	k8sClient     client.Client
+	eventRecorder record.EventRecorder
+
+	controllers map[string]*runningController
+
+	rbacPreflight     bool
+	hookProbeInterval time.Duration
+
+	name   string
+	logger logr.Logger
+}
+
+// runningController is a started controller, with the object it was started
+// for.
+type runningController struct {
+	Controller
+	obj      client.Object
+	logLevel *logging.Level
+}
+
+// NewReconciler returns a Reconciler of the controller objects of kind, whose
+// type is controllerType. Its logs, and those of the controllers it starts,
+// are named name.
+func NewReconciler(controllerContext common.ControllerContext, controllerType common.ControllerType, kind Kind, rbacPreflight bool, hookProbeInterval time.Duration, name string) *Reconciler {
+	return &Reconciler{
+		kind:           kind,
+		controllerType: controllerType,
+
+		k8sClient:     controllerContext.K8sClient,
+		eventRecorder: controllerContext.EventRecorder,
+
+		controllers: make(map[string]*runningController),
+
+		rbacPreflight:     rbacPreflight,
+		hookProbeInterval: hookProbeInterval,
+
+		name:   name,
+		logger: logging.Logger.WithName(name),
+	}
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	name := request.Name
+	r.logger.V(4).Info("Sync "+r.controllerType.String(), "name", name)
+
+	obj := r.kind.NewObject()
+	err := r.k8sClient.Get(ctx, request.NamespacedName, obj)
+	if apierrors.IsNotFound(err) {
+		r.logger.V(4).Info(r.controllerType.String()+" has been deleted", "name", name)
+		// Stop and remove the controller if it exists.
+		var hookTypes []common.HookType
+		if c, ok := r.controllers[name]; ok {
+			for hookType := range r.kind.Webhooks(c.obj) {
+				hookTypes = append(hookTypes, hookType)
+			}
+			c.Stop()
+			defer r.eventRecorder.Eventf(
+				c.obj,
+				v1.EventTypeNormal,
+				events.ReasonStopped,
+				"Stopped controller: %s", name)
+			delete(r.controllers, name)
+		}
+		hooks.ForgetProbes(name, r.controllerType, hookTypes...)
+		metrics.ForgetAppliedGeneration(name, r.controllerType)
+		return reconcile.Result{}, nil
+	}
+	if err != nil {
+		r.eventRecorder.Eventf(
+			obj,
+			v1.EventTypeNormal,
+			events.ReasonSyncError,
+			"[%s] sync error - %s", obj.GetName(), err)
+		return reconcile.Result{}, err
+	}
+	if _, running := r.controllers[name]; !running {
+		unmet, err := common.UnmetDependencies(ctx, r.k8sClient, r.kind.DependsOn(obj))
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if len(unmet) > 0 {
+			r.logger.Info("Waiting for dependencies", "name", name, "dependencies", unmet)
+			return reconcile.Result{RequeueAfter: common.DependencyRecheckInterval}, r.updateConditions(ctx, obj, unmet)
+		}
+	}
+	if r.rbacPreflight && r.needsStart(obj) {
+		missing, err := common.MissingRBACRules(ctx, r.k8sClient, r.kind.RBACRules(obj))
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if len(missing) > 0 {
+			r.logger.Info("Missing RBAC permissions", "name", name, "rules", missing)
+			return reconcile.Result{RequeueAfter: common.RBACRecheckInterval}, r.updateMissingRBAC(ctx, obj, missing)
+		}
+	}
+	if err := r.reconcileController(obj); err != nil {
+		return reconcile.Result{}, err
+	}
+	if err := r.updateConditions(ctx, obj, nil); err != nil {
+		return reconcile.Result{}, err
+	}
+	if r.hookProbeInterval <= 0 {
+		return reconcile.Result{}, nil
+	}
+	// Probe the webhooks again after the interval.
+	unreachable := hooks.ProbeWebhooks(ctx, name, r.controllerType, r.kind.Webhooks(obj))
+	return reconcile.Result{RequeueAfter: r.hookProbeInterval}, r.updateHooksReachable(ctx, obj, unreachable)
+}
+
+func (r *Reconciler) updateConditions(ctx context.Context, obj client.Object, unmet []string) error {
+	conditions, observedGeneration := r.kind.Status(obj)
+	changed := common.SetDependencyConditions(conditions, obj.GetGeneration(), unmet)
+	if r.rbacPreflight && len(unmet) == 0 {
+		changed = common.SetMissingRBACCondition(conditions, obj.GetGeneration(), nil) || changed
+	}
+	if len(unmet) == 0 && *observedGeneration != obj.GetGeneration() {
+		// The running controller uses the current spec.
+		*observedGeneration = obj.GetGeneration()
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return r.k8sClient.Status().Update(ctx, obj)
+}
+
+func (r *Reconciler) updateMissingRBAC(ctx context.Context, obj client.Object, missing []common.RBACRule) error {
+	conditions, _ := r.kind.Status(obj)
+	if !common.SetMissingRBACCondition(conditions, obj.GetGeneration(), missing) {
+		return nil
+	}
+	return r.k8sClient.Status().Update(ctx, obj)
+}
+
+func (r *Reconciler) updateHooksReachable(ctx context.Context, obj client.Object, unreachable []string) error {
+	if len(unreachable) > 0 {
+		r.logger.Info("Unreachable webhooks", "name", obj.GetName(), "webhooks", unreachable)
+	}
+	conditions, _ := r.kind.Status(obj)
+	if !common.SetHooksReachableCondition(conditions, obj.GetGeneration(), unreachable) {
+		return nil
+	}
+	return r.k8sClient.Status().Update(ctx, obj)
+}
+
+// needsStart reports whether obj isn't running yet, or must be restarted
+// because its spec changed.
+func (r *Reconciler) needsStart(obj client.Object) bool {
+	running, ok := r.controllers[obj.GetName()]
+	return !ok || !apiequality.Semantic.DeepEqual(r.kind.Spec(obj), r.kind.Spec(running.obj))
+}
+
+func (r *Reconciler) reconcileController(obj client.Object) error {
+	name := obj.GetName()
+	if c, ok := r.controllers[name]; ok {
+		// The controller was already started.
+		if apiequality.Semantic.DeepEqual(r.kind.Spec(obj), r.kind.Spec(c.obj)) {
+			// Nothing has changed, but the log level may be.
+			common.SyncLogLevel(r.eventRecorder, obj, c.logLevel)
+			metrics.SetAppliedGeneration(name, r.controllerType, obj.GetGeneration())
+			return nil
+		}
+		r.logger.Info("Applying "+r.controllerType.String()+" spec change", "name", name,
+			"previousGeneration", c.obj.GetGeneration(), "generation", obj.GetGeneration())
+		// Stop and remove the controller so it can be recreated.
+		c.Stop()
+		r.eventRecorder.Eventf(
+			obj,
+			v1.EventTypeNormal,
+			events.ReasonStopped,
+			"Stopped controller: %s", name)
+		delete(r.controllers, name)
+		metrics.ForgetAppliedGeneration(name, r.controllerType)
+	}
+
+	logLevel := logging.NewLevel()
+	common.SyncLogLevel(r.eventRecorder, obj, logLevel)
+	c, err := r.kind.NewController(obj, logLevel.Logger().WithName(r.name))
+	if err != nil {
+		r.eventRecorder.Eventf(
+			obj,
+			v1.EventTypeWarning,
+			events.ReasonCreateError,
+			"Cannot create new controller: %s", err.Error())
+		return err
+	}
+	c.Start()
+	r.eventRecorder.Eventf(
+		obj,
+		v1.EventTypeNormal,
+		events.ReasonStarted,
+		"Started controller: %s", name)
+	r.controllers[name] = &runningController{Controller: c, obj: obj, logLevel: logLevel}
+	metrics.SetAppliedGeneration(name, r.controllerType, obj.GetGeneration())
+	r.logger.Info("Applied "+r.controllerType.String()+" spec", "name", name, "generation", obj.GetGeneration())
+	return nil
+}
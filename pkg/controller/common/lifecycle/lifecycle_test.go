@@ -0,0 +1,130 @@
+package lifecycle
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+)
+
+type fakeController struct {
+	running bool
+}
+
+func (c *fakeController) Start() { c.running = true }
+func (c *fakeController) Stop()  { c.running = false }
+
+// fakeKind runs fakeControllers for GlobalControllers.
+type fakeKind struct {
+	started []*fakeController
+}
+
+func (k *fakeKind) NewObject() client.Object {
+	return &v1alpha1.GlobalController{}
+}
+
+func (k *fakeKind) Spec(obj client.Object) interface{} {
+	return obj.(*v1alpha1.GlobalController).Spec
+}
+
+func (k *fakeKind) Status(obj client.Object) (*[]metav1.Condition, *int64) {
+	gc := obj.(*v1alpha1.GlobalController)
+	return &gc.Status.Conditions, &gc.Status.ObservedGeneration
+}
+
+func (k *fakeKind) DependsOn(obj client.Object) []v1alpha1.ControllerDependency {
+	return obj.(*v1alpha1.GlobalController).Spec.DependsOn
+}
+
+func (k *fakeKind) RBACRules(obj client.Object) []common.RBACRule {
+	return nil
+}
+
+func (k *fakeKind) Webhooks(obj client.Object) map[common.HookType]*v1alpha1.Hook {
+	return nil
+}
+
+func (k *fakeKind) NewController(obj client.Object, logger logr.Logger) (Controller, error) {
+	c := &fakeController{}
+	k.started = append(k.started, c)
+	return c, nil
+}
+
+func newTestReconciler(t *testing.T, objects ...client.Object) (*Reconciler, *fakeKind, client.Client) {
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+	kind := &fakeKind{}
+	controllerContext := common.ControllerContext{K8sClient: k8sClient, EventRecorder: record.NewFakeRecorder(100)}
+	return NewReconciler(controllerContext, common.GlobalController, kind, false, 0, "test"), kind, k8sClient
+}
+
+func reconcileTest(t *testing.T, r *Reconciler) {
+	t.Helper()
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: "test"}}
+	if _, err := r.Reconcile(context.TODO(), request); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+}
+
+func TestReconciler_Reconcile(t *testing.T) {
+	gc := &v1alpha1.GlobalController{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Generation: 1},
+		Spec:       v1alpha1.GlobalControllerSpec{ResyncPeriodSeconds: new(int32)},
+	}
+	r, kind, k8sClient := newTestReconciler(t, gc)
+
+	// The controller is started for a new object.
+	reconcileTest(t, r)
+	if len(kind.started) != 1 || !kind.started[0].running {
+		t.Fatalf("expected a running controller, got %v", kind.started)
+	}
+	got := &v1alpha1.GlobalController{}
+	if err := k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(gc), got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Status.ObservedGeneration != 1 {
+		t.Errorf("expected observedGeneration 1, got %d", got.Status.ObservedGeneration)
+	}
+
+	// It's kept as long as the spec doesn't change.
+	reconcileTest(t, r)
+	if len(kind.started) != 1 {
+		t.Fatalf("expected the controller to be kept, got %v", kind.started)
+	}
+
+	// It's restarted when the spec changes.
+	resync := int32(60)
+	got.Spec.ResyncPeriodSeconds = &resync
+	if err := k8sClient.Update(context.TODO(), got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reconcileTest(t, r)
+	if len(kind.started) != 2 || kind.started[0].running || !kind.started[1].running {
+		t.Fatalf("expected the controller to be restarted, got %v", kind.started)
+	}
+
+	// It's stopped when the object is deleted.
+	if err := k8sClient.Delete(context.TODO(), got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reconcileTest(t, r)
+	if kind.started[1].running {
+		t.Errorf("expected the controller to be stopped")
+	}
+	if len(r.controllers) != 0 {
+		t.Errorf("expected no running controllers, got %v", r.controllers)
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"metacontroller/pkg/events"
+	"metacontroller/pkg/logging"
+)
+
+// LogLevelAnnotation sets the verbosity of the logs of a controller, from 0
+// to MaxLogLevel, overriding the verbosity of Metacontroller.
+const LogLevelAnnotation = "metacontroller.k8s.io/log-level"
+
+// MaxLogLevel is the highest verbosity LogLevelAnnotation may set.
+const MaxLogLevel = 10
+
+// LogLevel returns the verbosity set by the LogLevelAnnotation of controller,
+// or nil if it has none.
+func LogLevel(controller client.Object) (*int, error) {
+	value, ok := controller.GetAnnotations()[LogLevelAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	level, err := strconv.Atoi(value)
+	if err != nil || level < 0 || level > MaxLogLevel {
+		return nil, fmt.Errorf("invalid %s %q: must be an integer from 0 to %d", LogLevelAnnotation, value, MaxLogLevel)
+	}
+	return &level, nil
+}
+
+// SyncLogLevel sets level to the verbosity set on controller, or back to the
+// verbosity of Metacontroller if it has none. An invalid verbosity is
+// reported with an event on controller, and ignored.
+func SyncLogLevel(recorder record.EventRecorder, controller client.Object, level *logging.Level) {
+	verbosity, err := LogLevel(controller)
+	if err != nil {
+		recorder.Eventf(controller, v1.EventTypeWarning, events.ReasonSyncError, "[%s] Sync error - %s", controller.GetName(), err)
+	}
+	level.Set(verbosity)
+}
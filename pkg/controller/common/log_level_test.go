@@ -0,0 +1,36 @@
+package common
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+func TestLogLevel(t *testing.T) {
+	tests := []struct {
+		annotations map[string]string
+		want        *int
+		wantErr     bool
+	}{
+		{annotations: nil},
+		{annotations: map[string]string{LogLevelAnnotation: "7"}, want: pointer.IntPtr(7)},
+		{annotations: map[string]string{LogLevelAnnotation: "0"}, want: pointer.IntPtr(0)},
+		{annotations: map[string]string{LogLevelAnnotation: "11"}, wantErr: true},
+		{annotations: map[string]string{LogLevelAnnotation: "-1"}, wantErr: true},
+		{annotations: map[string]string{LogLevelAnnotation: "debug"}, wantErr: true},
+	}
+	for _, tc := range tests {
+		controller := &v1alpha1.CompositeController{ObjectMeta: metav1.ObjectMeta{Name: "test", Annotations: tc.annotations}}
+		got, err := LogLevel(controller)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("LogLevel(%v) error = %v, wantErr %v", tc.annotations, err, tc.wantErr)
+			continue
+		}
+		if (got == nil) != (tc.want == nil) || (got != nil && *got != *tc.want) {
+			t.Errorf("LogLevel(%v) = %v, want %v", tc.annotations, got, tc.want)
+		}
+	}
+}
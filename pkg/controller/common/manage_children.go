@@ -20,29 +20,50 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"metacontroller/pkg/logging"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
 
+	"github.com/go-logr/logr"
 	"k8s.io/utils/pointer"
 
 	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/audit"
 	dynamicapply "metacontroller/pkg/dynamic/apply"
 	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	"metacontroller/pkg/tracing"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 )
 
-func ApplyUpdate(orig, update *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+func ApplyUpdate(orig, update *unstructured.Unstructured, logger logr.Logger) (*unstructured.Unstructured, error) {
 	// The controller only returns a partial object.
 	// We compute the full updated object in the style of "kubectl apply".
 	lastApplied, err := dynamicapply.GetLastApplied(orig)
 	if err != nil {
 		return nil, err
 	}
+	newObj, err := mergeUpdate(orig, lastApplied, update)
+	if err != nil {
+		return nil, err
+	}
+	if err = dynamicapply.SetLastApplied(newObj, update.UnstructuredContent()); err != nil {
+		logger.Error(err, "failed to set lastApplied")
+	}
+	return newObj, nil
+}
+
+// mergeUpdate merges update into orig, leaving system fields and status alone.
+func mergeUpdate(orig *unstructured.Unstructured, lastApplied map[string]interface{}, update *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	var err error
 	newObj := &unstructured.Unstructured{}
-	newObj.Object, err = dynamicapply.Merge(orig.UnstructuredContent(), lastApplied, update.UnstructuredContent())
+	// Built-in kinds are merged with their own patch strategies, like
+	// strategic merge patches.
+	patchMeta := dynamicapply.BuiltInPatchMeta(orig.GetAPIVersion(), orig.GetKind())
+	newObj.Object, err = dynamicapply.MergeWithPatchMeta(orig.UnstructuredContent(), lastApplied, update.UnstructuredContent(), patchMeta)
 	if err != nil {
 		return nil, err
 	}
@@ -58,9 +79,6 @@ func ApplyUpdate(orig, update *unstructured.Unstructured) (*unstructured.Unstruc
 	if err := revertField(newObj, orig, "status"); err != nil {
 		return nil, fmt.Errorf("failed to revert .status: %w", err)
 	}
-	if err = dynamicapply.SetLastApplied(newObj, update.UnstructuredContent()); err != nil {
-		logging.Logger.Error(err, "failed to set lastApplied")
-	}
 	return newObj, nil
 }
 
@@ -127,105 +145,260 @@ type ChildUpdateStrategy interface {
 	GetMethod(apiGroup, kind string) v1alpha1.ChildUpdateMethod
 }
 
-func ManageChildren(dynClient *dynamicclientset.Clientset, updateStrategy ChildUpdateStrategy, parent *unstructured.Unstructured, observedChildren, desiredChildren RelativeObjectMap) error {
+// ChildAction is an operation on a child object.
+type ChildAction string
+
+const (
+	ChildCreate ChildAction = "Create"
+	ChildUpdate ChildAction = "Update"
+	ChildDelete ChildAction = "Delete"
+)
+
+// ChildResult is the outcome of an operation on a child object.
+type ChildResult struct {
+	APIVersion string      `json:"apiVersion"`
+	Kind       string      `json:"kind"`
+	Namespace  string      `json:"namespace,omitempty"`
+	Name       string      `json:"name"`
+	Action     ChildAction `json:"action"`
+	// Result is either "Succeeded" or "Failed".
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+	// DryRun is whether the operation was only dry-run.
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// Object is the desired object, or the observed one for deletes.
+	Object *unstructured.Unstructured `json:"-"`
+	// Diff is the JSON merge patch an update makes to the object, if update
+	// diffs are reported or the update is dry-run. Otherwise, if the audit
+	// log is enabled, it's the patch of the update as sent.
+	Diff []byte `json:"-"`
+}
+
+// ChildFailure is a failed operation along with the object it was about.
+type ChildFailure struct {
+	ChildResult
+	Manifest *unstructured.Unstructured `json:"manifest"`
+}
+
+// ChildFailures returns the failed operations among results.
+func ChildFailures(results []ChildResult) []ChildFailure {
+	var failures []ChildFailure
+	for _, result := range results {
+		if result.Error != "" {
+			failures = append(failures, ChildFailure{ChildResult: result, Manifest: result.Object})
+		}
+	}
+	return failures
+}
+
+// FailureMessage describes a failed operation on a child.
+func (r ChildResult) FailureMessage() string {
+	return fmt.Sprintf("Can't %s %s: %s", strings.ToLower(string(r.Action)), r.describeChild(), r.Error)
+}
+
+// DryRunMessage describes an operation on a child which was only dry-run,
+// with the diff of updates.
+func (r ChildResult) DryRunMessage() string {
+	message := fmt.Sprintf("Would %s %s", strings.ToLower(string(r.Action)), r.describeChild())
+	if len(r.Diff) == 0 {
+		return message
+	}
+	return message + ": " + truncatePatch(r.Diff)
+}
+
+func (r ChildResult) describeChild() string {
+	if r.Namespace != "" {
+		return fmt.Sprintf("%s %s/%s", r.Kind, r.Namespace, r.Name)
+	}
+	return fmt.Sprintf("%s %s", r.Kind, r.Name)
+}
+
+// ChildApplyError is returned by ManageChildren when all its errors are
+// failed operations on children, which are described by their ChildResult.
+type ChildApplyError struct {
+	Err error
+}
+
+func (e *ChildApplyError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ChildApplyError) Unwrap() error {
+	return e.Err
+}
+
+// childResults collects the ChildResult of each operation.
+type childResults []ChildResult
+
+func (r *childResults) add(action ChildAction, obj *unstructured.Unstructured, namespace string, err error) {
+	result := ChildResult{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  namespace,
+		Name:       obj.GetName(),
+		Action:     action,
+		Result:     "Succeeded",
+		Object:     obj,
+	}
+	if err != nil {
+		result.Result = "Failed"
+		result.Error = err.Error()
+	}
+	*r = append(*r, result)
+}
+
+// setLastDiff sets the update diff of the last result.
+func (r childResults) setLastDiff(diff []byte) {
+	r[len(r)-1].Diff = diff
+}
+
+// ManageChildren deletes, creates and updates children so they match the
+// desired ones, and returns the result of each operation it attempted.
+// Children are created and updated in kindOrder, and deleted in the reverse
+// order. The children of the same kind are written concurrently, up to the
+// concurrency of applyStrategies, and only dry-run if it says so.
+// The ID of the sync of ctx, if any, is logged with each operation.
+func ManageChildren(ctx context.Context, dynClient *dynamicclientset.Clientset, updateStrategy ChildUpdateStrategy, applyStrategies *ChildApplyStrategies, kindOrder *KindOrder, parent *unstructured.Unstructured, observedChildren, desiredChildren RelativeObjectMap) ([]ChildResult, error) {
 	// If some operations fail, keep trying others so, for example,
 	// we don't block recovery (create new Pod) on a failed delete.
 	var errs []error
+	var results childResults
 
 	// Delete observed, owned objects that are not desired.
-	for key, objects := range observedChildren {
+	deleteOrder := kindOrder.Sorted(observedChildren)
+	for i := len(deleteOrder) - 1; i >= 0; i-- {
+		key := deleteOrder[i]
 		client, err := dynClient.Kind(key.GroupVersion().String(), key.Kind)
 		if err != nil {
 			errs = append(errs, err)
 			continue
 		}
-		if err := deleteChildren(client, parent, objects, desiredChildren[key]); err != nil {
+		if err := deleteChildren(ctx, client, applyStrategies, parent, observedChildren[key], desiredChildren[key], &results); err != nil {
 			errs = append(errs, err)
 			continue
 		}
 	}
 
 	// Create or update desired objects.
-	for key, objects := range desiredChildren {
+	appliedCRDs := false
+	for _, key := range kindOrder.Sorted(desiredChildren) {
+		objects := desiredChildren[key]
 		client, err := dynClient.Kind(key.GroupVersion().String(), key.Kind)
+		if err != nil && appliedCRDs {
+			// The kind may be served by a CRD applied just before, which
+			// discovery doesn't know about yet.
+			dynClient.RefreshDiscovery()
+			appliedCRDs = false
+			client, err = dynClient.Kind(key.GroupVersion().String(), key.Kind)
+		}
 		if err != nil {
 			errs = append(errs, err)
 			continue
 		}
-		if err := updateChildren(client, updateStrategy, parent, observedChildren[key], objects); err != nil {
+		if err := updateChildren(ctx, client, updateStrategy, applyStrategies, parent, observedChildren[key], objects, &results); err != nil {
 			errs = append(errs, err)
 			continue
 		}
+		if key.Group == "apiextensions.k8s.io" && key.Kind == "CustomResourceDefinition" {
+			appliedCRDs = true
+		}
 	}
 
-	return utilerrors.NewAggregate(errs)
+	if applyStrategies.DryRun() {
+		for i := range results {
+			results[i].DryRun = true
+		}
+	}
+
+	err := utilerrors.NewAggregate(errs)
+	if err != nil && len(utilerrors.Flatten(err).Errors()) == len(ChildFailures(results)) {
+		// Every error is the failure of an operation on a child.
+		return results, &ChildApplyError{Err: err}
+	}
+	return results, err
 }
 
-func deleteChildren(client *dynamicclientset.ResourceClient, parent *unstructured.Unstructured, observed, desired map[string]*unstructured.Unstructured) error {
-	var errs []error
-	for name, obj := range observed {
+func deleteChildren(ctx context.Context, client *dynamicclientset.ResourceClient, applyStrategies *ChildApplyStrategies, parent *unstructured.Unstructured, observed, desired map[string]*unstructured.Unstructured, results *childResults) error {
+	syncID := tracing.SyncID(ctx)
+	return forEachChild(applyStrategies.Concurrency(), observed, results, func(name string, obj *unstructured.Unstructured, results *childResults) error {
 		if obj.GetDeletionTimestamp() != nil {
 			// Skip objects that are already pending deletion.
-			continue
+			return nil
 		}
-		if desired == nil || desired[name] == nil {
-			// This observed object wasn't listed as desired.
-			logging.Logger.Info("Deleting child", "parent", parent, "child", obj)
-			uid := obj.GetUID()
-			// Explicitly request deletion propagation, which is what users expect,
-			// since some objects default to orphaning for backwards compatibility.
-			propagation := metav1.DeletePropagationBackground
-			err := client.Namespace(obj.GetNamespace()).Delete(
-				context.TODO(),
-				obj.GetName(),
-				metav1.DeleteOptions{
-					Preconditions:     &metav1.Preconditions{UID: &uid},
-					PropagationPolicy: &propagation,
-				},
-			)
-			if err != nil {
-				errs = append(errs, fmt.Errorf("can't delete %v: %w", describeObject(obj), err))
-				continue
-			}
+		if desired != nil && desired[name] != nil {
+			return nil
 		}
-	}
-	return utilerrors.NewAggregate(errs)
+		// This observed object wasn't listed as desired.
+		applyStrategies.Logger().Info("Deleting child", "parent", parent, "child", obj, "syncID", syncID)
+		uid := obj.GetUID()
+		// Explicitly request deletion propagation, which is what users expect,
+		// since some objects default to orphaning for backwards compatibility.
+		propagation := metav1.DeletePropagationBackground
+		err := client.Namespace(obj.GetNamespace()).Delete(
+			ctx,
+			obj.GetName(),
+			metav1.DeleteOptions{
+				Preconditions:     &metav1.Preconditions{UID: &uid},
+				PropagationPolicy: &propagation,
+				DryRun:            applyStrategies.dryRunOptions(),
+			},
+		)
+		results.add(ChildDelete, obj, obj.GetNamespace(), err)
+		if err != nil {
+			return fmt.Errorf("can't delete %v: %w", describeObject(obj), err)
+		}
+		return nil
+	})
 }
 
-func updateChildren(client *dynamicclientset.ResourceClient, updateStrategy ChildUpdateStrategy, parent *unstructured.Unstructured, observed, desired map[string]*unstructured.Unstructured) error {
-	var errs []error
-	for name, obj := range desired {
+func updateChildren(ctx context.Context, client *dynamicclientset.ResourceClient, updateStrategy ChildUpdateStrategy, applyStrategies *ChildApplyStrategies, parent *unstructured.Unstructured, observed, desired map[string]*unstructured.Unstructured, results *childResults) error {
+	serverSide := applyStrategies.Get(client.Group, client.Kind) == v1alpha1.ChildApplyServerSide
+	logger := applyStrategies.Logger()
+	syncID := tracing.SyncID(ctx)
+	return forEachChild(applyStrategies.Concurrency(), desired, results, func(name string, obj *unstructured.Unstructured, results *childResults) error {
 		ns := obj.GetNamespace()
 		if ns == "" {
 			ns = parent.GetNamespace()
 		}
 		if oldObj := observed[name]; oldObj != nil {
 			// Update
-			newObj, err := ApplyUpdate(oldObj, obj)
+			if serverSide && needsFieldOwnershipMigration(oldObj) {
+				// Take over the fields of client-side updates first, so the
+				// server-side apply removes them once they're not desired.
+				logger.Info("Migrating field ownership to server-side apply", "parent", parent, "child", obj, "syncID", syncID)
+				migrated, err := applyStrategies.migrateFieldOwnership(client, parent, ns, oldObj)
+				if err != nil {
+					results.add(ChildUpdate, obj, ns, err)
+					return err
+				}
+				oldObj = migrated
+			}
+			newObj, err := applyStrategies.ApplyUpdate(oldObj, obj)
 			if err != nil {
-				errs = append(errs, err)
-				continue
+				results.add(ChildUpdate, obj, ns, err)
+				return err
 			}
 
 			// Attempt an update, if the 3-way merge resulted in any changes.
 			if reflect.DeepEqual(newObj.UnstructuredContent(), oldObj.UnstructuredContent()) {
 				// Nothing changed.
-				continue
+				return nil
 			}
-			if logging.Logger.V(5).Enabled() {
+			if logger.V(5).Enabled() {
 				mergePatch, err := JsonMergePatch(oldObj, newObj)
 				if err != nil {
-					logging.Logger.V(5).Error(err, "Cannot create merge patch to visualize diff")
+					logger.V(5).Error(err, "Cannot create merge patch to visualize diff")
 				} else {
 					rawMergePatch := json.RawMessage(mergePatch)
-					logging.Logger.V(5).Info("Diff between observed and desired", "mergePatch", rawMergePatch)
+					logger.V(5).Info("Diff between observed and desired", "mergePatch", rawMergePatch)
 				}
 			}
 
 			// Leave it alone if it's pending deletion.
 			if oldObj.GetDeletionTimestamp() != nil {
-				logging.Logger.Info("Not updating", "parent", parent, "child", obj, "reason", "Pending deletion of child object")
-				continue
+				logger.Info("Not updating", "parent", parent, "child", obj, "syncID", syncID, "reason", "Pending deletion of child object")
+				return nil
 			}
 
 			// Check the update strategy for this child kind.
@@ -233,63 +406,131 @@ func updateChildren(client *dynamicclientset.ResourceClient, updateStrategy Chil
 			case v1alpha1.ChildUpdateOnDelete, "":
 				// This means we don't try to update anything unless it gets deleted
 				// by someone else (we won't delete it ourselves).
-				logging.Logger.V(5).Info("Not updating", "parent", parent, "child", obj, "reason", "OnDelete update strategy selected")
-				continue
+				logger.V(5).Info("Not updating", "parent", parent, "child", obj, "reason", "OnDelete update strategy selected")
+				return nil
 			case v1alpha1.ChildUpdateRecreate, v1alpha1.ChildUpdateRollingRecreate:
 				// Delete the object (now) and recreate it (on the next sync).
-				logging.Logger.Info("Deleting for update", "parent", parent, "child", obj, "reason", "Recreate update strategy selected")
+				logger.Info("Deleting for update", "parent", parent, "child", obj, "syncID", syncID, "reason", "Recreate update strategy selected")
 				uid := oldObj.GetUID()
 				// Explicitly request deletion propagation, which is what users expect,
 				// since some objects default to orphaning for backwards compatibility.
 				propagation := metav1.DeletePropagationBackground
 				err := client.Namespace(ns).Delete(
-					context.TODO(),
+					ctx,
 					obj.GetName(),
 					metav1.DeleteOptions{
 						Preconditions:     &metav1.Preconditions{UID: &uid},
 						PropagationPolicy: &propagation,
+						DryRun:            applyStrategies.dryRunOptions(),
 					},
 				)
-				if err != nil {
-					errs = append(errs, err)
-					continue
+				results.add(ChildDelete, oldObj, ns, err)
+				return err
+			case v1alpha1.ChildUpdateInPlace, v1alpha1.ChildUpdateRollingInPlace, v1alpha1.ChildUpdateBlueGreen:
+				// Update the object in-place. With BlueGreen, only a child whose
+				// name matches its desired content is observed, so this only
+				// reverts changes made by others.
+				logger.Info("Updating", "parent", parent, "child", obj, "syncID", syncID, "reason", "Recreate update strategy selected")
+				var diff []byte
+				if applyStrategies.DryRun() {
+					// Dry-running the update also tells what it changes.
+					diff, err = dryRunUpdateDiff(client, applyStrategies, ns, parent, oldObj, newObj, obj)
+					results.add(ChildUpdate, obj, ns, err)
+					results.setLastDiff(diff)
+					return err
 				}
-			case v1alpha1.ChildUpdateInPlace, v1alpha1.ChildUpdateRollingInPlace:
-				// Update the object in-place.
-				logging.Logger.Info("Updating", "parent", parent, "child", obj, "reason", "Recreate update strategy selected")
-				if _, err := client.Namespace(ns).Update(context.TODO(), newObj, metav1.UpdateOptions{}); err != nil {
-					errs = append(errs, err)
-					continue
+				if applyStrategies.UpdateDiff() != nil {
+					diff, err = dryRunUpdateDiff(client, applyStrategies, ns, parent, oldObj, newObj, obj)
+					if err != nil {
+						logger.Error(err, "Can't compute update diff", "parent", parent, "child", obj, "syncID", syncID)
+					} else {
+						logger.Info("Update diff", "parent", parent, "child", obj, "syncID", syncID, "diff", json.RawMessage(diff))
+					}
+				} else if audit.Log.Enabled() {
+					// The audit log gets the patch of the update as sent.
+					diff, _ = JsonMergePatch(oldObj, newObj)
 				}
+				if serverSide {
+					err = applyStrategies.serverSideApply(client, ns, parent, obj)
+				} else {
+					_, err = client.Namespace(ns).Update(ctx, newObj, metav1.UpdateOptions{})
+				}
+				results.add(ChildUpdate, obj, ns, err)
+				results.setLastDiff(diff)
+				return err
 			default:
-				errs = append(errs, fmt.Errorf("invalid update strategy for %v: unknown method %q", client.Kind, method))
-				continue
-			}
-		} else {
-			// Create
-			logging.Logger.Info("Creating", "parent", parent, "child", obj)
-
-			// The controller should return a partial object containing only the
-			// fields it cares about. We save this partial object so we can do
-			// a 3-way merge upon update, in the style of "kubectl apply".
-			//
-			// Make sure this happens before we add anything else to the object.
-			if err := dynamicapply.SetLastApplied(obj, obj.UnstructuredContent()); err != nil {
-				errs = append(errs, err)
-				continue
+				err := fmt.Errorf("invalid update strategy for %v: unknown method %q", client.Kind, method)
+				results.add(ChildUpdate, obj, ns, err)
+				return err
 			}
+		}
+
+		// Create
+		logger.Info("Creating", "parent", parent, "child", obj, "syncID", syncID)
+
+		if serverSide {
+			err := applyStrategies.serverSideApply(client, ns, parent, obj)
+			results.add(ChildCreate, obj, ns, err)
+			return err
+		}
+
+		// The controller should return a partial object containing only the
+		// fields it cares about. We save this partial object so we can do
+		// a 3-way merge upon update, in the style of "kubectl apply".
+		//
+		// Make sure this happens before we add anything else to the object.
+		if err := dynamicapply.SetLastApplied(obj, obj.UnstructuredContent()); err != nil {
+			results.add(ChildCreate, obj, ns, err)
+			return err
+		}
 
-			// We always claim everything we create.
+		// We always claim everything we create, through labels
+		// if the child can't have an ownerReference to the parent.
+		if canOwnByReference(parent, client.Namespaced, ns) {
 			controllerRef := MakeControllerRef(parent)
 			ownerRefs := obj.GetOwnerReferences()
 			ownerRefs = append(ownerRefs, *controllerRef)
 			obj.SetOwnerReferences(ownerRefs)
-
-			if _, err := client.Namespace(ns).Create(context.TODO(), obj, metav1.CreateOptions{}); err != nil {
-				errs = append(errs, err)
-				continue
-			}
 		}
+
+		_, err := client.Namespace(ns).Create(ctx, obj, metav1.CreateOptions{DryRun: applyStrategies.dryRunOptions()})
+		results.add(ChildCreate, obj, ns, err)
+		return err
+	})
+}
+
+// forEachChild calls fn for each of objects, running up to concurrency calls
+// at once. The results and errors of the calls are collected in the order of
+// the object names, so they don't depend on which call finishes first.
+func forEachChild(concurrency int, objects map[string]*unstructured.Unstructured, results *childResults, fn func(name string, obj *unstructured.Unstructured, results *childResults) error) error {
+	names := make([]string, 0, len(objects))
+	for name := range objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	objectResults := make([]childResults, len(names))
+	errs := make([]error, len(names))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(i int, name string) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			errs[i] = fn(name, objects[name], &objectResults[i])
+		}(i, name)
+	}
+	wg.Wait()
+
+	for _, r := range objectResults {
+		*results = append(*results, r...)
 	}
 	return utilerrors.NewAggregate(errs)
 }
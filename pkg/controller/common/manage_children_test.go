@@ -1,8 +1,11 @@
 package common
 
 import (
+	"errors"
 	"reflect"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 
@@ -65,3 +68,94 @@ func TestRevertObjectMetaSystemFields(t *testing.T) {
 		t.Fatalf("revertObjectMetaSystemFields() = %#v, want %#v", got, want)
 	}
 }
+
+func TestChildResults_add(t *testing.T) {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("Pod")
+	obj.SetName("test")
+	var results childResults
+
+	results.add(ChildCreate, obj, "default", nil)
+	results.add(ChildDelete, obj, "default", errors.New("forbidden"))
+
+	expected := childResults{
+		{APIVersion: "v1", Kind: "Pod", Namespace: "default", Name: "test", Action: ChildCreate, Result: "Succeeded", Object: obj},
+		{APIVersion: "v1", Kind: "Pod", Namespace: "default", Name: "test", Action: ChildDelete, Result: "Failed", Error: "forbidden", Object: obj},
+	}
+	if diff := cmp.Diff(expected, results); diff != "" {
+		t.Errorf("unexpected results (-want +got):\n%s", diff)
+	}
+
+	failures := ChildFailures(results)
+	if len(failures) != 1 || failures[0].Action != ChildDelete || failures[0].Manifest != obj {
+		t.Errorf("expected only the failed delete, got: %+v", failures)
+	}
+}
+
+func TestChildResult_FailureMessage(t *testing.T) {
+	namespaced := ChildResult{Kind: "Pod", Namespace: "default", Name: "test", Action: ChildCreate, Error: "forbidden"}
+	if got, want := namespaced.FailureMessage(), "Can't create Pod default/test: forbidden"; got != want {
+		t.Errorf("FailureMessage() = %q, want %q", got, want)
+	}
+	cluster := ChildResult{Kind: "ClusterRole", Name: "test", Action: ChildDelete, Error: "forbidden"}
+	if got, want := cluster.FailureMessage(), "Can't delete ClusterRole test: forbidden"; got != want {
+		t.Errorf("FailureMessage() = %q, want %q", got, want)
+	}
+}
+
+func TestChildResult_DryRunMessage(t *testing.T) {
+	update := ChildResult{Kind: "Deployment", Namespace: "default", Name: "frontend", Action: ChildUpdate, Diff: []byte(`{"spec":{"replicas":3}}`), DryRun: true}
+	if got, want := update.DryRunMessage(), `Would update Deployment default/frontend: {"spec":{"replicas":3}}`; got != want {
+		t.Errorf("DryRunMessage() = %q, want %q", got, want)
+	}
+	create := ChildResult{Kind: "ClusterRole", Name: "frontend", Action: ChildCreate, DryRun: true}
+	if got, want := create.DryRunMessage(), "Would create ClusterRole frontend"; got != want {
+		t.Errorf("DryRunMessage() = %q, want %q", got, want)
+	}
+}
+
+func TestForEachChild_boundsConcurrencyAndOrdersResults(t *testing.T) {
+	objects := make(map[string]*unstructured.Unstructured)
+	for _, name := range []string{"c", "a", "e", "b", "d"} {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind("Pod")
+		obj.SetName(name)
+		objects[name] = obj
+	}
+	var running, maxRunning int32
+	var results childResults
+
+	err := forEachChild(2, objects, &results, func(name string, obj *unstructured.Unstructured, results *childResults) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		if name == "b" {
+			results.add(ChildCreate, obj, "default", errors.New("forbidden"))
+			return errors.New("forbidden")
+		}
+		results.add(ChildCreate, obj, "default", nil)
+		return nil
+	})
+
+	if err == nil {
+		t.Errorf("expected the error of b")
+	}
+	if maxRunning != 2 {
+		t.Errorf("expected 2 calls at once, got: %d", maxRunning)
+	}
+	var names []string
+	for _, result := range results {
+		names = append(names, result.Name)
+	}
+	if diff := cmp.Diff([]string{"a", "b", "c", "d", "e"}, names); diff != "" {
+		t.Errorf("unexpected result order (-want +got):\n%s", diff)
+	}
+}
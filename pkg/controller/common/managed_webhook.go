@@ -0,0 +1,201 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/audit"
+)
+
+const (
+	// ManagedWebhookLabel is the label selecting the pods of the managed
+	// webhook of a controller.
+	ManagedWebhookLabel = "metacontroller.k8s.io/managed-webhook"
+
+	defaultManagedWebhookPort  int32 = 8080
+	managedWebhookServicePort  int32 = 80
+	managedWebhookContainer          = "webhook"
+	managedWebhookFieldManager       = "metacontroller"
+)
+
+// ManagedWebhookName returns the name of the Deployment and Service of the
+// managed webhook of a controller.
+func ManagedWebhookName(controllerType ControllerType, controllerName string) (string, error) {
+	name := strings.ToLower(controllerType.String()) + "-" + controllerName
+	if errs := validation.IsDNS1035Label(name); len(errs) > 0 {
+		return "", fmt.Errorf("invalid managed webhook name %q: %s", name, strings.Join(errs, ", "))
+	}
+	return name, nil
+}
+
+// SyncManagedWebhook applies the Deployment and Service of the managed
+// webhook of a controller, and deletes the ones of its previous managed
+// webhook if it moved or was removed. It returns the new status of the
+// managed webhook.
+func SyncManagedWebhook(
+	ctx context.Context,
+	k8sClient client.Client,
+	controllerType ControllerType,
+	controllerName string,
+	ownerRef metav1.OwnerReference,
+	managed *v1alpha1.ManagedWebhook,
+	previous *v1alpha1.ManagedWebhookStatus) (*v1alpha1.ManagedWebhookStatus, error) {
+	// The writes are recorded on behalf of the controller itself.
+	recorder := audit.Recorder{
+		Controller: audit.Controller{Name: controllerName, Type: controllerType.String()},
+		Parent:     audit.Reference{APIVersion: ownerRef.APIVersion, Kind: ownerRef.Kind, Name: ownerRef.Name},
+	}
+	var status *v1alpha1.ManagedWebhookStatus
+	if managed != nil {
+		name, err := ManagedWebhookName(controllerType, controllerName)
+		if err != nil {
+			return previous, err
+		}
+		deployment, service := managedWebhookObjects(name, ownerRef, managed)
+		for _, obj := range []client.Object{deployment, service} {
+			err := k8sClient.Patch(ctx, obj, client.Apply, client.FieldOwner(managedWebhookFieldManager), client.ForceOwnership)
+			recorder.Record(managedWebhookReference(obj), audit.ActionPatch, "", false, err)
+			if err != nil {
+				return previous, fmt.Errorf("can't apply managed webhook %s %s/%s: %w", obj.GetObjectKind().GroupVersionKind().Kind, managed.Namespace, name, err)
+			}
+		}
+		status = &v1alpha1.ManagedWebhookStatus{Name: name, Namespace: managed.Namespace}
+	}
+	if previous != nil && (status == nil || *previous != *status) {
+		for _, obj := range []client.Object{&appsv1.Deployment{}, &corev1.Service{}} {
+			obj.SetName(previous.Name)
+			obj.SetNamespace(previous.Namespace)
+			err := k8sClient.Delete(ctx, obj)
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			recorder.Record(managedWebhookReference(obj), audit.ActionDelete, "", false, err)
+			if err != nil {
+				return previous, fmt.Errorf("can't delete previous managed webhook %s/%s: %w", previous.Namespace, previous.Name, err)
+			}
+		}
+	}
+	return status, nil
+}
+
+// managedWebhookReference returns a reference to obj, the Deployment or Service
+// of a managed webhook.
+func managedWebhookReference(obj client.Object) audit.Reference {
+	reference := audit.Reference{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+	switch obj.(type) {
+	case *appsv1.Deployment:
+		reference.APIVersion, reference.Kind = "apps/v1", "Deployment"
+	case *corev1.Service:
+		reference.APIVersion, reference.Kind = "v1", "Service"
+	}
+	return reference
+}
+
+// managedWebhookObjects returns the desired Deployment and Service of a
+// managed webhook.
+func managedWebhookObjects(name string, ownerRef metav1.OwnerReference, managed *v1alpha1.ManagedWebhook) (*appsv1.Deployment, *corev1.Service) {
+	port := defaultManagedWebhookPort
+	if managed.Port != nil {
+		port = *managed.Port
+	}
+	labels := map[string]string{ManagedWebhookLabel: name}
+	objectMeta := metav1.ObjectMeta{
+		Name:            name,
+		Namespace:       managed.Namespace,
+		Labels:          map[string]string{"app.kubernetes.io/managed-by": "metacontroller", ManagedWebhookLabel: name},
+		OwnerReferences: []metav1.OwnerReference{ownerRef},
+	}
+	container := corev1.Container{
+		Name:    managedWebhookContainer,
+		Image:   managed.Image,
+		Command: managed.Command,
+		Args:    managed.Args,
+		Env:     managed.Env,
+		Ports: []corev1.ContainerPort{{
+			Name:          "http",
+			ContainerPort: port,
+			Protocol:      corev1.ProtocolTCP,
+		}},
+	}
+	if managed.Resources != nil {
+		container.Resources = *managed.Resources
+	}
+	deployment := &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: objectMeta,
+		Spec: appsv1.DeploymentSpec{
+			Replicas: managed.Replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: managed.ServiceAccountName,
+					Containers:         []corev1.Container{container},
+				},
+			},
+		},
+	}
+	service := &corev1.Service{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: *objectMeta.DeepCopy(),
+		Spec: corev1.ServiceSpec{
+			Selector: labels,
+			Ports: []corev1.ServicePort{{
+				Name:       "http",
+				Port:       managedWebhookServicePort,
+				TargetPort: intstr.FromString("http"),
+				Protocol:   corev1.ProtocolTCP,
+			}},
+		},
+	}
+	return deployment, service
+}
+
+// ApplyManagedWebhookService points the webhooks which only set a path at
+// the Service of a managed webhook.
+func ApplyManagedWebhookService(hooks []*v1alpha1.Hook, status *v1alpha1.ManagedWebhookStatus) {
+	if status == nil {
+		return
+	}
+	for _, hook := range hooks {
+		if hook == nil || hook.Webhook == nil {
+			continue
+		}
+		webhook := hook.Webhook
+		if webhook.URL != nil || webhook.Service != nil || webhook.Path == nil {
+			continue
+		}
+		port := managedWebhookServicePort
+		webhook.Service = &v1alpha1.ServiceReference{
+			Name:      status.Name,
+			Namespace: status.Namespace,
+			Port:      &port,
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/audit"
+)
+
+func TestManagedWebhookName(t *testing.T) {
+	name, err := ManagedWebhookName(CompositeController, "bluegreen")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name != "compositecontroller-bluegreen" {
+		t.Errorf("expected compositecontroller-bluegreen, got %s", name)
+	}
+	if _, err := ManagedWebhookName(DecoratorController, "has.dots"); err == nil {
+		t.Error("expected an error for a name which isn't a valid Service name")
+	}
+}
+
+func TestManagedWebhookObjects(t *testing.T) {
+	ownerRef := metav1.OwnerReference{Name: "bluegreen", Kind: "CompositeController"}
+	managed := &v1alpha1.ManagedWebhook{
+		Image:     "example/hooks:v1",
+		Namespace: "hooks",
+		Replicas:  pointer.Int32Ptr(2),
+		Port:      pointer.Int32Ptr(3000),
+		Args:      []string{"--verbose"},
+	}
+
+	deployment, service := managedWebhookObjects("compositecontroller-bluegreen", ownerRef, managed)
+
+	if deployment.Namespace != "hooks" || service.Namespace != "hooks" {
+		t.Errorf("expected objects in namespace hooks, got %s and %s", deployment.Namespace, service.Namespace)
+	}
+	if len(deployment.OwnerReferences) != 1 || deployment.OwnerReferences[0].Name != "bluegreen" {
+		t.Errorf("expected deployment owned by bluegreen, got %v", deployment.OwnerReferences)
+	}
+	if *deployment.Spec.Replicas != 2 {
+		t.Errorf("expected 2 replicas, got %d", *deployment.Spec.Replicas)
+	}
+	container := deployment.Spec.Template.Spec.Containers[0]
+	if container.Image != "example/hooks:v1" || container.Ports[0].ContainerPort != 3000 {
+		t.Errorf("unexpected container %v", container)
+	}
+	selector := deployment.Spec.Selector.MatchLabels[ManagedWebhookLabel]
+	if selector != "compositecontroller-bluegreen" || service.Spec.Selector[ManagedWebhookLabel] != selector {
+		t.Errorf("expected service to select the deployment pods, got %v and %v", deployment.Spec.Selector, service.Spec.Selector)
+	}
+	if service.Spec.Ports[0].Port != 80 || service.Spec.Ports[0].TargetPort.StrVal != "http" {
+		t.Errorf("unexpected service port %v", service.Spec.Ports[0])
+	}
+}
+
+func TestApplyManagedWebhookService(t *testing.T) {
+	sync := &v1alpha1.Hook{Webhook: &v1alpha1.Webhook{Path: pointer.StringPtr("/sync")}}
+	finalize := &v1alpha1.Hook{Webhook: &v1alpha1.Webhook{URL: pointer.StringPtr("http://other/finalize")}}
+	status := &v1alpha1.ManagedWebhookStatus{Name: "compositecontroller-bluegreen", Namespace: "hooks"}
+
+	ApplyManagedWebhookService([]*v1alpha1.Hook{sync, finalize, nil}, status)
+
+	service := sync.Webhook.Service
+	if service == nil || service.Name != status.Name || service.Namespace != "hooks" || *service.Port != 80 {
+		t.Errorf("expected sync hook pointed at the managed webhook, got %v", service)
+	}
+	if finalize.Webhook.Service != nil {
+		t.Errorf("expected finalize hook with URL unchanged, got %v", finalize.Webhook.Service)
+	}
+}
+
+func TestSyncManagedWebhook_DeletesPrevious(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	objectMeta := metav1.ObjectMeta{Name: "compositecontroller-bluegreen", Namespace: "hooks"}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&appsv1.Deployment{ObjectMeta: objectMeta},
+		&corev1.Service{ObjectMeta: objectMeta},
+	).Build()
+	previous := &v1alpha1.ManagedWebhookStatus{Name: objectMeta.Name, Namespace: objectMeta.Namespace}
+	var auditLog bytes.Buffer
+	audit.Log.SetOutput(&auditLog)
+	defer audit.Log.SetOutput(nil)
+
+	status, err := SyncManagedWebhook(context.Background(), k8sClient, CompositeController, "bluegreen", metav1.OwnerReference{}, nil, previous)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status != nil {
+		t.Errorf("expected no status, got %v", status)
+	}
+	for _, obj := range []client.Object{&appsv1.Deployment{}, &corev1.Service{}} {
+		err := k8sClient.Get(context.Background(), client.ObjectKey{Name: objectMeta.Name, Namespace: objectMeta.Namespace}, obj)
+		if !apierrors.IsNotFound(err) {
+			t.Errorf("expected %T to be deleted, got %v", obj, err)
+		}
+	}
+	for _, kind := range []string{"Deployment", "Service"} {
+		if !strings.Contains(auditLog.String(), `"kind":"`+kind+`","namespace":"hooks","name":"compositecontroller-bluegreen"},"action":"Delete","result":"Succeeded"`) {
+			t.Errorf("expected the deletion of the %s to be audited, got: %s", kind, auditLog.String())
+		}
+	}
+}
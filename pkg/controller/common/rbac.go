@@ -0,0 +1,221 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+// RBACRecheckInterval is how often a controller missing permissions checks
+// them again.
+const RBACRecheckInterval = time.Minute
+
+var (
+	parentVerbs  = []string{"get", "list", "watch", "update", "patch"}
+	statusVerbs  = []string{"update", "patch"}
+	childVerbs   = []string{"get", "list", "watch", "create", "update", "patch", "delete"}
+	relatedVerbs = []string{"get", "list", "watch"}
+)
+
+// RBACRule is a set of verbs on a resource, in all namespaces.
+type RBACRule struct {
+	Group       string
+	Resource    string
+	Subresource string
+	Verbs       []string
+}
+
+func (r RBACRule) String() string {
+	resource := r.Resource
+	if r.Subresource != "" {
+		resource += "/" + r.Subresource
+	}
+	group := r.Group
+	if group == "" {
+		group = "core"
+	}
+	return fmt.Sprintf("%s %s: %s", group, resource, strings.Join(r.Verbs, ","))
+}
+
+func newRBACRule(apiVersion, resource, subresource string, verbs []string) RBACRule {
+	gv, _ := schema.ParseGroupVersion(apiVersion)
+	return RBACRule{Group: gv.Group, Resource: resource, Subresource: subresource, Verbs: verbs}
+}
+
+// CompositeControllerRBACRules returns the permissions needed to run cc.
+// Related resources requested by the customize hook aren't included, since
+// they're only known at runtime.
+func CompositeControllerRBACRules(cc *v1alpha1.CompositeController) []RBACRule {
+	parent := cc.Spec.ParentResource
+	rules := []RBACRule{newRBACRule(parent.APIVersion, parent.Resource, "", parentVerbs)}
+	// The status of built-in parents isn't written.
+	if group, _ := ParseAPIVersion(parent.APIVersion); !IsBuiltInAPIGroup(group) {
+		rules = append(rules, newRBACRule(parent.APIVersion, parent.Resource, "status", statusVerbs))
+	}
+	for _, child := range cc.Spec.ChildResources {
+		rules = append(rules, newRBACRule(child.APIVersion, child.Resource, "", childVerbs))
+	}
+	return rules
+}
+
+// DecoratorControllerRBACRules returns the permissions needed to run dc.
+// Related resources requested by the customize hook aren't included, since
+// they're only known at runtime.
+func DecoratorControllerRBACRules(dc *v1alpha1.DecoratorController) []RBACRule {
+	var rules []RBACRule
+	for _, resource := range dc.Spec.Resources {
+		rules = append(rules,
+			newRBACRule(resource.APIVersion, resource.Resource, "", parentVerbs),
+			newRBACRule(resource.APIVersion, resource.Resource, "status", statusVerbs),
+		)
+	}
+	for _, attachment := range dc.Spec.Attachments {
+		rules = append(rules, newRBACRule(attachment.APIVersion, attachment.Resource, "", childVerbs))
+	}
+	return rules
+}
+
+// GlobalControllerRBACRules returns the permissions needed to run gc.
+func GlobalControllerRBACRules(gc *v1alpha1.GlobalController) []RBACRule {
+	var rules []RBACRule
+	for _, attachment := range gc.Spec.Attachments {
+		rules = append(rules, newRBACRule(attachment.APIVersion, attachment.Resource, "", childVerbs))
+	}
+	for _, related := range gc.Spec.Related {
+		rules = append(rules, newRBACRule(related.APIVersion, related.Resource, "", relatedVerbs))
+	}
+	return rules
+}
+
+// CronControllerRBACRules returns the permissions needed to run cc.
+func CronControllerRBACRules(cc *v1alpha1.CronController) []RBACRule {
+	var rules []RBACRule
+	if cc.Spec.ParentResource != nil {
+		rules = append(rules, newRBACRule(cc.Spec.ParentResource.APIVersion, cc.Spec.ParentResource.Resource, "", relatedVerbs))
+	}
+	for _, child := range cc.Spec.ChildResources {
+		rules = append(rules, newRBACRule(child.APIVersion, child.Resource, "", childVerbs))
+	}
+	return rules
+}
+
+// ExternalResourceControllerRBACRules returns the permissions needed to run erc.
+func ExternalResourceControllerRBACRules(erc *v1alpha1.ExternalResourceController) []RBACRule {
+	parent := erc.Spec.ParentResource
+	return []RBACRule{
+		newRBACRule(parent.APIVersion, parent.Resource, "", parentVerbs),
+		newRBACRule(parent.APIVersion, parent.Resource, "status", statusVerbs),
+	}
+}
+
+// StateMachineControllerRBACRules returns the permissions needed to run smc.
+func StateMachineControllerRBACRules(smc *v1alpha1.StateMachineController) []RBACRule {
+	parent := smc.Spec.ParentResource
+	rules := []RBACRule{
+		newRBACRule(parent.APIVersion, parent.Resource, "", parentVerbs),
+		newRBACRule(parent.APIVersion, parent.Resource, "status", statusVerbs),
+	}
+	for _, child := range smc.Spec.ChildResources {
+		rules = append(rules, newRBACRule(child.APIVersion, child.Resource, "", childVerbs))
+	}
+	return rules
+}
+
+// MissingRBACRules checks each rule with a SelfSubjectAccessReview and returns
+// the verbs metacontroller isn't allowed, grouped by resource.
+func MissingRBACRules(ctx context.Context, k8sClient client.Client, rules []RBACRule) ([]RBACRule, error) {
+	var missing []RBACRule
+	for _, rule := range rules {
+		var deniedVerbs []string
+		for _, verb := range rule.Verbs {
+			review := &authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: &authorizationv1.ResourceAttributes{
+						Verb:        verb,
+						Group:       rule.Group,
+						Resource:    rule.Resource,
+						Subresource: rule.Subresource,
+					},
+				},
+			}
+			if err := k8sClient.Create(ctx, review); err != nil {
+				return nil, fmt.Errorf("can't review access to %s: %w", rule, err)
+			}
+			if !review.Status.Allowed {
+				deniedVerbs = append(deniedVerbs, verb)
+			}
+		}
+		if len(deniedVerbs) > 0 {
+			missing = append(missing, RBACRule{
+				Group:       rule.Group,
+				Resource:    rule.Resource,
+				Subresource: rule.Subresource,
+				Verbs:       deniedVerbs,
+			})
+		}
+	}
+	return missing, nil
+}
+
+// SetMissingRBACCondition records on conditions whether the controller is
+// missing the given permissions, and reports whether anything changed.
+func SetMissingRBACCondition(conditions *[]metav1.Condition, generation int64, missing []RBACRule) bool {
+	before := make([]metav1.Condition, len(*conditions))
+	copy(before, *conditions)
+
+	if len(missing) > 0 {
+		descriptions := make([]string, 0, len(missing))
+		for _, rule := range missing {
+			descriptions = append(descriptions, rule.String())
+		}
+		message := "Missing permissions: " + strings.Join(descriptions, "; ")
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               v1alpha1.ConditionMissingRBAC,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: generation,
+			Reason:             "AccessDenied",
+			Message:            message,
+		})
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               v1alpha1.ConditionReady,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: generation,
+			Reason:             "MissingRBAC",
+			Message:            message,
+		})
+	} else {
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               v1alpha1.ConditionMissingRBAC,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: generation,
+			Reason:             "AccessAllowed",
+		})
+	}
+	return !equality.Semantic.DeepEqual(before, *conditions)
+}
@@ -0,0 +1,66 @@
+package common
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+// accessReviewClient allows every verb but "delete".
+type accessReviewClient struct {
+	client.Client
+}
+
+func (c *accessReviewClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	review := obj.(*authorizationv1.SelfSubjectAccessReview)
+	review.Status.Allowed = review.Spec.ResourceAttributes.Verb != "delete"
+	return nil
+}
+
+func TestMissingRBACRules(t *testing.T) {
+	cc := &v1alpha1.CompositeController{
+		Spec: v1alpha1.CompositeControllerSpec{
+			ParentResource: v1alpha1.CompositeControllerParentResourceRule{
+				ResourceRule: v1alpha1.ResourceRule{APIVersion: "example.com/v1", Resource: "foos"},
+			},
+			ChildResources: []v1alpha1.CompositeControllerChildResourceRule{
+				{ResourceRule: v1alpha1.ResourceRule{APIVersion: "v1", Resource: "pods"}},
+			},
+		},
+	}
+
+	missing, err := MissingRBACRules(context.Background(), &accessReviewClient{}, CompositeControllerRBACRules(cc))
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(missing) != 1 || missing[0].String() != "core pods: delete" {
+		t.Errorf("expected only delete on pods to be missing, got: %v", missing)
+	}
+}
+
+func TestSetMissingRBACCondition(t *testing.T) {
+	var conditions []metav1.Condition
+	missing := []RBACRule{{Group: "apps", Resource: "deployments", Verbs: []string{"create"}}}
+
+	if !SetMissingRBACCondition(&conditions, 1, missing) {
+		t.Errorf("expected conditions to change")
+	}
+	condition := meta.FindStatusCondition(conditions, v1alpha1.ConditionMissingRBAC)
+	if condition == nil || condition.Status != metav1.ConditionTrue || !strings.Contains(condition.Message, "apps deployments: create") {
+		t.Errorf("unexpected %s condition: %+v", v1alpha1.ConditionMissingRBAC, condition)
+	}
+	if !SetMissingRBACCondition(&conditions, 1, nil) {
+		t.Errorf("expected conditions to change")
+	}
+	if meta.IsStatusConditionTrue(conditions, v1alpha1.ConditionMissingRBAC) {
+		t.Errorf("expected %s to be False", v1alpha1.ConditionMissingRBAC)
+	}
+}
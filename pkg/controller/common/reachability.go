@@ -0,0 +1,52 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+// SetHooksReachableCondition records on conditions whether the controller's
+// webhooks responded to health probes, and reports whether anything changed.
+func SetHooksReachableCondition(conditions *[]metav1.Condition, generation int64, unreachable []string) bool {
+	before := make([]metav1.Condition, len(*conditions))
+	copy(before, *conditions)
+
+	if len(unreachable) > 0 {
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               v1alpha1.ConditionHooksReachable,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: generation,
+			Reason:             "WebhookUnreachable",
+			Message:            strings.Join(unreachable, "; "),
+		})
+	} else {
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               v1alpha1.ConditionHooksReachable,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: generation,
+			Reason:             "ProbesSucceeded",
+		})
+	}
+	return !equality.Semantic.DeepEqual(before, *conditions)
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	jp "github.com/evanphx/json-patch/v5"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sjson "k8s.io/apimachinery/pkg/util/json"
+)
+
+// StatusChecksum returns the hex-encoded SHA-256 of the compact JSON encoding
+// of status, with object keys sorted.
+func StatusChecksum(status map[string]interface{}) (string, error) {
+	if status == nil {
+		status = map[string]interface{}{}
+	}
+	statusJson, err := k8sjson.Marshal(status)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(statusJson)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ApplyStatusPatch applies the JSON merge patch to status and verifies that
+// the checksum of the result matches the expected one. A mismatch means the
+// hook computed the patch against a different status than the one we have.
+func ApplyStatusPatch(status, patch map[string]interface{}, checksum string) (map[string]interface{}, error) {
+	if status == nil {
+		status = map[string]interface{}{}
+	}
+	statusJson, err := k8sjson.Marshal(status)
+	if err != nil {
+		return nil, err
+	}
+	patchJson, err := k8sjson.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+	mergedJson, err := jp.MergePatch(statusJson, patchJson)
+	if err != nil {
+		return nil, fmt.Errorf("can't apply status patch: %w", err)
+	}
+	merged := make(map[string]interface{})
+	if err := k8sjson.Unmarshal(mergedJson, &merged); err != nil {
+		return nil, err
+	}
+	actual, err := StatusChecksum(merged)
+	if err != nil {
+		return nil, err
+	}
+	if actual != checksum {
+		return nil, fmt.Errorf("status checksum mismatch: expected %q, got %q", checksum, actual)
+	}
+	return merged, nil
+}
+
+// RelatedResourcesStaleCondition is the parent status condition type set while
+// related objects are selected using last known-good customize hook rules.
+const RelatedResourcesStaleCondition = "RelatedResourcesStale"
+
+// SetRelatedResourcesStaleCondition sets the RelatedResourcesStale condition in
+// status if customizeErr is not nil, and removes it otherwise.
+// It returns the updated status, which is only allocated if needed.
+func SetRelatedResourcesStaleCondition(status map[string]interface{}, customizeErr error) map[string]interface{} {
+	if customizeErr == nil {
+		return setCondition(status, RelatedResourcesStaleCondition, nil)
+	}
+	return setCondition(status, RelatedResourcesStaleCondition, map[string]interface{}{
+		"status":  "True",
+		"reason":  "CustomizeHookFailed",
+		"message": customizeErr.Error(),
+	})
+}
+
+// PausedCondition is the parent status condition type set while syncs of the
+// parent are paused.
+const PausedCondition = "Paused"
+
+// PausedAnnotation pauses the syncs of a parent when set to "true".
+const PausedAnnotation = "metacontroller.k8s.io/paused"
+
+// SetPausedCondition sets the Paused condition in status with the given
+// reason and message, or removes it if reason is empty.
+// It returns the updated status, which is only allocated if needed.
+func SetPausedCondition(status map[string]interface{}, reason, message string) map[string]interface{} {
+	if reason == "" {
+		return setCondition(status, PausedCondition, nil)
+	}
+	return setCondition(status, PausedCondition, map[string]interface{}{
+		"status":  "True",
+		"reason":  reason,
+		"message": message,
+	})
+}
+
+// setCondition replaces the condition of the given type in status, or removes
+// it if condition is nil. The lastTransitionTime of the previous condition is
+// kept if its status is the same.
+// It returns the updated status, which is only allocated if needed.
+func setCondition(status map[string]interface{}, conditionType string, condition map[string]interface{}) map[string]interface{} {
+	conditions, _, _ := unstructured.NestedSlice(status, "conditions")
+
+	var updated []interface{}
+	var previous map[string]interface{}
+	for _, c := range conditions {
+		if m, ok := c.(map[string]interface{}); ok && m["type"] == conditionType {
+			previous = m
+			continue
+		}
+		updated = append(updated, c)
+	}
+
+	if condition == nil {
+		if previous == nil {
+			return status
+		}
+		if updated == nil {
+			updated = []interface{}{}
+		}
+		status["conditions"] = updated
+		return status
+	}
+
+	condition["type"] = conditionType
+	condition["lastTransitionTime"] = metav1.Now().UTC().Format(time.RFC3339)
+	if previous != nil && previous["status"] == condition["status"] && previous["lastTransitionTime"] != nil {
+		condition["lastTransitionTime"], _ = previous["lastTransitionTime"].(string)
+	}
+	if status == nil {
+		status = make(map[string]interface{})
+	}
+	status["conditions"] = append(updated, condition)
+	return status
+}
+
+// IsStatusApplied tells whether status is already server-side applied to obj
+// by fieldManager: each of its fields has the applied value, and fieldManager
+// doesn't own any other status field, which applying status would remove.
+func IsStatusApplied(obj *unstructured.Unstructured, status map[string]interface{}, fieldManager string) bool {
+	current, _, _ := unstructured.NestedMap(obj.UnstructuredContent(), "status")
+	for field, value := range status {
+		if !reflect.DeepEqual(current[field], value) {
+			return false
+		}
+	}
+	for field := range appliedStatusFields(obj, fieldManager) {
+		if _, ok := status[field]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// appliedStatusFields returns the top-level status fields server-side applied
+// to obj by fieldManager.
+func appliedStatusFields(obj *unstructured.Unstructured, fieldManager string) map[string]bool {
+	fields := make(map[string]bool)
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Manager != fieldManager || entry.Operation != metav1.ManagedFieldsOperationApply || entry.FieldsV1 == nil {
+			continue
+		}
+		var set map[string]interface{}
+		if err := k8sjson.Unmarshal(entry.FieldsV1.Raw, &set); err != nil {
+			continue
+		}
+		status, _ := set["f:status"].(map[string]interface{})
+		for key := range status {
+			if strings.HasPrefix(key, "f:") {
+				fields[strings.TrimPrefix(key, "f:")] = true
+			}
+		}
+	}
+	return fields
+}
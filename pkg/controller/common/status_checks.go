@@ -0,0 +1,53 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	dynamicobject "metacontroller/pkg/dynamic/object"
+)
+
+// ChildStatusCheck returns an error describing the first status check which
+// child doesn't pass, if any.
+func ChildStatusCheck(checks *v1alpha1.ChildUpdateStatusChecks, child *unstructured.Unstructured) error {
+	if checks == nil {
+		// Nothing to check.
+		return nil
+	}
+
+	for _, condCheck := range checks.Conditions {
+		cond, err := dynamicobject.GetStatusCondition(child.UnstructuredContent(), condCheck.Type)
+		if err != nil || cond == nil {
+			return fmt.Errorf("required condition type missing: %q", condCheck.Type)
+		}
+		if condCheck.Status != nil {
+			if cond.Status != *condCheck.Status {
+				return fmt.Errorf("%q condition status is %q (want %q)", condCheck.Type, cond.Status, *condCheck.Status)
+			}
+		}
+		if condCheck.Reason != nil {
+			if cond.Reason != *condCheck.Reason {
+				return fmt.Errorf("%q condition reason is %q (want %q)", condCheck.Type, cond.Reason, *condCheck.Reason)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package common
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestStatusChecksum_isStableForKeyOrder(t *testing.T) {
+	first, err := StatusChecksum(map[string]interface{}{"a": int64(1), "b": "x"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := StatusChecksum(map[string]interface{}{"b": "x", "a": int64(1)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first != second {
+		t.Errorf("expected equal checksums, got %s and %s", first, second)
+	}
+}
+
+func TestApplyStatusPatch(t *testing.T) {
+	status := map[string]interface{}{
+		"replicas": int64(1),
+		"entries":  map[string]interface{}{"a": "ready", "b": "ready"},
+	}
+	patch := map[string]interface{}{
+		"replicas": int64(2),
+		"entries":  map[string]interface{}{"a": nil, "c": "pending"},
+	}
+	expected := map[string]interface{}{
+		"replicas": int64(2),
+		"entries":  map[string]interface{}{"b": "ready", "c": "pending"},
+	}
+	checksum, err := StatusChecksum(expected)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	merged, err := ApplyStatusPatch(status, patch, checksum)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("expected %v, got %v", expected, merged)
+	}
+}
+
+func TestApplyStatusPatch_checksumMismatch(t *testing.T) {
+	status := map[string]interface{}{"replicas": int64(1)}
+	patch := map[string]interface{}{"replicas": int64(2)}
+
+	_, err := ApplyStatusPatch(status, patch, "0000")
+
+	if err == nil {
+		t.Errorf("expected checksum mismatch error")
+	}
+}
+
+func TestSetRelatedResourcesStaleCondition(t *testing.T) {
+	other := map[string]interface{}{"type": "Ready", "status": "True"}
+	status := map[string]interface{}{"conditions": []interface{}{other}}
+
+	status = SetRelatedResourcesStaleCondition(status, fmt.Errorf("hook down"))
+
+	conditions := status["conditions"].([]interface{})
+	if len(conditions) != 2 {
+		t.Fatalf("expected 2 conditions, got %v", conditions)
+	}
+	stale := conditions[1].(map[string]interface{})
+	if stale["type"] != RelatedResourcesStaleCondition || stale["status"] != "True" || stale["message"] != "hook down" {
+		t.Errorf("unexpected condition %v", stale)
+	}
+
+	status = SetRelatedResourcesStaleCondition(status, nil)
+
+	conditions = status["conditions"].([]interface{})
+	if !reflect.DeepEqual(conditions, []interface{}{other}) {
+		t.Errorf("expected only the Ready condition to remain, got %v", conditions)
+	}
+}
+
+func TestSetRelatedResourcesStaleCondition_noErrorLeavesStatusAlone(t *testing.T) {
+	if status := SetRelatedResourcesStaleCondition(nil, nil); status != nil {
+		t.Errorf("expected nil status, got %v", status)
+	}
+}
+
+func TestSetPausedCondition_keepsLastTransitionTime(t *testing.T) {
+	previous := map[string]interface{}{"type": PausedCondition, "status": "True", "reason": "ParentPaused", "lastTransitionTime": "2021-01-01T00:00:00Z"}
+	status := map[string]interface{}{"conditions": []interface{}{previous}}
+
+	status = SetPausedCondition(status, "ControllerPaused", "paused")
+
+	expected := []interface{}{
+		map[string]interface{}{"type": PausedCondition, "status": "True", "reason": "ControllerPaused", "message": "paused", "lastTransitionTime": "2021-01-01T00:00:00Z"},
+	}
+	if !reflect.DeepEqual(status["conditions"], expected) {
+		t.Errorf("expected %v, got %v", expected, status["conditions"])
+	}
+}
+
+func TestIsStatusApplied(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{"replicas": int64(2), "phase": "Ready", "external": "x"},
+	}}
+	obj.SetManagedFields([]metav1.ManagedFieldsEntry{
+		{
+			Manager:   "metacontroller-status",
+			Operation: metav1.ManagedFieldsOperationApply,
+			FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:status":{"f:replicas":{},"f:phase":{}}}`)},
+		},
+		{
+			Manager:   "kubectl",
+			Operation: metav1.ManagedFieldsOperationUpdate,
+			FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:status":{"f:external":{}}}`)},
+		},
+	})
+
+	tests := []struct {
+		name     string
+		status   map[string]interface{}
+		expected bool
+	}{
+		{name: "same fields", status: map[string]interface{}{"replicas": int64(2), "phase": "Ready"}, expected: true},
+		{name: "changed field", status: map[string]interface{}{"replicas": int64(3), "phase": "Ready"}, expected: false},
+		{name: "removed field", status: map[string]interface{}{"replicas": int64(2)}, expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := IsStatusApplied(obj, tt.status, "metacontroller-status"); actual != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
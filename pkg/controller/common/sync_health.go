@@ -0,0 +1,111 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+const (
+	// MaxLastErrors is how many sync errors are kept in the status of a
+	// controller.
+	MaxLastErrors = 5
+	// maxErrorMessageLength is the length sync errors are truncated to in the
+	// status of a controller.
+	maxErrorMessageLength = 1024
+	// StatusRefreshInterval is how often the status of a controller is
+	// refreshed with the state of its parents, unless its webhooks are probed,
+	// which refreshes it at the probe interval.
+	StatusRefreshInterval = time.Minute
+)
+
+// SyncHealth tracks the parents of a controller whose last sync failed.
+type SyncHealth struct {
+	mutex   sync.Mutex
+	failing map[string]v1alpha1.SyncErrorSummary
+}
+
+func NewSyncHealth() *SyncHealth {
+	return &SyncHealth{failing: make(map[string]v1alpha1.SyncErrorSummary)}
+}
+
+// Record records the outcome of a sync of parent, whose queue key is key.
+func (h *SyncHealth) Record(key string, parent *unstructured.Unstructured, err error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if err == nil {
+		delete(h.failing, key)
+		return
+	}
+	message := err.Error()
+	if len(message) > maxErrorMessageLength {
+		message = message[:maxErrorMessageLength] + "..."
+	}
+	if previous, ok := h.failing[key]; ok && previous.Message == message {
+		// Retries failing with the same error don't change the status.
+		return
+	}
+	h.failing[key] = v1alpha1.SyncErrorSummary{
+		Kind:      parent.GetKind(),
+		Namespace: parent.GetNamespace(),
+		Name:      parent.GetName(),
+		Message:   message,
+		Since:     metav1.Now().Rfc3339Copy(),
+	}
+}
+
+// Forget forgets the parent whose queue key is key, once it's deleted.
+func (h *SyncHealth) Forget(key string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	delete(h.failing, key)
+}
+
+// Failing returns the number of parents whose last sync failed, and their
+// most recent errors, newest first.
+func (h *SyncHealth) Failing() (int32, []v1alpha1.SyncErrorSummary) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if len(h.failing) == 0 {
+		return 0, nil
+	}
+	summaries := make([]v1alpha1.SyncErrorSummary, 0, len(h.failing))
+	for _, summary := range h.failing {
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if !summaries[i].Since.Equal(&summaries[j].Since) {
+			return summaries[j].Since.Before(&summaries[i].Since)
+		}
+		// Keep the order stable, so the status only changes with the errors.
+		if summaries[i].Namespace != summaries[j].Namespace {
+			return summaries[i].Namespace < summaries[j].Namespace
+		}
+		return summaries[i].Name < summaries[j].Name
+	})
+	if len(summaries) > MaxLastErrors {
+		summaries = summaries[:MaxLastErrors]
+	}
+	return int32(len(h.failing)), summaries
+}
@@ -0,0 +1,60 @@
+package common
+
+import (
+	"errors"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestSyncHealth(t *testing.T) {
+	health := NewSyncHealth()
+	parent := func(name string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetKind("Parent")
+		obj.SetNamespace("default")
+		obj.SetName(name)
+		return obj
+	}
+
+	for i, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		health.Record("default/"+name, parent(name), errors.New("boom"))
+		if i == 0 {
+			health.Record("default/"+name, parent(name), errors.New("boom"))
+		}
+	}
+	health.Record("default/g", parent("g"), nil)
+
+	failing, lastErrors := health.Failing()
+	if failing != 6 {
+		t.Errorf("expected 6 failing parents, got %d", failing)
+	}
+	if len(lastErrors) != MaxLastErrors {
+		t.Fatalf("expected %d errors, got %d", MaxLastErrors, len(lastErrors))
+	}
+	if got := lastErrors[0]; got.Kind != "Parent" || got.Namespace != "default" || got.Message != "boom" {
+		t.Errorf("unexpected error summary: %+v", got)
+	}
+
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		health.Record("default/"+name, parent(name), nil)
+	}
+	health.Forget("default/f")
+	if failing, lastErrors := health.Failing(); failing != 0 || lastErrors != nil {
+		t.Errorf("expected no failing parents, got %d: %+v", failing, lastErrors)
+	}
+}
+
+func TestSyncHealth_keepsSinceOfRepeatedError(t *testing.T) {
+	health := NewSyncHealth()
+	obj := &unstructured.Unstructured{}
+	obj.SetName("parent")
+
+	health.Record("parent", obj, errors.New("boom"))
+	_, first := health.Failing()
+	health.Record("parent", obj, errors.New("boom"))
+	_, second := health.Failing()
+	if !first[0].Since.Equal(&second[0].Since) {
+		t.Errorf("expected a repeated error to keep its time, got %v then %v", first[0].Since, second[0].Since)
+	}
+}
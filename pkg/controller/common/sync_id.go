@@ -0,0 +1,64 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	"metacontroller/pkg/tracing"
+)
+
+// SyncIDAnnotation is set on the events recorded about an object while it's
+// being synced, to the ID of that sync.
+const SyncIDAnnotation = "metacontroller.k8s.io/sync-id"
+
+// syncIDRecorder sets SyncIDAnnotation on the events it records.
+type syncIDRecorder struct {
+	record.EventRecorder
+	syncID string
+}
+
+// SyncEventRecorder wraps recorder so the events it records carry the ID of
+// the sync of ctx in SyncIDAnnotation. It returns recorder itself if ctx isn't
+// part of a sync.
+func SyncEventRecorder(ctx context.Context, recorder record.EventRecorder) record.EventRecorder {
+	syncID := tracing.SyncID(ctx)
+	if syncID == "" {
+		return recorder
+	}
+	return syncIDRecorder{EventRecorder: recorder, syncID: syncID}
+}
+
+func (r syncIDRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	r.AnnotatedEventf(object, nil, eventtype, reason, "%s", message)
+}
+
+func (r syncIDRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.AnnotatedEventf(object, nil, eventtype, reason, messageFmt, args...)
+}
+
+func (r syncIDRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	merged := make(map[string]string, len(annotations)+1)
+	for k, v := range annotations {
+		merged[k] = v
+	}
+	merged[SyncIDAnnotation] = r.syncID
+	r.EventRecorder.AnnotatedEventf(object, merged, eventtype, reason, messageFmt, args...)
+}
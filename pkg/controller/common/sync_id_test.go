@@ -0,0 +1,54 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/tracing"
+)
+
+// annotationRecorder records the annotations of each event.
+type annotationRecorder struct {
+	record.EventRecorder
+	annotations []map[string]string
+}
+
+func (r *annotationRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	r.annotations = append(r.annotations, nil)
+}
+
+func (r *annotationRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.annotations = append(r.annotations, nil)
+}
+
+func (r *annotationRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.annotations = append(r.annotations, annotations)
+}
+
+func TestSyncEventRecorder(t *testing.T) {
+	obj := &v1alpha1.CompositeController{ObjectMeta: metav1.ObjectMeta{Name: "test", UID: "uid-2"}}
+	fake := &annotationRecorder{}
+
+	SyncEventRecorder(context.TODO(), fake).Event(obj, "Normal", "Outside", "not synced")
+	ctx, span := tracing.Start(context.TODO(), "sync")
+	defer span.End()
+	recorder := SyncEventRecorder(ctx, fake)
+	recorder.Event(obj, "Normal", "Inside", "synced")
+	recorder.AnnotatedEventf(obj, map[string]string{"other": "value"}, "Normal", "Inside", "synced")
+
+	syncID := tracing.SyncID(ctx)
+	want := []map[string]string{
+		nil,
+		{SyncIDAnnotation: syncID},
+		{SyncIDAnnotation: syncID, "other": "value"},
+	}
+	if got, want := fmt.Sprint(fake.annotations), fmt.Sprint(want); got != want {
+		t.Errorf("annotations = %s, want %s", got, want)
+	}
+}
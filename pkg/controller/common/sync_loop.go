@@ -0,0 +1,194 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// DefaultSyncLoopSyncs and DefaultSyncLoopWindow are the default
+	// SyncLoopLimits.
+	DefaultSyncLoopSyncs  = 10
+	DefaultSyncLoopWindow = time.Minute
+
+	// The backoff of an object doubles each time it's synced in a hot loop
+	// again, until it stays quiet for a whole window.
+	minLoopBackoff = time.Second
+	maxLoopBackoff = 5 * time.Minute
+)
+
+// SyncLoopLimits tell when the syncs of an object make a hot loop: when Syncs
+// of its syncs, which each wrote its children or its status, happen within
+// Window. Hot loops aren't detected if Syncs is zero.
+type SyncLoopLimits struct {
+	Syncs  int
+	Window time.Duration
+}
+
+// LoopDetector detects, for one controller, the objects which are synced in a
+// hot loop, for example because the output of a hook fights a mutating
+// admission webhook, and backs their syncs off.
+//
+// Only the syncs which wrote something count, so objects resynced often, or
+// whose children change often, aren't backed off as long as their syncs
+// leave them alone.
+type LoopDetector struct {
+	limits SyncLoopLimits
+
+	mutex   sync.Mutex
+	objects map[string]*syncHistory
+
+	now func() time.Time
+}
+
+type syncHistory struct {
+	// The times of the last writing syncs, up to the limit.
+	syncs []time.Time
+	// The number of times a hot loop was detected in a row.
+	loops   int
+	backoff time.Time
+}
+
+// NewLoopDetector returns a LoopDetector detecting the hot loops exceeding
+// limits.
+func NewLoopDetector(limits SyncLoopLimits) *LoopDetector {
+	return &LoopDetector{
+		limits:  limits,
+		objects: make(map[string]*syncHistory),
+		now:     time.Now,
+	}
+}
+
+// Wrote records a successful sync of key which wrote its children or its
+// status. It returns the number of hot loops detected in a row for key,
+// including the one this sync makes, or zero if it doesn't make one.
+func (d *LoopDetector) Wrote(key string) int {
+	if d.limits.Syncs <= 0 {
+		return 0
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	now := d.now()
+	history, ok := d.objects[key]
+	if !ok || d.quiet(history, now) {
+		history = &syncHistory{}
+		d.objects[key] = history
+	}
+	history.syncs = append(history.syncs, now)
+	if len(history.syncs) > d.limits.Syncs {
+		history.syncs = history.syncs[1:]
+	}
+	if len(history.syncs) < d.limits.Syncs || now.Sub(history.syncs[0]) > d.limits.Window {
+		return 0
+	}
+
+	backoff := maxLoopBackoff
+	if history.loops < 32 && minLoopBackoff<<history.loops < maxLoopBackoff {
+		backoff = minLoopBackoff << history.loops
+	}
+	history.loops++
+	history.backoff = now.Add(backoff)
+	return history.loops
+}
+
+// quiet tells whether the object wasn't synced for long enough since its last
+// sync, or since the end of its backoff, to forget its loops.
+func (d *LoopDetector) quiet(h *syncHistory, now time.Time) bool {
+	last := h.syncs[len(h.syncs)-1]
+	if h.backoff.After(last) {
+		last = h.backoff
+	}
+	return now.Sub(last) > d.limits.Window
+}
+
+// Backoff returns how long the next sync of key must wait, if it's synced in
+// a hot loop.
+func (d *LoopDetector) Backoff(key string) time.Duration {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	history, ok := d.objects[key]
+	if !ok {
+		return 0
+	}
+	if backoff := history.backoff.Sub(d.now()); backoff > 0 {
+		return backoff
+	}
+	return 0
+}
+
+// Forget forgets the syncs of key, for example because the object was deleted.
+func (d *LoopDetector) Forget(key string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	delete(d.objects, key)
+}
+
+type syncWritesKey struct{}
+
+// SyncWrites records whether a sync wrote the children or the status of the
+// object it synced.
+type SyncWrites struct {
+	mutex sync.Mutex
+	wrote bool
+}
+
+// WithSyncWrites returns a copy of ctx in which the writes of a sync are
+// recorded with RecordWrites, and what they are recorded in.
+func WithSyncWrites(ctx context.Context) (context.Context, *SyncWrites) {
+	writes := &SyncWrites{}
+	return context.WithValue(ctx, syncWritesKey{}, writes), writes
+}
+
+// RecordWrites records that the sync of ctx wrote, if wrote is true.
+func RecordWrites(ctx context.Context, wrote bool) {
+	writes, ok := ctx.Value(syncWritesKey{}).(*SyncWrites)
+	if !ok || !wrote {
+		return
+	}
+	writes.mutex.Lock()
+	defer writes.mutex.Unlock()
+	writes.wrote = true
+}
+
+// Wrote returns whether the sync wrote.
+func (w *SyncWrites) Wrote() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.wrote
+}
+
+// WroteChildren returns whether results include writes which were made to
+// children, rather than only dry-run.
+func WroteChildren(results []ChildResult) bool {
+	for _, result := range results {
+		if result.Result == "Succeeded" && !result.DryRun {
+			return true
+		}
+	}
+	return false
+}
+
+// WroteObject returns whether updated, returned by a write of orig, is a new
+// version of it, rather than orig left as it was.
+func WroteObject(orig, updated *unstructured.Unstructured) bool {
+	return updated != nil && updated.GetResourceVersion() != orig.GetResourceVersion()
+}
@@ -0,0 +1,111 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestLoopDetector(t *testing.T) {
+	detector := NewLoopDetector(SyncLoopLimits{Syncs: DefaultSyncLoopSyncs, Window: DefaultSyncLoopWindow})
+	now := time.Unix(100, 0)
+	detector.now = func() time.Time { return now }
+
+	var loops []int
+	for i := 0; i < DefaultSyncLoopSyncs; i++ {
+		if loop := detector.Wrote("default/foo"); loop > 0 {
+			loops = append(loops, loop)
+		}
+		now = now.Add(time.Second)
+	}
+	if len(loops) != 1 || loops[0] != 1 {
+		t.Fatalf("expected the loop to be detected once, got: %v", loops)
+	}
+	if backoff := detector.Backoff("default/foo"); backoff != 0 {
+		t.Errorf("expected the first backoff to have expired, got: %v", backoff)
+	}
+
+	// The backoff escalates while the loop goes on.
+	if loop := detector.Wrote("default/foo"); loop != 2 {
+		t.Errorf("expected the second loop in a row, got: %d", loop)
+	}
+	if backoff := detector.Backoff("default/foo"); backoff != 2*minLoopBackoff {
+		t.Errorf("expected backoff %v, got: %v", 2*minLoopBackoff, backoff)
+	}
+	if backoff := detector.Backoff("default/bar"); backoff != 0 {
+		t.Errorf("expected no backoff for other keys, got: %v", backoff)
+	}
+
+	// Loops are forgotten once the object is quiet.
+	now = now.Add(2 * DefaultSyncLoopWindow)
+	if loop := detector.Wrote("default/foo"); loop != 0 {
+		t.Errorf("expected no loop after a quiet period")
+	}
+	if len(detector.objects["default/foo"].syncs) != 1 {
+		t.Errorf("expected the sync history to be reset, got: %v", detector.objects["default/foo"].syncs)
+	}
+}
+
+func TestLoopDetector_limits(t *testing.T) {
+	detector := NewLoopDetector(SyncLoopLimits{Syncs: 3, Window: 10 * time.Second})
+	now := time.Unix(100, 0)
+	detector.now = func() time.Time { return now }
+
+	// Writing every 6 seconds stays below 3 writes in 10 seconds.
+	for i := 0; i < 10; i++ {
+		if loop := detector.Wrote("default/foo"); loop != 0 {
+			t.Fatalf("expected no loop below the limits, got: %d", loop)
+		}
+		now = now.Add(6 * time.Second)
+	}
+	// Writing every second exceeds them.
+	detected := false
+	for i := 0; i < 3; i++ {
+		if detector.Wrote("default/foo") > 0 {
+			detected = true
+		}
+		now = now.Add(time.Second)
+	}
+	if !detected {
+		t.Errorf("expected a loop above the limits")
+	}
+
+	disabled := NewLoopDetector(SyncLoopLimits{})
+	for i := 0; i < 100; i++ {
+		if loop := disabled.Wrote("default/foo"); loop != 0 {
+			t.Fatalf("expected no loop detection without limits, got: %d", loop)
+		}
+	}
+}
+
+func TestSyncWrites(t *testing.T) {
+	// Recording without SyncWrites in the context does nothing.
+	RecordWrites(context.Background(), true)
+
+	ctx, writes := WithSyncWrites(context.Background())
+	RecordWrites(ctx, WroteChildren([]ChildResult{
+		{Action: ChildUpdate, Result: "Failed"},
+		{Action: ChildCreate, Result: "Succeeded", DryRun: true},
+	}))
+	orig := &unstructured.Unstructured{}
+	orig.SetResourceVersion("1")
+	RecordWrites(ctx, WroteObject(orig, orig.DeepCopy()))
+	if writes.Wrote() {
+		t.Errorf("expected failed writes, dry-runs and unchanged objects not to count")
+	}
+
+	updated := orig.DeepCopy()
+	updated.SetResourceVersion("2")
+	RecordWrites(ctx, WroteObject(orig, updated))
+	if !writes.Wrote() {
+		t.Errorf("expected the update of the object to count")
+	}
+
+	_, writes = WithSyncWrites(context.Background())
+	RecordWrites(context.Background(), true)
+	if writes.Wrote() {
+		t.Errorf("expected the writes of each sync to be recorded separately")
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"reflect"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// SyncStatusRefreshInterval is how old status.sync.lastSyncTime gets before
+// it's refreshed by a sync which changes nothing else in the block. Writing
+// the time of every sync would trigger another sync of the parent, forever.
+const SyncStatusRefreshInterval = time.Minute
+
+// SyncRecord is the outcome of a sync of a parent, recorded in its status.sync
+// block. HookLatency and Children are kept from the previous block when they
+// aren't known, e.g. when the sync failed before calling the hooks.
+type SyncRecord struct {
+	Time        time.Time
+	HookLatency time.Duration
+	Children    RelativeObjectMap
+	Error       error
+}
+
+// SetSyncStatus sets the status.sync block of status from record, if enabled
+// by the status conventions. oldStatus is the current status of the parent.
+// It returns the updated status, which is only allocated if needed.
+func (c *StatusConventions) SetSyncStatus(oldStatus, status map[string]interface{}, record SyncRecord) map[string]interface{} {
+	if c == nil || !c.syncStatus {
+		return status
+	}
+	oldBlock, _ := oldStatus["sync"].(map[string]interface{})
+	block := runtime.DeepCopyJSON(oldBlock)
+	if block == nil {
+		block = make(map[string]interface{})
+	}
+	block["lastSyncTime"] = record.Time.UTC().Format(time.RFC3339)
+	if record.HookLatency > 0 {
+		block["lastHookLatency"] = record.HookLatency.Round(time.Millisecond).String()
+	}
+	if record.Children != nil {
+		children := make(map[string]interface{}, len(record.Children))
+		for gvk, objects := range record.Children {
+			// Keyed like the children of sync requests, e.g. "Pod.v1".
+			key, _ := gvk.MarshalText()
+			children[string(key)] = int64(len(objects))
+		}
+		block["children"] = children
+	}
+	if record.Error != nil {
+		block["lastError"] = record.Error.Error()
+	} else {
+		delete(block, "lastError")
+	}
+	if oldBlock != nil && syncStatusFresh(oldBlock, block, record.Time) {
+		// Keep the block as it is, including the time and latency.
+		block = runtime.DeepCopyJSON(oldBlock)
+	}
+	if status == nil {
+		status = make(map[string]interface{})
+	}
+	status["sync"] = block
+	return status
+}
+
+// syncStatusFresh returns whether oldBlock only differs from block by its time
+// and latency, and was recorded less than SyncStatusRefreshInterval ago.
+func syncStatusFresh(oldBlock, block map[string]interface{}, now time.Time) bool {
+	lastSyncTime, _ := oldBlock["lastSyncTime"].(string)
+	last, err := time.Parse(time.RFC3339, lastSyncTime)
+	if err != nil || now.Sub(last) >= SyncStatusRefreshInterval {
+		return false
+	}
+	withoutTimes := func(block map[string]interface{}) map[string]interface{} {
+		result := make(map[string]interface{}, len(block))
+		for key, value := range block {
+			if key != "lastSyncTime" && key != "lastHookLatency" {
+				result[key] = value
+			}
+		}
+		return result
+	}
+	return reflect.DeepEqual(withoutTimes(oldBlock), withoutTimes(block))
+}
+
+// SyncStatusPatch returns a JSON merge patch turning the status.sync block of
+// oldStatus into the one of status, removing the fields which are gone.
+func SyncStatusPatch(oldStatus, status map[string]interface{}) map[string]interface{} {
+	return mergePatchOf(oldStatus["sync"], status["sync"])
+}
+
+func mergePatchOf(old, desired interface{}) map[string]interface{} {
+	oldMap, _ := old.(map[string]interface{})
+	patch, _ := runtime.DeepCopyJSONValue(desired).(map[string]interface{})
+	if patch == nil {
+		return nil
+	}
+	for key, oldValue := range oldMap {
+		value, found := patch[key]
+		if !found {
+			patch[key] = nil
+		} else if _, isMap := value.(map[string]interface{}); isMap {
+			patch[key] = mergePatchOf(oldValue, value)
+		}
+	}
+	return patch
+}
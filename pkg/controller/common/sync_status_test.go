@@ -0,0 +1,109 @@
+package common
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/pointer"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+func TestStatusConventions_SetSyncStatus(t *testing.T) {
+	conventions, err := NewStatusConventions(&v1alpha1.StatusConventions{SyncStatus: pointer.BoolPtr(true)})
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	parent := &unstructured.Unstructured{}
+	pod := &unstructured.Unstructured{}
+	pod.SetAPIVersion("v1")
+	pod.SetKind("Pod")
+	pod.SetName("a")
+	children := make(RelativeObjectMap)
+	children.Insert(parent, pod)
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	status := conventions.SetSyncStatus(nil, map[string]interface{}{"phase": "Running"}, SyncRecord{
+		Time:        start,
+		HookLatency: 1234567 * time.Microsecond,
+		Children:    children,
+	})
+	expected := map[string]interface{}{
+		"phase": "Running",
+		"sync": map[string]interface{}{
+			"lastSyncTime":    "2021-01-01T00:00:00Z",
+			"lastHookLatency": "1.235s",
+			"children":        map[string]interface{}{"Pod.v1": int64(1)},
+		},
+	}
+	if diff := cmp.Diff(expected, status); diff != "" {
+		t.Fatalf("unexpected status (-want +got):\n%s", diff)
+	}
+
+	// A sync changing nothing but the time and latency keeps the block.
+	resynced := conventions.SetSyncStatus(status, map[string]interface{}{}, SyncRecord{
+		Time:        start.Add(10 * time.Second),
+		HookLatency: time.Second,
+		Children:    children,
+	})
+	if diff := cmp.Diff(status["sync"], resynced["sync"]); diff != "" {
+		t.Errorf("expected a fresh sync block to be kept (-want +got):\n%s", diff)
+	}
+
+	// Errors are recorded right away, keeping the latency and children.
+	failed := conventions.SetSyncStatus(status, status, SyncRecord{
+		Time:  start.Add(20 * time.Second),
+		Error: errors.New("boom"),
+	})
+	expectedSync := map[string]interface{}{
+		"lastSyncTime":    "2021-01-01T00:00:20Z",
+		"lastHookLatency": "1.235s",
+		"lastError":       "boom",
+		"children":        map[string]interface{}{"Pod.v1": int64(1)},
+	}
+	if diff := cmp.Diff(expectedSync, failed["sync"]); diff != "" {
+		t.Errorf("unexpected sync block (-want +got):\n%s", diff)
+	}
+
+	// A stale block is refreshed.
+	refreshed := conventions.SetSyncStatus(status, map[string]interface{}{}, SyncRecord{
+		Time:     start.Add(SyncStatusRefreshInterval),
+		Children: children,
+	})
+	if got := refreshed["sync"].(map[string]interface{})["lastSyncTime"]; got != "2021-01-01T00:01:00Z" {
+		t.Errorf("expected lastSyncTime to be refreshed, got %v", got)
+	}
+}
+
+func TestStatusConventions_SetSyncStatus_Disabled(t *testing.T) {
+	conventions, err := NewStatusConventions(&v1alpha1.StatusConventions{})
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	for _, c := range []*StatusConventions{nil, conventions} {
+		if status := c.SetSyncStatus(nil, nil, SyncRecord{Time: time.Now()}); status != nil {
+			t.Errorf("expected status to be left alone, got %v", status)
+		}
+	}
+}
+
+func TestSyncStatusPatch(t *testing.T) {
+	oldStatus := map[string]interface{}{"sync": map[string]interface{}{
+		"lastError": "boom",
+		"children":  map[string]interface{}{"Pod.v1": int64(1), "Service.v1": int64(1)},
+	}}
+	status := map[string]interface{}{"sync": map[string]interface{}{
+		"children": map[string]interface{}{"Pod.v1": int64(2)},
+	}}
+
+	expected := map[string]interface{}{
+		"lastError": nil,
+		"children":  map[string]interface{}{"Pod.v1": int64(2), "Service.v1": nil},
+	}
+	if diff := cmp.Diff(expected, SyncStatusPatch(oldStatus, status)); diff != "" {
+		t.Errorf("unexpected patch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// SyncTokenStore remembers, for each parent, the syncToken returned by the
+// last sync hook call which was fully applied. Tokens are only kept in memory,
+// so the first sync of each parent after a restart is always a full one.
+type SyncTokenStore struct {
+	mutex  sync.Mutex
+	tokens map[string]syncToken
+}
+
+type syncToken struct {
+	uid   types.UID
+	token string
+}
+
+// NewSyncTokenStore returns an empty SyncTokenStore.
+func NewSyncTokenStore() *SyncTokenStore {
+	return &SyncTokenStore{tokens: make(map[string]syncToken)}
+}
+
+// Get returns the token of the parent with the given queue key, or "" if
+// there's none or it was stored for a previous object with the same name.
+func (s *SyncTokenStore) Get(key string, uid types.UID) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	stored, ok := s.tokens[key]
+	if !ok || stored.uid != uid {
+		return ""
+	}
+	return stored.token
+}
+
+// Set stores the token of a parent. An empty token removes it.
+func (s *SyncTokenStore) Set(key string, uid types.UID, token string) {
+	if token == "" {
+		s.Forget(key)
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.tokens[key] = syncToken{uid: uid, token: token}
+}
+
+// Forget removes the token of a parent, e.g. once it's deleted.
+func (s *SyncTokenStore) Forget(key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.tokens, key)
+}
@@ -0,0 +1,20 @@
+package common
+
+import "testing"
+
+func TestSyncTokenStore(t *testing.T) {
+	store := NewSyncTokenStore()
+	store.Set("default/foo", "uid-1", "token-1")
+
+	if token := store.Get("default/foo", "uid-1"); token != "token-1" {
+		t.Errorf("expected token-1, got: %q", token)
+	}
+	if token := store.Get("default/foo", "uid-2"); token != "" {
+		t.Errorf("expected no token for a recreated parent, got: %q", token)
+	}
+
+	store.Set("default/foo", "uid-1", "")
+	if token := store.Get("default/foo", "uid-1"); token != "" {
+		t.Errorf("expected an empty token to remove it, got: %q", token)
+	}
+}
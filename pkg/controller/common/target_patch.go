@@ -0,0 +1,123 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	jp "github.com/evanphx/json-patch/v5"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sjson "k8s.io/apimachinery/pkg/util/json"
+)
+
+// TargetPatchPaths are the fields of an object which a hook may change with
+// a JSON merge patch, e.g. the targetPatch of a decorator's sync response.
+type TargetPatchPaths struct {
+	paths []fieldPath
+}
+
+// NewTargetPatchPaths parses paths, either JSON Pointers or dot-separated
+// paths, which must be within spec, metadata.labels or metadata.annotations.
+func NewTargetPatchPaths(paths []string) (*TargetPatchPaths, error) {
+	p := &TargetPatchPaths{}
+	for _, path := range paths {
+		parsed, err := parseFieldPath(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid targetPatchPaths: %w", err)
+		}
+		if !(parsed[0] == "spec" ||
+			len(parsed) > 1 && parsed[0] == "metadata" && (parsed[1] == "labels" || parsed[1] == "annotations")) {
+			return nil, fmt.Errorf("invalid targetPatchPaths: path %q must be within spec, metadata.labels or metadata.annotations", path)
+		}
+		p.paths = append(p.paths, parsed)
+	}
+	return p, nil
+}
+
+// Apply applies the JSON merge patch to obj, and returns whether obj changed.
+// Nothing is applied if the patch changes any field outside of the paths.
+func (p *TargetPatchPaths) Apply(obj *unstructured.Unstructured, patch map[string]interface{}) (bool, error) {
+	if len(patch) == 0 {
+		return false, nil
+	}
+	if err := p.check(patch, nil); err != nil {
+		return false, err
+	}
+	objJson, err := k8sjson.Marshal(obj.UnstructuredContent())
+	if err != nil {
+		return false, err
+	}
+	patchJson, err := k8sjson.Marshal(patch)
+	if err != nil {
+		return false, err
+	}
+	mergedJson, err := jp.MergePatch(objJson, patchJson)
+	if err != nil {
+		return false, fmt.Errorf("can't apply target patch: %w", err)
+	}
+	merged := &unstructured.Unstructured{}
+	if err := merged.UnmarshalJSON(mergedJson); err != nil {
+		return false, err
+	}
+	if reflect.DeepEqual(obj.UnstructuredContent(), merged.UnstructuredContent()) {
+		return false, nil
+	}
+	obj.SetUnstructuredContent(merged.UnstructuredContent())
+	return true, nil
+}
+
+// check returns an error if the patch, at the given path of the object,
+// changes a field which isn't within one of the paths. Nested objects of the
+// patch are merged, so they're allowed as long as they lead to one of the paths.
+func (p *TargetPatchPaths) check(patch map[string]interface{}, path fieldPath) error {
+	for key, value := range patch {
+		field := append(path[:len(path):len(path)], key)
+		if p.allows(field) {
+			continue
+		}
+		if nested, ok := value.(map[string]interface{}); ok && p.leadsTo(field) {
+			if err := p.check(nested, field); err != nil {
+				return err
+			}
+			continue
+		}
+		return fmt.Errorf("invalid targetPatch: field %s isn't within targetPatchPaths", strings.Join(field, "."))
+	}
+	return nil
+}
+
+// allows returns whether field is one of the paths, or within one of them.
+func (p *TargetPatchPaths) allows(field fieldPath) bool {
+	for _, path := range p.paths {
+		if len(path) <= len(field) && reflect.DeepEqual(path, field[:len(path)]) {
+			return true
+		}
+	}
+	return false
+}
+
+// leadsTo returns whether one of the paths is within field.
+func (p *TargetPatchPaths) leadsTo(field fieldPath) bool {
+	for _, path := range p.paths {
+		if len(path) > len(field) && reflect.DeepEqual(path[:len(field)], field) {
+			return true
+		}
+	}
+	return false
+}
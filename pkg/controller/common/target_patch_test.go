@@ -0,0 +1,97 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestNewTargetPatchPaths_Invalid(t *testing.T) {
+	for _, path := range []string{"status.replicas", "metadata.name", "/metadata/finalizers", "metadata"} {
+		if _, err := NewTargetPatchPaths([]string{path}); err == nil {
+			t.Errorf("expected an error for %q", path)
+		}
+	}
+}
+
+func TestTargetPatchPaths_Apply(t *testing.T) {
+	paths, err := NewTargetPatchPaths([]string{"spec.replicas", "/metadata/annotations/sidecar.example.com~1inject"})
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	newTarget := func() *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata":   map[string]interface{}{"name": "test", "annotations": map[string]interface{}{"a": "b"}},
+			"spec":       map[string]interface{}{"replicas": int64(1), "paused": false},
+		}}
+	}
+	tests := []struct {
+		name     string
+		patch    map[string]interface{}
+		changed  bool
+		valid    bool
+		expected map[string]interface{}
+	}{
+		{
+			name:    "allowed fields",
+			patch:   map[string]interface{}{"spec": map[string]interface{}{"replicas": 3}, "metadata": map[string]interface{}{"annotations": map[string]interface{}{"sidecar.example.com/inject": "true"}}},
+			changed: true,
+			valid:   true,
+			expected: map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"name": "test", "annotations": map[string]interface{}{"a": "b", "sidecar.example.com/inject": "true"}},
+				"spec":       map[string]interface{}{"replicas": int64(3), "paused": false},
+			},
+		},
+		{
+			name:     "unchanged",
+			patch:    map[string]interface{}{"spec": map[string]interface{}{"replicas": 1}},
+			changed:  false,
+			valid:    true,
+			expected: newTarget().Object,
+		},
+		{
+			name:  "field outside of the paths",
+			patch: map[string]interface{}{"spec": map[string]interface{}{"replicas": 3, "paused": true}},
+			valid: false,
+		},
+		{
+			name:  "replacing a parent of the paths",
+			patch: map[string]interface{}{"spec": nil},
+			valid: false,
+		},
+		{
+			name:  "other annotation",
+			patch: map[string]interface{}{"metadata": map[string]interface{}{"annotations": map[string]interface{}{"a": nil}}},
+			valid: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := newTarget()
+			changed, err := paths.Apply(target, tt.patch)
+			if !tt.valid {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				if !reflect.DeepEqual(target.Object, newTarget().Object) {
+					t.Errorf("expected target to be unchanged, got: %v", target.Object)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err should be nil, got: %v", err)
+			}
+			if changed != tt.changed {
+				t.Errorf("expected changed to be %v, got %v", tt.changed, changed)
+			}
+			if !reflect.DeepEqual(target.Object, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, target.Object)
+			}
+		})
+	}
+}
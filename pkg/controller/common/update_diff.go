@@ -0,0 +1,69 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+)
+
+// updateDiffIgnoredFields are the fields which the API server changes on
+// every update, so they aren't part of update diffs.
+var updateDiffIgnoredFields = [][]string{
+	{"metadata", "managedFields"},
+	{"status"},
+}
+
+// dryRunUpdateDiff returns the JSON merge patch from observed to what the API
+// server would store for the update of a child, found with a server-side dry
+// run, so defaulting and mutating admission are taken into account. A patch
+// of "{}" means the update doesn't change anything once stored.
+func dryRunUpdateDiff(client *dynamicclientset.ResourceClient, applyStrategies *ChildApplyStrategies, namespace string, parent, observed, newObj, desired *unstructured.Unstructured) ([]byte, error) {
+	var dryRun *unstructured.Unstructured
+	var err error
+	if applyStrategies.Get(client.Group, client.Kind) == v1alpha1.ChildApplyServerSide {
+		dryRun, err = applyStrategies.applyPatch(client, namespace, parent, desired, []string{metav1.DryRunAll})
+	} else {
+		dryRun, err = client.Namespace(namespace).Update(context.TODO(), newObj, metav1.UpdateOptions{DryRun: []string{metav1.DryRunAll}})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't dry-run the update of %v: %w", describeObject(observed), err)
+	}
+	if err := revertObjectMetaSystemFields(dryRun, observed); err != nil {
+		return nil, err
+	}
+	for _, field := range updateDiffIgnoredFields {
+		if err := revertField(dryRun, observed, field...); err != nil {
+			return nil, err
+		}
+	}
+	return JsonMergePatch(observed, dryRun)
+}
+
+// UpdateDiffMessage describes the diff of a child update.
+func (r ChildResult) UpdateDiffMessage() string {
+	if string(r.Diff) == "{}" {
+		return fmt.Sprintf("Updating %v, which doesn't change it once stored", describeObject(r.Object))
+	}
+	return fmt.Sprintf("Updating %v: %s", describeObject(r.Object), truncatePatch(r.Diff))
+}
@@ -0,0 +1,25 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestChildResult_UpdateDiffMessage(t *testing.T) {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("apps/v1")
+	obj.SetKind("Deployment")
+	obj.SetNamespace("default")
+	obj.SetName("web")
+
+	changed := ChildResult{Object: obj, Diff: []byte(`{"spec":{"replicas":3}}`)}
+	if got := changed.UpdateDiffMessage(); !strings.HasSuffix(got, `: {"spec":{"replicas":3}}`) {
+		t.Errorf("expected message ending with the diff, got %q", got)
+	}
+	unchanged := ChildResult{Object: obj, Diff: []byte(`{}`)}
+	if got := unchanged.UpdateDiffMessage(); !strings.Contains(got, "doesn't change it") {
+		t.Errorf("expected message saying the update changes nothing, got %q", got)
+	}
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	dynamicinformer "metacontroller/pkg/dynamic/informer"
+)
+
+// WatchSelectors converts the watch selector of a resource rule to the
+// selectors of the shared informer watching its objects.
+// A nil selector selects everything.
+func WatchSelectors(selector *v1alpha1.WatchSelector) (dynamicinformer.Selectors, error) {
+	if selector == nil {
+		return dynamicinformer.Selectors{}, nil
+	}
+	selectors := dynamicinformer.Selectors{FieldSelector: selector.FieldSelector}
+	if selector.LabelSelector != nil {
+		labelSelector, err := metav1.LabelSelectorAsSelector(selector.LabelSelector)
+		if err != nil {
+			return dynamicinformer.Selectors{}, fmt.Errorf("can't convert label selector: %w", err)
+		}
+		selectors.LabelSelector = labelSelector.String()
+	}
+	return selectors, nil
+}
+
+// InformerSelectors records the selectors of the informers of a controller.
+// A controller has a single informer per resource, so all its rules for the
+// same resource must have the same watch selector.
+type InformerSelectors map[schema.GroupVersionResource]dynamicinformer.Selectors
+
+// Add records that the informer of gvr is restricted by selectors. It returns
+// whether an informer was already recorded for gvr, which is an error if that
+// informer has different selectors.
+func (m InformerSelectors) Add(gvr schema.GroupVersionResource, selectors dynamicinformer.Selectors) (bool, error) {
+	existing, ok := m[gvr]
+	if !ok {
+		m[gvr] = selectors
+		return false, nil
+	}
+	if existing != selectors {
+		return true, fmt.Errorf("rules for resource %q in apiVersion %q have different watch selectors (%v and %v)", gvr.Resource, gvr.GroupVersion(), existing, selectors)
+	}
+	return true, nil
+}
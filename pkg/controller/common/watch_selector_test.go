@@ -0,0 +1,71 @@
+package common
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	dynamicinformer "metacontroller/pkg/dynamic/informer"
+)
+
+func TestWatchSelectors(t *testing.T) {
+	selectors, err := WatchSelectors(&v1alpha1.WatchSelector{
+		LabelSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"app": "web"},
+			MatchExpressions: []metav1.LabelSelectorRequirement{{
+				Key:      "tier",
+				Operator: metav1.LabelSelectorOpExists,
+			}},
+		},
+		FieldSelector: "metadata.namespace=prod",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := dynamicinformer.Selectors{LabelSelector: "app=web,tier", FieldSelector: "metadata.namespace=prod"}
+	if selectors != expected {
+		t.Errorf("expected %v, got %v", expected, selectors)
+	}
+}
+
+func TestWatchSelectors_Nil(t *testing.T) {
+	selectors, err := WatchSelectors(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !selectors.IsEmpty() {
+		t.Errorf("expected empty selectors, got %v", selectors)
+	}
+}
+
+func TestWatchSelectors_Invalid(t *testing.T) {
+	_, err := WatchSelectors(&v1alpha1.WatchSelector{
+		LabelSelector: &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{{
+				Key:      "tier",
+				Operator: metav1.LabelSelectorOpIn,
+			}},
+		},
+	})
+	if err == nil {
+		t.Error("expected error for In requirement without values")
+	}
+}
+
+func TestInformerSelectors_Add(t *testing.T) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "services"}
+	m := make(InformerSelectors)
+	selectors := dynamicinformer.Selectors{LabelSelector: "app=web"}
+
+	if exists, err := m.Add(gvr, selectors); exists || err != nil {
+		t.Fatalf("expected first rule to be added, got exists=%v err=%v", exists, err)
+	}
+	if exists, err := m.Add(gvr, selectors); !exists || err != nil {
+		t.Errorf("expected same selectors to share the informer, got exists=%v err=%v", exists, err)
+	}
+	if _, err := m.Add(gvr, dynamicinformer.Selectors{}); err == nil {
+		t.Error("expected error for different selectors")
+	}
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ApplyWaveAnnotation overrides the apply wave of a desired child.
+const ApplyWaveAnnotation = "metacontroller.k8s.io/apply-wave"
+
+// ChildWaves holds the apply wave and readiness check of each child kind.
+// Children are only created or updated once all desired children of the
+// previous waves exist and are ready. A nil *ChildWaves puts all children in
+// the same wave.
+type ChildWaves struct {
+	env   *cel.Env
+	kinds map[string]childWave
+}
+
+type childWave struct {
+	wave         int32
+	readiness    cel.Program
+	finalizeWave *int32
+}
+
+// NewChildWaves returns ChildWaves where all kinds are in wave 0, and
+// children are ready as soon as they exist.
+func NewChildWaves() (*ChildWaves, error) {
+	env, err := cel.NewEnv(cel.Declarations(decls.NewVar("object", decls.Dyn)))
+	if err != nil {
+		return nil, err
+	}
+	return &ChildWaves{env: env, kinds: make(map[string]childWave)}, nil
+}
+
+// Set sets the default wave of the given kind, and the CEL expression over
+// `object` which tells whether a child of that kind is ready, if any.
+func (w *ChildWaves) Set(apiGroup, kind string, wave int32, readinessExpression string) error {
+	options := childWave{wave: wave}
+	if readinessExpression != "" {
+		ast, issues := w.env.Compile(readinessExpression)
+		if issues != nil && issues.Err() != nil {
+			return fmt.Errorf("invalid readinessExpression of %v: %w", kind, issues.Err())
+		}
+		program, err := w.env.Program(ast)
+		if err != nil {
+			return fmt.Errorf("invalid readinessExpression of %v: %w", kind, err)
+		}
+		options.readiness = program
+	}
+	w.kinds[fmt.Sprintf("%s.%s", kind, apiGroup)] = options
+	return nil
+}
+
+// SetFinalizeWave overrides the finalize wave of the given kind, which
+// otherwise is the opposite of its apply wave, so children are deleted in the
+// reverse order of their creation.
+func (w *ChildWaves) SetFinalizeWave(apiGroup, kind string, wave int32) {
+	key := fmt.Sprintf("%s.%s", kind, apiGroup)
+	options := w.kinds[key]
+	options.finalizeWave = &wave
+	w.kinds[key] = options
+}
+
+func (w *ChildWaves) get(obj *unstructured.Unstructured) childWave {
+	apiGroup, _ := ParseAPIVersion(obj.GetAPIVersion())
+	return w.kinds[fmt.Sprintf("%s.%s", obj.GetKind(), apiGroup)]
+}
+
+// wave returns the apply wave of a desired child.
+func (w *ChildWaves) wave(obj *unstructured.Unstructured) (int32, error) {
+	value, ok := obj.GetAnnotations()[ApplyWaveAnnotation]
+	if !ok {
+		return w.get(obj).wave, nil
+	}
+	wave, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s annotation on %v: %w", ApplyWaveAnnotation, describeObject(obj), err)
+	}
+	return int32(wave), nil
+}
+
+// finalizeWave returns the finalize wave of an observed child.
+func (w *ChildWaves) finalizeWave(obj *unstructured.Unstructured) (int32, error) {
+	if wave := w.get(obj).finalizeWave; wave != nil {
+		return *wave, nil
+	}
+	wave, err := w.wave(obj)
+	return -wave, err
+}
+
+// Ready tells whether an observed child is ready, according to the
+// readinessExpression of its kind.
+func (w *ChildWaves) Ready(obj *unstructured.Unstructured) (bool, error) {
+	return w.ready(obj)
+}
+
+// ready tells whether an observed child is ready.
+func (w *ChildWaves) ready(obj *unstructured.Unstructured) (bool, error) {
+	program := w.get(obj).readiness
+	if program == nil {
+		return true, nil
+	}
+	out, _, err := program.Eval(map[string]interface{}{"object": obj.UnstructuredContent()})
+	if err != nil {
+		return false, fmt.Errorf("can't evaluate readinessExpression on %v: %w", describeObject(obj), err)
+	}
+	ready, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("readinessExpression on %v returned %T instead of a bool", describeObject(obj), out.Value())
+	}
+	return ready, nil
+}
+
+// Gate returns the observed and desired children which can be managed now,
+// leaving out those of the waves after the first one with a desired child
+// which is missing or not ready. Since they're left out of both, they're
+// neither created, updated nor deleted. Observed children which aren't
+// desired anymore are always kept, so they're deleted right away.
+// It also returns the wave which is waited for, if any.
+func (w *ChildWaves) Gate(observed, desired RelativeObjectMap) (RelativeObjectMap, RelativeObjectMap, *int32, error) {
+	if w == nil {
+		return observed, desired, nil, nil
+	}
+	// The observed counterpart of each desired child, by wave.
+	waves := make(map[int32][]*unstructured.Unstructured)
+	for gvk, group := range desired {
+		for name, obj := range group {
+			wave, err := w.wave(obj)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			waves[wave] = append(waves[wave], observed[gvk][name])
+		}
+	}
+	order := make([]int32, 0, len(waves))
+	for wave := range waves {
+		order = append(order, wave)
+	}
+	sort.Slice(order, func(i, j int) bool { return order[i] < order[j] })
+
+	// The last wave is never waited for, since there's nothing after it.
+	var waiting *int32
+	for i := 0; i < len(order)-1 && waiting == nil; i++ {
+		for _, child := range waves[order[i]] {
+			ready := child != nil
+			if ready {
+				var err error
+				if ready, err = w.ready(child); err != nil {
+					return nil, nil, nil, err
+				}
+			}
+			if !ready {
+				waiting = &order[i]
+				break
+			}
+		}
+	}
+	if waiting == nil {
+		return observed, desired, nil, nil
+	}
+
+	gatedObserved := make(RelativeObjectMap, len(observed))
+	for gvk, group := range observed {
+		gatedObserved[gvk] = make(map[string]*unstructured.Unstructured, len(group))
+		for name, obj := range group {
+			gatedObserved[gvk][name] = obj
+		}
+	}
+	gatedDesired := make(RelativeObjectMap, len(desired))
+	for gvk, group := range desired {
+		gatedDesired[gvk] = make(map[string]*unstructured.Unstructured, len(group))
+		for name, obj := range group {
+			wave, _ := w.wave(obj)
+			if wave > *waiting {
+				delete(gatedObserved[gvk], name)
+				continue
+			}
+			gatedDesired[gvk][name] = obj
+		}
+	}
+	return gatedObserved, gatedDesired, waiting, nil
+}
+
+// GateDeletion returns the observed children which can be managed while the
+// parent is finalized, leaving out the children to delete whose finalize wave
+// comes after the first one with children left. Since they're left out, they
+// aren't deleted until all children of the previous waves are gone, including
+// those pending deletion. It also returns the finalize wave which is waited
+// for, if any.
+func (w *ChildWaves) GateDeletion(observed, desired RelativeObjectMap) (RelativeObjectMap, *int32, error) {
+	if w == nil {
+		return observed, nil, nil
+	}
+	// The finalize wave of each child which isn't desired anymore.
+	waves := make(map[*unstructured.Unstructured]int32)
+	var first *int32
+	for gvk, group := range observed {
+		for name, obj := range group {
+			if desired[gvk][name] != nil {
+				continue
+			}
+			wave, err := w.finalizeWave(obj)
+			if err != nil {
+				return nil, nil, err
+			}
+			waves[obj] = wave
+			if first == nil || wave < *first {
+				first = &wave
+			}
+		}
+	}
+	var waiting *int32
+	for _, wave := range waves {
+		if wave > *first {
+			waiting = first
+			break
+		}
+	}
+	if waiting == nil {
+		return observed, nil, nil
+	}
+
+	gatedObserved := make(RelativeObjectMap, len(observed))
+	for gvk, group := range observed {
+		gatedObserved[gvk] = make(map[string]*unstructured.Unstructured, len(group))
+		for name, obj := range group {
+			if wave, ok := waves[obj]; ok && wave > *waiting {
+				continue
+			}
+			gatedObserved[gvk][name] = obj
+		}
+	}
+	return gatedObserved, waiting, nil
+}
@@ -0,0 +1,159 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/pointer"
+)
+
+func newWaveChild(kind, name string, ready bool) *unstructured.Unstructured {
+	child := &unstructured.Unstructured{}
+	child.SetAPIVersion("v1")
+	child.SetKind(kind)
+	child.SetName(name)
+	_ = unstructured.SetNestedField(child.Object, ready, "status", "ready")
+	return child
+}
+
+func TestChildWaves_Gate(t *testing.T) {
+	secrets := GroupVersionKind{schema.GroupVersionKind{Version: "v1", Kind: "Secret"}}
+	pods := GroupVersionKind{schema.GroupVersionKind{Version: "v1", Kind: "Pod"}}
+	waves, err := NewChildWaves()
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if err := waves.Set("", "Pod", 1, ""); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if err := waves.Set("", "Secret", 0, "object.status.ready"); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	desired := RelativeObjectMap{
+		secrets: {"creds": newWaveChild("Secret", "creds", false)},
+		pods:    {"app": newWaveChild("Pod", "app", false)},
+	}
+
+	tests := []struct {
+		name            string
+		observed        RelativeObjectMap
+		expectedWaiting bool
+	}{
+		{
+			name:            "first wave missing",
+			observed:        RelativeObjectMap{pods: {"app": newWaveChild("Pod", "app", false)}},
+			expectedWaiting: true,
+		},
+		{
+			name:            "first wave not ready",
+			observed:        RelativeObjectMap{secrets: {"creds": newWaveChild("Secret", "creds", false)}, pods: {"app": newWaveChild("Pod", "app", false)}},
+			expectedWaiting: true,
+		},
+		{
+			name:     "first wave ready",
+			observed: RelativeObjectMap{secrets: {"creds": newWaveChild("Secret", "creds", true)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			observed, gated, waiting, err := waves.Gate(tt.observed, desired)
+			if err != nil {
+				t.Fatalf("err should be nil, got: %v", err)
+			}
+			if (waiting != nil) != tt.expectedWaiting {
+				t.Fatalf("expected waiting to be %v, got: %v", tt.expectedWaiting, waiting)
+			}
+			_, podDesired := gated[pods]["app"]
+			_, podObserved := observed[pods]["app"]
+			if podDesired == tt.expectedWaiting {
+				t.Errorf("expected the Pod to be desired only once the Secret is ready")
+			}
+			if tt.expectedWaiting && podObserved {
+				t.Errorf("expected the observed Pod to be left out while waiting")
+			}
+		})
+	}
+}
+
+func TestChildWaves_Gate_whenAnnotationInvalid_returnError(t *testing.T) {
+	waves, _ := NewChildWaves()
+	child := newWaveChild("Pod", "app", true)
+	child.SetAnnotations(map[string]string{ApplyWaveAnnotation: "first"})
+	pods := GroupVersionKind{schema.GroupVersionKind{Version: "v1", Kind: "Pod"}}
+
+	_, _, _, err := waves.Gate(RelativeObjectMap{}, RelativeObjectMap{pods: {"app": child}})
+
+	if err == nil {
+		t.Errorf("expected an error for an invalid %s annotation", ApplyWaveAnnotation)
+	}
+}
+
+func TestChildWaves_GateDeletion(t *testing.T) {
+	pvcs := GroupVersionKind{schema.GroupVersionKind{Version: "v1", Kind: "PersistentVolumeClaim"}}
+	pods := GroupVersionKind{schema.GroupVersionKind{Version: "v1", Kind: "Pod"}}
+	secrets := GroupVersionKind{schema.GroupVersionKind{Version: "v1", Kind: "Secret"}}
+	waves, err := NewChildWaves()
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	// Pods are deleted first, since they're applied last.
+	if err := waves.Set("", "Pod", 1, ""); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	waves.SetFinalizeWave("", "Secret", 1)
+
+	tests := []struct {
+		name            string
+		observed        RelativeObjectMap
+		expectedWaiting *int32
+		expectedKinds   []GroupVersionKind
+	}{
+		{
+			name: "all waves left",
+			observed: RelativeObjectMap{
+				pods:    {"app": newWaveChild("Pod", "app", true)},
+				pvcs:    {"data": newWaveChild("PersistentVolumeClaim", "data", true)},
+				secrets: {"creds": newWaveChild("Secret", "creds", true)},
+			},
+			expectedWaiting: pointer.Int32Ptr(-1),
+			expectedKinds:   []GroupVersionKind{pods},
+		},
+		{
+			name: "first wave gone",
+			observed: RelativeObjectMap{
+				pvcs:    {"data": newWaveChild("PersistentVolumeClaim", "data", true)},
+				secrets: {"creds": newWaveChild("Secret", "creds", true)},
+			},
+			expectedWaiting: pointer.Int32Ptr(0),
+			expectedKinds:   []GroupVersionKind{pvcs},
+		},
+		{
+			name:          "last wave left",
+			observed:      RelativeObjectMap{secrets: {"creds": newWaveChild("Secret", "creds", true)}},
+			expectedKinds: []GroupVersionKind{secrets},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gated, waiting, err := waves.GateDeletion(tt.observed, RelativeObjectMap{})
+			if err != nil {
+				t.Fatalf("err should be nil, got: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.expectedWaiting, waiting); diff != "" {
+				t.Errorf("unexpected waiting wave (-want +got):\n%s", diff)
+			}
+			var kinds []GroupVersionKind
+			for gvk, group := range gated {
+				if len(group) > 0 {
+					kinds = append(kinds, gvk)
+				}
+			}
+			if diff := cmp.Diff(tt.expectedKinds, kinds); diff != "" {
+				t.Errorf("unexpected children to delete (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import "fmt"
+
+// ControllerWorkers returns the number of sync workers of a controller:
+// workers if it's set, or defaultWorkers, the number set with the --workers
+// flag, otherwise.
+func ControllerWorkers(defaultWorkers int, workers *int32) (int, error) {
+	if workers == nil {
+		return defaultWorkers, nil
+	}
+	if *workers < 1 {
+		return 0, fmt.Errorf("invalid workers %d: must be at least 1", *workers)
+	}
+	return int(*workers), nil
+}
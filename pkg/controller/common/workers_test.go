@@ -0,0 +1,31 @@
+package common
+
+import (
+	"testing"
+
+	"k8s.io/utils/pointer"
+)
+
+func TestControllerWorkers(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers *int32
+		want    int
+		wantErr bool
+	}{
+		{name: "unset uses the default", want: 5},
+		{name: "set overrides the default", workers: pointer.Int32Ptr(20), want: 20},
+		{name: "zero is invalid", workers: pointer.Int32Ptr(0), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ControllerWorkers(5, tt.workers)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ControllerWorkers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ControllerWorkers() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
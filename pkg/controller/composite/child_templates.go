@@ -0,0 +1,137 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+)
+
+// childTemplates renders the children defined inline in a CompositeController
+// spec, as Go templates of YAML or JSON manifests.
+type childTemplates []*template.Template
+
+var childTemplateFuncs = template.FuncMap{
+	"toJson": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+}
+
+func newChildTemplates(templates []v1alpha1.ChildTemplate) (childTemplates, error) {
+	parsed := make(childTemplates, 0, len(templates))
+	names := make(map[string]bool, len(templates))
+	for _, t := range templates {
+		if names[t.Name] {
+			return nil, fmt.Errorf("duplicate child template %q", t.Name)
+		}
+		names[t.Name] = true
+		tmpl, err := template.New(t.Name).Funcs(childTemplateFuncs).Option("missingkey=zero").Parse(t.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid child template %q: %w", t.Name, err)
+		}
+		parsed = append(parsed, tmpl)
+	}
+	return parsed, nil
+}
+
+// render returns the children rendered from parent. Each template may render
+// any number of manifests, separated by `---`.
+func (t childTemplates) render(parent *unstructured.Unstructured, derived map[string]interface{}) ([]*unstructured.Unstructured, error) {
+	data := map[string]interface{}{
+		"parent":  parent.UnstructuredContent(),
+		"derived": derived,
+	}
+	var children []*unstructured.Unstructured
+	for _, tmpl := range t {
+		var out bytes.Buffer
+		if err := tmpl.Execute(&out, data); err != nil {
+			return nil, fmt.Errorf("can't render child template %q: %w", tmpl.Name(), err)
+		}
+		decoder := utilyaml.NewYAMLOrJSONDecoder(&out, out.Len())
+		for {
+			var manifest map[string]interface{}
+			if err := decoder.Decode(&manifest); err != nil {
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				return nil, fmt.Errorf("child template %q rendered an invalid manifest: %w", tmpl.Name(), err)
+			}
+			if len(manifest) == 0 {
+				// Templates may render nothing, e.g. behind a condition.
+				continue
+			}
+			// Round-trip through JSON so numbers are decoded like in hook
+			// responses, as int64 rather than float64.
+			data, err := json.Marshal(manifest)
+			if err != nil {
+				return nil, err
+			}
+			child := &unstructured.Unstructured{}
+			if err := child.UnmarshalJSON(data); err != nil {
+				return nil, fmt.Errorf("child template %q rendered an invalid manifest: %w", tmpl.Name(), err)
+			}
+			children = append(children, child)
+		}
+	}
+	return children, nil
+}
+
+// overrideChildren returns the templated children, replaced by the children
+// of the same kind and name returned by the sync hook, followed by the other
+// children returned by the sync hook.
+func overrideChildren(parent *unstructured.Unstructured, templated, returned []*unstructured.Unstructured) []*unstructured.Unstructured {
+	if len(templated) == 0 {
+		return returned
+	}
+	key := func(obj *unstructured.Unstructured) string {
+		apiGroup, _ := common.ParseAPIVersion(obj.GetAPIVersion())
+		namespace := obj.GetNamespace()
+		if namespace == "" {
+			namespace = parent.GetNamespace()
+		}
+		return fmt.Sprintf("%s.%s/%s/%s", obj.GetKind(), apiGroup, namespace, obj.GetName())
+	}
+	overrides := make(map[string]*unstructured.Unstructured, len(returned))
+	for _, obj := range returned {
+		overrides[key(obj)] = obj
+	}
+	children := make([]*unstructured.Unstructured, 0, len(templated)+len(returned))
+	for _, obj := range templated {
+		if override, ok := overrides[key(obj)]; ok {
+			obj = override
+			delete(overrides, key(obj))
+		}
+		children = append(children, obj)
+	}
+	for _, obj := range returned {
+		if _, ok := overrides[key(obj)]; ok {
+			children = append(children, obj)
+		}
+	}
+	return children
+}
@@ -0,0 +1,111 @@
+package composite
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+func TestChildTemplates_Render(t *testing.T) {
+	templates, err := newChildTemplates([]v1alpha1.ChildTemplate{
+		{
+			Name: "service",
+			Template: `apiVersion: v1
+kind: Service
+metadata:
+  name: {{ .parent.metadata.name }}
+spec:
+  selector: {{ toJson .parent.spec.selector }}
+  ports:
+  - port: {{ .derived.port }}
+`,
+		},
+		{
+			Name: "configmaps",
+			Template: `{{ range $i, $key := .parent.spec.keys }}
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ $.parent.metadata.name }}-{{ $key }}
+{{ end }}`,
+		},
+	})
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	parent := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "web"},
+		"spec": map[string]interface{}{
+			"selector": map[string]interface{}{"app": "web"},
+			"keys":     []interface{}{"a", "b"},
+		},
+	}}
+
+	children, err := templates.render(parent, map[string]interface{}{"port": int64(8080)})
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	expected := []*unstructured.Unstructured{
+		{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Service",
+			"metadata":   map[string]interface{}{"name": "web"},
+			"spec": map[string]interface{}{
+				"selector": map[string]interface{}{"app": "web"},
+				"ports":    []interface{}{map[string]interface{}{"port": int64(8080)}},
+			},
+		}},
+		{Object: map[string]interface{}{"apiVersion": "v1", "kind": "ConfigMap", "metadata": map[string]interface{}{"name": "web-a"}}},
+		{Object: map[string]interface{}{"apiVersion": "v1", "kind": "ConfigMap", "metadata": map[string]interface{}{"name": "web-b"}}},
+	}
+	if diff := cmp.Diff(expected, children); diff != "" {
+		t.Errorf("unexpected children (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewChildTemplates_Invalid(t *testing.T) {
+	if _, err := newChildTemplates([]v1alpha1.ChildTemplate{{Name: "a", Template: "{{ .parent"}}); err == nil {
+		t.Error("expected an error for an unparsable template")
+	}
+	if _, err := newChildTemplates([]v1alpha1.ChildTemplate{{Name: "a"}, {Name: "a"}}); err == nil {
+		t.Error("expected an error for duplicate template names")
+	}
+}
+
+func TestOverrideChildren(t *testing.T) {
+	parent := &unstructured.Unstructured{}
+	parent.SetNamespace("ns")
+	newChild := func(kind, namespace, name, owner string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind(kind)
+		obj.SetNamespace(namespace)
+		obj.SetName(name)
+		obj.SetLabels(map[string]string{"from": owner})
+		return obj
+	}
+	templated := []*unstructured.Unstructured{
+		newChild("ConfigMap", "", "a", "template"),
+		newChild("ConfigMap", "", "b", "template"),
+	}
+	returned := []*unstructured.Unstructured{
+		newChild("ConfigMap", "ns", "b", "hook"),
+		newChild("Secret", "", "b", "hook"),
+	}
+
+	children := overrideChildren(parent, templated, returned)
+
+	expected := []*unstructured.Unstructured{
+		newChild("ConfigMap", "", "a", "template"),
+		newChild("ConfigMap", "ns", "b", "hook"),
+		newChild("Secret", "", "b", "hook"),
+	}
+	if diff := cmp.Diff(expected, children); diff != "" {
+		t.Errorf("unexpected children (-want +got):\n%s", diff)
+	}
+}
@@ -18,9 +18,11 @@ package composite
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"metacontroller/pkg/hooks"
 	"metacontroller/pkg/logging"
+	"metacontroller/pkg/metrics"
 	"reflect"
 	"sync"
 	"time"
@@ -32,6 +34,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/tools/record"
 
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"k8s.io/klog/v2"
@@ -40,22 +43,27 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 
 	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/audit"
 	mcclientset "metacontroller/pkg/client/generated/clientset/internalclientset"
 	mclisters "metacontroller/pkg/client/generated/lister/metacontroller/v1alpha1"
 	"metacontroller/pkg/controller/common"
 	"metacontroller/pkg/controller/common/customize"
 	"metacontroller/pkg/controller/common/finalizer"
+	"metacontroller/pkg/debug"
 	dynamicclientset "metacontroller/pkg/dynamic/clientset"
 	dynamiccontrollerref "metacontroller/pkg/dynamic/controllerref"
 	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
 	dynamicinformer "metacontroller/pkg/dynamic/informer"
+	dynamicobject "metacontroller/pkg/dynamic/object"
 	k8s "metacontroller/pkg/third_party/kubernetes"
+	"metacontroller/pkg/tracing"
 )
 
 type parentController struct {
@@ -68,24 +76,52 @@ type parentController struct {
 	dynClient      *dynamicclientset.Clientset
 	parentClient   *dynamicclientset.ResourceClient
 	parentInformer *dynamicinformer.ResourceInformer
+	parentSelector *parentSelector
+	// parentStatus is whether the status of parents is written. Built-in
+	// types don't have room for the status computed by hooks.
+	parentStatus bool
+	// selectorPruned is set once a generated spec.selector was pruned from a
+	// parent by the schema of the parent resource.
+	selectorPruned int32
 
 	revisionLister mclisters.ControllerRevisionLister
 
 	stopCh, doneCh chan struct{}
 	queue          workqueue.RateLimitingInterface
-
-	updateStrategy updateStrategyMap
-	childInformers common.InformerMap
+	lagTracker     *metrics.LagTracker
+	loopDetector   *common.LoopDetector
+	syncTokens     *common.SyncTokenStore
+	health         *common.SyncHealth
+	childVersions  *common.ChildVersionStore
+	derivedFields  *common.DerivedFields
+	conventions    *common.StatusConventions
+
+	updateStrategy  updateStrategyMap
+	applyStrategies *common.ChildApplyStrategies
+	childWaves      *common.ChildWaves
+	kindOrder       *common.KindOrder
+	retainedKinds   map[string]bool
+	childLimits     *common.ChildLimits
+	childValidator  *common.ChildValidator
+	childNamespaces *common.ChildNamespaces
+	childTemplates  childTemplates
+	childInformers  common.InformerMap
+
+	nsInformer *dynamicinformer.ResourceInformer
 
 	numWorkers    int
+	childDebounce time.Duration
 	eventRecorder record.EventRecorder
 
-	finalizer    *finalizer.Manager
-	customize    *customize.Manager
-	syncHook     hooks.HookExecutor
-	finalizeHook hooks.HookExecutor
+	finalizer      *finalizer.Manager
+	customize      *customize.Manager
+	syncHook       hooks.HookExecutor
+	finalizeHook   hooks.HookExecutor
+	eventsHook     hooks.HookExecutor
+	applyErrorHook hooks.HookExecutor
 
-	logger logr.Logger
+	logger   logr.Logger
+	logLevel *logging.Level
 }
 
 func newParentController(
@@ -97,8 +133,21 @@ func newParentController(
 	revisionLister mclisters.ControllerRevisionLister,
 	cc *v1alpha1.CompositeController,
 	numWorkers int,
+	syncLoops common.SyncLoopLimits,
 	logger logr.Logger,
 ) (pc *parentController, newErr error) {
+	dynClient, err := common.ControllerClientset(dynClient, cc.Spec.ClientRateLimit)
+	if err != nil {
+		return nil, err
+	}
+	numWorkers, err = common.ControllerWorkers(numWorkers, cc.Spec.Workers)
+	if err != nil {
+		return nil, err
+	}
+	childDebounce, err := common.ChildEventDebounce(cc.Spec.ChildEventDebounce)
+	if err != nil {
+		return nil, err
+	}
 	// Make a dynamic client for the parent resource.
 	parentClient, err := dynClient.Resource(cc.Spec.ParentResource.APIVersion, cc.Spec.ParentResource.Resource)
 	if err != nil {
@@ -110,9 +159,50 @@ func newParentController(
 	if err != nil {
 		return nil, err
 	}
+	applyStrategies, err := makeApplyStrategies(resources, cc)
+	if err != nil {
+		return nil, err
+	}
+	applyStrategies.SetLogger(logger.WithName(cc.Name))
+	childWaves, err := makeChildWaves(resources, cc)
+	if err != nil {
+		return nil, err
+	}
+	retainedKinds, err := makeRetainedKinds(resources, cc)
+	if err != nil {
+		return nil, err
+	}
+	childLimits, err := makeChildLimits(resources, cc)
+	if err != nil {
+		return nil, err
+	}
+	var namespaceInformer *dynamicinformer.ResourceInformer
+	childNamespaces, err := makeChildNamespaces(resources, cc, func(name string) (map[string]string, error) {
+		namespace, err := common.GetObject(namespaceInformer, "", name)
+		if err != nil {
+			return nil, err
+		}
+		return namespace.GetLabels(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Deltas are only computed against the full set of children, which isn't
+	// what the hook gets for each revision during rolling updates.
+	var childVersions *common.ChildVersionStore
+	if cc.Spec.DeltaSync != nil && *cc.Spec.DeltaSync {
+		if updateStrategy.anyRolling() {
+			return nil, fmt.Errorf("deltaSync can't be used with rolling update strategies")
+		}
+		childVersions = common.NewChildVersionStore()
+	}
 
 	// Create informer for the parent resource.
-	parentInformer, err := dynInformers.Resource(cc.Spec.ParentResource.APIVersion, cc.Spec.ParentResource.Resource)
+	parentWatchSelectors, err := common.WatchSelectors(cc.Spec.ParentResource.WatchSelector)
+	if err != nil {
+		return nil, fmt.Errorf("can't convert watch selector for parent resource: %w", err)
+	}
+	parentInformer, err := dynInformers.ResourceWithSelectors(cc.Spec.ParentResource.APIVersion, cc.Spec.ParentResource.Resource, parentWatchSelectors)
 	if err != nil {
 		return nil, fmt.Errorf("can't create informer for parent resource: %w", err)
 	}
@@ -126,19 +216,47 @@ func newParentController(
 			for _, childInformer := range childInformers {
 				childInformer.Close()
 			}
+			if namespaceInformer != nil {
+				namespaceInformer.Close()
+			}
 			parentInformer.Close()
 		}
 	}()
+	childWatchSelectors := make(common.InformerSelectors)
 	for _, child := range cc.Spec.ChildResources {
-		childInformer, err := dynInformers.Resource(child.APIVersion, child.Resource)
-		if err != nil {
-			return nil, fmt.Errorf("can't create informer for child resource: %w", err)
-		}
 		groupVersion, err := schema.ParseGroupVersion(child.APIVersion)
 		if err != nil {
 			return nil, fmt.Errorf("can't parse child resource groupVersion: %w", err)
 		}
-		childInformers.Set(groupVersion.WithResource(child.Resource), childInformer)
+		selectors, err := common.WatchSelectors(child.WatchSelector)
+		if err != nil {
+			return nil, fmt.Errorf("can't convert watch selector for child resource %q in apiVersion %q: %w", child.Resource, child.APIVersion, err)
+		}
+		gvr := groupVersion.WithResource(child.Resource)
+		exists, err := childWatchSelectors.Add(gvr, selectors)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			// Rules for the same resource share its informer.
+			continue
+		}
+		childInformer, err := dynInformers.ResourceWithSelectors(child.APIVersion, child.Resource, selectors)
+		if err != nil {
+			return nil, fmt.Errorf("can't create informer for child resource: %w", err)
+		}
+		childInformers.Set(gvr, childInformer)
+	}
+	// Namespace labels are only needed to match the namespaceSelector of
+	// cross-namespace children.
+	for _, child := range cc.Spec.ChildResources {
+		if child.CrossNamespace != nil && child.CrossNamespace.NamespaceSelector != nil {
+			namespaceInformer, err = dynInformers.Resource("v1", "namespaces")
+			if err != nil {
+				return nil, fmt.Errorf("can't create informer for namespaces: %w", err)
+			}
+			break
+		}
 	}
 
 	parentGroupVersion := schema.GroupVersion{Group: parentResource.Group, Version: parentResource.Version}
@@ -147,39 +265,96 @@ func newParentController(
 	parentResources.Set(schema.GroupKind{Group: parentGroupVersion.Group, Kind: parentResource.Kind}, parentResource)
 	parentInformers := make(common.InformerMap)
 	parentInformers.Set(parentGroupVersion.WithResource(parentResource.Name), parentInformer)
-	if cc.Spec.Hooks == nil {
-		return nil, fmt.Errorf("no hooks defined")
+	parentSelector, err := newParentSelector(cc.Spec.ParentResource)
+	if err != nil {
+		return nil, err
 	}
-	syncHook, err := hooks.NewHookExecutor(cc.Spec.Hooks.Sync, cc.Name, common.CompositeController, common.SyncHook)
+	childTemplates, err := newChildTemplates(cc.Spec.ChildTemplates)
 	if err != nil {
 		return nil, err
 	}
-	finalizeHook, err := hooks.NewHookExecutor(cc.Spec.Hooks.Finalize, cc.Name, common.CompositeController, common.FinalizeHook)
+	hooksSpec := cc.Spec.Hooks
+	if hooksSpec == nil {
+		hooksSpec = &v1alpha1.CompositeControllerHooks{}
+	}
+	if hooksSpec.Sync == nil && len(childTemplates) == 0 {
+		return nil, fmt.Errorf("no sync hook or child templates defined")
+	}
+	// All hooks of the controller share the same rate limiter.
+	hookRateLimiter, err := hooks.NewRateLimiter(cc.Spec.RateLimit)
+	if err != nil {
+		return nil, err
+	}
+	syncHook, err := hooks.NewHookExecutor(hooksSpec.Sync, cc.Name, common.CompositeController, common.SyncHook, dynClient, cc.Spec.HookTransport, logger)
+	if err != nil {
+		return nil, err
+	}
+	finalizeHook, err := hooks.NewHookExecutor(hooksSpec.Finalize, cc.Name, common.CompositeController, common.FinalizeHook, dynClient, cc.Spec.HookTransport, logger)
+	if err != nil {
+		return nil, err
+	}
+	eventsHook, err := hooks.NewHookExecutor(hooksSpec.Events, cc.Name, common.CompositeController, common.EventsHook, dynClient, cc.Spec.HookTransport, logger)
+	if err != nil {
+		return nil, err
+	}
+	applyErrorHook, err := hooks.NewHookExecutor(hooksSpec.ApplyError, cc.Name, common.CompositeController, common.ApplyErrorHook, dynClient, cc.Spec.HookTransport, logger)
+	if err != nil {
+		return nil, err
+	}
+	derivedFields, err := common.NewDerivedFields(cc.Spec.DerivedFields, "parent", "children")
+	if err != nil {
+		return nil, err
+	}
+	conventions, err := common.NewStatusConventions(cc.Spec.StatusConventions)
 	if err != nil {
 		return nil, err
 	}
 
 	pc = &parentController{
-		cc:             cc,
-		resources:      resources,
-		mcClient:       mcClient,
-		dynClient:      dynClient,
-		childInformers: childInformers,
-		parentClient:   parentClient,
-		parentInformer: parentInformer,
-		parentResource: parentResource,
-		revisionLister: revisionLister,
-		updateStrategy: updateStrategy,
-		queue:          workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), common.CompositeController.String()+"-"+cc.Name),
-		numWorkers:     numWorkers,
-		eventRecorder:  eventRecorder,
+		cc:              cc,
+		resources:       resources,
+		mcClient:        mcClient,
+		dynClient:       dynClient,
+		childInformers:  childInformers,
+		parentClient:    parentClient,
+		parentInformer:  parentInformer,
+		parentSelector:  parentSelector,
+		parentStatus:    parentResource.HasSubresource("status") && !common.IsBuiltInAPIGroup(parentResource.Group),
+		parentResource:  parentResource,
+		revisionLister:  revisionLister,
+		updateStrategy:  updateStrategy,
+		applyStrategies: applyStrategies,
+		childWaves:      childWaves,
+		kindOrder:       common.NewKindOrder(cc.Spec.KindOrder),
+		retainedKinds:   retainedKinds,
+		childLimits:     childLimits,
+		childValidator:  makeChildValidator(dynClient, cc),
+		childNamespaces: childNamespaces,
+		childTemplates:  childTemplates,
+		nsInformer:      namespaceInformer,
+		queue:           metrics.NewControllerQueue(cc.Name, common.CompositeController),
+		lagTracker:      metrics.NewLagTracker(cc.Name, common.CompositeController),
+		loopDetector:    common.NewLoopDetector(syncLoops),
+		syncTokens:      common.NewSyncTokenStore(),
+		health:          common.NewSyncHealth(),
+		childVersions:   childVersions,
+		derivedFields:   derivedFields,
+		conventions:     conventions,
+		numWorkers:      numWorkers,
+		childDebounce:   childDebounce,
+		eventRecorder:   eventRecorder,
+		// Children in other namespaces, or cluster-scoped ones, aren't deleted
+		// with the parent by the garbage collector, so a finalizer is needed
+		// to delete them. Retained children need one to be released in time.
 		finalizer: finalizer.NewManager(
-			"metacontroller.io/compositecontroller-"+cc.Name,
-			cc.Spec.Hooks.Finalize != nil,
+			parentFinalizerName(cc.Name),
+			hooksSpec.Finalize != nil || (parentResource.Namespaced && childNamespaces.Any()) || len(retainedKinds) > 0,
 		),
-		syncHook:     syncHook,
-		finalizeHook: finalizeHook,
-		logger:       logger.WithName(cc.Name),
+		syncHook:       hooks.WithRateLimiter(syncHook, hookRateLimiter),
+		finalizeHook:   hooks.WithRateLimiter(finalizeHook, hookRateLimiter),
+		eventsHook:     hooks.WithRateLimiter(eventsHook, hookRateLimiter),
+		applyErrorHook: hooks.WithRateLimiter(applyErrorHook, hookRateLimiter),
+		logger:         logger.WithName(cc.Name),
 	}
 
 	pc.customize, err = customize.NewCustomizeManager(
@@ -192,6 +367,8 @@ func newParentController(
 		parentResources,
 		pc.logger,
 		common.CompositeController,
+		hookRateLimiter,
+		cc.Spec.HookTransport,
 	)
 	if err != nil {
 		return nil, err
@@ -203,6 +380,7 @@ func newParentController(
 func (pc *parentController) Start() {
 	pc.stopCh = make(chan struct{})
 	pc.doneCh = make(chan struct{})
+	debug.Controllers.Register(pc.cc.Name, common.CompositeController, pc.watched)
 
 	pc.customize.Start(pc.stopCh)
 
@@ -216,21 +394,24 @@ func (pc *parentController) Start() {
 	}
 	if pc.cc.Spec.ResyncPeriodSeconds != nil {
 		// Use a custom resync period if requested. This only applies to the parent.
-		resyncPeriod := time.Duration(*pc.cc.Spec.ResyncPeriodSeconds) * time.Second
-		// Put a reasonable limit on it.
-		if resyncPeriod < time.Second {
-			resyncPeriod = time.Second
-		}
-		pc.parentInformer.Informer().AddEventHandlerWithResyncPeriod(parentHandlers, resyncPeriod)
+		pc.parentInformer.Informer().AddEventHandlerWithResyncPeriod(parentHandlers, resyncPeriod(*pc.cc.Spec.ResyncPeriodSeconds))
 	} else {
 		pc.parentInformer.Informer().AddEventHandler(parentHandlers)
 	}
-	for _, childInformer := range pc.childInformers {
-		childInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+	childResyncPeriods := pc.childResyncPeriods()
+	for gvr, childInformer := range pc.childInformers {
+		childHandlers := cache.ResourceEventHandlerFuncs{
 			AddFunc:    pc.onChildAdd,
 			UpdateFunc: pc.onChildUpdate,
 			DeleteFunc: pc.onChildDelete,
-		})
+		}
+		if period, ok := childResyncPeriods[gvr]; ok {
+			// Resyncs of these children sync their parents.
+			childHandlers.UpdateFunc = pc.onChildResync
+			childInformer.Informer().AddEventHandlerWithResyncPeriod(childHandlers, period)
+		} else {
+			childInformer.Informer().AddEventHandler(childHandlers)
+		}
 	}
 
 	go func() {
@@ -249,6 +430,9 @@ func (pc *parentController) Start() {
 		for _, childInformer := range pc.childInformers {
 			syncFuncs = append(syncFuncs, childInformer.Informer().HasSynced)
 		}
+		if pc.nsInformer != nil {
+			syncFuncs = append(syncFuncs, pc.nsInformer.Informer().HasSynced)
+		}
 		if !cache.WaitForNamedCacheSync(pc.parentResource.Kind, pc.stopCh, syncFuncs...) {
 			// We wait forever unless Stop() is called, so this isn't an error.
 			pc.logger.Info("CompositeController cache sync never finished", "controller", pc.cc)
@@ -267,8 +451,40 @@ func (pc *parentController) Start() {
 	}()
 }
 
+// resyncPeriod converts a resync period in seconds, with a reasonable limit.
+func resyncPeriod(seconds int32) time.Duration {
+	period := time.Duration(seconds) * time.Second
+	if period < time.Second {
+		period = time.Second
+	}
+	return period
+}
+
+// childResyncPeriods returns the resync period of each child resource which
+// overrides it.
+func (pc *parentController) childResyncPeriods() map[schema.GroupVersionResource]time.Duration {
+	periods := make(map[schema.GroupVersionResource]time.Duration)
+	for _, child := range pc.cc.Spec.ChildResources {
+		if child.ResyncPeriodSeconds == nil {
+			continue
+		}
+		groupVersion, err := schema.ParseGroupVersion(child.APIVersion)
+		if err != nil {
+			continue
+		}
+		periods[groupVersion.WithResource(child.Resource)] = resyncPeriod(*child.ResyncPeriodSeconds)
+	}
+	return periods
+}
+
+// watched returns the state of the watches of the controller.
+func (pc *parentController) watched() []dynamicinformer.WatchState {
+	return common.WatchStates(append(pc.childInformers.Informers(), pc.parentInformer, pc.nsInformer)...)
+}
+
 func (pc *parentController) Stop() {
 	close(pc.stopCh)
+	debug.Controllers.Unregister(pc.cc.Name, common.CompositeController)
 	pc.queue.ShutDown()
 	<-pc.doneCh
 
@@ -277,10 +493,15 @@ func (pc *parentController) Stop() {
 		informer.Informer().RemoveEventHandlers()
 		informer.Close()
 	}
+	if pc.nsInformer != nil {
+		pc.nsInformer.Close()
+	}
 	// Remove event handlers and close informer for the parent resource.
 	pc.parentInformer.Informer().RemoveEventHandlers()
 	pc.parentInformer.Close()
 	pc.customize.Stop()
+	pc.lagTracker.Stop()
+	metrics.ForgetSyncLoops(pc.cc.Name, common.CompositeController)
 }
 
 func (pc *parentController) worker() {
@@ -294,6 +515,13 @@ func (pc *parentController) processNextWorkItem() bool {
 		return false
 	}
 	defer pc.queue.Done(key)
+	pc.lagTracker.Dequeued(key.(string))
+
+	// Parents synced in a hot loop wait for their backoff to expire.
+	if backoff := pc.loopDetector.Backoff(key.(string)); backoff > 0 {
+		pc.queue.AddAfter(key, backoff)
+		return true
+	}
 
 	if err := pc.sync(key.(string)); err != nil {
 		utilruntime.HandleError(fmt.Errorf("failed to sync %v '%v': %w", pc.parentResource.Kind, key, err))
@@ -306,11 +534,29 @@ func (pc *parentController) processNextWorkItem() bool {
 }
 
 func (pc *parentController) enqueueParentObject(obj interface{}) {
+	pc.enqueueParentObjectDebounced(obj, 0)
+}
+
+// enqueueParentForChild enqueues parent because of an event of one of its
+// children, once the child event debounce window of the controller is over.
+func (pc *parentController) enqueueParentForChild(parent *unstructured.Unstructured) {
+	pc.enqueueParentObjectDebounced(parent, pc.childDebounce)
+}
+
+// enqueueParentObjectDebounced enqueues obj after debounce, if positive.
+// The queue only keeps the earliest pending time of a key, so all events
+// within the window are coalesced into a single sync.
+func (pc *parentController) enqueueParentObjectDebounced(obj interface{}, debounce time.Duration) {
 	key, err := common.KeyFunc(obj)
 	if err != nil {
 		utilruntime.HandleError(fmt.Errorf("couldn't get key for object %+v: %w", obj, err))
 		return
 	}
+	pc.lagTracker.Enqueued(key)
+	if debounce > 0 {
+		pc.queue.AddAfter(key, debounce)
+		return
+	}
 	pc.queue.Add(key)
 }
 
@@ -331,6 +577,7 @@ func (pc *parentController) updateParentObject(old, cur interface{}) {
 	// different status (e.g. you have some incrementing counter).
 	// Doing that is an anti-pattern anyway because status generation should be
 	// idempotent if nothing meaningful has actually changed in the system.
+	pc.lagTracker.ObserveUpdate(old.(*unstructured.Unstructured), cur.(*unstructured.Unstructured))
 	pc.enqueueParentObject(cur)
 }
 
@@ -365,6 +612,20 @@ func (pc *parentController) resolveControllerRef(childNamespace string, controll
 	return parent
 }
 
+// resolveLabelOwner returns the parent recorded on child with labels,
+// or nil if it isn't a parent of this controller.
+func (pc *parentController) resolveLabelOwner(child *unstructured.Unstructured) *unstructured.Unstructured {
+	namespace, name, uid, found := common.LabelOwnerOf(child)
+	if !found || !pc.parentResource.Namespaced {
+		return nil
+	}
+	parent, err := common.GetObject(pc.parentInformer, namespace, name)
+	if err != nil || parent.GetUID() != uid {
+		return nil
+	}
+	return parent
+}
+
 func (pc *parentController) onChildAdd(obj interface{}) {
 	child := obj.(*unstructured.Unstructured)
 
@@ -373,6 +634,13 @@ func (pc *parentController) onChildAdd(obj interface{}) {
 		return
 	}
 
+	// Children in other namespaces than their parent's are owned through labels.
+	if parent := pc.resolveLabelOwner(child); parent != nil {
+		pc.logger.V(4).Info("Child created or updated", "parent_kind", pc.parentResource.Kind, "parent", parent, "child", child)
+		pc.enqueueParentForChild(parent)
+		return
+	}
+
 	// If it has a ControllerRef, that's all that matters.
 	if controllerRef := metav1.GetControllerOf(child); controllerRef != nil {
 		parent := pc.resolveControllerRef(child.GetNamespace(), controllerRef)
@@ -381,7 +649,7 @@ func (pc *parentController) onChildAdd(obj interface{}) {
 			return
 		}
 		pc.logger.V(4).Info("Child created or updated", "parent_kind", pc.parentResource.Kind, "parent", parent, "child", child)
-		pc.enqueueParentObject(parent)
+		pc.enqueueParentForChild(parent)
 		return
 	}
 
@@ -393,7 +661,7 @@ func (pc *parentController) onChildAdd(obj interface{}) {
 	}
 	pc.logger.V(4).Info("Orphan child created or updated", "parent_kind", pc.parentResource.Kind, "child", child)
 	for _, parent := range parents {
-		pc.enqueueParentObject(parent)
+		pc.enqueueParentForChild(parent)
 	}
 }
 
@@ -406,12 +674,33 @@ func (pc *parentController) onChildUpdate(old, cur interface{}) {
 	if oldChild.GetResourceVersion() == curChild.GetResourceVersion() {
 		return
 	}
+	pc.lagTracker.ObserveUpdate(oldChild, curChild)
 
 	// Other than that, we treat updates the same as creates.
 	// Level-triggered controllers shouldn't care what the old state was.
 	pc.onChildAdd(cur)
 }
 
+// onChildResync handles updates of children whose resource has its own resync
+// period. Unlike onChildUpdate, resyncs enqueue the parent of the child, but
+// not the potential parents of orphans.
+func (pc *parentController) onChildResync(old, cur interface{}) {
+	oldChild := old.(*unstructured.Unstructured)
+	curChild := cur.(*unstructured.Unstructured)
+	if oldChild.GetResourceVersion() != curChild.GetResourceVersion() {
+		pc.onChildUpdate(old, cur)
+		return
+	}
+
+	if parent := pc.resolveLabelOwner(curChild); parent != nil {
+		pc.enqueueParentForChild(parent)
+	} else if controllerRef := metav1.GetControllerOf(curChild); controllerRef != nil {
+		if parent := pc.resolveControllerRef(curChild.GetNamespace(), controllerRef); parent != nil {
+			pc.enqueueParentForChild(parent)
+		}
+	}
+}
+
 func (pc *parentController) onChildDelete(obj interface{}) {
 	child, ok := obj.(*unstructured.Unstructured)
 
@@ -428,6 +717,12 @@ func (pc *parentController) onChildDelete(obj interface{}) {
 		}
 	}
 
+	if parent := pc.resolveLabelOwner(child); parent != nil {
+		pc.logger.V(4).Info("Child deleted", "parent_kind", pc.parentResource.Kind, "parent", parent, "child", child)
+		pc.enqueueParentForChild(parent)
+		return
+	}
+
 	// If it's an orphan, there's nothing to do because we never adopt orphans
 	// that are being deleted.
 	controllerRef := metav1.GetControllerOf(child)
@@ -442,7 +737,7 @@ func (pc *parentController) onChildDelete(obj interface{}) {
 		return
 	}
 	pc.logger.V(4).Info("Child deleted", "parent_kind", pc.parentResource.Kind, "parent", parent, "child", child)
-	pc.enqueueParentObject(parent)
+	pc.enqueueParentForChild(parent)
 }
 
 func (pc *parentController) findPotentialParents(child *unstructured.Unstructured) []*unstructured.Unstructured {
@@ -487,39 +782,106 @@ func (pc *parentController) sync(key string) error {
 	if apierrors.IsNotFound(err) {
 		// Swallow the error since there's no point retrying if the parent is gone.
 		pc.logger.V(4).Info("Parent object has been deleted", "parent_kind", pc.parentResource.Kind, "object", klog.KRef(namespace, name))
+		pc.syncTokens.Forget(key)
+		if pc.childVersions != nil {
+			pc.childVersions.Forget(key)
+		}
+		pc.loopDetector.Forget(key)
+		pc.health.Forget(key)
 		return nil
 	}
 	if err != nil {
 		return err
 	}
-	err = pc.syncParentObject(parent)
+	// Parents which don't match the selectors of the parent resource rule are
+	// ignored, unless they still have our finalizer to remove.
+	if !pc.parentSelector.Matches(parent) && !dynamicobject.HasFinalizer(parent, pc.finalizer.Name) {
+		pc.logger.V(4).Info("Ignoring parent not matching selectors", "object", klog.KObj(parent))
+		pc.health.Forget(key)
+		return nil
+	}
+	ctx, writes := common.WithSyncWrites(logging.NewContext(context.Background(), pc.logger))
+	ctx, span := tracing.Start(ctx, "sync",
+		"controller.type", common.CompositeController.String(),
+		"controller.name", pc.cc.Name,
+		"parent.kind", pc.parentResource.Kind,
+		"parent.namespace", namespace,
+		"parent.name", name)
+	defer span.End()
+	err = pc.syncParentObject(ctx, parent)
+	span.RecordError(err)
+	pc.health.Record(key, parent, err)
 	if err != nil {
-		pc.eventRecorder.Eventf(
-			parent,
-			v1.EventTypeWarning,
-			events.ReasonSyncError,
-			"Sync error: %s", err)
+		pc.recordSyncError(parent, err)
+		// Failures to apply children were each reported on their own.
+		var applyErr *common.ChildApplyError
+		if !errors.As(err, &applyErr) {
+			pc.syncEvents(ctx).Eventf(
+				parent,
+				v1.EventTypeWarning,
+				hooks.SyncErrorReason(err),
+				"Sync error: %s", err)
+		}
+	} else if writes.Wrote() {
+		pc.recordSyncLoop(ctx, key, parent)
 	}
 	return err
 }
 
-func (pc *parentController) syncParentObject(parent *unstructured.Unstructured) error {
+func (pc *parentController) syncParentObject(ctx context.Context, parent *unstructured.Unstructured) error {
+	if pc.reportOnly() {
+		return pc.reportDrift(ctx, parent)
+	}
+
+	// Paused parents are left alone, even while they're deleted, except for a
+	// condition telling so.
+	if reason, message := pc.pauseReason(parent); reason != "" {
+		pc.logger.V(4).Info("Sync paused", "object", klog.KObj(parent), "reason", reason)
+		if err := pc.updatePausedCondition(parent, reason, message); err != nil {
+			return fmt.Errorf("can't update status for %v %v/%v: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
+		}
+		return nil
+	}
+
 	// Before taking any other action, add our finalizer (if desired).
 	// This ensures we have a chance to clean up after any action we later take.
-	updatedParent, err := pc.finalizer.SyncObject(pc.parentClient, parent)
+	updatedParent, err := pc.finalizer.SyncObject(pc.parentClient.Audited(pc.auditRecorder(parent)), parent)
 	if err != nil {
 		// If we fail to do this, abort before doing anything else and requeue.
 		return fmt.Errorf("can't sync finalizer for %v %v/%v: %w", parent.GetKind(), parent.GetNamespace(), parent.GetName(), err)
 	}
 	parent = updatedParent
 
+	// With generated selectors, keep the parent's spec.selector in line with
+	// the labels of its children.
+	parent, err = pc.syncGeneratedSelector(parent)
+	if err != nil {
+		return err
+	}
+
+	// With consistent reads, read the parent and its potential children
+	// from the API server at a single resourceVersion instead of from caches.
+	var snapshot childSnapshot
+	if pc.cc.Spec.ConsistentReads != nil && *pc.cc.Spec.ConsistentReads {
+		parent, snapshot, err = pc.readSnapshot(ctx, parent)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Roll the parent back to a recorded revision, if requested.
+	// Updating the parent triggers another sync with the restored state.
+	if rolledBack, err := pc.syncRollback(ctx, parent); err != nil || rolledBack {
+		return err
+	}
+
 	// Claim all matching child resources, including orphan/adopt as necessary.
-	observedChildren, err := pc.claimChildren(parent)
+	observedChildren, err := pc.claimChildren(parent, snapshot)
 	if err != nil {
 		return err
 	}
 
-	relatedObjects, err := pc.customize.GetRelatedObjects(parent)
+	relatedObjects, err := pc.customize.GetRelatedObjects(ctx, parent)
 	if err != nil {
 		return err
 	}
@@ -527,21 +889,91 @@ func (pc *parentController) syncParentObject(parent *unstructured.Unstructured)
 	// Reconcile ControllerRevisions belonging to this parent.
 	// Call the sync hook for each revision, then compute the overall status and
 	// desired children, accounting for any rollout in progress.
-	syncResult, err := pc.syncRevisions(parent, observedChildren, relatedObjects)
+	syncResult, err := pc.syncRevisions(ctx, parent, observedChildren, relatedObjects)
 	if err != nil {
 		return err
 	}
 	desiredChildren := common.MakeRelativeObjectMap(parent, syncResult.Children)
+	// Reject the whole response if it has more children than allowed,
+	// leaving the parent and its children as they are.
+	if limitErr := pc.childLimits.Check(desiredChildren); limitErr != nil {
+		pc.syncEvents(ctx).Event(parent, v1.EventTypeWarning, events.ReasonChildLimitExceeded, limitErr.Error())
+		if err := pc.updateStatusCondition(parent, func(status map[string]interface{}) map[string]interface{} {
+			return common.SetChildLimitExceededCondition(status, limitErr)
+		}); err != nil {
+			return fmt.Errorf("can't update status for %v %v/%v: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
+		}
+		return fmt.Errorf("%v %v/%v: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), limitErr)
+	}
+	if validationErr := pc.childValidator.Check(desiredChildren); validationErr != nil {
+		pc.syncEvents(ctx).Event(parent, v1.EventTypeWarning, events.ReasonChildInvalid, validationErr.Error())
+		if err := pc.updateStatusCondition(parent, func(status map[string]interface{}) map[string]interface{} {
+			return common.SetChildInvalidCondition(status, validationErr)
+		}); err != nil {
+			return fmt.Errorf("can't update status for %v %v/%v: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
+		}
+		return fmt.Errorf("%v %v/%v: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), validationErr)
+	}
+	if err := pc.childNamespaces.Check(parent, desiredChildren); err != nil {
+		return err
+	}
+
+	// Desired children owned by someone else are left alone, rather than
+	// fighting over them.
+	conflicts := common.FindConflicts(parent, observedChildren, desiredChildren, common.CachedChildGetter(pc.dynClient, pc.childInformers))
+	for _, conflict := range conflicts {
+		pc.syncEvents(ctx).Event(parent, v1.EventTypeWarning, events.ReasonChildConflict, conflict.Message(parent))
+	}
+
+	// Children owned through labels aren't deleted with the parent by the
+	// garbage collector, so they're deleted before our finalizer is removed.
+	if parent.GetDeletionTimestamp() != nil && pc.childNamespaces.Any() {
+		remaining := pc.finalizeLabelOwnedChildren(parent, observedChildren, desiredChildren)
+		syncResult.Finalized = (syncResult.Finalized || !pc.finalizeHook.IsEnabled()) && !remaining
+	}
+
+	// Retained children are released from the parent before our finalizer is
+	// removed, so the garbage collector leaves them alone.
+	if parent.GetDeletionTimestamp() != nil && len(pc.retainedKinds) > 0 && pc.finalizer.ShouldFinalize(parent) {
+		if err := pc.releaseRetainedChildren(parent, observedChildren, desiredChildren); err != nil {
+			return err
+		}
+		if !pc.finalizeHook.IsEnabled() && !pc.childNamespaces.Any() {
+			syncResult.Finalized = true
+		}
+	}
+
+	// While finalizing, children are deleted in finalize waves. The children
+	// of a wave are only deleted once those of the previous waves are gone,
+	// and our finalizer is kept until then.
+	managedChildren := observedChildren
+	if parent.GetDeletionTimestamp() != nil {
+		var deletingWave *int32
+		managedChildren, deletingWave, err = pc.childWaves.GateDeletion(observedChildren, desiredChildren)
+		if err != nil {
+			return err
+		}
+		if deletingWave != nil {
+			pc.logger.V(4).Info("Waiting for children to be deleted", "object", klog.KObj(parent), "wave", *deletingWave)
+			syncResult.Finalized = false
+		}
+	}
 
 	// Enqueue a delayed resync, if requested.
 	if syncResult.ResyncAfterSeconds > 0 {
 		pc.enqueueParentObjectAfter(parent, time.Duration(syncResult.ResyncAfterSeconds*float64(time.Second)))
 	}
 
+	// The hook reported that nothing changed since the last applied sync.
+	if syncResult.NotModified {
+		pc.logger.V(4).Info("Sync not modified", "object", klog.KObj(parent))
+		return nil
+	}
+
 	// If all revisions agree that they've finished finalizing,
 	// remove our finalizer.
 	if syncResult.Finalized {
-		updatedParent, err := pc.parentClient.Namespace(parent.GetNamespace()).RemoveFinalizer(parent, pc.finalizer.Name)
+		updatedParent, err := pc.parentClient.Namespace(parent.GetNamespace()).Audited(pc.auditRecorder(parent)).RemoveFinalizer(parent, pc.finalizer.Name)
 		if err != nil {
 			return fmt.Errorf("can't remove finalizer for %v %v/%v: %w", parent.GetKind(), parent.GetNamespace(), parent.GetName(), err)
 		}
@@ -549,6 +981,190 @@ func (pc *parentController) syncParentObject(parent *unstructured.Unstructured)
 	}
 
 	// Enforce invariants between parent selector and child labels.
+	if err := pc.enforceChildLabels(parent, desiredChildren); err != nil {
+		return err
+	}
+
+	// With the Review adoption policy, desired children which exist as orphans
+	// are left alone until approved, reporting what adopting them would do.
+	var pendingAdoptions []common.PendingAdoption
+	if pc.cc.Spec.AdoptionPolicy == v1alpha1.AdoptionReview {
+		pendingAdoptions, err = common.FindPendingAdoptions(pc.updateStrategy, pc.applyStrategies, parent, observedChildren, desiredChildren, common.CachedChildGetter(pc.dynClient, pc.childInformers))
+		if err != nil {
+			return err
+		}
+		for _, adoption := range pendingAdoptions {
+			pc.syncEvents(ctx).Event(parent, v1.EventTypeNormal, events.ReasonAdoptionPending, adoption.Message())
+		}
+	}
+
+	// Reconcile child objects belonging to this parent.
+	// Remember manage error, but continue to update status regardless.
+	//
+	// We only manage children if the parent is "alive" (not pending deletion),
+	// or if it's pending deletion and we have a `finalize` hook.
+	var manageErr error
+	var childResults []common.ChildResult
+	var waitingWave *int32
+	var waitingReplacement bool
+	if parent.GetDeletionTimestamp() == nil || pc.finalizer.ShouldFinalize(parent) {
+		// Reconcile children.
+		_, span := tracing.Start(ctx, "manage children")
+		// Children of later apply waves are left alone until the previous ones
+		// are ready. Their readiness changes trigger a new sync.
+		var managedObserved, managedDesired common.RelativeObjectMap
+		managedObserved, managedDesired, waitingWave, err = pc.childWaves.Gate(managedChildren, desiredChildren)
+		// Children replaced with the BlueGreen method are kept until their
+		// replacement is ready, which triggers a new sync.
+		if err == nil {
+			managedObserved, managedDesired, waitingReplacement, err = common.BlueGreen(pc.updateStrategy, managedObserved, managedDesired, pc.childReady)
+		}
+		// Children updated with the Recreate method are recreated within the
+		// maxUnavailable budget, and from the partition on.
+		if err == nil {
+			managedObserved, managedDesired, err = pc.gateRecreate(managedObserved, managedDesired)
+		}
+		if err == nil {
+			if waitingReplacement {
+				pc.logger.V(4).Info("Waiting for replacement children to be ready", "object", klog.KObj(parent))
+			}
+			if waitingWave != nil {
+				pc.logger.V(4).Info("Waiting for children to be ready", "object", klog.KObj(parent), "wave", *waitingWave)
+			}
+			childResults, err = common.ManageChildren(ctx, pc.dynClient, pc.updateStrategy, pc.applyStrategies, pc.kindOrder, parent, managedObserved, managedDesired)
+		}
+		// Subresources aren't dry-run, so they're left alone in dry-run mode.
+		if err == nil && len(syncResult.ChildSubresources) > 0 && !pc.applyStrategies.DryRun() {
+			err = common.ApplyChildSubresources(pc.dynClient, pc.auditRecorder(parent), parent, observedChildren, syncResult.ChildSubresources)
+		}
+		if err != nil {
+			manageErr = fmt.Errorf("can't reconcile children for %v %v/%v: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
+		}
+		span.RecordError(manageErr)
+		span.End()
+		common.AuditChildren(pc.cc.Name, common.CompositeController, parent, childResults)
+		common.RecordWrites(ctx, common.WroteChildren(childResults))
+		pc.recordUpdateDiffs(ctx, parent, childResults)
+		pc.recordChildFailures(ctx, parent, childResults)
+		pc.recordDryRunWrites(ctx, parent, childResults)
+		pc.callEventsHook(ctx, parent, childResults)
+	}
+	var applyErrorResult *ApplyErrorHookResponse
+	if manageErr != nil {
+		applyErrorResult = pc.callApplyErrorHook(ctx, parent, childResults)
+	}
+
+	// Update parent status.
+	// We'll want to make sure this happens after manageChildren once we support observedGeneration.
+	// Report whether related objects were selected using stale customize rules.
+	customizeErr := pc.customize.CustomizeHookError(parent)
+	record := common.SyncRecord{HookLatency: syncResult.latency, Error: manageErr}
+	var updatedStatus *unstructured.Unstructured
+	if applyErrorResult != nil && applyErrorResult.Status != nil {
+		if updatedStatus, err = pc.updateParentStatus(parent, pc.desiredStatus(parent, applyErrorResult.Status, observedChildren, conflicts, pendingAdoptions, customizeErr, record)); err != nil {
+			return fmt.Errorf("can't update status for %v %v/%v: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
+		}
+	} else if syncResult.StatusPatch != nil {
+		if updatedStatus, err = pc.patchParentStatus(parent, syncResult.StatusPatch, syncResult.StatusChecksum, observedChildren, conflicts, pendingAdoptions, customizeErr, record); err != nil {
+			return fmt.Errorf("can't patch status for %v %v/%v: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
+		}
+	} else if updatedStatus, err = pc.updateParentStatus(parent, pc.desiredStatus(parent, syncResult.Status, observedChildren, conflicts, pendingAdoptions, customizeErr, record)); err != nil {
+		return fmt.Errorf("can't update status for %v %v/%v: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
+	}
+	common.RecordWrites(ctx, common.WroteObject(parent, updatedStatus))
+
+	// Only remember the token once its sync was fully applied.
+	if manageErr == nil && waitingWave == nil && !waitingReplacement && len(pendingAdoptions) == 0 {
+		pc.syncTokens.Set(parentKey(parent), parent.GetUID(), syncResult.SyncToken)
+	} else {
+		pc.syncTokens.Forget(parentKey(parent))
+	}
+	if pc.childVersions != nil {
+		if manageErr == nil {
+			pc.childVersions.Set(parentKey(parent), parent.GetUID(), common.MakeChildVersions(observedChildren))
+		} else {
+			pc.childVersions.Forget(parentKey(parent))
+		}
+	}
+	if applyErrorResult != nil && applyErrorResult.Handled {
+		pc.logger.Info("Failures to reconcile children were handled by the applyError hook", "object", klog.KObj(parent), "error", manageErr)
+		return nil
+	}
+	return manageErr
+}
+
+// finalizeLabelOwnedChildren removes the children owned through labels from the
+// desired children, so they get deleted, and returns true if any of them still
+// exist.
+func (pc *parentController) finalizeLabelOwnedChildren(parent *unstructured.Unstructured, observedChildren, desiredChildren common.RelativeObjectMap) bool {
+	for _, group := range desiredChildren {
+		for name, obj := range group {
+			if pc.childNamespaces.IsLabelOwned(parent, obj) {
+				delete(group, name)
+			}
+		}
+	}
+	for _, group := range observedChildren {
+		for _, obj := range group {
+			if pc.childNamespaces.IsLabelOwned(parent, obj) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// releaseRetainedChildren removes the ownership of parent from its children
+// of retained kinds, and leaves them out of the children to manage.
+func (pc *parentController) releaseRetainedChildren(parent *unstructured.Unstructured, observedChildren, desiredChildren common.RelativeObjectMap) error {
+	var errs []error
+	for key, group := range observedChildren {
+		if !pc.retainedKinds[claimMapKey(key.Group, key.Kind)] {
+			continue
+		}
+		delete(observedChildren, key)
+		delete(desiredChildren, key)
+		client, err := pc.dynClient.Kind(key.GroupVersion().String(), key.Kind)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, obj := range group {
+			pc.logger.V(4).Info("Releasing retained child", "object", klog.KObj(parent), "child", klog.KObj(obj), "kind", obj.GetKind())
+			_, err := client.Namespace(obj.GetNamespace()).Audited(pc.auditRecorder(parent)).AtomicUpdate(obj, func(obj *unstructured.Unstructured) bool {
+				return removeOwner(obj, parent.GetUID())
+			})
+			if err != nil && !apierrors.IsNotFound(err) {
+				errs = append(errs, fmt.Errorf("can't release %v %v/%v: %w", obj.GetKind(), obj.GetNamespace(), obj.GetName(), err))
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// auditRecorder returns the recorder of the writes made on behalf of parent,
+// besides the operations on its children.
+func (pc *parentController) auditRecorder(parent *unstructured.Unstructured) audit.Recorder {
+	return common.AuditRecorder(pc.cc.Name, common.CompositeController, parent)
+}
+
+// parentKey returns the queue key of parent.
+func parentKey(parent *unstructured.Unstructured) string {
+	key, _ := common.KeyFunc(parent)
+	return key
+}
+
+// isUsingGeneratedLabelSelector returns whether children are selected by the
+// controller-uid label. Built-in parents don't have a spec.selector for their
+// children, so they always use it.
+func (pc *parentController) isUsingGeneratedLabelSelector() bool {
+	return (pc.cc.Spec.GenerateSelector != nil && *pc.cc.Spec.GenerateSelector) ||
+		common.IsBuiltInAPIGroup(pc.parentResource.Group)
+}
+
+// enforceChildLabels adds the labels Metacontroller manages to the desired
+// children of parent, and makes sure they all match the parent's selector.
+func (pc *parentController) enforceChildLabels(parent *unstructured.Unstructured, desiredChildren common.RelativeObjectMap) error {
 	selector, err := pc.makeSelector(parent, nil)
 	if err != nil {
 		return err
@@ -562,7 +1178,7 @@ func (pc *parentController) syncParentObject(parent *unstructured.Unstructured)
 			}
 			// If selector generation is enabled, add the controller-uid label to all
 			// desired children so they match the generated selector.
-			if pc.cc.Spec.GenerateSelector != nil && *pc.cc.Spec.GenerateSelector {
+			if pc.isUsingGeneratedLabelSelector() {
 				if objLabels == nil {
 					objLabels = make(map[string]string, 1)
 				}
@@ -571,6 +1187,12 @@ func (pc *parentController) syncParentObject(parent *unstructured.Unstructured)
 					obj.SetLabels(objLabels)
 				}
 			}
+			// Children in other namespaces, or cluster-scoped ones, point to the
+			// parent with labels, since they can't have an ownerReference to it.
+			if pc.childNamespaces.IsLabelOwned(parent, obj) {
+				common.SetLabelOwner(parent, obj)
+				objLabels = obj.GetLabels()
+			}
 			// Make sure all desired children match the parent's selector.
 			// We consider it user error to try to create children that would be
 			// immediately orphaned.
@@ -579,31 +1201,7 @@ func (pc *parentController) syncParentObject(parent *unstructured.Unstructured)
 			}
 		}
 	}
-
-	// Reconcile child objects belonging to this parent.
-	// Remember manage error, but continue to update status regardless.
-	//
-	// We only manage children if the parent is "alive" (not pending deletion),
-	// or if it's pending deletion and we have a `finalize` hook.
-	var manageErr error
-	if parent.GetDeletionTimestamp() == nil || pc.finalizer.ShouldFinalize(parent) {
-		// Reconcile children.
-		if err := common.ManageChildren(pc.dynClient, pc.updateStrategy, parent, observedChildren, desiredChildren); err != nil {
-			manageErr = fmt.Errorf("can't reconcile children for %v %v/%v: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
-		}
-	}
-
-	// Update parent status.
-	// We'll want to make sure this happens after manageChildren once we support observedGeneration.
-	if _, err := pc.updateParentStatus(parent, syncResult.Status); err != nil {
-		return fmt.Errorf("can't update status for %v %v/%v: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
-	}
-
-	return manageErr
-}
-
-func (pc *parentController) isUsingGeneratedLabelSelector() bool {
-	return pc.cc.Spec.GenerateSelector != nil && *pc.cc.Spec.GenerateSelector
+	return nil
 }
 
 func (pc *parentController) makeSelector(parent *unstructured.Unstructured, extraMatchLabels map[string]string) (labels.Selector, error) {
@@ -636,6 +1234,22 @@ func (pc *parentController) makeSelector(parent *unstructured.Unstructured, extr
 	return selector, nil
 }
 
+// filterAdoptable leaves out the orphans which the adoption policy doesn't
+// allow to adopt, so they're never claimed.
+func filterAdoptable(policy v1alpha1.AdoptionPolicy, objects []*unstructured.Unstructured) []*unstructured.Unstructured {
+	if policy == "" || policy == v1alpha1.AdoptionIfMatchingSelector {
+		return objects
+	}
+	result := make([]*unstructured.Unstructured, 0, len(objects))
+	for _, obj := range objects {
+		if metav1.GetControllerOf(obj) != nil ||
+			(policy != v1alpha1.AdoptionNever && obj.GetAnnotations()[common.AdoptAnnotation] == "true") {
+			result = append(result, obj)
+		}
+	}
+	return result
+}
+
 func (pc *parentController) canAdoptFunc(parent *unstructured.Unstructured) func() error {
 	return k8s.RecheckDeletionTimestamp(func() (metav1.Object, error) {
 		// Make sure this is always an uncached read.
@@ -650,7 +1264,9 @@ func (pc *parentController) canAdoptFunc(parent *unstructured.Unstructured) func
 	})
 }
 
-func (pc *parentController) claimChildren(parent *unstructured.Unstructured) (common.RelativeObjectMap, error) {
+// claimChildren claims the children of parent among the objects in the child
+// informers, or in snapshot if it's not nil.
+func (pc *parentController) claimChildren(parent *unstructured.Unstructured, snapshot childSnapshot) (common.RelativeObjectMap, error) {
 	// Set up values common to all child types.
 	parentNamespace := parent.GetNamespace()
 	parentGVK := pc.parentResource.GroupVersionKind()
@@ -664,38 +1280,69 @@ func (pc *parentController) claimChildren(parent *unstructured.Unstructured) (co
 	childMap := make(common.RelativeObjectMap)
 	for _, child := range pc.cc.Spec.ChildResources {
 		// List all objects of the child kind in the parent object's namespace,
-		// or in all namespaces if the parent is cluster-scoped.
+		// or in all namespaces if the parent is cluster-scoped or the children
+		// may be placed outside of its namespace.
 		childClient, err := pc.dynClient.Resource(child.APIVersion, child.Resource)
 		if err != nil {
 			return nil, err
 		}
 		groupVersion, _ := schema.ParseGroupVersion(child.APIVersion)
-		informer := pc.childInformers.Get(groupVersion.WithResource(child.Resource))
-		if informer == nil {
-			return nil, fmt.Errorf("no informer for resource %q in apiVersion %q", child.Resource, child.APIVersion)
-		}
+		labelOwned := pc.parentResource.Namespaced && pc.childNamespaces.Enabled(groupVersion.Group, childClient.Kind)
 		var all []*unstructured.Unstructured
-		if pc.parentResource.Namespaced {
-			all, err = informer.Lister().Namespace(parentNamespace).List(labels.Everything())
+		if snapshot != nil {
+			all = snapshot[groupVersion.WithResource(child.Resource)]
 		} else {
-			all, err = informer.Lister().List(labels.Everything())
-		}
-		if err != nil {
-			return nil, fmt.Errorf("can't list %v children: %w", childClient.Kind, err)
+			informer := pc.childInformers.Get(groupVersion.WithResource(child.Resource))
+			if informer == nil {
+				return nil, fmt.Errorf("no informer for resource %q in apiVersion %q", child.Resource, child.APIVersion)
+			}
+			if pc.parentResource.Namespaced && !labelOwned {
+				all, err = informer.Lister().Namespace(parentNamespace).List(labels.Everything())
+			} else {
+				all, err = informer.Lister().List(labels.Everything())
+			}
+			if err != nil {
+				return nil, fmt.Errorf("can't list %v children: %w", childClient.Kind, err)
+			}
 		}
 
 		// Always include the requested groups, even if there are no entries.
 		childMap.InitGroup(childClient.GroupVersionKind())
 
+		// Children in other namespaces, or cluster-scoped ones, can't have an
+		// ownerReference to the parent, so they're owned through labels.
+		// They're never adopted.
+		if labelOwned {
+			local := make([]*unstructured.Unstructured, 0, len(all))
+			for _, obj := range all {
+				if obj.GetNamespace() == parentNamespace {
+					local = append(local, obj)
+				} else if common.IsLabelOwnedBy(parent, obj) {
+					childMap.Insert(parent, obj)
+				}
+			}
+			all = local
+		}
+
+		// In ReportOnly and dry-run modes, only the children the parent
+		// already owns are observed, without adopting or releasing any.
+		if pc.reportOnly() || pc.applyStrategies.DryRun() {
+			for _, obj := range all {
+				if controllerRef := metav1.GetControllerOf(obj); controllerRef != nil && controllerRef.UID == parent.GetUID() {
+					childMap.Insert(parent, obj)
+				}
+			}
+			continue
+		}
+
 		// Handle orphan/adopt and filter by owner+selector.
-		crm := dynamiccontrollerref.NewUnstructuredManager(childClient, parent, selector, parentGVK, childClient.GroupVersionKind(), canAdoptFunc)
-		children, err := crm.ClaimChildren(all)
+		crm := dynamiccontrollerref.NewUnstructuredManager(childClient.Audited(pc.auditRecorder(parent)), parent, selector, parentGVK, childClient.GroupVersionKind(), canAdoptFunc)
+		children, err := crm.ClaimChildren(filterAdoptable(pc.cc.Spec.AdoptionPolicy, all))
 		if err != nil {
 			return nil, fmt.Errorf("can't claim %v children: %w", childClient.Kind, err)
 		}
 
 		// Add children to map by name.
-		// Note that we limit each parent to only working within its own namespace.
 		for _, obj := range children {
 			childMap.Insert(parent, obj)
 		}
@@ -703,7 +1350,146 @@ func (pc *parentController) claimChildren(parent *unstructured.Unstructured) (co
 	return childMap, nil
 }
 
+// patchParentStatus applies a status patch returned by the sync hook, after
+// verifying the checksum of the resulting status. Only the patch is sent to the
+// API server, which avoids rewriting very large statuses on every sync.
+func (pc *parentController) patchParentStatus(parent *unstructured.Unstructured, patch map[string]interface{}, checksum string, children common.RelativeObjectMap, conflicts []common.ChildConflict, pendingAdoptions []common.PendingAdoption, customizeErr error, record common.SyncRecord) (*unstructured.Unstructured, error) {
+	if !pc.parentStatus {
+		return parent, nil
+	}
+	status, _, err := unstructured.NestedMap(parent.UnstructuredContent(), "status")
+	if err != nil {
+		return nil, err
+	}
+	merged, err := common.ApplyStatusPatch(status, patch, checksum)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := merged["conditions"]
+	merged = pc.desiredStatus(parent, merged, children, conflicts, pendingAdoptions, customizeErr, record)
+	if !reflect.DeepEqual(conditions, merged["conditions"]) {
+		// Lists are replaced as a whole by merge patches.
+		patch["conditions"] = merged["conditions"]
+	}
+	if !reflect.DeepEqual(status["sync"], merged["sync"]) {
+		patch["sync"] = common.SyncStatusPatch(status, merged)
+	}
+
+	merged["observedGeneration"] = parent.GetGeneration()
+	if reflect.DeepEqual(status, merged) {
+		// Nothing to do.
+		return parent, nil
+	}
+	patch["observedGeneration"] = parent.GetGeneration()
+	return pc.parentClient.Namespace(parent.GetNamespace()).Audited(pc.auditRecorder(parent)).PatchStatus(parent, patch)
+}
+
+// desiredStatus adds the fields managed by Metacontroller to the status
+// computed by hooks: those of the status conventions, if enabled, including
+// the sync block recording the sync, and the ChildConflict, AdoptionPending
+// and RelatedResourcesStale conditions.
+func (pc *parentController) desiredStatus(parent *unstructured.Unstructured, status map[string]interface{}, children common.RelativeObjectMap, conflicts []common.ChildConflict, pendingAdoptions []common.PendingAdoption, customizeErr error, record common.SyncRecord) map[string]interface{} {
+	oldStatus, _, _ := unstructured.NestedMap(parent.UnstructuredContent(), "status")
+	status = pc.conventions.Apply(oldStatus, status, map[string]interface{}{
+		"parent":   common.ObjectVariable(parent),
+		"children": common.RelativeObjectMapVariable(children),
+	})
+	record.Time = time.Now()
+	record.Children = children
+	status = pc.conventions.SetSyncStatus(oldStatus, status, record)
+	status = common.SetPausedCondition(status, "", "")
+	status = common.SetChildLimitExceededCondition(status, nil)
+	status = common.SetChildInvalidCondition(status, nil)
+	status = common.SetChildConflictCondition(status, parent, conflicts)
+	status = common.SetAdoptionPendingCondition(status, pendingAdoptions)
+	return common.SetRelatedResourcesStaleCondition(status, customizeErr)
+}
+
+// syncEvents returns the recorder of the events about the parent synced with
+// ctx, which carry the ID of the sync.
+func (pc *parentController) syncEvents(ctx context.Context) record.EventRecorder {
+	return common.SyncEventRecorder(ctx, pc.eventRecorder)
+}
+
+// recordSyncLoop records a sync of parent which wrote its children or status,
+// and reports the hot loop it makes, if any. Only the first loop in a row gets
+// an event, while the backoff goes on escalating.
+func (pc *parentController) recordSyncLoop(ctx context.Context, key string, parent *unstructured.Unstructured) {
+	loops := pc.loopDetector.Wrote(key)
+	if loops == 0 {
+		return
+	}
+	metrics.RecordSyncLoop(pc.cc.Name, common.CompositeController)
+	if loops > 1 {
+		return
+	}
+	pc.logger.Info("Sync loop detected, backing off", "object", klog.KObj(parent), "syncID", tracing.SyncID(ctx))
+	pc.syncEvents(ctx).Eventf(
+		parent,
+		v1.EventTypeWarning,
+		events.ReasonSyncLoopDetected,
+		"Synced too often, backing off: check that the hooks return the same output for the same input")
+}
+
+// recordSyncError records err in the sync block of the status of parent, if
+// enabled by the status conventions.
+func (pc *parentController) recordSyncError(parent *unstructured.Unstructured, syncErr error) {
+	err := pc.updateStatusCondition(parent, func(status map[string]interface{}) map[string]interface{} {
+		return pc.conventions.SetSyncStatus(status, status, common.SyncRecord{Time: time.Now(), Error: syncErr})
+	})
+	if err != nil {
+		pc.logger.Error(err, "Can't record sync error", "object", klog.KObj(parent))
+	}
+}
+
+// pauseReason returns the reason and message of the Paused condition, if syncs
+// of parent are paused, either by the controller or by the parent itself.
+func (pc *parentController) pauseReason(parent *unstructured.Unstructured) (string, string) {
+	if pc.cc.Spec.Paused != nil && *pc.cc.Spec.Paused {
+		return "ControllerPaused", fmt.Sprintf("Syncs are paused by CompositeController %s", pc.cc.Name)
+	}
+	if parent.GetAnnotations()[common.PausedAnnotation] == "true" {
+		return "ParentPaused", fmt.Sprintf("Syncs are paused by the %s annotation", common.PausedAnnotation)
+	}
+	return "", ""
+}
+
+// updatePausedCondition sets the Paused condition of parent, leaving the rest
+// of its status alone.
+func (pc *parentController) updatePausedCondition(parent *unstructured.Unstructured, reason, message string) error {
+	return pc.updateStatusCondition(parent, func(status map[string]interface{}) map[string]interface{} {
+		return common.SetPausedCondition(status, reason, message)
+	})
+}
+
+// updateStatusCondition sets a condition of parent with setCondition, leaving
+// the rest of its status alone.
+func (pc *parentController) updateStatusCondition(parent *unstructured.Unstructured, setCondition func(status map[string]interface{}) map[string]interface{}) error {
+	if !pc.parentStatus {
+		return nil
+	}
+	status, _, _ := unstructured.NestedMap(parent.UnstructuredContent(), "status")
+	if reflect.DeepEqual(setCondition(runtime.DeepCopyJSON(status)), status) {
+		// Nothing to do.
+		return nil
+	}
+	_, err := pc.parentClient.Namespace(parent.GetNamespace()).Audited(pc.auditRecorder(parent)).AtomicStatusUpdate(parent, func(obj *unstructured.Unstructured) bool {
+		status, _, _ := unstructured.NestedMap(obj.UnstructuredContent(), "status")
+		updated := setCondition(runtime.DeepCopyJSON(status))
+		if reflect.DeepEqual(updated, status) {
+			return false
+		}
+		obj.UnstructuredContent()["status"] = updated
+		return true
+	})
+	return err
+}
+
 func (pc *parentController) updateParentStatus(parent *unstructured.Unstructured, status map[string]interface{}) (*unstructured.Unstructured, error) {
+	if !pc.parentStatus {
+		return parent, nil
+	}
 	// Inject ObservedGeneration before comparing with old status,
 	// so we're comparing against the final form we desire.
 	if status == nil {
@@ -711,9 +1497,19 @@ func (pc *parentController) updateParentStatus(parent *unstructured.Unstructured
 	}
 	status["observedGeneration"] = parent.GetGeneration()
 
+	if pc.cc.Spec.StatusApplyStrategy == v1alpha1.StatusApplyServerSideApply {
+		// Only write the fields we computed, leaving the others alone.
+		fieldManager := common.StatusFieldManager(pc.cc.Spec.FieldManager)
+		if common.IsStatusApplied(parent, status, fieldManager) {
+			// Nothing to do.
+			return parent, nil
+		}
+		return pc.parentClient.Namespace(parent.GetNamespace()).Audited(pc.auditRecorder(parent)).ApplyStatus(parent, status, fieldManager)
+	}
+
 	// Overwrite .status field of parent object without touching other parts.
 	// We can't use Patch() because we need to ensure that the UID matches.
-	return pc.parentClient.Namespace(parent.GetNamespace()).AtomicStatusUpdate(parent, func(obj *unstructured.Unstructured) bool {
+	return pc.parentClient.Namespace(parent.GetNamespace()).Audited(pc.auditRecorder(parent)).AtomicStatusUpdate(parent, func(obj *unstructured.Unstructured) bool {
 		oldStatus := obj.UnstructuredContent()["status"]
 		if reflect.DeepEqual(oldStatus, status) {
 			// Nothing to do.
@@ -724,3 +1520,56 @@ func (pc *parentController) updateParentStatus(parent *unstructured.Unstructured
 		return true
 	})
 }
+
+// matchedParents returns the number of parents matching the selectors of the
+// parent resource rule.
+func (pc *parentController) matchedParents() int32 {
+	parents, err := pc.parentInformer.Lister().List(labels.Everything())
+	if err != nil {
+		pc.logger.Error(err, "Can't list parents", "parent_kind", pc.parentResource.Kind)
+		return 0
+	}
+	var matched int32
+	for _, parent := range parents {
+		if pc.parentSelector.Matches(parent) {
+			matched++
+		}
+	}
+	return matched
+}
+
+// recordChildFailures records an event on parent for each failed operation
+// on its children.
+func (pc *parentController) recordChildFailures(ctx context.Context, parent *unstructured.Unstructured, results []common.ChildResult) {
+	for _, failure := range common.ChildFailures(results) {
+		pc.syncEvents(ctx).Event(parent, v1.EventTypeWarning, events.ReasonChildApplyError, failure.FailureMessage())
+	}
+}
+
+// recordUpdateDiffs records an event on parent with the diff of each child
+// update, if the controller reports them in events.
+func (pc *parentController) recordUpdateDiffs(ctx context.Context, parent *unstructured.Unstructured, results []common.ChildResult) {
+	updateDiff := pc.applyStrategies.UpdateDiff()
+	if updateDiff == nil || updateDiff.Report != v1alpha1.ChildUpdateDiffEvent || pc.applyStrategies.DryRun() {
+		return
+	}
+	for _, result := range results {
+		if result.Diff != nil {
+			pc.syncEvents(ctx).Event(parent, v1.EventTypeNormal, events.ReasonChildUpdateDiff, result.UpdateDiffMessage())
+		}
+	}
+}
+
+// recordDryRunWrites records an event on parent for each write to its
+// children which was only dry-run, and counts them.
+func (pc *parentController) recordDryRunWrites(ctx context.Context, parent *unstructured.Unstructured, results []common.ChildResult) {
+	if !pc.applyStrategies.DryRun() {
+		return
+	}
+	for _, result := range results {
+		if result.Error == "" {
+			pc.syncEvents(ctx).Event(parent, v1.EventTypeNormal, events.ReasonDryRun, result.DryRunMessage())
+		}
+	}
+	metrics.RecordDryRunWrites(pc.cc.Name, common.CompositeController, results)
+}
@@ -21,8 +21,8 @@ import (
 	"crypto/sha1" // #nosec
 	"encoding/hex"
 	"fmt"
-	"metacontroller/pkg/logging"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 
@@ -37,12 +37,18 @@ import (
 	"k8s.io/apimachinery/pkg/util/json"
 
 	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/audit"
+	mcclientv1alpha1 "metacontroller/pkg/client/generated/clientset/internalclientset/typed/metacontroller/v1alpha1"
 	dynamiccontrollerref "metacontroller/pkg/dynamic/controllerref"
 )
 
 const (
 	labelKeyAPIGroup = "metacontroller.k8s.io/apiGroup"
 	labelKeyResource = "metacontroller.k8s.io/resource"
+
+	// defaultRevisionHistoryLimit is the number of ControllerRevisions without
+	// children that are kept per parent, unless the controller sets its own limit.
+	defaultRevisionHistoryLimit = 10
 )
 
 func (pc *parentController) claimRevisions(parent *unstructured.Unstructured) ([]*v1alpha1.ControllerRevision, error) {
@@ -66,7 +72,7 @@ func (pc *parentController) claimRevisions(parent *unstructured.Unstructured) ([
 	}
 
 	// Handle orphan/adopt and filter by owner+selector.
-	client := pc.mcClient.MetacontrollerV1alpha1().ControllerRevisions(parent.GetNamespace())
+	client := pc.revisionClient(parent)
 	crm := dynamiccontrollerref.NewControllerRevisionManager(client, parent, selector, parentGVK, canAdoptFunc)
 	revisions, err := crm.ClaimControllerRevisions(all)
 	if err != nil {
@@ -75,20 +81,28 @@ func (pc *parentController) claimRevisions(parent *unstructured.Unstructured) ([
 	return revisions, nil
 }
 
-func (pc *parentController) syncRevisions(parent *unstructured.Unstructured, observedChildren common.RelativeObjectMap, relatedObjects common.RelativeObjectMap) (*SyncHookResponse, error) {
+func (pc *parentController) syncRevisions(ctx context.Context, parent *unstructured.Unstructured, observedChildren common.RelativeObjectMap, relatedObjects common.RelativeObjectMap) (*SyncHookResponse, error) {
 	// If no child resources use rolling updates, just sync the latest parent.
 	// Also, if the parent object is being deleted and we don't have a finalizer,
 	// just sync the latest parent to get the status since we won't manage
-	// children anyway.
-	if !pc.updateStrategy.anyRolling() ||
+	// children anyway. The same goes for ReportOnly and dry-run modes, which
+	// write no ControllerRevisions.
+	if !pc.updateStrategy.anyRolling() || pc.reportOnly() || pc.applyStrategies.DryRun() ||
 		(parent.GetDeletionTimestamp() != nil && !pc.finalizer.ShouldFinalize(parent)) {
 		syncRequest := &SyncHookRequest{
 			Controller: pc.cc,
 			Parent:     parent,
 			Children:   observedChildren,
 			Related:    relatedObjects,
+			SyncToken:  pc.syncTokens.Get(parentKey(parent), parent.GetUID()),
+		}
+		if pc.childVersions != nil {
+			syncRequest.deltaBase = pc.childVersions.Get(parentKey(parent), parent.GetUID())
+			if syncRequest.deltaBase == nil {
+				syncRequest.deltaBase = common.ChildVersions{}
+			}
 		}
-		syncResult, err := pc.callHook(syncRequest)
+		syncResult, err := pc.callHook(ctx, syncRequest)
 		if err != nil {
 			return nil, fmt.Errorf("sync hook failed for %v %v/%v: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
 		}
@@ -103,13 +117,7 @@ func (pc *parentController) syncRevisions(parent *unstructured.Unstructured, obs
 
 	// Extract the fields from parent that the controller author
 	// said are relevant for revision history.
-	// If nothing was specified, default to all of "spec".
-	var fieldPaths []string
-	if rh := pc.cc.Spec.ParentResource.RevisionHistory; rh != nil && len(rh.FieldPaths) > 0 {
-		fieldPaths = rh.FieldPaths
-	} else {
-		fieldPaths = []string{"spec"}
-	}
+	fieldPaths := pc.revisionFieldPaths()
 	latestPatch, err := makePatch(parent.UnstructuredContent(), fieldPaths)
 	if err != nil {
 		return nil, err
@@ -121,10 +129,18 @@ func (pc *parentController) syncRevisions(parent *unstructured.Unstructured, obs
 	parentRevisions := make([]*parentRevision, 0, len(observedRevisions)+1)
 	parentRevisions = append(parentRevisions, latest)
 
+	// Revisions that no longer own any children are kept only as history,
+	// so the parent can be rolled back to them. They don't take part in the sync.
+	var history []*v1alpha1.ControllerRevision
+	var maxRevision int64
+
 	// Materialize the parent object that each revision represents
 	// by applying its parentPatch to the current parent object.
 	// We make deep copies of the ControllerRevisions since we modify them later.
 	for _, revision := range observedRevisions {
+		if revision.Revision > maxRevision {
+			maxRevision = revision.Revision
+		}
 		patch := make(map[string]interface{})
 		if err := json.Unmarshal(revision.ParentPatch.Raw, &patch); err != nil {
 			return nil, fmt.Errorf("can't unmarshal ControllerRevision parentPatch: %w", err)
@@ -134,6 +150,10 @@ func (pc *parentController) syncRevisions(parent *unstructured.Unstructured, obs
 			latest.revision = revision.DeepCopy()
 			continue
 		}
+		if (&parentRevision{revision: revision}).countChildren() == 0 {
+			history = append(history, revision.DeepCopy())
+			continue
+		}
 		// Also deep copy parent, so we can apply the patch to it.
 		pr := &parentRevision{parent: latest.parent.DeepCopy(), revision: revision.DeepCopy()}
 		if err := applyPatch(pr.parent.UnstructuredContent(), patch, fieldPaths); err != nil {
@@ -150,6 +170,11 @@ func (pc *parentController) syncRevisions(parent *unstructured.Unstructured, obs
 		}
 		latest.revision = revision
 	}
+	// The latest revision always has the highest revision number,
+	// including when the parent returns to (or is rolled back to) an older state.
+	if latest.revision.Revision == 0 || latest.revision.Revision < maxRevision {
+		latest.revision.Revision = maxRevision + 1
+	}
 
 	// Call the sync hook to get each parent revision's idea of the desired children.
 	var wg sync.WaitGroup
@@ -163,7 +188,7 @@ func (pc *parentController) syncRevisions(parent *unstructured.Unstructured, obs
 				Parent:     pr.parent,
 				Children:   observedChildren,
 			}
-			syncResult, err := pc.callHook(syncRequest)
+			syncResult, err := pc.callHook(ctx, syncRequest)
 			if err != nil {
 				pr.syncError = err
 				return
@@ -186,22 +211,24 @@ func (pc *parentController) syncRevisions(parent *unstructured.Unstructured, obs
 		return nil, err
 	}
 
-	// Remove any ControllerRevisions that no longer have any children.
-	// We don't remember previous revisions that we finished migrating away from.
-	// The user is responsible for recovering an old config from source control
-	// if a rollback is necessary.
-	parentRevisions = pruneParentRevisions(parentRevisions)
+	// Stop syncing ControllerRevisions that no longer have any children.
+	// They're remembered as history, up to the revisionHistoryLimit,
+	// so the parent can be rolled back to them later.
+	parentRevisions, retired := pruneParentRevisions(parentRevisions)
+	history = append(history, retired...)
 
 	// Reconcile any changes to ControllerRevision objects.
 	// For now, we require these changes to all commit before we start managing
 	// children.
 	// We don't want to start acting before we persist our desired end state.
-	desiredRevisions := make([]*v1alpha1.ControllerRevision, 0, len(parentRevisions))
+	desiredRevisions := make([]*v1alpha1.ControllerRevision, 0, len(parentRevisions)+len(history))
 	for _, pr := range parentRevisions {
 		if pr.revision != nil {
 			desiredRevisions = append(desiredRevisions, pr.revision)
 		}
 	}
+	// Older history is garbage-collected along with any other undesired revisions.
+	desiredRevisions = append(desiredRevisions, limitRevisionHistory(history, pc.revisionHistoryLimit())...)
 	if err := pc.manageRevisions(parent, observedRevisions, desiredRevisions); err != nil {
 		return nil, fmt.Errorf("%v %v/%v: can't reconcile ControllerRevisions: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
 	}
@@ -228,8 +255,10 @@ func (pc *parentController) syncRevisions(parent *unstructured.Unstructured, obs
 	// Build a single, aggregated syncResult.
 	// We only take parent status from the latest revision.
 	syncResult := &SyncHookResponse{
-		Status:   latest.syncResult.Status,
-		Children: desiredChildren.List(),
+		Status:         latest.syncResult.Status,
+		StatusPatch:    latest.syncResult.StatusPatch,
+		StatusChecksum: latest.syncResult.StatusChecksum,
+		Children:       desiredChildren.List(),
 	}
 
 	// Aggregate `resyncAfterSeconds` from all revisions.
@@ -241,6 +270,11 @@ func (pc *parentController) syncRevisions(parent *unstructured.Unstructured, obs
 		}
 	}
 
+	// The hooks of all revisions add up to the latency of the sync.
+	for _, pr := range parentRevisions {
+		syncResult.latency += pr.syncResult.latency
+	}
+
 	// Aggregate `finalized` from all revisions. We're finalized if all agree.
 	syncResult.Finalized = true
 	for _, pr := range parentRevisions {
@@ -254,7 +288,7 @@ func (pc *parentController) syncRevisions(parent *unstructured.Unstructured, obs
 }
 
 func (pc *parentController) manageRevisions(parent *unstructured.Unstructured, observedRevisions, desiredRevisions []*v1alpha1.ControllerRevision) error {
-	client := pc.mcClient.MetacontrollerV1alpha1().ControllerRevisions(parent.GetNamespace())
+	client := pc.revisionClient(parent)
 
 	// Build maps for convenient lookup by object name.
 	observedMap := make(map[string]*v1alpha1.ControllerRevision, len(observedRevisions))
@@ -271,7 +305,7 @@ func (pc *parentController) manageRevisions(parent *unstructured.Unstructured, o
 			opts := metav1.DeleteOptions{
 				Preconditions: &metav1.Preconditions{UID: &revision.UID},
 			}
-			logging.Logger.Info("Deleting ControllerRevision", "parent_kind", parent.GetKind(), "parent", parent, "name", revision.GetName())
+			pc.logger.Info("Deleting ControllerRevision", "parent_kind", parent.GetKind(), "parent", parent, "name", revision.GetName())
 			if err := client.Delete(context.TODO(), revision.Name, opts); err != nil {
 				return fmt.Errorf("can't delete ControllerRevision %v for %v %v/%v: %w", revision.Name, pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
 			}
@@ -288,16 +322,16 @@ func (pc *parentController) manageRevisions(parent *unstructured.Unstructured, o
 			}
 			if oldObj.GetResourceVersion() != revision.GetResourceVersion() {
 				revision.SetResourceVersion(oldObj.GetResourceVersion())
-				logging.Logger.V(6).Info("ControllerRevision's resource version updated", "old", oldObj.GetObjectMeta().GetResourceVersion(), "new", revision.GetObjectMeta().GetResourceVersion())
+				pc.logger.V(6).Info("ControllerRevision's resource version updated", "old", oldObj.GetObjectMeta().GetResourceVersion(), "new", revision.GetObjectMeta().GetResourceVersion())
 			}
 			updated, err := client.Update(context.TODO(), revision, metav1.UpdateOptions{})
 			if err != nil {
 				return fmt.Errorf("can't update ControllerRevision %v for %v %v/%v: %w", revision.Name, pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
 			}
-			logging.Logger.Info("ControllerRevision updated", "parent_kind", parent.GetKind(), "parent", parent, "name", revision.GetName(), "resource_version", updated.GetResourceVersion())
+			pc.logger.Info("ControllerRevision updated", "parent_kind", parent.GetKind(), "parent", parent, "name", revision.GetName(), "resource_version", updated.GetResourceVersion())
 		} else {
 			// Create
-			logging.Logger.Info("Creating ControllerRevision", "parent_kind", parent.GetKind(), "parent", parent, "name", revision.GetName())
+			pc.logger.Info("Creating ControllerRevision", "parent_kind", parent.GetKind(), "parent", parent, "name", revision.GetName())
 			if _, err := client.Create(context.TODO(), revision, metav1.CreateOptions{}); err != nil {
 				return fmt.Errorf("can't create ControllerRevision %v for %v %v/%v: %w", revision.Name, pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
 			}
@@ -307,6 +341,66 @@ func (pc *parentController) manageRevisions(parent *unstructured.Unstructured, o
 	return nil
 }
 
+// revisionClient returns the client of the ControllerRevisions of parent,
+// which records its writes in the audit log.
+func (pc *parentController) revisionClient(parent *unstructured.Unstructured) mcclientv1alpha1.ControllerRevisionInterface {
+	return &auditedRevisionClient{
+		ControllerRevisionInterface: pc.mcClient.MetacontrollerV1alpha1().ControllerRevisions(parent.GetNamespace()),
+		recorder:                    pc.auditRecorder(parent),
+		namespace:                   parent.GetNamespace(),
+	}
+}
+
+// auditedRevisionClient records the writes made through a
+// ControllerRevisionInterface scoped to namespace.
+type auditedRevisionClient struct {
+	mcclientv1alpha1.ControllerRevisionInterface
+	recorder  audit.Recorder
+	namespace string
+}
+
+func (c *auditedRevisionClient) reference(name string) audit.Reference {
+	return audit.Reference{
+		APIVersion: v1alpha1.SchemeGroupVersion.String(),
+		Kind:       "ControllerRevision",
+		Namespace:  c.namespace,
+		Name:       name,
+	}
+}
+
+func (c *auditedRevisionClient) Create(ctx context.Context, revision *v1alpha1.ControllerRevision, opts metav1.CreateOptions) (*v1alpha1.ControllerRevision, error) {
+	result, err := c.ControllerRevisionInterface.Create(ctx, revision, opts)
+	c.recorder.Record(c.reference(revision.Name), audit.ActionCreate, "", len(opts.DryRun) > 0, err)
+	return result, err
+}
+
+func (c *auditedRevisionClient) Update(ctx context.Context, revision *v1alpha1.ControllerRevision, opts metav1.UpdateOptions) (*v1alpha1.ControllerRevision, error) {
+	result, err := c.ControllerRevisionInterface.Update(ctx, revision, opts)
+	c.recorder.Record(c.reference(revision.Name), audit.ActionUpdate, "", len(opts.DryRun) > 0, err)
+	return result, err
+}
+
+func (c *auditedRevisionClient) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	err := c.ControllerRevisionInterface.Delete(ctx, name, opts)
+	c.recorder.Record(c.reference(name), audit.ActionDelete, "", len(opts.DryRun) > 0, err)
+	return err
+}
+
+// UpdateWithRetries is like the one of ControllerRevisionInterface, which
+// doesn't update through the Update of c. Updates which turn out to be no-ops
+// aren't recorded.
+func (c *auditedRevisionClient) UpdateWithRetries(orig *v1alpha1.ControllerRevision, updateFn func(*v1alpha1.ControllerRevision) bool) (*v1alpha1.ControllerRevision, error) {
+	updated := false
+	result, err := c.ControllerRevisionInterface.UpdateWithRetries(orig, func(revision *v1alpha1.ControllerRevision) bool {
+		updated = updateFn(revision)
+		return updated
+	})
+	if updated {
+		c.recorder.Record(c.reference(orig.Name), audit.ActionUpdate, "", false, err)
+	}
+	return result, err
+}
+
 func (pc *parentController) newControllerRevision(parent *unstructured.Unstructured, patch map[string]interface{}) (*v1alpha1.ControllerRevision, error) {
 	patchData, err := json.Marshal(patch)
 	if err != nil {
@@ -379,6 +473,24 @@ func controllerRevisionHash(parentUID, patchData []byte) string {
 	return hex.EncodeToString(hasher.Sum(nil))
 }
 
+// revisionFieldPaths returns the parent fields that make up a revision.
+// If nothing was specified, default to all of "spec".
+func (pc *parentController) revisionFieldPaths() []string {
+	if rh := pc.cc.Spec.ParentResource.RevisionHistory; rh != nil && len(rh.FieldPaths) > 0 {
+		return rh.FieldPaths
+	}
+	return []string{"spec"}
+}
+
+// revisionHistoryLimit returns how many ControllerRevisions without children
+// are kept per parent.
+func (pc *parentController) revisionHistoryLimit() int {
+	if us := pc.cc.Spec.UpdateStrategy; us != nil && us.RevisionHistoryLimit != nil {
+		return int(*us.RevisionHistoryLimit)
+	}
+	return defaultRevisionHistoryLimit
+}
+
 func makePatch(src map[string]interface{}, fieldPaths []string) (map[string]interface{}, error) {
 	patch := make(map[string]interface{})
 	for _, fieldPath := range fieldPaths {
@@ -485,17 +597,32 @@ func (pr *parentRevision) removeChild(apiGroup, kind, name string) {
 	children.Names = append(children.Names[:pos], children.Names[pos+1:]...)
 }
 
-func pruneParentRevisions(parentRevisions []*parentRevision) []*parentRevision {
+func pruneParentRevisions(parentRevisions []*parentRevision) ([]*parentRevision, []*v1alpha1.ControllerRevision) {
 	result := make([]*parentRevision, 0, len(parentRevisions))
+	var retired []*v1alpha1.ControllerRevision
 	// Always include the first item (the latest revision).
 	result = append(result, parentRevisions[0])
 	// Include the rest only if they have remaining children.
 	for _, pr := range parentRevisions[1:] {
 		if pr.countChildren() > 0 {
 			result = append(result, pr)
+		} else {
+			retired = append(retired, pr.revision)
 		}
 	}
-	return result
+	return result, retired
+}
+
+// limitRevisionHistory returns the newest history revisions, up to limit,
+// ordered by descending revision number.
+func limitRevisionHistory(history []*v1alpha1.ControllerRevision, limit int) []*v1alpha1.ControllerRevision {
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Revision > history[j].Revision
+	})
+	if len(history) > limit {
+		history = history[:limit]
+	}
+	return history
 }
 
 type childClaimMap map[string]map[string]*parentRevision
@@ -0,0 +1,80 @@
+package composite
+
+import (
+	"testing"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func revisionWithChildren(name string, revision int64, children ...string) *v1alpha1.ControllerRevision {
+	cr := &v1alpha1.ControllerRevision{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Revision:   revision,
+	}
+	if len(children) > 0 {
+		cr.Children = []v1alpha1.ControllerRevisionChildren{{Kind: "Pod", Names: children}}
+	}
+	return cr
+}
+
+func TestPruneParentRevisions_retiresRevisionsWithoutChildren(t *testing.T) {
+	parentRevisions := []*parentRevision{
+		{revision: revisionWithChildren("latest", 3)},
+		{revision: revisionWithChildren("active", 2, "pod-1")},
+		{revision: revisionWithChildren("done", 1)},
+	}
+
+	active, retired := pruneParentRevisions(parentRevisions)
+
+	if len(active) != 2 || active[0].revision.Name != "latest" || active[1].revision.Name != "active" {
+		t.Errorf("expected latest and active revisions to remain, got: %v", active)
+	}
+	if len(retired) != 1 || retired[0].Name != "done" {
+		t.Errorf("expected done revision to be retired, got: %v", retired)
+	}
+}
+
+func TestLimitRevisionHistory_keepsNewest(t *testing.T) {
+	history := []*v1alpha1.ControllerRevision{
+		revisionWithChildren("a", 1),
+		revisionWithChildren("c", 4),
+		revisionWithChildren("b", 2),
+	}
+
+	kept := limitRevisionHistory(history, 2)
+
+	var names []string
+	for _, revision := range kept {
+		names = append(names, revision.Name)
+	}
+	if diff := cmp.Diff([]string{"c", "b"}, names); diff != "" {
+		t.Errorf("unexpected history (-want +got):\n%s", diff)
+	}
+}
+
+func TestRevisionHistoryLimit(t *testing.T) {
+	var zero int32
+	tests := []struct {
+		name     string
+		strategy *v1alpha1.CompositeControllerUpdateStrategy
+		expected int
+	}{
+		{name: "default", expected: defaultRevisionHistoryLimit},
+		{name: "no limit set", strategy: &v1alpha1.CompositeControllerUpdateStrategy{}, expected: defaultRevisionHistoryLimit},
+		{name: "zero", strategy: &v1alpha1.CompositeControllerUpdateStrategy{RevisionHistoryLimit: &zero}, expected: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := &parentController{cc: &v1alpha1.CompositeController{
+				Spec: v1alpha1.CompositeControllerSpec{UpdateStrategy: tt.strategy},
+			}}
+
+			if got := pc.revisionHistoryLimit(); got != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package composite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/pointer"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/metrics"
+)
+
+func TestFilterAdoptable(t *testing.T) {
+	owned := &unstructured.Unstructured{}
+	owned.SetName("owned")
+	owned.SetOwnerReferences([]metav1.OwnerReference{{UID: "parent-uid", Controller: pointer.BoolPtr(true)}})
+	orphan := &unstructured.Unstructured{}
+	orphan.SetName("orphan")
+	annotated := &unstructured.Unstructured{}
+	annotated.SetName("annotated")
+	annotated.SetAnnotations(map[string]string{common.AdoptAnnotation: "true"})
+	objects := []*unstructured.Unstructured{owned, orphan, annotated}
+
+	tests := []struct {
+		policy   v1alpha1.AdoptionPolicy
+		expected []string
+	}{
+		{policy: "", expected: []string{"owned", "orphan", "annotated"}},
+		{policy: v1alpha1.AdoptionIfMatchingSelector, expected: []string{"owned", "orphan", "annotated"}},
+		{policy: v1alpha1.AdoptionRequireAnnotation, expected: []string{"owned", "annotated"}},
+		{policy: v1alpha1.AdoptionReview, expected: []string{"owned", "annotated"}},
+		{policy: v1alpha1.AdoptionNever, expected: []string{"owned"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			var names []string
+			for _, obj := range filterAdoptable(tt.policy, objects) {
+				names = append(names, obj.GetName())
+			}
+
+			if diff := cmp.Diff(tt.expected, names); diff != "" {
+				t.Errorf("unexpected adoptable objects (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestChildResyncPeriods(t *testing.T) {
+	pc := &parentController{cc: &v1alpha1.CompositeController{Spec: v1alpha1.CompositeControllerSpec{
+		ChildResources: []v1alpha1.CompositeControllerChildResourceRule{
+			{ResourceRule: v1alpha1.ResourceRule{APIVersion: "v1", Resource: "pods"}, ResyncPeriodSeconds: pointer.Int32Ptr(30)},
+			{ResourceRule: v1alpha1.ResourceRule{APIVersion: "v1", Resource: "persistentvolumes"}, ResyncPeriodSeconds: pointer.Int32Ptr(0)},
+			{ResourceRule: v1alpha1.ResourceRule{APIVersion: "apps/v1", Resource: "deployments"}},
+		},
+	}}}
+
+	expected := map[schema.GroupVersionResource]time.Duration{
+		{Version: "v1", Resource: "pods"}:              30 * time.Second,
+		{Version: "v1", Resource: "persistentvolumes"}: time.Second,
+	}
+	if diff := cmp.Diff(expected, pc.childResyncPeriods()); diff != "" {
+		t.Errorf("unexpected resync periods (-want +got):\n%s", diff)
+	}
+}
+
+func TestPauseReason(t *testing.T) {
+	paused := &unstructured.Unstructured{}
+	paused.SetAnnotations(map[string]string{common.PausedAnnotation: "true"})
+
+	tests := []struct {
+		name     string
+		cc       v1alpha1.CompositeControllerSpec
+		parent   *unstructured.Unstructured
+		expected string
+	}{
+		{name: "not paused", parent: &unstructured.Unstructured{}, expected: ""},
+		{name: "parent paused", parent: paused, expected: "ParentPaused"},
+		{name: "controller paused", cc: v1alpha1.CompositeControllerSpec{Paused: pointer.BoolPtr(true)}, parent: paused, expected: "ControllerPaused"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := &parentController{cc: &v1alpha1.CompositeController{Spec: tt.cc}}
+
+			if reason, _ := pc.pauseReason(tt.parent); reason != tt.expected {
+				t.Errorf("expected reason %q, got %q", tt.expected, reason)
+			}
+		})
+	}
+}
+
+func TestEnqueueParentForChild_coalescesEventsWithinDebounce(t *testing.T) {
+	pc := &parentController{
+		queue:         workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		lagTracker:    metrics.NewLagTracker("test", common.CompositeController),
+		childDebounce: 50 * time.Millisecond,
+	}
+	defer pc.queue.ShutDown()
+	parent := &unstructured.Unstructured{}
+	parent.SetNamespace("default")
+	parent.SetName("parent")
+
+	for i := 0; i < 3; i++ {
+		pc.enqueueParentForChild(parent)
+	}
+	if got := pc.queue.Len(); got != 0 {
+		t.Fatalf("expected no sync before the debounce window is over, got %d queued", got)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if got := pc.queue.Len(); got != 1 {
+		t.Errorf("expected a single sync once the debounce window is over, got %d queued", got)
+	}
+}
+
+func TestRecordChildFailures(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	pc := &parentController{eventRecorder: recorder}
+	parent := &unstructured.Unstructured{}
+	parent.SetName("parent")
+
+	pc.recordChildFailures(context.TODO(), parent, []common.ChildResult{
+		{Kind: "ConfigMap", Namespace: "default", Name: "ok", Action: common.ChildCreate, Result: "Succeeded"},
+		{Kind: "Deployment", Namespace: "default", Name: "web", Action: common.ChildUpdate, Result: "Failed", Error: "forbidden"},
+	})
+
+	want := "Warning ChildApplyError Can't update Deployment default/web: forbidden"
+	select {
+	case got := <-recorder.Events:
+		if got != want {
+			t.Errorf("expected event %q, got %q", want, got)
+		}
+	default:
+		t.Fatalf("expected event %q, got none", want)
+	}
+	if len(recorder.Events) != 0 {
+		t.Errorf("expected no event for the successful operation, got %q", <-recorder.Events)
+	}
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	dynamicobject "metacontroller/pkg/dynamic/object"
+)
+
+const (
+	// deletionPolicyFinalizer holds the deletion of a CompositeController
+	// until its deletionPolicy was applied to the children of its parents.
+	deletionPolicyFinalizer = "metacontroller.k8s.io/deletion-policy"
+	// abandonedByLabel marks children abandoned by a deleted CompositeController.
+	abandonedByLabel = "metacontroller.k8s.io/abandoned-by"
+)
+
+// parentFinalizerName returns the finalizer set on the parents of the
+// CompositeController with the given name.
+func parentFinalizerName(ccName string) string {
+	return "metacontroller.io/compositecontroller-" + ccName
+}
+
+// syncDeletionPolicyFinalizer adds or removes the deletion policy finalizer on
+// cc as necessary. Children are left as they are without it.
+func (mc *Metacontroller) syncDeletionPolicyFinalizer(ctx context.Context, cc *v1alpha1.CompositeController) error {
+	needed := cc.Spec.DeletionPolicy == v1alpha1.ControllerDeletionDeleteChildren ||
+		cc.Spec.DeletionPolicy == v1alpha1.ControllerDeletionAbandon
+	if needed == controllerutil.ContainsFinalizer(cc, deletionPolicyFinalizer) {
+		return nil
+	}
+	if needed {
+		controllerutil.AddFinalizer(cc, deletionPolicyFinalizer)
+	} else {
+		controllerutil.RemoveFinalizer(cc, deletionPolicyFinalizer)
+	}
+	return mc.k8sClient.Update(ctx, cc)
+}
+
+// finalizeCompositeController applies the deletionPolicy of cc, which is being
+// deleted, then removes the deletion policy finalizer. The controller must be
+// stopped first, so it doesn't recreate children.
+func (mc *Metacontroller) finalizeCompositeController(ctx context.Context, cc *v1alpha1.CompositeController) error {
+	if !controllerutil.ContainsFinalizer(cc, deletionPolicyFinalizer) {
+		return nil
+	}
+	mc.logger.Info("Applying deletion policy", "name", cc.Name, "deletionPolicy", cc.Spec.DeletionPolicy)
+	if err := mc.applyDeletionPolicy(ctx, cc); err != nil {
+		return fmt.Errorf("can't apply deletion policy %v of CompositeController %v: %w", cc.Spec.DeletionPolicy, cc.Name, err)
+	}
+	controllerutil.RemoveFinalizer(cc, deletionPolicyFinalizer)
+	return mc.k8sClient.Update(ctx, cc)
+}
+
+// applyDeletionPolicy deletes or abandons the children of all parents of cc.
+// It reads from the API server, since the informers of cc were closed.
+func (mc *Metacontroller) applyDeletionPolicy(ctx context.Context, cc *v1alpha1.CompositeController) error {
+	parentClient, err := mc.dynClient.Resource(cc.Spec.ParentResource.APIVersion, cc.Spec.ParentResource.Resource)
+	if err != nil {
+		return err
+	}
+	parents, err := listParents(ctx, parentClient, cc)
+	if err != nil {
+		return err
+	}
+	parentsByUID := make(map[types.UID]*unstructured.Unstructured, len(parents))
+	for _, parent := range parents {
+		parentsByUID[parent.GetUID()] = parent
+	}
+
+	var errs []error
+	for _, child := range cc.Spec.ChildResources {
+		childClient, err := mc.dynClient.Resource(child.APIVersion, child.Resource)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		children, err := childClient.Namespace("").List(ctx, metav1.ListOptions{})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("can't list %v: %w", childClient.Kind, err))
+			continue
+		}
+		for i := range children.Items {
+			obj := &children.Items[i]
+			parentUID, owned := childOwnerUID(obj)
+			parent := parentsByUID[parentUID]
+			if !owned || parent == nil {
+				continue
+			}
+			client := childClient.Audited(common.AuditRecorder(cc.Name, common.CompositeController, parent))
+			if err := applyChildDeletionPolicy(ctx, client, cc, obj, parentUID); err != nil && !apierrors.IsNotFound(err) {
+				errs = append(errs, fmt.Errorf("can't apply deletion policy to %v %v/%v: %w", obj.GetKind(), obj.GetNamespace(), obj.GetName(), err))
+			}
+		}
+	}
+
+	// The controller won't sync the parents anymore,
+	// so its finalizer would keep them from being deleted.
+	for _, parent := range parents {
+		if !dynamicobject.HasFinalizer(parent, parentFinalizerName(cc.Name)) {
+			continue
+		}
+		client := parentClient.Namespace(parent.GetNamespace()).Audited(common.AuditRecorder(cc.Name, common.CompositeController, parent))
+		if _, err := client.RemoveFinalizer(parent, parentFinalizerName(cc.Name)); err != nil {
+			errs = append(errs, fmt.Errorf("can't remove finalizer for %v %v/%v: %w", parent.GetKind(), parent.GetNamespace(), parent.GetName(), err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// listParents returns the parents of cc: the objects of its parent resource
+// which it watches and which match its selectors, or which still have its
+// finalizer. Other controllers may have parents of the same resource.
+func listParents(ctx context.Context, parentClient *dynamicclientset.ResourceClient, cc *v1alpha1.CompositeController) ([]*unstructured.Unstructured, error) {
+	watchSelectors, err := common.WatchSelectors(cc.Spec.ParentResource.WatchSelector)
+	if err != nil {
+		return nil, fmt.Errorf("can't convert watch selector for parent resource: %w", err)
+	}
+	selector, err := newParentSelector(cc.Spec.ParentResource)
+	if err != nil {
+		return nil, err
+	}
+	list, err := parentClient.Namespace("").List(ctx, metav1.ListOptions{
+		LabelSelector: watchSelectors.LabelSelector,
+		FieldSelector: watchSelectors.FieldSelector,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't list %v: %w", parentClient.Kind, err)
+	}
+	var parents []*unstructured.Unstructured
+	for i := range list.Items {
+		parent := &list.Items[i]
+		if selector.Matches(parent) || dynamicobject.HasFinalizer(parent, parentFinalizerName(cc.Name)) {
+			parents = append(parents, parent)
+		}
+	}
+	return parents, nil
+}
+
+// childOwnerUID returns the UID of the parent which owns obj, either through
+// its controllerRef or through labels.
+func childOwnerUID(obj *unstructured.Unstructured) (types.UID, bool) {
+	if controllerRef := metav1.GetControllerOf(obj); controllerRef != nil {
+		return controllerRef.UID, true
+	}
+	if _, _, uid, found := common.LabelOwnerOf(obj); found {
+		return uid, true
+	}
+	return "", false
+}
+
+func applyChildDeletionPolicy(ctx context.Context, client *dynamicclientset.ResourceClient, cc *v1alpha1.CompositeController, obj *unstructured.Unstructured, parentUID types.UID) error {
+	switch cc.Spec.DeletionPolicy {
+	case v1alpha1.ControllerDeletionDeleteChildren:
+		if obj.GetDeletionTimestamp() != nil {
+			return nil
+		}
+		uid := obj.GetUID()
+		propagation := metav1.DeletePropagationBackground
+		return client.Namespace(obj.GetNamespace()).Delete(ctx, obj.GetName(), metav1.DeleteOptions{
+			Preconditions:     &metav1.Preconditions{UID: &uid},
+			PropagationPolicy: &propagation,
+		})
+	case v1alpha1.ControllerDeletionAbandon:
+		_, err := client.Namespace(obj.GetNamespace()).AtomicUpdate(obj, func(obj *unstructured.Unstructured) bool {
+			removeOwner(obj, parentUID)
+			objLabels := obj.GetLabels()
+			if objLabels == nil {
+				objLabels = make(map[string]string, 1)
+			}
+			objLabels[abandonedByLabel] = cc.Name
+			obj.SetLabels(objLabels)
+			return true
+		})
+		return err
+	}
+	return nil
+}
+
+// removeOwner removes the ownerReference and owner labels of the parent with
+// the given UID from obj, and returns whether there were any.
+func removeOwner(obj *unstructured.Unstructured, parentUID types.UID) bool {
+	changed := false
+	var ownerRefs []metav1.OwnerReference
+	for _, ownerRef := range obj.GetOwnerReferences() {
+		if ownerRef.UID == parentUID {
+			changed = true
+			continue
+		}
+		ownerRefs = append(ownerRefs, ownerRef)
+	}
+	if changed {
+		obj.SetOwnerReferences(ownerRefs)
+	}
+	objLabels := obj.GetLabels()
+	if objLabels[common.OwnerUIDLabel] == string(parentUID) {
+		delete(objLabels, common.OwnerUIDLabel)
+		delete(objLabels, common.OwnerNamespaceLabel)
+		obj.SetLabels(objLabels)
+		annotations := obj.GetAnnotations()
+		delete(annotations, common.OwnerNameAnnotation)
+		obj.SetAnnotations(annotations)
+		changed = true
+	}
+	return changed
+}
@@ -0,0 +1,166 @@
+package composite
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+)
+
+func TestChildOwnerUID(t *testing.T) {
+	parent := &metav1.ObjectMeta{Namespace: "app", Name: "parent", UID: "parent-uid"}
+	byReference := &unstructured.Unstructured{}
+	byReference.SetOwnerReferences([]metav1.OwnerReference{
+		{UID: "other-uid"},
+		{UID: "parent-uid", Controller: pointer.BoolPtr(true)},
+	})
+	byLabels := &unstructured.Unstructured{}
+	common.SetLabelOwner(parent, byLabels)
+
+	tests := []struct {
+		name          string
+		obj           *unstructured.Unstructured
+		expectedOwned bool
+	}{
+		{name: "controllerRef", obj: byReference, expectedOwned: true},
+		{name: "labels", obj: byLabels, expectedOwned: true},
+		{name: "orphan", obj: &unstructured.Unstructured{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uid, owned := childOwnerUID(tt.obj)
+
+			if owned != tt.expectedOwned {
+				t.Fatalf("expected owned to be %v", tt.expectedOwned)
+			}
+			if owned && uid != parent.UID {
+				t.Errorf("expected owner %v, got %v", parent.UID, uid)
+			}
+		})
+	}
+}
+
+func TestRemoveOwner(t *testing.T) {
+	parent := &metav1.ObjectMeta{Namespace: "app", Name: "parent", UID: "parent-uid"}
+	byReference := &unstructured.Unstructured{}
+	byReference.SetOwnerReferences([]metav1.OwnerReference{
+		{UID: "other-uid"},
+		{UID: "parent-uid", Controller: pointer.BoolPtr(true)},
+	})
+	byLabels := &unstructured.Unstructured{}
+	common.SetLabelOwner(parent, byLabels)
+	otherParent := &unstructured.Unstructured{}
+	common.SetLabelOwner(&metav1.ObjectMeta{Namespace: "app", Name: "other", UID: "other-uid"}, otherParent)
+
+	tests := []struct {
+		name            string
+		obj             *unstructured.Unstructured
+		expectedChanged bool
+	}{
+		{name: "controllerRef", obj: byReference, expectedChanged: true},
+		{name: "labels", obj: byLabels, expectedChanged: true},
+		{name: "other parent", obj: otherParent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := removeOwner(tt.obj, parent.UID)
+
+			if changed != tt.expectedChanged {
+				t.Fatalf("expected changed to be %v", tt.expectedChanged)
+			}
+			if uid, owned := childOwnerUID(tt.obj); owned && uid == parent.UID {
+				t.Errorf("expected obj to be released, still owned by %v", uid)
+			}
+		})
+	}
+	if refs := byReference.GetOwnerReferences(); len(refs) != 1 || refs[0].UID != "other-uid" {
+		t.Errorf("expected other ownerReferences to be kept, got %v", refs)
+	}
+}
+
+func TestApplyDeletionPolicy_sharedParentResource(t *testing.T) {
+	newParent := func(name, app string) *unstructured.Unstructured {
+		parent := newSnapshotObject("example.com/v1", "Thing", "default", name)
+		parent.SetUID(types.UID(name + "-uid"))
+		parent.SetLabels(map[string]string{"app": app})
+		return parent
+	}
+	newChild := func(name string, parent *unstructured.Unstructured) *unstructured.Unstructured {
+		child := newSnapshotObject("v1", "ConfigMap", "default", name)
+		child.SetOwnerReferences([]metav1.OwnerReference{*common.MakeControllerRef(parent)})
+		return child
+	}
+	newController := func(name, app string) *v1alpha1.CompositeController {
+		return &v1alpha1.CompositeController{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1alpha1.CompositeControllerSpec{
+				ParentResource: v1alpha1.CompositeControllerParentResourceRule{
+					ResourceRule:  v1alpha1.ResourceRule{APIVersion: "example.com/v1", Resource: "things"},
+					LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}},
+				},
+				ChildResources: []v1alpha1.CompositeControllerChildResourceRule{
+					{ResourceRule: v1alpha1.ResourceRule{APIVersion: "v1", Resource: "configmaps"}},
+				},
+				DeletionPolicy: v1alpha1.ControllerDeletionDeleteChildren,
+			},
+		}
+	}
+	// The parents of both controllers are things, told apart by their labels.
+	parent := newParent("parent", "deleted")
+	otherParent := newParent("other-parent", "other")
+	// A parent which no longer matches, but still has the finalizer of the
+	// deleted controller.
+	unlabeled := newParent("unlabeled", "")
+	unlabeled.SetFinalizers([]string{parentFinalizerName("deleted")})
+	pc, _ := newSnapshotController(t, true,
+		parent, otherParent, unlabeled,
+		newChild("child", parent), newChild("other-child", otherParent), newChild("unlabeled-child", unlabeled))
+	mc := &Metacontroller{dynClient: pc.dynClient}
+	remainingChildren := func() []string {
+		configMaps, err := pc.dynClient.Resource("v1", "configmaps")
+		if err != nil {
+			t.Fatalf("err should be nil, got: %v", err)
+		}
+		children, err := configMaps.Namespace("").List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			t.Fatalf("err should be nil, got: %v", err)
+		}
+		var names []string
+		for _, child := range children.Items {
+			names = append(names, child.GetName())
+		}
+		return names
+	}
+
+	if err := mc.applyDeletionPolicy(context.TODO(), newController("deleted", "deleted")); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if names := remainingChildren(); len(names) != 1 || names[0] != "other-child" {
+		t.Errorf("expected only the child of the other controller to be left, got: %v", names)
+	}
+
+	if err := mc.applyDeletionPolicy(context.TODO(), newController("other", "other")); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if names := remainingChildren(); len(names) != 0 {
+		t.Errorf("expected all children to be deleted, got: %v", names)
+	}
+
+	things, err := pc.dynClient.Resource("example.com/v1", "things")
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	updated, err := things.Namespace("default").Get(context.TODO(), "unlabeled", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if len(updated.GetFinalizers()) != 0 {
+		t.Errorf("expected the finalizer of the deleted controller to be removed, got: %v", updated.GetFinalizers())
+	}
+}
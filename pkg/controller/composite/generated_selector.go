@@ -0,0 +1,84 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"fmt"
+	"reflect"
+	"sync/atomic"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+)
+
+// generatedSelector returns the spec.selector generated for parent, which
+// selects its children by the controller-uid label, like Job does.
+func generatedSelector(parent *unstructured.Unstructured) map[string]interface{} {
+	return map[string]interface{}{
+		"matchLabels": map[string]interface{}{"controller-uid": string(parent.GetUID())},
+	}
+}
+
+// managesSelector returns whether cc maintains the spec.selector of its
+// parents. Built-in parents don't have room for one.
+func managesSelector(cc *v1alpha1.CompositeController) bool {
+	group, _ := common.ParseAPIVersion(cc.Spec.ParentResource.APIVersion)
+	return cc.Spec.GenerateSelector != nil && *cc.Spec.GenerateSelector && !common.IsBuiltInAPIGroup(group)
+}
+
+// needsGeneratedSelector returns whether the spec.selector of parent must be
+// set to the generated one: if it's missing, or if it selects another
+// controller-uid, e.g. because it was copied from another parent.
+// Other selectors were set by users, and are left alone.
+func needsGeneratedSelector(parent *unstructured.Unstructured) bool {
+	selector, found, _ := unstructured.NestedFieldNoCopy(parent.UnstructuredContent(), "spec", "selector")
+	if !found || selector == nil {
+		return true
+	}
+	uid, found, _ := unstructured.NestedString(parent.UnstructuredContent(), "spec", "selector", "matchLabels", "controller-uid")
+	return found && uid != string(parent.GetUID())
+}
+
+// syncGeneratedSelector sets the spec.selector of parent to the generated
+// one if needed, so it shows which children the parent owns.
+func (pc *parentController) syncGeneratedSelector(parent *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	if !managesSelector(pc.cc) || atomic.LoadInt32(&pc.selectorPruned) != 0 ||
+		parent.GetDeletionTimestamp() != nil || !needsGeneratedSelector(parent) {
+		return parent, nil
+	}
+	selector := generatedSelector(parent)
+	updated, err := pc.parentClient.Namespace(parent.GetNamespace()).Audited(pc.auditRecorder(parent)).AtomicUpdate(parent, func(obj *unstructured.Unstructured) bool {
+		if !needsGeneratedSelector(obj) {
+			return false
+		}
+		_ = unstructured.SetNestedMap(obj.UnstructuredContent(), selector, "spec", "selector")
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't set generated selector of %v %v/%v: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
+	}
+	if actual, _, _ := unstructured.NestedMap(updated.UnstructuredContent(), "spec", "selector"); !reflect.DeepEqual(actual, selector) && needsGeneratedSelector(updated) {
+		// The schema of the parent resource pruned the selector, so there's no
+		// point in trying again for other parents.
+		atomic.StoreInt32(&pc.selectorPruned, 1)
+		pc.logger.Info("Parent resource doesn't keep spec.selector, not setting generated selectors", "object", klog.KObj(parent))
+	}
+	return updated, nil
+}
@@ -17,12 +17,16 @@ limitations under the License.
 package composite
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
 
 	"metacontroller/pkg/apis/metacontroller/v1alpha1"
 	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/hooks"
 )
 
 // SyncHookRequest is the object sent as JSON to the sync and finalize hooks.
@@ -32,6 +36,26 @@ type SyncHookRequest struct {
 	Children   common.RelativeObjectMap      `json:"children"`
 	Related    common.RelativeObjectMap      `json:"related"`
 	Finalizing bool                          `json:"finalizing"`
+
+	// SyncToken is the syncToken of the last sync response which was fully
+	// applied, if any.
+	SyncToken string `json:"syncToken,omitempty"`
+
+	// Derived holds the values of the controller's derivedFields.
+	Derived map[string]interface{} `json:"derived,omitempty"`
+
+	// Delta means Children only holds the children which were added or
+	// changed since the last sync which was fully applied, and RemovedChildren
+	// the names of those which are gone.
+	Delta           bool                                 `json:"delta,omitempty"`
+	RemovedChildren map[common.GroupVersionKind][]string `json:"removedChildren,omitempty"`
+	// ChildrenDigest is the digest of all observed children, sent when
+	// deltaSync is enabled.
+	ChildrenDigest string `json:"childrenDigest,omitempty"`
+
+	// deltaBase holds the child versions a delta is computed against. It's
+	// nil unless deltaSync is enabled.
+	deltaBase common.ChildVersions
 }
 
 // SyncHookResponse is the expected format of the JSON response from the sync and finalize hooks.
@@ -39,30 +63,161 @@ type SyncHookResponse struct {
 	Status   map[string]interface{}       `json:"status"`
 	Children []*unstructured.Unstructured `json:"children"`
 
+	// ChildSubresources are the desired contents of subresources of children,
+	// like their scale, merged into them apart from the children themselves.
+	ChildSubresources []common.ChildSubresource `json:"childSubresources"`
+
+	// StatusPatch is a JSON merge patch against the parent's current status.
+	// If set, it's used instead of Status, and StatusChecksum must match the
+	// checksum of the resulting full status.
+	StatusPatch    map[string]interface{} `json:"statusPatch"`
+	StatusChecksum string                 `json:"statusChecksum"`
+
 	ResyncAfterSeconds float64 `json:"resyncAfterSeconds"`
 
+	// SyncToken is an opaque value sent back in the next sync request.
+	SyncToken string `json:"syncToken"`
+	// NotModified means nothing changed since the sync response which returned
+	// the request's SyncToken, so children and status are left untouched.
+	NotModified bool `json:"notModified"`
+	// FullSyncRequired asks for the request to be sent again with all
+	// children, e.g. because the hook lost the state a delta applies to.
+	FullSyncRequired bool `json:"fullSyncRequired"`
+
 	// Finalized is only used by the finalize hook.
 	Finalized bool `json:"finalized"`
+
+	// latency is how long the hooks took to compute the response.
+	latency time.Duration
 }
 
-func (pc *parentController) callHook(request *SyncHookRequest) (*SyncHookResponse, error) {
+func (pc *parentController) callHook(ctx context.Context, request *SyncHookRequest) (*SyncHookResponse, error) {
 	var response SyncHookResponse
+	start := time.Now()
+	defer func() { response.latency = time.Since(start) }()
+	ctx = hooks.WithParent(ctx, request.Parent)
+	derived, err := pc.derivedFields.Evaluate(map[string]interface{}{
+		"parent":   common.ObjectVariable(request.Parent),
+		"children": common.RelativeObjectMapVariable(request.Children),
+	})
+	if err != nil {
+		return nil, err
+	}
+	request.Derived = derived
 	// First check if we should instead call the finalize hook,
 	// which has the same API as the sync hook except that it's
 	// called while the object is pending deletion.
 	if request.Parent.GetDeletionTimestamp() != nil && pc.finalizeHook.IsEnabled() {
 		// Finalize
 		request.Finalizing = true
-		if err := pc.finalizeHook.Execute(request, &response); err != nil {
+		request.SyncToken = ""
+		if err := pc.finalizeHook.Execute(ctx, request, &response); err != nil {
 			return nil, fmt.Errorf("finalize hook failed: %w", err)
 		}
 	} else {
 		// Sync
 		request.Finalizing = false
-		if err := pc.syncHook.Execute(request, &response); err != nil {
+		templated, err := pc.childTemplates.render(request.Parent, derived)
+		if err != nil {
+			return nil, err
+		}
+		if !pc.syncHook.IsEnabled() {
+			// Without a sync hook, the children come only from the templates,
+			// and the parent's status is left as it is.
+			response.Status, _, _ = unstructured.NestedMap(request.Parent.UnstructuredContent(), "status")
+			response.Children = templated
+			return &response, nil
+		}
+		children := request.Children
+		if request.deltaBase != nil {
+			request.ChildrenDigest = common.MakeChildVersions(children).Digest()
+			if len(request.deltaBase) > 0 {
+				request.Children, request.RemovedChildren = common.ChildDelta(children, request.deltaBase)
+				request.Delta = true
+			}
+		}
+		if err := pc.syncHook.Execute(ctx, request, &response); err != nil {
 			return nil, fmt.Errorf("sync hook failed: %w", err)
 		}
+		if response.FullSyncRequired && request.Delta {
+			// Resend the request with all children.
+			request.Children, request.RemovedChildren, request.Delta = children, nil, false
+			response = SyncHookResponse{}
+			if err := pc.syncHook.Execute(ctx, request, &response); err != nil {
+				return nil, fmt.Errorf("sync hook failed: %w", err)
+			}
+		}
+		response.Children = overrideChildren(request.Parent, templated, response.Children)
+	}
+	if response.FullSyncRequired {
+		return nil, fmt.Errorf("invalid hook response: fullSyncRequired requires a delta request")
+	}
+	if response.NotModified && request.SyncToken == "" {
+		return nil, fmt.Errorf("invalid hook response: notModified requires a syncToken in the request")
 	}
 
 	return &response, nil
 }
+
+// EventsHookRequest is the object sent as JSON to the events hook.
+type EventsHookRequest struct {
+	Controller *v1alpha1.CompositeController `json:"controller"`
+	Parent     *unstructured.Unstructured    `json:"parent"`
+	Children   []common.ChildResult          `json:"children"`
+}
+
+// EventsHookResponse is the expected format of the JSON response from the
+// events hook. It has no fields, since the response is ignored.
+type EventsHookResponse struct{}
+
+// callEventsHook reports the results of applying the children of parent.
+// It only logs errors, since children are already applied.
+func (pc *parentController) callEventsHook(ctx context.Context, parent *unstructured.Unstructured, results []common.ChildResult) {
+	if !pc.eventsHook.IsEnabled() || len(results) == 0 {
+		return
+	}
+	request := &EventsHookRequest{
+		Controller: pc.cc,
+		Parent:     parent,
+		Children:   results,
+	}
+	if err := pc.eventsHook.Execute(hooks.WithParent(ctx, parent), request, &EventsHookResponse{}); err != nil {
+		pc.logger.Error(err, "Events hook failed", "parent_kind", pc.parentResource.Kind, "object", klog.KObj(parent))
+	}
+}
+
+// ApplyErrorHookRequest is the object sent as JSON to the applyError hook.
+type ApplyErrorHookRequest struct {
+	Controller *v1alpha1.CompositeController `json:"controller"`
+	Parent     *unstructured.Unstructured    `json:"parent"`
+	Failures   []common.ChildFailure         `json:"failures"`
+}
+
+// ApplyErrorHookResponse is the expected format of the JSON response from the
+// applyError hook.
+type ApplyErrorHookResponse struct {
+	// Status, if set, replaces the status returned by the sync hook.
+	Status map[string]interface{} `json:"status"`
+	// Handled means the failures don't need to be retried with backoff.
+	Handled bool `json:"handled"`
+}
+
+// callApplyErrorHook reports the failed operations on the children of parent.
+// It returns nil if the hook isn't enabled or failed.
+func (pc *parentController) callApplyErrorHook(ctx context.Context, parent *unstructured.Unstructured, results []common.ChildResult) *ApplyErrorHookResponse {
+	failures := common.ChildFailures(results)
+	if !pc.applyErrorHook.IsEnabled() || len(failures) == 0 {
+		return nil
+	}
+	request := &ApplyErrorHookRequest{
+		Controller: pc.cc,
+		Parent:     parent,
+		Failures:   failures,
+	}
+	var response ApplyErrorHookResponse
+	if err := pc.applyErrorHook.Execute(hooks.WithParent(ctx, parent), request, &response); err != nil {
+		pc.logger.Error(err, "ApplyError hook failed", "parent_kind", pc.parentResource.Kind, "object", klog.KObj(parent))
+		return nil
+	}
+	return &response
+}
@@ -18,7 +18,10 @@ package composite
 
 import (
 	"context"
+	"metacontroller/pkg/hooks"
 	"metacontroller/pkg/logging"
+	"metacontroller/pkg/metrics"
+	"time"
 
 	"github.com/go-logr/logr"
 
@@ -43,6 +46,7 @@ import (
 
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/cache"
 )
 
@@ -61,11 +65,14 @@ type Metacontroller struct {
 
 	parentControllers map[string]*parentController
 
-	numWorkers int
-	logger     logr.Logger
+	numWorkers        int
+	syncLoops         common.SyncLoopLimits
+	rbacPreflight     bool
+	hookProbeInterval time.Duration
+	logger            logr.Logger
 }
 
-func NewMetacontroller(controllerContext common.ControllerContext, mcClient mcclientset.Interface, numWorkers int) *Metacontroller {
+func NewMetacontroller(controllerContext common.ControllerContext, mcClient mcclientset.Interface, numWorkers int, rbacPreflight bool, hookProbeInterval time.Duration) *Metacontroller {
 	mc := &Metacontroller{
 		k8sClient:     controllerContext.K8sClient,
 		resources:     controllerContext.Resources,
@@ -80,8 +87,11 @@ func NewMetacontroller(controllerContext common.ControllerContext, mcClient mccl
 
 		parentControllers: make(map[string]*parentController),
 
-		numWorkers: numWorkers,
-		logger:     logging.Logger.WithName("composite"),
+		numWorkers:        numWorkers,
+		syncLoops:         controllerContext.SyncLoops,
+		rbacPreflight:     rbacPreflight,
+		hookProbeInterval: hookProbeInterval,
+		logger:            logging.Logger.WithName("composite"),
 	}
 
 	return mc
@@ -96,15 +106,9 @@ func (mc *Metacontroller) Reconcile(ctx context.Context, request reconcile.Reque
 	if apierrors.IsNotFound(err) {
 		mc.logger.Info("CompositeController has been deleted", "name", compositeControllerName)
 		// Stop and remove the controller if it exists.
-		if pc, ok := mc.parentControllers[compositeControllerName]; ok {
-			pc.Stop()
-			defer pc.eventRecorder.Eventf(
-				pc.cc,
-				v1.EventTypeNormal,
-				events.ReasonStopped,
-				"Stopped controller: %s", pc.cc.Name)
-			delete(mc.parentControllers, compositeControllerName)
-		}
+		mc.stopParentController(compositeControllerName)
+		hooks.ForgetProbes(compositeControllerName, common.CompositeController)
+		metrics.ForgetAppliedGeneration(compositeControllerName, common.CompositeController)
 		return reconcile.Result{}, nil
 	}
 
@@ -116,11 +120,37 @@ func (mc *Metacontroller) Reconcile(ctx context.Context, request reconcile.Reque
 			"[%s] Sync error - %s", cc.Name, err)
 		return reconcile.Result{}, err
 	}
+	if cc.DeletionTimestamp != nil {
+		// Stop the controller before applying its deletionPolicy,
+		// so it doesn't recreate children.
+		mc.stopParentController(compositeControllerName)
+		return reconcile.Result{}, mc.finalizeCompositeController(ctx, &cc)
+	}
+	if err := mc.syncDeletionPolicyFinalizer(ctx, &cc); err != nil {
+		return reconcile.Result{}, err
+	}
+	// The running controller keeps the spec with the settings of its
+	// ControllerGroup, so it's restarted when the group changes.
+	if err := mc.syncManagedWebhook(ctx, &cc); err != nil {
+		mc.eventRecorder.Eventf(
+			&cc, v1.EventTypeWarning,
+			events.ReasonSyncError,
+			"[%s] Sync error - %s", cc.Name, err)
+		return reconcile.Result{}, err
+	}
+	if err := mc.applyControllerGroup(ctx, &cc); err != nil {
+		mc.eventRecorder.Eventf(
+			&cc, v1.EventTypeWarning,
+			events.ReasonSyncError,
+			"[%s] Sync error - %s", cc.Name, err)
+		return reconcile.Result{}, err
+	}
 	parentClient, err := mc.dynClient.Resource(cc.Spec.ParentResource.APIVersion, cc.Spec.ParentResource.Resource)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
-	if found := parentClient.APIResource.HasSubresource("status"); !found {
+	// Built-in parents are synced without writing their status.
+	if found := parentClient.APIResource.HasSubresource("status"); !found && !common.IsBuiltInAPIGroup(parentClient.Group) {
 		mc.eventRecorder.Eventf(
 			&cc,
 			v1.EventTypeWarning,
@@ -135,23 +165,206 @@ func (mc *Metacontroller) Reconcile(ctx context.Context, request reconcile.Reque
 		// returning, as we cannot do anything until 'Status' subresource is added to parent resource
 		return reconcile.Result{}, nil
 	}
+	if _, running := mc.parentControllers[cc.Name]; !running {
+		unmet, err := common.UnmetDependencies(ctx, mc.k8sClient, cc.Spec.DependsOn)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if len(unmet) > 0 {
+			mc.logger.Info("Waiting for dependencies", "name", compositeControllerName, "dependencies", unmet)
+			return reconcile.Result{RequeueAfter: common.DependencyRecheckInterval}, mc.updateConditions(ctx, &cc, unmet)
+		}
+	}
+	if mc.rbacPreflight && mc.needsStart(&cc) {
+		missing, err := common.MissingRBACRules(ctx, mc.k8sClient, common.CompositeControllerRBACRules(&cc))
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if len(missing) > 0 {
+			mc.logger.Info("Missing RBAC permissions", "name", compositeControllerName, "rules", missing)
+			return reconcile.Result{RequeueAfter: common.RBACRecheckInterval}, mc.updateMissingRBAC(ctx, &cc, missing)
+		}
+	}
 	reconcileErr := mc.reconcileCompositeController(&cc)
-	return reconcile.Result{}, reconcileErr
+	if reconcileErr != nil {
+		return reconcile.Result{}, reconcileErr
+	}
+	if err := mc.updateConditions(ctx, &cc, nil); err != nil {
+		return reconcile.Result{}, err
+	}
+	if err := mc.updateSyncStatus(ctx, &cc); err != nil {
+		return reconcile.Result{}, err
+	}
+	if mc.hookProbeInterval <= 0 {
+		// Refresh the status again after the interval.
+		return reconcile.Result{RequeueAfter: common.StatusRefreshInterval}, nil
+	}
+	// Probe the webhooks again after the interval.
+	unreachable := hooks.ProbeWebhooks(ctx, cc.Name, common.CompositeController, ccWebhooks(&cc))
+	return reconcile.Result{RequeueAfter: mc.hookProbeInterval}, mc.updateHooksReachable(ctx, &cc, unreachable)
+}
+
+// stopParentController stops and removes the controller with the given name,
+// if it's running.
+func (mc *Metacontroller) stopParentController(name string) {
+	pc, ok := mc.parentControllers[name]
+	if !ok {
+		return
+	}
+	pc.Stop()
+	pc.eventRecorder.Eventf(
+		pc.cc,
+		v1.EventTypeNormal,
+		events.ReasonStopped,
+		"Stopped controller: %s", pc.cc.Name)
+	delete(mc.parentControllers, name)
+}
+
+func (mc *Metacontroller) updateConditions(ctx context.Context, cc *v1alpha1.CompositeController, unmet []string) error {
+	changed := common.SetDependencyConditions(&cc.Status.Conditions, cc.Generation, unmet)
+	if mc.rbacPreflight && len(unmet) == 0 {
+		changed = common.SetMissingRBACCondition(&cc.Status.Conditions, cc.Generation, nil) || changed
+	}
+	if len(unmet) == 0 && cc.Status.ObservedGeneration != cc.Generation {
+		// The running controller uses the current spec.
+		cc.Status.ObservedGeneration = cc.Generation
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return mc.k8sClient.Status().Update(ctx, cc)
+}
+
+// updateSyncStatus records in the status of cc how many parents it matches,
+// and which of them fail to sync.
+func (mc *Metacontroller) updateSyncStatus(ctx context.Context, cc *v1alpha1.CompositeController) error {
+	running, ok := mc.parentControllers[cc.Name]
+	if !ok {
+		return nil
+	}
+	matched := running.matchedParents()
+	failing, lastErrors := running.health.Failing()
+	if cc.Status.Parents == matched && cc.Status.FailingParents == failing && apiequality.Semantic.DeepEqual(cc.Status.LastErrors, lastErrors) {
+		return nil
+	}
+	cc.Status.Parents = matched
+	cc.Status.FailingParents = failing
+	cc.Status.LastErrors = lastErrors
+	return mc.k8sClient.Status().Update(ctx, cc)
+}
+
+func (mc *Metacontroller) updateMissingRBAC(ctx context.Context, cc *v1alpha1.CompositeController, missing []common.RBACRule) error {
+	if !common.SetMissingRBACCondition(&cc.Status.Conditions, cc.Generation, missing) {
+		return nil
+	}
+	return mc.k8sClient.Status().Update(ctx, cc)
+}
+
+func (mc *Metacontroller) updateHooksReachable(ctx context.Context, cc *v1alpha1.CompositeController, unreachable []string) error {
+	if len(unreachable) > 0 {
+		mc.logger.Info("Unreachable webhooks", "name", cc.Name, "webhooks", unreachable)
+	}
+	if !common.SetHooksReachableCondition(&cc.Status.Conditions, cc.Generation, unreachable) {
+		return nil
+	}
+	return mc.k8sClient.Status().Update(ctx, cc)
+}
+
+// ccWebhooks returns the hooks of cc which can be probed.
+func ccWebhooks(cc *v1alpha1.CompositeController) map[common.HookType]*v1alpha1.Hook {
+	if cc.Spec.Hooks == nil {
+		return nil
+	}
+	return map[common.HookType]*v1alpha1.Hook{
+		common.CustomizeHook:  cc.Spec.Hooks.Customize,
+		common.SyncHook:       cc.Spec.Hooks.Sync,
+		common.FinalizeHook:   cc.Spec.Hooks.Finalize,
+		common.EventsHook:     cc.Spec.Hooks.Events,
+		common.ApplyErrorHook: cc.Spec.Hooks.ApplyError,
+	}
+}
+
+// applyControllerGroup fills in the webhook settings cc takes from its
+// ControllerGroup.
+func (mc *Metacontroller) applyControllerGroup(ctx context.Context, cc *v1alpha1.CompositeController) error {
+	return common.ApplyControllerGroup(ctx, mc.k8sClient, cc.Spec.ControllerGroupRef, ccHookList(cc), &cc.Spec.RateLimit, &cc.Spec.HookTransport)
+}
+
+// syncManagedWebhook applies the managed webhook of cc, records it in the
+// status and points the hooks of cc which only set a path at it.
+func (mc *Metacontroller) syncManagedWebhook(ctx context.Context, cc *v1alpha1.CompositeController) error {
+	if cc.Spec.ManagedWebhook == nil && cc.Status.ManagedWebhook == nil {
+		return nil
+	}
+	ownerRef := *metav1.NewControllerRef(cc, v1alpha1.SchemeGroupVersion.WithKind("CompositeController"))
+	status, err := common.SyncManagedWebhook(ctx, mc.k8sClient, common.CompositeController, cc.Name, ownerRef, cc.Spec.ManagedWebhook, cc.Status.ManagedWebhook)
+	if err != nil {
+		return err
+	}
+	if !apiequality.Semantic.DeepEqual(status, cc.Status.ManagedWebhook) {
+		cc.Status.ManagedWebhook = status
+		if err := mc.k8sClient.Status().Update(ctx, cc); err != nil {
+			return err
+		}
+	}
+	common.ApplyManagedWebhookService(ccHookList(cc), status)
+	return nil
+}
+
+// ccHookList returns all the hooks of cc.
+func ccHookList(cc *v1alpha1.CompositeController) []*v1alpha1.Hook {
+	h := cc.Spec.Hooks
+	if h == nil {
+		return nil
+	}
+	return []*v1alpha1.Hook{h.Customize, h.Sync, h.Finalize, h.Events, h.ApplyError, h.PreUpdateChild, h.PostUpdateChild}
+}
+
+// ControllerGroupRequests returns a request for each CompositeController in
+// the given ControllerGroup, so they're resynced when it changes.
+func (mc *Metacontroller) ControllerGroupRequests(group client.Object) []reconcile.Request {
+	ccList := v1alpha1.CompositeControllerList{}
+	if err := mc.k8sClient.List(context.Background(), &ccList); err != nil {
+		mc.logger.Error(err, "Can't list CompositeControllers", "controllerGroup", group.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, cc := range ccList.Items {
+		if cc.Spec.ControllerGroupRef != nil && cc.Spec.ControllerGroupRef.Name == group.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKey{Name: cc.Name}})
+		}
+	}
+	return requests
+}
+
+// needsStart reports whether cc isn't running yet, or must be restarted
+// because its spec changed.
+func (mc *Metacontroller) needsStart(cc *v1alpha1.CompositeController) bool {
+	running, ok := mc.parentControllers[cc.Name]
+	return !ok || !apiequality.Semantic.DeepEqual(cc.Spec, running.cc.Spec)
 }
 
 func (mc *Metacontroller) reconcileCompositeController(cc *v1alpha1.CompositeController) error {
 	if pc, ok := mc.parentControllers[cc.Name]; ok {
 		// The controller was already started.
 		if apiequality.Semantic.DeepEqual(cc.Spec, pc.cc.Spec) {
-			// Nothing has changed.
+			// Nothing has changed, but the log level may be.
+			common.SyncLogLevel(mc.eventRecorder, cc, pc.logLevel)
+			metrics.SetAppliedGeneration(cc.Name, common.CompositeController, cc.Generation)
 			return nil
 		}
+		mc.logger.Info("Applying CompositeController spec change", "name", cc.Name,
+			"previousGeneration", pc.cc.Generation, "generation", cc.Generation)
 		// Stop and remove the controller so it can be recreated.
 		pc.Stop()
 		mc.eventRecorder.Eventf(cc, v1.EventTypeNormal, events.ReasonStopped, "Stopped controller: %s", cc.Name)
 		delete(mc.parentControllers, cc.Name)
+		metrics.ForgetAppliedGeneration(cc.Name, common.CompositeController)
 	}
 
+	logLevel := logging.NewLevel()
+	common.SyncLogLevel(mc.eventRecorder, cc, logLevel)
 	pc, err := newParentController(
 		mc.resources,
 		mc.dynClient,
@@ -161,7 +374,8 @@ func (mc *Metacontroller) reconcileCompositeController(cc *v1alpha1.CompositeCon
 		mc.revisionLister,
 		cc,
 		mc.numWorkers,
-		mc.logger)
+		mc.syncLoops,
+		logLevel.Logger().WithName("composite"))
 	if err != nil {
 		mc.eventRecorder.Eventf(
 			cc,
@@ -170,8 +384,11 @@ func (mc *Metacontroller) reconcileCompositeController(cc *v1alpha1.CompositeCon
 			"Cannot create new controller: %s", err.Error())
 		return err
 	}
+	pc.logLevel = logLevel
 	pc.Start()
 	mc.eventRecorder.Eventf(cc, v1.EventTypeNormal, events.ReasonStarted, "Started controller: %s", cc.Name)
 	mc.parentControllers[cc.Name] = pc
+	metrics.SetAppliedGeneration(cc.Name, common.CompositeController, cc.Generation)
+	mc.logger.Info("Applied CompositeController spec", "name", cc.Name, "generation", cc.Generation)
 	return nil
 }
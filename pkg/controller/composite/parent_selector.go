@@ -0,0 +1,59 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+)
+
+// parentSelector scopes a controller to the parents matching the label and
+// annotation selectors of its parent resource rule.
+type parentSelector struct {
+	labelSelector      labels.Selector
+	annotationSelector *common.AnnotationSelector
+}
+
+func newParentSelector(rule v1alpha1.CompositeControllerParentResourceRule) (*parentSelector, error) {
+	ps := &parentSelector{
+		labelSelector: labels.Everything(),
+	}
+	var err error
+	if rule.LabelSelector != nil {
+		ps.labelSelector, err = metav1.LabelSelectorAsSelector(rule.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("can't convert label selector for parent resource %q in apiVersion %q: %w", rule.Resource, rule.APIVersion, err)
+		}
+	}
+	ps.annotationSelector, err = common.NewAnnotationSelector(rule.AnnotationSelector)
+	if err != nil {
+		return nil, fmt.Errorf("can't convert annotation selector for parent resource %q in apiVersion %q: %w", rule.Resource, rule.APIVersion, err)
+	}
+	return ps, nil
+}
+
+// Matches returns whether obj must be synced as a parent.
+func (ps *parentSelector) Matches(obj *unstructured.Unstructured) bool {
+	return ps.labelSelector.Matches(labels.Set(obj.GetLabels())) &&
+		ps.annotationSelector.Matches(obj.GetAnnotations())
+}
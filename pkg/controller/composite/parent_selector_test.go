@@ -0,0 +1,56 @@
+package composite
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+func TestParentSelector_Matches(t *testing.T) {
+	selector, err := newParentSelector(v1alpha1.CompositeControllerParentResourceRule{
+		ResourceRule:  v1alpha1.ResourceRule{APIVersion: "v1", Resource: "namespaces"},
+		LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+		AnnotationSelector: &v1alpha1.AnnotationSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "example.com/quota", Operator: metav1.LabelSelectorOpExists}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	newNamespace := func(labels, annotations map[string]string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetLabels(labels)
+		obj.SetAnnotations(annotations)
+		return obj
+	}
+
+	tests := []struct {
+		name     string
+		obj      *unstructured.Unstructured
+		expected bool
+	}{
+		{"both match", newNamespace(map[string]string{"team": "a"}, map[string]string{"example.com/quota": "small"}), true},
+		{"label mismatch", newNamespace(map[string]string{"team": "b"}, map[string]string{"example.com/quota": "small"}), false},
+		{"annotation missing", newNamespace(map[string]string{"team": "a"}, nil), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selector.Matches(tt.obj); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParentSelector_Everything(t *testing.T) {
+	selector, err := newParentSelector(v1alpha1.CompositeControllerParentResourceRule{})
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if !selector.Matches(&unstructured.Unstructured{Object: map[string]interface{}{}}) {
+		t.Error("expected a parent rule without selectors to match every parent")
+	}
+}
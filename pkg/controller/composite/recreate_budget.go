@@ -0,0 +1,142 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"reflect"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+)
+
+// gateRecreate leaves out the children of kinds updated with the Recreate
+// method which can't be recreated yet: those whose index, in the order of
+// their names, is below the partition, and the outdated ones beyond the
+// maxUnavailable budget. Since they're left out of both the observed and
+// desired children, they're neither updated nor deleted. Children are
+// recreated from the last one, like the Pods of a StatefulSet.
+func (pc *parentController) gateRecreate(observed, desired common.RelativeObjectMap) (common.RelativeObjectMap, common.RelativeObjectMap, error) {
+	var gatedObserved, gatedDesired common.RelativeObjectMap
+	for gvk, group := range desired {
+		strategy := pc.updateStrategy.get(gvk.Group, gvk.Kind)
+		if strategy == nil || strategy.Method != v1alpha1.ChildUpdateRecreate ||
+			(strategy.MaxUnavailable == nil && strategy.Partition == nil) {
+			continue
+		}
+		heldBack, err := pc.heldBackRecreates(strategy, observed[gvk], group)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(heldBack) == 0 {
+			continue
+		}
+		if gatedObserved == nil {
+			gatedObserved, gatedDesired = shallowCopy(observed), shallowCopy(desired)
+		}
+		gatedObserved[gvk] = withoutNames(observed[gvk], heldBack)
+		gatedDesired[gvk] = withoutNames(group, heldBack)
+	}
+	if gatedObserved == nil {
+		return observed, desired, nil
+	}
+	return gatedObserved, gatedDesired, nil
+}
+
+// heldBackRecreates returns the names of the observed children of a kind
+// which can't be recreated yet, according to strategy.
+func (pc *parentController) heldBackRecreates(strategy *v1alpha1.CompositeControllerChildUpdateStrategy, observed, desired map[string]*unstructured.Unstructured) ([]string, error) {
+	names := make([]string, 0, len(desired))
+	for name := range desired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	partition := 0
+	if strategy.Partition != nil {
+		partition = int(*strategy.Partition)
+	}
+	var heldBack, outdated []string
+	unavailable := 0
+	for i, name := range names {
+		child := observed[name]
+		if child == nil || child.GetDeletionTimestamp() != nil {
+			unavailable++
+			continue
+		}
+		if i < partition {
+			heldBack = append(heldBack, name)
+			continue
+		}
+		ready, err := pc.childReady(child)
+		if err != nil {
+			return nil, err
+		}
+		if !ready {
+			unavailable++
+		}
+		updated, err := pc.applyStrategies.ApplyUpdate(child, desired[name])
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(updated.UnstructuredContent(), child.UnstructuredContent()) {
+			outdated = append(outdated, name)
+		}
+	}
+	if strategy.MaxUnavailable == nil {
+		return heldBack, nil
+	}
+
+	maxUnavailable, err := intstr.GetScaledValueFromIntOrPercent(strategy.MaxUnavailable, len(names), false)
+	if err != nil {
+		return nil, err
+	}
+	// Always allow some progress, even if the percentage rounds down to 0.
+	if maxUnavailable < 1 {
+		maxUnavailable = 1
+	}
+	budget := maxUnavailable - unavailable
+	if budget < 0 {
+		budget = 0
+	}
+	if budget < len(outdated) {
+		heldBack = append(heldBack, outdated[:len(outdated)-budget]...)
+	}
+	return heldBack, nil
+}
+
+func shallowCopy(m common.RelativeObjectMap) common.RelativeObjectMap {
+	copied := make(common.RelativeObjectMap, len(m))
+	for gvk, group := range m {
+		copied[gvk] = group
+	}
+	return copied
+}
+
+func withoutNames(group map[string]*unstructured.Unstructured, names []string) map[string]*unstructured.Unstructured {
+	filtered := make(map[string]*unstructured.Unstructured, len(group))
+	for name, obj := range group {
+		filtered[name] = obj
+	}
+	for _, name := range names {
+		delete(filtered, name)
+	}
+	return filtered
+}
@@ -0,0 +1,91 @@
+package composite
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	dynamicapply "metacontroller/pkg/dynamic/apply"
+)
+
+func TestGateRecreate(t *testing.T) {
+	parent := &unstructured.Unstructured{}
+	parent.SetNamespace("ns")
+	newPod := func(name, image string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind("Pod")
+		obj.SetNamespace("ns")
+		obj.SetName(name)
+		_ = unstructured.SetNestedField(obj.Object, image, "spec", "image")
+		return obj
+	}
+	observedPod := func(name, image string) *unstructured.Unstructured {
+		obj := newPod(name, image)
+		_ = dynamicapply.SetLastApplied(obj, newPod(name, image).UnstructuredContent())
+		return obj
+	}
+	waves, err := common.NewChildWaves()
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		strategy *v1alpha1.CompositeControllerChildUpdateStrategy
+		observed []*unstructured.Unstructured
+		expected []string
+	}{
+		{
+			name:     "maxUnavailable",
+			strategy: &v1alpha1.CompositeControllerChildUpdateStrategy{Method: v1alpha1.ChildUpdateRecreate, MaxUnavailable: &intstr.IntOrString{Type: intstr.Int, IntVal: 2}},
+			observed: []*unstructured.Unstructured{observedPod("a", "v1"), observedPod("b", "v1"), observedPod("c", "v1"), observedPod("d", "v1")},
+			expected: []string{"c", "d"},
+		},
+		{
+			name:     "maxUnavailable with missing child",
+			strategy: &v1alpha1.CompositeControllerChildUpdateStrategy{Method: v1alpha1.ChildUpdateRecreate, MaxUnavailable: &intstr.IntOrString{Type: intstr.String, StrVal: "50%"}},
+			observed: []*unstructured.Unstructured{observedPod("a", "v1"), observedPod("b", "v1"), observedPod("c", "v1")},
+			expected: []string{"c", "d"},
+		},
+		{
+			name:     "partition",
+			strategy: &v1alpha1.CompositeControllerChildUpdateStrategy{Method: v1alpha1.ChildUpdateRecreate, Partition: pointer.Int32Ptr(3)},
+			observed: []*unstructured.Unstructured{observedPod("a", "v1"), observedPod("b", "v2"), observedPod("c", "v1"), observedPod("d", "v1")},
+			expected: []string{"d"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc := &parentController{
+				updateStrategy:  updateStrategyMap{claimMapKey("", "Pod"): tt.strategy},
+				applyStrategies: common.NewChildApplyStrategies("", ""),
+				childWaves:      waves,
+			}
+			observed := common.MakeRelativeObjectMap(parent, tt.observed)
+			desired := common.MakeRelativeObjectMap(parent, []*unstructured.Unstructured{
+				newPod("a", "v2"), newPod("b", "v2"), newPod("c", "v2"), newPod("d", "v2"),
+			})
+
+			_, managedDesired, err := pc.gateRecreate(observed, desired)
+			if err != nil {
+				t.Fatalf("err should be nil, got: %v", err)
+			}
+
+			var names []string
+			for _, obj := range managedDesired.List() {
+				names = append(names, obj.GetName())
+			}
+			sort.Strings(names)
+			if diff := cmp.Diff(tt.expected, names); diff != "" {
+				t.Errorf("unexpected managed children (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/events"
+	"metacontroller/pkg/metrics"
+)
+
+// reportOnly returns true if the controller is in ReportOnly mode.
+func (pc *parentController) reportOnly() bool {
+	return pc.cc.Spec.Mode == v1alpha1.ControllerModeReportOnly
+}
+
+// reportDrift calls the hooks for parent like a sync does, then reports how
+// its children differ from the desired ones, with events and metrics, without
+// writing anything.
+func (pc *parentController) reportDrift(ctx context.Context, parent *unstructured.Unstructured) error {
+	observedChildren, err := pc.claimChildren(parent, nil)
+	if err != nil {
+		return err
+	}
+	relatedObjects, err := pc.customize.GetRelatedObjects(ctx, parent)
+	if err != nil {
+		return err
+	}
+	syncResult, err := pc.syncRevisions(ctx, parent, observedChildren, relatedObjects)
+	if err != nil {
+		return err
+	}
+	desiredChildren := common.MakeRelativeObjectMap(parent, syncResult.Children)
+	if err := pc.childLimits.Check(desiredChildren); err != nil {
+		pc.syncEvents(ctx).Event(parent, v1.EventTypeWarning, events.ReasonChildLimitExceeded, err.Error())
+		return fmt.Errorf("%v %v/%v: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
+	}
+	if err := pc.childValidator.Check(desiredChildren); err != nil {
+		pc.syncEvents(ctx).Event(parent, v1.EventTypeWarning, events.ReasonChildInvalid, err.Error())
+		return fmt.Errorf("%v %v/%v: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
+	}
+	if err := pc.childNamespaces.Check(parent, desiredChildren); err != nil {
+		return err
+	}
+	conflicts := common.FindConflicts(parent, observedChildren, desiredChildren, common.CachedChildGetter(pc.dynClient, pc.childInformers))
+	for _, conflict := range conflicts {
+		pc.syncEvents(ctx).Event(parent, v1.EventTypeWarning, events.ReasonChildConflict, conflict.Message(parent))
+	}
+	if err := pc.enforceChildLabels(parent, desiredChildren); err != nil {
+		return err
+	}
+
+	if syncResult.ResyncAfterSeconds > 0 {
+		pc.enqueueParentObjectAfter(parent, time.Duration(syncResult.ResyncAfterSeconds*float64(time.Second)))
+	}
+
+	drifts, err := common.DiffChildren(pc.updateStrategy, pc.applyStrategies, parent, observedChildren, desiredChildren)
+	for _, drift := range drifts {
+		pc.logger.Info("Drift detected", "object", klog.KObj(parent), "child", klog.KObj(drift.Object), "kind", drift.Object.GetKind(), "action", drift.Action)
+		pc.syncEvents(ctx).Event(parent, v1.EventTypeNormal, events.ReasonDriftDetected, drift.Message())
+	}
+	metrics.RecordDrift(pc.cc.Name, common.CompositeController, drifts)
+	if err != nil {
+		return fmt.Errorf("can't diff children for %v %v/%v: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/json"
+
+	"metacontroller/pkg/events"
+)
+
+// rollbackToAnnotation on a parent object asks the controller to restore the
+// parent fields recorded in the ControllerRevision with the given revision
+// number. The value "0" means the previous revision.
+const rollbackToAnnotation = "metacontroller.k8s.io/rollback-to"
+
+// syncRollback restores the parent to the revision requested by the rollback
+// annotation, and removes the annotation. It returns true if the parent was
+// updated, in which case the update triggers another sync.
+func (pc *parentController) syncRollback(ctx context.Context, parent *unstructured.Unstructured) (bool, error) {
+	value, requested := parent.GetAnnotations()[rollbackToAnnotation]
+	if !requested || parent.GetDeletionTimestamp() != nil {
+		return false, nil
+	}
+	fieldPaths := pc.revisionFieldPaths()
+	revision, patch, err := pc.findRollbackRevision(parent, value, fieldPaths)
+	if err != nil {
+		// Drop the request rather than retrying it, since it can't succeed.
+		pc.syncEvents(ctx).Eventf(parent, v1.EventTypeWarning, events.ReasonRollbackError, "Can't roll back to revision %q: %s", value, err)
+	}
+
+	var applyErr error
+	_, err = pc.parentClient.Namespace(parent.GetNamespace()).Audited(pc.auditRecorder(parent)).AtomicUpdate(parent, func(obj *unstructured.Unstructured) bool {
+		if patch != nil {
+			if applyErr = applyPatch(obj.UnstructuredContent(), patch, fieldPaths); applyErr != nil {
+				return false
+			}
+		}
+		annotations := obj.GetAnnotations()
+		delete(annotations, rollbackToAnnotation)
+		obj.SetAnnotations(annotations)
+		return true
+	})
+	if applyErr != nil {
+		return false, fmt.Errorf("can't roll back %v %v/%v to revision %v: %w", parent.GetKind(), parent.GetNamespace(), parent.GetName(), revision, applyErr)
+	}
+	if err != nil {
+		return false, fmt.Errorf("can't roll back %v %v/%v: %w", parent.GetKind(), parent.GetNamespace(), parent.GetName(), err)
+	}
+	if patch != nil {
+		pc.syncEvents(ctx).Eventf(parent, v1.EventTypeNormal, events.ReasonRolledBack, "Rolled back to revision %d", revision)
+	}
+	return true, nil
+}
+
+// findRollbackRevision returns the number and parentPatch of the
+// ControllerRevision that the annotation value refers to.
+func (pc *parentController) findRollbackRevision(parent *unstructured.Unstructured, value string, fieldPaths []string) (int64, map[string]interface{}, error) {
+	target, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || target < 0 {
+		return 0, nil, fmt.Errorf("invalid revision number")
+	}
+	if !pc.updateStrategy.anyRolling() {
+		return 0, nil, fmt.Errorf("revisions are only recorded for rolling update strategies")
+	}
+	revisions, err := pc.claimRevisions(parent)
+	if err != nil {
+		return 0, nil, err
+	}
+	currentPatch, err := makePatch(parent.UnstructuredContent(), fieldPaths)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// Look at the newest revisions first, so "0" finds the previous one.
+	sort.SliceStable(revisions, func(i, j int) bool {
+		return revisions[i].Revision > revisions[j].Revision
+	})
+	for _, revision := range revisions {
+		if target != 0 && revision.Revision != target {
+			continue
+		}
+		patch := make(map[string]interface{})
+		if err := json.Unmarshal(revision.ParentPatch.Raw, &patch); err != nil {
+			return 0, nil, fmt.Errorf("can't unmarshal ControllerRevision parentPatch: %w", err)
+		}
+		if target == 0 && reflect.DeepEqual(patch, currentPatch) {
+			// This is the current revision.
+			continue
+		}
+		return revision.Revision, patch, nil
+	}
+	return 0, nil, fmt.Errorf("revision not found")
+}
@@ -27,6 +27,7 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
 	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
 	dynamicobject "metacontroller/pkg/dynamic/object"
 )
@@ -67,7 +68,7 @@ func (pc *parentController) syncRollingUpdate(parentRevisions []*parentRevision,
 				// The child wasn't observed, so we don't know if it'll match latest.
 				continue
 			}
-			updated, err := common.ApplyUpdate(child, desiredChild)
+			updated, err := pc.applyStrategies.ApplyUpdate(child, desiredChild)
 			if err != nil {
 				// We can't prove it'll be a no-op, so don't move it to latest.
 				continue
@@ -180,7 +181,7 @@ func (pc *parentController) shouldContinueRolling(latest *parentRevision, observ
 			// Is this child up-to-date with what the latest revision wants?
 			// Apply the latest update to it and see if anything changes.
 			update := latest.desiredChildMap.FindGroupKindName(groupKind, name)
-			updated, err := common.ApplyUpdate(child, update)
+			updated, err := pc.applyStrategies.ApplyUpdate(child, update)
 			if err != nil {
 				return fmt.Errorf("can't check if child %v %v is updated: %w", ck.Kind, name, err)
 			}
@@ -206,7 +207,7 @@ func (pc *parentController) shouldContinueRolling(latest *parentRevision, observ
 				}
 			}
 			// Check the child status according to the updateStrategy.
-			if err := childStatusCheck(&strategy.StatusChecks, child); err != nil {
+			if err := common.ChildStatusCheck(&strategy.StatusChecks, child); err != nil {
 				// If any child already on the latest revision fails the status check,
 				// pause the rollout.
 				return fmt.Errorf("child %v %v failed status check: %w", ck.Kind, name, err)
@@ -278,29 +279,16 @@ func (pc *parentController) syncRevisionClaims(parentRevisions []*parentRevision
 	return claimed
 }
 
-func childStatusCheck(checks *v1alpha1.ChildUpdateStatusChecks, child *unstructured.Unstructured) error {
-	if checks == nil {
-		// Nothing to check.
-		return nil
-	}
-
-	for _, condCheck := range checks.Conditions {
-		cond, err := dynamicobject.GetStatusCondition(child.UnstructuredContent(), condCheck.Type)
-		if err != nil || cond == nil {
-			return fmt.Errorf("required condition type missing: %q", condCheck.Type)
-		}
-		if condCheck.Status != nil {
-			if cond.Status != *condCheck.Status {
-				return fmt.Errorf("%q condition status is %q (want %q)", condCheck.Type, cond.Status, *condCheck.Status)
-			}
-		}
-		if condCheck.Reason != nil {
-			if cond.Reason != *condCheck.Reason {
-				return fmt.Errorf("%q condition reason is %q (want %q)", condCheck.Type, cond.Reason, *condCheck.Reason)
-			}
+// childReady tells whether a child passes the statusChecks of its update
+// strategy, and its readinessExpression, if any.
+func (pc *parentController) childReady(child *unstructured.Unstructured) (bool, error) {
+	apiGroup, _ := common.ParseAPIVersion(child.GetAPIVersion())
+	if strategy := pc.updateStrategy.get(apiGroup, child.GetKind()); strategy != nil {
+		if err := common.ChildStatusCheck(&strategy.StatusChecks, child); err != nil {
+			return false, nil
 		}
 	}
-	return nil
+	return pc.childWaves.Ready(child)
 }
 
 type updateStrategyMap map[string]*v1alpha1.CompositeControllerChildUpdateStrategy
@@ -360,3 +348,129 @@ func makeUpdateStrategyMap(resources *dynamicdiscovery.ResourceMap, cc *v1alpha1
 	}
 	return m, nil
 }
+
+func makeChildWaves(resources *dynamicdiscovery.ResourceMap, cc *v1alpha1.CompositeController) (*common.ChildWaves, error) {
+	waves, err := common.NewChildWaves()
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range cc.Spec.ChildResources {
+		if child.ApplyWave == 0 && child.ReadinessExpression == "" && child.FinalizeWave == nil {
+			continue
+		}
+		resource := resources.Get(child.APIVersion, child.Resource)
+		if resource == nil {
+			return nil, fmt.Errorf("can't find child resource %q in %v", child.Resource, child.APIVersion)
+		}
+		apiGroup, _ := common.ParseAPIVersion(child.APIVersion)
+		if err := waves.Set(apiGroup, resource.Kind, child.ApplyWave, child.ReadinessExpression); err != nil {
+			return nil, err
+		}
+		if child.FinalizeWave != nil {
+			waves.SetFinalizeWave(apiGroup, resource.Kind, *child.FinalizeWave)
+		}
+	}
+	return waves, nil
+}
+
+func makeRetainedKinds(resources *dynamicdiscovery.ResourceMap, cc *v1alpha1.CompositeController) (map[string]bool, error) {
+	retained := make(map[string]bool)
+	for _, child := range cc.Spec.ChildResources {
+		if child.DeletionPolicy != v1alpha1.ChildDeletionRetain {
+			continue
+		}
+		resource := resources.Get(child.APIVersion, child.Resource)
+		if resource == nil {
+			return nil, fmt.Errorf("can't find child resource %q in %v", child.Resource, child.APIVersion)
+		}
+		apiGroup, _ := common.ParseAPIVersion(child.APIVersion)
+		retained[claimMapKey(apiGroup, resource.Kind)] = true
+	}
+	return retained, nil
+}
+
+func makeChildLimits(resources *dynamicdiscovery.ResourceMap, cc *v1alpha1.CompositeController) (*common.ChildLimits, error) {
+	if cc.Spec.Limits == nil {
+		return nil, nil
+	}
+	limits := common.NewChildLimits(cc.Spec.Limits.MaxChildrenPerParent)
+	for _, limit := range cc.Spec.Limits.MaxChildrenPerKind {
+		resource := resources.Get(limit.APIVersion, limit.Resource)
+		if resource == nil {
+			return nil, fmt.Errorf("can't find resource %q in %v", limit.Resource, limit.APIVersion)
+		}
+		apiGroup, _ := common.ParseAPIVersion(limit.APIVersion)
+		limits.Set(apiGroup, resource.Kind, limit.MaxChildren)
+	}
+	return limits, nil
+}
+
+func makeChildValidator(dynClient *dynamicclientset.Clientset, cc *v1alpha1.CompositeController) *common.ChildValidator {
+	if cc.Spec.ValidateChildren == nil || !*cc.Spec.ValidateChildren {
+		return nil
+	}
+	return common.NewChildValidator(dynClient)
+}
+
+func makeChildNamespaces(resources *dynamicdiscovery.ResourceMap, cc *v1alpha1.CompositeController, namespaceLabels func(name string) (map[string]string, error)) (*common.ChildNamespaces, error) {
+	namespaces := common.NewChildNamespaces(namespaceLabels)
+	parentResource := resources.Get(cc.Spec.ParentResource.APIVersion, cc.Spec.ParentResource.Resource)
+	if parentResource == nil {
+		return nil, fmt.Errorf("can't find parent resource %q in %v", cc.Spec.ParentResource.Resource, cc.Spec.ParentResource.APIVersion)
+	}
+	for _, child := range cc.Spec.ChildResources {
+		clusterScoped := child.ClusterScoped != nil && *child.ClusterScoped
+		resource := resources.Get(child.APIVersion, child.Resource)
+		if resource == nil {
+			return nil, fmt.Errorf("can't find child resource %q in %v", child.Resource, child.APIVersion)
+		}
+		apiGroup, _ := common.ParseAPIVersion(child.APIVersion)
+		if resource.Namespaced {
+			if clusterScoped {
+				return nil, fmt.Errorf("clusterScoped can't be set for namespaced child resource %q in %v", child.Resource, child.APIVersion)
+			}
+			if child.CrossNamespace == nil {
+				continue
+			}
+			if err := namespaces.Set(apiGroup, resource.Kind, child.CrossNamespace); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if child.CrossNamespace != nil {
+			return nil, fmt.Errorf("crossNamespace can't be set for cluster-scoped child resource %q in %v", child.Resource, child.APIVersion)
+		}
+		// Cluster-scoped children of a namespaced parent are opt-in.
+		if parentResource.Namespaced {
+			namespaces.SetClusterScoped(apiGroup, resource.Kind, clusterScoped)
+		}
+	}
+	return namespaces, nil
+}
+
+func makeApplyStrategies(resources *dynamicdiscovery.ResourceMap, cc *v1alpha1.CompositeController) (*common.ChildApplyStrategies, error) {
+	strategies := common.NewChildApplyStrategies(cc.Spec.ApplyStrategy, cc.Spec.FieldManager)
+	strategies.SetAuditController(cc.Name, common.CompositeController)
+	strategies.SetUpdateDiff(cc.Spec.UpdateDiff)
+	strategies.SetDryRun(cc.Spec.DryRun)
+	if err := strategies.SetConcurrency(cc.Spec.ChildConcurrency); err != nil {
+		return nil, err
+	}
+	for _, child := range cc.Spec.ChildResources {
+		if child.ApplyStrategy == "" && child.ConflictPolicy == nil && len(child.IgnorePaths) == 0 {
+			continue
+		}
+		resource := resources.Get(child.APIVersion, child.Resource)
+		if resource == nil {
+			return nil, fmt.Errorf("can't find child resource %q in %v", child.Resource, child.APIVersion)
+		}
+		apiGroup, _ := common.ParseAPIVersion(child.APIVersion)
+		if err := strategies.Set(apiGroup, resource.Kind, child.ApplyStrategy, child.ConflictPolicy); err != nil {
+			return nil, err
+		}
+		if err := strategies.SetIgnorePaths(apiGroup, resource.Kind, child.IgnorePaths); err != nil {
+			return nil, err
+		}
+	}
+	return strategies, nil
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+)
+
+// SelectorValidationPath is the path of the admission webhook which rejects
+// changes to the spec.selector of parents.
+const SelectorValidationPath = "/validate-parent-selector"
+
+// selectorValidator rejects updates of parents of CompositeControllers which
+// change their spec.selector, since that would orphan their children.
+type selectorValidator struct {
+	k8sClient client.Client
+}
+
+// NewSelectorValidator returns the admission handler served at
+// SelectorValidationPath.
+func NewSelectorValidator(k8sClient client.Client) admission.Handler {
+	return &selectorValidator{k8sClient: k8sClient}
+}
+
+func (v *selectorValidator) Handle(ctx context.Context, request admission.Request) admission.Response {
+	if request.Operation != admissionv1.Update || request.SubResource != "" {
+		return admission.Allowed("")
+	}
+	var ccList v1alpha1.CompositeControllerList
+	if err := v.k8sClient.List(ctx, &ccList); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	var oldParent, parent unstructured.Unstructured
+	if err := json.Unmarshal(request.OldObject.Raw, &oldParent.Object); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if err := json.Unmarshal(request.Object.Raw, &parent.Object); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	for i := range ccList.Items {
+		cc := &ccList.Items[i]
+		group, _ := common.ParseAPIVersion(cc.Spec.ParentResource.APIVersion)
+		if group != request.Resource.Group || cc.Spec.ParentResource.Resource != request.Resource.Resource {
+			continue
+		}
+		if err := validateSelectorUpdate(cc, &oldParent, &parent); err != nil {
+			return admission.Denied(err.Error())
+		}
+	}
+	return admission.Allowed("")
+}
+
+// validateSelectorUpdate returns an error if the update of a parent of cc
+// from oldParent to parent changes its spec.selector. A selector may be set
+// if there was none, but generated selectors may only be set by
+// Metacontroller.
+func validateSelectorUpdate(cc *v1alpha1.CompositeController, oldParent, parent *unstructured.Unstructured) error {
+	oldSelector, _, _ := unstructured.NestedFieldNoCopy(oldParent.UnstructuredContent(), "spec", "selector")
+	selector, _, _ := unstructured.NestedFieldNoCopy(parent.UnstructuredContent(), "spec", "selector")
+	if reflect.DeepEqual(oldSelector, selector) {
+		return nil
+	}
+	if managesSelector(cc) {
+		if needsGeneratedSelector(oldParent) && reflect.DeepEqual(selector, generatedSelector(oldParent)) {
+			return nil
+		}
+		return fmt.Errorf("spec.selector is generated by CompositeController %s and can't be changed", cc.Name)
+	}
+	if oldSelector == nil {
+		return nil
+	}
+	return fmt.Errorf("spec.selector can't be changed, since it would orphan the children managed by CompositeController %s", cc.Name)
+}
@@ -0,0 +1,119 @@
+package composite
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+func newSelectorParent(selector map[string]interface{}) *unstructured.Unstructured {
+	parent := &unstructured.Unstructured{Object: map[string]interface{}{"spec": map[string]interface{}{}}}
+	parent.SetAPIVersion("example.com/v1")
+	parent.SetKind("Foo")
+	parent.SetName("foo")
+	parent.SetUID("foo-uid")
+	if selector != nil {
+		_ = unstructured.SetNestedMap(parent.Object, selector, "spec", "selector")
+	}
+	return parent
+}
+
+func TestNeedsGeneratedSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector map[string]interface{}
+		expected bool
+	}{
+		{"missing", nil, true},
+		{"generated", map[string]interface{}{"matchLabels": map[string]interface{}{"controller-uid": "foo-uid"}}, false},
+		{"copied from another parent", map[string]interface{}{"matchLabels": map[string]interface{}{"controller-uid": "bar-uid"}}, true},
+		{"set by users", map[string]interface{}{"matchLabels": map[string]interface{}{"app": "foo"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := needsGeneratedSelector(newSelectorParent(tt.selector)); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidateSelectorUpdate(t *testing.T) {
+	userSelector := map[string]interface{}{"matchLabels": map[string]interface{}{"app": "foo"}}
+	otherSelector := map[string]interface{}{"matchLabels": map[string]interface{}{"app": "bar"}}
+	generated := generatedSelector(newSelectorParent(nil))
+	newCC := func(generateSelector bool) *v1alpha1.CompositeController {
+		cc := &v1alpha1.CompositeController{}
+		cc.Name = "foo-controller"
+		cc.Spec.ParentResource.APIVersion = "example.com/v1"
+		cc.Spec.ParentResource.Resource = "foos"
+		cc.Spec.GenerateSelector = pointer.BoolPtr(generateSelector)
+		return cc
+	}
+
+	tests := []struct {
+		name             string
+		generateSelector bool
+		oldSelector      map[string]interface{}
+		selector         map[string]interface{}
+		allowed          bool
+	}{
+		{"unchanged", false, userSelector, userSelector, true},
+		{"set", false, nil, userSelector, true},
+		{"changed", false, userSelector, otherSelector, false},
+		{"removed", false, userSelector, nil, false},
+		{"generated set by metacontroller", true, nil, generated, true},
+		{"generated set by users", true, nil, userSelector, false},
+		{"generated changed", true, generated, userSelector, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSelectorUpdate(newCC(tt.generateSelector), newSelectorParent(tt.oldSelector), newSelectorParent(tt.selector))
+			if allowed := err == nil; allowed != tt.allowed {
+				t.Errorf("expected allowed to be %v, got error: %v", tt.allowed, err)
+			}
+		})
+	}
+}
+
+func TestSelectorValidator_Handle(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	cc := &v1alpha1.CompositeController{}
+	cc.Name = "foo-controller"
+	cc.Spec.ParentResource.APIVersion = "example.com/v1"
+	cc.Spec.ParentResource.Resource = "foos"
+	validator := NewSelectorValidator(fake.NewClientBuilder().WithScheme(scheme).WithObjects(cc).Build())
+
+	request := func(resource string, oldSelector, selector map[string]interface{}) admission.Request {
+		oldRaw, _ := json.Marshal(newSelectorParent(oldSelector).Object)
+		raw, _ := json.Marshal(newSelectorParent(selector).Object)
+		return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Update,
+			Resource:  metav1.GroupVersionResource{Group: "example.com", Version: "v1", Resource: resource},
+			OldObject: runtime.RawExtension{Raw: oldRaw},
+			Object:    runtime.RawExtension{Raw: raw},
+		}}
+	}
+	userSelector := map[string]interface{}{"matchLabels": map[string]interface{}{"app": "foo"}}
+	otherSelector := map[string]interface{}{"matchLabels": map[string]interface{}{"app": "bar"}}
+
+	if response := validator.Handle(context.Background(), request("foos", userSelector, otherSelector)); response.Allowed {
+		t.Error("expected a selector change of a parent to be denied")
+	}
+	if response := validator.Handle(context.Background(), request("bars", userSelector, otherSelector)); !response.Allowed {
+		t.Errorf("expected objects which aren't parents to be allowed, got: %v", response.Result)
+	}
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package composite
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// childSnapshot holds the objects of each child resource which may be claimed
+// by a parent, read at the same resourceVersion as the parent.
+type childSnapshot map[schema.GroupVersionResource][]*unstructured.Unstructured
+
+// readSnapshot reads parent from the API server, then lists the objects of each
+// child resource at exactly the resourceVersion of that read, so the sync hook
+// gets a causally consistent view of the parent and all its children.
+//
+// If that resourceVersion was compacted before all children were listed, which
+// happens when listing them takes longer than the compaction interval of the
+// API server, the whole snapshot is read again once, at a newer resourceVersion.
+func (pc *parentController) readSnapshot(ctx context.Context, parent *unstructured.Unstructured) (*unstructured.Unstructured, childSnapshot, error) {
+	fresh, snapshot, err := pc.readSnapshotOnce(ctx, parent)
+	if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+		pc.logger.V(4).Info("Snapshot resourceVersion expired, reading it again", "parent_kind", pc.parentResource.Kind, "parent", parent, "error", err.Error())
+		fresh, snapshot, err = pc.readSnapshotOnce(ctx, parent)
+	}
+	return fresh, snapshot, err
+}
+
+func (pc *parentController) readSnapshotOnce(ctx context.Context, parent *unstructured.Unstructured) (*unstructured.Unstructured, childSnapshot, error) {
+	// A list (unlike a get) returns the resourceVersion of the whole
+	// collection, which can be used to pin the reads of the children.
+	parents, err := pc.parentClient.Namespace(parent.GetNamespace()).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("metadata.name", parent.GetName()).String(),
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't read %v %v/%v: %w", pc.parentResource.Kind, parent.GetNamespace(), parent.GetName(), err)
+	}
+	if len(parents.Items) == 0 {
+		return nil, nil, apierrors.NewNotFound(pc.parentResource.GroupVersion().WithResource(pc.parentResource.Name).GroupResource(), parent.GetName())
+	}
+	fresh := &parents.Items[0]
+	resourceVersion := parents.GetResourceVersion()
+
+	// Like the informers, list children in the parent's namespace, or in all
+	// namespaces if the parent is cluster-scoped or the children may be placed
+	// in other namespaces.
+	snapshot := make(childSnapshot)
+	for _, child := range pc.cc.Spec.ChildResources {
+		childClient, err := pc.dynClient.Resource(child.APIVersion, child.Resource)
+		if err != nil {
+			return nil, nil, err
+		}
+		namespace := ""
+		if pc.parentResource.Namespaced && !pc.childNamespaces.Enabled(childClient.Group, childClient.Kind) {
+			namespace = parent.GetNamespace()
+		}
+		list, err := childClient.Namespace(namespace).List(ctx, metav1.ListOptions{
+			ResourceVersion:      resourceVersion,
+			ResourceVersionMatch: metav1.ResourceVersionMatchExact,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("can't list %v children at resourceVersion %v: %w", childClient.Kind, resourceVersion, err)
+		}
+		objects := make([]*unstructured.Unstructured, 0, len(list.Items))
+		for i := range list.Items {
+			objects = append(objects, &list.Items[i])
+		}
+		groupVersion, _ := schema.ParseGroupVersion(child.APIVersion)
+		snapshot[groupVersion.WithResource(child.Resource)] = objects
+	}
+	return fresh, snapshot, nil
+}
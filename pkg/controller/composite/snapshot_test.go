@@ -0,0 +1,214 @@
+package composite
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/dynamic"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
+)
+
+type listCall struct {
+	resource  string
+	namespace string
+	options   metav1.ListOptions
+}
+
+// listRecorder is a dynamic client which records the lists it's asked for,
+// fails the next lists of a resource with the given errors, and gives each
+// list the number of lists made so far as resourceVersion.
+type listRecorder struct {
+	dynamic.Interface
+	lists []listCall
+	errs  map[string][]error
+}
+
+func (r *listRecorder) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	client := r.Interface.Resource(gvr)
+	return &recordedResource{ResourceInterface: client, root: client, recorder: r, resource: gvr.Resource}
+}
+
+type recordedResource struct {
+	dynamic.ResourceInterface
+	root      dynamic.NamespaceableResourceInterface
+	recorder  *listRecorder
+	resource  string
+	namespace string
+}
+
+func (r *recordedResource) Namespace(namespace string) dynamic.ResourceInterface {
+	return &recordedResource{ResourceInterface: r.root.Namespace(namespace), root: r.root, recorder: r.recorder, resource: r.resource, namespace: namespace}
+}
+
+func (r *recordedResource) List(ctx context.Context, options metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	r.recorder.lists = append(r.recorder.lists, listCall{resource: r.resource, namespace: r.namespace, options: options})
+	if errs := r.recorder.errs[r.resource]; len(errs) > 0 {
+		r.recorder.errs[r.resource] = errs[1:]
+		return nil, errs[0]
+	}
+	list, err := r.ResourceInterface.List(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	list.SetResourceVersion(strconv.Itoa(len(r.recorder.lists)))
+	return list, nil
+}
+
+func newSnapshotObject(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+// newSnapshotController returns a parentController of things, with configmaps
+// as children, reading from a listRecorder holding objects.
+func newSnapshotController(t *testing.T, namespaced bool, objects ...runtime.Object) (*parentController, *listRecorder) {
+	discovery := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: []*metav1.APIResourceList{
+		{
+			GroupVersion: "example.com/v1",
+			APIResources: []metav1.APIResource{{Name: "things", Kind: "Thing", Namespaced: namespaced}},
+		},
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap", Namespaced: true}},
+		},
+	}}}
+	resources := dynamicdiscovery.NewResourceMap(discovery)
+	resources.Refresh()
+	recorder := &listRecorder{
+		Interface: fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+			{Group: "example.com", Version: "v1", Resource: "things"}: "ThingList",
+			{Version: "v1", Resource: "configmaps"}:                   "ConfigMapList",
+		}, objects...),
+		errs: make(map[string][]error),
+	}
+	dynClient := dynamicclientset.NewForDynamicClient(resources, recorder)
+	parentClient, err := dynClient.Resource("example.com/v1", "things")
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	pc := &parentController{
+		cc: &v1alpha1.CompositeController{Spec: v1alpha1.CompositeControllerSpec{
+			ChildResources: []v1alpha1.CompositeControllerChildResourceRule{
+				{ResourceRule: v1alpha1.ResourceRule{APIVersion: "v1", Resource: "configmaps"}},
+			},
+		}},
+		dynClient:      dynClient,
+		parentClient:   parentClient,
+		parentResource: parentClient.APIResource,
+		logger:         logr.Discard(),
+	}
+	return pc, recorder
+}
+
+func TestReadSnapshot(t *testing.T) {
+	tests := []struct {
+		name           string
+		namespaced     bool
+		parent         *unstructured.Unstructured
+		childNamespace string
+	}{
+		{
+			name:           "namespaced parent",
+			namespaced:     true,
+			parent:         newSnapshotObject("example.com/v1", "Thing", "default", "my-thing"),
+			childNamespace: "default",
+		},
+		{
+			name:           "cluster-scoped parent",
+			namespaced:     false,
+			parent:         newSnapshotObject("example.com/v1", "Thing", "", "my-thing"),
+			childNamespace: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			child := newSnapshotObject("v1", "ConfigMap", "default", "my-child")
+			pc, recorder := newSnapshotController(t, tt.namespaced, tt.parent, child)
+
+			fresh, snapshot, err := pc.readSnapshot(context.TODO(), tt.parent)
+			if err != nil {
+				t.Fatalf("err should be nil, got: %v", err)
+			}
+			if fresh.GetName() != "my-thing" {
+				t.Errorf("expected the parent to be read, got: %v", fresh.GetName())
+			}
+			children := snapshot[schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}]
+			if len(children) != 1 || children[0].GetName() != "my-child" {
+				t.Errorf("expected the child to be listed, got: %v", children)
+			}
+
+			if len(recorder.lists) != 2 {
+				t.Fatalf("expected 2 lists, got: %+v", recorder.lists)
+			}
+			parentList, childList := recorder.lists[0], recorder.lists[1]
+			if parentList.resource != "things" || parentList.namespace != tt.parent.GetNamespace() || parentList.options.FieldSelector != "metadata.name=my-thing" {
+				t.Errorf("expected the parent to be listed by name, got: %+v", parentList)
+			}
+			if childList.resource != "configmaps" || childList.namespace != tt.childNamespace {
+				t.Errorf("expected the children to be listed in %q, got: %+v", tt.childNamespace, childList)
+			}
+			if childList.options.ResourceVersion != "1" || childList.options.ResourceVersionMatch != metav1.ResourceVersionMatchExact {
+				t.Errorf("expected the children to be listed at exactly the resourceVersion of the parent list, got: %+v", childList.options)
+			}
+		})
+	}
+}
+
+func TestReadSnapshot_parentNotFound(t *testing.T) {
+	pc, _ := newSnapshotController(t, true)
+
+	_, _, err := pc.readSnapshot(context.TODO(), newSnapshotObject("example.com/v1", "Thing", "default", "my-thing"))
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("expected a NotFound error, got: %v", err)
+	}
+}
+
+func TestReadSnapshot_resourceVersionExpired(t *testing.T) {
+	parent := newSnapshotObject("example.com/v1", "Thing", "default", "my-thing")
+	expired := apierrors.NewResourceExpired("too old resource version: 1 (5)")
+
+	t.Run("read again once", func(t *testing.T) {
+		pc, recorder := newSnapshotController(t, true, parent)
+		recorder.errs["configmaps"] = []error{expired}
+
+		if _, _, err := pc.readSnapshot(context.TODO(), parent); err != nil {
+			t.Fatalf("err should be nil, got: %v", err)
+		}
+		if len(recorder.lists) != 4 {
+			t.Fatalf("expected the snapshot to be read twice, got: %+v", recorder.lists)
+		}
+		if retry := recorder.lists[3]; retry.resource != "configmaps" || retry.options.ResourceVersion != "3" {
+			t.Errorf("expected the children to be listed again at the newer resourceVersion, got: %+v", retry)
+		}
+	})
+
+	t.Run("give up after the second time", func(t *testing.T) {
+		pc, recorder := newSnapshotController(t, true, parent)
+		recorder.errs["configmaps"] = []error{expired, expired}
+
+		_, _, err := pc.readSnapshot(context.TODO(), parent)
+		if !apierrors.IsResourceExpired(err) {
+			t.Errorf("expected the expired error, got: %v", err)
+		}
+		if len(recorder.lists) != 4 {
+			t.Errorf("expected the snapshot to be read twice, got: %+v", recorder.lists)
+		}
+	})
+}
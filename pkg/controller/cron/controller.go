@@ -0,0 +1,538 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cron
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/debug"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
+	dynamicinformer "metacontroller/pkg/dynamic/informer"
+	"metacontroller/pkg/events"
+	"metacontroller/pkg/hooks"
+	"metacontroller/pkg/logging"
+	"metacontroller/pkg/metrics"
+	"metacontroller/pkg/tracing"
+)
+
+// cronController calls the sync hook of a CronController on its schedule,
+// once for every matching parent, or once globally if there's no parent
+// resource. In the global case the CronController object itself is the
+// parent of the children.
+type cronController struct {
+	cc       *v1alpha1.CronController
+	schedule *schedule
+
+	// owner is the parent used when there's no parent resource.
+	owner *unstructured.Unstructured
+
+	parentResource *dynamicdiscovery.APIResource
+	parentInformer *dynamicinformer.ResourceInformer
+	parentSelector labels.Selector
+
+	resources *dynamicdiscovery.ResourceMap
+	dynClient *dynamicclientset.Clientset
+
+	stopCh, doneCh chan struct{}
+	queue          workqueue.RateLimitingInterface
+
+	// scheduled holds the pending scheduled time of each queued key. It's
+	// kept until the sync succeeds, so retries report the same time.
+	scheduledMutex sync.Mutex
+	scheduled      map[string]time.Time
+
+	updateStrategy  updateStrategyMap
+	applyStrategies *common.ChildApplyStrategies
+
+	childInformers common.InformerMap
+
+	eventRecorder record.EventRecorder
+	syncHook      hooks.HookExecutor
+
+	logger logr.Logger
+}
+
+func newCronController(resources *dynamicdiscovery.ResourceMap, dynClient *dynamicclientset.Clientset, dynInformers *dynamicinformer.SharedInformerFactory, eventRecorder record.EventRecorder, cc *v1alpha1.CronController, logger logr.Logger) (controller *cronController, newErr error) {
+	if cc.Spec.Hooks == nil || cc.Spec.Hooks.Sync == nil {
+		return nil, fmt.Errorf("no sync hook defined")
+	}
+	schedule, err := parseSchedule(cc.Spec.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	hookRateLimiter, err := hooks.NewRateLimiter(cc.Spec.RateLimit)
+	if err != nil {
+		return nil, err
+	}
+	syncHook, err := hooks.NewHookExecutor(cc.Spec.Hooks.Sync, cc.Name, common.CronController, common.SyncHook, dynClient, cc.Spec.HookTransport, logger)
+	if err != nil {
+		return nil, err
+	}
+	owner, err := makeOwner(cc)
+	if err != nil {
+		return nil, err
+	}
+	updateStrategy, err := makeUpdateStrategyMap(resources, cc)
+	if err != nil {
+		return nil, err
+	}
+	applyStrategies, err := makeApplyStrategies(resources, cc)
+	if err != nil {
+		return nil, err
+	}
+	applyStrategies.SetLogger(logger.WithName(cc.Name))
+
+	c := &cronController{
+		cc:              cc,
+		schedule:        schedule,
+		owner:           owner,
+		parentSelector:  labels.Everything(),
+		resources:       resources,
+		dynClient:       dynClient,
+		queue:           metrics.NewControllerQueue(cc.Name, common.CronController),
+		scheduled:       make(map[string]time.Time),
+		updateStrategy:  updateStrategy,
+		applyStrategies: applyStrategies,
+		childInformers:  make(common.InformerMap),
+		eventRecorder:   eventRecorder,
+		syncHook:        hooks.WithRateLimiter(syncHook, hookRateLimiter),
+		logger:          logger.WithName(cc.Name),
+	}
+
+	defer func() {
+		if newErr != nil {
+			// If newCronController fails, Close() any informers we created
+			// since Stop() will never be called.
+			if c.parentInformer != nil {
+				c.parentInformer.Close()
+			}
+			for _, informer := range c.childInformers {
+				informer.Close()
+			}
+		}
+	}()
+
+	if rule := cc.Spec.ParentResource; rule != nil {
+		c.parentResource = resources.Get(rule.APIVersion, rule.Resource)
+		if c.parentResource == nil {
+			return nil, fmt.Errorf("can't find parent resource %q in %v", rule.Resource, rule.APIVersion)
+		}
+		if rule.LabelSelector != nil {
+			c.parentSelector, err = metav1.LabelSelectorAsSelector(rule.LabelSelector)
+			if err != nil {
+				return nil, fmt.Errorf("can't convert label selector for parent resource %q in apiVersion %q: %w", rule.Resource, rule.APIVersion, err)
+			}
+		}
+		c.parentInformer, err = dynInformers.Resource(rule.APIVersion, rule.Resource)
+		if err != nil {
+			return nil, fmt.Errorf("can't create informer for parent resource: %w", err)
+		}
+	}
+
+	for _, child := range cc.Spec.ChildResources {
+		groupVersion, err := schema.ParseGroupVersion(child.APIVersion)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse child resource groupVersion: %w", err)
+		}
+		gvr := groupVersion.WithResource(child.Resource)
+		if c.childInformers.Get(gvr) != nil {
+			continue
+		}
+		informer, err := dynInformers.Resource(child.APIVersion, child.Resource)
+		if err != nil {
+			return nil, fmt.Errorf("can't create informer for child resource: %w", err)
+		}
+		c.childInformers.Set(gvr, informer)
+	}
+
+	return c, nil
+}
+
+// makeOwner returns cc as the unstructured parent of its global children.
+func makeOwner(cc *v1alpha1.CronController) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(cc)
+	if err != nil {
+		return nil, fmt.Errorf("can't convert CronController %q: %w", cc.Name, err)
+	}
+	owner := &unstructured.Unstructured{Object: content}
+	// Objects read with a typed client don't have their TypeMeta set.
+	owner.SetGroupVersionKind(v1alpha1.SchemeGroupVersion.WithKind("CronController"))
+	return owner, nil
+}
+
+func (c *cronController) Start() {
+	c.stopCh = make(chan struct{})
+	c.doneCh = make(chan struct{})
+	debug.Controllers.Register(c.cc.Name, common.CronController, c.watched)
+
+	go func() {
+		defer close(c.doneCh)
+		defer utilruntime.HandleCrash()
+
+		c.logger.Info("Starting CronController", "controller", c.cc.Name)
+		c.eventRecorder.Eventf(c.cc, v1.EventTypeNormal, events.ReasonStarting, "Starting controller: %s", c.cc.Name)
+		defer c.logger.Info("Shutting down CronController", "controller", c.cc.Name)
+		defer c.eventRecorder.Eventf(c.cc, v1.EventTypeNormal, events.ReasonStopping, "Stopping controller: %s", c.cc.Name)
+
+		// Wait for all informers.
+		c.logger.Info("Waiting for CronController caches to sync", "controller", c.cc.Name)
+		syncFuncs := make([]cache.InformerSynced, 0, 1+len(c.childInformers))
+		if c.parentInformer != nil {
+			syncFuncs = append(syncFuncs, c.parentInformer.Informer().HasSynced)
+		}
+		for _, informer := range c.childInformers {
+			syncFuncs = append(syncFuncs, informer.Informer().HasSynced)
+		}
+		if !cache.WaitForNamedCacheSync(c.cc.Name, c.stopCh, syncFuncs...) {
+			// We wait forever unless Stop() is called, so this isn't an error.
+			c.logger.Info("CronController cache sync never finished", "controller", c.cc.Name)
+			return
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wait.Until(c.worker, time.Second, c.stopCh)
+		}()
+		c.runSchedule()
+		wg.Wait()
+	}()
+}
+
+// watched returns the state of the watches of the controller.
+func (c *cronController) watched() []dynamicinformer.WatchState {
+	return common.WatchStates(append(c.childInformers.Informers(), c.parentInformer)...)
+}
+
+func (c *cronController) Stop() {
+	close(c.stopCh)
+	debug.Controllers.Unregister(c.cc.Name, common.CronController)
+	c.queue.ShutDown()
+	<-c.doneCh
+
+	if c.parentInformer != nil {
+		c.parentInformer.Close()
+	}
+	for _, informer := range c.childInformers {
+		informer.Close()
+	}
+}
+
+// runSchedule enqueues the parents at every scheduled time until Stop() is
+// called. Times missed while the controller wasn't running aren't caught up.
+func (c *cronController) runSchedule() {
+	for {
+		next := c.schedule.next(time.Now())
+		if next.IsZero() {
+			c.logger.Info("Schedule never fires", "controller", c.cc.Name, "schedule", c.cc.Spec.Schedule)
+			<-c.stopCh
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-c.stopCh:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if err := c.fire(next); err != nil {
+			utilruntime.HandleError(fmt.Errorf("can't enqueue parents of %v for %v: %w", c.cc.Name, next, err))
+		}
+	}
+}
+
+// fire enqueues every matching parent for the given scheduled time.
+func (c *cronController) fire(scheduledTime time.Time) error {
+	if c.cc.Spec.Suspend != nil && *c.cc.Spec.Suspend {
+		c.logger.V(4).Info("Skipping suspended CronController", "controller", c.cc.Name, "scheduledTime", scheduledTime)
+		return nil
+	}
+	if c.parentInformer == nil {
+		c.enqueue(c.cc.Name, scheduledTime)
+		return nil
+	}
+	parents, err := c.parentInformer.Lister().List(c.parentSelector)
+	if err != nil {
+		return err
+	}
+	for _, parent := range parents {
+		key, err := cache.MetaNamespaceKeyFunc(parent)
+		if err != nil {
+			return err
+		}
+		c.enqueue(key, scheduledTime)
+	}
+	return nil
+}
+
+func (c *cronController) enqueue(key string, scheduledTime time.Time) {
+	c.scheduledMutex.Lock()
+	c.scheduled[key] = scheduledTime
+	c.scheduledMutex.Unlock()
+	c.queue.Add(key)
+}
+
+func (c *cronController) scheduledTime(key string) (time.Time, bool) {
+	c.scheduledMutex.Lock()
+	defer c.scheduledMutex.Unlock()
+	scheduledTime, ok := c.scheduled[key]
+	return scheduledTime, ok
+}
+
+// done forgets the scheduled time of key, unless a later time was scheduled
+// in the meantime.
+func (c *cronController) done(key string, scheduledTime time.Time) {
+	c.scheduledMutex.Lock()
+	defer c.scheduledMutex.Unlock()
+	if c.scheduled[key].Equal(scheduledTime) {
+		delete(c.scheduled, key)
+	}
+}
+
+func (c *cronController) worker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *cronController) processNextWorkItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	scheduledTime, ok := c.scheduledTime(key.(string))
+	if !ok {
+		c.queue.Forget(key)
+		return true
+	}
+	if err := c.sync(key.(string), scheduledTime); err != nil {
+		utilruntime.HandleError(fmt.Errorf("failed to sync %v %q: %w", c.cc.Name, key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+
+	c.queue.Forget(key)
+	c.done(key.(string), scheduledTime)
+	return true
+}
+
+func (c *cronController) sync(key string, scheduledTime time.Time) error {
+	ctx, span := tracing.Start(logging.NewContext(context.Background(), c.logger), "sync",
+		"controller.type", common.CronController.String(),
+		"controller.name", c.cc.Name,
+		"key", key)
+	defer span.End()
+
+	parent := c.owner
+	if c.parentInformer != nil {
+		namespace, name, err := cache.SplitMetaNamespaceKey(key)
+		if err != nil {
+			return err
+		}
+		parent, err = common.GetObject(c.parentInformer, namespace, name)
+		if apierrors.IsNotFound(err) {
+			// Swallow the error since there's no point retrying if the parent is gone.
+			c.logger.V(4).Info("Parent has been deleted", "parent", key)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if parent.GetDeletionTimestamp() != nil {
+			c.logger.V(4).Info("Skipping deleting parent", "parent", key)
+			return nil
+		}
+	}
+
+	err := c.syncParent(ctx, parent, scheduledTime)
+	span.RecordError(err)
+	if err != nil {
+		common.SyncEventRecorder(ctx, c.eventRecorder).Eventf(
+			parent,
+			v1.EventTypeWarning,
+			hooks.SyncErrorReason(err),
+			"Sync error: %s", err.Error())
+	}
+	return err
+}
+
+func (c *cronController) syncParent(ctx context.Context, parent *unstructured.Unstructured, scheduledTime time.Time) error {
+	c.logger.V(4).Info("CronController sync", "controller", c.cc.Name, "parent", parent.GetName(), "scheduledTime", scheduledTime, "syncID", tracing.SyncID(ctx))
+
+	observedChildren, err := c.getChildren(parent)
+	if err != nil {
+		return err
+	}
+	syncRequest := &SyncHookRequest{
+		Controller:    c.cc,
+		Children:      observedChildren,
+		ScheduledTime: metav1.NewTime(scheduledTime),
+	}
+	if c.parentInformer != nil {
+		syncRequest.Parent = parent
+	}
+	syncResult, err := c.callHook(ctx, parent, syncRequest)
+	if err != nil {
+		return err
+	}
+	desiredChildren := common.MakeRelativeObjectMap(parent, syncResult.Children)
+
+	_, span := tracing.Start(ctx, "manage children")
+	defer span.End()
+	results, err := common.ManageChildren(ctx, c.dynClient, c.updateStrategy, c.applyStrategies, nil, parent, observedChildren, desiredChildren)
+	common.AuditChildren(c.cc.Name, common.CronController, parent, results)
+	c.recordDryRunWrites(ctx, parent, results)
+	if err != nil {
+		err = fmt.Errorf("can't reconcile children for %v %v/%v: %w", parent.GetKind(), parent.GetNamespace(), parent.GetName(), err)
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
+
+// getChildren returns the children controlled by parent, in its namespace if
+// it's namespaced, or else in all namespaces.
+func (c *cronController) getChildren(parent *unstructured.Unstructured) (common.RelativeObjectMap, error) {
+	childMap := make(common.RelativeObjectMap)
+	for _, child := range c.cc.Spec.ChildResources {
+		groupVersion, _ := schema.ParseGroupVersion(child.APIVersion)
+		informer := c.childInformers.Get(groupVersion.WithResource(child.Resource))
+		if informer == nil {
+			return nil, fmt.Errorf("no informer for resource %q in apiVersion %q", child.Resource, child.APIVersion)
+		}
+		resource := c.resources.Get(child.APIVersion, child.Resource)
+		if resource == nil {
+			return nil, fmt.Errorf("can't find resource %q in apiVersion %q", child.Resource, child.APIVersion)
+		}
+		var all []*unstructured.Unstructured
+		var err error
+		if parent.GetNamespace() != "" {
+			all, err = informer.Lister().Namespace(parent.GetNamespace()).List(labels.Everything())
+		} else {
+			all, err = informer.Lister().List(labels.Everything())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can't list children for resource %q in apiVersion %q: %w", child.Resource, child.APIVersion, err)
+		}
+		// Always include the requested groups, even if there are no entries.
+		childMap.InitGroup(resource.GroupVersionKind())
+		for _, obj := range all {
+			if isControlledBy(obj, parent) {
+				childMap.Insert(parent, obj)
+			}
+		}
+	}
+	return childMap, nil
+}
+
+func isControlledBy(obj, parent *unstructured.Unstructured) bool {
+	controllerRef := metav1.GetControllerOf(obj)
+	return controllerRef != nil && controllerRef.UID == parent.GetUID()
+}
+
+type updateStrategyMap map[string]*v1alpha1.CronControllerChildUpdateStrategy
+
+func (m updateStrategyMap) GetMethod(apiGroup, kind string) v1alpha1.ChildUpdateMethod {
+	strategy := m[updateStrategyMapKey(apiGroup, kind)]
+	if strategy == nil || strategy.Method == "" {
+		return v1alpha1.ChildUpdateOnDelete
+	}
+	return strategy.Method
+}
+
+func updateStrategyMapKey(apiGroup, kind string) string {
+	return fmt.Sprintf("%s.%s", kind, apiGroup)
+}
+
+func makeUpdateStrategyMap(resources *dynamicdiscovery.ResourceMap, cc *v1alpha1.CronController) (updateStrategyMap, error) {
+	m := make(updateStrategyMap)
+	for _, child := range cc.Spec.ChildResources {
+		if child.UpdateStrategy == nil {
+			continue
+		}
+		switch child.UpdateStrategy.Method {
+		case "", v1alpha1.ChildUpdateOnDelete, v1alpha1.ChildUpdateInPlace, v1alpha1.ChildUpdateRecreate:
+		default:
+			return nil, fmt.Errorf("unsupported update method %q for child resource %q in %v", child.UpdateStrategy.Method, child.Resource, child.APIVersion)
+		}
+		// Map resource name to kind name.
+		resource := resources.Get(child.APIVersion, child.Resource)
+		if resource == nil {
+			return nil, fmt.Errorf("can't find child resource %q in %v", child.Resource, child.APIVersion)
+		}
+		// Ignore API version.
+		apiGroup, _ := common.ParseAPIVersion(child.APIVersion)
+		m[updateStrategyMapKey(apiGroup, resource.Kind)] = child.UpdateStrategy
+	}
+	return m, nil
+}
+
+func makeApplyStrategies(resources *dynamicdiscovery.ResourceMap, cc *v1alpha1.CronController) (*common.ChildApplyStrategies, error) {
+	strategies := common.NewChildApplyStrategies(cc.Spec.ApplyStrategy, cc.Spec.FieldManager)
+	strategies.SetAuditController(cc.Name, common.CronController)
+	strategies.SetDryRun(cc.Spec.DryRun)
+	for _, child := range cc.Spec.ChildResources {
+		if child.ApplyStrategy == "" {
+			continue
+		}
+		resource := resources.Get(child.APIVersion, child.Resource)
+		if resource == nil {
+			return nil, fmt.Errorf("can't find child resource %q in %v", child.Resource, child.APIVersion)
+		}
+		apiGroup, _ := common.ParseAPIVersion(child.APIVersion)
+		if err := strategies.Set(apiGroup, resource.Kind, child.ApplyStrategy, nil); err != nil {
+			return nil, err
+		}
+	}
+	return strategies, nil
+}
+
+// recordDryRunWrites records an event on parent for each write to its
+// children which was only dry-run, and counts them.
+func (c *cronController) recordDryRunWrites(ctx context.Context, parent *unstructured.Unstructured, results []common.ChildResult) {
+	if !c.applyStrategies.DryRun() {
+		return
+	}
+	for _, result := range results {
+		if result.Error == "" {
+			common.SyncEventRecorder(ctx, c.eventRecorder).Event(parent, v1.EventTypeNormal, events.ReasonDryRun, result.DryRunMessage())
+		}
+	}
+	metrics.RecordDryRunWrites(c.cc.Name, common.CronController, results)
+}
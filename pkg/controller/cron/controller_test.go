@@ -0,0 +1,92 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/pointer"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/logging"
+)
+
+func newTestController(suspend *bool) *cronController {
+	cc := &v1alpha1.CronController{}
+	cc.Name = "reports"
+	cc.Spec.Suspend = suspend
+	return &cronController{
+		cc:        cc,
+		queue:     workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		scheduled: make(map[string]time.Time),
+		logger:    logging.Logger,
+	}
+}
+
+func TestCronController_FireGlobal(t *testing.T) {
+	c := newTestController(nil)
+	defer c.queue.ShutDown()
+	scheduledTime := time.Date(2021, time.March, 15, 10, 0, 0, 0, time.UTC)
+
+	if err := c.fire(scheduledTime); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if c.queue.Len() != 1 {
+		t.Fatalf("expected 1 queued key, got %d", c.queue.Len())
+	}
+	got, ok := c.scheduledTime("reports")
+	if !ok || !got.Equal(scheduledTime) {
+		t.Errorf("expected scheduled time %v, got %v", scheduledTime, got)
+	}
+}
+
+func TestCronController_FireSuspended(t *testing.T) {
+	c := newTestController(pointer.BoolPtr(true))
+	defer c.queue.ShutDown()
+
+	if err := c.fire(time.Now()); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if c.queue.Len() != 0 {
+		t.Errorf("expected no queued key, got %d", c.queue.Len())
+	}
+}
+
+func TestCronController_DoneKeepsLaterTime(t *testing.T) {
+	c := newTestController(nil)
+	defer c.queue.ShutDown()
+	first := time.Date(2021, time.March, 15, 10, 0, 0, 0, time.UTC)
+	second := first.Add(time.Minute)
+
+	c.enqueue("reports", first)
+	c.enqueue("reports", second)
+	c.done("reports", first)
+	if got, ok := c.scheduledTime("reports"); !ok || !got.Equal(second) {
+		t.Errorf("expected scheduled time %v to be kept, got %v", second, got)
+	}
+
+	c.done("reports", second)
+	if _, ok := c.scheduledTime("reports"); ok {
+		t.Errorf("expected scheduled time to be forgotten")
+	}
+}
+
+func TestMakeOwner(t *testing.T) {
+	cc := &v1alpha1.CronController{}
+	cc.Name = "reports"
+	cc.UID = "cc-uid"
+
+	owner, err := makeOwner(cc)
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	controllerRef := common.MakeControllerRef(owner)
+	if controllerRef.APIVersion != "metacontroller.k8s.io/v1alpha1" || controllerRef.Kind != "CronController" {
+		t.Errorf("unexpected controllerRef type: %v %v", controllerRef.APIVersion, controllerRef.Kind)
+	}
+	if controllerRef.Name != "reports" || controllerRef.UID != "cc-uid" {
+		t.Errorf("unexpected controllerRef target: %v %v", controllerRef.Name, controllerRef.UID)
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cron
+
+import (
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/controller/common/lifecycle"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
+	dynamicinformer "metacontroller/pkg/dynamic/informer"
+)
+
+// NewMetacontroller returns the reconciler which runs the controllers of
+// CronControllers.
+func NewMetacontroller(controllerContext common.ControllerContext, rbacPreflight bool, hookProbeInterval time.Duration) *lifecycle.Reconciler {
+	kind := &cronControllerKind{
+		resources:     controllerContext.Resources,
+		dynClient:     controllerContext.DynClient,
+		dynInformers:  controllerContext.DynInformers,
+		eventRecorder: controllerContext.EventRecorder,
+	}
+	return lifecycle.NewReconciler(controllerContext, common.CronController, kind, rbacPreflight, hookProbeInterval, "cron")
+}
+
+// cronControllerKind adapts CronControllers to a lifecycle.Reconciler.
+type cronControllerKind struct {
+	resources     *dynamicdiscovery.ResourceMap
+	dynClient     *dynamicclientset.Clientset
+	dynInformers  *dynamicinformer.SharedInformerFactory
+	eventRecorder record.EventRecorder
+}
+
+func (k *cronControllerKind) NewObject() client.Object {
+	return &v1alpha1.CronController{}
+}
+
+func (k *cronControllerKind) Spec(obj client.Object) interface{} {
+	return obj.(*v1alpha1.CronController).Spec
+}
+
+func (k *cronControllerKind) Status(obj client.Object) (*[]metav1.Condition, *int64) {
+	cc := obj.(*v1alpha1.CronController)
+	return &cc.Status.Conditions, &cc.Status.ObservedGeneration
+}
+
+func (k *cronControllerKind) DependsOn(obj client.Object) []v1alpha1.ControllerDependency {
+	return obj.(*v1alpha1.CronController).Spec.DependsOn
+}
+
+func (k *cronControllerKind) RBACRules(obj client.Object) []common.RBACRule {
+	return common.CronControllerRBACRules(obj.(*v1alpha1.CronController))
+}
+
+func (k *cronControllerKind) Webhooks(obj client.Object) map[common.HookType]*v1alpha1.Hook {
+	return ccWebhooks(obj.(*v1alpha1.CronController))
+}
+
+func (k *cronControllerKind) NewController(obj client.Object, logger logr.Logger) (lifecycle.Controller, error) {
+	return newCronController(k.resources, k.dynClient, k.dynInformers, k.eventRecorder, obj.(*v1alpha1.CronController), logger)
+}
+
+// ccWebhooks returns the hooks of cc which can be probed.
+func ccWebhooks(cc *v1alpha1.CronController) map[common.HookType]*v1alpha1.Hook {
+	if cc.Spec.Hooks == nil {
+		return nil
+	}
+	return map[common.HookType]*v1alpha1.Hook{
+		common.SyncHook: cc.Spec.Hooks.Sync,
+	}
+}
@@ -0,0 +1,189 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schedule is a parsed cron schedule in the standard five field format:
+// minute, hour, day of month, month and day of week.
+type schedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// domStar and dowStar record whether the day fields were "*", since a
+	// day matches either day field when both are restricted.
+	domStar, dowStar bool
+}
+
+type fieldBounds struct {
+	name     string
+	min, max int
+}
+
+var (
+	minuteBounds = fieldBounds{"minute", 0, 59}
+	hourBounds   = fieldBounds{"hour", 0, 23}
+	domBounds    = fieldBounds{"day of month", 1, 31}
+	monthBounds  = fieldBounds{"month", 1, 12}
+	dowBounds    = fieldBounds{"day of week", 0, 7}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// parseSchedule parses a cron expression, or one of the @yearly, @monthly,
+// @weekly, @daily and @hourly descriptors. Times are evaluated in UTC.
+func parseSchedule(spec string) (*schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if expanded, ok := descriptors[spec]; ok {
+		spec = expanded
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields, got %d", spec, len(fields))
+	}
+	s := &schedule{
+		domStar: strings.HasPrefix(fields[2], "*") || fields[2] == "?",
+		dowStar: strings.HasPrefix(fields[4], "*") || fields[4] == "?",
+	}
+	var err error
+	for i, f := range []struct {
+		bits   *uint64
+		bounds fieldBounds
+	}{
+		{&s.minute, minuteBounds},
+		{&s.hour, hourBounds},
+		{&s.dom, domBounds},
+		{&s.month, monthBounds},
+		{&s.dow, dowBounds},
+	} {
+		if *f.bits, err = parseField(fields[i], f.bounds); err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+	}
+	// Both 0 and 7 mean Sunday.
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	return s, nil
+}
+
+// parseField parses a comma separated list of values, ranges and steps,
+// e.g. "1,15-20,*/5", into a bit set.
+func parseField(field string, bounds fieldBounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			rangePart = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field %q", bounds.name, part)
+			}
+		}
+		low, high := bounds.min, bounds.max
+		switch {
+		case rangePart == "*" || rangePart == "?":
+		case strings.Contains(rangePart, "-"):
+			ends := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = parseValue(ends[0], bounds); err != nil {
+				return 0, err
+			}
+			if high, err = parseValue(ends[1], bounds); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid range in %s field %q", bounds.name, part)
+			}
+		default:
+			value, err := parseValue(rangePart, bounds)
+			if err != nil {
+				return 0, err
+			}
+			low = value
+			// A single value with a step, e.g. "5/15", runs to the maximum.
+			if step == 1 {
+				high = value
+			}
+		}
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(value string, bounds fieldBounds) (int, error) {
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value in %s field %q", bounds.name, value)
+	}
+	if v < bounds.min || v > bounds.max {
+		return 0, fmt.Errorf("%s value %d out of range [%d, %d]", bounds.name, v, bounds.min, bounds.max)
+	}
+	return v, nil
+}
+
+// next returns the first scheduled time strictly after t, or the zero time
+// if the schedule never fires, e.g. "0 0 30 2 *".
+func (s *schedule) next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	// Give up after five years, which covers every leap year combination.
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,61 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	from := time.Date(2021, time.March, 15, 10, 30, 20, 0, time.UTC) // Monday
+	tests := []struct {
+		name     string
+		schedule string
+		want     time.Time
+	}{
+		{"every minute", "* * * * *", time.Date(2021, time.March, 15, 10, 31, 0, 0, time.UTC)},
+		{"every 15 minutes", "*/15 * * * *", time.Date(2021, time.March, 15, 10, 45, 0, 0, time.UTC)},
+		{"list", "5,40 * * * *", time.Date(2021, time.March, 15, 10, 40, 0, 0, time.UTC)},
+		{"range", "0 12-14 * * *", time.Date(2021, time.March, 15, 12, 0, 0, 0, time.UTC)},
+		{"value with step", "10/20 * * * *", time.Date(2021, time.March, 15, 10, 50, 0, 0, time.UTC)},
+		{"hourly", "@hourly", time.Date(2021, time.March, 15, 11, 0, 0, 0, time.UTC)},
+		{"daily", "@daily", time.Date(2021, time.March, 16, 0, 0, 0, 0, time.UTC)},
+		{"monthly", "@monthly", time.Date(2021, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"yearly", "@yearly", time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"sunday as 7", "0 0 * * 7", time.Date(2021, time.March, 21, 0, 0, 0, 0, time.UTC)},
+		{"day of month or week", "0 0 20 * 3", time.Date(2021, time.March, 17, 0, 0, 0, 0, time.UTC)},
+		{"leap day", "0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"never", "0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseSchedule(tt.schedule)
+			if err != nil {
+				t.Fatalf("err should be nil, got: %v", err)
+			}
+			if got := s.next(from); !got.Equal(tt.want) {
+				t.Errorf("next(%v) = %v, want %v", from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@reboot",
+	} {
+		if _, err := parseSchedule(spec); err == nil {
+			t.Errorf("expected error for schedule %q", spec)
+		}
+	}
+}
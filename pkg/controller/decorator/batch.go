@@ -0,0 +1,190 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decorator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/hooks"
+	"metacontroller/pkg/logging"
+)
+
+// defaultSyncBatchMaxWait is how long a sync request waits for others to
+// join its batch if the controller doesn't set maxWaitMilliseconds.
+const defaultSyncBatchMaxWait = 50 * time.Millisecond
+
+// BatchSyncHookRequest is the object sent as JSON to the sync hook when the
+// controller batches sync calls. The requests don't repeat the controller.
+type BatchSyncHookRequest struct {
+	Controller *v1alpha1.DecoratorController `json:"controller"`
+	Requests   []*SyncHookRequest            `json:"requests"`
+}
+
+// BatchSyncHookResponse is the expected format of the JSON response from a
+// batched sync hook, with one response per request, in the same order.
+type BatchSyncHookResponse struct {
+	Responses []*SyncHookResponse `json:"responses"`
+}
+
+// syncBatcher is a HookExecutor which gathers the sync requests of
+// concurrent workers and sends them to the sync hook in a single call.
+type syncBatcher struct {
+	executor   hooks.HookExecutor
+	controller *v1alpha1.DecoratorController
+	maxSize    int
+	maxWait    time.Duration
+
+	mutex   sync.Mutex
+	pending []*batchedSync
+	timer   *time.Timer
+}
+
+type batchedSync struct {
+	request  *SyncHookRequest
+	response *SyncHookResponse
+	err      error
+	done     chan struct{}
+	// logger is the logger of the sync which made the request.
+	logger logr.Logger
+}
+
+// withSyncBatch returns a HookExecutor which batches the calls to executor,
+// or executor itself if the controller doesn't batch sync calls.
+func withSyncBatch(executor hooks.HookExecutor, dc *v1alpha1.DecoratorController) (hooks.HookExecutor, error) {
+	batch := dc.Spec.SyncBatch
+	if batch == nil || !executor.IsEnabled() {
+		return executor, nil
+	}
+	if batch.MaxSize < 2 {
+		return nil, fmt.Errorf("invalid syncBatch config: 'maxSize' must be at least 2")
+	}
+	maxWait := defaultSyncBatchMaxWait
+	if batch.MaxWaitMilliseconds != nil {
+		if *batch.MaxWaitMilliseconds <= 0 {
+			return nil, fmt.Errorf("invalid syncBatch config: 'maxWaitMilliseconds' must be positive")
+		}
+		maxWait = time.Duration(*batch.MaxWaitMilliseconds) * time.Millisecond
+	}
+	return &syncBatcher{
+		executor:   executor,
+		controller: dc,
+		maxSize:    int(batch.MaxSize),
+		maxWait:    maxWait,
+	}, nil
+}
+
+func (b *syncBatcher) IsEnabled() bool {
+	return b.executor.IsEnabled()
+}
+
+// Execute adds request to the pending batch and waits until the batch was
+// sent, which happens once it's full or maxWait after its first request.
+func (b *syncBatcher) Execute(ctx context.Context, request interface{}, response interface{}) error {
+	syncRequest, ok := request.(*SyncHookRequest)
+	if !ok {
+		return fmt.Errorf("can't batch request of type %T", request)
+	}
+	syncResponse, ok := response.(*SyncHookResponse)
+	if !ok {
+		return fmt.Errorf("can't batch response of type %T", response)
+	}
+	item := &batchedSync{request: syncRequest, done: make(chan struct{}), logger: logging.FromContext(ctx)}
+
+	b.mutex.Lock()
+	b.pending = append(b.pending, item)
+	if len(b.pending) >= b.maxSize {
+		batch := b.takePending()
+		b.mutex.Unlock()
+		go b.send(batch)
+	} else {
+		if len(b.pending) == 1 {
+			b.timer = time.AfterFunc(b.maxWait, b.flush)
+		}
+		b.mutex.Unlock()
+	}
+
+	select {
+	case <-item.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if item.err != nil {
+		return item.err
+	}
+	*syncResponse = *item.response
+	return nil
+}
+
+// takePending returns the pending batch and starts a new one.
+// The caller must hold the mutex.
+func (b *syncBatcher) takePending() []*batchedSync {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	batch := b.pending
+	b.pending = nil
+	return batch
+}
+
+// flush sends the pending batch once maxWait elapsed.
+func (b *syncBatcher) flush() {
+	b.mutex.Lock()
+	batch := b.takePending()
+	b.mutex.Unlock()
+	if len(batch) > 0 {
+		b.send(batch)
+	}
+}
+
+// send calls the sync hook with batch and hands each response to the
+// request it belongs to.
+func (b *syncBatcher) send(batch []*batchedSync) {
+	request := &BatchSyncHookRequest{
+		Controller: b.controller,
+		Requests:   make([]*SyncHookRequest, 0, len(batch)),
+	}
+	for _, item := range batch {
+		syncRequest := *item.request
+		syncRequest.Controller = nil
+		request.Requests = append(request.Requests, &syncRequest)
+	}
+	// The batch isn't tied to the context of any single request, but all the
+	// requests come from the same controller, so they share its logger.
+	var response BatchSyncHookResponse
+	err := b.executor.Execute(logging.NewContext(context.Background(), batch[0].logger), request, &response)
+	if err == nil && len(response.Responses) != len(batch) {
+		err = fmt.Errorf("invalid batch response: got %d responses for %d requests", len(response.Responses), len(batch))
+	}
+	for i, item := range batch {
+		switch {
+		case err != nil:
+			item.err = err
+		case response.Responses[i] == nil:
+			item.err = fmt.Errorf("invalid batch response: response %d is null", i)
+		default:
+			item.response = response.Responses[i]
+		}
+		close(item.done)
+	}
+}
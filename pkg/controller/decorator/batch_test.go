@@ -0,0 +1,126 @@
+package decorator
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+// fakeBatchHook answers each batched request with the name of its object
+// as syncToken.
+type fakeBatchHook struct {
+	mutex sync.Mutex
+	sizes []int
+	extra bool
+}
+
+func (f *fakeBatchHook) IsEnabled() bool {
+	return true
+}
+
+func (f *fakeBatchHook) Execute(ctx context.Context, request interface{}, response interface{}) error {
+	batchRequest := request.(*BatchSyncHookRequest)
+	batchResponse := response.(*BatchSyncHookResponse)
+	f.mutex.Lock()
+	f.sizes = append(f.sizes, len(batchRequest.Requests))
+	f.mutex.Unlock()
+	for _, r := range batchRequest.Requests {
+		batchResponse.Responses = append(batchResponse.Responses, &SyncHookResponse{SyncToken: r.Object.GetName()})
+	}
+	if f.extra {
+		batchResponse.Responses = append(batchResponse.Responses, &SyncHookResponse{})
+	}
+	return nil
+}
+
+func newBatcher(t *testing.T, hook *fakeBatchHook, maxSize, maxWaitMilliseconds int32) *syncBatcher {
+	dc := &v1alpha1.DecoratorController{}
+	dc.Spec.SyncBatch = &v1alpha1.DecoratorSyncBatch{MaxSize: maxSize, MaxWaitMilliseconds: &maxWaitMilliseconds}
+	executor, err := withSyncBatch(hook, dc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return executor.(*syncBatcher)
+}
+
+func executeAll(batcher *syncBatcher, names ...string) ([]*SyncHookResponse, []error) {
+	responses := make([]*SyncHookResponse, len(names))
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			object := &unstructured.Unstructured{}
+			object.SetName(name)
+			responses[i] = &SyncHookResponse{}
+			errs[i] = batcher.Execute(context.Background(), &SyncHookRequest{Object: object}, responses[i])
+		}(i, name)
+	}
+	wg.Wait()
+	return responses, errs
+}
+
+func TestSyncBatcher_fullBatch(t *testing.T) {
+	hook := &fakeBatchHook{}
+	// The long wait makes sure the batch is sent because it's full.
+	batcher := newBatcher(t, hook, 3, 60000)
+
+	names := []string{"a", "b", "c"}
+	responses, errs := executeAll(batcher, names...)
+
+	if len(hook.sizes) != 1 || hook.sizes[0] != 3 {
+		t.Fatalf("expected a single batch of 3 requests, got %v", hook.sizes)
+	}
+	for i, name := range names {
+		if errs[i] != nil {
+			t.Errorf("unexpected error: %v", errs[i])
+		} else if responses[i].SyncToken != name {
+			t.Errorf("expected response for %q, got %q", name, responses[i].SyncToken)
+		}
+	}
+}
+
+func TestSyncBatcher_maxWait(t *testing.T) {
+	hook := &fakeBatchHook{}
+	batcher := newBatcher(t, hook, 10, 10)
+
+	start := time.Now()
+	responses, errs := executeAll(batcher, "a")
+
+	if errs[0] != nil {
+		t.Fatalf("unexpected error: %v", errs[0])
+	}
+	if responses[0].SyncToken != "a" {
+		t.Errorf("expected response for %q, got %q", "a", responses[0].SyncToken)
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Errorf("expected partial batch to wait, but it was sent after %v", elapsed)
+	}
+}
+
+func TestSyncBatcher_responseCountMismatch(t *testing.T) {
+	hook := &fakeBatchHook{extra: true}
+	batcher := newBatcher(t, hook, 2, 60000)
+
+	_, errs := executeAll(batcher, "a", "b")
+
+	for _, err := range errs {
+		if err == nil {
+			t.Error("expected error for mismatched number of responses")
+		}
+	}
+}
+
+func TestWithSyncBatch_invalidMaxSize(t *testing.T) {
+	dc := &v1alpha1.DecoratorController{}
+	dc.Spec.SyncBatch = &v1alpha1.DecoratorSyncBatch{MaxSize: 1}
+	if _, err := withSyncBatch(&fakeBatchHook{}, dc); err == nil {
+		t.Error("expected error for maxSize 1")
+	}
+}
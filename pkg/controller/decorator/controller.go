@@ -18,8 +18,11 @@ package decorator
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"metacontroller/pkg/hooks"
+	"metacontroller/pkg/logging"
+	"metacontroller/pkg/metrics"
 	"reflect"
 	"strings"
 	"sync"
@@ -42,18 +45,23 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 
 	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/audit"
 	"metacontroller/pkg/controller/common"
 	"metacontroller/pkg/controller/common/customize"
 	"metacontroller/pkg/controller/common/finalizer"
+	"metacontroller/pkg/debug"
 	dynamicclientset "metacontroller/pkg/dynamic/clientset"
 	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
 	dynamicobject "metacontroller/pkg/dynamic/object"
+	"metacontroller/pkg/tracing"
 )
 
 const (
@@ -72,32 +80,95 @@ type decoratorController struct {
 
 	stopCh, doneCh chan struct{}
 	queue          workqueue.RateLimitingInterface
+	lagTracker     *metrics.LagTracker
+	loopDetector   *common.LoopDetector
+	syncTokens     *common.SyncTokenStore
+	health         *common.SyncHealth
+	derivedFields  *common.DerivedFields
 
-	updateStrategy updateStrategyMap
+	targetPatchPaths *common.TargetPatchPaths
+	childNamespaces  *common.ChildNamespaces
+	childValidator   *common.ChildValidator
+	sharedKinds      sharedKinds
+
+	updateStrategy  updateStrategyMap
+	applyStrategies *common.ChildApplyStrategies
 
 	parentInformers common.InformerMap
 	childInformers  common.InformerMap
 
+	nsInformer *dynamicinformer.ResourceInformer
+
 	numWorkers    int
+	childDebounce time.Duration
 	eventRecorder record.EventRecorder
 
-	finalizer    *finalizer.Manager
-	customize    *customize.Manager
-	syncHook     hooks.HookExecutor
-	finalizeHook hooks.HookExecutor
+	finalizer      *finalizer.Manager
+	customize      *customize.Manager
+	syncHook       hooks.HookExecutor
+	finalizeHook   hooks.HookExecutor
+	eventsHook     hooks.HookExecutor
+	applyErrorHook hooks.HookExecutor
 
-	logger logr.Logger
+	logger   logr.Logger
+	logLevel *logging.Level
 }
 
-func newDecoratorController(resources *dynamicdiscovery.ResourceMap, dynClient *dynamicclientset.Clientset, dynInformers *dynamicinformer.SharedInformerFactory, eventRecorder record.EventRecorder, dc *v1alpha1.DecoratorController, numWorkers int, logger logr.Logger) (controller *decoratorController, newErr error) {
+func newDecoratorController(resources *dynamicdiscovery.ResourceMap, dynClient *dynamicclientset.Clientset, dynInformers *dynamicinformer.SharedInformerFactory, eventRecorder record.EventRecorder, dc *v1alpha1.DecoratorController, numWorkers int, syncLoops common.SyncLoopLimits, logger logr.Logger) (controller *decoratorController, newErr error) {
 	if dc.Spec.Hooks == nil {
 		return nil, fmt.Errorf("no hooks defined")
 	}
-	syncHook, err := hooks.NewHookExecutor(dc.Spec.Hooks.Sync, dc.Name, common.DecoratorController, common.SyncHook)
+	dynClient, err := common.ControllerClientset(dynClient, dc.Spec.ClientRateLimit)
+	if err != nil {
+		return nil, err
+	}
+	numWorkers, err = common.ControllerWorkers(numWorkers, dc.Spec.Workers)
+	if err != nil {
+		return nil, err
+	}
+	childDebounce, err := common.ChildEventDebounce(dc.Spec.ChildEventDebounce)
+	if err != nil {
+		return nil, err
+	}
+	// All hooks of the controller share the same rate limiter.
+	hookRateLimiter, err := hooks.NewRateLimiter(dc.Spec.RateLimit)
+	if err != nil {
+		return nil, err
+	}
+	syncHook, err := hooks.NewHookExecutor(dc.Spec.Hooks.Sync, dc.Name, common.DecoratorController, common.SyncHook, dynClient, dc.Spec.HookTransport, logger)
+	if err != nil {
+		return nil, err
+	}
+	// Batches are sent through the rate limiter, so each counts as one call.
+	batchedSyncHook, err := withSyncBatch(hooks.WithRateLimiter(syncHook, hookRateLimiter), dc)
 	if err != nil {
 		return nil, err
 	}
-	finalizeHook, err := hooks.NewHookExecutor(dc.Spec.Hooks.Finalize, dc.Name, common.DecoratorController, common.FinalizeHook)
+	finalizeHook, err := hooks.NewHookExecutor(dc.Spec.Hooks.Finalize, dc.Name, common.DecoratorController, common.FinalizeHook, dynClient, dc.Spec.HookTransport, logger)
+	if err != nil {
+		return nil, err
+	}
+	eventsHook, err := hooks.NewHookExecutor(dc.Spec.Hooks.Events, dc.Name, common.DecoratorController, common.EventsHook, dynClient, dc.Spec.HookTransport, logger)
+	if err != nil {
+		return nil, err
+	}
+	applyErrorHook, err := hooks.NewHookExecutor(dc.Spec.Hooks.ApplyError, dc.Name, common.DecoratorController, common.ApplyErrorHook, dynClient, dc.Spec.HookTransport, logger)
+	if err != nil {
+		return nil, err
+	}
+	derivedFields, err := common.NewDerivedFields(dc.Spec.DerivedFields, "object", "attachments")
+	if err != nil {
+		return nil, err
+	}
+	targetPatchPaths, err := common.NewTargetPatchPaths(dc.Spec.TargetPatchPaths)
+	if err != nil {
+		return nil, err
+	}
+	childNamespaces, err := makeChildNamespaces(resources, dc)
+	if err != nil {
+		return nil, err
+	}
+	sharedKinds, err := makeSharedKinds(resources, dc)
 	if err != nil {
 		return nil, err
 	}
@@ -110,16 +181,31 @@ func newDecoratorController(resources *dynamicdiscovery.ResourceMap, dynClient *
 		parentInformers: make(common.InformerMap),
 		childInformers:  make(common.InformerMap),
 
-		queue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), common.DecoratorController.String()+"-"+dc.Name),
-		numWorkers:    numWorkers,
-		eventRecorder: eventRecorder,
+		queue:            metrics.NewControllerQueue(dc.Name, common.DecoratorController),
+		lagTracker:       metrics.NewLagTracker(dc.Name, common.DecoratorController),
+		loopDetector:     common.NewLoopDetector(syncLoops),
+		syncTokens:       common.NewSyncTokenStore(),
+		health:           common.NewSyncHealth(),
+		derivedFields:    derivedFields,
+		targetPatchPaths: targetPatchPaths,
+		childNamespaces:  childNamespaces,
+		childValidator:   makeChildValidator(dynClient, dc),
+		sharedKinds:      sharedKinds,
+		numWorkers:       numWorkers,
+		childDebounce:    childDebounce,
+		eventRecorder:    eventRecorder,
+		// Cluster-scoped attachments of namespaced targets aren't deleted with
+		// the target by the garbage collector, so a finalizer is needed to
+		// delete them.
 		finalizer: finalizer.NewManager(
 			"metacontroller.io/decoratorcontroller-"+dc.Name,
-			dc.Spec.Hooks.Finalize != nil,
+			dc.Spec.Hooks.Finalize != nil || childNamespaces.Any(),
 		),
-		syncHook:     syncHook,
-		finalizeHook: finalizeHook,
-		logger:       logger.WithName(dc.Name),
+		syncHook:       batchedSyncHook,
+		finalizeHook:   hooks.WithRateLimiter(finalizeHook, hookRateLimiter),
+		eventsHook:     hooks.WithRateLimiter(eventsHook, hookRateLimiter),
+		applyErrorHook: hooks.WithRateLimiter(applyErrorHook, hookRateLimiter),
+		logger:         logger.WithName(dc.Name),
 	}
 
 	customize, err := customize.NewCustomizeManager(
@@ -132,13 +218,21 @@ func newDecoratorController(resources *dynamicdiscovery.ResourceMap, dynClient *
 		c.parentKinds,
 		c.logger,
 		common.CompositeController,
+		hookRateLimiter,
+		dc.Spec.HookTransport,
 	)
 	if err != nil {
 		return nil, err
 	}
 	c.customize = customize
 
-	c.parentSelector, err = newDecoratorSelector(resources, dc)
+	c.parentSelector, err = newDecoratorSelector(resources, dc, func(name string) (map[string]string, error) {
+		namespace, err := common.GetObject(c.nsInformer, "", name)
+		if err != nil {
+			return nil, err
+		}
+		return namespace.GetLabels(), nil
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -157,6 +251,11 @@ func newDecoratorController(resources *dynamicdiscovery.ResourceMap, dynClient *
 	if err != nil {
 		return nil, err
 	}
+	c.applyStrategies, err = makeApplyStrategies(resources, dc)
+	if err != nil {
+		return nil, err
+	}
+	c.applyStrategies.SetLogger(c.logger)
 
 	// Create informers for all parent and child resources.
 	defer func() {
@@ -169,31 +268,71 @@ func newDecoratorController(resources *dynamicdiscovery.ResourceMap, dynClient *
 			for _, informer := range c.parentInformers {
 				informer.Close()
 			}
+			if c.nsInformer != nil {
+				c.nsInformer.Close()
+			}
 		}
 	}()
 
+	parentWatchSelectors := make(common.InformerSelectors)
 	for _, parent := range dc.Spec.Resources {
-		informer, err := dynInformers.Resource(parent.APIVersion, parent.Resource)
-		if err != nil {
-			return nil, fmt.Errorf("can't create informer for parent resource: %w", err)
-		}
 		groupVersion, err := schema.ParseGroupVersion(parent.APIVersion)
 		if err != nil {
 			return nil, fmt.Errorf("can't parse parent resource groupVersion: %w", err)
 		}
-		c.parentInformers.Set(groupVersion.WithResource(parent.Resource), informer)
+		selectors, err := common.WatchSelectors(parent.WatchSelector)
+		if err != nil {
+			return nil, fmt.Errorf("can't convert watch selector for parent resource %q in apiVersion %q: %w", parent.Resource, parent.APIVersion, err)
+		}
+		gvr := groupVersion.WithResource(parent.Resource)
+		exists, err := parentWatchSelectors.Add(gvr, selectors)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			// Rules for the same resource share its informer.
+			continue
+		}
+		informer, err := dynInformers.ResourceWithSelectors(parent.APIVersion, parent.Resource, selectors)
+		if err != nil {
+			return nil, fmt.Errorf("can't create informer for parent resource: %w", err)
+		}
+		c.parentInformers.Set(gvr, informer)
 	}
 
+	childWatchSelectors := make(common.InformerSelectors)
 	for _, child := range dc.Spec.Attachments {
-		informer, err := dynInformers.Resource(child.APIVersion, child.Resource)
+		groupVersion, err := schema.ParseGroupVersion(child.APIVersion)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse child resource groupVersion: %w", err)
+		}
+		selectors, err := common.WatchSelectors(child.WatchSelector)
+		if err != nil {
+			return nil, fmt.Errorf("can't convert watch selector for child resource %q in apiVersion %q: %w", child.Resource, child.APIVersion, err)
+		}
+		gvr := groupVersion.WithResource(child.Resource)
+		exists, err := childWatchSelectors.Add(gvr, selectors)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			// Rules for the same resource share its informer.
+			continue
+		}
+		informer, err := dynInformers.ResourceWithSelectors(child.APIVersion, child.Resource, selectors)
 		if err != nil {
 			return nil, fmt.Errorf("can't create informer for child resource: %w", err)
 		}
-		groupVersion, err := schema.ParseGroupVersion(child.APIVersion)
+		c.childInformers.Set(gvr, informer)
+	}
+
+	// Namespace labels are only needed to match the namespaceSelector of
+	// parent resources.
+	if c.parentSelector.needsNamespaceLabels() {
+		c.nsInformer, err = dynInformers.Resource("v1", "namespaces")
 		if err != nil {
-			return nil, fmt.Errorf("can't parse child resource groupVersion: %w", err)
+			return nil, fmt.Errorf("can't create informer for namespaces: %w", err)
 		}
-		c.childInformers.Set(groupVersion.WithResource(child.Resource), informer)
 	}
 
 	return c, nil
@@ -202,6 +341,7 @@ func newDecoratorController(resources *dynamicdiscovery.ResourceMap, dynClient *
 func (c *decoratorController) Start() {
 	c.stopCh = make(chan struct{})
 	c.doneCh = make(chan struct{})
+	debug.Controllers.Register(c.dc.Name, common.DecoratorController, c.watched)
 
 	// Install event handlers. DecoratorControllers can be created at any time,
 	// so we have to assume the shared informers are already running. We can't
@@ -234,6 +374,12 @@ func (c *decoratorController) Start() {
 			DeleteFunc: c.onChildDelete,
 		})
 	}
+	if c.nsInformer != nil {
+		c.nsInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    c.onNamespaceAdd,
+			UpdateFunc: c.onNamespaceUpdate,
+		})
+	}
 
 	go func() {
 		defer close(c.doneCh)
@@ -253,6 +399,9 @@ func (c *decoratorController) Start() {
 		for _, informer := range c.childInformers {
 			syncFuncs = append(syncFuncs, informer.Informer().HasSynced)
 		}
+		if c.nsInformer != nil {
+			syncFuncs = append(syncFuncs, c.nsInformer.Informer().HasSynced)
+		}
 		if !cache.WaitForNamedCacheSync(c.dc.Name, c.stopCh, syncFuncs...) {
 			// We wait forever unless Stop() is called, so this isn't an error.
 			c.logger.Info("DecoratorController cache sync never finished", "controller", c.dc)
@@ -271,8 +420,14 @@ func (c *decoratorController) Start() {
 	}()
 }
 
+// watched returns the state of the watches of the controller.
+func (c *decoratorController) watched() []dynamicinformer.WatchState {
+	return common.WatchStates(append(append(c.parentInformers.Informers(), c.childInformers.Informers()...), c.nsInformer)...)
+}
+
 func (c *decoratorController) Stop() {
 	close(c.stopCh)
+	debug.Controllers.Unregister(c.dc.Name, common.DecoratorController)
 	c.queue.ShutDown()
 	<-c.doneCh
 
@@ -286,7 +441,13 @@ func (c *decoratorController) Stop() {
 		informer.Informer().RemoveEventHandlers()
 		informer.Close()
 	}
+	if c.nsInformer != nil {
+		c.nsInformer.Informer().RemoveEventHandlers()
+		c.nsInformer.Close()
+	}
 	c.customize.Stop()
+	c.lagTracker.Stop()
+	metrics.ForgetSyncLoops(c.dc.Name, common.DecoratorController)
 }
 
 func (c *decoratorController) worker() {
@@ -300,6 +461,13 @@ func (c *decoratorController) processNextWorkItem() bool {
 		return false
 	}
 	defer c.queue.Done(key)
+	c.lagTracker.Dequeued(key.(string))
+
+	// Targets synced in a hot loop wait for their backoff to expire.
+	if backoff := c.loopDetector.Backoff(key.(string)); backoff > 0 {
+		c.queue.AddAfter(key, backoff)
+		return true
+	}
 
 	if err := c.sync(key.(string)); err != nil {
 		utilruntime.HandleError(fmt.Errorf("failed to sync %v '%v': %w", c.dc.Name, key, err))
@@ -312,6 +480,19 @@ func (c *decoratorController) processNextWorkItem() bool {
 }
 
 func (c *decoratorController) enqueueParentObject(obj interface{}) {
+	c.enqueueParentObjectDebounced(obj, 0)
+}
+
+// enqueueParentForChild enqueues parent because of an event of one of its
+// attachments, once the child event debounce window of the controller is over.
+func (c *decoratorController) enqueueParentForChild(parent *unstructured.Unstructured) {
+	c.enqueueParentObjectDebounced(parent, c.childDebounce)
+}
+
+// enqueueParentObjectDebounced enqueues obj after debounce, if positive.
+// The queue only keeps the earliest pending time of a key, so all events
+// within the window are coalesced into a single sync.
+func (c *decoratorController) enqueueParentObjectDebounced(obj interface{}, debounce time.Duration) {
 	// If the parent doesn't match our selector, and it doesn't have our
 	// finalizer, we don't care about it.
 	if parent, ok := obj.(*unstructured.Unstructured); ok {
@@ -325,6 +506,11 @@ func (c *decoratorController) enqueueParentObject(obj interface{}) {
 		utilruntime.HandleError(fmt.Errorf("couldn't get key for object %+v: %w", obj, err))
 		return
 	}
+	c.lagTracker.Enqueued(key)
+	if debounce > 0 {
+		c.queue.AddAfter(key, debounce)
+		return
+	}
 	c.queue.Add(key)
 }
 
@@ -339,9 +525,41 @@ func (c *decoratorController) enqueueParentObjectAfter(obj interface{}, delay ti
 
 func (c *decoratorController) updateParentObject(old, cur interface{}) {
 	// TODO(enisoc): Is there any way to avoid resyncing after our own updates?
+	c.lagTracker.ObserveUpdate(old.(*unstructured.Unstructured), cur.(*unstructured.Unstructured))
 	c.enqueueParentObject(cur)
 }
 
+func (c *decoratorController) onNamespaceAdd(obj interface{}) {
+	if namespace, ok := obj.(*unstructured.Unstructured); ok {
+		c.enqueueParentsInNamespace(namespace.GetName())
+	}
+}
+
+func (c *decoratorController) onNamespaceUpdate(old, cur interface{}) {
+	oldNamespace := old.(*unstructured.Unstructured)
+	curNamespace := cur.(*unstructured.Unstructured)
+	// Parents only have to be synced again if they might now match the
+	// namespaceSelector, or no longer match it.
+	if reflect.DeepEqual(oldNamespace.GetLabels(), curNamespace.GetLabels()) {
+		return
+	}
+	c.enqueueParentsInNamespace(curNamespace.GetName())
+}
+
+// enqueueParentsInNamespace enqueues all parents in the given namespace.
+func (c *decoratorController) enqueueParentsInNamespace(namespace string) {
+	for _, informer := range c.parentInformers {
+		parents, err := informer.Lister().Namespace(namespace).List(labels.Everything())
+		if err != nil {
+			utilruntime.HandleError(fmt.Errorf("can't list parents in namespace %q: %w", namespace, err))
+			continue
+		}
+		for _, parent := range parents {
+			c.enqueueParentObject(parent)
+		}
+	}
+}
+
 // resolveControllerRef returns the controller referenced by a ControllerRef,
 // or nil if the ControllerRef could not be resolved to a matching controller
 // of the correct Kind.
@@ -386,6 +604,41 @@ func (c *decoratorController) resolveControllerRef(childNamespace string, contro
 	return parent
 }
 
+// auditRecorder returns the recorder of the writes made on behalf of parent,
+// besides the operations on its attachments.
+func (c *decoratorController) auditRecorder(parent *unstructured.Unstructured) audit.Recorder {
+	return common.AuditRecorder(c.dc.Name, common.DecoratorController, parent)
+}
+
+// resolveLabelOwner returns the parent recorded on child with labels,
+// or nil if it isn't a parent of this controller.
+func (c *decoratorController) resolveLabelOwner(child *unstructured.Unstructured) *unstructured.Unstructured {
+	namespace, name, uid, found := common.LabelOwnerOf(child)
+	if !found || namespace == "" {
+		return nil
+	}
+	// The labels don't record the kind of the parent, so look for it in all
+	// namespaced parent resources.
+	for _, resource := range c.parentKinds {
+		if !resource.Namespaced {
+			continue
+		}
+		informer := c.parentInformers.Get(resource.GroupVersion().WithResource(resource.Name))
+		if informer == nil {
+			continue
+		}
+		parent, err := common.GetObject(informer, namespace, name)
+		if err != nil || parent.GetUID() != uid {
+			continue
+		}
+		if !c.parentSelector.Matches(parent) && !dynamicobject.HasFinalizer(parent, c.finalizer.Name) {
+			return nil
+		}
+		return parent
+	}
+	return nil
+}
+
 func (c *decoratorController) onChildAdd(obj interface{}) {
 	child := obj.(*unstructured.Unstructured)
 
@@ -394,10 +647,18 @@ func (c *decoratorController) onChildAdd(obj interface{}) {
 		return
 	}
 
+	// Cluster-scoped attachments of namespaced targets are owned through labels.
+	if parent := c.resolveLabelOwner(child); parent != nil {
+		c.logger.V(4).Info("Child created or updated", "controller", c.dc, "parent", parent, "child", child)
+		c.enqueueParentForChild(parent)
+		return
+	}
+
 	// If it has no ControllerRef, we don't care.
 	// DecoratorController doesn't do adoption since there are no child selectors.
 	controllerRef := metav1.GetControllerOf(child)
 	if controllerRef == nil {
+		c.enqueueSharingParents(child)
 		return
 	}
 
@@ -407,7 +668,21 @@ func (c *decoratorController) onChildAdd(obj interface{}) {
 		return
 	}
 	c.logger.V(4).Info("Child created or updated", "controller", c.dc, "parent", parent, "child", child)
-	c.enqueueParentObject(parent)
+	c.enqueueParentForChild(parent)
+}
+
+// enqueueSharingParents enqueues all parents referencing child, if it's a
+// shared attachment created by this controller.
+func (c *decoratorController) enqueueSharingParents(child *unstructured.Unstructured) {
+	if child.GetAnnotations()[decoratorControllerAnnotation] != c.dc.Name {
+		return
+	}
+	ownerRefs := child.GetOwnerReferences()
+	for i := range ownerRefs {
+		if parent := c.resolveControllerRef(child.GetNamespace(), &ownerRefs[i]); parent != nil {
+			c.enqueueParentForChild(parent)
+		}
+	}
 }
 
 func (c *decoratorController) onChildUpdate(old, cur interface{}) {
@@ -419,6 +694,7 @@ func (c *decoratorController) onChildUpdate(old, cur interface{}) {
 	if oldChild.GetResourceVersion() == curChild.GetResourceVersion() {
 		return
 	}
+	c.lagTracker.ObserveUpdate(oldChild, curChild)
 
 	// Other than that, we treat updates the same as creates.
 	// Level-triggered controllers shouldn't care what the old state was.
@@ -441,10 +717,17 @@ func (c *decoratorController) onChildDelete(obj interface{}) {
 		}
 	}
 
+	if parent := c.resolveLabelOwner(child); parent != nil {
+		c.logger.V(4).Info("DecoratorController child deleted", "controller", c.dc, "parent", parent, "child", child)
+		c.enqueueParentForChild(parent)
+		return
+	}
+
 	// If it's an orphan, there's nothing to do because we never adopt orphans
 	// that are being deleted.
 	controllerRef := metav1.GetControllerOf(child)
 	if controllerRef == nil {
+		c.enqueueSharingParents(child)
 		return
 	}
 
@@ -455,7 +738,7 @@ func (c *decoratorController) onChildDelete(obj interface{}) {
 		return
 	}
 	c.logger.V(4).Info("DecoratorController child deleted", "controller", c.dc, "parent", parent, "child", child)
-	c.enqueueParentObject(parent)
+	c.enqueueParentForChild(parent)
 }
 
 func (c *decoratorController) sync(key string) error {
@@ -466,9 +749,9 @@ func (c *decoratorController) sync(key string) error {
 
 	c.logger.V(4).Info("Sync", "object", klog.KRef(namespace, name))
 
-	resource := c.resources.GetKind(apiVersion, kind)
-	if resource == nil {
-		return fmt.Errorf("can't find kind %q in apiVersion %q", kind, apiVersion)
+	resource, err := c.resources.LookupKind(apiVersion, kind)
+	if err != nil {
+		return err
 	}
 
 	groupVersion, _ := schema.ParseGroupVersion(apiVersion)
@@ -480,34 +763,90 @@ func (c *decoratorController) sync(key string) error {
 	if apierrors.IsNotFound(err) {
 		// Swallow the error since there's no point retrying if the parent is gone.
 		c.logger.V(4).Info("Parent object has been deleted", "kind", kind, "object", klog.KRef(namespace, name))
+		c.syncTokens.Forget(key)
+		c.loopDetector.Forget(key)
+		c.health.Forget(key)
 		return nil
 	}
 	if err != nil {
 		return err
 	}
-	err = c.syncParentObject(parent)
+	ctx, writes := common.WithSyncWrites(logging.NewContext(context.Background(), c.logger))
+	ctx, span := tracing.Start(ctx, "sync",
+		"controller.type", common.DecoratorController.String(),
+		"controller.name", c.dc.Name,
+		"parent.kind", kind,
+		"parent.namespace", namespace,
+		"parent.name", name)
+	defer span.End()
+	err = c.syncParentObject(ctx, parent)
+	span.RecordError(err)
+	c.health.Record(key, parent, err)
 	if err != nil {
-		c.eventRecorder.Eventf(
-			parent,
-			v1.EventTypeWarning,
-			events.ReasonSyncError,
-			"Sync error: %s", err.Error())
+		// Failures to apply children were each reported on their own.
+		var applyErr *common.ChildApplyError
+		if !errors.As(err, &applyErr) {
+			c.syncEvents(ctx).Eventf(
+				parent,
+				v1.EventTypeWarning,
+				hooks.SyncErrorReason(err),
+				"Sync error: %s", err.Error())
+		}
+	} else if writes.Wrote() {
+		c.recordSyncLoop(ctx, key, parent)
 	}
 	return err
 }
 
-func (c *decoratorController) syncParentObject(parent *unstructured.Unstructured) error {
+// syncEvents returns the recorder of the events about the target synced with
+// ctx, which carry the ID of the sync.
+func (c *decoratorController) syncEvents(ctx context.Context) record.EventRecorder {
+	return common.SyncEventRecorder(ctx, c.eventRecorder)
+}
+
+// recordSyncLoop records a sync of parent which wrote its attachments or its
+// status, and reports the hot loop it makes, if any. Only the first loop in a
+// row gets an event, while the backoff goes on escalating.
+func (c *decoratorController) recordSyncLoop(ctx context.Context, key string, parent *unstructured.Unstructured) {
+	loops := c.loopDetector.Wrote(key)
+	if loops == 0 {
+		return
+	}
+	metrics.RecordSyncLoop(c.dc.Name, common.DecoratorController)
+	if loops > 1 {
+		return
+	}
+	c.logger.Info("Sync loop detected, backing off", "object", klog.KObj(parent), "syncID", tracing.SyncID(ctx))
+	c.syncEvents(ctx).Eventf(
+		parent,
+		v1.EventTypeWarning,
+		events.ReasonSyncLoopDetected,
+		"Synced too often, backing off: check that the hooks return the same output for the same input")
+}
+
+func (c *decoratorController) syncParentObject(ctx context.Context, parent *unstructured.Unstructured) error {
 	// If it doesn't match our selector, and it doesn't have our finalizer, ignore it.
 	if !c.parentSelector.Matches(parent) && !dynamicobject.HasFinalizer(parent, c.finalizer.Name) {
 		return nil
 	}
 
-	c.logger.V(4).Info("DecoratorController sync", "controller", c.dc, "parent", parent)
+	c.logger.V(4).Info("DecoratorController sync", "controller", c.dc, "parent", parent, "syncID", tracing.SyncID(ctx))
 
 	parentClient, err := c.dynClient.Kind(parent.GetAPIVersion(), parent.GetKind())
 	if err != nil {
 		return fmt.Errorf("can't get client for %v %v/%v: %w", parent.GetKind(), parent.GetNamespace(), parent.GetName(), err)
 	}
+	parentClient = parentClient.Audited(c.auditRecorder(parent))
+
+	// Paused targets are left alone, even while they're deleted, except for a
+	// condition telling so.
+	if reason, message := c.pauseReason(parent); reason != "" {
+		c.logger.V(4).Info("Sync paused", "object", klog.KObj(parent), "reason", reason)
+		if err := updatePausedCondition(parentClient, parent, reason, message); err != nil {
+			return fmt.Errorf("can't update status for %v %v/%v: %w", parent.GetKind(), parent.GetNamespace(), parent.GetName(), err)
+		}
+		return nil
+	}
 
 	// Before taking any other action, add our finalizer (if desired).
 	// This ensures we have a chance to clean up after any action we later take.
@@ -530,33 +869,69 @@ func (c *decoratorController) syncParentObject(parent *unstructured.Unstructured
 		return err
 	}
 
-	relatedObjects, err := c.customize.GetRelatedObjects(parent)
+	relatedObjects, err := c.customize.GetRelatedObjects(ctx, parent)
 	if err != nil {
 		return err
 	}
 
 	// Call the sync hook to get the desired annotations and children.
+	key, err := parentQueueKey(parent)
+	if err != nil {
+		return err
+	}
 	syncRequest := &SyncHookRequest{
 		Controller:  c.dc,
 		Object:      parent,
 		Attachments: observedChildren,
 		Related:     relatedObjects,
+		SyncToken:   c.syncTokens.Get(key, parent.GetUID()),
 	}
-	syncResult, err := c.callHook(syncRequest)
+	syncResult, err := c.callHook(ctx, syncRequest)
 	if err != nil {
 		return err
 	}
 	desiredChildren := common.MakeRelativeObjectMap(parent, syncResult.Attachments)
 
+	// Desired attachments owned by someone else are left alone, rather than
+	// fighting over them.
+	conflicts := common.FindConflicts(parent, observedChildren, desiredChildren, common.CachedChildGetter(c.dynClient, c.childInformers))
+	for _, conflict := range conflicts {
+		c.syncEvents(ctx).Event(parent, v1.EventTypeWarning, events.ReasonChildConflict, conflict.Message(parent))
+	}
+	if err := c.childNamespaces.Check(parent, desiredChildren); err != nil {
+		return err
+	}
+	if err := c.childValidator.Check(desiredChildren); err != nil {
+		c.syncEvents(ctx).Event(parent, v1.EventTypeWarning, events.ReasonChildInvalid, err.Error())
+		return fmt.Errorf("%v %v/%v: %w", parent.GetKind(), parent.GetNamespace(), parent.GetName(), err)
+	}
+
+	// Attachments owned through labels aren't deleted with the target by the
+	// garbage collector, so they're deleted before our finalizer is removed.
+	if parent.GetDeletionTimestamp() != nil && c.childNamespaces.Any() {
+		remaining := c.finalizeLabelOwnedChildren(parent, observedChildren, desiredChildren)
+		syncResult.Finalized = (syncResult.Finalized || !c.finalizeHook.IsEnabled()) && !remaining
+	}
+
 	// Enqueue a delayed resync, if requested.
 	if syncResult.ResyncAfterSeconds > 0 {
 		c.enqueueParentObjectAfter(parent, time.Duration(syncResult.ResyncAfterSeconds*float64(time.Second)))
 	}
 
-	// Set desired labels and annotations on parent.
+	// The hook reported that nothing changed since the last applied sync.
+	if syncResult.NotModified {
+		c.logger.V(4).Info("Sync not modified", "object", klog.KObj(parent))
+		return nil
+	}
+
+	// Apply the target patch, and set desired labels and annotations on parent.
 	// Also remove finalizer if requested.
 	// Make a copy since parent is from the cache.
 	updatedParent = parent.DeepCopy()
+	targetPatched, err := c.targetPatchPaths.Apply(updatedParent, syncResult.TargetPatch)
+	if err != nil {
+		return fmt.Errorf("can't patch %v %v/%v: %w", parent.GetKind(), parent.GetNamespace(), parent.GetName(), err)
+	}
 	parentLabels := updatedParent.GetLabels()
 	if parentLabels == nil {
 		parentLabels = make(map[string]string)
@@ -571,15 +946,20 @@ func (c *decoratorController) syncParentObject(parent *unstructured.Unstructured
 	}
 	if syncResult.Status == nil {
 		// A null .status in the sync response means leave it unchanged.
-		syncResult.Status = parentStatus
+		// Use a separate copy, since we may still add conditions below.
+		syncResult.Status, _, _ = unstructured.NestedMap(updatedParent.Object, "status")
 	}
+	// Report whether related objects were selected using stale customize rules.
+	syncResult.Status = common.SetRelatedResourcesStaleCondition(syncResult.Status, c.customize.CustomizeHookError(parent))
+	syncResult.Status = common.SetChildConflictCondition(syncResult.Status, parent, conflicts)
+	syncResult.Status = common.SetPausedCondition(syncResult.Status, "", "")
 
 	labelsChanged := updateStringMap(parentLabels, syncResult.Labels)
 	annotationsChanged := updateStringMap(parentAnnotations, syncResult.Annotations)
 	statusChanged := !reflect.DeepEqual(parentStatus, syncResult.Status)
 
 	// Only do the update if something changed.
-	if labelsChanged || annotationsChanged || statusChanged ||
+	if targetPatched || labelsChanged || annotationsChanged || statusChanged ||
 		(syncResult.Finalized && dynamicobject.HasFinalizer(parent, c.finalizer.Name)) {
 		updatedParent.SetLabels(parentLabels)
 		updatedParent.SetAnnotations(parentAnnotations)
@@ -606,6 +986,7 @@ func (c *decoratorController) syncParentObject(parent *unstructured.Unstructured
 		if err != nil {
 			return fmt.Errorf("can't update %v %v/%v: %w", parent.GetKind(), parent.GetNamespace(), parent.GetName(), err)
 		}
+		common.RecordWrites(ctx, true)
 	}
 
 	// Add an annotation to all desired children to remember that they were
@@ -623,6 +1004,15 @@ func (c *decoratorController) syncParentObject(parent *unstructured.Unstructured
 			child.SetAnnotations(ann)
 		}
 	}
+	// Cluster-scoped attachments of namespaced targets point to the target
+	// with labels, since they can't have an ownerReference to it.
+	for _, group := range desiredChildren {
+		for _, child := range group {
+			if c.childNamespaces.IsLabelOwned(parent, child) {
+				common.SetLabelOwner(parent, child)
+			}
+		}
+	}
 
 	// Reconcile child objects belonging to this parent.
 	// Remember manage error, but continue to update status regardless.
@@ -630,16 +1020,95 @@ func (c *decoratorController) syncParentObject(parent *unstructured.Unstructured
 	// We only manage children if the parent is "alive" (not pending deletion),
 	// or if it's pending deletion and we have a `finalize` hook.
 	var manageErr error
+	var childResults []common.ChildResult
+	var waitingRecreate bool
 	if parent.GetDeletionTimestamp() == nil || c.finalizer.ShouldFinalize(parent) {
 		// Reconcile children.
-		if err := common.ManageChildren(c.dynClient, c.updateStrategy, parent, observedChildren, desiredChildren); err != nil {
+		_, span := tracing.Start(ctx, "manage children")
+		var err error
+		var managedObserved, managedDesired common.RelativeObjectMap
+		sharedObserved, sharedDesired := c.sharedKinds.split(observedChildren, desiredChildren)
+		// Attachments updated with the RollingRecreate method are recreated
+		// within the maxUnavailable budget.
+		managedObserved, managedDesired, waitingRecreate, err = c.gateRollingRecreate(observedChildren, desiredChildren)
+		if err == nil {
+			if waitingRecreate {
+				c.logger.V(4).Info("Waiting for recreated attachments to be ready", "object", klog.KObj(parent))
+			}
+			childResults, err = common.ManageChildren(ctx, c.dynClient, c.updateStrategy, c.applyStrategies, nil, parent, managedObserved, managedDesired)
+		}
+		// Subresources and shared attachments aren't dry-run, so they're left
+		// alone in dry-run mode.
+		if err == nil && len(syncResult.AttachmentSubresources) > 0 && !c.applyStrategies.DryRun() {
+			err = common.ApplyChildSubresources(c.dynClient, c.auditRecorder(parent), parent, observedChildren, syncResult.AttachmentSubresources)
+		}
+		if !c.applyStrategies.DryRun() {
+			if sharedErr := c.manageSharedAttachments(parent, sharedObserved, sharedDesired); sharedErr != nil {
+				err = utilerrors.NewAggregate([]error{err, sharedErr})
+			}
+		}
+		if err != nil {
 			manageErr = fmt.Errorf("can't reconcile children for %v %v/%v: %w", parent.GetKind(), parent.GetNamespace(), parent.GetName(), err)
 		}
+		span.RecordError(manageErr)
+		span.End()
+		common.AuditChildren(c.dc.Name, common.DecoratorController, parent, childResults)
+		common.RecordWrites(ctx, common.WroteChildren(childResults))
+		c.recordUpdateDiffs(ctx, parent, childResults)
+		c.recordChildFailures(ctx, parent, childResults)
+		c.recordDryRunWrites(ctx, parent, childResults)
+		c.callEventsHook(ctx, parent, childResults)
+	}
+	var applyErrorResult *ApplyErrorHookResponse
+	if manageErr != nil {
+		applyErrorResult = c.callApplyErrorHook(ctx, parent, childResults)
 	}
 
+	// Only remember the token once its sync was fully applied.
+	if manageErr == nil && !waitingRecreate {
+		c.syncTokens.Set(key, parent.GetUID(), syncResult.SyncToken)
+	} else {
+		c.syncTokens.Forget(key)
+	}
+	if applyErrorResult != nil && applyErrorResult.Handled {
+		c.logger.Info("Failures to reconcile attachments were handled by the applyError hook", "object", klog.KObj(parent), "error", manageErr)
+		return nil
+	}
 	return manageErr
 }
 
+// pauseReason returns the reason and message of the Paused condition, if syncs
+// of parent are paused, either by the controller or by the parent itself.
+func (c *decoratorController) pauseReason(parent *unstructured.Unstructured) (string, string) {
+	if c.dc.Spec.Paused != nil && *c.dc.Spec.Paused {
+		return "ControllerPaused", fmt.Sprintf("Syncs are paused by DecoratorController %s", c.dc.Name)
+	}
+	if parent.GetAnnotations()[common.PausedAnnotation] == "true" {
+		return "ParentPaused", fmt.Sprintf("Syncs are paused by the %s annotation", common.PausedAnnotation)
+	}
+	return "", ""
+}
+
+// updatePausedCondition sets the Paused condition of parent, leaving the rest
+// of its status alone.
+func updatePausedCondition(parentClient *dynamicclientset.ResourceClient, parent *unstructured.Unstructured, reason, message string) error {
+	status, _, _ := unstructured.NestedMap(parent.UnstructuredContent(), "status")
+	if reflect.DeepEqual(common.SetPausedCondition(runtime.DeepCopyJSON(status), reason, message), status) {
+		// Nothing to do.
+		return nil
+	}
+	_, err := parentClient.Namespace(parent.GetNamespace()).AtomicStatusUpdate(parent, func(obj *unstructured.Unstructured) bool {
+		status, _, _ := unstructured.NestedMap(obj.UnstructuredContent(), "status")
+		updated := common.SetPausedCondition(runtime.DeepCopyJSON(status), reason, message)
+		if reflect.DeepEqual(updated, status) {
+			return false
+		}
+		obj.UnstructuredContent()["status"] = updated
+		return true
+	})
+	return err
+}
+
 func (c *decoratorController) getChildren(parent *unstructured.Unstructured) (common.RelativeObjectMap, error) {
 	parentUID := parent.GetUID()
 	parentNamespace := parent.GetNamespace()
@@ -653,9 +1122,15 @@ func (c *decoratorController) getChildren(parent *unstructured.Unstructured) (co
 		if informer == nil {
 			return nil, fmt.Errorf("no informer for resource %q in apiVersion %q", child.Resource, child.APIVersion)
 		}
+		resource := c.resources.Get(child.APIVersion, child.Resource)
+		if resource == nil {
+			return nil, fmt.Errorf("can't find resource %q in apiVersion %q", child.Resource, child.APIVersion)
+		}
+		// Cluster-scoped attachments of a namespaced parent are owned through labels.
+		labelOwned := parentNamespace != "" && c.childNamespaces.Enabled(groupVersion.Group, resource.Kind)
 		var all []*unstructured.Unstructured
 		var err error
-		if parentNamespace != "" {
+		if parentNamespace != "" && !labelOwned {
 			all, err = informer.Lister().Namespace(parentNamespace).List(labels.Everything())
 		} else {
 			all, err = informer.Lister().List(labels.Everything())
@@ -665,17 +1140,20 @@ func (c *decoratorController) getChildren(parent *unstructured.Unstructured) (co
 		}
 
 		// Always include the requested groups, even if there are no entries.
-		resource := c.resources.Get(child.APIVersion, child.Resource)
-		if resource == nil {
-			return nil, fmt.Errorf("can't find resource %q in apiVersion %q", child.Resource, child.APIVersion)
-		}
 		childMap.InitGroup(resource.GroupVersionKind())
 
 		// Take only the objects that belong to this parent,
 		// and that were created by this decorator.
 		for _, obj := range all {
-			controllerRef := metav1.GetControllerOf(obj)
-			if controllerRef == nil || controllerRef.UID != parentUID {
+			if c.sharedKinds[updateStrategyMapKey(groupVersion.Group, resource.Kind)] {
+				if !isSharedBy(parent, obj) {
+					continue
+				}
+			} else if labelOwned {
+				if !common.IsLabelOwnedBy(parent, obj) {
+					continue
+				}
+			} else if controllerRef := metav1.GetControllerOf(obj); controllerRef == nil || controllerRef.UID != parentUID {
 				continue
 			}
 			if obj.GetAnnotations()[decoratorControllerAnnotation] != c.dc.Name {
@@ -687,6 +1165,27 @@ func (c *decoratorController) getChildren(parent *unstructured.Unstructured) (co
 	return childMap, nil
 }
 
+// finalizeLabelOwnedChildren leaves the attachments owned through labels out of
+// the desired ones, so they're deleted, and returns whether any of them still
+// exist.
+func (c *decoratorController) finalizeLabelOwnedChildren(parent *unstructured.Unstructured, observedChildren, desiredChildren common.RelativeObjectMap) bool {
+	for _, group := range desiredChildren {
+		for name, obj := range group {
+			if c.childNamespaces.IsLabelOwned(parent, obj) {
+				delete(group, name)
+			}
+		}
+	}
+	for _, group := range observedChildren {
+		for _, obj := range group {
+			if c.childNamespaces.IsLabelOwned(parent, obj) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 type updateStrategyMap map[string]*v1alpha1.DecoratorControllerAttachmentUpdateStrategy
 
 func (m updateStrategyMap) GetMethod(apiGroup, kind string) v1alpha1.ChildUpdateMethod {
@@ -708,7 +1207,18 @@ func updateStrategyMapKey(apiGroup, kind string) string {
 func makeUpdateStrategyMap(resources *dynamicdiscovery.ResourceMap, dc *v1alpha1.DecoratorController) (updateStrategyMap, error) {
 	m := make(updateStrategyMap)
 	for _, child := range dc.Spec.Attachments {
-		if child.UpdateStrategy != nil && child.UpdateStrategy.Method != v1alpha1.ChildUpdateOnDelete {
+		if child.UpdateStrategy == nil {
+			continue
+		}
+		switch child.UpdateStrategy.Method {
+		case "", v1alpha1.ChildUpdateOnDelete, v1alpha1.ChildUpdateInPlace, v1alpha1.ChildUpdateRecreate, v1alpha1.ChildUpdateRollingRecreate:
+		default:
+			return nil, fmt.Errorf("unsupported update method %q for attachment resource %q in %v", child.UpdateStrategy.Method, child.Resource, child.APIVersion)
+		}
+		if child.UpdateStrategy.MaxUnavailable != nil && child.UpdateStrategy.Method != v1alpha1.ChildUpdateRollingRecreate {
+			return nil, fmt.Errorf("maxUnavailable of attachment resource %q in %v requires the RollingRecreate update method", child.Resource, child.APIVersion)
+		}
+		if child.UpdateStrategy.Method != v1alpha1.ChildUpdateOnDelete {
 			// Map resource name to kind name.
 			resource := resources.Get(child.APIVersion, child.Resource)
 			if resource == nil {
@@ -723,6 +1233,73 @@ func makeUpdateStrategyMap(resources *dynamicdiscovery.ResourceMap, dc *v1alpha1
 	return m, nil
 }
 
+func makeApplyStrategies(resources *dynamicdiscovery.ResourceMap, dc *v1alpha1.DecoratorController) (*common.ChildApplyStrategies, error) {
+	strategies := common.NewChildApplyStrategies(dc.Spec.ApplyStrategy, dc.Spec.FieldManager)
+	strategies.SetAuditController(dc.Name, common.DecoratorController)
+	strategies.SetUpdateDiff(dc.Spec.UpdateDiff)
+	strategies.SetDryRun(dc.Spec.DryRun)
+	if err := strategies.SetConcurrency(dc.Spec.ChildConcurrency); err != nil {
+		return nil, err
+	}
+	for _, child := range dc.Spec.Attachments {
+		if child.ApplyStrategy == "" && child.ConflictPolicy == nil && len(child.IgnorePaths) == 0 {
+			continue
+		}
+		resource := resources.Get(child.APIVersion, child.Resource)
+		if resource == nil {
+			return nil, fmt.Errorf("can't find child resource %q in %v", child.Resource, child.APIVersion)
+		}
+		apiGroup, _ := common.ParseAPIVersion(child.APIVersion)
+		if err := strategies.Set(apiGroup, resource.Kind, child.ApplyStrategy, child.ConflictPolicy); err != nil {
+			return nil, err
+		}
+		if err := strategies.SetIgnorePaths(apiGroup, resource.Kind, child.IgnorePaths); err != nil {
+			return nil, err
+		}
+	}
+	return strategies, nil
+}
+
+func makeChildValidator(dynClient *dynamicclientset.Clientset, dc *v1alpha1.DecoratorController) *common.ChildValidator {
+	if dc.Spec.ValidateChildren == nil || !*dc.Spec.ValidateChildren {
+		return nil
+	}
+	return common.NewChildValidator(dynClient)
+}
+
+// makeChildNamespaces returns which cluster-scoped attachments are allowed for
+// namespaced targets. Attachments in other namespaces are never allowed.
+func makeChildNamespaces(resources *dynamicdiscovery.ResourceMap, dc *v1alpha1.DecoratorController) (*common.ChildNamespaces, error) {
+	namespaces := common.NewChildNamespaces(nil)
+	namespacedTargets := false
+	for _, parent := range dc.Spec.Resources {
+		resource := resources.Get(parent.APIVersion, parent.Resource)
+		if resource == nil {
+			return nil, fmt.Errorf("can't find resource %q in apiVersion %q", parent.Resource, parent.APIVersion)
+		}
+		namespacedTargets = namespacedTargets || resource.Namespaced
+	}
+	for _, child := range dc.Spec.Attachments {
+		clusterScoped := child.ClusterScoped != nil && *child.ClusterScoped
+		resource := resources.Get(child.APIVersion, child.Resource)
+		if resource == nil {
+			return nil, fmt.Errorf("can't find child resource %q in %v", child.Resource, child.APIVersion)
+		}
+		if resource.Namespaced {
+			if clusterScoped {
+				return nil, fmt.Errorf("clusterScoped can't be set for namespaced child resource %q in %v", child.Resource, child.APIVersion)
+			}
+			continue
+		}
+		// Cluster-scoped attachments of a namespaced target are opt-in.
+		if namespacedTargets {
+			apiGroup, _ := common.ParseAPIVersion(child.APIVersion)
+			namespaces.SetClusterScoped(apiGroup, resource.Kind, clusterScoped)
+		}
+	}
+	return namespaces, nil
+}
+
 func parentQueueKey(obj interface{}) (string, error) {
 	switch o := obj.(type) {
 	case cache.DeletedFinalStateUnknown:
@@ -763,3 +1340,58 @@ func updateStringMap(dest map[string]string, updates map[string]*string) (change
 	}
 	return changed
 }
+
+// matchedTargets returns the number of targets matching the selectors of the
+// controller.
+func (c *decoratorController) matchedTargets() int32 {
+	var matched int32
+	for _, informer := range c.parentInformers {
+		parents, err := informer.Lister().List(labels.Everything())
+		if err != nil {
+			c.logger.Error(err, "Can't list targets")
+			continue
+		}
+		for _, parent := range parents {
+			if c.parentSelector.Matches(parent) {
+				matched++
+			}
+		}
+	}
+	return matched
+}
+
+// recordChildFailures records an event on parent for each failed operation
+// on its children.
+func (c *decoratorController) recordChildFailures(ctx context.Context, parent *unstructured.Unstructured, results []common.ChildResult) {
+	for _, failure := range common.ChildFailures(results) {
+		c.syncEvents(ctx).Event(parent, v1.EventTypeWarning, events.ReasonChildApplyError, failure.FailureMessage())
+	}
+}
+
+// recordUpdateDiffs records an event on parent with the diff of each child
+// update, if the controller reports them in events.
+func (c *decoratorController) recordUpdateDiffs(ctx context.Context, parent *unstructured.Unstructured, results []common.ChildResult) {
+	updateDiff := c.applyStrategies.UpdateDiff()
+	if updateDiff == nil || updateDiff.Report != v1alpha1.ChildUpdateDiffEvent || c.applyStrategies.DryRun() {
+		return
+	}
+	for _, result := range results {
+		if result.Diff != nil {
+			c.syncEvents(ctx).Event(parent, v1.EventTypeNormal, events.ReasonChildUpdateDiff, result.UpdateDiffMessage())
+		}
+	}
+}
+
+// recordDryRunWrites records an event on parent for each write to its
+// children which was only dry-run, and counts them.
+func (c *decoratorController) recordDryRunWrites(ctx context.Context, parent *unstructured.Unstructured, results []common.ChildResult) {
+	if !c.applyStrategies.DryRun() {
+		return
+	}
+	for _, result := range results {
+		if result.Error == "" {
+			c.syncEvents(ctx).Event(parent, v1.EventTypeNormal, events.ReasonDryRun, result.DryRunMessage())
+		}
+	}
+	metrics.RecordDryRunWrites(c.dc.Name, common.DecoratorController, results)
+}
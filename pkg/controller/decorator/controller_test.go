@@ -0,0 +1,371 @@
+package decorator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/pointer"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/controller/common/finalizer"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
+	dynamicinformer "metacontroller/pkg/dynamic/informer"
+)
+
+var (
+	configMapsResource = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	widgetsResource    = schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}
+)
+
+// newTestResources returns the resources of namespaced configmaps and secrets,
+// and of cluster-scoped widgets.
+func newTestResources() *dynamicdiscovery.ResourceMap {
+	discovery := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+				{Name: "secrets", Kind: "Secret", Namespaced: true},
+			},
+		},
+		{
+			GroupVersion: "example.com/v1",
+			APIResources: []metav1.APIResource{{Name: "widgets", Kind: "Widget", Namespaced: false}},
+		},
+	}}}
+	resources := dynamicdiscovery.NewResourceMap(discovery)
+	resources.Refresh()
+	return resources
+}
+
+func newTestObject(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetUID(types.UID("uid-" + name))
+	return obj
+}
+
+// newLabelOwnerController returns a controller decorating the configmaps
+// labeled app=web with cluster-scoped widgets, whose informers hold objects.
+func newLabelOwnerController(t *testing.T, objects ...runtime.Object) *decoratorController {
+	dc := &v1alpha1.DecoratorController{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Spec: v1alpha1.DecoratorControllerSpec{
+			Resources: []v1alpha1.DecoratorControllerResourceRule{{
+				ResourceRule:  v1alpha1.ResourceRule{APIVersion: "v1", Resource: "configmaps"},
+				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			}},
+			Attachments: []v1alpha1.DecoratorControllerAttachmentRule{{
+				ResourceRule:  v1alpha1.ResourceRule{APIVersion: "example.com/v1", Resource: "widgets"},
+				ClusterScoped: pointer.BoolPtr(true),
+			}},
+		},
+	}
+	resources := newTestResources()
+	dynClient := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		configMapsResource: "ConfigMapList",
+		widgetsResource:    "WidgetList",
+	}, objects...)
+	clientset := dynamicclientset.NewForDynamicClient(resources, dynClient)
+	informers := dynamicinformer.NewSharedInformerFactory(clientset, 0)
+
+	c := &decoratorController{
+		dc:              dc,
+		resources:       resources,
+		dynClient:       clientset,
+		parentKinds:     make(common.GroupKindMap),
+		parentInformers: make(common.InformerMap),
+		childInformers:  make(common.InformerMap),
+		sharedKinds:     sharedKinds{},
+		finalizer:       finalizer.NewManager("metacontroller.io/decoratorcontroller-test", true),
+		logger:          logr.Discard(),
+	}
+	var err error
+	if c.parentSelector, err = newDecoratorSelector(resources, dc, nil); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if c.childNamespaces, err = makeChildNamespaces(resources, dc); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	c.parentKinds.Set(schema.GroupKind{Kind: "ConfigMap"}, resources.Get("v1", "configmaps"))
+	for gvr, informerMap := range map[schema.GroupVersionResource]common.InformerMap{configMapsResource: c.parentInformers, widgetsResource: c.childInformers} {
+		informer, err := informers.Resource(gvr.GroupVersion().String(), gvr.Resource)
+		if err != nil {
+			t.Fatalf("err should be nil, got: %v", err)
+		}
+		t.Cleanup(informer.Close)
+		informerMap.Set(gvr, informer)
+		stopCh := make(chan struct{})
+		synced := cache.WaitForCacheSync(stopCh, informer.Informer().HasSynced)
+		close(stopCh)
+		if !synced {
+			t.Fatalf("%v informer never synced", gvr.Resource)
+		}
+	}
+	return c
+}
+
+// newTarget returns a configmap in the default namespace, with the labels of
+// the targets of newLabelOwnerController if selected.
+func newTarget(name string, selected bool, finalizers ...string) *unstructured.Unstructured {
+	target := newTestObject("v1", "ConfigMap", "default", name)
+	if selected {
+		target.SetLabels(map[string]string{"app": "web"})
+	}
+	target.SetFinalizers(finalizers)
+	return target
+}
+
+// newWidget returns a widget attached by the test controller, owned through
+// labels by owner if not nil.
+func newWidget(name string, owner *unstructured.Unstructured) *unstructured.Unstructured {
+	widget := newTestObject("example.com/v1", "Widget", "", name)
+	widget.SetAnnotations(map[string]string{decoratorControllerAnnotation: "test"})
+	if owner != nil {
+		common.SetLabelOwner(owner, widget)
+	}
+	return widget
+}
+
+func TestMakeChildNamespaces(t *testing.T) {
+	resources := newTestResources()
+	tests := []struct {
+		name          string
+		target        string
+		attachment    string
+		clusterScoped *bool
+		wantErr       bool
+		wantEnabled   bool
+		wantLabels    bool
+	}{
+		{
+			name:          "cluster-scoped attachment of namespaced targets",
+			target:        "configmaps",
+			attachment:    "widgets",
+			clusterScoped: pointer.BoolPtr(true),
+			wantEnabled:   true,
+			wantLabels:    true,
+		},
+		{
+			name:        "cluster-scoped attachment of namespaced targets without opting in",
+			target:      "configmaps",
+			attachment:  "widgets",
+			wantEnabled: false,
+			wantLabels:  true,
+		},
+		{
+			name:          "cluster-scoped attachment of cluster-scoped targets",
+			target:        "widgets",
+			attachment:    "widgets",
+			clusterScoped: pointer.BoolPtr(true),
+			wantEnabled:   false,
+		},
+		{
+			name:       "namespaced attachment",
+			target:     "configmaps",
+			attachment: "secrets",
+		},
+		{
+			name:          "clusterScoped on a namespaced attachment",
+			target:        "configmaps",
+			attachment:    "secrets",
+			clusterScoped: pointer.BoolPtr(true),
+			wantErr:       true,
+		},
+		{
+			name:       "unknown attachment",
+			target:     "configmaps",
+			attachment: "gadgets",
+			wantErr:    true,
+		},
+	}
+	apiVersions := map[string]string{"configmaps": "v1", "secrets": "v1", "widgets": "example.com/v1", "gadgets": "example.com/v1"}
+	kinds := map[string]string{"secrets": "Secret", "widgets": "Widget", "gadgets": "Gadget"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dc := &v1alpha1.DecoratorController{Spec: v1alpha1.DecoratorControllerSpec{
+				Resources: []v1alpha1.DecoratorControllerResourceRule{{
+					ResourceRule: v1alpha1.ResourceRule{APIVersion: apiVersions[tt.target], Resource: tt.target},
+				}},
+				Attachments: []v1alpha1.DecoratorControllerAttachmentRule{{
+					ResourceRule:  v1alpha1.ResourceRule{APIVersion: apiVersions[tt.attachment], Resource: tt.attachment},
+					ClusterScoped: tt.clusterScoped,
+				}},
+			}}
+			namespaces, err := makeChildNamespaces(resources, dc)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err should be nil, got: %v", err)
+			}
+			apiGroup, _ := common.ParseAPIVersion(apiVersions[tt.attachment])
+			if got := namespaces.Enabled(apiGroup, kinds[tt.attachment]); got != tt.wantEnabled {
+				t.Errorf("expected Enabled to be %v, got %v", tt.wantEnabled, got)
+			}
+			target := newTestObject(apiVersions[tt.target], "", "default", "target")
+			if tt.target == "widgets" {
+				target.SetNamespace("")
+			}
+			attachment := newTestObject(apiVersions[tt.attachment], kinds[tt.attachment], "", "attachment")
+			if tt.attachment == "secrets" {
+				attachment.SetNamespace("default")
+			}
+			if got := namespaces.IsLabelOwned(target, attachment); got != tt.wantLabels {
+				t.Errorf("expected IsLabelOwned to be %v, got %v", tt.wantLabels, got)
+			}
+		})
+	}
+}
+
+func TestResolveLabelOwner(t *testing.T) {
+	selected := newTarget("selected", true)
+	unselected := newTarget("unselected", false)
+	finalizing := newTarget("finalizing", false, "metacontroller.io/decoratorcontroller-test")
+	c := newLabelOwnerController(t, selected, unselected, finalizing)
+
+	replaced := selected.DeepCopy()
+	replaced.SetUID("uid-replaced")
+	missing := newTarget("missing", true)
+	clusterScoped := newTestObject("example.com/v1", "Widget", "", "cluster-scoped")
+
+	tests := []struct {
+		name   string
+		widget *unstructured.Unstructured
+		want   *unstructured.Unstructured
+	}{
+		{name: "selected target", widget: newWidget("w", selected), want: selected},
+		{name: "target being finalized", widget: newWidget("w", finalizing), want: finalizing},
+		{name: "target no longer selected", widget: newWidget("w", unselected)},
+		{name: "uid mismatch", widget: newWidget("w", replaced)},
+		{name: "missing target", widget: newWidget("w", missing)},
+		{name: "cluster-scoped owner", widget: newWidget("w", clusterScoped)},
+		{name: "no owner labels", widget: newWidget("w", nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := c.resolveLabelOwner(tt.widget)
+			switch {
+			case tt.want == nil && got != nil:
+				t.Errorf("expected no owner, got: %v/%v", got.GetNamespace(), got.GetName())
+			case tt.want != nil && (got == nil || got.GetUID() != tt.want.GetUID()):
+				t.Errorf("expected owner %v, got: %v", tt.want.GetName(), got)
+			}
+		})
+	}
+}
+
+func TestGetChildren_labelOwned(t *testing.T) {
+	target := newTarget("target", true)
+	other := newTarget("other", true)
+	owned := newWidget("owned", target)
+	otherOwned := newWidget("other-owned", other)
+	otherController := newWidget("other-controller", target)
+	otherController.SetAnnotations(map[string]string{decoratorControllerAnnotation: "other"})
+	ownerRef := newWidget("owner-ref", nil)
+	ownerRef.SetOwnerReferences([]metav1.OwnerReference{*common.MakeControllerRef(target)})
+	c := newLabelOwnerController(t, target, other, owned, otherOwned, otherController, ownerRef)
+
+	children, err := c.getChildren(target)
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	widgets := children.List()
+	if len(widgets) != 1 || widgets[0].GetName() != "owned" {
+		var names []string
+		for _, widget := range widgets {
+			names = append(names, widget.GetName())
+		}
+		t.Errorf("expected only the widget owned through labels by the target, got: %v", names)
+	}
+}
+
+func TestFinalizeLabelOwnedChildren(t *testing.T) {
+	target := newTarget("target", true)
+	c := newLabelOwnerController(t)
+	widget := newWidget("owned", target)
+
+	tests := []struct {
+		name     string
+		observed []*unstructured.Unstructured
+		want     bool
+	}{
+		{name: "attachment still exists", observed: []*unstructured.Unstructured{widget}, want: true},
+		{name: "attachment deleted", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			observed := common.MakeRelativeObjectMap(target, tt.observed)
+			desired := common.MakeRelativeObjectMap(target, []*unstructured.Unstructured{widget.DeepCopy()})
+			if got := c.finalizeLabelOwnedChildren(target, observed, desired); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+			if len(desired.List()) != 0 {
+				t.Errorf("expected the attachments owned through labels to be left out of the desired ones, got: %v", desired.List())
+			}
+		})
+	}
+}
+
+func TestSyncParentObject_paused(t *testing.T) {
+	paused := newTarget("paused", true)
+	paused.SetAnnotations(map[string]string{common.PausedAnnotation: "true"})
+
+	tests := []struct {
+		name       string
+		controller *bool
+		target     *unstructured.Unstructured
+		expected   string
+	}{
+		{name: "parent paused", target: paused, expected: "ParentPaused"},
+		{name: "controller paused", controller: pointer.BoolPtr(true), target: newTarget("target", true), expected: "ControllerPaused"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newLabelOwnerController(t, tt.target)
+			c.dc.Spec.Paused = tt.controller
+
+			if err := c.syncParentObject(context.TODO(), tt.target); err != nil {
+				t.Fatalf("err should be nil, got: %v", err)
+			}
+
+			client, err := c.dynClient.Resource("v1", "configmaps")
+			if err != nil {
+				t.Fatalf("err should be nil, got: %v", err)
+			}
+			target, err := client.Namespace("default").Get(context.TODO(), tt.target.GetName(), metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("err should be nil, got: %v", err)
+			}
+			if len(target.GetFinalizers()) != 0 {
+				t.Errorf("expected the paused target to be left alone, got finalizers %v", target.GetFinalizers())
+			}
+			conditions, _, _ := unstructured.NestedSlice(target.Object, "status", "conditions")
+			if len(conditions) != 1 {
+				t.Fatalf("expected a Paused condition, got: %v", conditions)
+			}
+			condition := conditions[0].(map[string]interface{})
+			if condition["type"] != common.PausedCondition || condition["reason"] != tt.expected {
+				t.Errorf("expected a Paused condition with reason %q, got: %v", tt.expected, condition)
+			}
+		})
+	}
+}
@@ -17,21 +17,31 @@ limitations under the License.
 package decorator
 
 import (
+	"context"
 	"fmt"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/klog/v2"
 
 	"metacontroller/pkg/apis/metacontroller/v1alpha1"
 	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/hooks"
 )
 
 // SyncHookRequest is the object sent as JSON to the sync hook.
 type SyncHookRequest struct {
-	Controller  *v1alpha1.DecoratorController `json:"controller"`
+	Controller  *v1alpha1.DecoratorController `json:"controller,omitempty"`
 	Object      *unstructured.Unstructured    `json:"object"`
 	Attachments common.RelativeObjectMap      `json:"attachments"`
 	Related     common.RelativeObjectMap      `json:"related"`
 	Finalizing  bool                          `json:"finalizing"`
+
+	// SyncToken is the syncToken of the last sync response which was fully
+	// applied, if any.
+	SyncToken string `json:"syncToken,omitempty"`
+
+	// Derived holds the values of the controller's derivedFields.
+	Derived map[string]interface{} `json:"derived,omitempty"`
 }
 
 // SyncHookResponse is the expected format of the JSON response from the sync hook.
@@ -41,18 +51,42 @@ type SyncHookResponse struct {
 	Status      map[string]interface{}       `json:"status"`
 	Attachments []*unstructured.Unstructured `json:"attachments"`
 
+	// AttachmentSubresources are the desired contents of subresources of
+	// attachments, like their scale, merged into them apart from the
+	// attachments themselves.
+	AttachmentSubresources []common.ChildSubresource `json:"attachmentSubresources"`
+
+	// TargetPatch is a JSON merge patch of the object, which may only change
+	// the fields within the controller's targetPatchPaths.
+	TargetPatch map[string]interface{} `json:"targetPatch,omitempty"`
+
 	ResyncAfterSeconds float64 `json:"resyncAfterSeconds"`
 
+	// SyncToken is an opaque value sent back in the next sync request.
+	SyncToken string `json:"syncToken"`
+	// NotModified means nothing changed since the sync response which returned
+	// the request's SyncToken, so the object and attachments are left untouched.
+	NotModified bool `json:"notModified"`
+
 	// Finalized is only used by the finalize hook.
 	Finalized bool `json:"finalized"`
 }
 
-func (c *decoratorController) callHook(request *SyncHookRequest) (*SyncHookResponse, error) {
+func (c *decoratorController) callHook(ctx context.Context, request *SyncHookRequest) (*SyncHookResponse, error) {
 	if c.dc.Spec.Hooks == nil {
 		return nil, fmt.Errorf("no hooks defined")
 	}
 
 	var response SyncHookResponse
+	ctx = hooks.WithParent(ctx, request.Object)
+	derived, err := c.derivedFields.Evaluate(map[string]interface{}{
+		"object":      common.ObjectVariable(request.Object),
+		"attachments": common.RelativeObjectMapVariable(request.Attachments),
+	})
+	if err != nil {
+		return nil, err
+	}
+	request.Derived = derived
 
 	// First check if we should instead call the finalize hook,
 	// which has the same API as the sync hook except that it's
@@ -66,16 +100,81 @@ func (c *decoratorController) callHook(request *SyncHookRequest) (*SyncHookRespo
 		(request.Object.GetDeletionTimestamp() != nil || !c.parentSelector.Matches(request.Object)) {
 		// Finalize
 		request.Finalizing = true
-		if err := c.finalizeHook.Execute(request, &response); err != nil {
+		request.SyncToken = ""
+		if err := c.finalizeHook.Execute(ctx, request, &response); err != nil {
 			return nil, fmt.Errorf("finalize hook failed: %w", err)
 		}
 	} else {
 		// Sync
 		request.Finalizing = false
-		if err := c.syncHook.Execute(request, &response); err != nil {
+		if err := c.syncHook.Execute(ctx, request, &response); err != nil {
 			return nil, fmt.Errorf("sync hook failed: %w", err)
 		}
 	}
+	if response.NotModified && request.SyncToken == "" {
+		return nil, fmt.Errorf("invalid hook response: notModified requires a syncToken in the request")
+	}
 
 	return &response, nil
 }
+
+// EventsHookRequest is the object sent as JSON to the events hook.
+type EventsHookRequest struct {
+	Controller  *v1alpha1.DecoratorController `json:"controller"`
+	Object      *unstructured.Unstructured    `json:"object"`
+	Attachments []common.ChildResult          `json:"attachments"`
+}
+
+// EventsHookResponse is the expected format of the JSON response from the
+// events hook. It has no fields, since the response is ignored.
+type EventsHookResponse struct{}
+
+// callEventsHook reports the results of applying the attachments of object.
+// It only logs errors, since attachments are already applied.
+func (c *decoratorController) callEventsHook(ctx context.Context, object *unstructured.Unstructured, results []common.ChildResult) {
+	if !c.eventsHook.IsEnabled() || len(results) == 0 {
+		return
+	}
+	request := &EventsHookRequest{
+		Controller:  c.dc,
+		Object:      object,
+		Attachments: results,
+	}
+	if err := c.eventsHook.Execute(hooks.WithParent(ctx, object), request, &EventsHookResponse{}); err != nil {
+		c.logger.Error(err, "Events hook failed", "object", klog.KObj(object))
+	}
+}
+
+// ApplyErrorHookRequest is the object sent as JSON to the applyError hook.
+type ApplyErrorHookRequest struct {
+	Controller *v1alpha1.DecoratorController `json:"controller"`
+	Object     *unstructured.Unstructured    `json:"object"`
+	Failures   []common.ChildFailure         `json:"failures"`
+}
+
+// ApplyErrorHookResponse is the expected format of the JSON response from the
+// applyError hook.
+type ApplyErrorHookResponse struct {
+	// Handled means the failures don't need to be retried with backoff.
+	Handled bool `json:"handled"`
+}
+
+// callApplyErrorHook reports the failed operations on the attachments of
+// object. It returns nil if the hook isn't enabled or failed.
+func (c *decoratorController) callApplyErrorHook(ctx context.Context, object *unstructured.Unstructured, results []common.ChildResult) *ApplyErrorHookResponse {
+	failures := common.ChildFailures(results)
+	if !c.applyErrorHook.IsEnabled() || len(failures) == 0 {
+		return nil
+	}
+	request := &ApplyErrorHookRequest{
+		Controller: c.dc,
+		Object:     object,
+		Failures:   failures,
+	}
+	var response ApplyErrorHookResponse
+	if err := c.applyErrorHook.Execute(hooks.WithParent(ctx, object), request, &response); err != nil {
+		c.logger.Error(err, "ApplyError hook failed", "object", klog.KObj(object))
+		return nil
+	}
+	return &response
+}
@@ -18,7 +18,10 @@ package decorator
 
 import (
 	"context"
+	"metacontroller/pkg/hooks"
 	"metacontroller/pkg/logging"
+	"metacontroller/pkg/metrics"
+	"time"
 
 	"github.com/go-logr/logr"
 
@@ -40,6 +43,7 @@ import (
 
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type Metacontroller struct {
@@ -53,12 +57,15 @@ type Metacontroller struct {
 
 	decoratorControllers map[string]*decoratorController
 
-	numWorkers int
+	numWorkers        int
+	syncLoops         common.SyncLoopLimits
+	rbacPreflight     bool
+	hookProbeInterval time.Duration
 
 	logger logr.Logger
 }
 
-func NewMetacontroller(controllerContext common.ControllerContext, numWorkers int) *Metacontroller {
+func NewMetacontroller(controllerContext common.ControllerContext, numWorkers int, rbacPreflight bool, hookProbeInterval time.Duration) *Metacontroller {
 	mc := &Metacontroller{
 		k8sClient:     controllerContext.K8sClient,
 		resources:     controllerContext.Resources,
@@ -68,7 +75,10 @@ func NewMetacontroller(controllerContext common.ControllerContext, numWorkers in
 
 		decoratorControllers: make(map[string]*decoratorController),
 
-		numWorkers: numWorkers,
+		numWorkers:        numWorkers,
+		syncLoops:         controllerContext.SyncLoops,
+		rbacPreflight:     rbacPreflight,
+		hookProbeInterval: hookProbeInterval,
 
 		logger: logging.Logger.WithName("decorator"),
 	}
@@ -94,6 +104,8 @@ func (mc *Metacontroller) Reconcile(ctx context.Context, request reconcile.Reque
 				"Stopped controller: %s", c.dc.Name)
 			delete(mc.decoratorControllers, decoratorControllerName)
 		}
+		hooks.ForgetProbes(decoratorControllerName, common.DecoratorController)
+		metrics.ForgetAppliedGeneration(decoratorControllerName, common.DecoratorController)
 		return reconcile.Result{}, nil
 	}
 	if err != nil {
@@ -104,17 +116,198 @@ func (mc *Metacontroller) Reconcile(ctx context.Context, request reconcile.Reque
 			"[%s] sync error - %s", dc.Name, err)
 		return reconcile.Result{}, err
 	}
+	// The running controller keeps the spec with the settings of its
+	// ControllerGroup, so it's restarted when the group changes.
+	if err := mc.syncManagedWebhook(ctx, &dc); err != nil {
+		mc.eventRecorder.Eventf(
+			&dc, v1.EventTypeWarning,
+			events.ReasonSyncError,
+			"[%s] Sync error - %s", dc.Name, err)
+		return reconcile.Result{}, err
+	}
+	if err := mc.applyControllerGroup(ctx, &dc); err != nil {
+		mc.eventRecorder.Eventf(
+			&dc,
+			v1.EventTypeWarning,
+			events.ReasonSyncError,
+			"[%s] sync error - %s", dc.Name, err)
+		return reconcile.Result{}, err
+	}
+	if _, running := mc.decoratorControllers[dc.Name]; !running {
+		unmet, err := common.UnmetDependencies(ctx, mc.k8sClient, dc.Spec.DependsOn)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if len(unmet) > 0 {
+			mc.logger.Info("Waiting for dependencies", "name", decoratorControllerName, "dependencies", unmet)
+			return reconcile.Result{RequeueAfter: common.DependencyRecheckInterval}, mc.updateConditions(ctx, &dc, unmet)
+		}
+	}
+	if mc.rbacPreflight && mc.needsStart(&dc) {
+		missing, err := common.MissingRBACRules(ctx, mc.k8sClient, common.DecoratorControllerRBACRules(&dc))
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if len(missing) > 0 {
+			mc.logger.Info("Missing RBAC permissions", "name", decoratorControllerName, "rules", missing)
+			return reconcile.Result{RequeueAfter: common.RBACRecheckInterval}, mc.updateMissingRBAC(ctx, &dc, missing)
+		}
+	}
 	reconcileErr := mc.reconcileDecoratorController(&dc)
-	return reconcile.Result{}, reconcileErr
+	if reconcileErr != nil {
+		return reconcile.Result{}, reconcileErr
+	}
+	if err := mc.updateConditions(ctx, &dc, nil); err != nil {
+		return reconcile.Result{}, err
+	}
+	if err := mc.updateSyncStatus(ctx, &dc); err != nil {
+		return reconcile.Result{}, err
+	}
+	if mc.hookProbeInterval <= 0 {
+		// Refresh the status again after the interval.
+		return reconcile.Result{RequeueAfter: common.StatusRefreshInterval}, nil
+	}
+	// Probe the webhooks again after the interval.
+	unreachable := hooks.ProbeWebhooks(ctx, dc.Name, common.DecoratorController, dcWebhooks(&dc))
+	return reconcile.Result{RequeueAfter: mc.hookProbeInterval}, mc.updateHooksReachable(ctx, &dc, unreachable)
+}
+
+func (mc *Metacontroller) updateConditions(ctx context.Context, dc *v1alpha1.DecoratorController, unmet []string) error {
+	changed := common.SetDependencyConditions(&dc.Status.Conditions, dc.Generation, unmet)
+	if mc.rbacPreflight && len(unmet) == 0 {
+		changed = common.SetMissingRBACCondition(&dc.Status.Conditions, dc.Generation, nil) || changed
+	}
+	if len(unmet) == 0 && dc.Status.ObservedGeneration != dc.Generation {
+		// The running controller uses the current spec.
+		dc.Status.ObservedGeneration = dc.Generation
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return mc.k8sClient.Status().Update(ctx, dc)
+}
+
+// updateSyncStatus records in the status of dc how many targets it matches,
+// and which of them fail to sync.
+func (mc *Metacontroller) updateSyncStatus(ctx context.Context, dc *v1alpha1.DecoratorController) error {
+	running, ok := mc.decoratorControllers[dc.Name]
+	if !ok {
+		return nil
+	}
+	matched := running.matchedTargets()
+	failing, lastErrors := running.health.Failing()
+	if dc.Status.Targets == matched && dc.Status.FailingTargets == failing && apiequality.Semantic.DeepEqual(dc.Status.LastErrors, lastErrors) {
+		return nil
+	}
+	dc.Status.Targets = matched
+	dc.Status.FailingTargets = failing
+	dc.Status.LastErrors = lastErrors
+	return mc.k8sClient.Status().Update(ctx, dc)
+}
+
+func (mc *Metacontroller) updateMissingRBAC(ctx context.Context, dc *v1alpha1.DecoratorController, missing []common.RBACRule) error {
+	if !common.SetMissingRBACCondition(&dc.Status.Conditions, dc.Generation, missing) {
+		return nil
+	}
+	return mc.k8sClient.Status().Update(ctx, dc)
+}
+
+func (mc *Metacontroller) updateHooksReachable(ctx context.Context, dc *v1alpha1.DecoratorController, unreachable []string) error {
+	if len(unreachable) > 0 {
+		mc.logger.Info("Unreachable webhooks", "name", dc.Name, "webhooks", unreachable)
+	}
+	if !common.SetHooksReachableCondition(&dc.Status.Conditions, dc.Generation, unreachable) {
+		return nil
+	}
+	return mc.k8sClient.Status().Update(ctx, dc)
+}
+
+// dcWebhooks returns the hooks of dc which can be probed.
+func dcWebhooks(dc *v1alpha1.DecoratorController) map[common.HookType]*v1alpha1.Hook {
+	if dc.Spec.Hooks == nil {
+		return nil
+	}
+	return map[common.HookType]*v1alpha1.Hook{
+		common.CustomizeHook:  dc.Spec.Hooks.Customize,
+		common.SyncHook:       dc.Spec.Hooks.Sync,
+		common.FinalizeHook:   dc.Spec.Hooks.Finalize,
+		common.EventsHook:     dc.Spec.Hooks.Events,
+		common.ApplyErrorHook: dc.Spec.Hooks.ApplyError,
+	}
+}
+
+// applyControllerGroup fills in the webhook settings dc takes from its
+// ControllerGroup.
+func (mc *Metacontroller) applyControllerGroup(ctx context.Context, dc *v1alpha1.DecoratorController) error {
+	return common.ApplyControllerGroup(ctx, mc.k8sClient, dc.Spec.ControllerGroupRef, dcHookList(dc), &dc.Spec.RateLimit, &dc.Spec.HookTransport)
+}
+
+// syncManagedWebhook applies the managed webhook of dc, records it in the
+// status and points the hooks of dc which only set a path at it.
+func (mc *Metacontroller) syncManagedWebhook(ctx context.Context, dc *v1alpha1.DecoratorController) error {
+	if dc.Spec.ManagedWebhook == nil && dc.Status.ManagedWebhook == nil {
+		return nil
+	}
+	ownerRef := *metav1.NewControllerRef(dc, v1alpha1.SchemeGroupVersion.WithKind("DecoratorController"))
+	status, err := common.SyncManagedWebhook(ctx, mc.k8sClient, common.DecoratorController, dc.Name, ownerRef, dc.Spec.ManagedWebhook, dc.Status.ManagedWebhook)
+	if err != nil {
+		return err
+	}
+	if !apiequality.Semantic.DeepEqual(status, dc.Status.ManagedWebhook) {
+		dc.Status.ManagedWebhook = status
+		if err := mc.k8sClient.Status().Update(ctx, dc); err != nil {
+			return err
+		}
+	}
+	common.ApplyManagedWebhookService(dcHookList(dc), status)
+	return nil
+}
+
+// dcHookList returns all the hooks of dc.
+func dcHookList(dc *v1alpha1.DecoratorController) []*v1alpha1.Hook {
+	h := dc.Spec.Hooks
+	if h == nil {
+		return nil
+	}
+	return []*v1alpha1.Hook{h.Customize, h.Sync, h.Finalize, h.Events, h.ApplyError}
+}
+
+// ControllerGroupRequests returns a request for each DecoratorController in
+// the given ControllerGroup, so they're resynced when it changes.
+func (mc *Metacontroller) ControllerGroupRequests(group client.Object) []reconcile.Request {
+	dcList := v1alpha1.DecoratorControllerList{}
+	if err := mc.k8sClient.List(context.Background(), &dcList); err != nil {
+		mc.logger.Error(err, "Can't list DecoratorControllers", "controllerGroup", group.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, dc := range dcList.Items {
+		if dc.Spec.ControllerGroupRef != nil && dc.Spec.ControllerGroupRef.Name == group.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKey{Name: dc.Name}})
+		}
+	}
+	return requests
+}
+
+// needsStart reports whether dc isn't running yet, or must be restarted
+// because its spec changed.
+func (mc *Metacontroller) needsStart(dc *v1alpha1.DecoratorController) bool {
+	running, ok := mc.decoratorControllers[dc.Name]
+	return !ok || !apiequality.Semantic.DeepEqual(dc.Spec, running.dc.Spec)
 }
 
 func (mc *Metacontroller) reconcileDecoratorController(dc *v1alpha1.DecoratorController) error {
 	if c, ok := mc.decoratorControllers[dc.Name]; ok {
 		// The controller was already started.
 		if apiequality.Semantic.DeepEqual(dc.Spec, c.dc.Spec) {
-			// Nothing has changed.
+			// Nothing has changed, but the log level may be.
+			common.SyncLogLevel(mc.eventRecorder, dc, c.logLevel)
+			metrics.SetAppliedGeneration(dc.Name, common.DecoratorController, dc.Generation)
 			return nil
 		}
+		mc.logger.Info("Applying DecoratorController spec change", "name", dc.Name,
+			"previousGeneration", c.dc.Generation, "generation", dc.Generation)
 		// Stop and remove the controller so it can be recreated.
 		c.Stop()
 		mc.eventRecorder.Eventf(
@@ -123,8 +316,11 @@ func (mc *Metacontroller) reconcileDecoratorController(dc *v1alpha1.DecoratorCon
 			events.ReasonStopped,
 			"Stopped controller: %s", dc.Name)
 		delete(mc.decoratorControllers, dc.Name)
+		metrics.ForgetAppliedGeneration(dc.Name, common.DecoratorController)
 	}
 
+	logLevel := logging.NewLevel()
+	common.SyncLogLevel(mc.eventRecorder, dc, logLevel)
 	c, err := newDecoratorController(
 		mc.resources,
 		mc.dynClient,
@@ -132,7 +328,8 @@ func (mc *Metacontroller) reconcileDecoratorController(dc *v1alpha1.DecoratorCon
 		mc.eventRecorder,
 		dc,
 		mc.numWorkers,
-		mc.logger,
+		mc.syncLoops,
+		logLevel.Logger().WithName("decorator"),
 	)
 	if err != nil {
 		mc.eventRecorder.Eventf(
@@ -142,6 +339,7 @@ func (mc *Metacontroller) reconcileDecoratorController(dc *v1alpha1.DecoratorCon
 			"Cannot create new controller: %s", err.Error())
 		return err
 	}
+	c.logLevel = logLevel
 	c.Start()
 	mc.eventRecorder.Eventf(
 		dc,
@@ -149,5 +347,7 @@ func (mc *Metacontroller) reconcileDecoratorController(dc *v1alpha1.DecoratorCon
 		events.ReasonStarted,
 		"Started controller: %s", dc.Name)
 	mc.decoratorControllers[dc.Name] = c
+	metrics.SetAppliedGeneration(dc.Name, common.DecoratorController, dc.Generation)
+	mc.logger.Info("Applied DecoratorController spec", "name", dc.Name, "generation", dc.Generation)
 	return nil
 }
@@ -0,0 +1,132 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decorator
+
+import (
+	"reflect"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+)
+
+// gateRollingRecreate leaves out the outdated attachments of kinds updated
+// with the RollingRecreate method which exceed the maxUnavailable budget of
+// the target. Since they're left out of both the observed and desired
+// attachments, they're neither updated nor deleted. The returned bool tells
+// whether some attachments were held back; they're recreated by a later sync,
+// once the readiness of the recreated ones changes.
+func (c *decoratorController) gateRollingRecreate(observed, desired common.RelativeObjectMap) (common.RelativeObjectMap, common.RelativeObjectMap, bool, error) {
+	var gatedObserved, gatedDesired common.RelativeObjectMap
+	for gvk, group := range desired {
+		strategy := c.updateStrategy.get(gvk.Group, gvk.Kind)
+		if strategy == nil || strategy.Method != v1alpha1.ChildUpdateRollingRecreate {
+			continue
+		}
+		heldBack, err := c.heldBackRecreates(strategy, observed[gvk], group)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		if len(heldBack) == 0 {
+			continue
+		}
+		if gatedObserved == nil {
+			gatedObserved, gatedDesired = shallowCopy(observed), shallowCopy(desired)
+		}
+		gatedObserved[gvk] = withoutNames(observed[gvk], heldBack)
+		gatedDesired[gvk] = withoutNames(group, heldBack)
+	}
+	if gatedObserved == nil {
+		return observed, desired, false, nil
+	}
+	return gatedObserved, gatedDesired, true, nil
+}
+
+// heldBackRecreates returns the names of the outdated attachments of a kind
+// which can't be recreated yet, because there are already maxUnavailable
+// attachments of that kind which are missing, pending deletion or failing
+// their statusChecks. Attachments are recreated in the order of their names.
+func (c *decoratorController) heldBackRecreates(strategy *v1alpha1.DecoratorControllerAttachmentUpdateStrategy, observed, desired map[string]*unstructured.Unstructured) ([]string, error) {
+	names := make([]string, 0, len(desired))
+	for name := range desired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var outdated []string
+	unavailable := 0
+	for _, name := range names {
+		attachment := observed[name]
+		if attachment == nil || attachment.GetDeletionTimestamp() != nil {
+			unavailable++
+			continue
+		}
+		if err := common.ChildStatusCheck(&strategy.StatusChecks, attachment); err != nil {
+			unavailable++
+		}
+		updated, err := c.applyStrategies.ApplyUpdate(attachment, desired[name])
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(updated.UnstructuredContent(), attachment.UnstructuredContent()) {
+			outdated = append(outdated, name)
+		}
+	}
+
+	maxUnavailable := 1
+	if strategy.MaxUnavailable != nil {
+		var err error
+		maxUnavailable, err = intstr.GetScaledValueFromIntOrPercent(strategy.MaxUnavailable, len(names), false)
+		if err != nil {
+			return nil, err
+		}
+		// Always allow some progress, even if the percentage rounds down to 0.
+		if maxUnavailable < 1 {
+			maxUnavailable = 1
+		}
+	}
+	budget := maxUnavailable - unavailable
+	if budget < 0 {
+		budget = 0
+	}
+	if budget >= len(outdated) {
+		return nil, nil
+	}
+	return outdated[budget:], nil
+}
+
+func shallowCopy(m common.RelativeObjectMap) common.RelativeObjectMap {
+	copied := make(common.RelativeObjectMap, len(m))
+	for gvk, group := range m {
+		copied[gvk] = group
+	}
+	return copied
+}
+
+func withoutNames(group map[string]*unstructured.Unstructured, names []string) map[string]*unstructured.Unstructured {
+	filtered := make(map[string]*unstructured.Unstructured, len(group))
+	for name, obj := range group {
+		filtered[name] = obj
+	}
+	for _, name := range names {
+		delete(filtered, name)
+	}
+	return filtered
+}
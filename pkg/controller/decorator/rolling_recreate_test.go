@@ -0,0 +1,108 @@
+package decorator
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	dynamicapply "metacontroller/pkg/dynamic/apply"
+)
+
+func TestGateRollingRecreate(t *testing.T) {
+	parent := &unstructured.Unstructured{}
+	parent.SetNamespace("ns")
+	newJob := func(name, image string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("batch/v1")
+		obj.SetKind("Job")
+		obj.SetNamespace("ns")
+		obj.SetName(name)
+		_ = unstructured.SetNestedField(obj.Object, image, "spec", "image")
+		return obj
+	}
+	observedJob := func(name, image string) *unstructured.Unstructured {
+		obj := newJob(name, image)
+		_ = dynamicapply.SetLastApplied(obj, newJob(name, image).UnstructuredContent())
+		return obj
+	}
+	withComplete := func(obj *unstructured.Unstructured, status string) *unstructured.Unstructured {
+		_ = unstructured.SetNestedSlice(obj.Object, []interface{}{
+			map[string]interface{}{"type": "Complete", "status": status},
+		}, "status", "conditions")
+		return obj
+	}
+	trueStatus := "True"
+	completeCheck := v1alpha1.ChildUpdateStatusChecks{
+		Conditions: []v1alpha1.StatusConditionCheck{{Type: "Complete", Status: &trueStatus}},
+	}
+
+	tests := []struct {
+		name     string
+		strategy *v1alpha1.DecoratorControllerAttachmentUpdateStrategy
+		observed []*unstructured.Unstructured
+		expected []string
+		waiting  bool
+	}{
+		{
+			name:     "maxUnavailable",
+			strategy: &v1alpha1.DecoratorControllerAttachmentUpdateStrategy{Method: v1alpha1.ChildUpdateRollingRecreate, MaxUnavailable: &intstr.IntOrString{Type: intstr.Int, IntVal: 2}},
+			observed: []*unstructured.Unstructured{observedJob("a", "v1"), observedJob("b", "v1"), observedJob("c", "v1"), observedJob("d", "v1")},
+			expected: []string{"a", "b"},
+			waiting:  true,
+		},
+		{
+			name:     "default budget with missing attachment",
+			strategy: &v1alpha1.DecoratorControllerAttachmentUpdateStrategy{Method: v1alpha1.ChildUpdateRollingRecreate},
+			observed: []*unstructured.Unstructured{observedJob("b", "v1"), observedJob("c", "v1"), observedJob("d", "v1")},
+			expected: []string{"a"},
+			waiting:  true,
+		},
+		{
+			name:     "statusChecks",
+			strategy: &v1alpha1.DecoratorControllerAttachmentUpdateStrategy{Method: v1alpha1.ChildUpdateRollingRecreate, StatusChecks: completeCheck, MaxUnavailable: &intstr.IntOrString{Type: intstr.String, StrVal: "50%"}},
+			observed: []*unstructured.Unstructured{withComplete(observedJob("a", "v2"), "False"), withComplete(observedJob("b", "v1"), "True"), withComplete(observedJob("c", "v1"), "True"), withComplete(observedJob("d", "v1"), "True")},
+			expected: []string{"a", "b"},
+			waiting:  true,
+		},
+		{
+			name:     "InPlace isn't gated",
+			strategy: &v1alpha1.DecoratorControllerAttachmentUpdateStrategy{Method: v1alpha1.ChildUpdateInPlace},
+			observed: []*unstructured.Unstructured{observedJob("a", "v1"), observedJob("b", "v1"), observedJob("c", "v1"), observedJob("d", "v1")},
+			expected: []string{"a", "b", "c", "d"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &decoratorController{
+				updateStrategy:  updateStrategyMap{updateStrategyMapKey("batch", "Job"): tt.strategy},
+				applyStrategies: common.NewChildApplyStrategies("", ""),
+			}
+			observed := common.MakeRelativeObjectMap(parent, tt.observed)
+			desired := common.MakeRelativeObjectMap(parent, []*unstructured.Unstructured{
+				newJob("a", "v2"), newJob("b", "v2"), newJob("c", "v2"), newJob("d", "v2"),
+			})
+
+			_, managedDesired, waiting, err := c.gateRollingRecreate(observed, desired)
+			if err != nil {
+				t.Fatalf("err should be nil, got: %v", err)
+			}
+
+			var names []string
+			for _, obj := range managedDesired.List() {
+				names = append(names, obj.GetName())
+			}
+			sort.Strings(names)
+			if diff := cmp.Diff(tt.expected, names); diff != "" {
+				t.Errorf("unexpected managed attachments (-want +got):\n%s", diff)
+			}
+			if waiting != tt.waiting {
+				t.Errorf("expected waiting %v, got %v", tt.waiting, waiting)
+			}
+		})
+	}
+}
@@ -19,9 +19,12 @@ package decorator
 import (
 	"fmt"
 
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	"metacontroller/pkg/apis/metacontroller/v1alpha1"
 	"metacontroller/pkg/controller/common"
@@ -30,15 +33,47 @@ import (
 
 type decoratorSelector struct {
 	labelSelectors      map[string]labels.Selector
-	annotationSelectors map[string]labels.Selector
+	annotationSelectors map[string]*common.AnnotationSelector
+	namespaceFilters    map[string]*namespaceFilter
+	filterExpressions   map[string]cel.Program
+
+	// excludeNamespaces and excludeObjects apply to the targets of all kinds.
+	excludeNamespaces sets.String
+	excludeObjects    labels.Selector
+
+	// namespaceLabels returns the labels of the namespace with the given name.
+	namespaceLabels func(name string) (map[string]string, error)
+}
+
+// namespaceFilter scopes the targets of a kind to some namespaces, listed or
+// matching a selector. Excluded namespaces never match.
+type namespaceFilter struct {
+	namespaces sets.String
+	exclude    sets.String
+	selector   labels.Selector
 }
 
-func newDecoratorSelector(resources *dynamicdiscovery.ResourceMap, dc *v1alpha1.DecoratorController) (*decoratorSelector, error) {
+// newDecoratorSelector returns the selector of the targets of dc.
+// namespaceLabels is only called for kinds with a namespaceSelector.
+func newDecoratorSelector(resources *dynamicdiscovery.ResourceMap, dc *v1alpha1.DecoratorController, namespaceLabels func(name string) (map[string]string, error)) (*decoratorSelector, error) {
 	ds := &decoratorSelector{
 		labelSelectors:      make(map[string]labels.Selector),
-		annotationSelectors: make(map[string]labels.Selector),
+		annotationSelectors: make(map[string]*common.AnnotationSelector),
+		namespaceFilters:    make(map[string]*namespaceFilter),
+		filterExpressions:   make(map[string]cel.Program),
+		excludeNamespaces:   sets.NewString(dc.Spec.ExcludeNamespaces...),
+		namespaceLabels:     namespaceLabels,
+	}
+	env, err := cel.NewEnv(cel.Declarations(decls.NewVar("object", decls.Dyn)))
+	if err != nil {
+		return nil, err
+	}
+	if dc.Spec.ExcludeObjectsMatching != nil {
+		ds.excludeObjects, err = metav1.LabelSelectorAsSelector(dc.Spec.ExcludeObjectsMatching)
+		if err != nil {
+			return nil, fmt.Errorf("can't convert excludeObjectsMatching selector: %w", err)
+		}
 	}
-	var err error
 
 	for _, parent := range dc.Spec.Resources {
 		// Keep the map by Group and Kind. Ignore Version.
@@ -60,26 +95,56 @@ func newDecoratorSelector(resources *dynamicdiscovery.ResourceMap, dc *v1alpha1.
 			ds.labelSelectors[key] = labels.Everything()
 		}
 
-		// Convert the annotation selector to a label selector, then to internal form.
-		if parent.AnnotationSelector != nil {
-			labelSelector := &metav1.LabelSelector{
-				MatchLabels:      parent.AnnotationSelector.MatchAnnotations,
-				MatchExpressions: parent.AnnotationSelector.MatchExpressions,
+		// Convert the annotation selector to the internal form. A missing one
+		// selects everything.
+		ds.annotationSelectors[key], err = common.NewAnnotationSelector(parent.AnnotationSelector)
+		if err != nil {
+			return nil, fmt.Errorf("can't convert annotation selector for parent resource %q in apiVersion %q: %w", parent.Resource, parent.APIVersion, err)
+		}
+
+		if parent.FilterExpression != "" {
+			ast, issues := env.Compile(parent.FilterExpression)
+			if issues != nil && issues.Err() != nil {
+				return nil, fmt.Errorf("invalid filterExpression for parent resource %q in apiVersion %q: %w", parent.Resource, parent.APIVersion, issues.Err())
 			}
-			ds.annotationSelectors[key], err = metav1.LabelSelectorAsSelector(labelSelector)
+			ds.filterExpressions[key], err = env.Program(ast)
 			if err != nil {
-				return nil, fmt.Errorf("can't convert annotation selector for parent resource %q in apiVersion %q: %w", parent.Resource, parent.APIVersion, err)
+				return nil, fmt.Errorf("invalid filterExpression for parent resource %q in apiVersion %q: %w", parent.Resource, parent.APIVersion, err)
 			}
-		} else {
-			// Add an explicit selector so we can tell the difference between
-			// missing (not a type we care about) and empty (select everything).
-			ds.annotationSelectors[key] = labels.Everything()
 		}
+
+		if parent.NamespaceSelector == nil && len(parent.Namespaces) == 0 && len(parent.ExcludeNamespaces) == 0 {
+			continue
+		}
+		if !resource.Namespaced {
+			return nil, fmt.Errorf("can't scope cluster-scoped parent resource %q in apiVersion %q to namespaces", parent.Resource, parent.APIVersion)
+		}
+		filter := &namespaceFilter{
+			namespaces: sets.NewString(parent.Namespaces...),
+			exclude:    sets.NewString(parent.ExcludeNamespaces...),
+		}
+		if parent.NamespaceSelector != nil {
+			filter.selector, err = metav1.LabelSelectorAsSelector(parent.NamespaceSelector)
+			if err != nil {
+				return nil, fmt.Errorf("can't convert namespace selector for parent resource %q in apiVersion %q: %w", parent.Resource, parent.APIVersion, err)
+			}
+		}
+		ds.namespaceFilters[key] = filter
 	}
 
 	return ds, nil
 }
 
+// needsNamespaceLabels returns whether any kind is scoped by a namespaceSelector.
+func (ds *decoratorSelector) needsNamespaceLabels() bool {
+	for _, filter := range ds.namespaceFilters {
+		if filter.selector != nil {
+			return true
+		}
+	}
+	return false
+}
+
 func (ds *decoratorSelector) Matches(obj *unstructured.Unstructured) bool {
 	// Look up the label and annotation selectors for this object.
 	// Use only Group and Kind. Ignore Version.
@@ -92,10 +157,66 @@ func (ds *decoratorSelector) Matches(obj *unstructured.Unstructured) bool {
 		// This object is not a kind we care about, so it doesn't match.
 		return false
 	}
+	if ds.isExcluded(obj) {
+		return false
+	}
 
-	// It must match both selectors.
+	// It must match both selectors, be in one of the selected namespaces,
+	// and satisfy the filterExpression.
 	return labelSelector.Matches(labels.Set(obj.GetLabels())) &&
-		annotationSelector.Matches(labels.Set(obj.GetAnnotations()))
+		annotationSelector.Matches(obj.GetAnnotations()) &&
+		ds.matchesNamespace(key, obj.GetNamespace()) &&
+		ds.matchesFilterExpression(key, obj)
+}
+
+// isExcluded returns whether obj is in one of the excluded namespaces, or
+// matches the excludeObjectsMatching selector of the controller.
+func (ds *decoratorSelector) isExcluded(obj *unstructured.Unstructured) bool {
+	if obj.GetNamespace() != "" && ds.excludeNamespaces.Has(obj.GetNamespace()) {
+		return true
+	}
+	return ds.excludeObjects != nil && ds.excludeObjects.Matches(labels.Set(obj.GetLabels()))
+}
+
+// matchesFilterExpression returns whether the filterExpression of the kind
+// evaluates to true for obj. Objects for which it fails, e.g. because a field
+// it reads is missing, don't match.
+func (ds *decoratorSelector) matchesFilterExpression(key string, obj *unstructured.Unstructured) bool {
+	program := ds.filterExpressions[key]
+	if program == nil {
+		return true
+	}
+	out, _, err := program.Eval(map[string]interface{}{"object": obj.UnstructuredContent()})
+	if err != nil {
+		return false
+	}
+	matches, ok := out.Value().(bool)
+	return ok && matches
+}
+
+func (ds *decoratorSelector) matchesNamespace(key, namespace string) bool {
+	filter := ds.namespaceFilters[key]
+	if filter == nil {
+		return true
+	}
+	if filter.exclude.Has(namespace) {
+		return false
+	}
+	if filter.namespaces.Has(namespace) {
+		return true
+	}
+	if filter.selector == nil {
+		// Only the listed namespaces are selected, unless there are none,
+		// which means all but the excluded ones.
+		return filter.namespaces.Len() == 0
+	}
+	namespaceLabels, err := ds.namespaceLabels(namespace)
+	if err != nil {
+		// The namespace is gone, or not in the cache yet. Its objects are
+		// synced again once its labels are known.
+		return false
+	}
+	return filter.selector.Matches(labels.Set(namespaceLabels))
 }
 
 func selectorMapKey(apiGroup, kind string) string {
@@ -0,0 +1,154 @@
+package decorator
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"metacontroller/pkg/controller/common"
+)
+
+func TestDecoratorSelector_MatchesNamespace(t *testing.T) {
+	namespaceLabels := map[string]map[string]string{
+		"tenant-a": {"tenant": "true"},
+		"tenant-b": {"tenant": "true"},
+		"system":   {},
+	}
+	tenants := labels.SelectorFromSet(labels.Set{"tenant": "true"})
+	tests := []struct {
+		name      string
+		filter    *namespaceFilter
+		namespace string
+		expected  bool
+	}{
+		{"no filter", nil, "system", true},
+		{"listed", &namespaceFilter{namespaces: sets.NewString("system")}, "system", true},
+		{"not listed", &namespaceFilter{namespaces: sets.NewString("system")}, "tenant-a", false},
+		{"not excluded", &namespaceFilter{exclude: sets.NewString("system")}, "tenant-a", true},
+		{"excluded", &namespaceFilter{exclude: sets.NewString("system")}, "system", false},
+		{"selected", &namespaceFilter{selector: tenants}, "tenant-a", true},
+		{"not selected", &namespaceFilter{selector: tenants}, "system", false},
+		{"listed but not selected", &namespaceFilter{namespaces: sets.NewString("system"), selector: tenants}, "system", true},
+		{"selected but excluded", &namespaceFilter{exclude: sets.NewString("tenant-b"), selector: tenants}, "tenant-b", false},
+		{"unknown namespace", &namespaceFilter{selector: tenants}, "gone", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := selectorMapKey("", "ConfigMap")
+			ds := &decoratorSelector{
+				labelSelectors:      map[string]labels.Selector{key: labels.Everything()},
+				annotationSelectors: map[string]*common.AnnotationSelector{key: {}},
+				namespaceFilters:    map[string]*namespaceFilter{},
+				namespaceLabels: func(name string) (map[string]string, error) {
+					if namespaceLabels, ok := namespaceLabels[name]; ok {
+						return namespaceLabels, nil
+					}
+					return nil, fmt.Errorf("namespace %q not found", name)
+				},
+			}
+			if tt.filter != nil {
+				ds.namespaceFilters[key] = tt.filter
+			}
+			obj := &unstructured.Unstructured{}
+			obj.SetAPIVersion("v1")
+			obj.SetKind("ConfigMap")
+			obj.SetNamespace(tt.namespace)
+			obj.SetName("test")
+
+			if got := ds.Matches(obj); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDecoratorSelector_MatchesFilterExpression(t *testing.T) {
+	env, err := cel.NewEnv(cel.Declarations(decls.NewVar("object", decls.Dyn)))
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	ast, issues := env.Compile(`object.spec.type == 'LoadBalancer' && object.spec.ports.exists(p, p.port == 443)`)
+	if issues != nil && issues.Err() != nil {
+		t.Fatalf("err should be nil, got: %v", issues.Err())
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	key := selectorMapKey("", "Service")
+	ds := &decoratorSelector{
+		labelSelectors:      map[string]labels.Selector{key: labels.Everything()},
+		annotationSelectors: map[string]*common.AnnotationSelector{key: {}},
+		filterExpressions:   map[string]cel.Program{key: program},
+	}
+	newService := func(spec map[string]interface{}) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+		obj.SetAPIVersion("v1")
+		obj.SetKind("Service")
+		obj.SetNamespace("default")
+		obj.SetName("test")
+		return obj
+	}
+	tests := []struct {
+		name     string
+		spec     map[string]interface{}
+		expected bool
+	}{
+		{"matches", map[string]interface{}{
+			"type":  "LoadBalancer",
+			"ports": []interface{}{map[string]interface{}{"port": int64(80)}, map[string]interface{}{"port": int64(443)}},
+		}, true},
+		{"other type", map[string]interface{}{
+			"type":  "ClusterIP",
+			"ports": []interface{}{map[string]interface{}{"port": int64(443)}},
+		}, false},
+		{"field missing", map[string]interface{}{"type": "LoadBalancer"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ds.Matches(newService(tt.spec)); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDecoratorSelector_Exclude(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		labels    map[string]string
+		expected  bool
+	}{
+		{"not excluded", "default", nil, true},
+		{"excluded namespace", "kube-system", nil, false},
+		{"excluded object", "default", map[string]string{"example.com/skip": "true"}, false},
+		{"cluster-scoped", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := selectorMapKey("", "ConfigMap")
+			ds := &decoratorSelector{
+				labelSelectors:      map[string]labels.Selector{key: labels.Everything()},
+				annotationSelectors: map[string]*common.AnnotationSelector{key: {}},
+				excludeNamespaces:   sets.NewString("kube-system", ""),
+				excludeObjects:      labels.SelectorFromSet(labels.Set{"example.com/skip": "true"}),
+			}
+			obj := &unstructured.Unstructured{}
+			obj.SetAPIVersion("v1")
+			obj.SetKind("ConfigMap")
+			obj.SetNamespace(tt.namespace)
+			obj.SetName("test")
+			obj.SetLabels(tt.labels)
+
+			if got := ds.Matches(obj); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package decorator
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
+)
+
+// sharedKinds holds the attachment kinds which are shared between targets.
+// Each target desiring a shared attachment has an ownerReference, which isn't
+// a controllerRef, to it. It's deleted once no target desires it anymore,
+// either by Metacontroller or by the garbage collector.
+type sharedKinds map[string]bool
+
+func makeSharedKinds(resources *dynamicdiscovery.ResourceMap, dc *v1alpha1.DecoratorController) (sharedKinds, error) {
+	shared := make(sharedKinds)
+	for _, child := range dc.Spec.Attachments {
+		if child.Shared == nil || !*child.Shared {
+			continue
+		}
+		if child.ClusterScoped != nil && *child.ClusterScoped {
+			return nil, fmt.Errorf("shared and clusterScoped can't both be set for child resource %q in %v", child.Resource, child.APIVersion)
+		}
+		resource := resources.Get(child.APIVersion, child.Resource)
+		if resource == nil {
+			return nil, fmt.Errorf("can't find child resource %q in %v", child.Resource, child.APIVersion)
+		}
+		apiGroup, _ := common.ParseAPIVersion(child.APIVersion)
+		shared[updateStrategyMapKey(apiGroup, resource.Kind)] = true
+	}
+	return shared, nil
+}
+
+func (k sharedKinds) has(key common.GroupVersionKind) bool {
+	return k[updateStrategyMapKey(key.Group, key.Kind)]
+}
+
+// split moves the shared attachments out of observed and desired, so they're
+// not managed like the attachments owned by a single target.
+func (k sharedKinds) split(observed, desired common.RelativeObjectMap) (sharedObserved, sharedDesired common.RelativeObjectMap) {
+	sharedObserved, sharedDesired = make(common.RelativeObjectMap), make(common.RelativeObjectMap)
+	for key, group := range observed {
+		if k.has(key) {
+			sharedObserved[key] = group
+			delete(observed, key)
+		}
+	}
+	for key, group := range desired {
+		if k.has(key) {
+			sharedDesired[key] = group
+			delete(desired, key)
+		}
+	}
+	return sharedObserved, sharedDesired
+}
+
+// isSharedBy returns true if obj is a shared attachment referenced by parent.
+func isSharedBy(parent, obj metav1.Object) bool {
+	for _, ownerRef := range obj.GetOwnerReferences() {
+		if ownerRef.UID == parent.GetUID() {
+			return true
+		}
+	}
+	return false
+}
+
+func makeSharedOwnerRef(parent *unstructured.Unstructured) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion: parent.GetAPIVersion(),
+		Kind:       parent.GetKind(),
+		Name:       parent.GetName(),
+		UID:        parent.GetUID(),
+	}
+}
+
+// manageSharedAttachments adds a reference from parent to each desired shared
+// attachment, creating those which don't exist yet, and removes it from those
+// which aren't desired anymore, deleting them if no other target references
+// them. Existing shared attachments aren't updated.
+func (c *decoratorController) manageSharedAttachments(parent *unstructured.Unstructured, observed, desired common.RelativeObjectMap) error {
+	var errs []error
+	get := common.CachedChildGetter(c.dynClient, c.childInformers)
+	for key, group := range desired {
+		client, err := c.dynClient.Kind(key.GroupVersion().String(), key.Kind)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		client = client.Audited(c.auditRecorder(parent))
+		for name, obj := range group {
+			if observed[key][name] != nil {
+				continue
+			}
+			namespace := obj.GetNamespace()
+			if namespace == "" && client.Namespaced {
+				namespace = parent.GetNamespace()
+			}
+			existing := get(key, namespace, obj.GetName())
+			if existing == nil {
+				c.logger.Info("Creating shared attachment", "object", klog.KObj(parent), "attachment", klog.KRef(namespace, obj.GetName()))
+				obj.SetOwnerReferences(append(obj.GetOwnerReferences(), makeSharedOwnerRef(parent)))
+				if _, err := client.Namespace(namespace).Create(context.TODO(), obj, metav1.CreateOptions{}); err != nil {
+					errs = append(errs, fmt.Errorf("can't create shared attachment %v %v/%v: %w", key.Kind, namespace, obj.GetName(), err))
+				}
+				continue
+			}
+			if existing.GetAnnotations()[decoratorControllerAnnotation] != c.dc.Name || metav1.GetControllerOf(existing) != nil {
+				errs = append(errs, fmt.Errorf("shared attachment %v %v/%v already exists, but wasn't created as a shared attachment by %v", key.Kind, namespace, obj.GetName(), c.dc.Name))
+				continue
+			}
+			_, err := client.Namespace(namespace).AtomicUpdate(existing, func(obj *unstructured.Unstructured) bool {
+				if isSharedBy(parent, obj) {
+					return false
+				}
+				obj.SetOwnerReferences(append(obj.GetOwnerReferences(), makeSharedOwnerRef(parent)))
+				return true
+			})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("can't reference shared attachment %v %v/%v: %w", key.Kind, namespace, obj.GetName(), err))
+			}
+		}
+	}
+	for key, group := range observed {
+		client, err := c.dynClient.Kind(key.GroupVersion().String(), key.Kind)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		client = client.Audited(c.auditRecorder(parent))
+		for name, obj := range group {
+			if desired[key][name] != nil {
+				continue
+			}
+			if err := c.releaseSharedAttachment(client.Namespace(obj.GetNamespace()), parent, obj); err != nil {
+				errs = append(errs, fmt.Errorf("can't release shared attachment %v %v/%v: %w", key.Kind, obj.GetNamespace(), obj.GetName(), err))
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// releaseSharedAttachment removes the reference from parent to obj, and
+// deletes obj if it was the last one.
+func (c *decoratorController) releaseSharedAttachment(client *dynamicclientset.ResourceClient, parent, obj *unstructured.Unstructured) error {
+	if ownerRefs := obj.GetOwnerReferences(); len(ownerRefs) == 1 && ownerRefs[0].UID == parent.GetUID() {
+		return c.deleteSharedAttachment(client, parent, obj)
+	}
+	updated, err := client.AtomicUpdate(obj, func(obj *unstructured.Unstructured) bool {
+		var ownerRefs []metav1.OwnerReference
+		for _, ownerRef := range obj.GetOwnerReferences() {
+			if ownerRef.UID != parent.GetUID() {
+				ownerRefs = append(ownerRefs, ownerRef)
+			}
+		}
+		if len(ownerRefs) == len(obj.GetOwnerReferences()) {
+			return false
+		}
+		obj.SetOwnerReferences(ownerRefs)
+		return true
+	})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// Other targets released it in the meantime.
+	if len(updated.GetOwnerReferences()) == 0 {
+		return c.deleteSharedAttachment(client, parent, updated)
+	}
+	return nil
+}
+
+// deleteSharedAttachment deletes obj, unless another target added a reference
+// to it since it was read.
+func (c *decoratorController) deleteSharedAttachment(client *dynamicclientset.ResourceClient, parent, obj *unstructured.Unstructured) error {
+	c.logger.Info("Deleting shared attachment", "object", klog.KObj(parent), "attachment", klog.KObj(obj))
+	uid, resourceVersion := obj.GetUID(), obj.GetResourceVersion()
+	propagation := metav1.DeletePropagationBackground
+	err := client.Delete(context.TODO(), obj.GetName(), metav1.DeleteOptions{
+		Preconditions:     &metav1.Preconditions{UID: &uid, ResourceVersion: &resourceVersion},
+		PropagationPolicy: &propagation,
+	})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
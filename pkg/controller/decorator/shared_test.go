@@ -0,0 +1,62 @@
+package decorator
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"metacontroller/pkg/controller/common"
+)
+
+func TestSharedKinds_Split(t *testing.T) {
+	parent := &unstructured.Unstructured{}
+	parent.SetNamespace("default")
+	parent.SetName("target")
+	parent.SetUID("target-uid")
+	newObject := func(apiVersion, kind, name string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion(apiVersion)
+		obj.SetKind(kind)
+		obj.SetNamespace("default")
+		obj.SetName(name)
+		return obj
+	}
+	configMap := newObject("v1", "ConfigMap", "shared")
+	secret := newObject("v1", "Secret", "owned")
+	observed := common.MakeRelativeObjectMap(parent, []*unstructured.Unstructured{configMap, secret})
+	desired := common.MakeRelativeObjectMap(parent, []*unstructured.Unstructured{configMap.DeepCopy(), secret.DeepCopy()})
+
+	sharedObserved, sharedDesired := sharedKinds{"ConfigMap.": true}.split(observed, desired)
+
+	for _, m := range []common.RelativeObjectMap{observed, desired} {
+		if m.FindGroupKindName(configMap.GroupVersionKind().GroupKind(), "shared") != nil {
+			t.Error("expected shared attachments to be moved out")
+		}
+		if m.FindGroupKindName(secret.GroupVersionKind().GroupKind(), "owned") == nil {
+			t.Error("expected other attachments to be kept")
+		}
+	}
+	for _, m := range []common.RelativeObjectMap{sharedObserved, sharedDesired} {
+		if len(m.List()) != 1 || m.FindGroupKindName(configMap.GroupVersionKind().GroupKind(), "shared") == nil {
+			t.Errorf("expected only the shared attachment, got: %v", m.List())
+		}
+	}
+}
+
+func TestIsSharedBy(t *testing.T) {
+	parent := &unstructured.Unstructured{}
+	parent.SetUID("target-uid")
+	obj := &unstructured.Unstructured{}
+	obj.SetOwnerReferences([]metav1.OwnerReference{{UID: "other-uid"}})
+	if isSharedBy(parent, obj) {
+		t.Error("expected attachment referenced by another target not to be shared by parent")
+	}
+	obj.SetOwnerReferences(append(obj.GetOwnerReferences(), makeSharedOwnerRef(parent)))
+	if !isSharedBy(parent, obj) {
+		t.Error("expected attachment to be shared by parent")
+	}
+	if metav1.GetControllerOf(obj) != nil {
+		t.Error("expected the reference of a shared attachment not to be a controllerRef")
+	}
+}
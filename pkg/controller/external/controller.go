@@ -0,0 +1,498 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/controller/common/finalizer"
+	"metacontroller/pkg/debug"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
+	dynamicinformer "metacontroller/pkg/dynamic/informer"
+	dynamicobject "metacontroller/pkg/dynamic/object"
+	"metacontroller/pkg/events"
+	"metacontroller/pkg/hooks"
+	"metacontroller/pkg/logging"
+	"metacontroller/pkg/metrics"
+	"metacontroller/pkg/tracing"
+)
+
+// finalizeRetryInterval is how long to wait before calling the delete hook
+// again, when it didn't finish and didn't ask for a delay.
+const finalizeRetryInterval = 5 * time.Second
+
+// externalResourceController reconciles one external resource per parent
+// object. The external ID and last observed state are kept in the
+// status.external field of the parent, and a finalizer makes sure the delete
+// hook is called before the parent goes away.
+type externalResourceController struct {
+	erc *v1alpha1.ExternalResourceController
+
+	parentResource *dynamicdiscovery.APIResource
+	parentClient   *dynamicclientset.ResourceClient
+	parentInformer *dynamicinformer.ResourceInformer
+	parentSelector labels.Selector
+
+	stopCh, doneCh chan struct{}
+	queue          workqueue.RateLimitingInterface
+
+	finalizer    *finalizer.Manager
+	resyncPeriod time.Duration
+
+	eventRecorder record.EventRecorder
+
+	observeHook hooks.HookExecutor
+	diffHook    hooks.HookExecutor
+	createHook  hooks.HookExecutor
+	updateHook  hooks.HookExecutor
+	deleteHook  hooks.HookExecutor
+
+	numWorkers int
+	logger     logr.Logger
+}
+
+func newExternalResourceController(dynClient *dynamicclientset.Clientset, dynInformers *dynamicinformer.SharedInformerFactory, eventRecorder record.EventRecorder, erc *v1alpha1.ExternalResourceController, numWorkers int, logger logr.Logger) (controller *externalResourceController, newErr error) {
+	spec := erc.Spec
+	if spec.Hooks == nil || spec.Hooks.Observe == nil || spec.Hooks.Create == nil || spec.Hooks.Delete == nil {
+		return nil, fmt.Errorf("observe, create and delete hooks must be defined")
+	}
+	parentClient, err := dynClient.Resource(spec.ParentResource.APIVersion, spec.ParentResource.Resource)
+	if err != nil {
+		return nil, fmt.Errorf("can't get client for parent resource %q in apiVersion %q: %w", spec.ParentResource.Resource, spec.ParentResource.APIVersion, err)
+	}
+	parentSelector := labels.Everything()
+	if spec.ParentResource.LabelSelector != nil {
+		parentSelector, err = metav1.LabelSelectorAsSelector(spec.ParentResource.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("can't convert label selector for parent resource %q in apiVersion %q: %w", spec.ParentResource.Resource, spec.ParentResource.APIVersion, err)
+		}
+	}
+	hookRateLimiter, err := hooks.NewRateLimiter(spec.RateLimit)
+	if err != nil {
+		return nil, err
+	}
+	newHook := func(hook *v1alpha1.Hook, hookType common.HookType) (hooks.HookExecutor, error) {
+		executor, err := hooks.NewHookExecutor(hook, erc.Name, common.ExternalResourceController, hookType, dynClient, spec.HookTransport, logger)
+		if err != nil {
+			return nil, err
+		}
+		return hooks.WithRateLimiter(executor, hookRateLimiter), nil
+	}
+	observeHook, err := newHook(spec.Hooks.Observe, common.ObserveHook)
+	if err != nil {
+		return nil, err
+	}
+	diffHook, err := newHook(spec.Hooks.Diff, common.DiffHook)
+	if err != nil {
+		return nil, err
+	}
+	createHook, err := newHook(spec.Hooks.Create, common.CreateHook)
+	if err != nil {
+		return nil, err
+	}
+	updateHook, err := newHook(spec.Hooks.Update, common.UpdateHook)
+	if err != nil {
+		return nil, err
+	}
+	deleteHook, err := newHook(spec.Hooks.Delete, common.DeleteHook)
+	if err != nil {
+		return nil, err
+	}
+
+	var resyncPeriod time.Duration
+	if spec.ResyncPeriodSeconds != nil {
+		resyncPeriod = time.Duration(*spec.ResyncPeriodSeconds) * time.Second
+		// Put a reasonable limit on it.
+		if resyncPeriod < time.Second {
+			resyncPeriod = time.Second
+		}
+	}
+
+	parentInformer, err := dynInformers.Resource(spec.ParentResource.APIVersion, spec.ParentResource.Resource)
+	if err != nil {
+		return nil, fmt.Errorf("can't create informer for parent resource: %w", err)
+	}
+
+	return &externalResourceController{
+		erc:            erc,
+		parentResource: parentClient.APIResource,
+		parentClient:   parentClient,
+		parentInformer: parentInformer,
+		parentSelector: parentSelector,
+		queue:          metrics.NewControllerQueue(erc.Name, common.ExternalResourceController),
+		finalizer:      finalizer.NewManager("metacontroller.io/externalresourcecontroller-"+erc.Name, true),
+		resyncPeriod:   resyncPeriod,
+		eventRecorder:  eventRecorder,
+		observeHook:    observeHook,
+		diffHook:       diffHook,
+		createHook:     createHook,
+		updateHook:     updateHook,
+		deleteHook:     deleteHook,
+		numWorkers:     numWorkers,
+		logger:         logger.WithName(erc.Name),
+	}, nil
+}
+
+func (c *externalResourceController) Start() {
+	c.stopCh = make(chan struct{})
+	c.doneCh = make(chan struct{})
+	debug.Controllers.Register(c.erc.Name, common.ExternalResourceController, c.watched)
+
+	// ExternalResourceControllers can be created at any time, so we have to
+	// assume the shared informers are already running.
+	c.parentInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueueParentObject,
+		UpdateFunc: c.updateParentObject,
+		DeleteFunc: c.enqueueParentObject,
+	})
+
+	go func() {
+		defer close(c.doneCh)
+		defer utilruntime.HandleCrash()
+
+		c.logger.Info("Starting ExternalResourceController", "controller", c.erc.Name)
+		c.eventRecorder.Eventf(c.erc, v1.EventTypeNormal, events.ReasonStarting, "Starting controller: %s", c.erc.Name)
+		defer c.logger.Info("Shutting down ExternalResourceController", "controller", c.erc.Name)
+		defer c.eventRecorder.Eventf(c.erc, v1.EventTypeNormal, events.ReasonStopping, "Stopping controller: %s", c.erc.Name)
+
+		// Wait for the parent informer.
+		c.logger.Info("Waiting for ExternalResourceController caches to sync", "controller", c.erc.Name)
+		if !cache.WaitForNamedCacheSync(c.erc.Name, c.stopCh, c.parentInformer.Informer().HasSynced) {
+			// We wait forever unless Stop() is called, so this isn't an error.
+			c.logger.Info("ExternalResourceController cache sync never finished", "controller", c.erc.Name)
+			return
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < c.numWorkers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				wait.Until(c.worker, time.Second, c.stopCh)
+			}()
+		}
+		wg.Wait()
+	}()
+}
+
+// watched returns the state of the watches of the controller.
+func (c *externalResourceController) watched() []dynamicinformer.WatchState {
+	return common.WatchStates(c.parentInformer)
+}
+
+func (c *externalResourceController) Stop() {
+	close(c.stopCh)
+	debug.Controllers.Unregister(c.erc.Name, common.ExternalResourceController)
+	c.queue.ShutDown()
+	<-c.doneCh
+
+	c.parentInformer.Informer().RemoveEventHandlers()
+	c.parentInformer.Close()
+}
+
+func (c *externalResourceController) worker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *externalResourceController) processNextWorkItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	if err := c.sync(key.(string)); err != nil {
+		utilruntime.HandleError(fmt.Errorf("failed to sync %v %q: %w", c.erc.Name, key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *externalResourceController) enqueueParentObject(obj interface{}) {
+	// If the parent doesn't match our selector, and it doesn't have our
+	// finalizer, we don't care about it.
+	if parent, ok := obj.(*unstructured.Unstructured); ok {
+		if !c.matches(parent) && !dynamicobject.HasFinalizer(parent, c.finalizer.Name) {
+			return
+		}
+	}
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("couldn't get key for object %+v: %w", obj, err))
+		return
+	}
+	c.queue.Add(key)
+}
+
+func (c *externalResourceController) updateParentObject(old, cur interface{}) {
+	oldParent := old.(*unstructured.Unstructured)
+	curParent := cur.(*unstructured.Unstructured)
+
+	// Don't sync if it's a no-op update (probably a relist/resync).
+	if oldParent.GetResourceVersion() == curParent.GetResourceVersion() {
+		return
+	}
+	c.enqueueParentObject(cur)
+}
+
+func (c *externalResourceController) matches(parent *unstructured.Unstructured) bool {
+	return c.parentSelector.Matches(labels.Set(parent.GetLabels()))
+}
+
+func (c *externalResourceController) sync(key string) error {
+	ctx, span := tracing.Start(logging.NewContext(context.Background(), c.logger), "sync",
+		"controller.type", common.ExternalResourceController.String(),
+		"controller.name", c.erc.Name,
+		"key", key)
+	defer span.End()
+
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	parent, err := common.GetObject(c.parentInformer, namespace, name)
+	if apierrors.IsNotFound(err) {
+		// Swallow the error since there's no point retrying if the parent is gone.
+		c.logger.V(4).Info("Parent has been deleted", "parent", key)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = c.syncParentObject(ctx, key, parent)
+	span.RecordError(err)
+	if err != nil {
+		common.SyncEventRecorder(ctx, c.eventRecorder).Eventf(
+			parent,
+			v1.EventTypeWarning,
+			hooks.SyncErrorReason(err),
+			"Sync error: %s", err.Error())
+	}
+	return err
+}
+
+func (c *externalResourceController) syncParentObject(ctx context.Context, key string, parent *unstructured.Unstructured) error {
+	hasFinalizer := dynamicobject.HasFinalizer(parent, c.finalizer.Name)
+	if !c.matches(parent) && !hasFinalizer {
+		return nil
+	}
+
+	c.logger.V(4).Info("ExternalResourceController sync", "controller", c.erc.Name, "parent", key, "syncID", tracing.SyncID(ctx))
+
+	external, err := getExternalStatus(parent)
+	if err != nil {
+		return err
+	}
+	parentClient := c.parentClient.Namespace(parent.GetNamespace()).Audited(common.AuditRecorder(c.erc.Name, common.ExternalResourceController, parent))
+
+	if parent.GetDeletionTimestamp() != nil {
+		if !c.finalizer.ShouldFinalize(parent) {
+			return nil
+		}
+		return c.finalize(ctx, key, parent, external)
+	}
+	if !c.matches(parent) {
+		// The parent was released by its labels: stop managing its external
+		// resource, but leave it alone.
+		_, err := parentClient.RemoveFinalizer(parent, c.finalizer.Name)
+		return err
+	}
+
+	// Before calling any hook, add our finalizer. This ensures we have a
+	// chance to delete the external resource we may create.
+	parent, err = c.finalizer.SyncObject(c.parentClient.Audited(common.AuditRecorder(c.erc.Name, common.ExternalResourceController, parent)), parent)
+	if err != nil {
+		return fmt.Errorf("can't sync finalizer for %v %v: %w", c.parentResource.Kind, key, err)
+	}
+
+	desired, err := c.reconcile(ctx, parent, external)
+	if err != nil {
+		return err
+	}
+	if err := c.updateExternalStatus(parentClient, parent, desired); err != nil {
+		return fmt.Errorf("can't update external status of %v %v: %w", parent.GetKind(), key, err)
+	}
+
+	if c.resyncPeriod > 0 {
+		c.queue.AddAfter(key, c.resyncPeriod)
+	}
+	return nil
+}
+
+// reconcile observes the external resource of parent, and creates or updates
+// it as needed. It returns the external status to record in the parent.
+func (c *externalResourceController) reconcile(ctx context.Context, parent *unstructured.Unstructured, external ExternalStatus) (ExternalStatus, error) {
+	request := &HookRequest{Controller: c.erc, Parent: parent, External: external}
+	var observed ObserveHookResponse
+	if err := callHook(ctx, c.observeHook, "observe", request, &observed); err != nil {
+		return external, err
+	}
+
+	if !observed.Exists {
+		if external.ID != "" {
+			c.logger.Info("External resource is gone, creating it again", "controller", c.erc.Name, "parent", parent.GetName(), "id", external.ID)
+		}
+		var created CreateHookResponse
+		if err := callHook(ctx, c.createHook, "create", request, &created); err != nil {
+			return external, err
+		}
+		if created.ID == "" {
+			return external, fmt.Errorf("create hook returned no external id")
+		}
+		common.SyncEventRecorder(ctx, c.eventRecorder).Eventf(parent, v1.EventTypeNormal, events.ReasonExternalCreated, "Created external resource %s", created.ID)
+		return ExternalStatus{ID: created.ID, State: created.State, ObservedGeneration: parent.GetGeneration()}, nil
+	}
+
+	current := ExternalStatus{ID: external.ID, State: observed.State, ObservedGeneration: external.ObservedGeneration}
+	if observed.ID != "" {
+		current.ID = observed.ID
+	}
+	request.External = current
+
+	// Without a diff hook, the external resource is up to date if it was
+	// created or updated for the current generation of the parent.
+	upToDate := current.ObservedGeneration == parent.GetGeneration()
+	if c.diffHook.IsEnabled() {
+		var diff DiffHookResponse
+		if err := callHook(ctx, c.diffHook, "diff", request, &diff); err != nil {
+			return external, err
+		}
+		upToDate = diff.UpToDate
+	}
+	if upToDate || !c.updateHook.IsEnabled() {
+		return current, nil
+	}
+
+	var updated UpdateHookResponse
+	if err := callHook(ctx, c.updateHook, "update", request, &updated); err != nil {
+		return external, err
+	}
+	common.SyncEventRecorder(ctx, c.eventRecorder).Eventf(parent, v1.EventTypeNormal, events.ReasonExternalUpdated, "Updated external resource %s", current.ID)
+	if updated.State != nil {
+		current.State = updated.State
+	}
+	current.ObservedGeneration = parent.GetGeneration()
+	return current, nil
+}
+
+// finalize calls the delete hook for the external resource of parent, if it
+// was created, and removes our finalizer once it's gone.
+func (c *externalResourceController) finalize(ctx context.Context, key string, parent *unstructured.Unstructured, external ExternalStatus) error {
+	parentClient := c.parentClient.Namespace(parent.GetNamespace()).Audited(common.AuditRecorder(c.erc.Name, common.ExternalResourceController, parent))
+	if external.ID != "" {
+		request := &HookRequest{Controller: c.erc, Parent: parent, External: external}
+		var deleted DeleteHookResponse
+		if err := callHook(ctx, c.deleteHook, "delete", request, &deleted); err != nil {
+			return err
+		}
+		if !deleted.Finalized {
+			delay := finalizeRetryInterval
+			if deleted.ResyncAfterSeconds > 0 {
+				delay = time.Duration(deleted.ResyncAfterSeconds * float64(time.Second))
+			}
+			c.queue.AddAfter(key, delay)
+			return nil
+		}
+		common.SyncEventRecorder(ctx, c.eventRecorder).Eventf(parent, v1.EventTypeNormal, events.ReasonExternalDeleted, "Deleted external resource %s", external.ID)
+	}
+	if _, err := parentClient.RemoveFinalizer(parent, c.finalizer.Name); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("can't remove finalizer for %v %v: %w", parent.GetKind(), key, err)
+	}
+	return nil
+}
+
+// getExternalStatus reads the status.external field of parent.
+func getExternalStatus(parent *unstructured.Unstructured) (ExternalStatus, error) {
+	var external ExternalStatus
+	content, found, err := unstructured.NestedFieldNoCopy(parent.Object, "status", "external")
+	if err != nil || !found || content == nil {
+		return external, err
+	}
+	data, err := json.Marshal(content)
+	if err != nil {
+		return external, err
+	}
+	if err := json.Unmarshal(data, &external); err != nil {
+		return external, fmt.Errorf("can't parse status.external: %w", err)
+	}
+	return external, nil
+}
+
+// externalStatusContent returns external as the content of an unstructured
+// field, with integers decoded as int64 like objects read from the API.
+func externalStatusContent(external ExternalStatus) (map[string]interface{}, error) {
+	data, err := json.Marshal(external)
+	if err != nil {
+		return nil, err
+	}
+	var content map[string]interface{}
+	if err := utiljson.Unmarshal(data, &content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// updateExternalStatus writes external to the status.external field of
+// parent, unless it's already there.
+func (c *externalResourceController) updateExternalStatus(parentClient *dynamicclientset.ResourceClient, parent *unstructured.Unstructured, external ExternalStatus) error {
+	content, err := externalStatusContent(external)
+	if err != nil {
+		return err
+	}
+	setExternal := func(obj *unstructured.Unstructured) bool {
+		current, _, _ := unstructured.NestedFieldNoCopy(obj.Object, "status", "external")
+		if apiequality.Semantic.DeepEqual(current, content) {
+			return false
+		}
+		if err := unstructured.SetNestedField(obj.Object, content, "status", "external"); err != nil {
+			return false
+		}
+		return true
+	}
+	// Check the cached parent first, to skip a GET when nothing changed.
+	if !setExternal(parent.DeepCopy()) {
+		return nil
+	}
+	_, err = parentClient.AtomicStatusUpdate(parent, setExternal)
+	return err
+}
@@ -0,0 +1,147 @@
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/hooks"
+)
+
+// fakeHook answers every call with response, and counts the calls.
+type fakeHook struct {
+	response string
+	calls    int
+}
+
+func (f *fakeHook) IsEnabled() bool {
+	return f != nil
+}
+
+func (f *fakeHook) Execute(ctx context.Context, request interface{}, response interface{}) error {
+	f.calls++
+	return json.Unmarshal([]byte(f.response), response)
+}
+
+// executor returns f as a HookExecutor, or a disabled one if f is nil.
+func (f *fakeHook) executor() hooks.HookExecutor {
+	if f == nil {
+		executor, _ := hooks.NewHookExecutor(nil, "", "", "", nil, nil, logr.Discard())
+		return executor
+	}
+	return f
+}
+
+func TestExternalStatus_RoundTrip(t *testing.T) {
+	external := ExternalStatus{
+		ID:                 "db-123",
+		State:              map[string]interface{}{"size": 10.0, "region": "eu"},
+		ObservedGeneration: 3,
+	}
+	content, err := externalStatusContent(external)
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if _, ok := content["observedGeneration"].(int64); !ok {
+		t.Errorf("expected observedGeneration to be an int64, got %T", content["observedGeneration"])
+	}
+
+	parent := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	if err := unstructured.SetNestedField(parent.Object, content, "status", "external"); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	got, err := getExternalStatus(parent)
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if !reflect.DeepEqual(got, external) {
+		t.Errorf("expected %v, got %v", external, got)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	tests := []struct {
+		name     string
+		external ExternalStatus
+		observe  string
+		diff     *fakeHook
+		update   *fakeHook
+		want     ExternalStatus
+		creates  int
+		updates  int
+	}{
+		{
+			name:    "missing resource is created",
+			observe: `{"exists": false}`,
+			update:  &fakeHook{response: `{}`},
+			want:    ExternalStatus{ID: "new-id", State: map[string]interface{}{"phase": "creating"}, ObservedGeneration: 2},
+			creates: 1,
+		},
+		{
+			name:     "up to date generation isn't updated",
+			external: ExternalStatus{ID: "id", ObservedGeneration: 2},
+			observe:  `{"exists": true, "state": {"phase": "ready"}}`,
+			update:   &fakeHook{response: `{}`},
+			want:     ExternalStatus{ID: "id", State: map[string]interface{}{"phase": "ready"}, ObservedGeneration: 2},
+		},
+		{
+			name:     "new generation is updated",
+			external: ExternalStatus{ID: "id", ObservedGeneration: 1},
+			observe:  `{"exists": true, "state": {"phase": "ready"}}`,
+			update:   &fakeHook{response: `{"state": {"phase": "updating"}}`},
+			want:     ExternalStatus{ID: "id", State: map[string]interface{}{"phase": "updating"}, ObservedGeneration: 2},
+			updates:  1,
+		},
+		{
+			name:     "diff hook decides",
+			external: ExternalStatus{ID: "id", ObservedGeneration: 1},
+			observe:  `{"exists": true, "state": {"phase": "ready"}}`,
+			diff:     &fakeHook{response: `{"upToDate": true}`},
+			update:   &fakeHook{response: `{}`},
+			want:     ExternalStatus{ID: "id", State: map[string]interface{}{"phase": "ready"}, ObservedGeneration: 1},
+		},
+		{
+			name:     "observe hook reports the id",
+			external: ExternalStatus{ObservedGeneration: 2},
+			observe:  `{"exists": true, "id": "found-id"}`,
+			want:     ExternalStatus{ID: "found-id", ObservedGeneration: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			create := &fakeHook{response: `{"id": "new-id", "state": {"phase": "creating"}}`}
+			c := &externalResourceController{
+				erc:           &v1alpha1.ExternalResourceController{},
+				eventRecorder: record.NewFakeRecorder(10),
+				observeHook:   &fakeHook{response: tt.observe},
+				diffHook:      tt.diff.executor(),
+				createHook:    create,
+				updateHook:    tt.update.executor(),
+			}
+			parent := &unstructured.Unstructured{}
+			parent.SetName("db")
+			parent.SetGeneration(2)
+
+			got, err := c.reconcile(context.Background(), parent, tt.external)
+			if err != nil {
+				t.Fatalf("err should be nil, got: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+			if create.calls != tt.creates {
+				t.Errorf("expected %d create calls, got %d", tt.creates, create.calls)
+			}
+			if tt.update != nil && tt.update.calls != tt.updates {
+				t.Errorf("expected %d update calls, got %d", tt.updates, tt.update.calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/hooks"
+)
+
+// ExternalStatus is what metacontroller remembers about the external resource
+// of a parent, in the parent's status.external field.
+type ExternalStatus struct {
+	ID                 string                 `json:"id,omitempty"`
+	State              map[string]interface{} `json:"state,omitempty"`
+	ObservedGeneration int64                  `json:"observedGeneration,omitempty"`
+}
+
+// HookRequest is the object sent as JSON to every hook.
+type HookRequest struct {
+	Controller *v1alpha1.ExternalResourceController `json:"controller"`
+	Parent     *unstructured.Unstructured           `json:"parent"`
+	External   ExternalStatus                       `json:"external"`
+}
+
+// ObserveHookResponse is the expected format of the JSON response from the observe hook.
+type ObserveHookResponse struct {
+	Exists bool                   `json:"exists"`
+	ID     string                 `json:"id,omitempty"`
+	State  map[string]interface{} `json:"state"`
+}
+
+// DiffHookResponse is the expected format of the JSON response from the diff hook.
+type DiffHookResponse struct {
+	UpToDate bool `json:"upToDate"`
+}
+
+// CreateHookResponse is the expected format of the JSON response from the create hook.
+type CreateHookResponse struct {
+	ID    string                 `json:"id"`
+	State map[string]interface{} `json:"state"`
+}
+
+// UpdateHookResponse is the expected format of the JSON response from the update hook.
+type UpdateHookResponse struct {
+	State map[string]interface{} `json:"state"`
+}
+
+// DeleteHookResponse is the expected format of the JSON response from the delete hook.
+type DeleteHookResponse struct {
+	Finalized bool `json:"finalized"`
+
+	ResyncAfterSeconds float64 `json:"resyncAfterSeconds"`
+}
+
+func callHook(ctx context.Context, hook hooks.HookExecutor, name string, request *HookRequest, response interface{}) error {
+	if err := hook.Execute(hooks.WithParent(ctx, request.Parent), request, response); err != nil {
+		return fmt.Errorf("%s hook failed: %w", name, err)
+	}
+	return nil
+}
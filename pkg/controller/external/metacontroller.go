@@ -0,0 +1,96 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package external
+
+import (
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/controller/common/lifecycle"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	dynamicinformer "metacontroller/pkg/dynamic/informer"
+)
+
+// NewMetacontroller returns the reconciler which runs the controllers of
+// ExternalResourceControllers.
+func NewMetacontroller(controllerContext common.ControllerContext, numWorkers int, rbacPreflight bool, hookProbeInterval time.Duration) *lifecycle.Reconciler {
+	kind := &externalResourceControllerKind{
+		dynClient:     controllerContext.DynClient,
+		dynInformers:  controllerContext.DynInformers,
+		eventRecorder: controllerContext.EventRecorder,
+		numWorkers:    numWorkers,
+	}
+	return lifecycle.NewReconciler(controllerContext, common.ExternalResourceController, kind, rbacPreflight, hookProbeInterval, "external")
+}
+
+// externalResourceControllerKind adapts ExternalResourceControllers to a
+// lifecycle.Reconciler.
+type externalResourceControllerKind struct {
+	dynClient     *dynamicclientset.Clientset
+	dynInformers  *dynamicinformer.SharedInformerFactory
+	eventRecorder record.EventRecorder
+	numWorkers    int
+}
+
+func (k *externalResourceControllerKind) NewObject() client.Object {
+	return &v1alpha1.ExternalResourceController{}
+}
+
+func (k *externalResourceControllerKind) Spec(obj client.Object) interface{} {
+	return obj.(*v1alpha1.ExternalResourceController).Spec
+}
+
+func (k *externalResourceControllerKind) Status(obj client.Object) (*[]metav1.Condition, *int64) {
+	erc := obj.(*v1alpha1.ExternalResourceController)
+	return &erc.Status.Conditions, &erc.Status.ObservedGeneration
+}
+
+func (k *externalResourceControllerKind) DependsOn(obj client.Object) []v1alpha1.ControllerDependency {
+	return obj.(*v1alpha1.ExternalResourceController).Spec.DependsOn
+}
+
+func (k *externalResourceControllerKind) RBACRules(obj client.Object) []common.RBACRule {
+	return common.ExternalResourceControllerRBACRules(obj.(*v1alpha1.ExternalResourceController))
+}
+
+func (k *externalResourceControllerKind) Webhooks(obj client.Object) map[common.HookType]*v1alpha1.Hook {
+	return ercWebhooks(obj.(*v1alpha1.ExternalResourceController))
+}
+
+func (k *externalResourceControllerKind) NewController(obj client.Object, logger logr.Logger) (lifecycle.Controller, error) {
+	return newExternalResourceController(k.dynClient, k.dynInformers, k.eventRecorder, obj.(*v1alpha1.ExternalResourceController), k.numWorkers, logger)
+}
+
+// ercWebhooks returns the hooks of erc which can be probed.
+func ercWebhooks(erc *v1alpha1.ExternalResourceController) map[common.HookType]*v1alpha1.Hook {
+	if erc.Spec.Hooks == nil {
+		return nil
+	}
+	return map[common.HookType]*v1alpha1.Hook{
+		common.ObserveHook: erc.Spec.Hooks.Observe,
+		common.DiffHook:    erc.Spec.Hooks.Diff,
+		common.CreateHook:  erc.Spec.Hooks.Create,
+		common.UpdateHook:  erc.Spec.Hooks.Update,
+		common.DeleteHook:  erc.Spec.Hooks.Delete,
+	}
+}
@@ -0,0 +1,565 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package global
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/debug"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
+	dynamicinformer "metacontroller/pkg/dynamic/informer"
+	"metacontroller/pkg/events"
+	"metacontroller/pkg/hooks"
+	"metacontroller/pkg/logging"
+	"metacontroller/pkg/metrics"
+	"metacontroller/pkg/tracing"
+)
+
+// globalController syncs the cluster-wide attachments of a GlobalController.
+// The GlobalController object itself is the parent of its attachments, so
+// they're deleted by the garbage collector along with it.
+type globalController struct {
+	gc     *v1alpha1.GlobalController
+	parent *unstructured.Unstructured
+
+	resources *dynamicdiscovery.ResourceMap
+	dynClient *dynamicclientset.Clientset
+
+	stopCh, doneCh chan struct{}
+	queue          workqueue.RateLimitingInterface
+
+	updateStrategy  updateStrategyMap
+	applyStrategies *common.ChildApplyStrategies
+
+	childInformers   common.InformerMap
+	relatedInformers common.InformerMap
+	relatedSelectors []relatedSelector
+
+	eventRecorder record.EventRecorder
+	syncHook      hooks.HookExecutor
+
+	logger logr.Logger
+}
+
+// relatedSelector selects the related objects of a related rule.
+type relatedSelector struct {
+	gvr       schema.GroupVersionResource
+	gvk       schema.GroupVersionKind
+	selector  labels.Selector
+	namespace string
+}
+
+func newGlobalController(resources *dynamicdiscovery.ResourceMap, dynClient *dynamicclientset.Clientset, dynInformers *dynamicinformer.SharedInformerFactory, eventRecorder record.EventRecorder, gc *v1alpha1.GlobalController, logger logr.Logger) (controller *globalController, newErr error) {
+	if gc.Spec.Hooks == nil || gc.Spec.Hooks.Sync == nil {
+		return nil, fmt.Errorf("no sync hook defined")
+	}
+	hookRateLimiter, err := hooks.NewRateLimiter(gc.Spec.RateLimit)
+	if err != nil {
+		return nil, err
+	}
+	syncHook, err := hooks.NewHookExecutor(gc.Spec.Hooks.Sync, gc.Name, common.GlobalController, common.SyncHook, dynClient, gc.Spec.HookTransport, logger)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := makeParent(gc)
+	if err != nil {
+		return nil, err
+	}
+	updateStrategy, err := makeUpdateStrategyMap(resources, gc)
+	if err != nil {
+		return nil, err
+	}
+	applyStrategies, err := makeApplyStrategies(resources, gc)
+	if err != nil {
+		return nil, err
+	}
+	applyStrategies.SetLogger(logger.WithName(gc.Name))
+
+	c := &globalController{
+		gc:               gc,
+		parent:           parent,
+		resources:        resources,
+		dynClient:        dynClient,
+		queue:            metrics.NewControllerQueue(gc.Name, common.GlobalController),
+		updateStrategy:   updateStrategy,
+		applyStrategies:  applyStrategies,
+		childInformers:   make(common.InformerMap),
+		relatedInformers: make(common.InformerMap),
+		eventRecorder:    eventRecorder,
+		syncHook:         hooks.WithRateLimiter(syncHook, hookRateLimiter),
+		logger:           logger.WithName(gc.Name),
+	}
+
+	defer func() {
+		if newErr != nil {
+			// If newGlobalController fails, Close() any informers we created
+			// since Stop() will never be called.
+			for _, informer := range c.childInformers {
+				informer.Close()
+			}
+			for _, informer := range c.relatedInformers {
+				informer.Close()
+			}
+		}
+	}()
+
+	for _, child := range gc.Spec.Attachments {
+		groupVersion, err := schema.ParseGroupVersion(child.APIVersion)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse child resource groupVersion: %w", err)
+		}
+		gvr := groupVersion.WithResource(child.Resource)
+		if c.childInformers.Get(gvr) != nil {
+			continue
+		}
+		informer, err := dynInformers.Resource(child.APIVersion, child.Resource)
+		if err != nil {
+			return nil, fmt.Errorf("can't create informer for child resource: %w", err)
+		}
+		c.childInformers.Set(gvr, informer)
+	}
+
+	for _, related := range gc.Spec.Related {
+		groupVersion, err := schema.ParseGroupVersion(related.APIVersion)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse related resource groupVersion: %w", err)
+		}
+		gvr := groupVersion.WithResource(related.Resource)
+		resource := resources.Get(related.APIVersion, related.Resource)
+		if resource == nil {
+			return nil, fmt.Errorf("can't find related resource %q in %v", related.Resource, related.APIVersion)
+		}
+		selector := labels.Everything()
+		if related.LabelSelector != nil {
+			selector, err = metav1.LabelSelectorAsSelector(related.LabelSelector)
+			if err != nil {
+				return nil, fmt.Errorf("can't convert label selector for related resource %q in apiVersion %q: %w", related.Resource, related.APIVersion, err)
+			}
+		}
+		c.relatedSelectors = append(c.relatedSelectors, relatedSelector{
+			gvr:       gvr,
+			gvk:       resource.GroupVersionKind(),
+			selector:  selector,
+			namespace: related.Namespace,
+		})
+		if c.relatedInformers.Get(gvr) != nil {
+			continue
+		}
+		informer, err := dynInformers.Resource(related.APIVersion, related.Resource)
+		if err != nil {
+			return nil, fmt.Errorf("can't create informer for related resource: %w", err)
+		}
+		c.relatedInformers.Set(gvr, informer)
+	}
+
+	return c, nil
+}
+
+// makeParent returns gc as the unstructured parent of its attachments.
+func makeParent(gc *v1alpha1.GlobalController) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(gc)
+	if err != nil {
+		return nil, fmt.Errorf("can't convert GlobalController %q: %w", gc.Name, err)
+	}
+	parent := &unstructured.Unstructured{Object: content}
+	// Objects read with a typed client don't have their TypeMeta set.
+	parent.SetGroupVersionKind(v1alpha1.SchemeGroupVersion.WithKind("GlobalController"))
+	return parent, nil
+}
+
+func (c *globalController) Start() {
+	c.stopCh = make(chan struct{})
+	c.doneCh = make(chan struct{})
+	debug.Controllers.Register(c.gc.Name, common.GlobalController, c.watched)
+
+	// GlobalControllers can be created at any time, so we have to assume the
+	// shared informers are already running.
+	for _, informer := range c.childInformers {
+		informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    c.onChildEvent,
+			UpdateFunc: c.onChildUpdate,
+			DeleteFunc: c.onChildEvent,
+		})
+	}
+	for _, informer := range c.relatedInformers {
+		informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    c.onRelatedEvent,
+			UpdateFunc: c.onRelatedUpdate,
+			DeleteFunc: c.onRelatedEvent,
+		})
+	}
+
+	go func() {
+		defer close(c.doneCh)
+		defer utilruntime.HandleCrash()
+
+		c.logger.Info("Starting GlobalController", "controller", c.gc.Name)
+		c.eventRecorder.Eventf(c.gc, v1.EventTypeNormal, events.ReasonStarting, "Starting controller: %s", c.gc.Name)
+		defer c.logger.Info("Shutting down GlobalController", "controller", c.gc.Name)
+		defer c.eventRecorder.Eventf(c.gc, v1.EventTypeNormal, events.ReasonStopping, "Stopping controller: %s", c.gc.Name)
+
+		// Wait for all informers.
+		c.logger.Info("Waiting for GlobalController caches to sync", "controller", c.gc.Name)
+		syncFuncs := make([]cache.InformerSynced, 0, len(c.childInformers)+len(c.relatedInformers))
+		for _, informer := range c.childInformers {
+			syncFuncs = append(syncFuncs, informer.Informer().HasSynced)
+		}
+		for _, informer := range c.relatedInformers {
+			syncFuncs = append(syncFuncs, informer.Informer().HasSynced)
+		}
+		if !cache.WaitForNamedCacheSync(c.gc.Name, c.stopCh, syncFuncs...) {
+			// We wait forever unless Stop() is called, so this isn't an error.
+			c.logger.Info("GlobalController cache sync never finished", "controller", c.gc.Name)
+			return
+		}
+
+		// There's a single parent, so a single worker is enough.
+		c.enqueue()
+		wait.Until(c.worker, time.Second, c.stopCh)
+	}()
+}
+
+// watched returns the state of the watches of the controller.
+func (c *globalController) watched() []dynamicinformer.WatchState {
+	return common.WatchStates(append(c.childInformers.Informers(), c.relatedInformers.Informers()...)...)
+}
+
+func (c *globalController) Stop() {
+	close(c.stopCh)
+	debug.Controllers.Unregister(c.gc.Name, common.GlobalController)
+	c.queue.ShutDown()
+	<-c.doneCh
+
+	for _, informer := range c.childInformers {
+		informer.Informer().RemoveEventHandlers()
+		informer.Close()
+	}
+	for _, informer := range c.relatedInformers {
+		informer.Informer().RemoveEventHandlers()
+		informer.Close()
+	}
+}
+
+func (c *globalController) worker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *globalController) processNextWorkItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	if err := c.sync(); err != nil {
+		utilruntime.HandleError(fmt.Errorf("failed to sync %v: %w", c.gc.Name, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *globalController) enqueue() {
+	c.queue.Add(c.gc.Name)
+}
+
+func (c *globalController) onChildEvent(obj interface{}) {
+	child, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("couldn't get object from tombstone %+v", obj))
+			return
+		}
+		child, ok = tombstone.Obj.(*unstructured.Unstructured)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("tombstone contained object that is not *unstructured.Unstructured %#v", obj))
+			return
+		}
+	}
+	if c.owns(child) {
+		c.enqueue()
+	}
+}
+
+func (c *globalController) onChildUpdate(old, cur interface{}) {
+	oldChild := old.(*unstructured.Unstructured)
+	curChild := cur.(*unstructured.Unstructured)
+
+	// Don't sync if it's a no-op update (probably a relist/resync).
+	if oldChild.GetResourceVersion() == curChild.GetResourceVersion() {
+		return
+	}
+	c.onChildEvent(cur)
+}
+
+func (c *globalController) onRelatedEvent(obj interface{}) {
+	// Deletions of related objects always trigger a sync, since a tombstone
+	// may no longer say whether the object was selected.
+	if related, ok := obj.(*unstructured.Unstructured); ok && !c.isRelated(related) {
+		return
+	}
+	c.enqueue()
+}
+
+func (c *globalController) onRelatedUpdate(old, cur interface{}) {
+	oldRelated := old.(*unstructured.Unstructured)
+	curRelated := cur.(*unstructured.Unstructured)
+
+	if oldRelated.GetResourceVersion() == curRelated.GetResourceVersion() {
+		return
+	}
+	// Objects which stop being selected are synced too, so they're removed
+	// from the related objects.
+	if c.isRelated(oldRelated) || c.isRelated(curRelated) {
+		c.enqueue()
+	}
+}
+
+// owns returns whether obj is an attachment of the controller.
+func (c *globalController) owns(obj *unstructured.Unstructured) bool {
+	controllerRef := metav1.GetControllerOf(obj)
+	return controllerRef != nil && controllerRef.UID == c.gc.UID
+}
+
+// isRelated returns whether obj is selected by one of the related rules.
+func (c *globalController) isRelated(obj *unstructured.Unstructured) bool {
+	for _, rule := range c.relatedSelectors {
+		if rule.matches(obj) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r relatedSelector) matches(obj *unstructured.Unstructured) bool {
+	if obj.GroupVersionKind().GroupKind() != r.gvk.GroupKind() {
+		return false
+	}
+	if r.namespace != "" && obj.GetNamespace() != r.namespace {
+		return false
+	}
+	return r.selector.Matches(labels.Set(obj.GetLabels()))
+}
+
+func (c *globalController) sync() error {
+	ctx, span := tracing.Start(logging.NewContext(context.Background(), c.logger), "sync",
+		"controller.type", common.GlobalController.String(),
+		"controller.name", c.gc.Name)
+	defer span.End()
+	err := c.syncAttachments(ctx)
+	span.RecordError(err)
+	if err != nil {
+		common.SyncEventRecorder(ctx, c.eventRecorder).Eventf(
+			c.gc,
+			v1.EventTypeWarning,
+			hooks.SyncErrorReason(err),
+			"Sync error: %s", err.Error())
+	}
+	return err
+}
+
+func (c *globalController) syncAttachments(ctx context.Context) error {
+	c.logger.V(4).Info("GlobalController sync", "controller", c.gc.Name, "syncID", tracing.SyncID(ctx))
+
+	observedChildren, err := c.getChildren()
+	if err != nil {
+		return err
+	}
+	relatedObjects, err := c.getRelated()
+	if err != nil {
+		return err
+	}
+	syncRequest := &SyncHookRequest{
+		Controller:  c.gc,
+		Attachments: observedChildren,
+		Related:     relatedObjects,
+	}
+	syncResult, err := c.callHook(ctx, syncRequest)
+	if err != nil {
+		return err
+	}
+	desiredChildren := common.MakeRelativeObjectMap(c.parent, syncResult.Attachments)
+
+	// Enqueue a delayed resync, if requested, or else on the resync period.
+	if syncResult.ResyncAfterSeconds > 0 {
+		c.queue.AddAfter(c.gc.Name, time.Duration(syncResult.ResyncAfterSeconds*float64(time.Second)))
+	} else if c.gc.Spec.ResyncPeriodSeconds != nil {
+		resyncPeriod := time.Duration(*c.gc.Spec.ResyncPeriodSeconds) * time.Second
+		// Put a reasonable limit on it.
+		if resyncPeriod < time.Second {
+			resyncPeriod = time.Second
+		}
+		c.queue.AddAfter(c.gc.Name, resyncPeriod)
+	}
+
+	_, span := tracing.Start(ctx, "manage children")
+	defer span.End()
+	results, err := common.ManageChildren(ctx, c.dynClient, c.updateStrategy, c.applyStrategies, nil, c.parent, observedChildren, desiredChildren)
+	common.AuditChildren(c.gc.Name, common.GlobalController, c.parent, results)
+	c.recordDryRunWrites(ctx, c.parent, results)
+	if err != nil {
+		err = fmt.Errorf("can't reconcile children for GlobalController %v: %w", c.gc.Name, err)
+		span.RecordError(err)
+		return err
+	}
+	return nil
+}
+
+// getChildren returns the attachments of the controller, in all namespaces.
+func (c *globalController) getChildren() (common.RelativeObjectMap, error) {
+	childMap := make(common.RelativeObjectMap)
+	for _, child := range c.gc.Spec.Attachments {
+		groupVersion, _ := schema.ParseGroupVersion(child.APIVersion)
+		informer := c.childInformers.Get(groupVersion.WithResource(child.Resource))
+		if informer == nil {
+			return nil, fmt.Errorf("no informer for resource %q in apiVersion %q", child.Resource, child.APIVersion)
+		}
+		resource := c.resources.Get(child.APIVersion, child.Resource)
+		if resource == nil {
+			return nil, fmt.Errorf("can't find resource %q in apiVersion %q", child.Resource, child.APIVersion)
+		}
+		all, err := informer.Lister().List(labels.Everything())
+		if err != nil {
+			return nil, fmt.Errorf("can't list children for resource %q in apiVersion %q: %w", child.Resource, child.APIVersion, err)
+		}
+		// Always include the requested groups, even if there are no entries.
+		childMap.InitGroup(resource.GroupVersionKind())
+		for _, obj := range all {
+			if c.owns(obj) {
+				childMap.Insert(c.parent, obj)
+			}
+		}
+	}
+	return childMap, nil
+}
+
+// getRelated returns the objects selected by the related rules.
+func (c *globalController) getRelated() (common.RelativeObjectMap, error) {
+	relatedMap := make(common.RelativeObjectMap)
+	for _, rule := range c.relatedSelectors {
+		informer := c.relatedInformers.Get(rule.gvr)
+		if informer == nil {
+			return nil, fmt.Errorf("no informer for resource %q in apiVersion %q", rule.gvr.Resource, rule.gvr.GroupVersion())
+		}
+		var all []*unstructured.Unstructured
+		var err error
+		if rule.namespace != "" {
+			all, err = informer.Lister().Namespace(rule.namespace).List(rule.selector)
+		} else {
+			all, err = informer.Lister().List(rule.selector)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can't list related objects for resource %q in apiVersion %q: %w", rule.gvr.Resource, rule.gvr.GroupVersion(), err)
+		}
+		relatedMap.InitGroup(rule.gvk)
+		relatedMap.InsertAll(c.parent, all)
+	}
+	return relatedMap, nil
+}
+
+type updateStrategyMap map[string]*v1alpha1.GlobalControllerAttachmentUpdateStrategy
+
+func (m updateStrategyMap) GetMethod(apiGroup, kind string) v1alpha1.ChildUpdateMethod {
+	strategy := m[updateStrategyMapKey(apiGroup, kind)]
+	if strategy == nil || strategy.Method == "" {
+		return v1alpha1.ChildUpdateOnDelete
+	}
+	return strategy.Method
+}
+
+func updateStrategyMapKey(apiGroup, kind string) string {
+	return fmt.Sprintf("%s.%s", kind, apiGroup)
+}
+
+func makeUpdateStrategyMap(resources *dynamicdiscovery.ResourceMap, gc *v1alpha1.GlobalController) (updateStrategyMap, error) {
+	m := make(updateStrategyMap)
+	for _, child := range gc.Spec.Attachments {
+		if child.UpdateStrategy == nil {
+			continue
+		}
+		switch child.UpdateStrategy.Method {
+		case "", v1alpha1.ChildUpdateOnDelete, v1alpha1.ChildUpdateInPlace, v1alpha1.ChildUpdateRecreate:
+		default:
+			return nil, fmt.Errorf("unsupported update method %q for attachment resource %q in %v", child.UpdateStrategy.Method, child.Resource, child.APIVersion)
+		}
+		// Map resource name to kind name.
+		resource := resources.Get(child.APIVersion, child.Resource)
+		if resource == nil {
+			return nil, fmt.Errorf("can't find child resource %q in %v", child.Resource, child.APIVersion)
+		}
+		// Ignore API version.
+		apiGroup, _ := common.ParseAPIVersion(child.APIVersion)
+		m[updateStrategyMapKey(apiGroup, resource.Kind)] = child.UpdateStrategy
+	}
+	return m, nil
+}
+
+func makeApplyStrategies(resources *dynamicdiscovery.ResourceMap, gc *v1alpha1.GlobalController) (*common.ChildApplyStrategies, error) {
+	strategies := common.NewChildApplyStrategies(gc.Spec.ApplyStrategy, gc.Spec.FieldManager)
+	strategies.SetAuditController(gc.Name, common.GlobalController)
+	strategies.SetDryRun(gc.Spec.DryRun)
+	for _, child := range gc.Spec.Attachments {
+		if child.ApplyStrategy == "" {
+			continue
+		}
+		resource := resources.Get(child.APIVersion, child.Resource)
+		if resource == nil {
+			return nil, fmt.Errorf("can't find child resource %q in %v", child.Resource, child.APIVersion)
+		}
+		apiGroup, _ := common.ParseAPIVersion(child.APIVersion)
+		if err := strategies.Set(apiGroup, resource.Kind, child.ApplyStrategy, nil); err != nil {
+			return nil, err
+		}
+	}
+	return strategies, nil
+}
+
+// recordDryRunWrites records an event on parent for each write to its
+// children which was only dry-run, and counts them.
+func (c *globalController) recordDryRunWrites(ctx context.Context, parent *unstructured.Unstructured, results []common.ChildResult) {
+	if !c.applyStrategies.DryRun() {
+		return
+	}
+	for _, result := range results {
+		if result.Error == "" {
+			common.SyncEventRecorder(ctx, c.eventRecorder).Event(parent, v1.EventTypeNormal, events.ReasonDryRun, result.DryRunMessage())
+		}
+	}
+	metrics.RecordDryRunWrites(c.gc.Name, common.GlobalController, results)
+}
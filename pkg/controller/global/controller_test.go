@@ -0,0 +1,97 @@
+package global
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+)
+
+func TestMakeParent(t *testing.T) {
+	gc := &v1alpha1.GlobalController{}
+	gc.Name = "addons"
+	gc.UID = "gc-uid"
+
+	parent, err := makeParent(gc)
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	controllerRef := common.MakeControllerRef(parent)
+	if controllerRef.APIVersion != "metacontroller.k8s.io/v1alpha1" || controllerRef.Kind != "GlobalController" {
+		t.Errorf("unexpected controllerRef type: %v %v", controllerRef.APIVersion, controllerRef.Kind)
+	}
+	if controllerRef.Name != "addons" || controllerRef.UID != "gc-uid" {
+		t.Errorf("unexpected controllerRef target: %v %v", controllerRef.Name, controllerRef.UID)
+	}
+}
+
+func TestGlobalController_Owns(t *testing.T) {
+	gc := &v1alpha1.GlobalController{}
+	gc.Name = "addons"
+	gc.UID = "gc-uid"
+	parent, err := makeParent(gc)
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	c := &globalController{gc: gc, parent: parent}
+
+	owned := &unstructured.Unstructured{}
+	owned.SetOwnerReferences([]metav1.OwnerReference{*common.MakeControllerRef(parent)})
+	other := &unstructured.Unstructured{}
+	otherRef := *common.MakeControllerRef(parent)
+	otherRef.UID = "other-uid"
+	other.SetOwnerReferences([]metav1.OwnerReference{otherRef})
+
+	if !c.owns(owned) {
+		t.Error("expected object with controllerRef to the GlobalController to be owned")
+	}
+	if c.owns(other) || c.owns(&unstructured.Unstructured{}) {
+		t.Error("expected objects without controllerRef to the GlobalController not to be owned")
+	}
+}
+
+func TestRelatedSelector_Matches(t *testing.T) {
+	rule := relatedSelector{
+		gvr:       schema.GroupVersionResource{Version: "v1", Resource: "nodes"},
+		gvk:       schema.GroupVersionKind{Version: "v1", Kind: "Node"},
+		selector:  labels.SelectorFromSet(labels.Set{"role": "worker"}),
+		namespace: "",
+	}
+	newObject := func(kind, namespace string, objLabels map[string]string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("v1")
+		obj.SetKind(kind)
+		obj.SetNamespace(namespace)
+		obj.SetLabels(objLabels)
+		return obj
+	}
+
+	tests := []struct {
+		name     string
+		rule     relatedSelector
+		obj      *unstructured.Unstructured
+		expected bool
+	}{
+		{"selected", rule, newObject("Node", "", map[string]string{"role": "worker"}), true},
+		{"not selected", rule, newObject("Node", "", map[string]string{"role": "control-plane"}), false},
+		{"other kind", rule, newObject("Pod", "default", map[string]string{"role": "worker"}), false},
+		{"other namespace", relatedSelector{
+			gvk:       schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+			selector:  labels.Everything(),
+			namespace: "kube-system",
+		}, newObject("ConfigMap", "default", nil), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.matches(tt.obj); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
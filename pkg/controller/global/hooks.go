@@ -0,0 +1,50 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package global
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/hooks"
+)
+
+// SyncHookRequest is the object sent as JSON to the sync hook.
+type SyncHookRequest struct {
+	Controller  *v1alpha1.GlobalController `json:"controller"`
+	Attachments common.RelativeObjectMap   `json:"attachments"`
+	Related     common.RelativeObjectMap   `json:"related"`
+}
+
+// SyncHookResponse is the expected format of the JSON response from the sync hook.
+type SyncHookResponse struct {
+	Attachments []*unstructured.Unstructured `json:"attachments"`
+
+	ResyncAfterSeconds float64 `json:"resyncAfterSeconds"`
+}
+
+func (c *globalController) callHook(ctx context.Context, request *SyncHookRequest) (*SyncHookResponse, error) {
+	var response SyncHookResponse
+	if err := c.syncHook.Execute(hooks.WithParent(ctx, c.parent), request, &response); err != nil {
+		return nil, fmt.Errorf("sync hook failed: %w", err)
+	}
+	return &response, nil
+}
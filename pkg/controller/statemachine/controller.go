@@ -0,0 +1,613 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statemachine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/debug"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
+	dynamicinformer "metacontroller/pkg/dynamic/informer"
+	"metacontroller/pkg/events"
+	"metacontroller/pkg/hooks"
+	"metacontroller/pkg/logging"
+	"metacontroller/pkg/metrics"
+	"metacontroller/pkg/tracing"
+)
+
+// defaultStateField is where the state of parents is kept, unless the parent
+// resource rule sets a stateField.
+const defaultStateField = "status.state"
+
+// stateHookType returns the hook type of the hook of a state, as used in
+// metrics and probes.
+func stateHookType(name string) common.HookType {
+	return common.HookType("state:" + name)
+}
+
+// stateMachineController calls the hook of the current state of each parent,
+// manages the children it returns, and moves the parent to the next state
+// once the condition of one of its transitions is met.
+type stateMachineController struct {
+	smc     *v1alpha1.StateMachineController
+	machine *stateMachine
+
+	parentResource *dynamicdiscovery.APIResource
+	parentClient   *dynamicclientset.ResourceClient
+	parentInformer *dynamicinformer.ResourceInformer
+	parentSelector labels.Selector
+	stateField     []string
+
+	resources *dynamicdiscovery.ResourceMap
+	dynClient *dynamicclientset.Clientset
+
+	stopCh, doneCh chan struct{}
+	queue          workqueue.RateLimitingInterface
+
+	updateStrategy  updateStrategyMap
+	applyStrategies *common.ChildApplyStrategies
+
+	childInformers common.InformerMap
+
+	resyncPeriod time.Duration
+
+	eventRecorder record.EventRecorder
+
+	numWorkers int
+	logger     logr.Logger
+}
+
+func newStateMachineController(resources *dynamicdiscovery.ResourceMap, dynClient *dynamicclientset.Clientset, dynInformers *dynamicinformer.SharedInformerFactory, eventRecorder record.EventRecorder, smc *v1alpha1.StateMachineController, numWorkers int, logger logr.Logger) (controller *stateMachineController, newErr error) {
+	spec := smc.Spec
+	hookRateLimiter, err := hooks.NewRateLimiter(spec.RateLimit)
+	if err != nil {
+		return nil, err
+	}
+	machine, err := newStateMachine(smc, func(name string, hook *v1alpha1.Hook) (hooks.HookExecutor, error) {
+		executor, err := hooks.NewHookExecutor(hook, smc.Name, common.StateMachineController, stateHookType(name), dynClient, spec.HookTransport, logger)
+		if err != nil {
+			return nil, err
+		}
+		return hooks.WithRateLimiter(executor, hookRateLimiter), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	parentClient, err := dynClient.Resource(spec.ParentResource.APIVersion, spec.ParentResource.Resource)
+	if err != nil {
+		return nil, fmt.Errorf("can't get client for parent resource %q in apiVersion %q: %w", spec.ParentResource.Resource, spec.ParentResource.APIVersion, err)
+	}
+	parentSelector := labels.Everything()
+	if spec.ParentResource.LabelSelector != nil {
+		parentSelector, err = metav1.LabelSelectorAsSelector(spec.ParentResource.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("can't convert label selector for parent resource %q in apiVersion %q: %w", spec.ParentResource.Resource, spec.ParentResource.APIVersion, err)
+		}
+	}
+	stateField, err := parseStateField(spec.ParentResource.StateField)
+	if err != nil {
+		return nil, err
+	}
+	updateStrategy, err := makeUpdateStrategyMap(resources, smc)
+	if err != nil {
+		return nil, err
+	}
+	applyStrategies, err := makeApplyStrategies(resources, smc)
+	if err != nil {
+		return nil, err
+	}
+	applyStrategies.SetLogger(logger.WithName(smc.Name))
+	var resyncPeriod time.Duration
+	if spec.ResyncPeriodSeconds != nil {
+		resyncPeriod = time.Duration(*spec.ResyncPeriodSeconds) * time.Second
+		// Put a reasonable limit on it.
+		if resyncPeriod < time.Second {
+			resyncPeriod = time.Second
+		}
+	}
+
+	c := &stateMachineController{
+		smc:             smc,
+		machine:         machine,
+		parentResource:  parentClient.APIResource,
+		parentClient:    parentClient,
+		parentSelector:  parentSelector,
+		stateField:      stateField,
+		resources:       resources,
+		dynClient:       dynClient,
+		queue:           metrics.NewControllerQueue(smc.Name, common.StateMachineController),
+		updateStrategy:  updateStrategy,
+		applyStrategies: applyStrategies,
+		childInformers:  make(common.InformerMap),
+		resyncPeriod:    resyncPeriod,
+		eventRecorder:   eventRecorder,
+		numWorkers:      numWorkers,
+		logger:          logger.WithName(smc.Name),
+	}
+
+	defer func() {
+		if newErr != nil {
+			// If newStateMachineController fails, Close() any informers we
+			// created since Stop() will never be called.
+			if c.parentInformer != nil {
+				c.parentInformer.Close()
+			}
+			for _, informer := range c.childInformers {
+				informer.Close()
+			}
+		}
+	}()
+
+	c.parentInformer, err = dynInformers.Resource(spec.ParentResource.APIVersion, spec.ParentResource.Resource)
+	if err != nil {
+		return nil, fmt.Errorf("can't create informer for parent resource: %w", err)
+	}
+	for _, child := range spec.ChildResources {
+		groupVersion, err := schema.ParseGroupVersion(child.APIVersion)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse child resource groupVersion: %w", err)
+		}
+		gvr := groupVersion.WithResource(child.Resource)
+		if c.childInformers.Get(gvr) != nil {
+			continue
+		}
+		informer, err := dynInformers.Resource(child.APIVersion, child.Resource)
+		if err != nil {
+			return nil, fmt.Errorf("can't create informer for child resource: %w", err)
+		}
+		c.childInformers.Set(gvr, informer)
+	}
+
+	return c, nil
+}
+
+// parseStateField parses the dot-separated path of the state field.
+func parseStateField(path string) ([]string, error) {
+	if path == "" {
+		path = defaultStateField
+	}
+	fields := strings.Split(path, ".")
+	for _, field := range fields {
+		if field == "" {
+			return nil, fmt.Errorf("invalid stateField %q", path)
+		}
+	}
+	return fields, nil
+}
+
+func (c *stateMachineController) Start() {
+	c.stopCh = make(chan struct{})
+	c.doneCh = make(chan struct{})
+	debug.Controllers.Register(c.smc.Name, common.StateMachineController, c.watched)
+
+	// StateMachineControllers can be created at any time, so we have to
+	// assume the shared informers are already running.
+	c.parentInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueueParentObject,
+		UpdateFunc: c.updateParentObject,
+		DeleteFunc: c.enqueueParentObject,
+	})
+	for _, informer := range c.childInformers {
+		informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    c.onChildEvent,
+			UpdateFunc: c.onChildUpdate,
+			DeleteFunc: c.onChildEvent,
+		})
+	}
+
+	go func() {
+		defer close(c.doneCh)
+		defer utilruntime.HandleCrash()
+
+		c.logger.Info("Starting StateMachineController", "controller", c.smc.Name)
+		c.eventRecorder.Eventf(c.smc, v1.EventTypeNormal, events.ReasonStarting, "Starting controller: %s", c.smc.Name)
+		defer c.logger.Info("Shutting down StateMachineController", "controller", c.smc.Name)
+		defer c.eventRecorder.Eventf(c.smc, v1.EventTypeNormal, events.ReasonStopping, "Stopping controller: %s", c.smc.Name)
+
+		// Wait for all informers.
+		c.logger.Info("Waiting for StateMachineController caches to sync", "controller", c.smc.Name)
+		syncFuncs := make([]cache.InformerSynced, 0, 1+len(c.childInformers))
+		syncFuncs = append(syncFuncs, c.parentInformer.Informer().HasSynced)
+		for _, informer := range c.childInformers {
+			syncFuncs = append(syncFuncs, informer.Informer().HasSynced)
+		}
+		if !cache.WaitForNamedCacheSync(c.smc.Name, c.stopCh, syncFuncs...) {
+			// We wait forever unless Stop() is called, so this isn't an error.
+			c.logger.Info("StateMachineController cache sync never finished", "controller", c.smc.Name)
+			return
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < c.numWorkers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				wait.Until(c.worker, time.Second, c.stopCh)
+			}()
+		}
+		wg.Wait()
+	}()
+}
+
+// watched returns the state of the watches of the controller.
+func (c *stateMachineController) watched() []dynamicinformer.WatchState {
+	return common.WatchStates(append(c.childInformers.Informers(), c.parentInformer)...)
+}
+
+func (c *stateMachineController) Stop() {
+	close(c.stopCh)
+	debug.Controllers.Unregister(c.smc.Name, common.StateMachineController)
+	c.queue.ShutDown()
+	<-c.doneCh
+
+	c.parentInformer.Informer().RemoveEventHandlers()
+	c.parentInformer.Close()
+	for _, informer := range c.childInformers {
+		informer.Informer().RemoveEventHandlers()
+		informer.Close()
+	}
+}
+
+func (c *stateMachineController) worker() {
+	for c.processNextWorkItem() {
+	}
+}
+
+func (c *stateMachineController) processNextWorkItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	if err := c.sync(key.(string)); err != nil {
+		utilruntime.HandleError(fmt.Errorf("failed to sync %v %q: %w", c.smc.Name, key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *stateMachineController) enqueueParentObject(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("couldn't get key for object %+v: %w", obj, err))
+		return
+	}
+	c.queue.Add(key)
+}
+
+func (c *stateMachineController) updateParentObject(old, cur interface{}) {
+	oldParent := old.(*unstructured.Unstructured)
+	curParent := cur.(*unstructured.Unstructured)
+
+	// Don't sync if it's a no-op update (probably a relist/resync).
+	if oldParent.GetResourceVersion() == curParent.GetResourceVersion() {
+		return
+	}
+	c.enqueueParentObject(cur)
+}
+
+func (c *stateMachineController) onChildEvent(obj interface{}) {
+	child, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("couldn't get object from tombstone %+v", obj))
+			return
+		}
+		child, ok = tombstone.Obj.(*unstructured.Unstructured)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("tombstone contained object that is not *unstructured.Unstructured %#v", obj))
+			return
+		}
+	}
+	controllerRef := metav1.GetControllerOf(child)
+	if controllerRef == nil {
+		return
+	}
+	if parent := c.resolveControllerRef(child.GetNamespace(), controllerRef); parent != nil {
+		c.enqueueParentObject(parent)
+	}
+}
+
+func (c *stateMachineController) onChildUpdate(old, cur interface{}) {
+	oldChild := old.(*unstructured.Unstructured)
+	curChild := cur.(*unstructured.Unstructured)
+
+	// Don't sync if it's a no-op update (probably a relist/resync).
+	if oldChild.GetResourceVersion() == curChild.GetResourceVersion() {
+		return
+	}
+	c.onChildEvent(cur)
+}
+
+// resolveControllerRef returns the parent referenced by a ControllerRef,
+// or nil if it isn't one of our parents.
+func (c *stateMachineController) resolveControllerRef(childNamespace string, controllerRef *metav1.OwnerReference) *unstructured.Unstructured {
+	// Don't even try to look up by Name if it's the wrong APIGroup or Kind.
+	if apiGroup, _ := common.ParseAPIVersion(controllerRef.APIVersion); apiGroup != c.parentResource.Group {
+		return nil
+	}
+	if controllerRef.Kind != c.parentResource.Kind {
+		return nil
+	}
+	parentNamespace := ""
+	if c.parentResource.Namespaced {
+		parentNamespace = childNamespace
+	}
+	parent, err := common.GetObject(c.parentInformer, parentNamespace, controllerRef.Name)
+	if err != nil || parent.GetUID() != controllerRef.UID {
+		return nil
+	}
+	return parent
+}
+
+func (c *stateMachineController) sync(key string) error {
+	ctx, span := tracing.Start(logging.NewContext(context.Background(), c.logger), "sync",
+		"controller.type", common.StateMachineController.String(),
+		"controller.name", c.smc.Name,
+		"key", key)
+	defer span.End()
+
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	parent, err := common.GetObject(c.parentInformer, namespace, name)
+	if apierrors.IsNotFound(err) {
+		// Swallow the error since there's no point retrying if the parent is gone.
+		c.logger.V(4).Info("Parent has been deleted", "parent", key)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !c.parentSelector.Matches(labels.Set(parent.GetLabels())) || parent.GetDeletionTimestamp() != nil {
+		return nil
+	}
+
+	err = c.syncParentObject(ctx, key, parent)
+	span.RecordError(err)
+	if err != nil {
+		common.SyncEventRecorder(ctx, c.eventRecorder).Eventf(
+			parent,
+			v1.EventTypeWarning,
+			hooks.SyncErrorReason(err),
+			"Sync error: %s", err.Error())
+	}
+	return err
+}
+
+func (c *stateMachineController) syncParentObject(ctx context.Context, key string, parent *unstructured.Unstructured) error {
+	stateName, _, err := unstructured.NestedString(parent.Object, c.stateField...)
+	if err != nil {
+		return fmt.Errorf("can't read state of %v %v: %w", parent.GetKind(), key, err)
+	}
+	current, err := c.machine.get(stateName)
+	if err != nil {
+		return err
+	}
+	if stateName == "" {
+		// Record the initial state, so transitions are always visible.
+		return c.setState(parent, current.name)
+	}
+
+	c.logger.V(4).Info("StateMachineController sync", "controller", c.smc.Name, "parent", key, "state", current.name, "syncID", tracing.SyncID(ctx))
+
+	observedChildren, err := c.getChildren(parent)
+	if err != nil {
+		return err
+	}
+
+	if current.hook.IsEnabled() {
+		syncRequest := &SyncHookRequest{
+			Controller: c.smc,
+			Parent:     parent,
+			State:      current.name,
+			Children:   observedChildren,
+		}
+		syncResult, err := callHook(ctx, current, syncRequest)
+		if err != nil {
+			return err
+		}
+		desiredChildren := common.MakeRelativeObjectMap(parent, syncResult.Children)
+		results, err := common.ManageChildren(ctx, c.dynClient, c.updateStrategy, c.applyStrategies, nil, parent, observedChildren, desiredChildren)
+		common.AuditChildren(c.smc.Name, common.StateMachineController, parent, results)
+		c.recordDryRunWrites(ctx, parent, results)
+		if err != nil {
+			return fmt.Errorf("can't reconcile children for %v %v: %w", parent.GetKind(), key, err)
+		}
+		if syncResult.ResyncAfterSeconds > 0 {
+			c.queue.AddAfter(key, time.Duration(syncResult.ResyncAfterSeconds*float64(time.Second)))
+		}
+	}
+
+	// Transitions are checked against the children as they were observed, so
+	// a condition never sees children it just asked for.
+	next, err := current.next(parent, observedChildren)
+	if err != nil {
+		return err
+	}
+	if next != "" {
+		c.logger.Info("State changed", "controller", c.smc.Name, "parent", key, "from", current.name, "to", next)
+		if err := c.setState(parent, next); err != nil {
+			return err
+		}
+		common.SyncEventRecorder(ctx, c.eventRecorder).Eventf(parent, v1.EventTypeNormal, events.ReasonStateChanged, "State changed from %s to %s", current.name, next)
+		return nil
+	}
+
+	if c.resyncPeriod > 0 {
+		c.queue.AddAfter(key, c.resyncPeriod)
+	}
+	return nil
+}
+
+// setState writes state to the state field of parent, through the status
+// subresource if it's a status field.
+func (c *stateMachineController) setState(parent *unstructured.Unstructured, state string) error {
+	update := func(obj *unstructured.Unstructured) bool {
+		current, _, _ := unstructured.NestedString(obj.Object, c.stateField...)
+		if current == state {
+			return false
+		}
+		if err := unstructured.SetNestedField(obj.Object, state, c.stateField...); err != nil {
+			return false
+		}
+		return true
+	}
+	client := c.parentClient.Namespace(parent.GetNamespace()).Audited(common.AuditRecorder(c.smc.Name, common.StateMachineController, parent))
+	var err error
+	if c.stateField[0] == "status" {
+		_, err = client.AtomicStatusUpdate(parent, update)
+	} else {
+		_, err = client.AtomicUpdate(parent, update)
+	}
+	if err != nil {
+		return fmt.Errorf("can't set state of %v %v/%v to %q: %w", parent.GetKind(), parent.GetNamespace(), parent.GetName(), state, err)
+	}
+	return nil
+}
+
+// getChildren returns the children controlled by parent.
+func (c *stateMachineController) getChildren(parent *unstructured.Unstructured) (common.RelativeObjectMap, error) {
+	childMap := make(common.RelativeObjectMap)
+	for _, child := range c.smc.Spec.ChildResources {
+		groupVersion, _ := schema.ParseGroupVersion(child.APIVersion)
+		informer := c.childInformers.Get(groupVersion.WithResource(child.Resource))
+		if informer == nil {
+			return nil, fmt.Errorf("no informer for resource %q in apiVersion %q", child.Resource, child.APIVersion)
+		}
+		resource := c.resources.Get(child.APIVersion, child.Resource)
+		if resource == nil {
+			return nil, fmt.Errorf("can't find resource %q in apiVersion %q", child.Resource, child.APIVersion)
+		}
+		var all []*unstructured.Unstructured
+		var err error
+		if parent.GetNamespace() != "" {
+			all, err = informer.Lister().Namespace(parent.GetNamespace()).List(labels.Everything())
+		} else {
+			all, err = informer.Lister().List(labels.Everything())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can't list children for resource %q in apiVersion %q: %w", child.Resource, child.APIVersion, err)
+		}
+		// Always include the requested groups, even if there are no entries.
+		childMap.InitGroup(resource.GroupVersionKind())
+		for _, obj := range all {
+			controllerRef := metav1.GetControllerOf(obj)
+			if controllerRef != nil && controllerRef.UID == parent.GetUID() {
+				childMap.Insert(parent, obj)
+			}
+		}
+	}
+	return childMap, nil
+}
+
+type updateStrategyMap map[string]*v1alpha1.StateMachineControllerChildUpdateStrategy
+
+func (m updateStrategyMap) GetMethod(apiGroup, kind string) v1alpha1.ChildUpdateMethod {
+	strategy := m[updateStrategyMapKey(apiGroup, kind)]
+	if strategy == nil || strategy.Method == "" {
+		return v1alpha1.ChildUpdateOnDelete
+	}
+	return strategy.Method
+}
+
+func updateStrategyMapKey(apiGroup, kind string) string {
+	return fmt.Sprintf("%s.%s", kind, apiGroup)
+}
+
+func makeUpdateStrategyMap(resources *dynamicdiscovery.ResourceMap, smc *v1alpha1.StateMachineController) (updateStrategyMap, error) {
+	m := make(updateStrategyMap)
+	for _, child := range smc.Spec.ChildResources {
+		if child.UpdateStrategy == nil {
+			continue
+		}
+		switch child.UpdateStrategy.Method {
+		case "", v1alpha1.ChildUpdateOnDelete, v1alpha1.ChildUpdateInPlace, v1alpha1.ChildUpdateRecreate:
+		default:
+			return nil, fmt.Errorf("unsupported update method %q for child resource %q in %v", child.UpdateStrategy.Method, child.Resource, child.APIVersion)
+		}
+		// Map resource name to kind name.
+		resource := resources.Get(child.APIVersion, child.Resource)
+		if resource == nil {
+			return nil, fmt.Errorf("can't find child resource %q in %v", child.Resource, child.APIVersion)
+		}
+		// Ignore API version.
+		apiGroup, _ := common.ParseAPIVersion(child.APIVersion)
+		m[updateStrategyMapKey(apiGroup, resource.Kind)] = child.UpdateStrategy
+	}
+	return m, nil
+}
+
+func makeApplyStrategies(resources *dynamicdiscovery.ResourceMap, smc *v1alpha1.StateMachineController) (*common.ChildApplyStrategies, error) {
+	strategies := common.NewChildApplyStrategies(smc.Spec.ApplyStrategy, smc.Spec.FieldManager)
+	strategies.SetAuditController(smc.Name, common.StateMachineController)
+	strategies.SetDryRun(smc.Spec.DryRun)
+	for _, child := range smc.Spec.ChildResources {
+		if child.ApplyStrategy == "" {
+			continue
+		}
+		resource := resources.Get(child.APIVersion, child.Resource)
+		if resource == nil {
+			return nil, fmt.Errorf("can't find child resource %q in %v", child.Resource, child.APIVersion)
+		}
+		apiGroup, _ := common.ParseAPIVersion(child.APIVersion)
+		if err := strategies.Set(apiGroup, resource.Kind, child.ApplyStrategy, nil); err != nil {
+			return nil, err
+		}
+	}
+	return strategies, nil
+}
+
+// recordDryRunWrites records an event on parent for each write to its
+// children which was only dry-run, and counts them.
+func (c *stateMachineController) recordDryRunWrites(ctx context.Context, parent *unstructured.Unstructured, results []common.ChildResult) {
+	if !c.applyStrategies.DryRun() {
+		return
+	}
+	for _, result := range results {
+		if result.Error == "" {
+			common.SyncEventRecorder(ctx, c.eventRecorder).Event(parent, v1.EventTypeNormal, events.ReasonDryRun, result.DryRunMessage())
+		}
+	}
+	metrics.RecordDryRunWrites(c.smc.Name, common.StateMachineController, results)
+}
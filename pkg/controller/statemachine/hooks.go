@@ -0,0 +1,52 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statemachine
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/hooks"
+)
+
+// SyncHookRequest is the object sent as JSON to the hook of the current state.
+type SyncHookRequest struct {
+	Controller *v1alpha1.StateMachineController `json:"controller"`
+	Parent     *unstructured.Unstructured       `json:"parent"`
+	State      string                           `json:"state"`
+	Children   common.RelativeObjectMap         `json:"children"`
+}
+
+// SyncHookResponse is the expected format of the JSON response from the hook
+// of a state.
+type SyncHookResponse struct {
+	Children []*unstructured.Unstructured `json:"children"`
+
+	ResyncAfterSeconds float64 `json:"resyncAfterSeconds"`
+}
+
+func callHook(ctx context.Context, s *state, request *SyncHookRequest) (*SyncHookResponse, error) {
+	var response SyncHookResponse
+	if err := s.hook.Execute(hooks.WithParent(ctx, request.Parent), request, &response); err != nil {
+		return nil, fmt.Errorf("hook of state %q failed: %w", s.name, err)
+	}
+	return &response, nil
+}
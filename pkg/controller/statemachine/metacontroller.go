@@ -0,0 +1,95 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statemachine
+
+import (
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/controller/common/lifecycle"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
+	dynamicinformer "metacontroller/pkg/dynamic/informer"
+)
+
+// NewMetacontroller returns the reconciler which runs the controllers of
+// StateMachineControllers.
+func NewMetacontroller(controllerContext common.ControllerContext, numWorkers int, rbacPreflight bool, hookProbeInterval time.Duration) *lifecycle.Reconciler {
+	kind := &stateMachineControllerKind{
+		resources:     controllerContext.Resources,
+		dynClient:     controllerContext.DynClient,
+		dynInformers:  controllerContext.DynInformers,
+		eventRecorder: controllerContext.EventRecorder,
+		numWorkers:    numWorkers,
+	}
+	return lifecycle.NewReconciler(controllerContext, common.StateMachineController, kind, rbacPreflight, hookProbeInterval, "statemachine")
+}
+
+// stateMachineControllerKind adapts StateMachineControllers to a
+// lifecycle.Reconciler.
+type stateMachineControllerKind struct {
+	resources     *dynamicdiscovery.ResourceMap
+	dynClient     *dynamicclientset.Clientset
+	dynInformers  *dynamicinformer.SharedInformerFactory
+	eventRecorder record.EventRecorder
+	numWorkers    int
+}
+
+func (k *stateMachineControllerKind) NewObject() client.Object {
+	return &v1alpha1.StateMachineController{}
+}
+
+func (k *stateMachineControllerKind) Spec(obj client.Object) interface{} {
+	return obj.(*v1alpha1.StateMachineController).Spec
+}
+
+func (k *stateMachineControllerKind) Status(obj client.Object) (*[]metav1.Condition, *int64) {
+	smc := obj.(*v1alpha1.StateMachineController)
+	return &smc.Status.Conditions, &smc.Status.ObservedGeneration
+}
+
+func (k *stateMachineControllerKind) DependsOn(obj client.Object) []v1alpha1.ControllerDependency {
+	return obj.(*v1alpha1.StateMachineController).Spec.DependsOn
+}
+
+func (k *stateMachineControllerKind) RBACRules(obj client.Object) []common.RBACRule {
+	return common.StateMachineControllerRBACRules(obj.(*v1alpha1.StateMachineController))
+}
+
+func (k *stateMachineControllerKind) Webhooks(obj client.Object) map[common.HookType]*v1alpha1.Hook {
+	return smcWebhooks(obj.(*v1alpha1.StateMachineController))
+}
+
+func (k *stateMachineControllerKind) NewController(obj client.Object, logger logr.Logger) (lifecycle.Controller, error) {
+	return newStateMachineController(k.resources, k.dynClient, k.dynInformers, k.eventRecorder, obj.(*v1alpha1.StateMachineController), k.numWorkers, logger)
+}
+
+// smcWebhooks returns the hooks of smc which can be probed, by the hook type
+// of their state.
+func smcWebhooks(smc *v1alpha1.StateMachineController) map[common.HookType]*v1alpha1.Hook {
+	webhooks := make(map[common.HookType]*v1alpha1.Hook, len(smc.Spec.States))
+	for _, s := range smc.Spec.States {
+		webhooks[stateHookType(s.Name)] = s.Hook
+	}
+	return webhooks
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statemachine
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/hooks"
+)
+
+// stateMachine holds the states of a StateMachineController, with the
+// conditions of their transitions compiled.
+type stateMachine struct {
+	initial string
+	states  map[string]*state
+}
+
+type state struct {
+	name        string
+	hook        hooks.HookExecutor
+	transitions []transition
+}
+
+type transition struct {
+	to        string
+	condition string
+	program   cel.Program
+}
+
+// newStateMachine validates the states of smc, and compiles the CEL condition
+// of each transition. newHook returns the executor of the hook of a state.
+func newStateMachine(smc *v1alpha1.StateMachineController, newHook func(name string, hook *v1alpha1.Hook) (hooks.HookExecutor, error)) (*stateMachine, error) {
+	env, err := cel.NewEnv(cel.Declarations(
+		decls.NewVar("parent", decls.Dyn),
+		decls.NewVar("children", decls.Dyn),
+		decls.NewVar("state", decls.String),
+	))
+	if err != nil {
+		return nil, err
+	}
+	m := &stateMachine{
+		initial: smc.Spec.InitialState,
+		states:  make(map[string]*state, len(smc.Spec.States)),
+	}
+	for _, s := range smc.Spec.States {
+		if _, ok := m.states[s.Name]; ok {
+			return nil, fmt.Errorf("duplicate state %q", s.Name)
+		}
+		hook, err := newHook(s.Name, s.Hook)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hook of state %q: %w", s.Name, err)
+		}
+		m.states[s.Name] = &state{name: s.Name, hook: hook}
+	}
+	if _, ok := m.states[m.initial]; !ok {
+		return nil, fmt.Errorf("initial state %q isn't a declared state", m.initial)
+	}
+	for _, s := range smc.Spec.States {
+		for _, t := range s.Transitions {
+			if _, ok := m.states[t.To]; !ok {
+				return nil, fmt.Errorf("transition of state %q to undeclared state %q", s.Name, t.To)
+			}
+			ast, issues := env.Compile(t.Condition)
+			if issues != nil && issues.Err() != nil {
+				return nil, fmt.Errorf("invalid condition of transition from %q to %q: %w", s.Name, t.To, issues.Err())
+			}
+			program, err := env.Program(ast)
+			if err != nil {
+				return nil, fmt.Errorf("invalid condition of transition from %q to %q: %w", s.Name, t.To, err)
+			}
+			m.states[s.Name].transitions = append(m.states[s.Name].transitions, transition{to: t.To, condition: t.Condition, program: program})
+		}
+	}
+	return m, nil
+}
+
+// get returns the state with the given name, or the initial state if name is
+// empty.
+func (m *stateMachine) get(name string) (*state, error) {
+	if name == "" {
+		name = m.initial
+	}
+	s, ok := m.states[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown state %q", name)
+	}
+	return s, nil
+}
+
+// next returns the target of the first transition of s whose condition is
+// true for parent and its children, or an empty string if there's none.
+func (s *state) next(parent *unstructured.Unstructured, children common.RelativeObjectMap) (string, error) {
+	if len(s.transitions) == 0 {
+		return "", nil
+	}
+	childContent := make(map[string]interface{}, len(children))
+	for gvk, group := range children {
+		key, err := gvk.MarshalText()
+		if err != nil {
+			return "", err
+		}
+		objects := make(map[string]interface{}, len(group))
+		for name, obj := range group {
+			objects[name] = obj.UnstructuredContent()
+		}
+		childContent[string(key)] = objects
+	}
+	vars := map[string]interface{}{
+		"parent":   parent.UnstructuredContent(),
+		"children": childContent,
+		"state":    s.name,
+	}
+	for _, t := range s.transitions {
+		out, _, err := t.program.Eval(vars)
+		if err != nil {
+			return "", fmt.Errorf("can't evaluate condition of transition from %q to %q: %w", s.name, t.to, err)
+		}
+		matched, ok := out.Value().(bool)
+		if !ok {
+			return "", fmt.Errorf("condition of transition from %q to %q returned %T instead of a bool", s.name, t.to, out.Value())
+		}
+		if matched {
+			return t.to, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,155 @@
+package statemachine
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	"metacontroller/pkg/hooks"
+)
+
+func noHook(name string, hook *v1alpha1.Hook) (hooks.HookExecutor, error) {
+	return nil, nil
+}
+
+func newTestMachine(t *testing.T, states ...v1alpha1.StateMachineState) *stateMachine {
+	smc := &v1alpha1.StateMachineController{}
+	smc.Spec.InitialState = states[0].Name
+	smc.Spec.States = states
+	machine, err := newStateMachine(smc, noHook)
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	return machine
+}
+
+func TestNewStateMachine_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		initial string
+		states  []v1alpha1.StateMachineState
+		wantErr string
+	}{
+		{
+			name:    "unknown initial state",
+			initial: "Missing",
+			states:  []v1alpha1.StateMachineState{{Name: "Pending"}},
+			wantErr: "initial state",
+		},
+		{
+			name:    "duplicate state",
+			initial: "Pending",
+			states:  []v1alpha1.StateMachineState{{Name: "Pending"}, {Name: "Pending"}},
+			wantErr: "duplicate state",
+		},
+		{
+			name:    "transition to undeclared state",
+			initial: "Pending",
+			states: []v1alpha1.StateMachineState{
+				{Name: "Pending", Transitions: []v1alpha1.StateMachineTransition{{To: "Missing", Condition: "true"}}},
+			},
+			wantErr: "undeclared state",
+		},
+		{
+			name:    "invalid condition",
+			initial: "Pending",
+			states: []v1alpha1.StateMachineState{
+				{Name: "Pending", Transitions: []v1alpha1.StateMachineTransition{{To: "Pending", Condition: "parent.("}}},
+			},
+			wantErr: "invalid condition",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			smc := &v1alpha1.StateMachineController{}
+			smc.Spec.InitialState = tt.initial
+			smc.Spec.States = tt.states
+			_, err := newStateMachine(smc, noHook)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestStateMachine_Get(t *testing.T) {
+	machine := newTestMachine(t, v1alpha1.StateMachineState{Name: "Pending"}, v1alpha1.StateMachineState{Name: "Ready"})
+
+	if s, err := machine.get(""); err != nil || s.name != "Pending" {
+		t.Errorf("expected initial state Pending, got %v, %v", s, err)
+	}
+	if s, err := machine.get("Ready"); err != nil || s.name != "Ready" {
+		t.Errorf("expected state Ready, got %v, %v", s, err)
+	}
+	if _, err := machine.get("Unknown"); err == nil {
+		t.Errorf("expected error for unknown state")
+	}
+}
+
+func TestState_Next(t *testing.T) {
+	machine := newTestMachine(t,
+		v1alpha1.StateMachineState{Name: "Provisioning", Transitions: []v1alpha1.StateMachineTransition{
+			{To: "Failed", Condition: `children["Job.batch/v1"].exists(name, children["Job.batch/v1"][name].status.failed > 0)`},
+			{To: "Ready", Condition: `children["Job.batch/v1"].all(name, children["Job.batch/v1"][name].status.succeeded > 0) && parent.spec.approved`},
+		}},
+		v1alpha1.StateMachineState{Name: "Ready"},
+		v1alpha1.StateMachineState{Name: "Failed"},
+	)
+	provisioning, _ := machine.get("Provisioning")
+	jobGVK := schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"}
+
+	newParent := func(approved bool) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{"approved": approved},
+		}}
+	}
+	newChildren := func(succeeded, failed int64) common.RelativeObjectMap {
+		job := &unstructured.Unstructured{Object: map[string]interface{}{
+			"status": map[string]interface{}{"succeeded": succeeded, "failed": failed},
+		}}
+		job.SetName("setup")
+		children := make(common.RelativeObjectMap)
+		children.InitGroup(jobGVK)
+		children[common.GroupVersionKind{GroupVersionKind: jobGVK}]["setup"] = job
+		return children
+	}
+
+	tests := []struct {
+		name     string
+		parent   *unstructured.Unstructured
+		children common.RelativeObjectMap
+		want     string
+	}{
+		{"no transition", newParent(true), newChildren(0, 0), ""},
+		{"first matching transition", newParent(true), newChildren(1, 1), "Failed"},
+		{"condition over parent", newParent(false), newChildren(1, 0), ""},
+		{"ready", newParent(true), newChildren(1, 0), "Ready"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := provisioning.next(tt.parent, tt.children)
+			if err != nil {
+				t.Fatalf("err should be nil, got: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseStateField(t *testing.T) {
+	if fields, err := parseStateField(""); err != nil || strings.Join(fields, ".") != defaultStateField {
+		t.Errorf("expected default state field, got %v, %v", fields, err)
+	}
+	if fields, err := parseStateField("spec.phase"); err != nil || len(fields) != 2 {
+		t.Errorf("expected spec.phase, got %v, %v", fields, err)
+	}
+	if _, err := parseStateField("status..phase"); err == nil {
+		t.Errorf("expected error for empty path segment")
+	}
+}
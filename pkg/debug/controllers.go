@@ -0,0 +1,171 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"metacontroller/pkg/controller/common"
+	dynamicinformer "metacontroller/pkg/dynamic/informer"
+	"metacontroller/pkg/hooks"
+	"metacontroller/pkg/metrics"
+)
+
+// Controllers holds the running controllers. Their state is served as JSON
+// on the /debug/controllers path of the metrics endpoint.
+var Controllers = NewControllerRegistry()
+
+// ControllerState is the state of a running controller.
+type ControllerState struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Watched are the resources watched by the informers of the controller.
+	Watched []dynamicinformer.WatchState `json:"watched"`
+	Queue   *metrics.QueueState          `json:"queue,omitempty"`
+}
+
+// State is the state served on /debug/controllers.
+type State struct {
+	Controllers []ControllerState `json:"controllers"`
+	// Endpoints are the connection pools and circuit breakers of the webhook
+	// endpoints, shared by all controllers.
+	Endpoints []hooks.EndpointStatus `json:"endpoints"`
+}
+
+type controllerKey struct {
+	name           string
+	controllerType common.ControllerType
+}
+
+// ControllerRegistry holds the running controllers, and how to get the state
+// of their watches.
+type ControllerRegistry struct {
+	mutex       sync.Mutex
+	controllers map[controllerKey]func() []dynamicinformer.WatchState
+}
+
+func NewControllerRegistry() *ControllerRegistry {
+	return &ControllerRegistry{controllers: make(map[controllerKey]func() []dynamicinformer.WatchState)}
+}
+
+// Register adds a running controller, whose watches are described by
+// watched.
+func (r *ControllerRegistry) Register(name string, controllerType common.ControllerType, watched func() []dynamicinformer.WatchState) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.controllers[controllerKey{name: name, controllerType: controllerType}] = watched
+}
+
+// Unregister removes a controller once it's stopped.
+func (r *ControllerRegistry) Unregister(name string, controllerType common.ControllerType) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.controllers, controllerKey{name: name, controllerType: controllerType})
+}
+
+// State returns the state of the running controllers, sorted by type and
+// name, and of the webhook endpoints.
+func (r *ControllerRegistry) State() State {
+	r.mutex.Lock()
+	watched := make(map[controllerKey]func() []dynamicinformer.WatchState, len(r.controllers))
+	for key, fn := range r.controllers {
+		watched[key] = fn
+	}
+	r.mutex.Unlock()
+
+	state := State{
+		Controllers: make([]ControllerState, 0, len(watched)),
+		Endpoints:   hooks.Endpoints.Status(),
+	}
+	for key, fn := range watched {
+		controller := ControllerState{
+			Name:    key.name,
+			Type:    key.controllerType.String(),
+			Watched: fn(),
+		}
+		if queue, ok := metrics.QueueStateOf(key.name, key.controllerType); ok {
+			controller.Queue = &queue
+		}
+		state.Controllers = append(state.Controllers, controller)
+	}
+	sort.Slice(state.Controllers, func(i, j int) bool {
+		if state.Controllers[i].Type != state.Controllers[j].Type {
+			return state.Controllers[i].Type < state.Controllers[j].Type
+		}
+		return state.Controllers[i].Name < state.Controllers[j].Name
+	})
+	return state
+}
+
+// ServeHTTP writes the state of the running controllers as JSON. The "name"
+// query parameter restricts it to the controllers with that name.
+func (r *ControllerRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	state := r.State()
+	if name := req.URL.Query().Get("name"); name != "" {
+		var controllers []ControllerState
+		for _, controller := range state.Controllers {
+			if controller.Name == name {
+				controllers = append(controllers, controller)
+			}
+		}
+		state.Controllers = controllers
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(state)
+}
+
+// Authenticated only lets the requests bearing token through to handler. All
+// requests are rejected if token is empty.
+func Authenticated(token string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		authorization := req.Header.Get("Authorization")
+		bearer := strings.TrimPrefix(authorization, "Bearer ")
+		if token == "" || bearer == authorization || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// AuthenticatedWrites lets the requests which only read, with the GET or HEAD
+// method, through to handler, and the others only if they bear token. The
+// others are forbidden if token is empty.
+func AuthenticatedWrites(token string, handler http.Handler) http.Handler {
+	authenticated := Authenticated(token, handler)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case req.Method == http.MethodGet || req.Method == http.MethodHead:
+			handler.ServeHTTP(w, req)
+		case token == "":
+			http.Error(w, "forbidden: no debug token is configured", http.StatusForbidden)
+		default:
+			authenticated.ServeHTTP(w, req)
+		}
+	})
+}
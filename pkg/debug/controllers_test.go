@@ -0,0 +1,101 @@
+package debug
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"metacontroller/pkg/controller/common"
+	dynamicinformer "metacontroller/pkg/dynamic/informer"
+	"metacontroller/pkg/metrics"
+)
+
+func TestControllerRegistry(t *testing.T) {
+	registry := NewControllerRegistry()
+	queue := metrics.NewControllerQueue("debugged", common.CompositeController)
+	defer queue.ShutDown()
+	queue.Add("default/parent")
+	registry.Register("debugged", common.CompositeController, func() []dynamicinformer.WatchState {
+		return []dynamicinformer.WatchState{{APIVersion: "v1", Resource: "configmaps", Synced: true}}
+	})
+	registry.Register("other", common.DecoratorController, func() []dynamicinformer.WatchState { return nil })
+
+	recorder := httptest.NewRecorder()
+	registry.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/controllers?name=debugged", nil))
+
+	var state State
+	if err := json.Unmarshal(recorder.Body.Bytes(), &state); err != nil {
+		t.Fatalf("can't decode state: %v", err)
+	}
+	if len(state.Controllers) != 1 {
+		t.Fatalf("expected only the named controller, got: %+v", state.Controllers)
+	}
+	controller := state.Controllers[0]
+	if controller.Type != "CompositeController" || len(controller.Watched) != 1 || !controller.Watched[0].Synced {
+		t.Errorf("unexpected controller state: %+v", controller)
+	}
+	if controller.Queue == nil || controller.Queue.Depth != 1 {
+		t.Errorf("expected a queue with one object, got: %+v", controller.Queue)
+	}
+
+	registry.Unregister("debugged", common.CompositeController)
+	if state := registry.State(); len(state.Controllers) != 1 || state.Controllers[0].Name != "other" {
+		t.Errorf("expected only the other controller once unregistered, got: %+v", state.Controllers)
+	}
+}
+
+func TestAuthenticated(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {})
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		want          int
+	}{
+		{name: "valid token", token: "secret", authorization: "Bearer secret", want: http.StatusOK},
+		{name: "wrong token", token: "secret", authorization: "Bearer guess", want: http.StatusUnauthorized},
+		{name: "missing bearer", token: "secret", authorization: "secret", want: http.StatusUnauthorized},
+		{name: "no token configured", authorization: "Bearer ", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/debug/controllers", nil)
+			req.Header.Set("Authorization", tt.authorization)
+			recorder := httptest.NewRecorder()
+			Authenticated(tt.token, handler).ServeHTTP(recorder, req)
+			if recorder.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, recorder.Code)
+			}
+		})
+	}
+}
+
+func TestAuthenticatedWrites(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {})
+	tests := []struct {
+		name          string
+		method        string
+		token         string
+		authorization string
+		want          int
+	}{
+		{name: "read without token", method: http.MethodGet, token: "secret", want: http.StatusOK},
+		{name: "read without token configured", method: http.MethodGet, want: http.StatusOK},
+		{name: "write with valid token", method: http.MethodPost, token: "secret", authorization: "Bearer secret", want: http.StatusOK},
+		{name: "write with wrong token", method: http.MethodPost, token: "secret", authorization: "Bearer guess", want: http.StatusUnauthorized},
+		{name: "write without token", method: http.MethodPost, token: "secret", want: http.StatusUnauthorized},
+		{name: "write without token configured", method: http.MethodPost, authorization: "Bearer ", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/debug/endpoints", nil)
+			req.Header.Set("Authorization", tt.authorization)
+			recorder := httptest.NewRecorder()
+			AuthenticatedWrites(tt.token, handler).ServeHTTP(recorder, req)
+			if recorder.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, recorder.Code)
+			}
+		})
+	}
+}
@@ -18,6 +18,8 @@ limitations under the License.
 // tries to guess the right thing to do without any type-specific knowledge.
 // Instead of generating a PATCH request, it does the patching locally and
 // returns a full object with the ResourceVersion intact.
+// For built-in types, it follows their patch strategies and merge keys, like
+// strategic merge patches do, instead of guessing.
 //
 // We can't use actual `kubectl apply` yet because it doesn't support strategic
 // merge for CRDs, which would make it infeasible to include a PodTemplateSpec
@@ -29,7 +31,10 @@ import (
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 )
 
 const (
@@ -67,18 +72,45 @@ func GetLastApplied(obj *unstructured.Unstructured) (map[string]interface{}, err
 // Merge updates the given observed object to apply the desired changes.
 // It returns an updated copy of the observed object if no error occurs.
 func Merge(observed, lastApplied, desired map[string]interface{}) (map[string]interface{}, error) {
+	return MergeWithPatchMeta(observed, lastApplied, desired, nil)
+}
+
+// MergeWithPatchMeta is like Merge, but follows the patch strategies and
+// merge keys of patchMeta for the fields it knows. The lists of other fields
+// are still guessed to be list maps or not.
+func MergeWithPatchMeta(observed, lastApplied, desired map[string]interface{}, patchMeta strategicpatch.LookupPatchMeta) (map[string]interface{}, error) {
 	// Make a copy of observed since merge() mutates the destination.
 	destination := runtime.DeepCopyJSON(observed)
 
-	if _, err := merge("", destination, lastApplied, desired); err != nil {
+	if _, err := merge("", destination, lastApplied, desired, patchMeta, nil); err != nil {
 		return nil, fmt.Errorf("can't merge desired changes: %w", err)
 	}
 	return destination, nil
 }
 
+// BuiltInPatchMeta returns the patch strategies and merge keys of the given
+// built-in kind, or nil if it isn't a kind known to client-go.
+func BuiltInPatchMeta(apiVersion, kind string) strategicpatch.LookupPatchMeta {
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return nil
+	}
+	obj, err := clientgoscheme.Scheme.New(gv.WithKind(kind))
+	if err != nil {
+		return nil
+	}
+	patchMeta, err := strategicpatch.NewPatchMetaFromStruct(obj)
+	if err != nil {
+		return nil
+	}
+	return patchMeta
+}
+
 // merge finds the diff from lastApplied to desired,
 // and applies it to destination, returning the replacement destination value.
-func merge(fieldPath string, destination, lastApplied, desired interface{}) (interface{}, error) {
+// The type of the value is described by schema, and the patch strategy of
+// its field by fieldMeta, each of which is nil if unknown.
+func merge(fieldPath string, destination, lastApplied, desired interface{}, schema strategicpatch.LookupPatchMeta, fieldMeta *strategicpatch.PatchMeta) (interface{}, error) {
 	switch destVal := destination.(type) {
 	case map[string]interface{}:
 		// destination is an object.
@@ -91,7 +123,10 @@ func merge(fieldPath string, destination, lastApplied, desired interface{}) (int
 		if !ok && desVal != nil {
 			return nil, fmt.Errorf("desired%s: expecting map[string]interface, got %T", fieldPath, desired)
 		}
-		return mergeObject(fieldPath, destVal, lastVal, desVal)
+		if hasPatchStrategy(fieldMeta, "replace") {
+			return desired, nil
+		}
+		return mergeObject(fieldPath, destVal, lastVal, desVal, schema)
 	case []interface{}:
 		// destination is an array.
 		// Make sure the others are arrays too (or null).
@@ -103,7 +138,7 @@ func merge(fieldPath string, destination, lastApplied, desired interface{}) (int
 		if !ok && desVal != nil {
 			return nil, fmt.Errorf("desired%s: expecting []interface, got %T", fieldPath, desired)
 		}
-		return mergeArray(fieldPath, destVal, lastVal, desVal)
+		return mergeArray(fieldPath, destVal, lastVal, desVal, schema, fieldMeta)
 	default:
 		// destination is a scalar or null.
 		// Just take the desired value. We won't be called if there's none.
@@ -111,7 +146,17 @@ func merge(fieldPath string, destination, lastApplied, desired interface{}) (int
 	}
 }
 
-func mergeObject(fieldPath string, destination, lastApplied, desired map[string]interface{}) (interface{}, error) {
+// fieldLookup returns the schema and the patch strategy of the value of the
+// given key of an object, or nil if unknown.
+type fieldLookup func(key string, value interface{}) (strategicpatch.LookupPatchMeta, *strategicpatch.PatchMeta)
+
+func mergeObject(fieldPath string, destination, lastApplied, desired map[string]interface{}, schema strategicpatch.LookupPatchMeta) (interface{}, error) {
+	return mergeEntries(fieldPath, destination, lastApplied, desired, func(key string, value interface{}) (strategicpatch.LookupPatchMeta, *strategicpatch.PatchMeta) {
+		return lookupField(schema, key, value)
+	})
+}
+
+func mergeEntries(fieldPath string, destination, lastApplied, desired map[string]interface{}, lookup fieldLookup) (interface{}, error) {
 	// Remove fields that were present in lastApplied, but no longer in desired.
 	for key := range lastApplied {
 		if _, present := desired[key]; !present {
@@ -122,7 +167,13 @@ func mergeObject(fieldPath string, destination, lastApplied, desired map[string]
 	// Add/Update all fields present in desired.
 	var err error
 	for key, desVal := range desired {
-		destination[key], err = merge(fmt.Sprintf("%s[%s]", fieldPath, key), destination[key], lastApplied[key], desVal)
+		if desVal == nil {
+			// An explicit null removes the field, whoever set it.
+			delete(destination, key)
+			continue
+		}
+		schema, fieldMeta := lookup(key, desVal)
+		destination[key], err = merge(fmt.Sprintf("%s[%s]", fieldPath, key), destination[key], lastApplied[key], desVal, schema, fieldMeta)
 		if err != nil {
 			return nil, err
 		}
@@ -131,10 +182,61 @@ func mergeObject(fieldPath string, destination, lastApplied, desired map[string]
 	return destination, nil
 }
 
-func mergeArray(fieldPath string, destination, lastApplied, desired []interface{}) (interface{}, error) {
+// lookupField returns the schema and the patch strategy of the given field
+// of an object described by schema. For lists, the schema is the one of their
+// items.
+func lookupField(schema strategicpatch.LookupPatchMeta, key string, value interface{}) (strategicpatch.LookupPatchMeta, *strategicpatch.PatchMeta) {
+	if schema == nil {
+		return nil, nil
+	}
+	var fieldSchema strategicpatch.LookupPatchMeta
+	var fieldMeta strategicpatch.PatchMeta
+	var err error
+	if _, ok := value.([]interface{}); ok {
+		fieldSchema, fieldMeta, err = schema.LookupPatchMetadataForSlice(key)
+	} else {
+		fieldSchema, fieldMeta, err = schema.LookupPatchMetadataForStruct(key)
+	}
+	if err != nil {
+		// The field isn't part of the type, or is a map without a schema of
+		// its own, e.g. labels.
+		return nil, nil
+	}
+	return fieldSchema, &fieldMeta
+}
+
+func hasPatchStrategy(fieldMeta *strategicpatch.PatchMeta, strategy string) bool {
+	if fieldMeta == nil {
+		return false
+	}
+	for _, s := range fieldMeta.GetPatchStrategies() {
+		if s == strategy {
+			return true
+		}
+	}
+	return false
+}
+
+func mergeArray(fieldPath string, destination, lastApplied, desired []interface{}, schema strategicpatch.LookupPatchMeta, fieldMeta *strategicpatch.PatchMeta) (interface{}, error) {
+	// If the type of the list is known, follow its patch strategy.
+	if fieldMeta != nil {
+		if !hasPatchStrategy(fieldMeta, "merge") {
+			return desired, nil
+		}
+		mergeKey := fieldMeta.GetPatchMergeKey()
+		if mergeKey == "" {
+			return mergeScalarList(destination, lastApplied, desired), nil
+		}
+		if !isListMap(mergeKey, destination, lastApplied, desired) {
+			// Items without the merge key can't be merged.
+			return desired, nil
+		}
+		return mergeListMap(fieldPath, mergeKey, destination, lastApplied, desired, schema)
+	}
+
 	// If it looks like a list map, use the special merge.
 	if mergeKey := detectListMapKey(destination, lastApplied, desired); mergeKey != "" {
-		return mergeListMap(fieldPath, mergeKey, destination, lastApplied, desired)
+		return mergeListMap(fieldPath, mergeKey, destination, lastApplied, desired, nil)
 	}
 
 	// It's a normal array. Just replace for now.
@@ -142,13 +244,15 @@ func mergeArray(fieldPath string, destination, lastApplied, desired []interface{
 	return desired, nil
 }
 
-func mergeListMap(fieldPath, mergeKey string, destination, lastApplied, desired []interface{}) (interface{}, error) {
+func mergeListMap(fieldPath, mergeKey string, destination, lastApplied, desired []interface{}, itemSchema strategicpatch.LookupPatchMeta) (interface{}, error) {
 	// Treat each list of objects as if it were a map, keyed by the mergeKey field.
 	destMap := makeListMap(mergeKey, destination)
 	lastMap := makeListMap(mergeKey, lastApplied)
 	desMap := makeListMap(mergeKey, desired)
 
-	_, err := mergeObject(fieldPath, destMap, lastMap, desMap)
+	_, err := mergeEntries(fieldPath, destMap, lastMap, desMap, func(string, interface{}) (strategicpatch.LookupPatchMeta, *strategicpatch.PatchMeta) {
+		return itemSchema, nil
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -177,6 +281,52 @@ func mergeListMap(fieldPath, mergeKey string, destination, lastApplied, desired
 	return destList, nil
 }
 
+// mergeScalarList merges lists of scalars as sets, like the finalizers of
+// objects: the items removed from lastApplied are removed, and the desired
+// items are added after the existing ones.
+func mergeScalarList(destination, lastApplied, desired []interface{}) []interface{} {
+	desiredSet := make(map[string]bool, len(desired))
+	for _, item := range desired {
+		desiredSet[stringMergeKey(item)] = true
+	}
+	removed := make(map[string]bool, len(lastApplied))
+	for _, item := range lastApplied {
+		if key := stringMergeKey(item); !desiredSet[key] {
+			removed[key] = true
+		}
+	}
+	destList := make([]interface{}, 0, len(destination)+len(desired))
+	added := make(map[string]bool, len(destination)+len(desired))
+	for _, list := range [][]interface{}{destination, desired} {
+		for _, item := range list {
+			key := stringMergeKey(item)
+			if removed[key] || added[key] {
+				continue
+			}
+			destList = append(destList, item)
+			added[key] = true
+		}
+	}
+	return destList
+}
+
+// isListMap returns whether all items of the lists are objects with the given
+// merge key.
+func isListMap(mergeKey string, lists ...[]interface{}) bool {
+	for _, list := range lists {
+		for _, item := range list {
+			obj, ok := item.(map[string]interface{})
+			if !ok {
+				return false
+			}
+			if _, ok := obj[mergeKey]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 func makeListMap(mergeKey string, list []interface{}) map[string]interface{} {
 	res := make(map[string]interface{}, len(list))
 	for _, item := range list {
@@ -52,6 +52,19 @@ func TestMerge(t *testing.T) {
 				"keep": "other"
 			}`,
 		},
+		{
+			name: "explicit null",
+			observed: `{
+				"scalar": "other",
+				"object": {"keep": "other"},
+				"nested": {"remove": "other", "keep": "other"}
+			}`,
+			lastApplied: `{}`,
+			desired:     `{"scalar": null, "object": null, "nested": {"remove": null}}`,
+			want: `{
+				"nested": {"keep": "other"}
+			}`,
+		},
 		{
 			name: "nested object",
 			observed: `{
@@ -208,3 +221,134 @@ func TestLastAppliedAnnotation(t *testing.T) {
 		t.Errorf("got %#v, want %#v", out, in)
 	}
 }
+
+func TestMergeWithPatchMeta(t *testing.T) {
+	table := []struct {
+		name, observed, lastApplied, desired, want string
+	}{
+		{
+			name: "merge key which isn't guessed",
+			observed: `{
+				"spec": {"containers": [{"name": "app", "volumeMounts": [
+					{"name": "data", "mountPath": "/data"},
+					{"name": "data", "mountPath": "/cache"}
+				]}]}
+			}`,
+			lastApplied: `{
+				"spec": {"containers": [{"name": "app", "volumeMounts": [
+					{"name": "data", "mountPath": "/data"}
+				]}]}
+			}`,
+			desired: `{
+				"spec": {"containers": [{"name": "app", "volumeMounts": [
+					{"name": "data", "mountPath": "/data", "readOnly": true}
+				]}]}
+			}`,
+			want: `{
+				"spec": {"containers": [{"name": "app", "volumeMounts": [
+					{"name": "data", "mountPath": "/data", "readOnly": true},
+					{"name": "data", "mountPath": "/cache"}
+				]}]}
+			}`,
+		},
+		{
+			name: "atomic list of objects with a conventional key",
+			observed: `{
+				"spec": {"containers": [{"name": "app", "env": [
+					{"name": "A", "value": "1"},
+					{"name": "INJECTED", "value": "x"}
+				]}], "securityContext": {"sysctls": [
+					{"name": "net.core.somaxconn", "value": "1024"},
+					{"name": "kernel.shm_rmid_forced", "value": "1"}
+				]}}
+			}`,
+			lastApplied: `{
+				"spec": {"containers": [{"name": "app", "env": [{"name": "A", "value": "1"}]}],
+				"securityContext": {"sysctls": [{"name": "net.core.somaxconn", "value": "1024"}]}}
+			}`,
+			desired: `{
+				"spec": {"containers": [{"name": "app", "env": [{"name": "A", "value": "2"}]}],
+				"securityContext": {"sysctls": [{"name": "net.core.somaxconn", "value": "2048"}]}}
+			}`,
+			want: `{
+				"spec": {"containers": [{"name": "app", "env": [
+					{"name": "A", "value": "2"},
+					{"name": "INJECTED", "value": "x"}
+				]}], "securityContext": {"sysctls": [
+					{"name": "net.core.somaxconn", "value": "2048"}
+				]}}
+			}`,
+		},
+		{
+			name: "scalar list with merge strategy",
+			observed: `{
+				"metadata": {"finalizers": ["other", "old"]},
+				"spec": {"containers": [{"name": "app", "args": ["--a", "--b"]}]}
+			}`,
+			lastApplied: `{
+				"metadata": {"finalizers": ["old"]},
+				"spec": {"containers": [{"name": "app", "args": ["--a"]}]}
+			}`,
+			desired: `{
+				"metadata": {"finalizers": ["new"]},
+				"spec": {"containers": [{"name": "app", "args": ["--c"]}]}
+			}`,
+			want: `{
+				"metadata": {"finalizers": ["other", "new"]},
+				"spec": {"containers": [{"name": "app", "args": ["--c"]}]}
+			}`,
+		},
+		{
+			name: "unknown fields are guessed",
+			observed: `{
+				"metadata": {"labels": {"keep": "other"}},
+				"spec": {"unknown": [{"name": "a", "value": "1"}, {"name": "b"}]}
+			}`,
+			lastApplied: `{"spec": {"unknown": [{"name": "a", "value": "1"}]}}`,
+			desired: `{
+				"metadata": {"labels": {"add": "new"}},
+				"spec": {"unknown": [{"name": "a", "value": "2"}]}
+			}`,
+			want: `{
+				"metadata": {"labels": {"keep": "other", "add": "new"}},
+				"spec": {"unknown": [{"name": "a", "value": "2"}, {"name": "b"}]}
+			}`,
+		},
+	}
+
+	patchMeta := BuiltInPatchMeta("v1", "Pod")
+	if patchMeta == nil {
+		t.Fatal("expected patch meta of v1 Pod")
+	}
+	for _, tc := range table {
+		var observed, lastApplied, desired, want map[string]interface{}
+		for _, in := range []struct {
+			json string
+			into *map[string]interface{}
+		}{{tc.observed, &observed}, {tc.lastApplied, &lastApplied}, {tc.desired, &desired}, {tc.want, &want}} {
+			if err := json.Unmarshal([]byte(in.json), in.into); err != nil {
+				t.Fatalf("%v: can't unmarshal %s: %v", tc.name, in.json, err)
+			}
+		}
+
+		got, err := MergeWithPatchMeta(observed, lastApplied, desired, patchMeta)
+		if err != nil {
+			t.Errorf("%v: MergeWithPatchMeta error: %v", tc.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Logf("reflect diff: a=got, b=want:\n%s", cmp.Diff(got, want))
+			t.Errorf("%v: MergeWithPatchMeta() = %#v, want %#v", tc.name, got, want)
+		}
+	}
+}
+
+func TestBuiltInPatchMeta(t *testing.T) {
+	if BuiltInPatchMeta("apps/v1", "Deployment") == nil {
+		t.Error("expected patch meta of apps/v1 Deployment")
+	}
+	if BuiltInPatchMeta("ctl.enisoc.com/v1", "CatSet") != nil {
+		t.Error("expected no patch meta of a custom resource")
+	}
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clientset
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+
+	"metacontroller/pkg/audit"
+	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
+)
+
+// Audited returns a copy of the ResourceClient which records each of its
+// writes with recorder, including those made by its convenience functions,
+// e.g. AtomicUpdate and AddFinalizer. Updates which turn out to be no-ops
+// aren't sent, so they aren't recorded either.
+func (rc *ResourceClient) Audited(recorder audit.Recorder) *ResourceClient {
+	return &ResourceClient{
+		ResourceInterface: &auditedResourceInterface{ResourceInterface: unaudited(rc.ResourceInterface), apiResource: rc.APIResource, namespace: rc.namespace, recorder: recorder},
+		APIResource:       rc.APIResource,
+		rootClient:        rc.rootClient,
+		status:            &auditedResourceInterface{ResourceInterface: unaudited(rc.status), apiResource: rc.APIResource, namespace: rc.namespace, recorder: recorder},
+		rootStatusClient:  rc.rootStatusClient,
+		namespace:         rc.namespace,
+		recorder:          &recorder,
+	}
+}
+
+// unaudited returns ri without its recorder, if it has one.
+func unaudited(ri dynamic.ResourceInterface) dynamic.ResourceInterface {
+	if audited, ok := ri.(*auditedResourceInterface); ok {
+		return audited.ResourceInterface
+	}
+	return ri
+}
+
+// auditedResourceInterface records the writes made through a
+// dynamic.ResourceInterface scoped to namespace.
+type auditedResourceInterface struct {
+	dynamic.ResourceInterface
+	apiResource *dynamicdiscovery.APIResource
+	namespace   string
+	recorder    audit.Recorder
+}
+
+func (ri *auditedResourceInterface) reference(namespace, name string) audit.Reference {
+	if namespace == "" {
+		namespace = ri.namespace
+	}
+	return audit.Reference{
+		APIVersion: ri.apiResource.APIVersion,
+		Kind:       ri.apiResource.Kind,
+		Namespace:  namespace,
+		Name:       name,
+	}
+}
+
+func subresourceOf(subresources []string) string {
+	if len(subresources) == 0 {
+		return ""
+	}
+	return subresources[0]
+}
+
+func (ri *auditedResourceInterface) Create(ctx context.Context, obj *unstructured.Unstructured, opts metav1.CreateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	result, err := ri.ResourceInterface.Create(ctx, obj, opts, subresources...)
+	ri.recorder.Record(ri.reference(obj.GetNamespace(), obj.GetName()), audit.ActionCreate, subresourceOf(subresources), len(opts.DryRun) > 0, err)
+	return result, err
+}
+
+func (ri *auditedResourceInterface) Update(ctx context.Context, obj *unstructured.Unstructured, opts metav1.UpdateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	result, err := ri.ResourceInterface.Update(ctx, obj, opts, subresources...)
+	ri.recorder.Record(ri.reference(obj.GetNamespace(), obj.GetName()), audit.ActionUpdate, subresourceOf(subresources), len(opts.DryRun) > 0, err)
+	return result, err
+}
+
+func (ri *auditedResourceInterface) UpdateStatus(ctx context.Context, obj *unstructured.Unstructured, opts metav1.UpdateOptions) (*unstructured.Unstructured, error) {
+	result, err := ri.ResourceInterface.UpdateStatus(ctx, obj, opts)
+	ri.recorder.Record(ri.reference(obj.GetNamespace(), obj.GetName()), audit.ActionUpdate, "status", len(opts.DryRun) > 0, err)
+	return result, err
+}
+
+func (ri *auditedResourceInterface) Delete(ctx context.Context, name string, opts metav1.DeleteOptions, subresources ...string) error {
+	err := ri.ResourceInterface.Delete(ctx, name, opts, subresources...)
+	ri.recorder.Record(ri.reference("", name), audit.ActionDelete, subresourceOf(subresources), len(opts.DryRun) > 0, err)
+	return err
+}
+
+func (ri *auditedResourceInterface) DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error {
+	err := ri.ResourceInterface.DeleteCollection(ctx, opts, listOpts)
+	ri.recorder.Record(ri.reference("", ""), audit.ActionDelete, "", len(opts.DryRun) > 0, err)
+	return err
+}
+
+func (ri *auditedResourceInterface) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	result, err := ri.ResourceInterface.Patch(ctx, name, pt, data, opts, subresources...)
+	ri.recorder.Record(ri.reference("", name), audit.ActionPatch, subresourceOf(subresources), len(opts.DryRun) > 0, err)
+	return result, err
+}
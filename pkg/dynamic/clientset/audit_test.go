@@ -0,0 +1,123 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clientset
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"metacontroller/pkg/audit"
+	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
+)
+
+func newTestClientset(objects ...runtime.Object) *Clientset {
+	return newTestClientsetFor(fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), objects...))
+}
+
+func newTestClientsetFor(dc *fakedynamic.FakeDynamicClient) *Clientset {
+	discovery := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{
+			{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+		},
+	}}}}
+	resources := dynamicdiscovery.NewResourceMap(discovery)
+	resources.Refresh()
+	return NewForDynamicClient(resources, dc)
+}
+
+func newTestConfigMap(name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetNamespace("default")
+	obj.SetName(name)
+	obj.SetUID(types.UID("uid-" + name))
+	return obj
+}
+
+func auditEntries(t *testing.T, out *bytes.Buffer) []audit.Entry {
+	var entries []audit.Entry
+	for _, line := range bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var entry audit.Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatalf("can't decode entry: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestResourceClient_Audited(t *testing.T) {
+	var out bytes.Buffer
+	audit.Log.SetOutput(&out)
+	defer audit.Log.SetOutput(nil)
+
+	parent := newTestConfigMap("parent")
+	cs := newTestClientset(parent)
+	client, err := cs.Resource("v1", "configmaps")
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	recorder := audit.Recorder{
+		Controller: audit.Controller{Name: "my-controller", Type: "CompositeController"},
+		Parent:     audit.ObjectReference(parent),
+	}
+	audited := client.Audited(recorder).Namespace("default")
+
+	if _, err := audited.AddFinalizer(parent, "my-finalizer"); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	// The finalizer is already there, so nothing is written.
+	if _, err := audited.AddFinalizer(parent, "my-finalizer"); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if err := audited.Delete(context.TODO(), "missing", metav1.DeleteOptions{}); err == nil {
+		t.Fatalf("expected an error deleting a missing object")
+	}
+	// Writes made without the audited client aren't recorded.
+	if _, err := client.Namespace("default").RemoveFinalizer(parent, "my-finalizer"); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	entries := auditEntries(t, &out)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d: %s", len(entries), out.String())
+	}
+	update := entries[0]
+	if update.Action != audit.ActionUpdate || update.Result != "Succeeded" || update.Controller != recorder.Controller || update.Parent != recorder.Parent || update.Child != recorder.Parent {
+		t.Errorf("unexpected entry of the finalizer update: %+v", update)
+	}
+	del := entries[1]
+	wantChild := audit.Reference{APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "missing"}
+	if del.Action != audit.ActionDelete || del.Result != "Failed" || del.Error == "" || del.Child != wantChild {
+		t.Errorf("unexpected entry of the failed delete: %+v", del)
+	}
+}
@@ -18,15 +18,20 @@ package clientset
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/pointer"
 
+	"metacontroller/pkg/audit"
 	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
 	dynamicobject "metacontroller/pkg/dynamic/object"
 )
@@ -35,24 +40,84 @@ type Clientset struct {
 	config    rest.Config
 	resources *dynamicdiscovery.ResourceMap
 	dc        dynamic.Interface
+	// statusDc writes the status of objects. It's dc, unless status writes
+	// have their own rate limits.
+	statusDc dynamic.Interface
 }
 
+// RateLimits are the client-side rate limits of the requests of a Clientset.
+type RateLimits struct {
+	QPS   float32
+	Burst int
+	// StatusQPS and StatusBurst, if StatusQPS is positive, give the status
+	// writes their own budget, separate from the other requests. Otherwise
+	// the status writes share the budget of QPS and Burst.
+	StatusQPS   float32
+	StatusBurst int
+}
+
+// New returns a Clientset with the rate limits of config.
 func New(config *rest.Config, resources *dynamicdiscovery.ResourceMap) (*Clientset, error) {
-	dc, err := dynamic.NewForConfig(config)
+	return NewWithRateLimits(config, resources, RateLimits{QPS: config.QPS, Burst: config.Burst})
+}
+
+// NewWithRateLimits returns a Clientset with the given rate limits, instead
+// of those of config.
+func NewWithRateLimits(config *rest.Config, resources *dynamicdiscovery.ResourceMap, limits RateLimits) (*Clientset, error) {
+	dc, err := newDynamicClient(config, limits.QPS, limits.Burst)
 	if err != nil {
 		return nil, fmt.Errorf("can't create dynamic client when creating clientset: %w", err)
 	}
+	statusDc := dc
+	if limits.StatusQPS > 0 {
+		statusDc, err = newDynamicClient(config, limits.StatusQPS, limits.StatusBurst)
+		if err != nil {
+			return nil, fmt.Errorf("can't create dynamic status client when creating clientset: %w", err)
+		}
+	}
 	return &Clientset{
 		config:    *config,
 		resources: resources,
 		dc:        dc,
+		statusDc:  statusDc,
 	}, nil
 }
 
+// NewForDynamicClient returns a Clientset which makes all its requests with dc,
+// e.g. a fake dynamic client in tests.
+func NewForDynamicClient(resources *dynamicdiscovery.ResourceMap, dc dynamic.Interface) *Clientset {
+	return &Clientset{
+		resources: resources,
+		dc:        dc,
+		statusDc:  dc,
+	}
+}
+
+// WithRateLimits returns a Clientset sharing the discovery of cs, with its
+// own rate limiters, so its requests don't use the budget of cs.
+func (cs *Clientset) WithRateLimits(limits RateLimits) (*Clientset, error) {
+	return NewWithRateLimits(&cs.config, cs.resources, limits)
+}
+
+// newDynamicClient returns a dynamic client with its own rate limiter.
+func newDynamicClient(config *rest.Config, qps float32, burst int) (dynamic.Interface, error) {
+	config = rest.CopyConfig(config)
+	config.QPS = qps
+	config.Burst = burst
+	config.RateLimiter = nil
+	return dynamic.NewForConfig(config)
+}
+
 func (cs *Clientset) HasSynced() bool {
 	return cs.resources.HasSynced()
 }
 
+// RefreshDiscovery refreshes the discovery info used to look up resources,
+// for example after creating a CRD.
+func (cs *Clientset) RefreshDiscovery() {
+	cs.resources.Refresh()
+}
+
 func (cs *Clientset) Resource(apiVersion, resource string) (*ResourceClient, error) {
 	// Look up the requested resource in discovery.
 	apiResource := cs.resources.Get(apiVersion, resource)
@@ -64,19 +129,22 @@ func (cs *Clientset) Resource(apiVersion, resource string) (*ResourceClient, err
 
 func (cs *Clientset) Kind(apiVersion, kind string) (*ResourceClient, error) {
 	// Look up the requested resource in discovery.
-	apiResource := cs.resources.GetKind(apiVersion, kind)
-	if apiResource == nil {
-		return nil, fmt.Errorf("discovery: can't find kind %s in apiVersion %s", kind, apiVersion)
+	apiResource, err := cs.resources.LookupKind(apiVersion, kind)
+	if err != nil {
+		return nil, err
 	}
 	return cs.resource(apiResource), nil
 }
 
 func (cs *Clientset) resource(apiResource *dynamicdiscovery.APIResource) *ResourceClient {
 	client := cs.dc.Resource(apiResource.GroupVersionResource())
+	statusClient := cs.statusDc.Resource(apiResource.GroupVersionResource())
 	return &ResourceClient{
 		ResourceInterface: client,
 		APIResource:       apiResource,
 		rootClient:        client,
+		status:            statusClient,
+		rootStatusClient:  statusClient,
 	}
 }
 
@@ -93,6 +161,15 @@ type ResourceClient struct {
 	*dynamicdiscovery.APIResource
 
 	rootClient dynamic.NamespaceableResourceInterface
+
+	// status writes the status of objects, with the status rate limits of
+	// the Clientset.
+	status           dynamic.ResourceInterface
+	rootStatusClient dynamic.NamespaceableResourceInterface
+
+	namespace string
+	// recorder, if set, records the writes in the audit log.
+	recorder *audit.Recorder
 }
 
 // Namespace returns a copy of the ResourceClient with the client namespace set.
@@ -107,14 +184,47 @@ func (rc *ResourceClient) Namespace(namespace string) *ResourceClient {
 	}
 	// Reset to cluster-scoped if provided namespace is empty.
 	ri := dynamic.ResourceInterface(rc.rootClient)
+	status := dynamic.ResourceInterface(rc.rootStatusClient)
 	if namespace != "" {
 		ri = rc.rootClient.Namespace(namespace)
+		status = rc.rootStatusClient.Namespace(namespace)
 	}
-	return &ResourceClient{
+	namespaced := &ResourceClient{
 		ResourceInterface: ri,
 		APIResource:       rc.APIResource,
 		rootClient:        rc.rootClient,
+		status:            status,
+		rootStatusClient:  rc.rootStatusClient,
+		namespace:         namespace,
 	}
+	if rc.recorder != nil {
+		return namespaced.Audited(*rc.recorder)
+	}
+	return namespaced
+}
+
+// Update replaces obj, leaving its managed fields as they are on the server.
+// Informers may only cache part of the managed fields of objects, which would
+// otherwise replace the real ones.
+func (rc *ResourceClient) Update(ctx context.Context, obj *unstructured.Unstructured, opts metav1.UpdateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	return rc.ResourceInterface.Update(ctx, withoutManagedFields(obj), opts, subresources...)
+}
+
+// UpdateStatus replaces the status of obj, leaving its managed fields as they
+// are on the server, like Update.
+func (rc *ResourceClient) UpdateStatus(ctx context.Context, obj *unstructured.Unstructured, opts metav1.UpdateOptions) (*unstructured.Unstructured, error) {
+	return rc.status.UpdateStatus(ctx, withoutManagedFields(obj), opts)
+}
+
+// withoutManagedFields returns obj, or a copy of it without managed fields if
+// it has some.
+func withoutManagedFields(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	if _, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "metadata", "managedFields"); !found {
+		return obj
+	}
+	obj = obj.DeepCopy()
+	unstructured.RemoveNestedField(obj.Object, "metadata", "managedFields")
+	return obj
 }
 
 // AtomicUpdate performs an atomic read-modify-write loop, retrying on
@@ -201,9 +311,123 @@ func (rc *ResourceClient) AtomicStatusUpdate(orig *unstructured.Unstructured, up
 		if rc.HasSubresource("status") {
 			result, err = rc.UpdateStatus(context.TODO(), current, metav1.UpdateOptions{})
 		} else {
-			result, err = rc.Update(context.TODO(), current, metav1.UpdateOptions{})
+			result, err = rc.status.Update(context.TODO(), withoutManagedFields(current), metav1.UpdateOptions{})
 		}
 		return err
 	})
 	return result, err
 }
+
+// PatchStatus applies a JSON merge patch to the status of the object.
+//
+// The patch was computed against the status of the provided 'orig' object, so
+// its uid and resourceVersion are included in the patch: the request fails
+// with a Conflict if the object was changed since, or deleted and replaced
+// with a new one, instead of applying the patch to another status.
+func (rc *ResourceClient) PatchStatus(orig *unstructured.Unstructured, statusPatch map[string]interface{}) (*unstructured.Unstructured, error) {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"uid":             orig.GetUID(),
+			"resourceVersion": orig.GetResourceVersion(),
+		},
+		"status": statusPatch,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't marshal status patch: %w", err)
+	}
+	var subresources []string
+	if rc.HasSubresource("status") {
+		subresources = append(subresources, "status")
+	}
+	return rc.status.Patch(context.TODO(), orig.GetName(), types.MergePatchType, patch, metav1.PatchOptions{}, subresources...)
+}
+
+// ApplyStatus server-side applies status to the status subresource of the
+// object, if any, as fieldManager. Conflicts are forced, and the fields
+// previously applied by fieldManager but missing from status are removed.
+//
+// Like AtomicStatusUpdate, it only uses the identity (name/uid) of the
+// provided 'orig' object.
+func (rc *ResourceClient) ApplyStatus(orig *unstructured.Unstructured, status map[string]interface{}, fieldManager string) (*unstructured.Unstructured, error) {
+	patch, err := json.Marshal(map[string]interface{}{
+		"apiVersion": orig.GetAPIVersion(),
+		"kind":       orig.GetKind(),
+		"metadata": map[string]interface{}{
+			"name": orig.GetName(),
+			"uid":  orig.GetUID(),
+		},
+		"status": status,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("can't marshal status apply patch: %w", err)
+	}
+	var subresources []string
+	if rc.HasSubresource("status") {
+		subresources = append(subresources, "status")
+	}
+	return rc.status.Patch(context.TODO(), orig.GetName(), types.ApplyPatchType, patch, metav1.PatchOptions{
+		FieldManager: fieldManager,
+		Force:        pointer.BoolPtr(true),
+	}, subresources...)
+}
+
+// subresource returns the discovery details of the given subresource, or an
+// error if the resource doesn't have it, or it doesn't support verb.
+func (rc *ResourceClient) subresource(subresource, verb string) (*dynamicdiscovery.APIResource, error) {
+	apiResource := rc.Subresource(subresource)
+	if apiResource == nil {
+		return nil, fmt.Errorf("discovery: resource %s in apiVersion %s has no %s subresource", rc.Name, rc.APIVersion, subresource)
+	}
+	if !apiResource.HasVerb(verb) {
+		return nil, fmt.Errorf("discovery: subresource %s/%s in apiVersion %s doesn't support %s", rc.Name, subresource, rc.APIVersion, verb)
+	}
+	return apiResource, nil
+}
+
+// GetSubresource returns the given subresource of the named object, e.g. its
+// scale, as the kind discovery lists for the subresource.
+func (rc *ResourceClient) GetSubresource(name, subresource string) (*unstructured.Unstructured, error) {
+	if _, err := rc.subresource(subresource, "get"); err != nil {
+		return nil, err
+	}
+	return rc.Get(context.TODO(), name, metav1.GetOptions{}, subresource)
+}
+
+// UpdateSubresource replaces the given subresource of the object named like
+// obj, e.g. the approval of a CertificateSigningRequest, with obj.
+func (rc *ResourceClient) UpdateSubresource(obj *unstructured.Unstructured, subresource string) (*unstructured.Unstructured, error) {
+	if _, err := rc.subresource(subresource, "update"); err != nil {
+		return nil, err
+	}
+	return rc.Update(context.TODO(), obj, metav1.UpdateOptions{}, subresource)
+}
+
+// PatchSubresource applies a JSON merge patch to the given subresource of the
+// named object, e.g. {"spec": {"replicas": 3}} to its scale.
+func (rc *ResourceClient) PatchSubresource(name, subresource string, patch map[string]interface{}) (*unstructured.Unstructured, error) {
+	if _, err := rc.subresource(subresource, "patch"); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return nil, fmt.Errorf("can't marshal %s patch: %w", subresource, err)
+	}
+	return rc.Patch(context.TODO(), name, types.MergePatchType, data, metav1.PatchOptions{}, subresource)
+}
+
+// CreateSubresource posts obj to the given subresource of the named object,
+// e.g. a TokenRequest to the token of a ServiceAccount, and returns the
+// response.
+func (rc *ResourceClient) CreateSubresource(name, subresource string, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	apiResource, err := rc.subresource(subresource, "create")
+	if err != nil {
+		return nil, err
+	}
+	// The dynamic client takes the name of the object from the request body.
+	request := obj.DeepCopy()
+	request.SetName(name)
+	if request.GetAPIVersion() == "" || request.GetKind() == "" {
+		request.SetGroupVersionKind(schema.GroupVersionKind{Group: apiResource.Group, Version: apiResource.Version, Kind: apiResource.Kind})
+	}
+	return rc.Create(context.TODO(), request, metav1.CreateOptions{}, subresource)
+}
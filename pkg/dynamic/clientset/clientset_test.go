@@ -0,0 +1,107 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clientset
+
+import (
+	"encoding/json"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/rest"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// withOptimisticConcurrency makes dc reject the patches of configmaps with a
+// resourceVersion which isn't the stored one, like the API server does.
+func withOptimisticConcurrency(dc *fakedynamic.FakeDynamicClient) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	dc.PrependReactor("patch", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		patch := action.(clienttesting.PatchAction)
+		var data struct {
+			Metadata struct {
+				ResourceVersion string `json:"resourceVersion"`
+			} `json:"metadata"`
+		}
+		if err := json.Unmarshal(patch.GetPatch(), &data); err != nil {
+			return true, nil, err
+		}
+		stored, err := dc.Tracker().Get(gvr, patch.GetNamespace(), patch.GetName())
+		if err != nil {
+			return true, nil, err
+		}
+		resourceVersion := stored.(metav1.Object).GetResourceVersion()
+		if data.Metadata.ResourceVersion != "" && data.Metadata.ResourceVersion != resourceVersion {
+			return true, nil, apierrors.NewConflict(gvr.GroupResource(), patch.GetName(), nil)
+		}
+		return false, nil, nil
+	})
+}
+
+func TestResourceClient_PatchStatus(t *testing.T) {
+	stored := newTestConfigMap("parent")
+	stored.SetResourceVersion("2")
+	if err := unstructured.SetNestedField(stored.Object, "2", "status", "observed"); err != nil {
+		t.Fatal(err)
+	}
+	dc := fakedynamic.NewSimpleDynamicClient(runtime.NewScheme(), stored)
+	withOptimisticConcurrency(dc)
+	client, err := newTestClientsetFor(dc).Resource("v1", "configmaps")
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	// The patch was computed against an older status.
+	stale := stored.DeepCopy()
+	stale.SetResourceVersion("1")
+	_, err = client.Namespace("default").PatchStatus(stale, map[string]interface{}{"observed": "1"})
+	if !apierrors.IsConflict(err) {
+		t.Fatalf("expected a Conflict patching from a stale object, got: %v", err)
+	}
+
+	updated, err := client.Namespace("default").PatchStatus(stored, map[string]interface{}{"observed": "3"})
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if observed, _, _ := unstructured.NestedString(updated.Object, "status", "observed"); observed != "3" {
+		t.Errorf("expected the status to be patched, got: %v", updated.Object["status"])
+	}
+}
+
+func TestNewWithRateLimits(t *testing.T) {
+	config := &rest.Config{Host: "https://example.com"}
+
+	shared, err := NewWithRateLimits(config, nil, RateLimits{QPS: 5, Burst: 5})
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if shared.statusDc != shared.dc {
+		t.Errorf("expected the status writes to share the budget of the other requests without StatusQPS")
+	}
+
+	separate, err := NewWithRateLimits(config, nil, RateLimits{QPS: 5, Burst: 5, StatusQPS: 1, StatusBurst: 1})
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if separate.statusDc == separate.dc {
+		t.Errorf("expected the status writes to have their own budget with StatusQPS")
+	}
+}
@@ -19,6 +19,7 @@ package discovery
 import (
 	"fmt"
 	"metacontroller/pkg/logging"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -31,7 +32,7 @@ import (
 type APIResource struct {
 	metav1.APIResource
 	APIVersion     string
-	subresourceMap map[string]bool
+	subresourceMap map[string]*APIResource
 }
 
 func (r *APIResource) GroupVersion() schema.GroupVersion {
@@ -56,11 +57,46 @@ func (r *APIResource) GroupResource() schema.GroupResource {
 }
 
 func (r *APIResource) HasSubresource(subresourceKey string) bool {
+	_, found := r.subresourceMap[subresourceKey]
+	return found
+}
+
+// Subresource returns the discovery details of the given subresource, like the
+// kind it's read and written as, or nil if the resource doesn't have it.
+func (r *APIResource) Subresource(subresourceKey string) *APIResource {
 	return r.subresourceMap[subresourceKey]
 }
 
+// HasVerb returns whether the resource supports the given verb. Resources which
+// don't list their verbs are assumed to support all of them.
+func (r *APIResource) HasVerb(verb string) bool {
+	if len(r.Verbs) == 0 {
+		return true
+	}
+	for _, v := range r.Verbs {
+		if v == verb {
+			return true
+		}
+	}
+	return false
+}
+
 type groupVersionEntry struct {
 	resources, kinds, subresources map[string]*APIResource
+	// ambiguousKinds lists, for each kind served by more than one resource
+	// in this group-version, the names of those resources.
+	ambiguousKinds map[string][]string
+}
+
+// AmbiguousKindError is returned when a kind can't be resolved to a single
+// resource.
+type AmbiguousKindError struct {
+	Kind       string
+	Candidates []string
+}
+
+func (e *AmbiguousKindError) Error() string {
+	return fmt.Sprintf("discovery: kind %s is ambiguous, it's served by %s", e.Kind, strings.Join(e.Candidates, ", "))
 }
 
 type ResourceMap struct {
@@ -82,15 +118,88 @@ func (rm *ResourceMap) Get(apiVersion, resource string) (result *APIResource) {
 	return gv.resources[resource]
 }
 
+// GetKind returns the resource serving kind in apiVersion, or nil if there's
+// no such resource or it's ambiguous. Use LookupKind to tell the two apart.
 func (rm *ResourceMap) GetKind(apiVersion, kind string) (result *APIResource) {
+	result, _ = rm.LookupKind(apiVersion, kind)
+	return result
+}
+
+// LookupKind returns the resource serving kind in apiVersion. If more than
+// one resource in apiVersion serves kind, it returns an AmbiguousKindError
+// listing them, rather than picking one.
+func (rm *ResourceMap) LookupKind(apiVersion, kind string) (*APIResource, error) {
 	rm.mutex.RLock()
 	defer rm.mutex.RUnlock()
 
 	gv, ok := rm.groupVersions[apiVersion]
 	if !ok {
-		return nil
+		return nil, fmt.Errorf("discovery: can't find apiVersion %s", apiVersion)
+	}
+	if candidates, ok := gv.ambiguousKinds[kind]; ok {
+		return nil, &AmbiguousKindError{Kind: apiVersion + "/" + kind, Candidates: candidates}
+	}
+	result, ok := gv.kinds[kind]
+	if !ok {
+		return nil, fmt.Errorf("discovery: can't find kind %s in apiVersion %s", kind, apiVersion)
 	}
-	return gv.kinds[kind]
+	return result, nil
+}
+
+// FindKind returns the resources serving kind in any group-version, sorted by
+// apiVersion, so callers can disambiguate kinds with the same name served by
+// several API groups.
+func (rm *ResourceMap) FindKind(kind string) []*APIResource {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+
+	var results []*APIResource
+	for _, gv := range rm.groupVersions {
+		if candidates, ok := gv.ambiguousKinds[kind]; ok {
+			for _, name := range candidates {
+				results = append(results, gv.resources[name])
+			}
+		} else if resource, ok := gv.kinds[kind]; ok {
+			results = append(results, resource)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].APIVersion != results[j].APIVersion {
+			return results[i].APIVersion < results[j].APIVersion
+		}
+		return results[i].Name < results[j].Name
+	})
+	return results
+}
+
+// LookupGroupKind returns the resource serving kind in the given API group,
+// using the first version in versions which serves it. It returns an error
+// if the group doesn't serve kind in any of those versions.
+func (rm *ResourceMap) LookupGroupKind(group, kind string, versions ...string) (*APIResource, error) {
+	for _, version := range versions {
+		apiVersion := schema.GroupVersion{Group: group, Version: version}.String()
+		resource, err := rm.LookupKind(apiVersion, kind)
+		if _, ambiguous := err.(*AmbiguousKindError); ambiguous {
+			return nil, err
+		}
+		if resource != nil {
+			return resource, nil
+		}
+	}
+	var servedBy []string
+	for _, resource := range rm.FindKind(kind) {
+		servedBy = append(servedBy, resource.APIVersion)
+	}
+	if len(servedBy) > 0 {
+		return nil, fmt.Errorf("discovery: can't find kind %s in group %q, it's served by %s", kind, group, strings.Join(servedBy, ", "))
+	}
+	return nil, fmt.Errorf("discovery: can't find kind %s in group %q", kind, group)
+}
+
+// Refresh fetches the discovery info right away, rather than waiting for the
+// next periodic refresh.
+func (rm *ResourceMap) Refresh() {
+	rm.refresh()
 }
 
 func (rm *ResourceMap) refresh() {
@@ -102,7 +211,15 @@ func (rm *ResourceMap) refresh() {
 		logging.Logger.Error(err, "Failed to fetch discovery info")
 		return
 	}
+	groupVersions := buildGroupVersions(groups)
+
+	// Replace the local cache.
+	rm.mutex.Lock()
+	rm.groupVersions = groupVersions
+	rm.mutex.Unlock()
+}
 
+func buildGroupVersions(groups []*metav1.APIResourceList) map[string]groupVersionEntry {
 	// Denormalize resource lists into maps for convenient lookup
 	// by either Group-Version-Kind or Group-Version-Resource.
 	groupVersions := make(map[string]groupVersionEntry, len(groups))
@@ -112,10 +229,19 @@ func (rm *ResourceMap) refresh() {
 			// This shouldn't happen because we get these values from the server.
 			panic(fmt.Errorf("received invalid GroupVersion from server: %w", err))
 		}
-		gve := groupVersionEntry{
-			resources:    make(map[string]*APIResource, len(group.APIResources)),
-			kinds:        make(map[string]*APIResource, len(group.APIResources)),
-			subresources: make(map[string]*APIResource, len(group.APIResources)),
+		// The same group-version may be listed more than once, e.g. when it's
+		// served by several aggregated API servers. Merge the entries instead
+		// of letting the last one win.
+		gve, duplicate := groupVersions[group.GroupVersion]
+		if duplicate {
+			logging.Logger.V(4).Info("Merging duplicate discovery entry", "groupVersion", group.GroupVersion)
+		} else {
+			gve = groupVersionEntry{
+				resources:      make(map[string]*APIResource, len(group.APIResources)),
+				kinds:          make(map[string]*APIResource, len(group.APIResources)),
+				subresources:   make(map[string]*APIResource, len(group.APIResources)),
+				ambiguousKinds: make(map[string][]string),
+			}
 		}
 
 		for i := range group.APIResources {
@@ -130,12 +256,24 @@ func (rm *ResourceMap) refresh() {
 			if apiResource.Version == "" {
 				apiResource.Version = gv.Version
 			}
+			if _, exists := gve.resources[apiResource.Name]; exists {
+				// Keep the first entry for a resource listed more than once.
+				continue
+			}
 			gve.resources[apiResource.Name] = apiResource
 			// Remember which resources are subresources, and map the kind to the main resource.
 			// This is different from what RESTMapper provides because we already know
 			// the full GroupVersionKind and just need the resource name.
 			if strings.ContainsRune(apiResource.Name, '/') {
 				gve.subresources[apiResource.Name] = apiResource
+			} else if existing, ok := gve.kinds[apiResource.Kind]; ok {
+				candidates := gve.ambiguousKinds[apiResource.Kind]
+				if len(candidates) == 0 {
+					candidates = []string{existing.Name}
+				}
+				candidates = append(candidates, apiResource.Name)
+				sort.Strings(candidates)
+				gve.ambiguousKinds[apiResource.Kind] = candidates
 			} else {
 				gve.kinds[apiResource.Kind] = apiResource
 			}
@@ -151,18 +289,14 @@ func (rm *ResourceMap) refresh() {
 				continue
 			}
 			if apiResource.subresourceMap == nil {
-				apiResource.subresourceMap = make(map[string]bool)
+				apiResource.subresourceMap = make(map[string]*APIResource)
 			}
-			apiResource.subresourceMap[subresourceKey] = true
+			apiResource.subresourceMap[subresourceKey] = gve.subresources[apiSubresourceName]
 		}
 
 		groupVersions[group.GroupVersion] = gve
 	}
-
-	// Replace the local cache.
-	rm.mutex.Lock()
-	rm.groupVersions = groupVersions
-	rm.mutex.Unlock()
+	return groupVersions
 }
 
 func (rm *ResourceMap) Start(refreshInterval time.Duration) {
@@ -0,0 +1,110 @@
+package discovery
+
+import (
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestResourceMap(groups ...*metav1.APIResourceList) *ResourceMap {
+	return &ResourceMap{groupVersions: buildGroupVersions(groups)}
+}
+
+func TestBuildGroupVersions_mergesDuplicateGroupVersions(t *testing.T) {
+	rm := newTestResourceMap(
+		&metav1.APIResourceList{
+			GroupVersion: "example.com/v1",
+			APIResources: []metav1.APIResource{{Name: "foos", Kind: "Foo"}},
+		},
+		&metav1.APIResourceList{
+			GroupVersion: "example.com/v1",
+			APIResources: []metav1.APIResource{{Name: "bars", Kind: "Bar"}, {Name: "foos/status", Kind: "Foo"}},
+		},
+	)
+
+	if rm.Get("example.com/v1", "foos") == nil {
+		t.Errorf("expected foos from the first entry to be kept")
+	}
+	if rm.GetKind("example.com/v1", "Bar") == nil {
+		t.Errorf("expected Bar from the second entry to be merged")
+	}
+	if !rm.Get("example.com/v1", "foos").HasSubresource("status") {
+		t.Errorf("expected foos to have the status subresource")
+	}
+}
+
+func TestSubresource(t *testing.T) {
+	rm := newTestResourceMap(&metav1.APIResourceList{
+		GroupVersion: "apps/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "deployments", Kind: "Deployment"},
+			{Name: "deployments/scale", Group: "autoscaling", Version: "v1", Kind: "Scale", Verbs: []string{"get", "patch", "update"}},
+		},
+	})
+	deployments := rm.Get("apps/v1", "deployments")
+
+	scale := deployments.Subresource("scale")
+	if scale == nil {
+		t.Fatalf("expected deployments to have the scale subresource")
+	}
+	if scale.Kind != "Scale" || scale.Group != "autoscaling" || scale.Version != "v1" {
+		t.Errorf("unexpected scale subresource: %v %v/%v", scale.Kind, scale.Group, scale.Version)
+	}
+	if !scale.HasVerb("patch") || scale.HasVerb("create") {
+		t.Errorf("unexpected verbs of the scale subresource: %v", scale.Verbs)
+	}
+	if deployments.Subresource("status") != nil || deployments.HasSubresource("status") {
+		t.Errorf("expected deployments not to have the status subresource")
+	}
+}
+
+func TestLookupKind_ambiguousKind(t *testing.T) {
+	rm := newTestResourceMap(&metav1.APIResourceList{
+		GroupVersion: "example.com/v1",
+		APIResources: []metav1.APIResource{{Name: "foos", Kind: "Foo"}, {Name: "legacyfoos", Kind: "Foo"}},
+	})
+
+	resource, err := rm.LookupKind("example.com/v1", "Foo")
+
+	var ambiguous *AmbiguousKindError
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("expected AmbiguousKindError, got: %v", err)
+	}
+	if resource != nil {
+		t.Errorf("expected no resource, got: %v", resource.Name)
+	}
+	if len(ambiguous.Candidates) != 2 || ambiguous.Candidates[0] != "foos" || ambiguous.Candidates[1] != "legacyfoos" {
+		t.Errorf("unexpected candidates: %v", ambiguous.Candidates)
+	}
+	if rm.GetKind("example.com/v1", "Foo") != nil {
+		t.Errorf("expected GetKind to return nil for an ambiguous kind")
+	}
+}
+
+func TestLookupGroupKind(t *testing.T) {
+	rm := newTestResourceMap(
+		&metav1.APIResourceList{
+			GroupVersion: "networking.k8s.io/v1",
+			APIResources: []metav1.APIResource{{Name: "ingresses", Kind: "Ingress"}},
+		},
+		&metav1.APIResourceList{
+			GroupVersion: "example.com/v1",
+			APIResources: []metav1.APIResource{{Name: "ingresses", Kind: "Ingress"}},
+		},
+	)
+
+	if found := rm.FindKind("Ingress"); len(found) != 2 || found[0].APIVersion != "example.com/v1" {
+		t.Errorf("expected Ingress to be found in both groups, got: %v", found)
+	}
+	resource, err := rm.LookupGroupKind("networking.k8s.io", "Ingress", "v1beta1", "v1")
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if resource.APIVersion != "networking.k8s.io/v1" {
+		t.Errorf("expected networking.k8s.io/v1, got: %s", resource.APIVersion)
+	}
+	if _, err := rm.LookupGroupKind("extensions", "Ingress", "v1beta1"); err == nil {
+		t.Errorf("expected error for a group not serving Ingress")
+	}
+}
@@ -31,10 +31,14 @@ import (
 type SharedInformerFactory struct {
 	clientset     *dynamicclientset.Clientset
 	defaultResync time.Duration
+	transform     TransformFunc
+	idleTimeout   time.Duration
+	listPageSize  int64
 
 	mutex           sync.Mutex
 	refCount        map[string]int
 	sharedInformers map[string]*sharedResourceInformer
+	idleTimers      map[string]*time.Timer
 }
 
 // NewSharedInformerFactory creates a new factory for shared, dynamic informers.
@@ -45,27 +49,74 @@ func NewSharedInformerFactory(clientset *dynamicclientset.Clientset, defaultResy
 		defaultResync:   defaultResync,
 		refCount:        make(map[string]int),
 		sharedInformers: make(map[string]*sharedResourceInformer),
+		idleTimers:      make(map[string]*time.Timer),
 	}
 }
 
+// SetTransform sets the function applied to objects before they're cached by
+// the informers started from now on, or nil to cache them as they are.
+func (f *SharedInformerFactory) SetTransform(transform TransformFunc) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.transform = transform
+}
+
+// SetListPageSize sets the maximum number of objects fetched by each request
+// of the lists made by the informers started from now on, or zero to let
+// client-go decide, which may fetch all objects at once when informers start.
+func (f *SharedInformerFactory) SetListPageSize(listPageSize int64) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.listPageSize = listPageSize
+}
+
+// SetIdleTimeout sets how long shared informers keep running once they have
+// no more subscribers, or zero to stop them immediately. A controller which is
+// recreated within the timeout, e.g. when its spec is updated, resumes the
+// running informers instead of listing all objects again.
+func (f *SharedInformerFactory) SetIdleTimeout(idleTimeout time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.idleTimeout = idleTimeout
+}
+
 // Resource returns a dynamic informer and lister for the given resource.
 // These are shared with any other controllers in the same process that request
 // the same resource.
 //
 // If this function returns successfully, the caller should ensure they call
 // Close() on the returned ResourceInformer when they no longer need it.
-// Shared informers that become unused will be stopped to minimize our load on
-// the API server.
+// Shared informers that become unused will be stopped, after the idle timeout
+// if one is set, to minimize our load on the API server.
 func (f *SharedInformerFactory) Resource(apiVersion, resource string) (*ResourceInformer, error) {
+	return f.ResourceWithSelectors(apiVersion, resource, Selectors{})
+}
+
+// ResourceWithSelectors is like Resource, but the returned informer only
+// caches the objects matching selectors. Informers are shared between the
+// callers requesting equivalent selectors, and each distinct set of selectors
+// gets its own informer with its own reference count.
+func (f *SharedInformerFactory) ResourceWithSelectors(apiVersion, resource string, selectors Selectors) (*ResourceInformer, error) {
+	selectors, err := selectors.canonical()
+	if err != nil {
+		return nil, fmt.Errorf("can't parse selectors for %v shared informer: %w", resourceKey(apiVersion, resource), err)
+	}
+
 	f.mutex.Lock()
 	defer f.mutex.Unlock()
 
 	// Return existing informer if there is one.
-	key := resourceKey(apiVersion, resource)
+	key := resourceKey(apiVersion, resource) + selectors.key()
 	if sharedInformer, ok := f.sharedInformers[key]; ok {
+		if timer, ok := f.idleTimers[key]; ok {
+			// Cancel the pending shutdown of an idle informer.
+			timer.Stop()
+			delete(f.idleTimers, key)
+			logging.Logger.V(4).Info("Resuming idle shared informer", "resource", resource, "api_version", apiVersion, "selectors", selectors)
+		}
 		count := f.refCount[key] + 1
 		f.refCount[key] = count
-		logging.Logger.V(4).Info("Subscribed to shared informer", "resource", resource, "api_version", apiVersion, "total_subscribers", count)
+		logging.Logger.V(4).Info("Subscribed to shared informer", "resource", resource, "api_version", apiVersion, "selectors", selectors, "total_subscribers", count)
 		return newResourceInformer(sharedInformer), nil
 	}
 
@@ -76,6 +127,15 @@ func (f *SharedInformerFactory) Resource(apiVersion, resource string) (*Resource
 	}
 	stopCh := make(chan struct{})
 
+	// stop stops the shared informer and forgets it, so the next request for
+	// the same key starts a new one.
+	stop := func() {
+		close(stopCh)
+		delete(f.refCount, key)
+		delete(f.sharedInformers, key)
+		delete(f.idleTimers, key)
+	}
+
 	// closeFn is called by users of the shared informer (via Close()) to indicate
 	// they no longer need it. We do all incrementing/decrementing of the ref
 	// count in the factory while holding the factory mutex, so that removing
@@ -85,7 +145,7 @@ func (f *SharedInformerFactory) Resource(apiVersion, resource string) (*Resource
 		defer f.mutex.Unlock()
 
 		count := f.refCount[key] - 1
-		logging.Logger.V(4).Info("Unsubscribed from shared informer", "resource", resource, "api_version", apiVersion, "total_subscribers", count)
+		logging.Logger.V(4).Info("Unsubscribed from shared informer", "resource", resource, "api_version", apiVersion, "selectors", selectors, "total_subscribers", count)
 
 		if count > 0 {
 			// Others are still using it.
@@ -94,14 +154,31 @@ func (f *SharedInformerFactory) Resource(apiVersion, resource string) (*Resource
 		}
 
 		// We're the last ones using it.
-		logging.Logger.V(4).Info("Stopping shared informer (no more subscribers)", "resource", resource, "api_version", apiVersion, "total_subscribers", count)
-		close(stopCh)
-		delete(f.refCount, key)
-		delete(f.sharedInformers, key)
+		if f.idleTimeout <= 0 {
+			logging.Logger.V(4).Info("Stopping shared informer (no more subscribers)", "resource", resource, "api_version", apiVersion, "selectors", selectors, "total_subscribers", count)
+			stop()
+			return
+		}
+		// Keep it running for a while, in case it's requested again soon, so
+		// controllers being recreated don't relist all objects.
+		logging.Logger.V(4).Info("Shared informer is idle (no more subscribers)", "resource", resource, "api_version", apiVersion, "selectors", selectors, "idle_timeout", f.idleTimeout)
+		f.refCount[key] = 0
+		var timer *time.Timer
+		timer = time.AfterFunc(f.idleTimeout, func() {
+			f.mutex.Lock()
+			defer f.mutex.Unlock()
+			if f.idleTimers[key] != timer {
+				// The informer was resumed in the meantime.
+				return
+			}
+			logging.Logger.V(4).Info("Stopping idle shared informer", "resource", resource, "api_version", apiVersion, "selectors", selectors)
+			stop()
+		})
+		f.idleTimers[key] = timer
 	}
 
-	logging.Logger.V(4).Info("Starting shared informer", "resource", resource, "api_version", apiVersion)
-	sharedInformer := newSharedResourceInformer(client, f.defaultResync, closeFn)
+	logging.Logger.V(4).Info("Starting shared informer", "resource", resource, "api_version", apiVersion, "selectors", selectors)
+	sharedInformer := newSharedResourceInformer(client, f.defaultResync, selectors, f.listPageSize, f.transform, closeFn)
 	f.sharedInformers[key] = sharedInformer
 	f.refCount[key] = 1
 
@@ -0,0 +1,110 @@
+package informer
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
+)
+
+func newTestFactory() *SharedInformerFactory {
+	discovery := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap", Namespaced: true}},
+		},
+	}}}
+	resources := dynamicdiscovery.NewResourceMap(discovery)
+	resources.Refresh()
+	dc := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		{Version: "v1", Resource: "configmaps"}: "ConfigMapList",
+	})
+	return NewSharedInformerFactory(dynamicclientset.NewForDynamicClient(resources, dc), 0)
+}
+
+// running returns the shared informer of configmaps, if it's still running,
+// and its number of subscribers.
+func running(f *SharedInformerFactory) (*sharedResourceInformer, int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	key := resourceKey("v1", "configmaps")
+	return f.sharedInformers[key], f.refCount[key]
+}
+
+func TestSharedInformerFactory_idleTimeout(t *testing.T) {
+	f := newTestFactory()
+	f.SetIdleTimeout(100 * time.Millisecond)
+
+	informer, err := f.Resource("v1", "configmaps")
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	started := informer.sharedResourceInformer
+	informer.Close()
+	if sharedInformer, count := running(f); sharedInformer != started || count != 0 {
+		t.Fatalf("expected the informer to be kept idle, got %v with %d subscribers", sharedInformer, count)
+	}
+
+	// Requested again before the timeout, the idle informer is resumed.
+	informer, err = f.Resource("v1", "configmaps")
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if informer.sharedResourceInformer != started {
+		t.Errorf("expected the idle informer to be resumed")
+	}
+	time.Sleep(200 * time.Millisecond)
+	if sharedInformer, count := running(f); sharedInformer != started || count != 1 {
+		t.Fatalf("expected the resumed informer to outlive the timeout, got %v with %d subscribers", sharedInformer, count)
+	}
+
+	// Idle past the timeout, the informer is torn down.
+	informer.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		sharedInformer, _ := running(f)
+		if sharedInformer == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the idle informer to be stopped after the timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	f.mutex.Lock()
+	if len(f.refCount) != 0 || len(f.idleTimers) != 0 {
+		t.Errorf("expected the informer to be forgotten, got refCount %v and idleTimers %v", f.refCount, f.idleTimers)
+	}
+	f.mutex.Unlock()
+
+	// The next request starts a new informer.
+	informer, err = f.Resource("v1", "configmaps")
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	defer informer.Close()
+	if informer.sharedResourceInformer == started {
+		t.Errorf("expected a new informer to be started")
+	}
+}
+
+func TestSharedInformerFactory_noIdleTimeout(t *testing.T) {
+	f := newTestFactory()
+
+	informer, err := f.Resource("v1", "configmaps")
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	informer.Close()
+	if sharedInformer, _ := running(f); sharedInformer != nil {
+		t.Errorf("expected the informer to be stopped without subscribers")
+	}
+}
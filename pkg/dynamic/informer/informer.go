@@ -32,6 +32,7 @@ import (
 	"k8s.io/client-go/tools/cache"
 
 	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
 
 	"k8s.io/client-go/dynamic/dynamiclister"
 )
@@ -56,7 +57,7 @@ type SharedIndexInformer interface {
 // Users of this package shouldn't create ResourceInformers directly.
 // The SharedInformerFactory returns a new ResourceInformer for each request,
 // but multiple ResourceInformers may share the same underlying informer if they
-// are for the same apiVersion and resource.
+// are for the same apiVersion, resource and selectors.
 //
 // When you're done with a ResourceInformer, you should call Close() on it.
 // Once all ResourceInformers for a shared informer are closed, the shared
@@ -89,6 +90,29 @@ func (ri *ResourceInformer) Lister() dynamiclister.Lister {
 	return ri.sharedResourceInformer.lister
 }
 
+// WatchState describes the resource watched by an informer, and whether its
+// cache is synced.
+type WatchState struct {
+	APIVersion string `json:"apiVersion"`
+	Resource   string `json:"resource"`
+	Selectors  string `json:"selectors,omitempty"`
+	Synced     bool   `json:"synced"`
+}
+
+// WatchState returns the state of the watch of the shared informer.
+func (ri *ResourceInformer) WatchState() WatchState {
+	sri := ri.sharedResourceInformer
+	state := WatchState{
+		APIVersion: sri.apiResource.APIVersion,
+		Resource:   sri.apiResource.Name,
+		Synced:     sri.informer.HasSynced(),
+	}
+	if !sri.selectors.IsEmpty() {
+		state.Selectors = sri.selectors.String()
+	}
+	return state
+}
+
 // Close marks this ResourceInformer as unused, allowing the underlying shared
 // informer to be stopped when no users are left.
 // You should call this when you no longer need the informer, so the watches
@@ -108,17 +132,45 @@ type sharedResourceInformer struct {
 
 	eventHandlers *sharedEventHandler
 
+	apiResource *dynamicdiscovery.APIResource
+	selectors   Selectors
+	activity    *informerActivity
+
 	close func()
 }
 
-func newSharedResourceInformer(client *dynamicclientset.ResourceClient, defaultResyncPeriod time.Duration, close func()) *sharedResourceInformer {
+func newSharedResourceInformer(client *dynamicclientset.ResourceClient, defaultResyncPeriod time.Duration, selectors Selectors, listPageSize int64, transform TransformFunc, close func()) *sharedResourceInformer {
+	activity := &informerActivity{}
 	informer := cache.NewSharedIndexInformer(
 		&cache.ListWatch{
 			ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
-				return client.List(context.TODO(), opts)
+				activity.countList()
+				selectors.apply(&opts)
+				if listPageSize > 0 {
+					paginateList(&opts, listPageSize)
+				}
+				list, err := client.List(context.TODO(), opts)
+				if err == nil && transform != nil {
+					transformList(transform, list)
+				}
+				return list, err
 			},
 			WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
-				return client.Watch(context.TODO(), opts)
+				selectors.apply(&opts)
+				// Bookmarks keep the resourceVersion of the informer recent on
+				// resources which rarely change, so a watch restarted after it
+				// timed out resumes where it stopped, instead of failing with
+				// "resource version too old" and relisting all objects.
+				opts.AllowWatchBookmarks = true
+				w, err := client.Watch(context.TODO(), opts)
+				if err != nil {
+					return w, err
+				}
+				w = activity.countWatch(w)
+				if transform != nil {
+					w = transformWatch(transform, w)
+				}
+				return w, nil
 			},
 		},
 		&unstructured.Unstructured{},
@@ -131,6 +183,9 @@ func newSharedResourceInformer(client *dynamicclientset.ResourceClient, defaultR
 		close:               close,
 		informer:            informer,
 		defaultResyncPeriod: defaultResyncPeriod,
+		apiResource:         client.APIResource,
+		selectors:           selectors,
+		activity:            activity,
 
 		//lister: dynamiclister.New(client.GroupResource(), informer.GetIndexer()),
 		lister: dynamiclister.New(informer.GetIndexer(), client.GroupVersionResource()),
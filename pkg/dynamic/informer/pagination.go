@@ -0,0 +1,39 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informer
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// paginateList adjusts opts, the options of a list request made by an
+// informer, so that full lists are fetched in pages of at most pageSize
+// objects, with limit and continue.
+//
+// Informers list at resourceVersion 0 when they start, which the watch cache
+// of the API server serves in a single response whatever the limit. Such lists
+// are turned into consistent lists, which are paginated. Relists at a given
+// resourceVersion, e.g. after a watch connection was closed, are left to the
+// watch cache.
+func paginateList(opts *metav1.ListOptions, pageSize int64) {
+	if opts.ResourceVersion == "0" {
+		opts.ResourceVersion = ""
+	}
+	if opts.ResourceVersion == "" {
+		opts.Limit = pageSize
+	}
+}
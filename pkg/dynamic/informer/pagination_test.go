@@ -0,0 +1,45 @@
+package informer
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPaginateList(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     metav1.ListOptions
+		expected metav1.ListOptions
+	}{
+		{
+			name:     "initial list from the watch cache",
+			opts:     metav1.ListOptions{ResourceVersion: "0", Limit: 500},
+			expected: metav1.ListOptions{ResourceVersion: "", Limit: 100},
+		},
+		{
+			name:     "consistent list",
+			opts:     metav1.ListOptions{},
+			expected: metav1.ListOptions{Limit: 100},
+		},
+		{
+			name:     "next page",
+			opts:     metav1.ListOptions{Continue: "token", Limit: 500},
+			expected: metav1.ListOptions{Continue: "token", Limit: 100},
+		},
+		{
+			name:     "relist at a resourceVersion",
+			opts:     metav1.ListOptions{ResourceVersion: "42"},
+			expected: metav1.ListOptions{ResourceVersion: "42"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := tc.opts
+			paginateList(&opts, 100)
+			if opts != tc.expected {
+				t.Errorf("expected %+v, got %+v", tc.expected, opts)
+			}
+		})
+	}
+}
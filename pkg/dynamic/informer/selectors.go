@@ -0,0 +1,78 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informer
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Selectors restricts a shared informer to the objects matching a label
+// selector and a field selector, both in their string form. The API server
+// does the filtering, so the objects which don't match are neither sent nor
+// cached. The zero value selects everything.
+type Selectors struct {
+	LabelSelector string
+	FieldSelector string
+}
+
+// IsEmpty returns whether s selects everything.
+func (s Selectors) IsEmpty() bool {
+	return s.LabelSelector == "" && s.FieldSelector == ""
+}
+
+func (s Selectors) String() string {
+	if s.IsEmpty() {
+		return "<all>"
+	}
+	return fmt.Sprintf("labels=%q fields=%q", s.LabelSelector, s.FieldSelector)
+}
+
+// canonical parses s and returns it in a normalized form, so equivalent
+// selectors written differently share the same informer.
+func (s Selectors) canonical() (Selectors, error) {
+	labelSelector, err := labels.Parse(s.LabelSelector)
+	if err != nil {
+		return Selectors{}, fmt.Errorf("invalid label selector %q: %w", s.LabelSelector, err)
+	}
+	fieldSelector, err := fields.ParseSelector(s.FieldSelector)
+	if err != nil {
+		return Selectors{}, fmt.Errorf("invalid field selector %q: %w", s.FieldSelector, err)
+	}
+	return Selectors{
+		LabelSelector: labelSelector.String(),
+		FieldSelector: fieldSelector.String(),
+	}, nil
+}
+
+// key returns the suffix added to the resource key of informers restricted by
+// s, which is empty if s selects everything.
+func (s Selectors) key() string {
+	if s.IsEmpty() {
+		return ""
+	}
+	return fmt.Sprintf("?labels=%s&fields=%s", s.LabelSelector, s.FieldSelector)
+}
+
+// apply sets the selectors of opts, the options of a list or watch request.
+func (s Selectors) apply(opts *metav1.ListOptions) {
+	opts.LabelSelector = s.LabelSelector
+	opts.FieldSelector = s.FieldSelector
+}
@@ -0,0 +1,53 @@
+package informer
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSelectors_Canonical(t *testing.T) {
+	a, err := Selectors{LabelSelector: "b=2, a=1", FieldSelector: "metadata.name=x"}.canonical()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := Selectors{LabelSelector: "a=1,b=2", FieldSelector: "metadata.name==x"}.canonical()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a != b {
+		t.Errorf("expected equivalent selectors to be equal, got %v and %v", a, b)
+	}
+	if a.key() == "" {
+		t.Errorf("expected non-empty key for %v", a)
+	}
+}
+
+func TestSelectors_Canonical_Empty(t *testing.T) {
+	s, err := Selectors{}.canonical()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !s.IsEmpty() || s.key() != "" {
+		t.Errorf("expected empty selectors to select everything, got %v with key %q", s, s.key())
+	}
+}
+
+func TestSelectors_Canonical_Invalid(t *testing.T) {
+	for _, s := range []Selectors{
+		{LabelSelector: "a in (b"},
+		{FieldSelector: "metadata.name"},
+	} {
+		if _, err := s.canonical(); err == nil {
+			t.Errorf("expected error for %v", s)
+		}
+	}
+}
+
+func TestSelectors_Apply(t *testing.T) {
+	opts := metav1.ListOptions{LabelSelector: "old", ResourceVersion: "1"}
+	Selectors{LabelSelector: "app=web"}.apply(&opts)
+	if opts.LabelSelector != "app=web" || opts.FieldSelector != "" || opts.ResourceVersion != "1" {
+		t.Errorf("unexpected list options: %+v", opts)
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informer
+
+import (
+	"encoding/json"
+	"sync/atomic"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// sizeSampleSize is the number of cached objects whose size is measured to
+// estimate the size of the whole cache of an informer.
+const sizeSampleSize = 50
+
+// InformerStats describes a shared informer and its activity since it was
+// started, e.g. to export metrics.
+type InformerStats struct {
+	APIVersion string
+	Kind       string
+	Resource   string
+	Selectors  Selectors
+
+	Subscribers int
+	Objects     int
+	// EstimatedBytes is the approximate size of the cached objects, in JSON,
+	// extrapolated from a sample of them.
+	EstimatedBytes int64
+
+	Lists         uint64
+	WatchRestarts uint64
+	// Events counts the added, modified and deleted events received by the
+	// watches of the informer.
+	Events map[watch.EventType]uint64
+}
+
+// Stats returns the stats of the running shared informers, including those
+// which are idle.
+func (f *SharedInformerFactory) Stats() []InformerStats {
+	f.mutex.Lock()
+	subscribers := make(map[*sharedResourceInformer]int, len(f.sharedInformers))
+	for key, sharedInformer := range f.sharedInformers {
+		subscribers[sharedInformer] = f.refCount[key]
+	}
+	f.mutex.Unlock()
+
+	stats := make([]InformerStats, 0, len(subscribers))
+	for sharedInformer, count := range subscribers {
+		stats = append(stats, sharedInformer.stats(count))
+	}
+	return stats
+}
+
+func (sri *sharedResourceInformer) stats(subscribers int) InformerStats {
+	objects := sri.informer.GetStore().List()
+	return InformerStats{
+		APIVersion:     sri.apiResource.APIVersion,
+		Kind:           sri.apiResource.Kind,
+		Resource:       sri.apiResource.Name,
+		Selectors:      sri.selectors,
+		Subscribers:    subscribers,
+		Objects:        len(objects),
+		EstimatedBytes: estimateSize(objects, sizeSampleSize),
+		Lists:          atomic.LoadUint64(&sri.activity.lists),
+		WatchRestarts:  sri.activity.watchRestarts(),
+		Events: map[watch.EventType]uint64{
+			watch.Added:    atomic.LoadUint64(&sri.activity.added),
+			watch.Modified: atomic.LoadUint64(&sri.activity.modified),
+			watch.Deleted:  atomic.LoadUint64(&sri.activity.deleted),
+		},
+	}
+}
+
+// estimateSize returns the approximate JSON size of objects, measuring at
+// most sampleSize of them, evenly spread, rather than all of them.
+func estimateSize(objects []interface{}, sampleSize int) int64 {
+	if len(objects) == 0 {
+		return 0
+	}
+	if sampleSize > len(objects) {
+		sampleSize = len(objects)
+	}
+	var sampled int64
+	for i := 0; i < sampleSize; i++ {
+		obj, ok := objects[i*len(objects)/sampleSize].(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		data, err := json.Marshal(obj.Object)
+		if err != nil {
+			continue
+		}
+		sampled += int64(len(data))
+	}
+	return sampled * int64(len(objects)) / int64(sampleSize)
+}
+
+// informerActivity counts the requests made by a shared informer and the
+// events it received. Its fields are accessed atomically.
+type informerActivity struct {
+	lists    uint64
+	watches  uint64
+	added    uint64
+	modified uint64
+	deleted  uint64
+}
+
+func (a *informerActivity) countList() {
+	atomic.AddUint64(&a.lists, 1)
+}
+
+// countWatch counts a new watch, and returns w counting its events.
+func (a *informerActivity) countWatch(w watch.Interface) watch.Interface {
+	atomic.AddUint64(&a.watches, 1)
+	return watch.Filter(w, func(event watch.Event) (watch.Event, bool) {
+		switch event.Type {
+		case watch.Added:
+			atomic.AddUint64(&a.added, 1)
+		case watch.Modified:
+			atomic.AddUint64(&a.modified, 1)
+		case watch.Deleted:
+			atomic.AddUint64(&a.deleted, 1)
+		}
+		return event, true
+	})
+}
+
+// watchRestarts returns the number of watches started after the first one,
+// e.g. because the previous one timed out or failed.
+func (a *informerActivity) watchRestarts() uint64 {
+	watches := atomic.LoadUint64(&a.watches)
+	if watches == 0 {
+		return 0
+	}
+	return watches - 1
+}
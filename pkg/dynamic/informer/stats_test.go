@@ -0,0 +1,100 @@
+package informer
+
+import (
+	"fmt"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+
+	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
+)
+
+func configMap(name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetNamespace("default")
+	obj.SetName(name)
+	return obj
+}
+
+func TestEstimateSize(t *testing.T) {
+	objects := make([]interface{}, 0, 200)
+	for i := 0; i < 200; i++ {
+		objects = append(objects, configMap(fmt.Sprintf("cm-%03d", i)))
+	}
+	exact := estimateSize(objects, len(objects))
+	sampled := estimateSize(objects, 10)
+	if exact == 0 {
+		t.Fatal("expected a non-zero size")
+	}
+	if sampled != exact {
+		t.Errorf("expected the sample of objects of the same size to give %d, got %d", exact, sampled)
+	}
+	if size := estimateSize(nil, 10); size != 0 {
+		t.Errorf("expected zero size without objects, got %d", size)
+	}
+}
+
+func TestInformerActivity(t *testing.T) {
+	activity := &informerActivity{}
+	if restarts := activity.watchRestarts(); restarts != 0 {
+		t.Errorf("expected no restarts before the first watch, got %d", restarts)
+	}
+	for i := 0; i < 2; i++ {
+		fake := watch.NewFake()
+		w := activity.countWatch(fake)
+		go func() {
+			fake.Add(configMap("a"))
+			fake.Modify(configMap("a"))
+			fake.Action(watch.Bookmark, configMap(""))
+		}()
+		for j := 0; j < 3; j++ {
+			<-w.ResultChan()
+		}
+		w.Stop()
+	}
+	if restarts := activity.watchRestarts(); restarts != 1 {
+		t.Errorf("expected 1 restart, got %d", restarts)
+	}
+	if activity.added != 2 || activity.modified != 2 || activity.deleted != 0 {
+		t.Errorf("unexpected event counts: %+v", activity)
+	}
+}
+
+func TestSharedInformerFactory_Stats(t *testing.T) {
+	informer := cache.NewSharedIndexInformer(&cache.ListWatch{}, &unstructured.Unstructured{}, 0, cache.Indexers{})
+	for _, name := range []string{"a", "b"} {
+		if err := informer.GetStore().Add(configMap(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sharedInformer := &sharedResourceInformer{
+		informer: informer,
+		apiResource: &dynamicdiscovery.APIResource{
+			APIResource: metav1.APIResource{Name: "configmaps", Kind: "ConfigMap"},
+			APIVersion:  "v1",
+		},
+		selectors: Selectors{LabelSelector: "app=web"},
+		activity:  &informerActivity{lists: 1},
+	}
+	f := &SharedInformerFactory{
+		refCount:        map[string]int{"configmaps.v1": 3},
+		sharedInformers: map[string]*sharedResourceInformer{"configmaps.v1": sharedInformer},
+	}
+
+	stats := f.Stats()
+	if len(stats) != 1 {
+		t.Fatalf("expected stats of 1 informer, got %v", stats)
+	}
+	got := stats[0]
+	if got.APIVersion != "v1" || got.Kind != "ConfigMap" || got.Resource != "configmaps" || got.Selectors.LabelSelector != "app=web" {
+		t.Errorf("unexpected informer description: %+v", got)
+	}
+	if got.Subscribers != 3 || got.Objects != 2 || got.EstimatedBytes == 0 || got.Lists != 1 {
+		t.Errorf("unexpected informer stats: %+v", got)
+	}
+}
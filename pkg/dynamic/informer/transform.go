@@ -0,0 +1,66 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package informer
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// TransformFunc changes objects in place before they're cached by informers,
+// e.g. to drop fields which are never read, to save memory.
+type TransformFunc func(obj *unstructured.Unstructured)
+
+// StripManagedFields drops the field sets of the managed fields entries of
+// obj, except those of server-side applies, which Metacontroller reads to
+// tell which fields it applied. The managers and times of the entries are
+// kept. Field sets are usually the largest part of the metadata of objects.
+func StripManagedFields(obj *unstructured.Unstructured) {
+	entries := obj.GetManagedFields()
+	if len(entries) == 0 {
+		return
+	}
+	stripped := false
+	for i := range entries {
+		if entries[i].Operation != metav1.ManagedFieldsOperationApply && entries[i].FieldsV1 != nil {
+			entries[i].FieldsV1 = nil
+			entries[i].FieldsType = ""
+			stripped = true
+		}
+	}
+	if stripped {
+		obj.SetManagedFields(entries)
+	}
+}
+
+// transformList applies transform to the items of list.
+func transformList(transform TransformFunc, list *unstructured.UnstructuredList) {
+	for i := range list.Items {
+		transform(&list.Items[i])
+	}
+}
+
+// transformWatch applies transform to the objects of the events of w.
+func transformWatch(transform TransformFunc, w watch.Interface) watch.Interface {
+	return watch.Filter(w, func(event watch.Event) (watch.Event, bool) {
+		if obj, ok := event.Object.(*unstructured.Unstructured); ok {
+			transform(obj)
+		}
+		return event, true
+	})
+}
@@ -0,0 +1,83 @@
+package informer
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func managedObject() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetName("test")
+	obj.SetManagedFields([]metav1.ManagedFieldsEntry{
+		{
+			Manager:    "metacontroller",
+			Operation:  metav1.ManagedFieldsOperationApply,
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{}}}`)},
+		},
+		{
+			Manager:    "kube-controller-manager",
+			Operation:  metav1.ManagedFieldsOperationUpdate,
+			Time:       &metav1.Time{Time: time.Unix(1600000000, 0)},
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:status":{"f:replicas":{}}}`)},
+		},
+	})
+	return obj
+}
+
+func TestStripManagedFields(t *testing.T) {
+	obj := managedObject()
+
+	StripManagedFields(obj)
+
+	entries := obj.GetManagedFields()
+	if len(entries) != 2 {
+		t.Fatalf("expected the entries to be kept, got: %v", entries)
+	}
+	if entries[0].FieldsV1 == nil {
+		t.Errorf("expected the fields of the server-side apply to be kept")
+	}
+	if entries[1].FieldsV1 != nil || entries[1].FieldsType != "" {
+		t.Errorf("expected the fields of the update to be dropped, got: %s", entries[1].FieldsV1.Raw)
+	}
+	if entries[1].Manager != "kube-controller-manager" || entries[1].Time == nil {
+		t.Errorf("expected the manager and time of the update to be kept, got: %v", entries[1])
+	}
+}
+
+func TestTransformWatch(t *testing.T) {
+	fake := watch.NewFake()
+	w := transformWatch(StripManagedFields, fake)
+	defer w.Stop()
+
+	go fake.Add(managedObject())
+	event := <-w.ResultChan()
+
+	obj := event.Object.(*unstructured.Unstructured)
+	if entries := obj.GetManagedFields(); entries[1].FieldsV1 != nil {
+		t.Errorf("expected the object of the event to be transformed, got: %v", entries)
+	}
+}
+
+func TestTransformWatch_Bookmark(t *testing.T) {
+	fake := watch.NewFake()
+	w := transformWatch(StripManagedFields, fake)
+	defer w.Stop()
+
+	bookmark := &unstructured.Unstructured{}
+	bookmark.SetResourceVersion("42")
+	go fake.Action(watch.Bookmark, bookmark)
+	event := <-w.ResultChan()
+
+	if event.Type != watch.Bookmark {
+		t.Fatalf("expected a bookmark event, got: %v", event.Type)
+	}
+	if rv := event.Object.(*unstructured.Unstructured).GetResourceVersion(); rv != "42" {
+		t.Errorf("expected the resourceVersion of the bookmark to be kept, got: %q", rv)
+	}
+}
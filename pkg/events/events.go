@@ -9,12 +9,29 @@ import (
 )
 
 const (
-	ReasonStarted     string = "Started"
-	ReasonStarting    string = "Starting"
-	ReasonStopped     string = "Stopped"
-	ReasonStopping    string = "Stopping"
-	ReasonSyncError   string = "SyncError"
-	ReasonCreateError string = "CreateError"
+	ReasonStarted             string = "Started"
+	ReasonStarting            string = "Starting"
+	ReasonStopped             string = "Stopped"
+	ReasonStopping            string = "Stopping"
+	ReasonSyncError           string = "SyncError"
+	ReasonCreateError         string = "CreateError"
+	ReasonInvalidHookResponse string = "InvalidHookResponse"
+	ReasonHookError           string = "HookError"
+	ReasonRolledBack          string = "RolledBack"
+	ReasonRollbackError       string = "RollbackError"
+	ReasonSyncLoopDetected    string = "SyncLoopDetected"
+	ReasonChildConflict       string = "ChildConflict"
+	ReasonChildLimitExceeded  string = "ChildLimitExceeded"
+	ReasonDriftDetected       string = "DriftDetected"
+	ReasonAdoptionPending     string = "AdoptionPending"
+	ReasonExternalCreated     string = "ExternalCreated"
+	ReasonExternalUpdated     string = "ExternalUpdated"
+	ReasonExternalDeleted     string = "ExternalDeleted"
+	ReasonStateChanged        string = "StateChanged"
+	ReasonChildUpdateDiff     string = "ChildUpdateDiff"
+	ReasonChildInvalid        string = "ChildInvalid"
+	ReasonChildApplyError     string = "ChildApplyError"
+	ReasonDryRun              string = "DryRun"
 )
 
 func NewBroadcaster(config *rest.Config, options record.CorrelatorOptions) (record.EventBroadcaster, error) {
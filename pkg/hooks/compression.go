@@ -0,0 +1,70 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+const gzipEncoding = "gzip"
+
+func validateCompression(compression *v1alpha1.WebhookCompression) (bool, error) {
+	if compression == nil {
+		return false, nil
+	}
+	switch *compression {
+	case v1alpha1.WebhookCompressionGzip:
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid webhook config: unknown compression %q", *compression)
+	}
+}
+
+// gzipBody compresses body.
+func gzipBody(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(body); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// readResponseBody reads the body of resp, decompressing it if the webhook
+// replied with a gzip Content-Encoding. The limit applies to the decompressed body.
+func readResponseBody(resp *http.Response, limit int64) ([]byte, error) {
+	var body io.Reader = resp.Body
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), gzipEncoding) {
+		reader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		body = reader
+	}
+	return readLimited(body, limit)
+}
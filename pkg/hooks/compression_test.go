@@ -0,0 +1,72 @@
+package hooks
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+)
+
+func TestWebhookExecutor_gzip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "gzip" {
+			t.Errorf("expected gzip Content-Encoding, got: %q", r.Header.Get("Content-Encoding"))
+		}
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("expected gzip Accept-Encoding, got: %q", r.Header.Get("Accept-Encoding"))
+		}
+		reader, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Fatalf("can't read gzip request: %v", err)
+		}
+		body, _ := ioutil.ReadAll(reader)
+		if string(body) != `{"parent":"foo"}` {
+			t.Errorf("unexpected request body: %s", body)
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		writer := gzip.NewWriter(w)
+		_, _ = writer.Write([]byte(`{"value":"ok"}`))
+		_ = writer.Close()
+	}))
+	defer server.Close()
+
+	compression := v1alpha1.WebhookCompressionGzip
+	executor, err := NewWebhookExecutor(&v1alpha1.Webhook{
+		URL:         &server.URL,
+		Compression: &compression,
+	}, "gzip", common.CompositeController, common.SyncHook, nil, logr.Discard())
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	var response struct {
+		Value string `json:"value"`
+	}
+	if err := executor.Execute(context.Background(), map[string]string{"parent": "foo"}, &response); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if response.Value != "ok" {
+		t.Errorf("expected value ok, got: %q", response.Value)
+	}
+}
+
+func TestNewWebhookExecutor_unknownCompression(t *testing.T) {
+	url := "http://localhost"
+	compression := v1alpha1.WebhookCompression("br")
+
+	_, err := NewWebhookExecutor(&v1alpha1.Webhook{
+		URL:         &url,
+		Compression: &compression,
+	}, "unknown-compression", common.CompositeController, common.SyncHook, nil, logr.Discard())
+
+	if err == nil {
+		t.Errorf("expected error for unknown compression")
+	}
+}
@@ -0,0 +1,427 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	controllerruntimemetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// circuitFailureThreshold is the number of consecutive failures after
+	// which calls to an endpoint fail fast.
+	circuitFailureThreshold = 5
+	// circuitCooldown is how long an open circuit waits before letting a
+	// single probe call through.
+	circuitCooldown = 30 * time.Second
+)
+
+// Endpoints holds the connection pools of all webhook endpoints. Its state is
+// served as JSON on the /debug/endpoints path of the metrics endpoint.
+var Endpoints = NewEndpointManager()
+
+func init() {
+	controllerruntimemetrics.Registry.MustRegister(Endpoints)
+}
+
+type circuitState string
+
+const (
+	circuitClosed   circuitState = "closed"
+	circuitOpen     circuitState = "open"
+	circuitHalfOpen circuitState = "half-open"
+)
+
+var circuitStates = []circuitState{circuitClosed, circuitOpen, circuitHalfOpen}
+
+// EndpointUnavailableError is returned without calling an endpoint whose
+// circuit is open, or which is drained.
+type EndpointUnavailableError struct {
+	Endpoint string
+	Reason   string
+}
+
+func (e *EndpointUnavailableError) Error() string {
+	return fmt.Sprintf("endpoint %s is unavailable: %s", e.Endpoint, e.Reason)
+}
+
+// EndpointStatus is the state of the connection pool of one endpoint.
+type EndpointStatus struct {
+	Endpoint            string       `json:"endpoint"`
+	OpenConnections     int64        `json:"openConnections"`
+	InFlight            int64        `json:"inFlight"`
+	Circuit             circuitState `json:"circuit"`
+	ConsecutiveFailures int          `json:"consecutiveFailures"`
+	// ErrorBudget is the number of failures left before the circuit opens.
+	ErrorBudget int    `json:"errorBudget"`
+	Requests    uint64 `json:"requests"`
+	Failures    uint64 `json:"failures"`
+	Draining    bool   `json:"draining"`
+}
+
+// EndpointManager pools connections per webhook endpoint (scheme and host),
+// and guards each endpoint with a circuit breaker.
+type EndpointManager struct {
+	mutex     sync.Mutex
+	endpoints map[string]*endpoint
+
+	now func() time.Time
+}
+
+// NewEndpointManager returns an EndpointManager without endpoints.
+func NewEndpointManager() *EndpointManager {
+	return &EndpointManager{
+		endpoints: make(map[string]*endpoint),
+		now:       time.Now,
+	}
+}
+
+// transport returns the http.RoundTripper to use for calls to rawURL with
+// the given connection settings.
+func (m *EndpointManager) transport(rawURL string, config transportConfig) (http.RoundTripper, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook url %q: %w", rawURL, err)
+	}
+	name := parsed.Scheme + "://" + parsed.Host
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	e, ok := m.endpoints[name]
+	if !ok {
+		e = &endpoint{
+			name:       name,
+			state:      circuitClosed,
+			transports: make(map[transportConfig]*http.Transport),
+			now:        m.now,
+		}
+		m.endpoints[name] = e
+	}
+	transport, ok := e.transports[config]
+	if !ok {
+		transport = config.newTransport(e)
+		e.transports[config] = transport
+	}
+	return &endpointTransport{endpoint: e, transport: transport}, nil
+}
+
+// Drain makes new calls to the endpoint wait until it's resumed (or until
+// the call times out), and closes its idle connections. Use it while the
+// webhook is redeployed.
+func (m *EndpointManager) Drain(name string) error {
+	e, err := m.get(name)
+	if err != nil {
+		return err
+	}
+	e.mutex.Lock()
+	if !e.draining {
+		e.draining = true
+		e.resumed = make(chan struct{})
+	}
+	e.mutex.Unlock()
+	// Transports are only added with the manager's mutex held.
+	m.mutex.Lock()
+	for _, transport := range e.transports {
+		transport.CloseIdleConnections()
+	}
+	m.mutex.Unlock()
+	return nil
+}
+
+// Resume lets calls to a drained endpoint through again.
+func (m *EndpointManager) Resume(name string) error {
+	e, err := m.get(name)
+	if err != nil {
+		return err
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.draining {
+		e.draining = false
+		close(e.resumed)
+	}
+	return nil
+}
+
+func (m *EndpointManager) get(name string) (*endpoint, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	e, ok := m.endpoints[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown endpoint %q", name)
+	}
+	return e, nil
+}
+
+// Status returns the state of all endpoints, sorted by name.
+func (m *EndpointManager) Status() []EndpointStatus {
+	m.mutex.Lock()
+	result := make([]EndpointStatus, 0, len(m.endpoints))
+	for _, e := range m.endpoints {
+		result = append(result, e.status())
+	}
+	m.mutex.Unlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Endpoint < result[j].Endpoint
+	})
+	return result
+}
+
+// ServeHTTP writes the state of all endpoints as JSON. A POST with the
+// "endpoint" and "action" (drain or resume) query parameters drains or
+// resumes an endpoint; it's up to the caller to authenticate it.
+func (m *EndpointManager) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		name := req.URL.Query().Get("endpoint")
+		var err error
+		switch action := req.URL.Query().Get("action"); action {
+		case "drain":
+			err = m.Drain(name)
+		case "resume":
+			err = m.Resume(name)
+		default:
+			http.Error(w, fmt.Sprintf("unknown action %q, must be drain or resume", action), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(m.Status())
+}
+
+var (
+	endpointOpenConnectionsDesc = prometheus.NewDesc(
+		"metacontroller_hook_endpoint_open_connections",
+		"Number of open connections to a webhook endpoint.",
+		[]string{"endpoint"}, nil)
+	endpointInFlightDesc = prometheus.NewDesc(
+		"metacontroller_hook_endpoint_in_flight_requests",
+		"Number of calls to a webhook endpoint currently in flight.",
+		[]string{"endpoint"}, nil)
+	endpointCircuitStateDesc = prometheus.NewDesc(
+		"metacontroller_hook_endpoint_circuit_state",
+		"State of the circuit breaker of a webhook endpoint (1 for the current state).",
+		[]string{"endpoint", "state"}, nil)
+	endpointErrorBudgetDesc = prometheus.NewDesc(
+		"metacontroller_hook_endpoint_error_budget",
+		"Number of consecutive failures left before the circuit of a webhook endpoint opens.",
+		[]string{"endpoint"}, nil)
+	endpointRequestsDesc = prometheus.NewDesc(
+		"metacontroller_hook_endpoint_requests_total",
+		"Number of calls made to a webhook endpoint.",
+		[]string{"endpoint"}, nil)
+	endpointFailuresDesc = prometheus.NewDesc(
+		"metacontroller_hook_endpoint_failures_total",
+		"Number of calls to a webhook endpoint which failed or returned a server error.",
+		[]string{"endpoint"}, nil)
+	endpointDrainingDesc = prometheus.NewDesc(
+		"metacontroller_hook_endpoint_draining",
+		"Whether a webhook endpoint is drained (1) or not (0).",
+		[]string{"endpoint"}, nil)
+)
+
+// Describe implements prometheus.Collector.
+func (m *EndpointManager) Describe(ch chan<- *prometheus.Desc) {
+	ch <- endpointOpenConnectionsDesc
+	ch <- endpointInFlightDesc
+	ch <- endpointCircuitStateDesc
+	ch <- endpointErrorBudgetDesc
+	ch <- endpointRequestsDesc
+	ch <- endpointFailuresDesc
+	ch <- endpointDrainingDesc
+}
+
+// Collect implements prometheus.Collector.
+func (m *EndpointManager) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range m.Status() {
+		ch <- prometheus.MustNewConstMetric(endpointOpenConnectionsDesc, prometheus.GaugeValue, float64(s.OpenConnections), s.Endpoint)
+		ch <- prometheus.MustNewConstMetric(endpointInFlightDesc, prometheus.GaugeValue, float64(s.InFlight), s.Endpoint)
+		for _, state := range circuitStates {
+			ch <- prometheus.MustNewConstMetric(endpointCircuitStateDesc, prometheus.GaugeValue, boolToFloat(s.Circuit == state), s.Endpoint, string(state))
+		}
+		ch <- prometheus.MustNewConstMetric(endpointErrorBudgetDesc, prometheus.GaugeValue, float64(s.ErrorBudget), s.Endpoint)
+		ch <- prometheus.MustNewConstMetric(endpointRequestsDesc, prometheus.CounterValue, float64(s.Requests), s.Endpoint)
+		ch <- prometheus.MustNewConstMetric(endpointFailuresDesc, prometheus.CounterValue, float64(s.Failures), s.Endpoint)
+		ch <- prometheus.MustNewConstMetric(endpointDrainingDesc, prometheus.GaugeValue, boolToFloat(s.Draining), s.Endpoint)
+	}
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// endpoint is the connection pool and circuit breaker of one endpoint.
+type endpoint struct {
+	// Accessed atomically, and first for 64-bit alignment on 32-bit platforms.
+	openConns int64
+	inFlight  int64
+
+	name string
+	// transports holds a transport for each distinct transportConfig used
+	// to call the endpoint.
+	transports map[transportConfig]*http.Transport
+
+	mutex               sync.Mutex
+	state               circuitState
+	probing             bool
+	consecutiveFailures int
+	openedAt            time.Time
+	requests            uint64
+	failures            uint64
+	draining            bool
+	resumed             chan struct{}
+
+	now func() time.Time
+}
+
+// endpointTransport calls an endpoint through one of its transports.
+type endpointTransport struct {
+	*endpoint
+	transport *http.Transport
+}
+
+// RoundTrip implements http.RoundTripper. Transport errors and 5xx responses
+// count as failures for the circuit breaker.
+func (t *endpointTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.acquire(req.Context()); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	atomic.AddInt64(&t.inFlight, 1)
+	resp, err := t.transport.RoundTrip(req)
+	atomic.AddInt64(&t.inFlight, -1)
+	t.release(err == nil && resp.StatusCode < http.StatusInternalServerError)
+	return resp, err
+}
+
+// acquire waits for the endpoint to be resumed if it's drained, and checks
+// whether the circuit lets the call through.
+func (e *endpoint) acquire(ctx context.Context) error {
+	e.mutex.Lock()
+	for e.draining {
+		resumed := e.resumed
+		e.mutex.Unlock()
+		select {
+		case <-resumed:
+		case <-ctx.Done():
+			return &EndpointUnavailableError{Endpoint: e.name, Reason: "drained"}
+		}
+		e.mutex.Lock()
+	}
+	defer e.mutex.Unlock()
+
+	switch e.state {
+	case circuitOpen:
+		if e.now().Sub(e.openedAt) < circuitCooldown {
+			return e.circuitOpenError()
+		}
+		e.state = circuitHalfOpen
+		e.probing = true
+	case circuitHalfOpen:
+		if e.probing {
+			return e.circuitOpenError()
+		}
+		e.probing = true
+	}
+	e.requests++
+	return nil
+}
+
+func (e *endpoint) release(succeeded bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.probing = false
+	if succeeded {
+		e.state = circuitClosed
+		e.consecutiveFailures = 0
+		return
+	}
+	e.failures++
+	e.consecutiveFailures++
+	if e.state == circuitHalfOpen || e.consecutiveFailures >= circuitFailureThreshold {
+		e.state = circuitOpen
+		e.openedAt = e.now()
+	}
+}
+
+func (e *endpoint) circuitOpenError() error {
+	return &EndpointUnavailableError{
+		Endpoint: e.name,
+		Reason:   fmt.Sprintf("circuit open after %d consecutive failures", e.consecutiveFailures),
+	}
+}
+
+func (e *endpoint) status() EndpointStatus {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	budget := circuitFailureThreshold - e.consecutiveFailures
+	if budget < 0 || e.state != circuitClosed {
+		budget = 0
+	}
+	return EndpointStatus{
+		Endpoint:            e.name,
+		OpenConnections:     atomic.LoadInt64(&e.openConns),
+		InFlight:            atomic.LoadInt64(&e.inFlight),
+		Circuit:             e.state,
+		ConsecutiveFailures: e.consecutiveFailures,
+		ErrorBudget:         budget,
+		Requests:            e.requests,
+		Failures:            e.failures,
+		Draining:            e.draining,
+	}
+}
+
+// countedConn decrements the open connections of its endpoint when closed.
+type countedConn struct {
+	net.Conn
+	endpoint *endpoint
+	once     sync.Once
+}
+
+func (c *countedConn) Close() error {
+	c.once.Do(func() {
+		atomic.AddInt64(&c.endpoint.openConns, -1)
+	})
+	return c.Conn.Close()
+}
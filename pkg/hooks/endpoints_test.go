@@ -0,0 +1,108 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEndpointManager_circuitBreaker(t *testing.T) {
+	status := http.StatusInternalServerError
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	manager := NewEndpointManager()
+	manager.now = func() time.Time { return now }
+	transport, err := manager.transport(server.URL+"/sync", defaultTransportConfig)
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	client := &http.Client{Transport: transport}
+
+	for i := 0; i < circuitFailureThreshold; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("call %d: err should be nil, got: %v", i, err)
+		}
+		resp.Body.Close()
+	}
+	_, err = client.Get(server.URL)
+	var unavailable *EndpointUnavailableError
+	if !errors.As(err, &unavailable) {
+		t.Fatalf("expected EndpointUnavailableError once the circuit is open, got: %v", err)
+	}
+	if s := manager.Status()[0]; s.Circuit != circuitOpen || s.Failures != circuitFailureThreshold || s.ErrorBudget != 0 {
+		t.Errorf("unexpected endpoint status: %+v", s)
+	}
+
+	// After the cooldown, a successful probe closes the circuit.
+	now = now.Add(circuitCooldown)
+	status = http.StatusOK
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	resp.Body.Close()
+	if s := manager.Status()[0]; s.Circuit != circuitClosed || s.ErrorBudget != circuitFailureThreshold {
+		t.Errorf("unexpected endpoint status: %+v", s)
+	}
+}
+
+func TestEndpointManager_drain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	manager := NewEndpointManager()
+	transport, err := manager.transport(server.URL, defaultTransportConfig)
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	client := &http.Client{Transport: transport}
+
+	drain := httptest.NewRecorder()
+	manager.ServeHTTP(drain, httptest.NewRequest(http.MethodPost, "/debug/endpoints?action=drain&endpoint="+server.URL, nil))
+	if drain.Code != http.StatusOK {
+		t.Fatalf("expected drain to succeed, got %d: %s", drain.Code, drain.Body)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	request, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	_, err = client.Do(request)
+	var unavailable *EndpointUnavailableError
+	if !errors.As(err, &unavailable) {
+		t.Errorf("expected EndpointUnavailableError while drained, got: %v", err)
+	}
+
+	done := make(chan error)
+	go func() {
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		done <- err
+	}()
+	if err := manager.Resume(server.URL); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("expected call to succeed once resumed, got: %v", err)
+	}
+}
+
+func TestEndpointManager_ServeHTTP_unknownEndpoint(t *testing.T) {
+	manager := NewEndpointManager()
+
+	response := httptest.NewRecorder()
+	manager.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/debug/endpoints?action=drain&endpoint=http://unknown", nil))
+
+	if response.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got: %d", http.StatusNotFound, response.Code)
+	}
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"errors"
+
+	"metacontroller/pkg/events"
+)
+
+// CallError is returned when a hook call fails, whether the hook couldn't be
+// reached, returned an error, or returned a response that can't be used.
+type CallError struct {
+	Hook string
+	Err  error
+}
+
+func (e *CallError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CallError) Unwrap() error {
+	return e.Err
+}
+
+// InvalidResponseError is returned when a hook response can't be decoded.
+type InvalidResponseError struct {
+	Err error
+}
+
+func (e *InvalidResponseError) Error() string {
+	return "can't unmarshal response: " + e.Err.Error()
+}
+
+func (e *InvalidResponseError) Unwrap() error {
+	return e.Err
+}
+
+// SyncErrorReason returns the reason of the event reporting a sync which
+// failed with err, telling hook failures apart from other errors.
+func SyncErrorReason(err error) string {
+	var invalid *InvalidResponseError
+	var violation *SchemaViolationError
+	var callErr *CallError
+	switch {
+	case errors.As(err, &invalid), errors.As(err, &violation):
+		return events.ReasonInvalidHookResponse
+	case errors.As(err, &callErr):
+		return events.ReasonHookError
+	default:
+		return events.ReasonSyncError
+	}
+}
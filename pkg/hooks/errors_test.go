@@ -0,0 +1,52 @@
+package hooks
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"metacontroller/pkg/events"
+)
+
+func TestSyncErrorReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "other error",
+			err:  errors.New("can't update status"),
+			want: events.ReasonSyncError,
+		},
+		{
+			name: "failed call",
+			err:  fmt.Errorf("sync hook failed: %w", &CallError{Hook: "sync", Err: errors.New("remote error: boom")}),
+			want: events.ReasonHookError,
+		},
+		{
+			name: "undecodable response",
+			err:  fmt.Errorf("sync hook failed: %w", &CallError{Hook: "sync", Err: &InvalidResponseError{Err: errors.New("bad json")}}),
+			want: events.ReasonInvalidHookResponse,
+		},
+		{
+			name: "schema violation",
+			err:  fmt.Errorf("sync hook failed: %w", &CallError{Hook: "sync", Err: &SchemaViolationError{Violations: []string{"status: required"}}}),
+			want: events.ReasonInvalidHookResponse,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SyncErrorReason(tt.err); got != tt.want {
+				t.Errorf("SyncErrorReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCallError_keepsMessage(t *testing.T) {
+	err := &CallError{Hook: "sync", Err: &InvalidResponseError{Err: errors.New("bad json")}}
+	if got, want := err.Error(), "can't unmarshal response: bad json"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	k8sjson "k8s.io/apimachinery/pkg/util/json"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/logging"
+	"metacontroller/pkg/tracing"
+)
+
+// SyncIDEnv is the environment variable holding the ID of the sync an exec
+// hook command is run for.
+const SyncIDEnv = "METACONTROLLER_SYNC_ID"
+
+// maxExecStderr limits how much of a failed command's stderr is reported.
+const maxExecStderr = 4096
+
+// ExecExecutor executes a hook locally, passing the request on stdin of a
+// command (or writing it to a UNIX socket) and reading the response from
+// stdout (or from the socket until it's closed).
+type ExecExecutor struct {
+	command   []string
+	socket    string
+	timeout   time.Duration
+	hookType  string
+	converter payloadConverter
+
+	maxResponseSize int64
+}
+
+// NewExecExecutor returns new ExecExecutor
+func NewExecExecutor(execHook *v1alpha1.ExecHook, hookType string, logger logr.Logger) (*ExecExecutor, error) {
+	if execHook == nil {
+		return nil, nil
+	}
+	hasCommand := len(execHook.Command) > 0
+	hasSocket := execHook.Socket != nil && *execHook.Socket != ""
+	if hasCommand == hasSocket {
+		return nil, fmt.Errorf("invalid exec hook config: must specify exactly one of 'command' or 'socket'")
+	}
+	timeout := 10 * time.Second
+	if execHook.Timeout != nil {
+		if execHook.Timeout.Duration <= 0 {
+			logger.Info("invalid exec hook config: timeout must be a non-zero positive duration. Defaulting to 10 seconds")
+		} else {
+			timeout = execHook.Timeout.Duration
+		}
+	}
+	executor := &ExecExecutor{
+		command:   execHook.Command,
+		timeout:   timeout,
+		hookType:  hookType,
+		converter: v1Converter{},
+
+		maxResponseSize: DefaultMaxResponseSize,
+	}
+	if hasSocket {
+		executor.socket = *execHook.Socket
+	}
+	return executor, nil
+}
+
+// Execute runs the hook with the request and decodes its output into response.
+func (e *ExecExecutor) Execute(ctx context.Context, request interface{}, response interface{}) (err error) {
+	ctx, span := tracing.StartClient(ctx, "hook "+e.hookType, "hook.type", e.hookType, "hook.exec", e.target())
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	// Encode request.
+	reqBody, err := k8sjson.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("can't marshal request: %w", err)
+	}
+	reqBody, err = e.converter.convertRequest(reqBody)
+	if err != nil {
+		return err
+	}
+	stats := callStatsFrom(ctx)
+	stats.requestBytes = len(reqBody)
+	if logger.V(6).Enabled() {
+		logger.Info("Exec hook request", "syncID", tracing.SyncID(ctx), "type", e.hookType, "exec", e.target(), "body", json.RawMessage(reqBody))
+	}
+
+	var respBody []byte
+	if e.socket != "" {
+		respBody, err = e.callSocket(ctx, reqBody)
+	} else {
+		respBody, err = e.runCommand(ctx, reqBody)
+	}
+	if err != nil {
+		return err
+	}
+	stats.responseBytes = len(respBody)
+	if logger.V(6).Enabled() {
+		logger.V(6).Info("Exec hook response", "syncID", tracing.SyncID(ctx), "type", e.hookType, "exec", e.target(), "body", json.RawMessage(respBody))
+	}
+
+	// Decode response.
+	respBody, err = e.converter.convertResponse(respBody)
+	if err != nil {
+		return err
+	}
+	if err := k8sjson.Unmarshal(respBody, response); err != nil {
+		return &InvalidResponseError{Err: err}
+	}
+	return nil
+}
+
+func (e *ExecExecutor) target() string {
+	if e.socket != "" {
+		return "unix://" + e.socket
+	}
+	return strings.Join(e.command, " ")
+}
+
+func (e *ExecExecutor) runCommand(ctx context.Context, reqBody []byte) ([]byte, error) {
+	// #nosec G204 -- the command is configured by the controller author.
+	cmd := exec.CommandContext(ctx, e.command[0], e.command[1:]...)
+	cmd.Stdin = bytes.NewReader(reqBody)
+	if syncID := tracing.SyncID(ctx); syncID != "" {
+		cmd.Env = append(os.Environ(), SyncIDEnv+"="+syncID)
+	}
+	stdout := &limitedBuffer{limit: e.maxResponseSize}
+	var stderr bytes.Buffer
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if stdout.exceeded {
+			return nil, fmt.Errorf("exec error: %w", &ResponseTooLargeError{Limit: e.maxResponseSize})
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("exec error: timed out after %v", e.timeout)
+		}
+		errOutput := stderr.Bytes()
+		if len(errOutput) > maxExecStderr {
+			errOutput = errOutput[:maxExecStderr]
+		}
+		return nil, fmt.Errorf("exec error: %w: %s", err, errOutput)
+	}
+	return stdout.Bytes(), nil
+}
+
+func (e *ExecExecutor) callSocket(ctx context.Context, reqBody []byte) ([]byte, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", e.socket)
+	if err != nil {
+		return nil, fmt.Errorf("socket error: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, fmt.Errorf("socket error: %w", err)
+		}
+	}
+
+	if _, err := conn.Write(reqBody); err != nil {
+		return nil, fmt.Errorf("socket error: can't write request: %w", err)
+	}
+	// Signal the end of the request, so the sidecar knows it can respond.
+	if unixConn, ok := conn.(*net.UnixConn); ok {
+		if err := unixConn.CloseWrite(); err != nil {
+			return nil, fmt.Errorf("socket error: %w", err)
+		}
+	}
+	respBody, err := readLimited(conn, e.maxResponseSize)
+	if err != nil {
+		return nil, fmt.Errorf("socket error: can't read response: %w", err)
+	}
+	return respBody, nil
+}
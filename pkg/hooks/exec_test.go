@@ -0,0 +1,148 @@
+package hooks
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/utils/pointer"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/tracing"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type execTestResponse struct {
+	Value string `json:"value"`
+}
+
+func TestNewExecExecutor_whenNilExecHook_returnNil(t *testing.T) {
+	executor, err := NewExecExecutor(nil, "sync", logr.Discard())
+
+	if err != nil {
+		t.Errorf("err should be nil, got: %v", err)
+	}
+	if executor != nil {
+		t.Errorf("ExecExecutor should be nil")
+	}
+}
+
+func TestNewExecExecutor_requiresExactlyOneTarget(t *testing.T) {
+	tables := []v1alpha1.ExecHook{
+		{},
+		{Command: []string{"cat"}, Socket: pointer.StringPtr("/tmp/hook.sock")},
+	}
+
+	for _, table := range tables {
+		if _, err := NewExecExecutor(&table, "sync", logr.Discard()); err == nil {
+			t.Errorf("expected error for %+v", table)
+		}
+	}
+}
+
+func TestExecExecutor_command(t *testing.T) {
+	executor, err := NewExecExecutor(&v1alpha1.ExecHook{
+		Command: []string{"sh", "-c", `grep -q '"parent":"foo"' && echo '{"value":"ok"}'`},
+	}, "sync", logr.Discard())
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	var response execTestResponse
+	if err := executor.Execute(context.Background(), map[string]string{"parent": "foo"}, &response); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if response.Value != "ok" {
+		t.Errorf("expected value ok, got: %q", response.Value)
+	}
+}
+
+func TestExecExecutor_command_passesSyncID(t *testing.T) {
+	executor, err := NewExecExecutor(&v1alpha1.ExecHook{
+		Command: []string{"sh", "-c", `cat >/dev/null; echo "{\"value\":\"$` + SyncIDEnv + `\"}"`},
+	}, "sync", logr.Discard())
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	ctx, span := tracing.Start(context.Background(), "sync")
+	defer span.End()
+	var response execTestResponse
+	if err := executor.Execute(ctx, map[string]string{"parent": "foo"}, &response); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if want := tracing.SyncID(ctx); response.Value != want {
+		t.Errorf("expected sync ID %q, got: %q", want, response.Value)
+	}
+}
+
+func TestExecExecutor_commandFailure_reportsStderr(t *testing.T) {
+	executor, err := NewExecExecutor(&v1alpha1.ExecHook{
+		Command: []string{"sh", "-c", "echo boom >&2; exit 3"},
+	}, "sync", logr.Discard())
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	var response execTestResponse
+	err = executor.Execute(context.Background(), map[string]string{}, &response)
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("expected error containing stderr, got: %v", err)
+	}
+}
+
+func TestExecExecutor_commandTimeout(t *testing.T) {
+	executor, err := NewExecExecutor(&v1alpha1.ExecHook{
+		Command: []string{"sleep", "5"},
+		Timeout: &v1.Duration{Duration: 50 * time.Millisecond},
+	}, "sync", logr.Discard())
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	var response execTestResponse
+	err = executor.Execute(context.Background(), map[string]string{}, &response)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected timeout error, got: %v", err)
+	}
+}
+
+func TestExecExecutor_socket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "hook.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("can't listen on socket: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		request, _ := ioutil.ReadAll(conn)
+		if !strings.Contains(string(request), `"parent":"foo"`) {
+			t.Errorf("unexpected request: %s", request)
+		}
+		_, _ = conn.Write([]byte(`{"value":"ok"}`))
+	}()
+
+	executor, err := NewExecExecutor(&v1alpha1.ExecHook{Socket: &socket}, "sync", logr.Discard())
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	var response execTestResponse
+	if err := executor.Execute(context.Background(), map[string]string{"parent": "foo"}, &response); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if response.Value != "ok" {
+		t.Errorf("expected value ok, got: %q", response.Value)
+	}
+}
@@ -17,45 +17,139 @@ limitations under the License.
 package hooks
 
 import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+
 	"metacontroller/pkg/apis/metacontroller/v1alpha1"
 	"metacontroller/pkg/controller/common"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	"metacontroller/pkg/metrics"
 )
 
 // HookExecutor an execute Hook requests
 type HookExecutor interface {
 	IsEnabled() bool
-	Execute(request interface{}, response interface{}) error
+	Execute(ctx context.Context, request interface{}, response interface{}) error
 }
 
-// NewHookExecutor return new HookExecutor which implements given v1alpha1.Hook
+// NewHookExecutor return new HookExecutor which implements given v1alpha1.Hook.
+// The dynamic client is used to read Secrets and ConfigMaps referenced by the
+// hook, hookTransport holds the connection settings of the controller, and
+// logger reports invalid settings which are replaced by their defaults.
 func NewHookExecutor(
 	hook *v1alpha1.Hook,
 	controllerName string,
 	controllerType common.ControllerType,
-	hookType common.HookType) (HookExecutor, error) {
-	if hook != nil {
-		executor, err := NewWebhookExecutor(hook.Webhook, controllerName, controllerType, hookType)
+	hookType common.HookType,
+	dynClient *dynamicclientset.Clientset,
+	hookTransport *v1alpha1.HookTransport,
+	logger logr.Logger) (HookExecutor, error) {
+	if hook == nil {
+		return &hookExecutorImpl{}, nil
+	}
+	transports := 0
+	for _, configured := range []bool{hook.Webhook != nil, hook.Exec != nil, hook.NATS != nil} {
+		if configured {
+			transports++
+		}
+	}
+	if transports > 1 {
+		return nil, fmt.Errorf("invalid hook config: must specify only one of 'webhook', 'exec' or 'nats'")
+	}
+	converter, err := newPayloadConverter(hook.Version)
+	if err != nil {
+		return nil, err
+	}
+	converter, err = withResponseSchema(converter, hook.ResponseSchema, dynClient)
+	if err != nil {
+		return nil, err
+	}
+	maxSize, err := maxResponseSize(hook.MaxResponseSize)
+	if err != nil {
+		return nil, err
+	}
+
+	webhookExecutor, err := NewWebhookExecutor(hook.Webhook, controllerName, controllerType, hookType, hookTransport, logger)
+	if err != nil {
+		return nil, err
+	}
+	if webhookExecutor != nil {
+		webhookExecutor.converter = converter
+		webhookExecutor.maxResponseSize = maxSize
+		webhookExecutor.signer, err = newRequestSigner(hook.Webhook.Signing, dynClient)
 		if err != nil {
 			return nil, err
 		}
-		return &hookExecutorImpl{
-			webhookExecutor: executor,
-		}, nil
 	}
+
+	execExecutor, err := NewExecExecutor(hook.Exec, hookType.String(), logger)
+	if err != nil {
+		return nil, err
+	}
+	if execExecutor != nil {
+		execExecutor.converter = converter
+		execExecutor.maxResponseSize = maxSize
+	}
+
+	natsExecutor, err := NewNATSExecutor(hook.NATS, hookType.String(), logger)
+	if err != nil {
+		return nil, err
+	}
+	if natsExecutor != nil {
+		natsExecutor.converter = converter
+		natsExecutor.maxResponseSize = maxSize
+	}
+
 	return &hookExecutorImpl{
-		webhookExecutor: nil,
+		webhookExecutor: webhookExecutor,
+		execExecutor:    execExecutor,
+		natsExecutor:    natsExecutor,
+		controllerName:  controllerName,
+		controllerType:  controllerType,
+		hookType:        hookType,
 	}, nil
 }
 
 // hookExecutorImpl is default implementation of HookExecutor
 type hookExecutorImpl struct {
 	webhookExecutor *WebhookExecutor
+	execExecutor    *ExecExecutor
+	natsExecutor    *NATSExecutor
+
+	controllerName string
+	controllerType common.ControllerType
+	hookType       common.HookType
 }
 
 func (h *hookExecutorImpl) IsEnabled() bool {
-	return h.webhookExecutor != nil
+	return h.webhookExecutor != nil || h.execExecutor != nil || h.natsExecutor != nil
 }
 
-func (h *hookExecutorImpl) Execute(request interface{}, response interface{}) error {
-	return h.webhookExecutor.Execute(request, response)
+func (h *hookExecutorImpl) Execute(ctx context.Context, request interface{}, response interface{}) error {
+	var err error
+	stats := &callStats{}
+	ctx = withCallStats(ctx, stats)
+	start := time.Now()
+	if h.execExecutor != nil {
+		err = h.execExecutor.Execute(ctx, request, response)
+	} else if h.natsExecutor != nil {
+		err = h.natsExecutor.Execute(ctx, request, response)
+	} else {
+		err = h.webhookExecutor.Execute(ctx, request, response)
+	}
+	metrics.ObserveHookCall(h.controllerName, h.controllerType, h.hookType, metrics.HookCall{
+		Duration:      time.Since(start),
+		RequestBytes:  stats.requestBytes,
+		ResponseBytes: stats.responseBytes,
+		StatusCode:    stats.statusCode,
+		Err:           err,
+	})
+	DebugRecorder.Record(parentFromContext(ctx), h.controllerName, h.hookType.String(), request, response, err)
+	if err != nil {
+		return &CallError{Hook: h.hookType.String(), Err: err}
+	}
+	return nil
 }
@@ -4,10 +4,12 @@ import (
 	"metacontroller/pkg/apis/metacontroller/v1alpha1"
 	"metacontroller/pkg/controller/common"
 	"testing"
+
+	"github.com/go-logr/logr"
 )
 
 func TestNewHookExecutor_whenNilHook_returnDisabledHookExecutor(t *testing.T) {
-	executor, err := NewHookExecutor(nil, "", common.CompositeController, "")
+	executor, err := NewHookExecutor(nil, "", common.CompositeController, "", nil, nil, logr.Discard())
 
 	if err != nil {
 		t.Errorf("err should be nil, got: %v", err)
@@ -21,7 +23,7 @@ func TestNewHookExecutor_whenNilHook_returnDisabledHookExecutor(t *testing.T) {
 func TestNewHookExecutor_whenHookWithNilWebhook_returnDisabledHookExecutor(t *testing.T) {
 	executor, err := NewHookExecutor(&v1alpha1.Hook{
 		Webhook: nil},
-		"", common.CompositeController, "")
+		"", common.CompositeController, "", nil, nil, logr.Discard())
 
 	if err != nil {
 		t.Errorf("err should be nil, got: %v", err)
@@ -0,0 +1,83 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// DefaultMaxResponseSize is the maximum size in bytes of a hook response,
+// for hooks which don't set their own maxResponseSize.
+var DefaultMaxResponseSize int64 = 64 << 20
+
+// ResponseTooLargeError is returned when a hook response exceeds the maximum
+// size allowed for the hook.
+type ResponseTooLargeError struct {
+	Limit int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("response exceeds the maximum size of %d bytes (see hook maxResponseSize)", e.Limit)
+}
+
+func maxResponseSize(size *resource.Quantity) (int64, error) {
+	if size == nil {
+		return DefaultMaxResponseSize, nil
+	}
+	if size.Sign() <= 0 {
+		return 0, fmt.Errorf("invalid hook config: maxResponseSize must be positive, got %s", size)
+	}
+	return size.Value(), nil
+}
+
+// readLimited reads r until EOF, and fails without buffering the rest of it
+// as soon as more than limit bytes are read.
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, &ResponseTooLargeError{Limit: limit}
+	}
+	return body, nil
+}
+
+// limitedBuffer is an io.Writer buffering up to limit bytes. It doesn't embed
+// bytes.Buffer, so io.Copy can't bypass Write through Buffer.ReadFrom.
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	limit    int64
+	exceeded bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if int64(b.buf.Len()+len(p)) > b.limit {
+		b.exceeded = true
+		return 0, &ResponseTooLargeError{Limit: b.limit}
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
@@ -0,0 +1,72 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+)
+
+func TestHookExecutor_maxResponseSize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"value":"` + strings.Repeat("x", 2048) + `"}`))
+	}))
+	defer server.Close()
+
+	maxSize := resource.MustParse("1Ki")
+	executor, err := NewHookExecutor(&v1alpha1.Hook{
+		Webhook:         &v1alpha1.Webhook{URL: &server.URL},
+		MaxResponseSize: &maxSize,
+	}, "max-response-size", common.CompositeController, common.SyncHook, nil, nil, logr.Discard())
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	var response map[string]string
+	err = executor.Execute(context.Background(), map[string]string{}, &response)
+
+	var tooLarge *ResponseTooLargeError
+	if !errors.As(err, &tooLarge) || tooLarge.Limit != 1024 {
+		t.Errorf("expected ResponseTooLargeError with limit 1024, got: %v", err)
+	}
+}
+
+func TestExecExecutor_maxResponseSize(t *testing.T) {
+	executor, err := NewExecExecutor(&v1alpha1.ExecHook{
+		Command: []string{"sh", "-c", "head -c 4096 /dev/zero"},
+	}, "sync", logr.Discard())
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	executor.maxResponseSize = 1024
+
+	var response map[string]string
+	err = executor.Execute(context.Background(), map[string]string{}, &response)
+
+	var tooLarge *ResponseTooLargeError
+	if !errors.As(err, &tooLarge) {
+		t.Errorf("expected ResponseTooLargeError, got: %v", err)
+	}
+}
+
+func TestNewHookExecutor_invalidMaxResponseSize(t *testing.T) {
+	url := "http://localhost"
+	maxSize := resource.MustParse("0")
+
+	_, err := NewHookExecutor(&v1alpha1.Hook{
+		Webhook:         &v1alpha1.Webhook{URL: &url},
+		MaxResponseSize: &maxSize,
+	}, "invalid-max-response-size", common.CompositeController, common.SyncHook, nil, nil, logr.Discard())
+
+	if err == nil {
+		t.Errorf("expected error for zero maxResponseSize")
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/nats-io/nats.go"
+	k8sjson "k8s.io/apimachinery/pkg/util/json"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/logging"
+	"metacontroller/pkg/tracing"
+)
+
+// NATSErrorHeader can be set on a reply to fail the hook call with its value.
+const NATSErrorHeader = "Metacontroller-Error"
+
+// natsConnections holds one connection per server URL, shared by all hooks.
+var natsConnections = &natsConnectionPool{conns: make(map[string]*nats.Conn)}
+
+type natsConnectionPool struct {
+	mutex sync.Mutex
+	conns map[string]*nats.Conn
+}
+
+// get returns the connection to url, creating it if needed. Connections keep
+// reconnecting in the background, so calls made while the server is
+// unavailable fail and the parent is retried later.
+func (p *natsConnectionPool) get(url string) (*nats.Conn, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if conn, ok := p.conns[url]; ok {
+		return conn, nil
+	}
+	conn, err := nats.Connect(url,
+		nats.Name("metacontroller"),
+		nats.MaxReconnects(-1),
+		nats.RetryOnFailedConnect(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+	p.conns[url] = conn
+	return conn, nil
+}
+
+// NATSExecutor executes a hook by NATS request-reply.
+type NATSExecutor struct {
+	url       string
+	subject   string
+	timeout   time.Duration
+	hookType  string
+	converter payloadConverter
+
+	maxResponseSize int64
+}
+
+// NewNATSExecutor returns new NATSExecutor
+func NewNATSExecutor(natsHook *v1alpha1.NATSHook, hookType string, logger logr.Logger) (*NATSExecutor, error) {
+	if natsHook == nil {
+		return nil, nil
+	}
+	if natsHook.URL == "" || natsHook.Subject == "" {
+		return nil, fmt.Errorf("invalid nats hook config: must specify 'url' and 'subject'")
+	}
+	timeout := 10 * time.Second
+	if natsHook.Timeout != nil {
+		if natsHook.Timeout.Duration <= 0 {
+			logger.Info("invalid nats hook config: timeout must be a non-zero positive duration. Defaulting to 10 seconds")
+		} else {
+			timeout = natsHook.Timeout.Duration
+		}
+	}
+	return &NATSExecutor{
+		url:       natsHook.URL,
+		subject:   natsHook.Subject,
+		timeout:   timeout,
+		hookType:  hookType,
+		converter: v1Converter{},
+
+		maxResponseSize: DefaultMaxResponseSize,
+	}, nil
+}
+
+// Execute publishes the request to the subject and decodes the reply into response.
+func (n *NATSExecutor) Execute(ctx context.Context, request interface{}, response interface{}) (err error) {
+	ctx, span := tracing.StartClient(ctx, "hook "+n.hookType, "hook.type", n.hookType, "nats.subject", n.subject)
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+	logger := logging.FromContext(ctx)
+
+	// Encode request.
+	reqBody, err := k8sjson.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("can't marshal request: %w", err)
+	}
+	reqBody, err = n.converter.convertRequest(reqBody)
+	if err != nil {
+		return err
+	}
+	stats := callStatsFrom(ctx)
+	stats.requestBytes = len(reqBody)
+	if logger.V(6).Enabled() {
+		logger.Info("NATS hook request", "syncID", tracing.SyncID(ctx), "type", n.hookType, "subject", n.subject, "body", json.RawMessage(reqBody))
+	}
+
+	conn, err := natsConnections.get(n.url)
+	if err != nil {
+		return fmt.Errorf("nats error: %w", err)
+	}
+	msg := nats.NewMsg(n.subject)
+	msg.Data = reqBody
+	if conn.HeadersSupported() {
+		tracing.Inject(ctx, http.Header(msg.Header))
+	}
+	reply, err := conn.RequestMsgWithContext(ctx, msg)
+	if err != nil {
+		return fmt.Errorf("nats error: %w", err)
+	}
+	stats.responseBytes = len(reply.Data)
+	if int64(len(reply.Data)) > n.maxResponseSize {
+		return fmt.Errorf("nats error: %w", &ResponseTooLargeError{Limit: n.maxResponseSize})
+	}
+	if logger.V(6).Enabled() {
+		logger.V(6).Info("NATS hook response", "syncID", tracing.SyncID(ctx), "type", n.hookType, "subject", n.subject, "body", json.RawMessage(reply.Data))
+	}
+	if remoteErr := reply.Header.Get(NATSErrorHeader); remoteErr != "" {
+		return fmt.Errorf("remote error: %s", remoteErr)
+	}
+
+	// Decode response.
+	respBody, err := n.converter.convertResponse(reply.Data)
+	if err != nil {
+		return err
+	}
+	if err := k8sjson.Unmarshal(respBody, response); err != nil {
+		return &InvalidResponseError{Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package hooks
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+)
+
+func TestNewNATSExecutor_requiresURLAndSubject(t *testing.T) {
+	_, err := NewNATSExecutor(&v1alpha1.NATSHook{URL: "nats://localhost:4222"}, "sync", logr.Discard())
+
+	if err == nil {
+		t.Errorf("expected error for missing subject")
+	}
+}
+
+func TestNewHookExecutor_rejectsMultipleTransports(t *testing.T) {
+	url := "http://localhost"
+
+	_, err := NewHookExecutor(&v1alpha1.Hook{
+		Webhook: &v1alpha1.Webhook{URL: &url},
+		NATS:    &v1alpha1.NATSHook{URL: "nats://localhost:4222", Subject: "sync"},
+	}, "multiple-transports", common.CompositeController, common.SyncHook, nil, nil, logr.Discard())
+
+	if err == nil {
+		t.Errorf("expected error for multiple transports")
+	}
+}
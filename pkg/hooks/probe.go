@@ -0,0 +1,159 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	controllerruntimemetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+)
+
+const probeTimeout = 5 * time.Second
+
+var probedHookTypes = []common.HookType{common.CustomizeHook, common.SyncHook, common.FinalizeHook, common.EventsHook, common.ApplyErrorHook,
+	common.ObserveHook, common.DiffHook, common.CreateHook, common.UpdateHook, common.DeleteHook}
+
+var hookUp = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "metacontroller_hook_up",
+		Help: "Whether the last health probe of a webhook succeeded (1) or not (0).",
+	},
+	[]string{"controller_name", "controller_type", "hook"},
+)
+
+var probeClient = &http.Client{
+	Timeout: probeTimeout,
+	// The probe is about reachability, so a redirect is a good enough answer.
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+func init() {
+	controllerruntimemetrics.Registry.MustRegister(hookUp)
+}
+
+// probeClientFor returns the client to probe webhook with, which goes
+// through the same proxy and trusts the same CAs as calls to the webhook.
+func probeClientFor(webhook *v1alpha1.Webhook) (*http.Client, error) {
+	if webhook.Proxy == nil && len(webhook.CABundle) == 0 {
+		return probeClient, nil
+	}
+	config, err := newTransportConfig(webhook, nil)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Probes are rare, so don't keep their connections open.
+	transport.DisableKeepAlives = true
+	config.configureProxyAndTLS(transport)
+	client := *probeClient
+	client.Transport = transport
+	return &client, nil
+}
+
+// ProbeWebhooks probes the webhooks among hooks concurrently, records the
+// results in the metacontroller_hook_up metric, and returns a description of
+// each unreachable one, sorted by hook type.
+func ProbeWebhooks(ctx context.Context, controllerName string, controllerType common.ControllerType, hooks map[common.HookType]*v1alpha1.Hook) []string {
+	var (
+		wg          sync.WaitGroup
+		mutex       sync.Mutex
+		unreachable []string
+	)
+	for hookType, hook := range hooks {
+		if hook == nil || hook.Webhook == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(hookType common.HookType, webhook *v1alpha1.Webhook) {
+			defer wg.Done()
+			up := 1.0
+			if err := probeWebhook(ctx, webhook); err != nil {
+				up = 0
+				mutex.Lock()
+				unreachable = append(unreachable, fmt.Sprintf("%s hook: %v", hookType, err))
+				mutex.Unlock()
+			}
+			hookUp.WithLabelValues(controllerName, controllerType.String(), hookType.String()).Set(up)
+		}(hookType, hook.Webhook)
+	}
+	wg.Wait()
+	sort.Strings(unreachable)
+	return unreachable
+}
+
+// ForgetProbes removes the probe results of a controller which was deleted.
+// Hook types which aren't built in, such as the hooks of states, are passed
+// as extraHookTypes.
+func ForgetProbes(controllerName string, controllerType common.ControllerType, extraHookTypes ...common.HookType) {
+	for _, hookType := range probedHookTypes {
+		hookUp.DeleteLabelValues(controllerName, controllerType.String(), hookType.String())
+	}
+	for _, hookType := range extraHookTypes {
+		hookUp.DeleteLabelValues(controllerName, controllerType.String(), hookType.String())
+	}
+}
+
+// probeWebhook sends a GET request to the healthPath of the webhook if it's
+// set, expecting a 2xx response. Otherwise, it sends a HEAD request to the
+// webhook itself, and any response other than a server error will do.
+func probeWebhook(ctx context.Context, webhook *v1alpha1.Webhook) error {
+	target, err := webhookURL(webhook)
+	if err != nil {
+		return err
+	}
+	method := http.MethodHead
+	if webhook.HealthPath != nil {
+		parsed, err := url.Parse(target)
+		if err != nil {
+			return err
+		}
+		parsed.Path = *webhook.HealthPath
+		parsed.RawQuery = ""
+		target = parsed.String()
+		method = http.MethodGet
+	}
+	request, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return err
+	}
+	client, err := probeClientFor(webhook)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError ||
+		(webhook.HealthPath != nil && resp.StatusCode >= http.StatusMultipleChoices) {
+		return fmt.Errorf("%s %s returned status %d", method, target, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package hooks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+)
+
+func TestProbeWebhooks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/healthz" && r.Method == http.MethodGet:
+		case r.URL.Path == "/down":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			// Hooks usually only accept POST, which is fine for a probe.
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer server.Close()
+
+	syncURL := server.URL + "/sync"
+	healthPath := "/healthz"
+	downURL := server.URL + "/down"
+	unreachable := ProbeWebhooks(context.Background(), "probe", common.CompositeController, map[common.HookType]*v1alpha1.Hook{
+		common.SyncHook:      {Webhook: &v1alpha1.Webhook{URL: &syncURL}},
+		common.CustomizeHook: {Webhook: &v1alpha1.Webhook{URL: &syncURL, HealthPath: &healthPath}},
+		common.FinalizeHook:  {Webhook: &v1alpha1.Webhook{URL: &downURL}},
+	})
+
+	if len(unreachable) != 1 || !strings.HasPrefix(unreachable[0], "finalize hook:") {
+		t.Errorf("expected only the finalize hook to be unreachable, got: %v", unreachable)
+	}
+}
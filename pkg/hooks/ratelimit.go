@@ -0,0 +1,71 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/client-go/util/flowcontrol"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+// NewRateLimiter returns a token bucket rate limiter for the given config,
+// or nil if hook invocations aren't limited.
+func NewRateLimiter(rateLimit *v1alpha1.HookRateLimit) (flowcontrol.RateLimiter, error) {
+	if rateLimit == nil {
+		return nil, nil
+	}
+	if rateLimit.QPS <= 0 {
+		return nil, fmt.Errorf("invalid rate limit config: 'qps' must be positive")
+	}
+	burst := rateLimit.QPS
+	if rateLimit.Burst != nil {
+		if *rateLimit.Burst <= 0 {
+			return nil, fmt.Errorf("invalid rate limit config: 'burst' must be positive")
+		}
+		burst = *rateLimit.Burst
+	}
+	return flowcontrol.NewTokenBucketRateLimiter(float32(rateLimit.QPS), int(burst)), nil
+}
+
+// WithRateLimiter returns a HookExecutor which waits for the limiter before
+// each invocation of executor. The limiter can be shared by all hooks of a
+// controller. If the limiter is nil, executor is returned as-is.
+func WithRateLimiter(executor HookExecutor, limiter flowcontrol.RateLimiter) HookExecutor {
+	if executor == nil || limiter == nil {
+		return executor
+	}
+	return &rateLimitedExecutor{executor: executor, limiter: limiter}
+}
+
+type rateLimitedExecutor struct {
+	executor HookExecutor
+	limiter  flowcontrol.RateLimiter
+}
+
+func (r *rateLimitedExecutor) IsEnabled() bool {
+	return r.executor.IsEnabled()
+}
+
+func (r *rateLimitedExecutor) Execute(ctx context.Context, request interface{}, response interface{}) error {
+	if err := r.limiter.Wait(ctx); err != nil {
+		return fmt.Errorf("rate limit error: %w", err)
+	}
+	return r.executor.Execute(ctx, request, response)
+}
@@ -0,0 +1,66 @@
+package hooks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+type countingExecutor struct {
+	calls int
+}
+
+func (c *countingExecutor) IsEnabled() bool {
+	return true
+}
+
+func (c *countingExecutor) Execute(ctx context.Context, request interface{}, response interface{}) error {
+	c.calls++
+	return nil
+}
+
+func TestNewRateLimiter_disabled(t *testing.T) {
+	limiter, err := NewRateLimiter(nil)
+
+	if err != nil {
+		t.Errorf("err should be nil, got: %v", err)
+	}
+	if limiter != nil {
+		t.Errorf("expected nil limiter, got: %v", limiter)
+	}
+}
+
+func TestNewRateLimiter_invalid(t *testing.T) {
+	burst := int32(0)
+
+	_, err := NewRateLimiter(&v1alpha1.HookRateLimit{QPS: 1, Burst: &burst})
+
+	if err == nil {
+		t.Errorf("expected error for zero burst")
+	}
+}
+
+func TestWithRateLimiter_waitsForToken(t *testing.T) {
+	limiter, err := NewRateLimiter(&v1alpha1.HookRateLimit{QPS: 1})
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	inner := &countingExecutor{}
+	executor := WithRateLimiter(inner, limiter)
+
+	if err := executor.Execute(context.Background(), nil, nil); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	// The bucket is empty now, so the next call can't get a token before
+	// the context expires.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := executor.Execute(ctx, nil, nil); err == nil {
+		t.Errorf("expected rate limit error")
+	}
+	if inner.calls != 1 {
+		t.Errorf("expected 1 call, got: %d", inner.calls)
+	}
+}
@@ -0,0 +1,268 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	k8sjson "k8s.io/apimachinery/pkg/util/json"
+)
+
+// DebugAnnotation on a parent object enables recording of the hook calls made
+// for it. The value is the number of calls to record, up to
+// maxDebugRecordings, or "true" for defaultDebugRecordings. Changing the value
+// starts a new recording.
+const DebugAnnotation = "metacontroller.k8s.io/debug"
+
+const (
+	defaultDebugRecordings = 10
+	// maxDebugRecordings bounds the recordings of a parent, whatever the
+	// annotation asks for.
+	maxDebugRecordings = 50
+	// maxDebugParents bounds memory use if many objects are annotated.
+	maxDebugParents = 100
+)
+
+// DebugRecorder holds the hook calls recorded for annotated parents. It's
+// served as JSON on the /debug/hooks path of the metrics endpoint.
+var DebugRecorder = NewRecorder()
+
+// HookRecording is a single recorded hook call.
+type HookRecording struct {
+	Time       time.Time       `json:"time"`
+	Controller string          `json:"controller"`
+	Hook       string          `json:"hook"`
+	Request    json.RawMessage `json:"request"`
+	Response   json.RawMessage `json:"response,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// ParentRecordings are the hook calls recorded for one parent object.
+type ParentRecordings struct {
+	APIVersion string          `json:"apiVersion"`
+	Kind       string          `json:"kind"`
+	Namespace  string          `json:"namespace,omitempty"`
+	Name       string          `json:"name"`
+	Recordings []HookRecording `json:"recordings"`
+
+	// session is the annotation value the recordings were made for, or empty
+	// once the annotation was removed.
+	session string
+	limit   int
+	updated time.Time
+}
+
+// Recorder records hook calls for parents having the DebugAnnotation.
+type Recorder struct {
+	mutex   sync.Mutex
+	parents map[types.UID]*ParentRecordings
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{parents: make(map[types.UID]*ParentRecordings)}
+}
+
+type parentContextKey struct{}
+
+// WithParent returns a copy of ctx carrying the parent object hooks are called
+// for, so their calls can be recorded.
+func WithParent(ctx context.Context, parent *unstructured.Unstructured) context.Context {
+	return context.WithValue(ctx, parentContextKey{}, parent)
+}
+
+func parentFromContext(ctx context.Context) *unstructured.Unstructured {
+	parent, _ := ctx.Value(parentContextKey{}).(*unstructured.Unstructured)
+	return parent
+}
+
+// Record stores a hook call if parent asks for it with the DebugAnnotation.
+func (r *Recorder) Record(parent *unstructured.Unstructured, controllerName, hookType string, request, response interface{}, callErr error) {
+	if parent == nil {
+		return
+	}
+	value, annotated := parent.GetAnnotations()[DebugAnnotation]
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	recordings, exists := r.parents[parent.GetUID()]
+	if !annotated {
+		if exists {
+			// Keep the recordings around, but start a new session if the
+			// annotation is added again.
+			recordings.session = ""
+		}
+		return
+	}
+	limit := debugRecordingLimit(value)
+	if limit <= 0 {
+		return
+	}
+	if !exists || recordings.session != value {
+		if !exists {
+			r.evictOldest()
+		}
+		recordings = &ParentRecordings{
+			APIVersion: parent.GetAPIVersion(),
+			Kind:       parent.GetKind(),
+			Namespace:  parent.GetNamespace(),
+			Name:       parent.GetName(),
+			session:    value,
+			limit:      limit,
+		}
+		r.parents[parent.GetUID()] = recordings
+	}
+	if len(recordings.Recordings) >= recordings.limit {
+		return
+	}
+
+	recording := HookRecording{
+		Time:       time.Now(),
+		Controller: controllerName,
+		Hook:       hookType,
+	}
+	if body, err := redactedJSON(request); err == nil {
+		recording.Request = body
+	}
+	if callErr != nil {
+		recording.Error = callErr.Error()
+	} else if body, err := redactedJSON(response); err == nil {
+		recording.Response = body
+	}
+	recordings.Recordings = append(recordings.Recordings, recording)
+	recordings.updated = recording.Time
+}
+
+func debugRecordingLimit(value string) int {
+	if value == "true" {
+		return defaultDebugRecordings
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil {
+		return 0
+	}
+	if limit > maxDebugRecordings {
+		return maxDebugRecordings
+	}
+	return limit
+}
+
+// redactedValue replaces the values of the data of Secrets in recordings.
+const redactedValue = "REDACTED"
+
+// redactedJSON returns body as JSON, with the values of the data of the
+// Secrets it contains, e.g. as children or related objects, redacted, so they
+// aren't served on the debug endpoint.
+func redactedJSON(body interface{}) ([]byte, error) {
+	raw, err := k8sjson.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err := k8sjson.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+	if !redactSecrets(value) {
+		return raw, nil
+	}
+	return k8sjson.Marshal(value)
+}
+
+// redactSecrets redacts the data and stringData of the Secrets in value, and
+// returns whether it found any.
+func redactSecrets(value interface{}) bool {
+	redacted := false
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if value["kind"] == "Secret" && value["apiVersion"] == "v1" {
+			for _, field := range []string{"data", "stringData"} {
+				if data, ok := value[field].(map[string]interface{}); ok {
+					for key := range data {
+						data[key] = redactedValue
+					}
+					redacted = true
+				}
+			}
+		}
+		for _, field := range value {
+			if redactSecrets(field) {
+				redacted = true
+			}
+		}
+	case []interface{}:
+		for _, item := range value {
+			if redactSecrets(item) {
+				redacted = true
+			}
+		}
+	}
+	return redacted
+}
+
+func (r *Recorder) evictOldest() {
+	if len(r.parents) < maxDebugParents {
+		return
+	}
+	var oldestUID types.UID
+	var oldest time.Time
+	for uid, recordings := range r.parents {
+		if oldestUID == "" || recordings.updated.Before(oldest) {
+			oldestUID, oldest = uid, recordings.updated
+		}
+	}
+	delete(r.parents, oldestUID)
+}
+
+// ServeHTTP writes the recordings as JSON. They can be filtered with the
+// "namespace" and "name" query parameters.
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	namespace := req.URL.Query().Get("namespace")
+	name := req.URL.Query().Get("name")
+
+	r.mutex.Lock()
+	result := make([]ParentRecordings, 0, len(r.parents))
+	for _, recordings := range r.parents {
+		if namespace != "" && recordings.Namespace != namespace {
+			continue
+		}
+		if name != "" && recordings.Name != name {
+			continue
+		}
+		copied := *recordings
+		copied.Recordings = append([]HookRecording(nil), recordings.Recordings...)
+		result = append(result, copied)
+	}
+	r.mutex.Unlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Name < result[j].Name
+	})
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,132 @@
+package hooks
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newDebugParent(annotation string) *unstructured.Unstructured {
+	parent := &unstructured.Unstructured{}
+	parent.SetAPIVersion("example.com/v1")
+	parent.SetKind("Foo")
+	parent.SetNamespace("default")
+	parent.SetName("foo")
+	parent.SetUID(types.UID("uid-1"))
+	if annotation != "" {
+		parent.SetAnnotations(map[string]string{DebugAnnotation: annotation})
+	}
+	return parent
+}
+
+func TestRecorder_recordsUpToLimit(t *testing.T) {
+	recorder := NewRecorder()
+	parent := newDebugParent("2")
+
+	recorder.Record(parent, "test", "sync", map[string]string{"n": "1"}, map[string]string{"ok": "1"}, nil)
+	recorder.Record(parent, "test", "sync", map[string]string{"n": "2"}, nil, errors.New("boom"))
+	recorder.Record(parent, "test", "sync", map[string]string{"n": "3"}, nil, nil)
+
+	recordings := recorder.parents[parent.GetUID()].Recordings
+	if len(recordings) != 2 {
+		t.Fatalf("expected 2 recordings, got: %d", len(recordings))
+	}
+	if string(recordings[0].Response) != `{"ok":"1"}` {
+		t.Errorf("unexpected response: %s", recordings[0].Response)
+	}
+	if recordings[1].Error != "boom" || recordings[1].Response != nil {
+		t.Errorf("expected error without response, got: %+v", recordings[1])
+	}
+
+	// Changing the annotation starts a new recording.
+	recorder.Record(newDebugParent("true"), "test", "sync", nil, nil, nil)
+	if len(recorder.parents[parent.GetUID()].Recordings) != 1 {
+		t.Errorf("expected a new recording to start")
+	}
+}
+
+func TestRecorder_limitIsBounded(t *testing.T) {
+	recorder := NewRecorder()
+	parent := newDebugParent("1000000")
+
+	for i := 0; i < maxDebugRecordings+10; i++ {
+		recorder.Record(parent, "test", "sync", nil, nil, nil)
+	}
+	if recordings := recorder.parents[parent.GetUID()].Recordings; len(recordings) != maxDebugRecordings {
+		t.Errorf("expected %d recordings, got: %d", maxDebugRecordings, len(recordings))
+	}
+}
+
+func TestRecorder_redactsSecrets(t *testing.T) {
+	recorder := NewRecorder()
+	parent := newDebugParent("1")
+	secret := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]interface{}{"name": "credentials"},
+		"data":       map[string]interface{}{"password": "aHVudGVyMg=="},
+		"stringData": map[string]interface{}{"token": "hunter2"},
+	}
+	configMap := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"data":       map[string]interface{}{"key": "value"},
+	}
+	request := map[string]interface{}{
+		"parent":   parent.Object,
+		"children": map[string]interface{}{"Secret.v1": map[string]interface{}{"credentials": secret}},
+		"related":  []interface{}{configMap},
+	}
+
+	recorder.Record(parent, "test", "sync", request, map[string]interface{}{"children": []interface{}{secret}}, nil)
+
+	recording := recorder.parents[parent.GetUID()].Recordings[0]
+	for _, body := range []json.RawMessage{recording.Request, recording.Response} {
+		for _, leaked := range []string{"aHVudGVyMg==", "hunter2"} {
+			if strings.Contains(string(body), leaked) {
+				t.Errorf("expected the data of the Secret to be redacted, got: %s", body)
+			}
+		}
+		if !strings.Contains(string(body), `"password":"REDACTED"`) || !strings.Contains(string(body), `"token":"REDACTED"`) {
+			t.Errorf("expected the keys of the Secret to be kept, got: %s", body)
+		}
+	}
+	if !strings.Contains(string(recording.Request), `"key":"value"`) {
+		t.Errorf("expected the data of other objects to be kept, got: %s", recording.Request)
+	}
+	if secret["data"].(map[string]interface{})["password"] != "aHVudGVyMg==" {
+		t.Errorf("expected the recorded objects to be left unchanged")
+	}
+}
+
+func TestRecorder_ignoresParentsWithoutAnnotation(t *testing.T) {
+	recorder := NewRecorder()
+
+	recorder.Record(newDebugParent(""), "test", "sync", nil, nil, nil)
+	recorder.Record(newDebugParent("not-a-number"), "test", "sync", nil, nil, nil)
+
+	if len(recorder.parents) != 0 {
+		t.Errorf("expected no recordings, got: %v", recorder.parents)
+	}
+}
+
+func TestRecorder_ServeHTTP(t *testing.T) {
+	recorder := NewRecorder()
+	recorder.Record(newDebugParent("1"), "test", "sync", map[string]string{}, map[string]string{}, nil)
+
+	w := httptest.NewRecorder()
+	recorder.ServeHTTP(w, httptest.NewRequest("GET", "/debug/hooks?namespace=default&name=foo", nil))
+
+	var result []ParentRecordings
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("can't unmarshal response: %v", err)
+	}
+	if len(result) != 1 || result[0].Name != "foo" || len(result[0].Recordings) != 1 {
+		t.Errorf("unexpected result: %s", w.Body.String())
+	}
+}
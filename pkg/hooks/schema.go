@@ -0,0 +1,451 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+)
+
+// schemaRefreshInterval is how long a response schema read from a ConfigMap
+// is used before it's read again, so it can be changed without a restart.
+const schemaRefreshInterval = time.Minute
+
+// SchemaViolationError is returned when a hook response doesn't conform to
+// the responseSchema of the hook.
+type SchemaViolationError struct {
+	Violations []string
+}
+
+func (e *SchemaViolationError) Error() string {
+	return "response doesn't match responseSchema: " + strings.Join(e.Violations, "; ")
+}
+
+// configMapGetter returns the data stored under key in the given ConfigMap.
+type configMapGetter func(namespace, name, key string) (string, error)
+
+func dynamicConfigMapGetter(dynClient *dynamicclientset.Clientset) configMapGetter {
+	return func(namespace, name, key string) (string, error) {
+		client, err := dynClient.Resource("v1", "configmaps")
+		if err != nil {
+			return "", err
+		}
+		configMap, err := client.Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		data, found, err := unstructured.NestedString(configMap.UnstructuredContent(), "data", key)
+		if err != nil {
+			return "", err
+		}
+		if !found {
+			return "", fmt.Errorf("key %q not found", key)
+		}
+		return data, nil
+	}
+}
+
+// schemaValidatingConverter validates responses against a JSON Schema,
+// before converting them with the wrapped payloadConverter.
+type schemaValidatingConverter struct {
+	payloadConverter
+	source *schemaSource
+}
+
+func (c *schemaValidatingConverter) convertResponse(body []byte) ([]byte, error) {
+	schema, err := c.source.get()
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return nil, fmt.Errorf("can't unmarshal response: %w", err)
+	}
+	var violations []string
+	schema.validate(value, "response", &violations)
+	if len(violations) > 0 {
+		return nil, &SchemaViolationError{Violations: violations}
+	}
+	return c.payloadConverter.convertResponse(body)
+}
+
+// withResponseSchema wraps converter to validate responses against the given
+// schema, if any.
+func withResponseSchema(converter payloadConverter, config *v1alpha1.HookResponseSchema, dynClient *dynamicclientset.Clientset) (payloadConverter, error) {
+	if config == nil {
+		return converter, nil
+	}
+	source, err := newSchemaSource(config, dynClient)
+	if err != nil {
+		return nil, err
+	}
+	return &schemaValidatingConverter{payloadConverter: converter, source: source}, nil
+}
+
+// schemaSource holds a compiled response schema, re-reading it periodically
+// if it comes from a ConfigMap.
+type schemaSource struct {
+	ref          *v1alpha1.ConfigMapKeyReference
+	getConfigMap configMapGetter
+
+	mutex     sync.Mutex
+	schema    *jsonSchema
+	data      string
+	fetchedAt time.Time
+}
+
+func newSchemaSource(config *v1alpha1.HookResponseSchema, dynClient *dynamicclientset.Clientset) (*schemaSource, error) {
+	if (config.Inline == nil) == (config.ConfigMapRef == nil) {
+		return nil, fmt.Errorf("invalid hook responseSchema config: must specify exactly one of 'inline' or 'configMapRef'")
+	}
+	if config.Inline != nil {
+		schema, err := parseJSONSchema(config.Inline.Raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hook responseSchema: %w", err)
+		}
+		return &schemaSource{schema: schema}, nil
+	}
+	ref := config.ConfigMapRef
+	if ref.Name == "" || ref.Namespace == "" || ref.Key == "" {
+		return nil, fmt.Errorf("invalid hook responseSchema config: must specify configMap 'name', 'namespace' and 'key'")
+	}
+	if dynClient == nil {
+		return nil, fmt.Errorf("invalid hook responseSchema config: no client to read configMap %s/%s", ref.Namespace, ref.Name)
+	}
+	return &schemaSource{
+		ref:          ref,
+		getConfigMap: dynamicConfigMapGetter(dynClient),
+	}, nil
+}
+
+func (s *schemaSource) get() (*jsonSchema, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.ref == nil || (s.schema != nil && time.Since(s.fetchedAt) < schemaRefreshInterval) {
+		return s.schema, nil
+	}
+	data, err := s.getConfigMap(s.ref.Namespace, s.ref.Name, s.ref.Key)
+	if err != nil {
+		return nil, fmt.Errorf("can't read responseSchema from configMap %s/%s: %w", s.ref.Namespace, s.ref.Name, err)
+	}
+	if s.schema == nil || data != s.data {
+		schema, err := parseJSONSchema([]byte(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid responseSchema in configMap %s/%s: %w", s.ref.Namespace, s.ref.Name, err)
+		}
+		s.schema = schema
+		s.data = data
+	}
+	s.fetchedAt = time.Now()
+	return s.schema, nil
+}
+
+// jsonSchema is a compiled JSON Schema. Only the subset of keywords that is
+// also supported by CustomResourceDefinition schemas is implemented.
+type jsonSchema struct {
+	types                []string
+	nullable             bool
+	enum                 []interface{}
+	properties           map[string]*jsonSchema
+	required             []string
+	additionalProperties *jsonSchema
+	noAdditional         bool
+	items                *jsonSchema
+	minItems, maxItems   *int
+	minLength, maxLength *int
+	minimum, maximum     *float64
+	pattern              *regexp.Regexp
+}
+
+// ignoredSchemaKeywords are accepted in schemas, but don't affect validation.
+var ignoredSchemaKeywords = map[string]bool{
+	"$schema":     true,
+	"$id":         true,
+	"title":       true,
+	"description": true,
+	"default":     true,
+	"example":     true,
+	"examples":    true,
+	"format":      true,
+}
+
+func parseJSONSchema(data []byte) (*jsonSchema, error) {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	return compileJSONSchema(raw, "schema")
+}
+
+func compileJSONSchema(raw interface{}, path string) (*jsonSchema, error) {
+	fields, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: must be an object", path)
+	}
+	schema := &jsonSchema{}
+	for keyword, value := range fields {
+		var err error
+		switch keyword {
+		case "type":
+			schema.types, err = schemaTypes(value)
+		case "nullable":
+			schema.nullable, ok = value.(bool)
+			if !ok {
+				err = fmt.Errorf("must be a boolean")
+			}
+		case "enum":
+			schema.enum, ok = value.([]interface{})
+			if !ok {
+				err = fmt.Errorf("must be an array")
+			}
+		case "properties":
+			properties, ok := value.(map[string]interface{})
+			if !ok {
+				err = fmt.Errorf("must be an object")
+				break
+			}
+			schema.properties = make(map[string]*jsonSchema, len(properties))
+			for name, property := range properties {
+				if schema.properties[name], err = compileJSONSchema(property, path+".properties."+name); err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			schema.required, err = stringList(value)
+		case "additionalProperties":
+			if allowed, ok := value.(bool); ok {
+				schema.noAdditional = !allowed
+			} else if schema.additionalProperties, err = compileJSONSchema(value, path+".additionalProperties"); err != nil {
+				return nil, err
+			}
+		case "items":
+			if schema.items, err = compileJSONSchema(value, path+".items"); err != nil {
+				return nil, err
+			}
+		case "minItems":
+			schema.minItems, err = schemaInt(value)
+		case "maxItems":
+			schema.maxItems, err = schemaInt(value)
+		case "minLength":
+			schema.minLength, err = schemaInt(value)
+		case "maxLength":
+			schema.maxLength, err = schemaInt(value)
+		case "minimum":
+			schema.minimum, err = schemaNumber(value)
+		case "maximum":
+			schema.maximum, err = schemaNumber(value)
+		case "pattern":
+			pattern, ok := value.(string)
+			if !ok {
+				err = fmt.Errorf("must be a string")
+				break
+			}
+			schema.pattern, err = regexp.Compile(pattern)
+		default:
+			if !ignoredSchemaKeywords[keyword] && !strings.HasPrefix(keyword, "x-") {
+				err = fmt.Errorf("unsupported keyword")
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", path, keyword, err)
+		}
+	}
+	return schema, nil
+}
+
+func schemaTypes(value interface{}) ([]string, error) {
+	var types []string
+	if single, ok := value.(string); ok {
+		types = []string{single}
+	} else {
+		var err error
+		if types, err = stringList(value); err != nil {
+			return nil, err
+		}
+	}
+	for _, t := range types {
+		switch t {
+		case "object", "array", "string", "number", "integer", "boolean", "null":
+		default:
+			return nil, fmt.Errorf("unknown type %q", t)
+		}
+	}
+	return types, nil
+}
+
+func stringList(value interface{}) ([]string, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("must be an array of strings")
+	}
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be an array of strings")
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+func schemaInt(value interface{}) (*int, error) {
+	n, ok := value.(float64)
+	if !ok || n < 0 || n != math.Trunc(n) {
+		return nil, fmt.Errorf("must be a non-negative integer")
+	}
+	i := int(n)
+	return &i, nil
+}
+
+func schemaNumber(value interface{}) (*float64, error) {
+	n, ok := value.(float64)
+	if !ok {
+		return nil, fmt.Errorf("must be a number")
+	}
+	return &n, nil
+}
+
+// validate appends a description of each way value doesn't conform to the
+// schema to violations, using path to locate the offending values.
+func (s *jsonSchema) validate(value interface{}, path string, violations *[]string) {
+	violate := func(format string, args ...interface{}) {
+		*violations = append(*violations, path+": "+fmt.Sprintf(format, args...))
+	}
+	if value == nil && s.nullable {
+		return
+	}
+	if len(s.types) > 0 && !matchesAnyType(value, s.types) {
+		violate("must be of type %s, got %s", strings.Join(s.types, " or "), jsonType(value))
+		return
+	}
+	if s.enum != nil && !inEnum(value, s.enum) {
+		violate("must be one of the values in enum")
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.required {
+			if _, ok := v[name]; !ok {
+				violate("missing required property %q", name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := s.properties[name]; ok {
+				property.validate(v[name], path+"."+name, violations)
+			} else if s.additionalProperties != nil {
+				s.additionalProperties.validate(v[name], path+"."+name, violations)
+			} else if s.noAdditional {
+				violate("unknown property %q", name)
+			}
+		}
+	case []interface{}:
+		if s.minItems != nil && len(v) < *s.minItems {
+			violate("must have at least %d items", *s.minItems)
+		}
+		if s.maxItems != nil && len(v) > *s.maxItems {
+			violate("must have at most %d items", *s.maxItems)
+		}
+		if s.items != nil {
+			for i, item := range v {
+				s.items.validate(item, fmt.Sprintf("%s[%d]", path, i), violations)
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.minLength != nil && length < *s.minLength {
+			violate("must be at least %d characters long", *s.minLength)
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			violate("must be at most %d characters long", *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			violate("must match pattern %q", s.pattern.String())
+		}
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			violate("must be greater than or equal to %v", *s.minimum)
+		}
+		if s.maximum != nil && v > *s.maximum {
+			violate("must be less than or equal to %v", *s.maximum)
+		}
+	}
+}
+
+func matchesAnyType(value interface{}, types []string) bool {
+	actual := jsonType(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType returns the JSON Schema type of a decoded JSON value, using
+// "integer" for numbers without a fractional part.
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func inEnum(value interface{}, enum []interface{}) bool {
+	for _, allowed := range enum {
+		if reflect.DeepEqual(value, allowed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package hooks
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+const testResponseSchema = `{
+	"type": "object",
+	"required": ["children"],
+	"properties": {
+		"status": {
+			"type": "object",
+			"properties": {
+				"replicas": {"type": "integer", "minimum": 0},
+				"phase": {"type": "string", "enum": ["Pending", "Ready"]}
+			}
+		},
+		"children": {
+			"type": "array",
+			"maxItems": 2,
+			"items": {"type": "object", "required": ["kind"]}
+		},
+		"resyncAfterSeconds": {"type": "number"}
+	},
+	"additionalProperties": false
+}`
+
+func TestJSONSchema_validate(t *testing.T) {
+	schema, err := parseJSONSchema([]byte(testResponseSchema))
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	tests := []struct {
+		name     string
+		response string
+		expected []string
+	}{
+		{
+			name:     "valid",
+			response: `{"status": {"replicas": 2, "phase": "Ready"}, "children": [{"kind": "Pod"}], "resyncAfterSeconds": 1.5}`,
+		},
+		{
+			name:     "invalid",
+			response: `{"status": {"replicas": 1.5, "phase": "Failed"}, "children": [{}, {"kind": "Pod"}, {"kind": "Pod"}], "extra": true}`,
+			expected: []string{
+				`response.children: must have at most 2 items`,
+				`response.children[0]: missing required property "kind"`,
+				`response: unknown property "extra"`,
+				`response.status.phase: must be one of the values in enum`,
+				`response.status.replicas: must be of type integer, got number`,
+			},
+		},
+		{
+			name:     "missing required",
+			response: `{"status": null}`,
+			expected: []string{
+				`response: missing required property "children"`,
+				`response.status: must be of type object, got null`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter := &schemaValidatingConverter{payloadConverter: v1Converter{}, source: &schemaSource{schema: schema}}
+
+			_, err := converter.convertResponse([]byte(tt.response))
+
+			var violation *SchemaViolationError
+			if tt.expected == nil {
+				if err != nil {
+					t.Errorf("err should be nil, got: %v", err)
+				}
+				return
+			}
+			if !errors.As(err, &violation) {
+				t.Fatalf("expected SchemaViolationError, got: %v", err)
+			}
+			if diff := cmp.Diff(tt.expected, violation.Violations); diff != "" {
+				t.Errorf("unexpected violations (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseJSONSchema_whenUnsupportedKeyword_returnError(t *testing.T) {
+	_, err := parseJSONSchema([]byte(`{"type": "object", "properties": {"a": {"oneOf": []}}}`))
+
+	if err == nil || err.Error() != "schema.properties.a.oneOf: unsupported keyword" {
+		t.Errorf("expected unsupported keyword error, got: %v", err)
+	}
+}
+
+func TestNewSchemaSource_whenBothInlineAndConfigMap_returnError(t *testing.T) {
+	_, err := newSchemaSource(&v1alpha1.HookResponseSchema{
+		Inline:       &runtime.RawExtension{Raw: []byte(`{}`)},
+		ConfigMapRef: &v1alpha1.ConfigMapKeyReference{Name: "schema", Namespace: "default", Key: "schema.json"},
+	}, nil)
+
+	if err == nil {
+		t.Errorf("expected error when both inline and configMapRef are set")
+	}
+}
+
+func TestSchemaSource_get_fromConfigMap(t *testing.T) {
+	calls := 0
+	source := &schemaSource{
+		ref: &v1alpha1.ConfigMapKeyReference{Name: "schema", Namespace: "default", Key: "schema.json"},
+		getConfigMap: func(namespace, name, key string) (string, error) {
+			calls++
+			return `{"type": "object"}`, nil
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		schema, err := source.get()
+		if err != nil {
+			t.Fatalf("err should be nil, got: %v", err)
+		}
+		if diff := cmp.Diff([]string{"object"}, schema.types); diff != "" {
+			t.Errorf("unexpected schema types (-want +got):\n%s", diff)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected schema to be cached, got %d reads", calls)
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	controllerruntimemetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"metacontroller/pkg/controller/common"
+)
+
+// maxShadowCallsInFlight is the number of concurrent calls to the shadow of
+// a webhook, above which calls to the shadow are skipped.
+const maxShadowCallsInFlight = 10
+
+// Results of the comparison of a shadow response with the primary one.
+const (
+	shadowMatch   = "match"
+	shadowDiff    = "diff"
+	shadowError   = "error"
+	shadowSkipped = "skipped"
+)
+
+var shadowResults = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "metacontroller_hook_shadow_results_total",
+		Help: "Number of shadow webhook calls, by result of the comparison with the primary response (match, diff, error or skipped).",
+	},
+	[]string{"controller_name", "controller_type", "hook", "result"},
+)
+
+func init() {
+	controllerruntimemetrics.Registry.MustRegister(shadowResults)
+}
+
+// shadowWebhook receives a copy of the requests sent to a webhook, and its
+// responses are compared with the primary ones, without being applied.
+type shadowWebhook struct {
+	client  *http.Client
+	url     string
+	timeout time.Duration
+
+	inFlight chan struct{}
+
+	controllerName string
+	controllerType string
+	hookType       string
+}
+
+func newShadowWebhook(
+	shadowURL *string,
+	config transportConfig,
+	timeout time.Duration,
+	controllerName string,
+	controllerType common.ControllerType,
+	hookType common.HookType) (*shadowWebhook, error) {
+	if shadowURL == nil {
+		return nil, nil
+	}
+	transport, err := Endpoints.transport(*shadowURL, config)
+	if err != nil {
+		return nil, err
+	}
+	return &shadowWebhook{
+		client:         &http.Client{Timeout: timeout, Transport: transport},
+		url:            *shadowURL,
+		timeout:        timeout,
+		inFlight:       make(chan struct{}, maxShadowCallsInFlight),
+		controllerName: controllerName,
+		controllerType: controllerType.String(),
+		hookType:       hookType.String(),
+	}, nil
+}
+
+// compare sends the encoded request to the shadow in the background, and
+// reports whether its response matches the primary one with logger.
+func (s *shadowWebhook) compare(logger logr.Logger, w *WebhookExecutor, reqBody []byte, signature string, primaryBody []byte) {
+	select {
+	case s.inFlight <- struct{}{}:
+	default:
+		s.record(shadowSkipped)
+		return
+	}
+	go func() {
+		defer func() { <-s.inFlight }()
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		defer cancel()
+
+		diff, err := s.call(ctx, w, reqBody, signature, primaryBody)
+		switch {
+		case err != nil:
+			s.record(shadowError)
+			logger.V(4).Info("Shadow webhook call failed", "type", s.hookType, "url", s.url, "error", err.Error())
+		case diff != "":
+			s.record(shadowDiff)
+			logger.Info("Shadow webhook response differs from primary",
+				"controller", s.controllerName, "type", s.hookType, "url", s.url, "diff", diff)
+		default:
+			s.record(shadowMatch)
+		}
+	}()
+}
+
+// call returns the differences between the shadow and primary responses, or
+// an empty string if they match.
+func (s *shadowWebhook) call(ctx context.Context, w *WebhookExecutor, reqBody []byte, signature string, primaryBody []byte) (string, error) {
+	resp, err := w.post(ctx, s.client, s.url, reqBody, signature)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	respBody, err := readResponseBody(resp, w.maxResponseSize)
+	if err != nil {
+		return "", fmt.Errorf("can't read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("remote error: %s", respBody)
+	}
+	respBody, err = w.converter.convertResponse(respBody)
+	if err != nil {
+		return "", err
+	}
+	var primary, shadow map[string]interface{}
+	if err := json.Unmarshal(primaryBody, &primary); err != nil {
+		return "", fmt.Errorf("can't unmarshal primary response: %w", err)
+	}
+	if err := json.Unmarshal(respBody, &shadow); err != nil {
+		return "", fmt.Errorf("can't unmarshal response: %w", err)
+	}
+	// Sync tokens are opaque to Metacontroller, and are expected to differ.
+	delete(primary, "syncToken")
+	delete(shadow, "syncToken")
+	return cmp.Diff(primary, shadow), nil
+}
+
+func (s *shadowWebhook) record(result string) {
+	shadowResults.WithLabelValues(s.controllerName, s.controllerType, s.hookType, result).Inc()
+}
@@ -0,0 +1,79 @@
+package hooks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+)
+
+func TestWebhookExecutor_shadow(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"value":"ok","syncToken":"1"}`))
+	}))
+	defer primary.Close()
+	shadowRequests := make(chan struct{}, 1)
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"value":"changed","syncToken":"2"}`))
+		shadowRequests <- struct{}{}
+	}))
+	defer shadow.Close()
+
+	executor, err := NewWebhookExecutor(&v1alpha1.Webhook{
+		URL:       &primary.URL,
+		ShadowURL: &shadow.URL,
+	}, "shadow", common.CompositeController, common.SyncHook, nil, logr.Discard())
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	var response struct {
+		Value string `json:"value"`
+	}
+	if err := executor.Execute(context.Background(), map[string]string{"parent": "foo"}, &response); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	if response.Value != "ok" {
+		t.Errorf("expected the primary response to be applied, got: %q", response.Value)
+	}
+	<-shadowRequests
+	diffs := shadowResults.WithLabelValues("shadow", common.CompositeController.String(), common.SyncHook.String(), shadowDiff)
+	deadline := time.Now().Add(5 * time.Second)
+	for testutil.ToFloat64(diffs) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 1 shadow diff, got: %v", testutil.ToFloat64(diffs))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestShadowWebhook_call_ignoresSyncToken(t *testing.T) {
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"value":"ok","syncToken":"2"}`))
+	}))
+	defer shadow.Close()
+	executor, err := NewWebhookExecutor(&v1alpha1.Webhook{
+		URL:       &shadow.URL,
+		ShadowURL: &shadow.URL,
+	}, "shadow-match", common.CompositeController, common.SyncHook, nil, logr.Discard())
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	diff, err := executor.shadow.call(context.Background(), executor, []byte(`{}`), "", []byte(`{"value":"ok","syncToken":"1"}`))
+
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if diff != "" {
+		t.Errorf("expected no diff, got: %s", diff)
+	}
+}
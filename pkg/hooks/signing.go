@@ -0,0 +1,123 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+)
+
+// SignatureHeader carries the HMAC-SHA256 signature of the request body,
+// in the form "sha256=<hex>".
+const SignatureHeader = "X-Metacontroller-Signature"
+
+// signingKeyRefreshInterval is how long a signing key read from a Secret is
+// used before it's read again, so key rotation doesn't need a restart.
+const signingKeyRefreshInterval = time.Minute
+
+// secretGetter returns the data stored under key in the given Secret.
+type secretGetter func(namespace, name, key string) ([]byte, error)
+
+// requestSigner signs hook request bodies with a shared secret.
+type requestSigner struct {
+	ref       v1alpha1.SecretKeyReference
+	getSecret secretGetter
+
+	mutex     sync.Mutex
+	key       []byte
+	fetchedAt time.Time
+}
+
+func newRequestSigner(signing *v1alpha1.WebhookSigning, dynClient *dynamicclientset.Clientset) (*requestSigner, error) {
+	if signing == nil {
+		return nil, nil
+	}
+	ref := signing.SecretRef
+	if ref.Name == "" || ref.Namespace == "" || ref.Key == "" {
+		return nil, fmt.Errorf("invalid webhook signing config: must specify secret 'name', 'namespace' and 'key'")
+	}
+	if dynClient == nil {
+		return nil, fmt.Errorf("invalid webhook signing config: no client to read secret %s/%s", ref.Namespace, ref.Name)
+	}
+	return &requestSigner{
+		ref:       ref,
+		getSecret: dynamicSecretGetter(dynClient),
+	}, nil
+}
+
+func dynamicSecretGetter(dynClient *dynamicclientset.Clientset) secretGetter {
+	return func(namespace, name, key string) ([]byte, error) {
+		client, err := dynClient.Resource("v1", "secrets")
+		if err != nil {
+			return nil, err
+		}
+		secret, err := client.Namespace(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		encoded, found, err := unstructured.NestedString(secret.UnstructuredContent(), "data", key)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, fmt.Errorf("key %q not found", key)
+		}
+		return base64.StdEncoding.DecodeString(encoded)
+	}
+}
+
+// sign returns the signature header value for body.
+func (s *requestSigner) sign(body []byte) (string, error) {
+	key, err := s.signingKey()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (s *requestSigner) signingKey() ([]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.key != nil && time.Since(s.fetchedAt) < signingKeyRefreshInterval {
+		return s.key, nil
+	}
+	key, err := s.getSecret(s.ref.Namespace, s.ref.Name, s.ref.Key)
+	if err != nil {
+		return nil, fmt.Errorf("can't read signing key from secret %s/%s: %w", s.ref.Namespace, s.ref.Name, err)
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("signing key %q in secret %s/%s is empty", s.ref.Key, s.ref.Namespace, s.ref.Name)
+	}
+	s.key = key
+	s.fetchedAt = time.Now()
+	return key, nil
+}
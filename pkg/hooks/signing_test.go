@@ -0,0 +1,73 @@
+package hooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+func TestNewRequestSigner_whenNilSigning_returnNil(t *testing.T) {
+	signer, err := newRequestSigner(nil, nil)
+
+	if err != nil {
+		t.Errorf("err should be nil, got: %v", err)
+	}
+	if signer != nil {
+		t.Errorf("signer should be nil")
+	}
+}
+
+func TestNewRequestSigner_whenIncompleteSecretRef_returnError(t *testing.T) {
+	_, err := newRequestSigner(&v1alpha1.WebhookSigning{
+		SecretRef: v1alpha1.SecretKeyReference{Name: "hook-secret"},
+	}, nil)
+
+	if err == nil {
+		t.Errorf("expected error for incomplete secretRef")
+	}
+}
+
+func TestRequestSigner_sign(t *testing.T) {
+	calls := 0
+	signer := &requestSigner{
+		ref: v1alpha1.SecretKeyReference{Name: "hook-secret", Namespace: "default", Key: "key"},
+		getSecret: func(namespace, name, key string) ([]byte, error) {
+			calls++
+			return []byte("s3cr3t"), nil
+		},
+	}
+	body := []byte(`{"parent":{}}`)
+	mac := hmac.New(sha256.New, []byte("s3cr3t"))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	for i := 0; i < 2; i++ {
+		signature, err := signer.sign(body)
+		if err != nil {
+			t.Fatalf("err should be nil, got: %v", err)
+		}
+		if signature != expected {
+			t.Errorf("expected %s, got: %s", expected, signature)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected signing key to be cached, got %d reads", calls)
+	}
+}
+
+func TestRequestSigner_sign_whenSecretUnavailable_returnError(t *testing.T) {
+	signer := &requestSigner{
+		ref: v1alpha1.SecretKeyReference{Name: "hook-secret", Namespace: "default", Key: "key"},
+		getSecret: func(namespace, name, key string) ([]byte, error) {
+			return nil, fmt.Errorf("not found")
+		},
+	}
+
+	if _, err := signer.sign([]byte("{}")); err == nil {
+		t.Errorf("expected error when secret is unavailable")
+	}
+}
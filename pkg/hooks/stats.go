@@ -0,0 +1,44 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import "context"
+
+// callStats collects what the executor of a hook call learns about it, for
+// the metrics of hook calls.
+type callStats struct {
+	requestBytes  int
+	responseBytes int
+	statusCode    int
+}
+
+type callStatsKey struct{}
+
+// withCallStats returns a context in which executors record their stats into
+// stats.
+func withCallStats(ctx context.Context, stats *callStats) context.Context {
+	return context.WithValue(ctx, callStatsKey{}, stats)
+}
+
+// callStatsFrom returns the stats recorded for the call of ctx, or stats
+// which aren't reported if the call isn't instrumented.
+func callStatsFrom(ctx context.Context) *callStats {
+	if stats, ok := ctx.Value(callStatsKey{}).(*callStats); ok {
+		return stats
+	}
+	return &callStats{}
+}
@@ -0,0 +1,47 @@
+package hooks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+)
+
+func TestWebhookExecutor_recordsCallStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte("overloaded"))
+	}))
+	defer server.Close()
+
+	executor, err := NewWebhookExecutor(&v1alpha1.Webhook{URL: &server.URL}, "stats", common.CompositeController, common.SyncHook, nil, logr.Discard())
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	stats := &callStats{}
+	var response struct{}
+	err = executor.Execute(withCallStats(context.Background(), stats), map[string]string{"parent": "foo"}, &response)
+
+	if err == nil {
+		t.Errorf("expected an error for a 503 response")
+	}
+	expected := callStats{requestBytes: len(`{"parent":"foo"}`), responseBytes: len("overloaded"), statusCode: http.StatusServiceUnavailable}
+	if *stats != expected {
+		t.Errorf("expected %+v, got: %+v", expected, *stats)
+	}
+}
+
+func TestCallStatsFrom_withoutStats(t *testing.T) {
+	stats := callStatsFrom(context.Background())
+	stats.requestBytes = 1
+
+	if callStatsFrom(context.Background()).requestBytes != 0 {
+		t.Errorf("expected stats not to be shared by calls which aren't instrumented")
+	}
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+// ProxyDirect is the webhook proxy value which disables the proxy given in
+// the environment.
+const ProxyDirect = "direct"
+
+// defaultTransportConfig matches the settings of http.DefaultTransport.
+var defaultTransportConfig = transportConfig{
+	dialTimeout:         30 * time.Second,
+	tlsHandshakeTimeout: 10 * time.Second,
+	keepAlive:           30 * time.Second,
+	idleConnTimeout:     90 * time.Second,
+	maxIdleConnsPerHost: http.DefaultMaxIdleConnsPerHost,
+}
+
+// transportConfig holds the settings of the connections to a webhook
+// endpoint. Webhooks of the same endpoint with the same settings share their
+// connections.
+type transportConfig struct {
+	dialTimeout         time.Duration
+	tlsHandshakeTimeout time.Duration
+	keepAlive           time.Duration
+	idleConnTimeout     time.Duration
+	maxIdleConnsPerHost int
+	disableKeepAlives   bool
+	// proxy is the URL of the proxy, ProxyDirect, or empty to use the
+	// proxy given in the environment.
+	proxy string
+	// caBundle holds the PEM-encoded CA certificates used instead of the
+	// system ones, if not empty.
+	caBundle string
+}
+
+// newTransportConfig returns the connection settings of webhook, given the
+// hookTransport of its controller.
+func newTransportConfig(webhook *v1alpha1.Webhook, hookTransport *v1alpha1.HookTransport) (transportConfig, error) {
+	config := defaultTransportConfig
+	if err := setDuration(&config.dialTimeout, webhook.ConnectTimeout, "webhook connectTimeout"); err != nil {
+		return config, err
+	}
+	if err := setDuration(&config.tlsHandshakeTimeout, webhook.TLSHandshakeTimeout, "webhook tlsHandshakeTimeout"); err != nil {
+		return config, err
+	}
+	if webhook.Proxy != nil && *webhook.Proxy != ProxyDirect {
+		proxyURL, err := url.Parse(*webhook.Proxy)
+		if err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return config, fmt.Errorf("invalid webhook proxy %q: must be a URL or %q", *webhook.Proxy, ProxyDirect)
+		}
+	}
+	if webhook.Proxy != nil {
+		config.proxy = *webhook.Proxy
+	}
+	if len(webhook.CABundle) > 0 {
+		if !x509.NewCertPool().AppendCertsFromPEM(webhook.CABundle) {
+			return config, fmt.Errorf("invalid webhook caBundle: no PEM-encoded certificates found")
+		}
+		config.caBundle = string(webhook.CABundle)
+	}
+	if hookTransport == nil {
+		return config, nil
+	}
+	if err := setDuration(&config.keepAlive, hookTransport.KeepAlive, "hookTransport keepAlive"); err != nil {
+		return config, err
+	}
+	if err := setDuration(&config.idleConnTimeout, hookTransport.IdleConnTimeout, "hookTransport idleConnTimeout"); err != nil {
+		return config, err
+	}
+	if hookTransport.MaxIdleConnsPerHost != nil {
+		if *hookTransport.MaxIdleConnsPerHost < 0 {
+			return config, fmt.Errorf("invalid hookTransport config: maxIdleConnsPerHost must not be negative")
+		}
+		config.maxIdleConnsPerHost = int(*hookTransport.MaxIdleConnsPerHost)
+	}
+	if hookTransport.DisableKeepAlives != nil {
+		config.disableKeepAlives = *hookTransport.DisableKeepAlives
+	}
+	return config, nil
+}
+
+func setDuration(target *time.Duration, value *metav1.Duration, field string) error {
+	if value == nil {
+		return nil
+	}
+	if value.Duration <= 0 {
+		return fmt.Errorf("invalid %s: must be a positive duration", field)
+	}
+	*target = value.Duration
+	return nil
+}
+
+// newTransport returns a transport with the given settings, which counts its
+// open connections in e.
+func (c transportConfig) newTransport(e *endpoint) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{Timeout: c.dialTimeout, KeepAlive: c.keepAlive}
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		atomic.AddInt64(&e.openConns, 1)
+		return &countedConn{Conn: conn, endpoint: e}, nil
+	}
+	transport.TLSHandshakeTimeout = c.tlsHandshakeTimeout
+	transport.IdleConnTimeout = c.idleConnTimeout
+	transport.MaxIdleConnsPerHost = c.maxIdleConnsPerHost
+	transport.DisableKeepAlives = c.disableKeepAlives
+	c.configureProxyAndTLS(transport)
+	return transport
+}
+
+// configureProxyAndTLS sets the proxy and CA certificates of transport. The
+// settings were validated by newTransportConfig.
+func (c transportConfig) configureProxyAndTLS(transport *http.Transport) {
+	switch c.proxy {
+	case "":
+		// Keep http.ProxyFromEnvironment.
+	case ProxyDirect:
+		transport.Proxy = nil
+	default:
+		proxyURL, _ := url.Parse(c.proxy)
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	if c.caBundle != "" {
+		roots := x509.NewCertPool()
+		roots.AppendCertsFromPEM([]byte(c.caBundle))
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+	}
+}
@@ -0,0 +1,125 @@
+package hooks
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+func TestNewTransportConfig(t *testing.T) {
+	config, err := newTransportConfig(&v1alpha1.Webhook{
+		ConnectTimeout: &metav1.Duration{Duration: time.Second},
+	}, &v1alpha1.HookTransport{
+		MaxIdleConnsPerHost: pointer.Int32Ptr(50),
+		KeepAlive:           &metav1.Duration{Duration: time.Minute},
+	})
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	expected := defaultTransportConfig
+	expected.dialTimeout = time.Second
+	expected.maxIdleConnsPerHost = 50
+	expected.keepAlive = time.Minute
+	if config != expected {
+		t.Errorf("expected %+v, got: %+v", expected, config)
+	}
+}
+
+func TestNewTransportConfig_whenInvalidTimeout_returnError(t *testing.T) {
+	_, err := newTransportConfig(&v1alpha1.Webhook{
+		TLSHandshakeTimeout: &metav1.Duration{Duration: -time.Second},
+	}, nil)
+
+	if err == nil {
+		t.Errorf("expected error for negative tlsHandshakeTimeout")
+	}
+}
+
+func TestEndpointManager_transport_sharesEndpointAcrossConfigs(t *testing.T) {
+	manager := NewEndpointManager()
+	keepAlive := defaultTransportConfig
+	keepAlive.maxIdleConnsPerHost = 50
+
+	first, _ := manager.transport("http://hook.example/sync", defaultTransportConfig)
+	second, _ := manager.transport("http://hook.example/finalize", keepAlive)
+	third, _ := manager.transport("http://hook.example/customize", defaultTransportConfig)
+
+	if len(manager.endpoints) != 1 {
+		t.Fatalf("expected 1 endpoint, got: %d", len(manager.endpoints))
+	}
+	if first.(*endpointTransport).transport == second.(*endpointTransport).transport {
+		t.Errorf("expected different transports for different configs")
+	}
+	if first.(*endpointTransport).transport != third.(*endpointTransport).transport {
+		t.Errorf("expected the same transport for the same config")
+	}
+	if second.(*endpointTransport).transport.MaxIdleConnsPerHost != 50 {
+		t.Errorf("expected maxIdleConnsPerHost 50, got: %d", second.(*endpointTransport).transport.MaxIdleConnsPerHost)
+	}
+}
+
+func TestEndpointManager_transport_withCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	config, err := newTransportConfig(&v1alpha1.Webhook{CABundle: caBundle}, nil)
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	transport, err := NewEndpointManager().transport(server.URL, config)
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+
+	if err != nil {
+		t.Fatalf("expected the server certificate to be trusted, got: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestEndpointManager_transport_withProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+	config, err := newTransportConfig(&v1alpha1.Webhook{Proxy: pointer.StringPtr(proxy.URL)}, nil)
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	transport, err := NewEndpointManager().transport("http://hook.example/sync", config)
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	resp, err := (&http.Client{Transport: transport}).Get("http://hook.example/sync")
+
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	resp.Body.Close()
+	if proxied != "http://hook.example/sync" {
+		t.Errorf("expected request to go through the proxy, got: %q", proxied)
+	}
+}
+
+func TestNewTransportConfig_whenInvalidProxyOrCABundle_returnError(t *testing.T) {
+	for _, webhook := range []*v1alpha1.Webhook{
+		{Proxy: pointer.StringPtr("proxy.example:3128")},
+		{CABundle: []byte("not a certificate")},
+	} {
+		if _, err := newTransportConfig(webhook, nil); err == nil {
+			t.Errorf("expected error for %+v", webhook)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+// HookAPIVersionV2 is the apiVersion carried by v2 hook requests and responses.
+const HookAPIVersionV2 = "hooks.metacontroller.k8s.io/v2"
+
+// payloadConverter converts encoded hook payloads between the internal
+// representation, which is the v1 schema, and the version spoken by a hook.
+type payloadConverter interface {
+	// convertRequest converts an encoded v1 request into the hook's version.
+	convertRequest(body []byte) ([]byte, error)
+	// convertResponse converts an encoded response in the hook's version to v1.
+	convertResponse(body []byte) ([]byte, error)
+}
+
+func newPayloadConverter(version *v1alpha1.HookVersion) (payloadConverter, error) {
+	if version == nil {
+		return v1Converter{}, nil
+	}
+	switch *version {
+	case v1alpha1.HookVersionV1:
+		return v1Converter{}, nil
+	case v1alpha1.HookVersionV2:
+		return v2Converter{}, nil
+	default:
+		return nil, fmt.Errorf("invalid hook config: unknown version %q", *version)
+	}
+}
+
+// v1Converter passes payloads through unchanged.
+type v1Converter struct{}
+
+func (v1Converter) convertRequest(body []byte) ([]byte, error) {
+	return body, nil
+}
+
+func (v1Converter) convertResponse(body []byte) ([]byte, error) {
+	return body, nil
+}
+
+// v2Converter adds an explicit apiVersion to requests and requires hooks to
+// echo it back in their responses, so a mismatch is detected instead of
+// silently misinterpreting the payload.
+type v2Converter struct{}
+
+func (v2Converter) convertRequest(body []byte) ([]byte, error) {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, fmt.Errorf("can't convert request to %s: %w", HookAPIVersionV2, err)
+	}
+	apiVersion, err := json.Marshal(HookAPIVersionV2)
+	if err != nil {
+		return nil, err
+	}
+	fields["apiVersion"] = apiVersion
+	return json.Marshal(fields)
+}
+
+func (v2Converter) convertResponse(body []byte) ([]byte, error) {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, fmt.Errorf("can't convert response from %s: %w", HookAPIVersionV2, err)
+	}
+	var apiVersion string
+	if raw, ok := fields["apiVersion"]; ok {
+		if err := json.Unmarshal(raw, &apiVersion); err != nil {
+			return nil, fmt.Errorf("invalid response apiVersion: %w", err)
+		}
+	}
+	if apiVersion != HookAPIVersionV2 {
+		return nil, fmt.Errorf("response apiVersion %q doesn't match expected %q", apiVersion, HookAPIVersionV2)
+	}
+	delete(fields, "apiVersion")
+	return json.Marshal(fields)
+}
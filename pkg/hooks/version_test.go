@@ -0,0 +1,130 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"metacontroller/pkg/controller/common"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+)
+
+func TestNewPayloadConverter_defaultsToV1(t *testing.T) {
+	converter, err := newPayloadConverter(nil)
+
+	if err != nil {
+		t.Errorf("err should be nil, got: %v", err)
+	}
+	if _, ok := converter.(v1Converter); !ok {
+		t.Errorf("expected v1Converter, got: %T", converter)
+	}
+}
+
+func TestNewPayloadConverter_unknownVersion(t *testing.T) {
+	version := v1alpha1.HookVersion("v9")
+
+	_, err := newPayloadConverter(&version)
+
+	if err == nil {
+		t.Errorf("expected error for unknown version")
+	}
+}
+
+func TestV2Converter_convertRequest_addsAPIVersion(t *testing.T) {
+	body, err := v2Converter{}.convertRequest([]byte(`{"parent":{"kind":"Foo"},"finalizing":false}`))
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	var request map[string]interface{}
+	if err := json.Unmarshal(body, &request); err != nil {
+		t.Fatalf("can't unmarshal converted request: %v", err)
+	}
+	if request["apiVersion"] != HookAPIVersionV2 {
+		t.Errorf("expected apiVersion %q, got: %v", HookAPIVersionV2, request["apiVersion"])
+	}
+	if _, ok := request["parent"]; !ok {
+		t.Errorf("expected parent to be preserved, got: %s", body)
+	}
+}
+
+func TestV2Converter_convertResponse(t *testing.T) {
+	tables := []struct {
+		name      string
+		body      string
+		expected  string
+		expectErr bool
+	}{
+		{
+			name:     "matching apiVersion is stripped",
+			body:     `{"apiVersion":"hooks.metacontroller.k8s.io/v2","status":{"replicas":1}}`,
+			expected: `{"status":{"replicas":1}}`,
+		},
+		{
+			name:      "missing apiVersion",
+			body:      `{"status":{}}`,
+			expectErr: true,
+		},
+		{
+			name:      "mismatched apiVersion",
+			body:      `{"apiVersion":"hooks.metacontroller.k8s.io/v3","status":{}}`,
+			expectErr: true,
+		},
+	}
+
+	for _, table := range tables {
+		body, err := v2Converter{}.convertResponse([]byte(table.body))
+		if table.expectErr {
+			if err == nil {
+				t.Errorf("%s: expected error", table.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: err should be nil, got: %v", table.name, err)
+			continue
+		}
+		if string(body) != table.expected {
+			t.Errorf("%s: expected %s, got: %s", table.name, table.expected, body)
+		}
+	}
+}
+
+func TestHookExecutor_v2RoundTrip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var request map[string]interface{}
+		if err := json.Unmarshal(body, &request); err != nil {
+			t.Errorf("can't unmarshal request: %v", err)
+		}
+		if request["apiVersion"] != HookAPIVersionV2 {
+			t.Errorf("expected apiVersion %q, got: %v", HookAPIVersionV2, request["apiVersion"])
+		}
+		_, _ = w.Write([]byte(`{"apiVersion":"hooks.metacontroller.k8s.io/v2","value":"ok"}`))
+	}))
+	defer server.Close()
+
+	version := v1alpha1.HookVersionV2
+	executor, err := NewHookExecutor(&v1alpha1.Hook{
+		Webhook: &v1alpha1.Webhook{URL: &server.URL},
+		Version: &version,
+	}, "v2-round-trip", common.CompositeController, common.SyncHook, nil, nil, logr.Discard())
+	if err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+
+	var response struct {
+		Value string `json:"value"`
+	}
+	if err := executor.Execute(context.Background(), map[string]string{"parent": "foo"}, &response); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if response.Value != "ok" {
+		t.Errorf("expected value ok, got: %q", response.Value)
+	}
+}
@@ -18,25 +18,34 @@ package hooks
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"metacontroller/pkg/controller/common"
 	"metacontroller/pkg/logging"
 	"metacontroller/pkg/metrics"
+	"metacontroller/pkg/tracing"
 	"net/http"
+	"strconv"
 	"time"
 
 	"metacontroller/pkg/apis/metacontroller/v1alpha1"
 
+	"github.com/go-logr/logr"
 	k8sjson "k8s.io/apimachinery/pkg/util/json"
 )
 
 // WebhookExecutor executes a call to a webhook
 type WebhookExecutor struct {
-	client   *http.Client
-	url      string
-	hookType string
+	client    *http.Client
+	url       string
+	hookType  string
+	converter payloadConverter
+	signer    *requestSigner
+	gzip      bool
+	shadow    *shadowWebhook
+
+	maxResponseSize int64
 }
 
 // NewWebhookExecutor returns new WebhookExecutor
@@ -44,7 +53,9 @@ func NewWebhookExecutor(
 	webhook *v1alpha1.Webhook,
 	controllerName string,
 	controllerType common.ControllerType,
-	hookType common.HookType) (*WebhookExecutor, error) {
+	hookType common.HookType,
+	hookTransport *v1alpha1.HookTransport,
+	logger logr.Logger) (*WebhookExecutor, error) {
 	if webhook == nil {
 		return nil, nil
 	}
@@ -52,11 +63,23 @@ func NewWebhookExecutor(
 	if err != nil {
 		return nil, err
 	}
+	gzip, err := validateCompression(webhook.Compression)
+	if err != nil {
+		return nil, err
+	}
 	hookTimeout, err := webhookTimeout(webhook)
 	if err != nil {
-		logging.Logger.Info(err.Error())
+		logger.Info(err.Error())
+	}
+	config, err := newTransportConfig(webhook, hookTransport)
+	if err != nil {
+		return nil, err
+	}
+	transport, err := Endpoints.transport(url, config)
+	if err != nil {
+		return nil, err
 	}
-	client := &http.Client{Timeout: hookTimeout}
+	client := &http.Client{Timeout: hookTimeout, Transport: transport}
 	client, err = metrics.InstrumentClientWithConstLabels(
 		controllerName,
 		controllerType,
@@ -66,37 +89,78 @@ func NewWebhookExecutor(
 	if err != nil {
 		return nil, err
 	}
+	shadow, err := newShadowWebhook(webhook.ShadowURL, config, hookTimeout, controllerName, controllerType, hookType)
+	if err != nil {
+		return nil, err
+	}
 	return &WebhookExecutor{
-		client:   client,
-		url:      url,
-		hookType: hookType.String(),
+		client:    client,
+		url:       url,
+		hookType:  hookType.String(),
+		converter: v1Converter{},
+		gzip:      gzip,
+		shadow:    shadow,
+
+		maxResponseSize: DefaultMaxResponseSize,
 	}, nil
 }
 
-func (w *WebhookExecutor) Execute(request interface{}, response interface{}) error {
+// Execute sends the request to the webhook and decodes the reply into response.
+// The trace context carried by ctx is propagated using the traceparent header.
+func (w *WebhookExecutor) Execute(ctx context.Context, request interface{}, response interface{}) (err error) {
+	ctx, span := tracing.StartClient(ctx, "hook "+w.hookType, "hook.type", w.hookType, "http.url", w.url)
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	logger := logging.FromContext(ctx)
+
 	// Encode request.
 	reqBody, err := k8sjson.Marshal(request)
 	if err != nil {
 		return fmt.Errorf("can't marshal request: %w", err)
 	}
-	if logging.Logger.V(6).Enabled() {
-		rawRequest := json.RawMessage(reqBody)
-		logging.Logger.Info("Webhook request", "type", w.hookType, "url", w.url, "body", rawRequest)
+	reqBody, err = w.converter.convertRequest(reqBody)
+	if err != nil {
+		return err
 	}
-	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(reqBody))
+	stats := callStatsFrom(ctx)
+	stats.requestBytes = len(reqBody)
+	if logger.V(6).Enabled() {
+		rawRequest := json.RawMessage(reqBody)
+		logger.Info("Webhook request", "syncID", tracing.SyncID(ctx), "type", w.hookType, "url", w.url, "body", rawRequest)
+	}
+	// The signature always covers the uncompressed request body.
+	var signature string
+	if w.signer != nil {
+		signature, err = w.signer.sign(reqBody)
+		if err != nil {
+			return err
+		}
+	}
+	if w.gzip {
+		reqBody, err = gzipBody(reqBody)
+		if err != nil {
+			return fmt.Errorf("can't compress request: %w", err)
+		}
+	}
+	resp, err := w.post(ctx, w.client, w.url, reqBody, signature)
 	if err != nil {
-		return fmt.Errorf("http error: %w", err)
+		return err
 	}
 	defer resp.Body.Close()
+	span.SetAttributes("http.status_code", strconv.Itoa(resp.StatusCode))
+	stats.statusCode = resp.StatusCode
 
 	// Read response.
-	respBody, err := ioutil.ReadAll(resp.Body)
+	respBody, err := readResponseBody(resp, w.maxResponseSize)
 	if err != nil {
 		return fmt.Errorf("can't read response body: %w", err)
 	}
-	if logging.Logger.V(6).Enabled() {
+	stats.responseBytes = len(respBody)
+	if logger.V(6).Enabled() {
 		rawResponse := json.RawMessage(respBody)
-		logging.Logger.V(6).Info("Webhook response", "type", w.hookType, "url", w.url, "body", rawResponse)
+		logger.V(6).Info("Webhook response", "syncID", tracing.SyncID(ctx), "type", w.hookType, "url", w.url, "body", rawResponse)
 	}
 
 	// Check status code.
@@ -105,12 +169,41 @@ func (w *WebhookExecutor) Execute(request interface{}, response interface{}) err
 	}
 
 	// Decode response.
+	respBody, err = w.converter.convertResponse(respBody)
+	if err != nil {
+		return err
+	}
 	if err := k8sjson.Unmarshal(respBody, response); err != nil {
-		return fmt.Errorf("can't unmarshal response: %w", err)
+		return &InvalidResponseError{Err: err}
+	}
+	if w.shadow != nil {
+		w.shadow.compare(logger, w, reqBody, signature, respBody)
 	}
 	return nil
 }
 
+// post sends the encoded request body to url with client.
+func (w *WebhookExecutor) post(ctx context.Context, client *http.Client, url string, reqBody []byte, signature string) (*http.Response, error) {
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("can't create request: %w", err)
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	if w.gzip {
+		httpRequest.Header.Set("Content-Encoding", gzipEncoding)
+		httpRequest.Header.Set("Accept-Encoding", gzipEncoding)
+	}
+	if signature != "" {
+		httpRequest.Header.Set(SignatureHeader, signature)
+	}
+	tracing.Inject(ctx, httpRequest.Header)
+	resp, err := client.Do(httpRequest)
+	if err != nil {
+		return nil, fmt.Errorf("http error: %w", err)
+	}
+	return resp, nil
+}
+
 func webhookURL(webhook *v1alpha1.Webhook) (string, error) {
 	if webhook.URL != nil {
 		// Full URL overrides everything else.
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-logr/logr"
 	"k8s.io/utils/pointer"
 
 	"metacontroller/pkg/apis/metacontroller/v1alpha1"
@@ -13,7 +14,7 @@ import (
 )
 
 func TestNewHookExecutor_whenNilWebHook_returnNilWebhookExecutor(t *testing.T) {
-	executor, err := NewWebhookExecutor(nil, "", common.CompositeController, "")
+	executor, err := NewWebhookExecutor(nil, "", common.CompositeController, "", nil, logr.Discard())
 
 	if err != nil {
 		t.Errorf("err should be nil, got: %v", err)
@@ -1,6 +1,7 @@
 package testutils
 
 import (
+	"context"
 	"fmt"
 	"metacontroller/pkg/hooks"
 	"reflect"
@@ -24,7 +25,7 @@ func (h *hookExecutorStub) IsEnabled() bool {
 	return true
 }
 
-func (h *hookExecutorStub) Execute(request interface{}, response interface{}) error {
+func (h *hookExecutorStub) Execute(ctx context.Context, request interface{}, response interface{}) error {
 	val := reflect.ValueOf(response)
 	if val.Kind() != reflect.Ptr {
 		return fmt.Errorf(`panic("not a pointer")`)
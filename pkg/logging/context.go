@@ -0,0 +1,37 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+)
+
+// NewContext returns a copy of ctx carrying logger, so the code called with
+// it, like hooks, logs like the controller which called it.
+func NewContext(ctx context.Context, logger logr.Logger) context.Context {
+	return logr.NewContext(ctx, logger)
+}
+
+// FromContext returns the logger carried by ctx, or Logger if it has none.
+func FromContext(ctx context.Context) logr.Logger {
+	if logger := logr.FromContext(ctx); logger != nil {
+		return logger
+	}
+	return Logger
+}
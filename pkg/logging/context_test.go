@@ -0,0 +1,32 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/utils/pointer"
+	controllerruntimezap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestFromContext(t *testing.T) {
+	var out bytes.Buffer
+	options = &controllerruntimezap.Options{DestWriter: &out}
+	defer func() { options = nil }()
+
+	level := NewLevel()
+	level.Set(pointer.IntPtr(6))
+	ctx := NewContext(context.Background(), level.Logger())
+
+	FromContext(ctx).V(6).Info("hook body")
+	if !strings.Contains(out.String(), "hook body") {
+		t.Errorf("expected the logger carried by ctx to be used, got: %s", out.String())
+	}
+
+	out.Reset()
+	FromContext(context.Background()).V(6).Info("discarded")
+	if out.Len() != 0 {
+		t.Errorf("expected Logger to be used without a logger in ctx, got: %s", out.String())
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"math"
+
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	controllerruntimezap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+// Level is the verbosity of a logger which can be changed while it's used,
+// so a single controller can log more, or less, than the others.
+type Level struct {
+	level  zap.AtomicLevel
+	logger logr.Logger
+}
+
+// NewLevel returns a Level with the verbosity of Logger, and a logger like
+// Logger which follows it.
+func NewLevel() *Level {
+	l := &Level{level: zap.NewAtomicLevelAt(defaultLevel())}
+	if options == nil {
+		// Logging isn't initialized, e.g. in unit tests.
+		l.logger = Logger
+		return l
+	}
+	l.logger = controllerruntimezap.New(controllerruntimezap.UseFlagOptions(options), controllerruntimezap.Level(l.level))
+	return l
+}
+
+// Logger returns the logger following the verbosity of l.
+func (l *Level) Logger() logr.Logger {
+	return l.logger
+}
+
+// Set makes the logger log the messages up to the given verbosity, as in
+// V(verbosity), or up to the verbosity of Logger if nil.
+func (l *Level) Set(verbosity *int) {
+	if verbosity == nil {
+		l.level.SetLevel(defaultLevel())
+		return
+	}
+	l.level.SetLevel(zapcore.Level(-*verbosity))
+}
+
+// Verbosity returns the highest verbosity which is logged.
+func (l *Level) Verbosity() int {
+	return -int(l.level.Level())
+}
+
+// defaultLevel returns the lowest zap level Logger logs.
+func defaultLevel() zapcore.Level {
+	if options == nil || options.Level == nil {
+		if options != nil && options.Development {
+			return zapcore.DebugLevel
+		}
+		return zapcore.InfoLevel
+	}
+	for level := zapcore.Level(math.MinInt8); level < zapcore.FatalLevel; level++ {
+		if options.Level.Enabled(level) {
+			return level
+		}
+	}
+	return zapcore.FatalLevel
+}
@@ -0,0 +1,38 @@
+package logging
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"k8s.io/utils/pointer"
+	controllerruntimezap "sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestLevel(t *testing.T) {
+	var out bytes.Buffer
+	options = &controllerruntimezap.Options{DestWriter: &out}
+	defer func() { options = nil }()
+
+	level := NewLevel()
+	if got := level.Verbosity(); got != 0 {
+		t.Errorf("expected the verbosity of Logger, got: %d", got)
+	}
+	level.Logger().V(3).Info("hidden")
+	if out.Len() != 0 {
+		t.Errorf("expected nothing to be logged at V(3), got: %s", out.String())
+	}
+
+	level.Set(pointer.IntPtr(3))
+	level.Logger().V(3).Info("shown")
+	if !strings.Contains(out.String(), "shown") {
+		t.Errorf("expected a message to be logged at V(3) once set, got: %s", out.String())
+	}
+
+	level.Set(nil)
+	out.Reset()
+	level.Logger().V(3).Info("hidden again")
+	if out.Len() != 0 || level.Verbosity() != 0 {
+		t.Errorf("expected the verbosity of Logger once reset, got: %s", out.String())
+	}
+}
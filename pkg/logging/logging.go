@@ -26,8 +26,12 @@ import (
 )
 
 var (
-	// Logger is global json log format logr
-	Logger logr.Logger
+	// Logger is global json log format logr, discarding messages until
+	// InitLogging is called (e.g. in unit tests)
+	Logger = logr.Discard()
+
+	// options are those Logger was built with, if InitLogging was called.
+	options *controllerruntimezap.Options
 )
 
 func InitLogging(opts *controllerruntimezap.Options) {
@@ -36,6 +40,7 @@ func InitLogging(opts *controllerruntimezap.Options) {
 			encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
 		})
 	}
+	options = opts
 	Logger = controllerruntimezap.New(controllerruntimezap.UseFlagOptions(opts))
 	klog.SetLogger(Logger)
 	controllerruntimelog.SetLogger(Logger)
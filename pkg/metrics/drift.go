@@ -0,0 +1,65 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"metacontroller/pkg/controller/common"
+)
+
+var driftedChildren = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metacontrollerPrefix,
+		Subsystem: "controller",
+		Name:      "drifted_children_total",
+		Help:      "Number of children found to differ from the desired state by controllers in ReportOnly mode, by the action which would be taken.",
+	},
+	[]string{"controller_name", "controller_type", "action"},
+)
+
+var dryRunWrites = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metacontrollerPrefix,
+		Subsystem: "controller",
+		Name:      "dry_run_writes_total",
+		Help:      "Number of writes to children which were only dry-run by controllers in dry-run mode, by action and by whether the API server accepted them.",
+	},
+	[]string{"controller_name", "controller_type", "action", "result"},
+)
+
+func init() {
+	registerer.MustRegister(driftedChildren, dryRunWrites)
+}
+
+// RecordDrift counts the drifted children found by a sync of the given
+// controller.
+func RecordDrift(controllerName string, controllerType common.ControllerType, drifts []common.ChildDrift) {
+	for _, drift := range drifts {
+		driftedChildren.WithLabelValues(controllerName, controllerType.String(), string(drift.Action)).Inc()
+	}
+}
+
+// RecordDryRunWrites counts the dry-run writes among results of a sync of the
+// given controller.
+func RecordDryRunWrites(controllerName string, controllerType common.ControllerType, results []common.ChildResult) {
+	for _, result := range results {
+		if result.DryRun {
+			dryRunWrites.WithLabelValues(controllerName, controllerType.String(), string(result.Action), result.Result).Inc()
+		}
+	}
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"metacontroller/pkg/controller/common"
+)
+
+var appliedGeneration = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: metacontrollerPrefix,
+		Subsystem: "controller",
+		Name:      "applied_generation",
+		Help:      "The metadata.generation of the CompositeController or DecoratorController spec used by the running controller.",
+	},
+	[]string{"controller_name", "controller_type"},
+)
+
+func init() {
+	registerer.MustRegister(appliedGeneration)
+}
+
+// SetAppliedGeneration records that the given controller is running with the
+// spec of the given generation.
+func SetAppliedGeneration(controllerName string, controllerType common.ControllerType, generation int64) {
+	appliedGeneration.WithLabelValues(controllerName, controllerType.String()).Set(float64(generation))
+}
+
+// ForgetAppliedGeneration removes the metric of a controller which isn't
+// running anymore.
+func ForgetAppliedGeneration(controllerName string, controllerType common.ControllerType) {
+	appliedGeneration.DeleteLabelValues(controllerName, controllerType.String())
+}
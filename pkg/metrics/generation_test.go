@@ -0,0 +1,25 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"metacontroller/pkg/controller/common"
+)
+
+func TestSetAppliedGeneration(t *testing.T) {
+	SetAppliedGeneration("applied", common.CompositeController, 3)
+	SetAppliedGeneration("applied", common.CompositeController, 4)
+
+	gauge := appliedGeneration.WithLabelValues("applied", common.CompositeController.String())
+	if value := testutil.ToFloat64(gauge); value != 4 {
+		t.Errorf("expected generation 4, got: %v", value)
+	}
+
+	ForgetAppliedGeneration("applied", common.CompositeController)
+
+	if count := testutil.CollectAndCount(appliedGeneration); count != 0 {
+		t.Errorf("expected no series, got: %d", count)
+	}
+}
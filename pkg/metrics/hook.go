@@ -0,0 +1,126 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"metacontroller/pkg/controller/common"
+)
+
+var (
+	hookLabels = []string{"controller_name", "controller_type", "hook_type"}
+	// Payloads range from a few hundred bytes to the default maximum size of
+	// responses, 64Mi.
+	hookSizeBuckets = prometheus.ExponentialBuckets(256, 4, 10)
+
+	hookCalls = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metacontrollerPrefix,
+			Subsystem: "hook",
+			Name:      "calls_total",
+			Help:      "Number of calls of the hooks of controllers.",
+		},
+		hookLabels,
+	)
+	hookErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metacontrollerPrefix,
+			Subsystem: "hook",
+			Name:      "errors_total",
+			Help:      "Number of failed calls of the hooks of controllers, by HTTP status code of the response (none if there was no error status).",
+		},
+		[]string{"controller_name", "controller_type", "hook_type", "code"},
+	)
+	hookDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metacontrollerPrefix,
+			Subsystem: "hook",
+			Name:      "duration_seconds",
+			Help:      "Duration of the calls of the hooks of controllers.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		hookLabels,
+	)
+	hookRequestSize = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metacontrollerPrefix,
+			Subsystem: "hook",
+			Name:      "request_size_bytes",
+			Help:      "Size of the requests sent to the hooks of controllers, before compression.",
+			Buckets:   hookSizeBuckets,
+		},
+		hookLabels,
+	)
+	hookResponseSize = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metacontrollerPrefix,
+			Subsystem: "hook",
+			Name:      "response_size_bytes",
+			Help:      "Size of the responses of the hooks of controllers, after decompression.",
+			Buckets:   hookSizeBuckets,
+		},
+		hookLabels,
+	)
+)
+
+func init() {
+	registerer.MustRegister(hookCalls, hookErrors, hookDuration, hookRequestSize, hookResponseSize)
+}
+
+// HookCall is the outcome of a call of a hook.
+type HookCall struct {
+	Duration time.Duration
+	// RequestBytes and ResponseBytes are the sizes of the request and of the
+	// response, if the call got that far.
+	RequestBytes  int
+	ResponseBytes int
+	// StatusCode is the HTTP status of the response of a webhook, or zero.
+	StatusCode int
+	Err        error
+}
+
+// ObserveHookCall records a call of a hook of the given controller.
+func ObserveHookCall(controllerName string, controllerType common.ControllerType, hookType common.HookType, call HookCall) {
+	labels := prometheus.Labels{
+		"controller_name": controllerName,
+		"controller_type": controllerType.String(),
+		"hook_type":       hookType.String(),
+	}
+	hookCalls.With(labels).Inc()
+	hookDuration.With(labels).Observe(call.Duration.Seconds())
+	if call.RequestBytes > 0 {
+		hookRequestSize.With(labels).Observe(float64(call.RequestBytes))
+	}
+	if call.ResponseBytes > 0 {
+		hookResponseSize.With(labels).Observe(float64(call.ResponseBytes))
+	}
+	if call.Err != nil {
+		code := "none"
+		if call.StatusCode != 0 && call.StatusCode != 200 {
+			code = strconv.Itoa(call.StatusCode)
+		}
+		errorLabels := prometheus.Labels{"code": code}
+		for name, value := range labels {
+			errorLabels[name] = value
+		}
+		hookErrors.With(errorLabels).Inc()
+	}
+}
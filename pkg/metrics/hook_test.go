@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"metacontroller/pkg/controller/common"
+)
+
+func TestObserveHookCall(t *testing.T) {
+	ObserveHookCall("hooked", common.CompositeController, common.SyncHook, HookCall{
+		Duration:      time.Second,
+		RequestBytes:  100,
+		ResponseBytes: 10,
+		StatusCode:    200,
+	})
+	ObserveHookCall("hooked", common.CompositeController, common.SyncHook, HookCall{
+		Duration:      time.Second,
+		RequestBytes:  100,
+		ResponseBytes: 10,
+		StatusCode:    503,
+		Err:           errors.New("remote error: overloaded"),
+	})
+	ObserveHookCall("hooked", common.CompositeController, common.SyncHook, HookCall{
+		Duration: time.Second,
+		Err:      errors.New("http error: timeout"),
+	})
+
+	labels := []string{"hooked", common.CompositeController.String(), common.SyncHook.String()}
+	if value := testutil.ToFloat64(hookCalls.WithLabelValues(labels...)); value != 3 {
+		t.Errorf("expected 3 calls, got: %v", value)
+	}
+	if value := testutil.ToFloat64(hookErrors.WithLabelValues(append(labels, "503")...)); value != 1 {
+		t.Errorf("expected 1 error with code 503, got: %v", value)
+	}
+	if value := testutil.ToFloat64(hookErrors.WithLabelValues(append(labels, "none")...)); value != 1 {
+		t.Errorf("expected 1 error without code, got: %v", value)
+	}
+	if count := testutil.CollectAndCount(hookRequestSize); count != 1 {
+		t.Errorf("expected a request size series, got: %d", count)
+	}
+}
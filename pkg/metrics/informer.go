@@ -0,0 +1,99 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/watch"
+
+	dynamicinformer "metacontroller/pkg/dynamic/informer"
+)
+
+var informerLabels = []string{"api_version", "kind", "resource", "selectors"}
+
+var (
+	informerSubscribersDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metacontrollerPrefix, "informer", "subscribers"),
+		"Number of controllers using a shared informer.",
+		informerLabels, nil,
+	)
+	informerObjectsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metacontrollerPrefix, "informer", "cached_objects"),
+		"Number of objects cached by a shared informer.",
+		informerLabels, nil,
+	)
+	informerBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metacontrollerPrefix, "informer", "cache_bytes_estimate"),
+		"Approximate size, in JSON, of the objects cached by a shared informer.",
+		informerLabels, nil,
+	)
+	informerListsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metacontrollerPrefix, "informer", "lists_total"),
+		"Number of list requests made by a shared informer.",
+		informerLabels, nil,
+	)
+	informerWatchRestartsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metacontrollerPrefix, "informer", "watch_restarts_total"),
+		"Number of watches restarted by a shared informer.",
+		informerLabels, nil,
+	)
+	informerEventsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metacontrollerPrefix, "informer", "events_total"),
+		"Number of watch events received by a shared informer, by type.",
+		append(informerLabels, "type"), nil,
+	)
+)
+
+// informerCollector exports metrics about the shared informers of a factory,
+// computed when they're scraped.
+type informerCollector struct {
+	factory *dynamicinformer.SharedInformerFactory
+}
+
+// RegisterInformerMetrics exports metrics about the shared informers created
+// by factory: their number of cached objects and its estimated size, and how
+// often they list, restart watches and receive events.
+func RegisterInformerMetrics(factory *dynamicinformer.SharedInformerFactory) error {
+	return registerer.Register(&informerCollector{factory: factory})
+}
+
+func (c *informerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- informerSubscribersDesc
+	ch <- informerObjectsDesc
+	ch <- informerBytesDesc
+	ch <- informerListsDesc
+	ch <- informerWatchRestartsDesc
+	ch <- informerEventsDesc
+}
+
+func (c *informerCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, stats := range c.factory.Stats() {
+		selectors := ""
+		if !stats.Selectors.IsEmpty() {
+			selectors = stats.Selectors.String()
+		}
+		labels := []string{stats.APIVersion, stats.Kind, stats.Resource, selectors}
+		ch <- prometheus.MustNewConstMetric(informerSubscribersDesc, prometheus.GaugeValue, float64(stats.Subscribers), labels...)
+		ch <- prometheus.MustNewConstMetric(informerObjectsDesc, prometheus.GaugeValue, float64(stats.Objects), labels...)
+		ch <- prometheus.MustNewConstMetric(informerBytesDesc, prometheus.GaugeValue, float64(stats.EstimatedBytes), labels...)
+		ch <- prometheus.MustNewConstMetric(informerListsDesc, prometheus.CounterValue, float64(stats.Lists), labels...)
+		ch <- prometheus.MustNewConstMetric(informerWatchRestartsDesc, prometheus.CounterValue, float64(stats.WatchRestarts), labels...)
+		for _, eventType := range []watch.EventType{watch.Added, watch.Modified, watch.Deleted} {
+			ch <- prometheus.MustNewConstMetric(informerEventsDesc, prometheus.CounterValue, float64(stats.Events[eventType]), append(labels, string(eventType))...)
+		}
+	}
+}
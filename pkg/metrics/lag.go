@@ -0,0 +1,130 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"metacontroller/pkg/controller/common"
+)
+
+var lagBuckets = []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+var (
+	watchLag = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metacontrollerPrefix,
+			Subsystem: "informer",
+			Name:      "event_lag_seconds",
+			Help:      "Delay between an object's last write on the API server and the receipt of its watch event.",
+			Buckets:   lagBuckets,
+		},
+		[]string{"controller_name", "controller_type"},
+	)
+	queueLag = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metacontrollerPrefix,
+			Subsystem: "queue",
+			Name:      "lag_seconds",
+			Help:      "Delay between the receipt of an event for an object and the start of its sync.",
+			Buckets:   lagBuckets,
+		},
+		[]string{"controller_name", "controller_type"},
+	)
+)
+
+func init() {
+	registerer.MustRegister(watchLag, queueLag)
+}
+
+// LagTracker measures, for one controller, how long watch events take to
+// arrive from the API server, and how long they then wait in the queue.
+type LagTracker struct {
+	labels prometheus.Labels
+
+	mutex    sync.Mutex
+	received map[string]time.Time
+
+	now func() time.Time
+}
+
+// NewLagTracker returns a LagTracker reporting metrics for the given controller.
+func NewLagTracker(controllerName string, controllerType common.ControllerType) *LagTracker {
+	return &LagTracker{
+		labels: prometheus.Labels{
+			"controller_name": controllerName,
+			"controller_type": controllerType.String(),
+		},
+		received: make(map[string]time.Time),
+		now:      time.Now,
+	}
+}
+
+// ObserveUpdate records the watch lag of an update event, using the time of
+// the latest write recorded in the object's managed fields. Resyncs, where
+// the object didn't change, are ignored.
+func (t *LagTracker) ObserveUpdate(old, cur metav1.Object) {
+	if old.GetResourceVersion() == cur.GetResourceVersion() {
+		return
+	}
+	var lastWrite time.Time
+	for _, entry := range cur.GetManagedFields() {
+		if entry.Time != nil && entry.Time.After(lastWrite) {
+			lastWrite = entry.Time.Time
+		}
+	}
+	if lastWrite.IsZero() {
+		return
+	}
+	lag := t.now().Sub(lastWrite)
+	if lag < 0 {
+		// Managed fields times have a resolution of one second.
+		lag = 0
+	}
+	watchLag.With(t.labels).Observe(lag.Seconds())
+}
+
+// Enqueued records that an event for key was received. Only the first event
+// is kept until the key is dequeued, since the queue merges duplicates.
+func (t *LagTracker) Enqueued(key string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if _, ok := t.received[key]; !ok {
+		t.received[key] = t.now()
+	}
+}
+
+// Dequeued records the queue lag of key, if it was enqueued by an event.
+func (t *LagTracker) Dequeued(key string) {
+	t.mutex.Lock()
+	received, ok := t.received[key]
+	delete(t.received, key)
+	t.mutex.Unlock()
+	if ok {
+		queueLag.With(t.labels).Observe(t.now().Sub(received).Seconds())
+	}
+}
+
+// Stop removes the metrics of the controller.
+func (t *LagTracker) Stop() {
+	watchLag.Delete(t.labels)
+	queueLag.Delete(t.labels)
+}
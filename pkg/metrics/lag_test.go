@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"metacontroller/pkg/controller/common"
+)
+
+func TestLagTracker_queueLag(t *testing.T) {
+	tracker := NewLagTracker("queue-lag", common.CompositeController)
+	defer tracker.Stop()
+	now := time.Unix(100, 0)
+	tracker.now = func() time.Time { return now }
+
+	tracker.Enqueued("default/foo")
+	now = now.Add(time.Second)
+	// A second event before the sync doesn't reset the receipt time.
+	tracker.Enqueued("default/foo")
+	now = now.Add(time.Second)
+	tracker.Dequeued("default/foo")
+	// Keys not enqueued by an event aren't observed.
+	tracker.Dequeued("default/bar")
+
+	if count := testutil.CollectAndCount(queueLag); count != 1 {
+		t.Errorf("expected 1 series, got: %d", count)
+	}
+	if len(tracker.received) != 0 {
+		t.Errorf("expected no pending keys, got: %v", tracker.received)
+	}
+}
+
+func TestLagTracker_ObserveUpdate_ignoresResync(t *testing.T) {
+	tracker := NewLagTracker("watch-lag", common.DecoratorController)
+	defer tracker.Stop()
+	writeTime := metav1.NewTime(time.Now())
+	obj := &metav1.ObjectMeta{
+		ResourceVersion: "1",
+		ManagedFields:   []metav1.ManagedFieldsEntry{{Time: &writeTime}},
+	}
+
+	tracker.ObserveUpdate(obj, obj)
+
+	if count := testutil.CollectAndCount(watchLag); count != 0 {
+		t.Errorf("expected no series, got: %d", count)
+	}
+}
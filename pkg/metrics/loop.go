@@ -0,0 +1,48 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"metacontroller/pkg/controller/common"
+)
+
+var syncLoops = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: metacontrollerPrefix,
+		Subsystem: "sync",
+		Name:      "loops_detected_total",
+		Help:      "Number of times an object was detected to be synced in a hot loop.",
+	},
+	[]string{"controller_name", "controller_type"},
+)
+
+func init() {
+	registerer.MustRegister(syncLoops)
+}
+
+// RecordSyncLoop counts a hot loop detected by common.LoopDetector for the
+// given controller.
+func RecordSyncLoop(controllerName string, controllerType common.ControllerType) {
+	syncLoops.WithLabelValues(controllerName, controllerType.String()).Inc()
+}
+
+// ForgetSyncLoops removes the count of hot loops of the given controller.
+func ForgetSyncLoops(controllerName string, controllerType common.ControllerType) {
+	syncLoops.DeleteLabelValues(controllerName, controllerType.String())
+}
@@ -0,0 +1,260 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/util/workqueue"
+
+	"metacontroller/pkg/controller/common"
+)
+
+var queueLabels = []string{"controller_name", "controller_type"}
+
+var (
+	queueDepthDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metacontrollerPrefix, "queue", "depth"),
+		"Number of objects waiting in the work queue of a controller to be synced.",
+		queueLabels, nil,
+	)
+	queueAddsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metacontrollerPrefix, "queue", "adds_total"),
+		"Number of times objects were added to the work queue of a controller, except for retries.",
+		queueLabels, nil,
+	)
+	queueRetriesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metacontrollerPrefix, "queue", "retries_total"),
+		"Number of times objects were added back to the work queue of a controller after a failed sync.",
+		queueLabels, nil,
+	)
+	queueLongestRunningDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metacontrollerPrefix, "queue", "longest_running_sync_seconds"),
+		"How long the longest sync in progress of a controller has been running.",
+		queueLabels, nil,
+	)
+)
+
+// queues holds the running ControllerQueues by controller.
+var queues = &queueSet{queues: make(map[queueKey]*ControllerQueue)}
+
+func init() {
+	registerer.MustRegister(queues)
+}
+
+type queueKey struct {
+	controllerName string
+	controllerType common.ControllerType
+}
+
+// ControllerQueue is the work queue of a controller, which reports its depth,
+// how often objects are added to it, and how long they take to be synced.
+type ControllerQueue struct {
+	workqueue.RateLimitingInterface
+	key queueKey
+
+	adds    uint64
+	retries uint64
+
+	mutex sync.Mutex
+	// processing holds when each object being synced was taken from the queue.
+	processing map[interface{}]time.Time
+	// retrying holds the objects added back after a failed sync, until they're
+	// forgotten after a successful one.
+	retrying map[interface{}]bool
+
+	now func() time.Time
+}
+
+// NewControllerQueue returns the work queue of the given controller. It
+// reports metrics until it's shut down.
+func NewControllerQueue(controllerName string, controllerType common.ControllerType) *ControllerQueue {
+	q := &ControllerQueue{
+		RateLimitingInterface: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerType.String()+"-"+controllerName),
+		key:                   queueKey{controllerName: controllerName, controllerType: controllerType},
+		processing:            make(map[interface{}]time.Time),
+		retrying:              make(map[interface{}]bool),
+		now:                   time.Now,
+	}
+	queues.add(q)
+	return q
+}
+
+func (q *ControllerQueue) Add(item interface{}) {
+	atomic.AddUint64(&q.adds, 1)
+	q.RateLimitingInterface.Add(item)
+}
+
+func (q *ControllerQueue) AddAfter(item interface{}, duration time.Duration) {
+	atomic.AddUint64(&q.adds, 1)
+	q.RateLimitingInterface.AddAfter(item, duration)
+}
+
+func (q *ControllerQueue) AddRateLimited(item interface{}) {
+	atomic.AddUint64(&q.retries, 1)
+	q.mutex.Lock()
+	q.retrying[item] = true
+	q.mutex.Unlock()
+	q.RateLimitingInterface.AddRateLimited(item)
+}
+
+func (q *ControllerQueue) Forget(item interface{}) {
+	q.mutex.Lock()
+	delete(q.retrying, item)
+	q.mutex.Unlock()
+	q.RateLimitingInterface.Forget(item)
+}
+
+func (q *ControllerQueue) Get() (interface{}, bool) {
+	item, shutdown := q.RateLimitingInterface.Get()
+	if !shutdown {
+		q.mutex.Lock()
+		q.processing[item] = q.now()
+		q.mutex.Unlock()
+	}
+	return item, shutdown
+}
+
+func (q *ControllerQueue) Done(item interface{}) {
+	q.mutex.Lock()
+	delete(q.processing, item)
+	q.mutex.Unlock()
+	q.RateLimitingInterface.Done(item)
+}
+
+func (q *ControllerQueue) ShutDown() {
+	queues.remove(q)
+	q.RateLimitingInterface.ShutDown()
+}
+
+// longestRunning returns for how long the oldest sync in progress has been
+// running.
+func (q *ControllerQueue) longestRunning() time.Duration {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	now := q.now()
+	var longest time.Duration
+	for _, start := range q.processing {
+		if running := now.Sub(start); running > longest {
+			longest = running
+		}
+	}
+	return longest
+}
+
+// QueueState describes the work queue of a controller, and the objects in it
+// which are being synced or retried.
+type QueueState struct {
+	Depth      int            `json:"depth"`
+	Processing []QueuedObject `json:"processing,omitempty"`
+	Retrying   []QueuedObject `json:"retrying,omitempty"`
+}
+
+// QueuedObject is an object in the work queue of a controller.
+type QueuedObject struct {
+	Key string `json:"key"`
+	// Since is when the sync of an object being synced started.
+	Since *time.Time `json:"since,omitempty"`
+	// Requeues is the number of failed syncs of an object retried in a row.
+	Requeues int `json:"requeues,omitempty"`
+}
+
+// State returns the state of the queue.
+func (q *ControllerQueue) State() QueueState {
+	state := QueueState{Depth: q.Len()}
+	q.mutex.Lock()
+	for item, start := range q.processing {
+		since := start
+		state.Processing = append(state.Processing, QueuedObject{Key: fmt.Sprint(item), Since: &since})
+	}
+	retrying := make([]interface{}, 0, len(q.retrying))
+	for item := range q.retrying {
+		retrying = append(retrying, item)
+	}
+	q.mutex.Unlock()
+	for _, item := range retrying {
+		state.Retrying = append(state.Retrying, QueuedObject{Key: fmt.Sprint(item), Requeues: q.NumRequeues(item)})
+	}
+	sortQueuedObjects(state.Processing)
+	sortQueuedObjects(state.Retrying)
+	return state
+}
+
+func sortQueuedObjects(objects []QueuedObject) {
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+}
+
+// QueueStateOf returns the state of the work queue of the given controller,
+// if it's running.
+func QueueStateOf(controllerName string, controllerType common.ControllerType) (QueueState, bool) {
+	queues.mutex.Lock()
+	q, ok := queues.queues[queueKey{controllerName: controllerName, controllerType: controllerType}]
+	queues.mutex.Unlock()
+	if !ok {
+		return QueueState{}, false
+	}
+	return q.State(), true
+}
+
+// queueSet exports the metrics of the running ControllerQueues, computed
+// when they're scraped.
+type queueSet struct {
+	mutex  sync.Mutex
+	queues map[queueKey]*ControllerQueue
+}
+
+// add adds q, replacing the queue of a previous instance of the same
+// controller, which may not have been shut down if it failed to start.
+func (s *queueSet) add(q *ControllerQueue) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.queues[q.key] = q
+}
+
+func (s *queueSet) remove(q *ControllerQueue) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.queues[q.key] == q {
+		delete(s.queues, q.key)
+	}
+}
+
+func (s *queueSet) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queueDepthDesc
+	ch <- queueAddsDesc
+	ch <- queueRetriesDesc
+	ch <- queueLongestRunningDesc
+}
+
+func (s *queueSet) Collect(ch chan<- prometheus.Metric) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for key, q := range s.queues {
+		labels := []string{key.controllerName, key.controllerType.String()}
+		ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(q.Len()), labels...)
+		ch <- prometheus.MustNewConstMetric(queueAddsDesc, prometheus.CounterValue, float64(atomic.LoadUint64(&q.adds)), labels...)
+		ch <- prometheus.MustNewConstMetric(queueRetriesDesc, prometheus.CounterValue, float64(atomic.LoadUint64(&q.retries)), labels...)
+		ch <- prometheus.MustNewConstMetric(queueLongestRunningDesc, prometheus.GaugeValue, q.longestRunning().Seconds(), labels...)
+	}
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"metacontroller/pkg/controller/common"
+)
+
+func TestControllerQueue(t *testing.T) {
+	q := NewControllerQueue("queued", common.CompositeController)
+	now := time.Now()
+	q.now = func() time.Time { return now }
+
+	q.Add("default/a")
+	q.Add("default/b")
+	item, _ := q.Get()
+	now = now.Add(3 * time.Second)
+	q.AddRateLimited(item)
+	q.Done(item)
+
+	if q.adds != 2 || q.retries != 1 {
+		t.Errorf("expected 2 adds and 1 retry, got: %d adds and %d retries", q.adds, q.retries)
+	}
+	if count := testutil.CollectAndCount(queues, "metacontroller_queue_depth"); count != 1 {
+		t.Errorf("expected a depth series, got: %d", count)
+	}
+
+	item, _ = q.Get()
+	now = now.Add(5 * time.Second)
+	if got := q.longestRunning(); got != 5*time.Second {
+		t.Errorf("expected a sync running for 5s, got: %v", got)
+	}
+	q.Done(item)
+	if got := q.longestRunning(); got != 0 {
+		t.Errorf("expected no sync running, got: %v", got)
+	}
+
+	q.ShutDown()
+	if count := testutil.CollectAndCount(queues); count != 0 {
+		t.Errorf("expected no series once shut down, got: %d", count)
+	}
+}
+
+func TestControllerQueue_State(t *testing.T) {
+	q := NewControllerQueue("state", common.CompositeController)
+	defer q.ShutDown()
+	now := time.Now()
+	q.now = func() time.Time { return now }
+
+	q.Add("default/a")
+	q.Add("default/b")
+	item, _ := q.Get()
+	q.AddRateLimited(item)
+	q.Done(item)
+	item, _ = q.Get()
+
+	state, ok := QueueStateOf("state", common.CompositeController)
+	if !ok {
+		t.Fatal("expected the queue to be found")
+	}
+	if len(state.Processing) != 1 || state.Processing[0].Key != item || !state.Processing[0].Since.Equal(now) {
+		t.Errorf("expected %v to be processing since %v, got: %+v", item, now, state.Processing)
+	}
+	if len(state.Retrying) != 1 || state.Retrying[0].Key != "default/a" || state.Retrying[0].Requeues != 1 {
+		t.Errorf("expected default/a to be retried once, got: %+v", state.Retrying)
+	}
+
+	q.Forget("default/a")
+	if state := q.State(); len(state.Retrying) != 0 {
+		t.Errorf("expected no retries once forgotten, got: %+v", state.Retrying)
+	}
+}
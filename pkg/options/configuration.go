@@ -30,4 +30,47 @@ type Configuration struct {
 	Workers           int
 	CorrelatorOptions record.CorrelatorOptions
 	MetricsEndpoint   string
+	// LeaderElection enables leader election, so that only one replica runs
+	// controllers while the others stand by with warm caches.
+	LeaderElection          bool
+	LeaderElectionNamespace string
+	LeaderElectionID        string
+	// RBACPreflight checks that metacontroller has the permissions each
+	// controller needs before starting it.
+	RBACPreflight bool
+	// HookProbeInterval is how often webhooks are probed for reachability
+	// (disabled if zero).
+	HookProbeInterval time.Duration
+	// AdmissionPort is the port of the admission webhook server validating
+	// updates of parents (disabled if zero), serving the certificate found in
+	// AdmissionCertDir.
+	AdmissionPort    int
+	AdmissionCertDir string
+	// StripManagedFields drops the field sets of managed fields entries which
+	// Metacontroller doesn't read from the objects cached by its informers.
+	StripManagedFields bool
+	// InformerIdleTimeout is how long shared informers keep running once no
+	// controller uses them (stopped immediately if zero).
+	InformerIdleTimeout time.Duration
+	// InformerListPageSize is the maximum number of objects fetched by each
+	// list request of shared informers (left to client-go if zero).
+	InformerListPageSize int64
+	// DynamicClientQPS and DynamicClientBurst are the rate limits of the
+	// requests of controllers for parents and children (those of RestConfig
+	// if zero). StatusClientQPS and StatusClientBurst, if StatusClientQPS is
+	// positive, are the separate rate limits of the status updates of parents.
+	DynamicClientQPS   float32
+	DynamicClientBurst int
+	StatusClientQPS    float32
+	StatusClientBurst  int
+	// DebugTokenFile holds the bearer token required to read the state of
+	// the controllers on /debug/controllers and the recorded hook calls on
+	// /debug/hooks (neither served if empty), and to drain webhook endpoints
+	// on /debug/endpoints (forbidden if empty).
+	DebugTokenFile string
+	// SyncLoopSyncs syncs of an object, which each wrote its children or its
+	// status, within SyncLoopWindow make a hot loop, whose syncs are backed
+	// off (not detected if SyncLoopSyncs is zero).
+	SyncLoopSyncs  int
+	SyncLoopWindow time.Duration
 }
@@ -18,10 +18,19 @@ package server
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
 	"metacontroller/pkg/controller/common"
 
+	"metacontroller/pkg/controller/cron"
 	"metacontroller/pkg/controller/decorator"
+	"metacontroller/pkg/controller/external"
+	"metacontroller/pkg/controller/global"
+	"metacontroller/pkg/controller/statemachine"
+	"metacontroller/pkg/debug"
+	"metacontroller/pkg/hooks"
+	"metacontroller/pkg/metrics"
 	"metacontroller/pkg/options"
 
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -29,11 +38,14 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/apis/metacontroller/v1beta1"
 	mcclientset "metacontroller/pkg/client/generated/clientset/internalclientset"
 	"metacontroller/pkg/controller/composite"
 
 	controllerruntime "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
 
 	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
 )
@@ -51,12 +63,20 @@ func New(configuration options.Configuration) (controllerruntime.Manager, error)
 	if err != nil {
 		return nil, err
 	}
+	if err := metrics.RegisterInformerMetrics(controllerContext.DynInformers); err != nil {
+		return nil, fmt.Errorf("can't register informer metrics: %w", err)
+	}
 
 	mgr, err := controllerruntime.NewManager(configuration.RestConfig, manager.Options{
 		// Disables serving built-in metrics.
 		// We already start a standalone metrics server in parallel to the manager.
-		MetricsBindAddress: configuration.MetricsEndpoint,
-		EventBroadcaster:   controllerContext.Broadcaster,
+		MetricsBindAddress:      configuration.MetricsEndpoint,
+		EventBroadcaster:        controllerContext.Broadcaster,
+		LeaderElection:          configuration.LeaderElection,
+		LeaderElectionNamespace: configuration.LeaderElectionNamespace,
+		LeaderElectionID:        configuration.LeaderElectionID,
+		Port:                    configuration.AdmissionPort,
+		CertDir:                 configuration.AdmissionCertDir,
 	})
 	if err != nil {
 		return nil, err
@@ -66,13 +86,17 @@ func New(configuration options.Configuration) (controllerruntime.Manager, error)
 	if err != nil {
 		return nil, err
 	}
+	err = v1beta1.AddToScheme(mgr.GetScheme())
+	if err != nil {
+		return nil, err
+	}
 
 	// Set the Kubernetes client to the one created by the manager.
 	// In this way we can take advantage of the underlying caching
 	// mechanism for reads instead of hitting the API directly.
 	controllerContext.K8sClient = mgr.GetClient()
 
-	compositeReconciler := composite.NewMetacontroller(*controllerContext, mcClient, configuration.Workers)
+	compositeReconciler := composite.NewMetacontroller(*controllerContext, mcClient, configuration.Workers, configuration.RBACPreflight, configuration.HookProbeInterval)
 	compositeCtrl, err := controller.New("composite-metacontroller", mgr, controller.Options{
 		Reconciler: compositeReconciler,
 	})
@@ -83,8 +107,12 @@ func New(configuration options.Configuration) (controllerruntime.Manager, error)
 	if err != nil {
 		return nil, err
 	}
+	err = compositeCtrl.Watch(&source.Kind{Type: &v1alpha1.ControllerGroup{}}, handler.EnqueueRequestsFromMapFunc(compositeReconciler.ControllerGroupRequests))
+	if err != nil {
+		return nil, err
+	}
 
-	decoratorReconciler := decorator.NewMetacontroller(*controllerContext, configuration.Workers)
+	decoratorReconciler := decorator.NewMetacontroller(*controllerContext, configuration.Workers, configuration.RBACPreflight, configuration.HookProbeInterval)
 	decoratorCtrl, err := controller.New("decorator-metacontroller", mgr, controller.Options{
 		Reconciler: decoratorReconciler,
 	})
@@ -95,6 +123,107 @@ func New(configuration options.Configuration) (controllerruntime.Manager, error)
 	if err != nil {
 		return nil, err
 	}
+	err = decoratorCtrl.Watch(&source.Kind{Type: &v1alpha1.ControllerGroup{}}, handler.EnqueueRequestsFromMapFunc(decoratorReconciler.ControllerGroupRequests))
+	if err != nil {
+		return nil, err
+	}
+
+	globalReconciler := global.NewMetacontroller(*controllerContext, configuration.RBACPreflight, configuration.HookProbeInterval)
+	globalCtrl, err := controller.New("global-metacontroller", mgr, controller.Options{
+		Reconciler: globalReconciler,
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = globalCtrl.Watch(&source.Kind{Type: &v1alpha1.GlobalController{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return nil, err
+	}
+
+	cronReconciler := cron.NewMetacontroller(*controllerContext, configuration.RBACPreflight, configuration.HookProbeInterval)
+	cronCtrl, err := controller.New("cron-metacontroller", mgr, controller.Options{
+		Reconciler: cronReconciler,
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = cronCtrl.Watch(&source.Kind{Type: &v1alpha1.CronController{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return nil, err
+	}
+
+	externalReconciler := external.NewMetacontroller(*controllerContext, configuration.Workers, configuration.RBACPreflight, configuration.HookProbeInterval)
+	externalCtrl, err := controller.New("external-metacontroller", mgr, controller.Options{
+		Reconciler: externalReconciler,
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = externalCtrl.Watch(&source.Kind{Type: &v1alpha1.ExternalResourceController{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return nil, err
+	}
+
+	stateMachineReconciler := statemachine.NewMetacontroller(*controllerContext, configuration.Workers, configuration.RBACPreflight, configuration.HookProbeInterval)
+	stateMachineCtrl, err := controller.New("statemachine-metacontroller", mgr, controller.Options{
+		Reconciler: stateMachineReconciler,
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = stateMachineCtrl.Watch(&source.Kind{Type: &v1alpha1.StateMachineController{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the token required by the debug endpoints which are sensitive.
+	var token string
+	if configuration.DebugTokenFile != "" {
+		rawToken, err := os.ReadFile(configuration.DebugTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't read debug token: %w", err)
+		}
+		token = strings.TrimSpace(string(rawToken))
+		if token == "" {
+			return nil, fmt.Errorf("debug token file %s is empty", configuration.DebugTokenFile)
+		}
+	}
+
+	// Serve the state of webhook endpoints, and let those holding the token
+	// drain them.
+	err = mgr.AddMetricsExtraHandler("/debug/endpoints", debug.AuthenticatedWrites(token, hooks.Endpoints))
+	if err != nil {
+		return nil, err
+	}
+
+	// Serve the state of the running controllers, and the recorded hook calls,
+	// to those holding the token.
+	if token != "" {
+		err = mgr.AddMetricsExtraHandler("/debug/controllers", debug.Authenticated(token, debug.Controllers))
+		if err != nil {
+			return nil, err
+		}
+		// Serve the hook calls recorded for parents with the debug annotation,
+		// which can contain the contents of children and related objects.
+		err = mgr.AddMetricsExtraHandler("/debug/hooks", debug.Authenticated(token, hooks.DebugRecorder))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Reject changes to the selectors of parents, which would orphan their
+	// children. The webhook server is only started once a handler is registered.
+	if configuration.AdmissionPort > 0 {
+		mgr.GetWebhookServer().Register(composite.SelectorValidationPath, &webhook.Admission{Handler: composite.NewSelectorValidator(mgr.GetClient())})
+		// Convert CompositeControllers and DecoratorControllers between
+		// v1alpha1, the version they are stored in, and v1beta1.
+		mgr.GetWebhookServer().Register("/convert", &conversion.Webhook{})
+	}
+
+	err = addCacheWarmer(mgr, configuration, controllerContext, mgr.GetClient())
+	if err != nil {
+		return nil, err
+	}
 
 	// We need to call Start after initializing the controllers
 	// to make sure all the needed informers are already created
@@ -0,0 +1,229 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	dynamicinformer "metacontroller/pkg/dynamic/informer"
+	"metacontroller/pkg/logging"
+	"metacontroller/pkg/options"
+)
+
+// cacheWarmer subscribes to the shared informers of every resource used by
+// CompositeControllers, DecoratorControllers, GlobalControllers,
+// CronControllers, ExternalResourceControllers and StateMachineControllers, on
+// every replica.
+//
+// Controllers only run on the elected leader. Standby replicas still run the
+// cacheWarmer, so their caches are already synced when they take over, and
+// they can serve metrics and debug queries in the meantime. On the leader the
+// controllers share the same informers, so this adds no extra watches.
+type cacheWarmer struct {
+	client       client.Client
+	dynInformers *dynamicinformer.SharedInformerFactory
+	interval     time.Duration
+
+	informers map[warmerKey]*dynamicinformer.ResourceInformer
+}
+
+// warmerKey identifies an informer of the cacheWarmer. Like the controllers,
+// it subscribes to a separate informer for each distinct watch selector.
+type warmerKey struct {
+	apiVersion string
+	resource   string
+	selectors  dynamicinformer.Selectors
+}
+
+// warmerRule is a resource rule of a controller, with its watch selector if
+// the rule has one.
+type warmerRule struct {
+	v1alpha1.ResourceRule
+	watchSelector *v1alpha1.WatchSelector
+}
+
+// runnableAdder is the part of manager.Manager which runs the cacheWarmer.
+type runnableAdder interface {
+	Add(manager.Runnable) error
+}
+
+// addCacheWarmer adds a cacheWarmer to mgr if leader election is enabled.
+// Standby replicas don't run controllers, but keep their caches warm so they
+// can take over quickly and serve metrics in the meantime. Without leader
+// election, the controllers open the same informers themselves.
+func addCacheWarmer(mgr runnableAdder, configuration options.Configuration, controllerContext *common.ControllerContext, client client.Client) error {
+	if !configuration.LeaderElection {
+		return nil
+	}
+	return mgr.Add(newCacheWarmer(controllerContext, client, configuration.DiscoveryInterval))
+}
+
+func newCacheWarmer(controllerContext *common.ControllerContext, client client.Client, interval time.Duration) *cacheWarmer {
+	return &cacheWarmer{
+		client:       client,
+		dynInformers: controllerContext.DynInformers,
+		interval:     interval,
+		informers:    make(map[warmerKey]*dynamicinformer.ResourceInformer),
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, so the
+// cacheWarmer also runs on replicas which are not the leader.
+func (w *cacheWarmer) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable.
+func (w *cacheWarmer) Start(ctx context.Context) error {
+	defer w.closeAll()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.sync(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *cacheWarmer) sync(ctx context.Context) {
+	rules, err := w.resourceRules(ctx)
+	if err != nil {
+		logging.Logger.V(4).Info("Can't list controllers to warm caches", "error", err)
+		return
+	}
+
+	wanted := make(map[warmerKey]bool, len(rules))
+	for _, rule := range rules {
+		selectors, err := common.WatchSelectors(rule.watchSelector)
+		if err != nil {
+			// The controller fails to start too, so it has nothing to share.
+			logging.Logger.V(4).Info("Can't warm cache", "api_version", rule.APIVersion, "resource", rule.Resource, "error", err)
+			continue
+		}
+		key := warmerKey{apiVersion: rule.APIVersion, resource: rule.Resource, selectors: selectors}
+		wanted[key] = true
+		if _, ok := w.informers[key]; ok {
+			continue
+		}
+		informer, err := w.dynInformers.ResourceWithSelectors(rule.APIVersion, rule.Resource, selectors)
+		if err != nil {
+			// Discovery may not have caught up yet; retry on the next tick.
+			logging.Logger.V(4).Info("Can't warm cache", "api_version", rule.APIVersion, "resource", rule.Resource, "selectors", selectors, "error", err)
+			continue
+		}
+		w.informers[key] = informer
+	}
+
+	for key, informer := range w.informers {
+		if !wanted[key] {
+			informer.Close()
+			delete(w.informers, key)
+		}
+	}
+}
+
+func (w *cacheWarmer) resourceRules(ctx context.Context) ([]warmerRule, error) {
+	var rules []warmerRule
+
+	var ccList v1alpha1.CompositeControllerList
+	if err := w.client.List(ctx, &ccList); err != nil {
+		return nil, err
+	}
+	for _, cc := range ccList.Items {
+		rules = append(rules, warmerRule{ResourceRule: cc.Spec.ParentResource.ResourceRule, watchSelector: cc.Spec.ParentResource.WatchSelector})
+		for _, child := range cc.Spec.ChildResources {
+			rules = append(rules, warmerRule{ResourceRule: child.ResourceRule, watchSelector: child.WatchSelector})
+		}
+	}
+
+	var dcList v1alpha1.DecoratorControllerList
+	if err := w.client.List(ctx, &dcList); err != nil {
+		return nil, err
+	}
+	for _, dc := range dcList.Items {
+		for _, resource := range dc.Spec.Resources {
+			rules = append(rules, warmerRule{ResourceRule: resource.ResourceRule, watchSelector: resource.WatchSelector})
+		}
+		for _, attachment := range dc.Spec.Attachments {
+			rules = append(rules, warmerRule{ResourceRule: attachment.ResourceRule, watchSelector: attachment.WatchSelector})
+		}
+	}
+
+	var gcList v1alpha1.GlobalControllerList
+	if err := w.client.List(ctx, &gcList); err != nil {
+		return nil, err
+	}
+	for _, gc := range gcList.Items {
+		for _, attachment := range gc.Spec.Attachments {
+			rules = append(rules, warmerRule{ResourceRule: attachment.ResourceRule})
+		}
+		for _, related := range gc.Spec.Related {
+			rules = append(rules, warmerRule{ResourceRule: related.ResourceRule})
+		}
+	}
+
+	var cronList v1alpha1.CronControllerList
+	if err := w.client.List(ctx, &cronList); err != nil {
+		return nil, err
+	}
+	for _, cronCtl := range cronList.Items {
+		if cronCtl.Spec.ParentResource != nil {
+			rules = append(rules, warmerRule{ResourceRule: cronCtl.Spec.ParentResource.ResourceRule})
+		}
+		for _, child := range cronCtl.Spec.ChildResources {
+			rules = append(rules, warmerRule{ResourceRule: child.ResourceRule})
+		}
+	}
+
+	var ercList v1alpha1.ExternalResourceControllerList
+	if err := w.client.List(ctx, &ercList); err != nil {
+		return nil, err
+	}
+	for _, erc := range ercList.Items {
+		rules = append(rules, warmerRule{ResourceRule: erc.Spec.ParentResource.ResourceRule})
+	}
+
+	var smcList v1alpha1.StateMachineControllerList
+	if err := w.client.List(ctx, &smcList); err != nil {
+		return nil, err
+	}
+	for _, smc := range smcList.Items {
+		rules = append(rules, warmerRule{ResourceRule: smc.Spec.ParentResource.ResourceRule})
+		for _, child := range smc.Spec.ChildResources {
+			rules = append(rules, warmerRule{ResourceRule: child.ResourceRule})
+		}
+	}
+	return rules, nil
+}
+
+func (w *cacheWarmer) closeAll() {
+	for key, informer := range w.informers {
+		informer.Close()
+		delete(w.informers, key)
+	}
+}
@@ -0,0 +1,215 @@
+package server
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"metacontroller/pkg/apis/metacontroller/v1alpha1"
+	"metacontroller/pkg/controller/common"
+	dynamicclientset "metacontroller/pkg/dynamic/clientset"
+	dynamicdiscovery "metacontroller/pkg/dynamic/discovery"
+	dynamicinformer "metacontroller/pkg/dynamic/informer"
+	"metacontroller/pkg/options"
+)
+
+// newTestControllerContext returns a ControllerContext whose informers watch
+// configmaps and secrets through a fake dynamic client.
+func newTestControllerContext() *common.ControllerContext {
+	discovery := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+				{Name: "secrets", Kind: "Secret", Namespaced: true},
+			},
+		},
+	}}}
+	resources := dynamicdiscovery.NewResourceMap(discovery)
+	resources.Refresh()
+	dc := fakedynamic.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		{Version: "v1", Resource: "configmaps"}: "ConfigMapList",
+		{Version: "v1", Resource: "secrets"}:    "SecretList",
+	})
+	clientset := dynamicclientset.NewForDynamicClient(resources, dc)
+	return &common.ControllerContext{
+		Resources:    resources,
+		DynClient:    clientset,
+		DynInformers: dynamicinformer.NewSharedInformerFactory(clientset, 0),
+	}
+}
+
+func newTestClient(t *testing.T, objects ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+}
+
+func newCompositeController(name, parentResource, childResource string) *v1alpha1.CompositeController {
+	return &v1alpha1.CompositeController{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1alpha1.CompositeControllerSpec{
+			ParentResource: v1alpha1.CompositeControllerParentResourceRule{
+				ResourceRule: v1alpha1.ResourceRule{APIVersion: "v1", Resource: parentResource},
+			},
+			ChildResources: []v1alpha1.CompositeControllerChildResourceRule{
+				{ResourceRule: v1alpha1.ResourceRule{APIVersion: "v1", Resource: childResource}},
+			},
+		},
+	}
+}
+
+// warmedInformers returns the informers running in factory, with their
+// number of subscribers.
+func warmedInformers(factory *dynamicinformer.SharedInformerFactory) []string {
+	var informers []string
+	for _, stats := range factory.Stats() {
+		informer := stats.Resource
+		if !stats.Selectors.IsEmpty() {
+			informer += "?" + stats.Selectors.LabelSelector
+		}
+		informers = append(informers, informer+"="+strconv.Itoa(stats.Subscribers))
+	}
+	sort.Strings(informers)
+	return informers
+}
+
+func expectInformers(t *testing.T, factory *dynamicinformer.SharedInformerFactory, expected ...string) {
+	t.Helper()
+	got := warmedInformers(factory)
+	if len(got) != len(expected) {
+		t.Fatalf("expected informers %v, got %v", expected, got)
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Fatalf("expected informers %v, got %v", expected, got)
+		}
+	}
+}
+
+func TestCacheWarmer_sync(t *testing.T) {
+	controllerContext := newTestControllerContext()
+	composite := newCompositeController("composite", "configmaps", "secrets")
+	decorator := &v1alpha1.DecoratorController{
+		ObjectMeta: metav1.ObjectMeta{Name: "decorator"},
+		Spec: v1alpha1.DecoratorControllerSpec{
+			Resources: []v1alpha1.DecoratorControllerResourceRule{
+				{
+					ResourceRule:  v1alpha1.ResourceRule{APIVersion: "v1", Resource: "configmaps"},
+					WatchSelector: &v1alpha1.WatchSelector{LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}}},
+				},
+			},
+			Attachments: []v1alpha1.DecoratorControllerAttachmentRule{
+				// Shares the informer of the CompositeController.
+				{ResourceRule: v1alpha1.ResourceRule{APIVersion: "v1", Resource: "secrets"}},
+				// Not served yet, so it's retried on the next sync.
+				{ResourceRule: v1alpha1.ResourceRule{APIVersion: "example.com/v1", Resource: "things"}},
+			},
+		},
+	}
+	k8sClient := newTestClient(t, composite, decorator)
+	w := newCacheWarmer(controllerContext, k8sClient, time.Minute)
+
+	w.sync(context.TODO())
+	expectInformers(t, controllerContext.DynInformers, "configmaps=1", "configmaps?app=test=1", "secrets=1")
+
+	// Syncing again keeps the informers which are still wanted.
+	w.sync(context.TODO())
+	expectInformers(t, controllerContext.DynInformers, "configmaps=1", "configmaps?app=test=1", "secrets=1")
+
+	// The rules of the remaining controllers are refreshed: the informers which
+	// are no longer used are closed, and the new ones are opened.
+	if err := k8sClient.Delete(context.TODO(), decorator); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := k8sClient.Create(context.TODO(), newCompositeController("other", "secrets", "secrets")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w.sync(context.TODO())
+	expectInformers(t, controllerContext.DynInformers, "configmaps=1", "secrets=1")
+	if len(w.informers) != 2 {
+		t.Errorf("expected 2 informers, got %v", w.informers)
+	}
+}
+
+func TestCacheWarmer_Start(t *testing.T) {
+	controllerContext := newTestControllerContext()
+	k8sClient := newTestClient(t, newCompositeController("composite", "configmaps", "secrets"))
+	w := newCacheWarmer(controllerContext, k8sClient, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- w.Start(ctx)
+	}()
+
+	// The caches are warmed as soon as it starts, without waiting for a tick.
+	deadline := time.Now().Add(5 * time.Second)
+	for len(controllerContext.DynInformers.Stats()) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the caches to be warmed, got %v", warmedInformers(controllerContext.DynInformers))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("err should be nil, got: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the cache warmer to stop")
+	}
+	expectInformers(t, controllerContext.DynInformers)
+}
+
+type recordingManager struct {
+	runnables []manager.Runnable
+}
+
+func (m *recordingManager) Add(runnable manager.Runnable) error {
+	m.runnables = append(m.runnables, runnable)
+	return nil
+}
+
+func TestAddCacheWarmer(t *testing.T) {
+	controllerContext := newTestControllerContext()
+	k8sClient := newTestClient(t)
+
+	mgr := &recordingManager{}
+	if err := addCacheWarmer(mgr, options.Configuration{LeaderElection: false}, controllerContext, k8sClient); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if len(mgr.runnables) != 0 {
+		t.Errorf("expected no cache warmer without leader election, got: %v", mgr.runnables)
+	}
+
+	if err := addCacheWarmer(mgr, options.Configuration{LeaderElection: true, DiscoveryInterval: time.Minute}, controllerContext, k8sClient); err != nil {
+		t.Fatalf("err should be nil, got: %v", err)
+	}
+	if len(mgr.runnables) != 1 {
+		t.Fatalf("expected a cache warmer with leader election, got: %v", mgr.runnables)
+	}
+	w, ok := mgr.runnables[0].(manager.LeaderElectionRunnable)
+	if !ok {
+		t.Fatalf("expected a manager.LeaderElectionRunnable, got: %T", mgr.runnables[0])
+	}
+	if w.NeedLeaderElection() {
+		t.Errorf("expected the cache warmer to also run on standby replicas")
+	}
+}
@@ -0,0 +1,246 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	otlpTracesPath     = "/v1/traces"
+	otlpMaxBatchSize   = 512
+	otlpMaxQueueSize   = 2048
+	otlpExportInterval = 5 * time.Second
+
+	otlpSpanKindInternal = 1
+	otlpSpanKindClient   = 3
+	otlpStatusCodeError  = 2
+)
+
+// OTLPExporter batches finished spans and sends them to an OpenTelemetry
+// collector using the OTLP/HTTP JSON encoding.
+type OTLPExporter struct {
+	client      *http.Client
+	url         string
+	serviceName string
+
+	queue  chan *Span
+	stopCh chan struct{}
+	doneCh chan struct{}
+
+	// OnError is called when a batch could not be delivered.
+	OnError func(err error)
+}
+
+// NewOTLPExporter returns a started exporter which sends spans to the given
+// collector endpoint, e.g. "http://otel-collector:4318".
+func NewOTLPExporter(endpoint, serviceName string) *OTLPExporter {
+	url := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(url, otlpTracesPath) {
+		url += otlpTracesPath
+	}
+	e := &OTLPExporter{
+		client:      &http.Client{Timeout: 10 * time.Second},
+		url:         url,
+		serviceName: serviceName,
+		queue:       make(chan *Span, otlpMaxQueueSize),
+		stopCh:      make(chan struct{}),
+		doneCh:      make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// Export queues the span for delivery. Spans are dropped if the queue is full
+// so that tracing never blocks reconciliation.
+func (e *OTLPExporter) Export(span *Span) {
+	select {
+	case e.queue <- span:
+	default:
+	}
+}
+
+// Shutdown flushes queued spans and stops the exporter.
+func (e *OTLPExporter) Shutdown() {
+	close(e.stopCh)
+	<-e.doneCh
+}
+
+func (e *OTLPExporter) run() {
+	defer close(e.doneCh)
+
+	ticker := time.NewTicker(otlpExportInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, otlpMaxBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.send(batch); err != nil && e.OnError != nil {
+			e.OnError(err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) >= otlpMaxBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-e.stopCh:
+			for {
+				select {
+				case span := <-e.queue:
+					batch = append(batch, span)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (e *OTLPExporter) send(spans []*Span) error {
+	body, err := json.Marshal(e.encode(spans))
+	if err != nil {
+		return fmt.Errorf("can't marshal spans: %w", err)
+	}
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("can't export spans: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("can't export spans: collector returned %d: %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            *otlpStatus    `json:"status,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func (e *OTLPExporter) encode(spans []*Span) otlpRequest {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		encoded = append(encoded, encodeSpan(span))
+	}
+	return otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: []otlpKeyValue{{Key: "service.name", Value: otlpAnyValue{StringValue: e.serviceName}}},
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "metacontroller"},
+				Spans: encoded,
+			}},
+		}},
+	}
+}
+
+func encodeSpan(span *Span) otlpSpan {
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+
+	encoded := otlpSpan{
+		TraceID:           hex.EncodeToString(span.TraceID[:]),
+		SpanID:            hex.EncodeToString(span.SpanID[:]),
+		Name:              span.Name,
+		Kind:              otlpSpanKindInternal,
+		StartTimeUnixNano: strconv.FormatInt(span.StartTime.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+	}
+	if span.ParentSpanID != [8]byte{} {
+		encoded.ParentSpanID = hex.EncodeToString(span.ParentSpanID[:])
+	}
+	if span.Client {
+		encoded.Kind = otlpSpanKindClient
+	}
+	keys := make([]string, 0, len(span.Attributes))
+	for key := range span.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		encoded.Attributes = append(encoded.Attributes, otlpKeyValue{Key: key, Value: otlpAnyValue{StringValue: span.Attributes[key]}})
+	}
+	if span.Err != nil {
+		encoded.Status = &otlpStatus{Code: otlpStatusCodeError, Message: span.Err.Error()}
+	}
+	return encoded
+}
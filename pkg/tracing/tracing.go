@@ -0,0 +1,213 @@
+/*
+Copyright 2021 Metacontroller authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing provides minimal span tracking with W3C Trace Context
+// propagation and an OTLP/HTTP exporter.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceparentHeader is the W3C Trace Context header used to propagate spans.
+const TraceparentHeader = "traceparent"
+
+// SyncIDHeader carries the ID of the sync a hook call is part of.
+const SyncIDHeader = "X-Metacontroller-Sync-ID"
+
+// Exporter receives finished spans.
+type Exporter interface {
+	Export(span *Span)
+}
+
+var (
+	exporterMutex sync.RWMutex
+	exporter      Exporter
+)
+
+// SetExporter configures the exporter which receives all finished spans.
+// Passing nil disables exporting; spans are still created so trace context
+// keeps being propagated to hooks.
+func SetExporter(e Exporter) {
+	exporterMutex.Lock()
+	defer exporterMutex.Unlock()
+	exporter = e
+}
+
+func currentExporter() Exporter {
+	exporterMutex.RLock()
+	defer exporterMutex.RUnlock()
+	return exporter
+}
+
+// Span is a single timed operation within a trace.
+type Span struct {
+	Name         string
+	TraceID      [16]byte
+	SpanID       [8]byte
+	ParentSpanID [8]byte
+	Client       bool
+	StartTime    time.Time
+	EndTime      time.Time
+	Attributes   map[string]string
+	Err          error
+
+	mutex sync.Mutex
+	ended bool
+}
+
+type spanContextKey struct{}
+
+// Start creates a new span which is a child of the span stored in ctx, if any,
+// and returns a context carrying the new span.
+func Start(ctx context.Context, name string, keysAndValues ...string) (context.Context, *Span) {
+	span := &Span{
+		Name:       name,
+		StartTime:  time.Now(),
+		Attributes: make(map[string]string),
+	}
+	if parent := FromContext(ctx); parent != nil {
+		span.TraceID = parent.TraceID
+		span.ParentSpanID = parent.SpanID
+	} else {
+		randomBytes(span.TraceID[:])
+	}
+	randomBytes(span.SpanID[:])
+	span.SetAttributes(keysAndValues...)
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// StartClient is like Start, but marks the span as an outgoing request.
+func StartClient(ctx context.Context, name string, keysAndValues ...string) (context.Context, *Span) {
+	ctx, span := Start(ctx, name, keysAndValues...)
+	span.Client = true
+	return ctx, span
+}
+
+// FromContext returns the span stored in ctx, or nil if there is none.
+func FromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// SetAttributes records the given key/value pairs on the span.
+func (s *Span) SetAttributes(keysAndValues ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		s.Attributes[keysAndValues[i]] = keysAndValues[i+1]
+	}
+}
+
+// RecordError marks the span as failed. A nil error is ignored.
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Err = err
+}
+
+// End finishes the span and hands it to the configured exporter.
+// Calling End more than once has no effect.
+func (s *Span) End() {
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	s.EndTime = time.Now()
+	s.mutex.Unlock()
+
+	if e := currentExporter(); e != nil {
+		e.Export(s)
+	}
+}
+
+// Traceparent returns the W3C traceparent header value for the span.
+func (s *Span) Traceparent() string {
+	flags := "00"
+	if currentExporter() != nil {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", hex.EncodeToString(s.TraceID[:]), hex.EncodeToString(s.SpanID[:]), flags)
+}
+
+// SyncID returns the ID of the sync the span stored in ctx is part of, or ""
+// if there's none. Each sync starts a new trace, so it's the trace ID, which
+// also finds the spans of the sync.
+func SyncID(ctx context.Context) string {
+	if span := FromContext(ctx); span != nil {
+		return hex.EncodeToString(span.TraceID[:])
+	}
+	return ""
+}
+
+// Inject sets the traceparent and sync ID headers for the span stored in ctx,
+// if any.
+func Inject(ctx context.Context, header http.Header) {
+	if span := FromContext(ctx); span != nil {
+		header.Set(TraceparentHeader, span.Traceparent())
+		header.Set(SyncIDHeader, SyncID(ctx))
+	}
+}
+
+// Extract returns a context whose next span continues the trace described by
+// the given traceparent header value. Invalid values are ignored.
+func Extract(ctx context.Context, traceparent string) context.Context {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) != 4 || parts[0] != "00" {
+		return ctx
+	}
+	remote := &Span{}
+	if !decodeHex(remote.TraceID[:], parts[1]) || !decodeHex(remote.SpanID[:], parts[2]) {
+		return ctx
+	}
+	return context.WithValue(ctx, spanContextKey{}, remote)
+}
+
+func decodeHex(dst []byte, s string) bool {
+	if hex.DecodedLen(len(s)) != len(dst) {
+		return false
+	}
+	if _, err := hex.Decode(dst, []byte(s)); err != nil {
+		return false
+	}
+	for _, b := range dst {
+		if b != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func randomBytes(b []byte) {
+	// crypto/rand only fails if the OS entropy source is unavailable, in which
+	// case there is nothing sensible left to do with the trace anyway.
+	_, _ = rand.Read(b)
+}
@@ -0,0 +1,144 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+type recordingExporter struct {
+	spans []*Span
+}
+
+func (r *recordingExporter) Export(span *Span) {
+	r.spans = append(r.spans, span)
+}
+
+func TestStart_childSpanSharesTraceID(t *testing.T) {
+	ctx, parent := Start(context.Background(), "parent")
+	_, child := Start(ctx, "child")
+
+	if child.TraceID != parent.TraceID {
+		t.Errorf("expected child to share trace id %x, got %x", parent.TraceID, child.TraceID)
+	}
+	if child.ParentSpanID != parent.SpanID {
+		t.Errorf("expected child parent span id %x, got %x", parent.SpanID, child.ParentSpanID)
+	}
+	if child.SpanID == parent.SpanID {
+		t.Errorf("expected child to have its own span id")
+	}
+}
+
+func TestInject_setsTraceparentHeader(t *testing.T) {
+	ctx, span := Start(context.Background(), "sync")
+	header := http.Header{}
+
+	Inject(ctx, header)
+
+	traceparent := header.Get(TraceparentHeader)
+	if !regexp.MustCompile(`^00-[0-9a-f]{32}-[0-9a-f]{16}-0[01]$`).MatchString(traceparent) {
+		t.Errorf("invalid traceparent header: %q", traceparent)
+	}
+	if traceparent != span.Traceparent() {
+		t.Errorf("expected %q, got %q", span.Traceparent(), traceparent)
+	}
+	if syncID := header.Get(SyncIDHeader); syncID == "" || !strings.Contains(traceparent, syncID) {
+		t.Errorf("expected the trace ID as sync ID, got %q", syncID)
+	}
+}
+
+func TestInject_withoutSpan_doesNothing(t *testing.T) {
+	header := http.Header{}
+
+	Inject(context.Background(), header)
+
+	if len(header) != 0 {
+		t.Errorf("expected no headers, got %v", header)
+	}
+}
+
+func TestExtract_continuesRemoteTrace(t *testing.T) {
+	ctx := Extract(context.Background(), "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	_, span := Start(ctx, "hook")
+
+	if got := fmt.Sprintf("%x", span.TraceID); got != "0af7651916cd43dd8448eb211c80319c" {
+		t.Errorf("unexpected trace id %s", got)
+	}
+	if got := fmt.Sprintf("%x", span.ParentSpanID); got != "b7ad6b7169203331" {
+		t.Errorf("unexpected parent span id %s", got)
+	}
+}
+
+func TestExtract_ignoresInvalidHeader(t *testing.T) {
+	tables := []string{
+		"",
+		"garbage",
+		"01-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		"00-00000000000000000000000000000000-b7ad6b7169203331-01",
+		"00-0af7651916cd43dd8448eb211c80319c-zzzz6b7169203331-01",
+	}
+	for _, table := range tables {
+		if FromContext(Extract(context.Background(), table)) != nil {
+			t.Errorf("expected header %q to be ignored", table)
+		}
+	}
+}
+
+func TestEnd_exportsOnce(t *testing.T) {
+	exporter := &recordingExporter{}
+	SetExporter(exporter)
+	defer SetExporter(nil)
+
+	_, span := Start(context.Background(), "sync")
+	span.End()
+	span.End()
+
+	if len(exporter.spans) != 1 {
+		t.Errorf("expected 1 exported span, got %d", len(exporter.spans))
+	}
+}
+
+func TestOTLPExporter_sendsSpansOnShutdown(t *testing.T) {
+	bodies := make(chan otlpRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != otlpTracesPath {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		var request otlpRequest
+		if err := json.Unmarshal(body, &request); err != nil {
+			t.Errorf("can't unmarshal request: %v", err)
+		}
+		bodies <- request
+	}))
+	defer server.Close()
+
+	exporter := NewOTLPExporter(server.URL, "metacontroller")
+	ctx, parent := Start(context.Background(), "sync", "controller", "test")
+	_, child := StartClient(ctx, "hook")
+	child.RecordError(fmt.Errorf("boom"))
+	exporter.Export(child)
+	exporter.Export(parent)
+	exporter.Shutdown()
+
+	request := <-bodies
+	spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Kind != otlpSpanKindClient || spans[0].Status == nil || spans[0].Status.Code != otlpStatusCodeError {
+		t.Errorf("unexpected hook span %+v", spans[0])
+	}
+	if spans[0].ParentSpanID != spans[1].SpanID {
+		t.Errorf("expected hook span to be a child of sync span")
+	}
+	if len(spans[1].Attributes) != 1 || spans[1].Attributes[0].Key != "controller" {
+		t.Errorf("unexpected sync span attributes %+v", spans[1].Attributes)
+	}
+}
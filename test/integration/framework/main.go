@@ -126,6 +126,21 @@ func testMain(tests func() int) error {
 	if err := execKubectl("wait", "--for=condition=Established", "crd", "decoratorcontrollers.metacontroller.k8s.io"); err != nil {
 		return fmt.Errorf("cannot install metacontroller CRDs: %v", err)
 	}
+	if err := execKubectl("wait", "--for=condition=Established", "crd", "globalcontrollers.metacontroller.k8s.io"); err != nil {
+		return fmt.Errorf("cannot install metacontroller CRDs: %v", err)
+	}
+	if err := execKubectl("wait", "--for=condition=Established", "crd", "croncontrollers.metacontroller.k8s.io"); err != nil {
+		return fmt.Errorf("cannot install metacontroller CRDs: %v", err)
+	}
+	if err := execKubectl("wait", "--for=condition=Established", "crd", "externalresourcecontrollers.metacontroller.k8s.io"); err != nil {
+		return fmt.Errorf("cannot install metacontroller CRDs: %v", err)
+	}
+	if err := execKubectl("wait", "--for=condition=Established", "crd", "statemachinecontrollers.metacontroller.k8s.io"); err != nil {
+		return fmt.Errorf("cannot install metacontroller CRDs: %v", err)
+	}
+	if err := execKubectl("wait", "--for=condition=Established", "crd", "controllergroups.metacontroller.k8s.io"); err != nil {
+		return fmt.Errorf("cannot install metacontroller CRDs: %v", err)
+	}
 
 	// In this integration test environment, there are no Nodes, so the
 	// metacontroller StatefulSet will not actually run anything.